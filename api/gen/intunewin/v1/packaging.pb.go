@@ -0,0 +1,687 @@
+// Packaging operations of intunewin for callers that integrate over gRPC.
+//
+// Packages and archives are streamed in chunks: the first message of a request
+// stream carries the options, and every message carries the next chunk of data.
+// The operations match the HTTP endpoints of `intunewin serve`.
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.8
+// 	protoc        (unknown)
+// source: intunewin/v1/packaging.proto
+
+package intunewinv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// ArchiveFormat is the format of the uploaded source archive.
+type ArchiveFormat int32
+
+const (
+	ArchiveFormat_ARCHIVE_FORMAT_UNSPECIFIED ArchiveFormat = 0
+	ArchiveFormat_ARCHIVE_FORMAT_ZIP         ArchiveFormat = 1
+	ArchiveFormat_ARCHIVE_FORMAT_TAR         ArchiveFormat = 2
+)
+
+// Enum value maps for ArchiveFormat.
+var (
+	ArchiveFormat_name = map[int32]string{
+		0: "ARCHIVE_FORMAT_UNSPECIFIED",
+		1: "ARCHIVE_FORMAT_ZIP",
+		2: "ARCHIVE_FORMAT_TAR",
+	}
+	ArchiveFormat_value = map[string]int32{
+		"ARCHIVE_FORMAT_UNSPECIFIED": 0,
+		"ARCHIVE_FORMAT_ZIP":         1,
+		"ARCHIVE_FORMAT_TAR":         2,
+	}
+)
+
+func (x ArchiveFormat) Enum() *ArchiveFormat {
+	p := new(ArchiveFormat)
+	*p = x
+	return p
+}
+
+func (x ArchiveFormat) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (ArchiveFormat) Descriptor() protoreflect.EnumDescriptor {
+	return file_intunewin_v1_packaging_proto_enumTypes[0].Descriptor()
+}
+
+func (ArchiveFormat) Type() protoreflect.EnumType {
+	return &file_intunewin_v1_packaging_proto_enumTypes[0]
+}
+
+func (x ArchiveFormat) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use ArchiveFormat.Descriptor instead.
+func (ArchiveFormat) EnumDescriptor() ([]byte, []int) {
+	return file_intunewin_v1_packaging_proto_rawDescGZIP(), []int{0}
+}
+
+// PackOptions describes the package to create.
+type PackOptions struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Name of the application, "app" when empty.
+	Name string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	// Path of the setup file in the archive.
+	SetupFile string        `protobuf:"bytes,2,opt,name=setup_file,json=setupFile,proto3" json:"setup_file,omitempty"`
+	Format    ArchiveFormat `protobuf:"varint,3,opt,name=format,proto3,enum=intunewin.v1.ArchiveFormat" json:"format,omitempty"`
+	// Description recorded in Detection.xml.
+	Description   string `protobuf:"bytes,4,opt,name=description,proto3" json:"description,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PackOptions) Reset() {
+	*x = PackOptions{}
+	mi := &file_intunewin_v1_packaging_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PackOptions) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PackOptions) ProtoMessage() {}
+
+func (x *PackOptions) ProtoReflect() protoreflect.Message {
+	mi := &file_intunewin_v1_packaging_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PackOptions.ProtoReflect.Descriptor instead.
+func (*PackOptions) Descriptor() ([]byte, []int) {
+	return file_intunewin_v1_packaging_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *PackOptions) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *PackOptions) GetSetupFile() string {
+	if x != nil {
+		return x.SetupFile
+	}
+	return ""
+}
+
+func (x *PackOptions) GetFormat() ArchiveFormat {
+	if x != nil {
+		return x.Format
+	}
+	return ArchiveFormat_ARCHIVE_FORMAT_UNSPECIFIED
+}
+
+func (x *PackOptions) GetDescription() string {
+	if x != nil {
+		return x.Description
+	}
+	return ""
+}
+
+type PackRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Set in the first message of the stream only.
+	Options *PackOptions `protobuf:"bytes,1,opt,name=options,proto3" json:"options,omitempty"`
+	// Next chunk of the source archive.
+	Chunk         []byte `protobuf:"bytes,2,opt,name=chunk,proto3" json:"chunk,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PackRequest) Reset() {
+	*x = PackRequest{}
+	mi := &file_intunewin_v1_packaging_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PackRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PackRequest) ProtoMessage() {}
+
+func (x *PackRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_intunewin_v1_packaging_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PackRequest.ProtoReflect.Descriptor instead.
+func (*PackRequest) Descriptor() ([]byte, []int) {
+	return file_intunewin_v1_packaging_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *PackRequest) GetOptions() *PackOptions {
+	if x != nil {
+		return x.Options
+	}
+	return nil
+}
+
+func (x *PackRequest) GetChunk() []byte {
+	if x != nil {
+		return x.Chunk
+	}
+	return nil
+}
+
+type PackResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Next chunk of the intunewin package.
+	Chunk         []byte `protobuf:"bytes,1,opt,name=chunk,proto3" json:"chunk,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PackResponse) Reset() {
+	*x = PackResponse{}
+	mi := &file_intunewin_v1_packaging_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PackResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PackResponse) ProtoMessage() {}
+
+func (x *PackResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_intunewin_v1_packaging_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PackResponse.ProtoReflect.Descriptor instead.
+func (*PackResponse) Descriptor() ([]byte, []int) {
+	return file_intunewin_v1_packaging_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *PackResponse) GetChunk() []byte {
+	if x != nil {
+		return x.Chunk
+	}
+	return nil
+}
+
+type UnpackRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Next chunk of the intunewin package.
+	Chunk         []byte `protobuf:"bytes,1,opt,name=chunk,proto3" json:"chunk,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UnpackRequest) Reset() {
+	*x = UnpackRequest{}
+	mi := &file_intunewin_v1_packaging_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UnpackRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UnpackRequest) ProtoMessage() {}
+
+func (x *UnpackRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_intunewin_v1_packaging_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UnpackRequest.ProtoReflect.Descriptor instead.
+func (*UnpackRequest) Descriptor() ([]byte, []int) {
+	return file_intunewin_v1_packaging_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *UnpackRequest) GetChunk() []byte {
+	if x != nil {
+		return x.Chunk
+	}
+	return nil
+}
+
+type UnpackResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Next chunk of the zip of the decrypted content.
+	Chunk         []byte `protobuf:"bytes,1,opt,name=chunk,proto3" json:"chunk,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UnpackResponse) Reset() {
+	*x = UnpackResponse{}
+	mi := &file_intunewin_v1_packaging_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UnpackResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UnpackResponse) ProtoMessage() {}
+
+func (x *UnpackResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_intunewin_v1_packaging_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UnpackResponse.ProtoReflect.Descriptor instead.
+func (*UnpackResponse) Descriptor() ([]byte, []int) {
+	return file_intunewin_v1_packaging_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *UnpackResponse) GetChunk() []byte {
+	if x != nil {
+		return x.Chunk
+	}
+	return nil
+}
+
+type InspectRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Next chunk of the intunewin package.
+	Chunk         []byte `protobuf:"bytes,1,opt,name=chunk,proto3" json:"chunk,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *InspectRequest) Reset() {
+	*x = InspectRequest{}
+	mi := &file_intunewin_v1_packaging_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *InspectRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*InspectRequest) ProtoMessage() {}
+
+func (x *InspectRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_intunewin_v1_packaging_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use InspectRequest.ProtoReflect.Descriptor instead.
+func (*InspectRequest) Descriptor() ([]byte, []int) {
+	return file_intunewin_v1_packaging_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *InspectRequest) GetChunk() []byte {
+	if x != nil {
+		return x.Chunk
+	}
+	return nil
+}
+
+// EncryptionInfo is the EncryptionInfo element of Detection.xml. Every value
+// is base64 encoded.
+type EncryptionInfo struct {
+	state                protoimpl.MessageState `protogen:"open.v1"`
+	EncryptionKey        string                 `protobuf:"bytes,1,opt,name=encryption_key,json=encryptionKey,proto3" json:"encryption_key,omitempty"`
+	MacKey               string                 `protobuf:"bytes,2,opt,name=mac_key,json=macKey,proto3" json:"mac_key,omitempty"`
+	InitializationVector string                 `protobuf:"bytes,3,opt,name=initialization_vector,json=initializationVector,proto3" json:"initialization_vector,omitempty"`
+	Mac                  string                 `protobuf:"bytes,4,opt,name=mac,proto3" json:"mac,omitempty"`
+	ProfileIdentifier    string                 `protobuf:"bytes,5,opt,name=profile_identifier,json=profileIdentifier,proto3" json:"profile_identifier,omitempty"`
+	FileDigest           string                 `protobuf:"bytes,6,opt,name=file_digest,json=fileDigest,proto3" json:"file_digest,omitempty"`
+	FileDigestAlgorithm  string                 `protobuf:"bytes,7,opt,name=file_digest_algorithm,json=fileDigestAlgorithm,proto3" json:"file_digest_algorithm,omitempty"`
+	unknownFields        protoimpl.UnknownFields
+	sizeCache            protoimpl.SizeCache
+}
+
+func (x *EncryptionInfo) Reset() {
+	*x = EncryptionInfo{}
+	mi := &file_intunewin_v1_packaging_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EncryptionInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EncryptionInfo) ProtoMessage() {}
+
+func (x *EncryptionInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_intunewin_v1_packaging_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EncryptionInfo.ProtoReflect.Descriptor instead.
+func (*EncryptionInfo) Descriptor() ([]byte, []int) {
+	return file_intunewin_v1_packaging_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *EncryptionInfo) GetEncryptionKey() string {
+	if x != nil {
+		return x.EncryptionKey
+	}
+	return ""
+}
+
+func (x *EncryptionInfo) GetMacKey() string {
+	if x != nil {
+		return x.MacKey
+	}
+	return ""
+}
+
+func (x *EncryptionInfo) GetInitializationVector() string {
+	if x != nil {
+		return x.InitializationVector
+	}
+	return ""
+}
+
+func (x *EncryptionInfo) GetMac() string {
+	if x != nil {
+		return x.Mac
+	}
+	return ""
+}
+
+func (x *EncryptionInfo) GetProfileIdentifier() string {
+	if x != nil {
+		return x.ProfileIdentifier
+	}
+	return ""
+}
+
+func (x *EncryptionInfo) GetFileDigest() string {
+	if x != nil {
+		return x.FileDigest
+	}
+	return ""
+}
+
+func (x *EncryptionInfo) GetFileDigestAlgorithm() string {
+	if x != nil {
+		return x.FileDigestAlgorithm
+	}
+	return ""
+}
+
+// InspectResponse is the Detection.xml metadata of a package.
+type InspectResponse struct {
+	state                  protoimpl.MessageState `protogen:"open.v1"`
+	ToolVersion            string                 `protobuf:"bytes,1,opt,name=tool_version,json=toolVersion,proto3" json:"tool_version,omitempty"`
+	Name                   string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Description            string                 `protobuf:"bytes,3,opt,name=description,proto3" json:"description,omitempty"`
+	UnencryptedContentSize int64                  `protobuf:"varint,4,opt,name=unencrypted_content_size,json=unencryptedContentSize,proto3" json:"unencrypted_content_size,omitempty"`
+	FileName               string                 `protobuf:"bytes,5,opt,name=file_name,json=fileName,proto3" json:"file_name,omitempty"`
+	SetupFile              string                 `protobuf:"bytes,6,opt,name=setup_file,json=setupFile,proto3" json:"setup_file,omitempty"`
+	EncryptionInfo         *EncryptionInfo        `protobuf:"bytes,7,opt,name=encryption_info,json=encryptionInfo,proto3" json:"encryption_info,omitempty"`
+	unknownFields          protoimpl.UnknownFields
+	sizeCache              protoimpl.SizeCache
+}
+
+func (x *InspectResponse) Reset() {
+	*x = InspectResponse{}
+	mi := &file_intunewin_v1_packaging_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *InspectResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*InspectResponse) ProtoMessage() {}
+
+func (x *InspectResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_intunewin_v1_packaging_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use InspectResponse.ProtoReflect.Descriptor instead.
+func (*InspectResponse) Descriptor() ([]byte, []int) {
+	return file_intunewin_v1_packaging_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *InspectResponse) GetToolVersion() string {
+	if x != nil {
+		return x.ToolVersion
+	}
+	return ""
+}
+
+func (x *InspectResponse) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *InspectResponse) GetDescription() string {
+	if x != nil {
+		return x.Description
+	}
+	return ""
+}
+
+func (x *InspectResponse) GetUnencryptedContentSize() int64 {
+	if x != nil {
+		return x.UnencryptedContentSize
+	}
+	return 0
+}
+
+func (x *InspectResponse) GetFileName() string {
+	if x != nil {
+		return x.FileName
+	}
+	return ""
+}
+
+func (x *InspectResponse) GetSetupFile() string {
+	if x != nil {
+		return x.SetupFile
+	}
+	return ""
+}
+
+func (x *InspectResponse) GetEncryptionInfo() *EncryptionInfo {
+	if x != nil {
+		return x.EncryptionInfo
+	}
+	return nil
+}
+
+var File_intunewin_v1_packaging_proto protoreflect.FileDescriptor
+
+const file_intunewin_v1_packaging_proto_rawDesc = "" +
+	"\n" +
+	"\x1cintunewin/v1/packaging.proto\x12\fintunewin.v1\"\x97\x01\n" +
+	"\vPackOptions\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x1d\n" +
+	"\n" +
+	"setup_file\x18\x02 \x01(\tR\tsetupFile\x123\n" +
+	"\x06format\x18\x03 \x01(\x0e2\x1b.intunewin.v1.ArchiveFormatR\x06format\x12 \n" +
+	"\vdescription\x18\x04 \x01(\tR\vdescription\"X\n" +
+	"\vPackRequest\x123\n" +
+	"\aoptions\x18\x01 \x01(\v2\x19.intunewin.v1.PackOptionsR\aoptions\x12\x14\n" +
+	"\x05chunk\x18\x02 \x01(\fR\x05chunk\"$\n" +
+	"\fPackResponse\x12\x14\n" +
+	"\x05chunk\x18\x01 \x01(\fR\x05chunk\"%\n" +
+	"\rUnpackRequest\x12\x14\n" +
+	"\x05chunk\x18\x01 \x01(\fR\x05chunk\"&\n" +
+	"\x0eUnpackResponse\x12\x14\n" +
+	"\x05chunk\x18\x01 \x01(\fR\x05chunk\"&\n" +
+	"\x0eInspectRequest\x12\x14\n" +
+	"\x05chunk\x18\x01 \x01(\fR\x05chunk\"\x9b\x02\n" +
+	"\x0eEncryptionInfo\x12%\n" +
+	"\x0eencryption_key\x18\x01 \x01(\tR\rencryptionKey\x12\x17\n" +
+	"\amac_key\x18\x02 \x01(\tR\x06macKey\x123\n" +
+	"\x15initialization_vector\x18\x03 \x01(\tR\x14initializationVector\x12\x10\n" +
+	"\x03mac\x18\x04 \x01(\tR\x03mac\x12-\n" +
+	"\x12profile_identifier\x18\x05 \x01(\tR\x11profileIdentifier\x12\x1f\n" +
+	"\vfile_digest\x18\x06 \x01(\tR\n" +
+	"fileDigest\x122\n" +
+	"\x15file_digest_algorithm\x18\a \x01(\tR\x13fileDigestAlgorithm\"\xa7\x02\n" +
+	"\x0fInspectResponse\x12!\n" +
+	"\ftool_version\x18\x01 \x01(\tR\vtoolVersion\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12 \n" +
+	"\vdescription\x18\x03 \x01(\tR\vdescription\x128\n" +
+	"\x18unencrypted_content_size\x18\x04 \x01(\x03R\x16unencryptedContentSize\x12\x1b\n" +
+	"\tfile_name\x18\x05 \x01(\tR\bfileName\x12\x1d\n" +
+	"\n" +
+	"setup_file\x18\x06 \x01(\tR\tsetupFile\x12E\n" +
+	"\x0fencryption_info\x18\a \x01(\v2\x1c.intunewin.v1.EncryptionInfoR\x0eencryptionInfo*_\n" +
+	"\rArchiveFormat\x12\x1e\n" +
+	"\x1aARCHIVE_FORMAT_UNSPECIFIED\x10\x00\x12\x16\n" +
+	"\x12ARCHIVE_FORMAT_ZIP\x10\x01\x12\x16\n" +
+	"\x12ARCHIVE_FORMAT_TAR\x10\x022\xe8\x01\n" +
+	"\x10PackagingService\x12A\n" +
+	"\x04Pack\x12\x19.intunewin.v1.PackRequest\x1a\x1a.intunewin.v1.PackResponse(\x010\x01\x12G\n" +
+	"\x06Unpack\x12\x1b.intunewin.v1.UnpackRequest\x1a\x1c.intunewin.v1.UnpackResponse(\x010\x01\x12H\n" +
+	"\aInspect\x12\x1c.intunewin.v1.InspectRequest\x1a\x1d.intunewin.v1.InspectResponse(\x01BCZAgithub.com/kenchan0130/intunewin/api/gen/intunewin/v1;intunewinv1b\x06proto3"
+
+var (
+	file_intunewin_v1_packaging_proto_rawDescOnce sync.Once
+	file_intunewin_v1_packaging_proto_rawDescData []byte
+)
+
+func file_intunewin_v1_packaging_proto_rawDescGZIP() []byte {
+	file_intunewin_v1_packaging_proto_rawDescOnce.Do(func() {
+		file_intunewin_v1_packaging_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_intunewin_v1_packaging_proto_rawDesc), len(file_intunewin_v1_packaging_proto_rawDesc)))
+	})
+	return file_intunewin_v1_packaging_proto_rawDescData
+}
+
+var file_intunewin_v1_packaging_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_intunewin_v1_packaging_proto_msgTypes = make([]protoimpl.MessageInfo, 8)
+var file_intunewin_v1_packaging_proto_goTypes = []any{
+	(ArchiveFormat)(0),      // 0: intunewin.v1.ArchiveFormat
+	(*PackOptions)(nil),     // 1: intunewin.v1.PackOptions
+	(*PackRequest)(nil),     // 2: intunewin.v1.PackRequest
+	(*PackResponse)(nil),    // 3: intunewin.v1.PackResponse
+	(*UnpackRequest)(nil),   // 4: intunewin.v1.UnpackRequest
+	(*UnpackResponse)(nil),  // 5: intunewin.v1.UnpackResponse
+	(*InspectRequest)(nil),  // 6: intunewin.v1.InspectRequest
+	(*EncryptionInfo)(nil),  // 7: intunewin.v1.EncryptionInfo
+	(*InspectResponse)(nil), // 8: intunewin.v1.InspectResponse
+}
+var file_intunewin_v1_packaging_proto_depIdxs = []int32{
+	0, // 0: intunewin.v1.PackOptions.format:type_name -> intunewin.v1.ArchiveFormat
+	1, // 1: intunewin.v1.PackRequest.options:type_name -> intunewin.v1.PackOptions
+	7, // 2: intunewin.v1.InspectResponse.encryption_info:type_name -> intunewin.v1.EncryptionInfo
+	2, // 3: intunewin.v1.PackagingService.Pack:input_type -> intunewin.v1.PackRequest
+	4, // 4: intunewin.v1.PackagingService.Unpack:input_type -> intunewin.v1.UnpackRequest
+	6, // 5: intunewin.v1.PackagingService.Inspect:input_type -> intunewin.v1.InspectRequest
+	3, // 6: intunewin.v1.PackagingService.Pack:output_type -> intunewin.v1.PackResponse
+	5, // 7: intunewin.v1.PackagingService.Unpack:output_type -> intunewin.v1.UnpackResponse
+	8, // 8: intunewin.v1.PackagingService.Inspect:output_type -> intunewin.v1.InspectResponse
+	6, // [6:9] is the sub-list for method output_type
+	3, // [3:6] is the sub-list for method input_type
+	3, // [3:3] is the sub-list for extension type_name
+	3, // [3:3] is the sub-list for extension extendee
+	0, // [0:3] is the sub-list for field type_name
+}
+
+func init() { file_intunewin_v1_packaging_proto_init() }
+func file_intunewin_v1_packaging_proto_init() {
+	if File_intunewin_v1_packaging_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_intunewin_v1_packaging_proto_rawDesc), len(file_intunewin_v1_packaging_proto_rawDesc)),
+			NumEnums:      1,
+			NumMessages:   8,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_intunewin_v1_packaging_proto_goTypes,
+		DependencyIndexes: file_intunewin_v1_packaging_proto_depIdxs,
+		EnumInfos:         file_intunewin_v1_packaging_proto_enumTypes,
+		MessageInfos:      file_intunewin_v1_packaging_proto_msgTypes,
+	}.Build()
+	File_intunewin_v1_packaging_proto = out.File
+	file_intunewin_v1_packaging_proto_goTypes = nil
+	file_intunewin_v1_packaging_proto_depIdxs = nil
+}
@@ -0,0 +1,192 @@
+// Packaging operations of intunewin for callers that integrate over gRPC.
+//
+// Packages and archives are streamed in chunks: the first message of a request
+// stream carries the options, and every message carries the next chunk of data.
+// The operations match the HTTP endpoints of `intunewin serve`.
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: intunewin/v1/packaging.proto
+
+package intunewinv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	PackagingService_Pack_FullMethodName    = "/intunewin.v1.PackagingService/Pack"
+	PackagingService_Unpack_FullMethodName  = "/intunewin.v1.PackagingService/Unpack"
+	PackagingService_Inspect_FullMethodName = "/intunewin.v1.PackagingService/Inspect"
+)
+
+// PackagingServiceClient is the client API for PackagingService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type PackagingServiceClient interface {
+	// Pack packs a zip or tar archive of the source folder into an intunewin package.
+	Pack(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[PackRequest, PackResponse], error)
+	// Unpack decrypts an intunewin package into a zip of its content.
+	Unpack(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[UnpackRequest, UnpackResponse], error)
+	// Inspect returns the Detection.xml metadata of an intunewin package without
+	// decrypting it.
+	Inspect(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[InspectRequest, InspectResponse], error)
+}
+
+type packagingServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewPackagingServiceClient(cc grpc.ClientConnInterface) PackagingServiceClient {
+	return &packagingServiceClient{cc}
+}
+
+func (c *packagingServiceClient) Pack(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[PackRequest, PackResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &PackagingService_ServiceDesc.Streams[0], PackagingService_Pack_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[PackRequest, PackResponse]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type PackagingService_PackClient = grpc.BidiStreamingClient[PackRequest, PackResponse]
+
+func (c *packagingServiceClient) Unpack(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[UnpackRequest, UnpackResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &PackagingService_ServiceDesc.Streams[1], PackagingService_Unpack_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[UnpackRequest, UnpackResponse]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type PackagingService_UnpackClient = grpc.BidiStreamingClient[UnpackRequest, UnpackResponse]
+
+func (c *packagingServiceClient) Inspect(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[InspectRequest, InspectResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &PackagingService_ServiceDesc.Streams[2], PackagingService_Inspect_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[InspectRequest, InspectResponse]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type PackagingService_InspectClient = grpc.ClientStreamingClient[InspectRequest, InspectResponse]
+
+// PackagingServiceServer is the server API for PackagingService service.
+// All implementations must embed UnimplementedPackagingServiceServer
+// for forward compatibility.
+type PackagingServiceServer interface {
+	// Pack packs a zip or tar archive of the source folder into an intunewin package.
+	Pack(grpc.BidiStreamingServer[PackRequest, PackResponse]) error
+	// Unpack decrypts an intunewin package into a zip of its content.
+	Unpack(grpc.BidiStreamingServer[UnpackRequest, UnpackResponse]) error
+	// Inspect returns the Detection.xml metadata of an intunewin package without
+	// decrypting it.
+	Inspect(grpc.ClientStreamingServer[InspectRequest, InspectResponse]) error
+	mustEmbedUnimplementedPackagingServiceServer()
+}
+
+// UnimplementedPackagingServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedPackagingServiceServer struct{}
+
+func (UnimplementedPackagingServiceServer) Pack(grpc.BidiStreamingServer[PackRequest, PackResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method Pack not implemented")
+}
+func (UnimplementedPackagingServiceServer) Unpack(grpc.BidiStreamingServer[UnpackRequest, UnpackResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method Unpack not implemented")
+}
+func (UnimplementedPackagingServiceServer) Inspect(grpc.ClientStreamingServer[InspectRequest, InspectResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method Inspect not implemented")
+}
+func (UnimplementedPackagingServiceServer) mustEmbedUnimplementedPackagingServiceServer() {}
+func (UnimplementedPackagingServiceServer) testEmbeddedByValue()                          {}
+
+// UnsafePackagingServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to PackagingServiceServer will
+// result in compilation errors.
+type UnsafePackagingServiceServer interface {
+	mustEmbedUnimplementedPackagingServiceServer()
+}
+
+func RegisterPackagingServiceServer(s grpc.ServiceRegistrar, srv PackagingServiceServer) {
+	// If the following call pancis, it indicates UnimplementedPackagingServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&PackagingService_ServiceDesc, srv)
+}
+
+func _PackagingService_Pack_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(PackagingServiceServer).Pack(&grpc.GenericServerStream[PackRequest, PackResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type PackagingService_PackServer = grpc.BidiStreamingServer[PackRequest, PackResponse]
+
+func _PackagingService_Unpack_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(PackagingServiceServer).Unpack(&grpc.GenericServerStream[UnpackRequest, UnpackResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type PackagingService_UnpackServer = grpc.BidiStreamingServer[UnpackRequest, UnpackResponse]
+
+func _PackagingService_Inspect_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(PackagingServiceServer).Inspect(&grpc.GenericServerStream[InspectRequest, InspectResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type PackagingService_InspectServer = grpc.ClientStreamingServer[InspectRequest, InspectResponse]
+
+// PackagingService_ServiceDesc is the grpc.ServiceDesc for PackagingService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var PackagingService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "intunewin.v1.PackagingService",
+	HandlerType: (*PackagingServiceServer)(nil),
+	Methods:     []grpc.MethodDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Pack",
+			Handler:       _PackagingService_Pack_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "Unpack",
+			Handler:       _PackagingService_Unpack_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "Inspect",
+			Handler:       _PackagingService_Inspect_Handler,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "intunewin/v1/packaging.proto",
+}
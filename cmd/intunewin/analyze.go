@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/kenchan0130/intunewin/internal/analyze"
+	"github.com/kenchan0130/intunewin/internal/dirs"
+	"github.com/kenchan0130/intunewin/internal/progress"
+	"github.com/kenchan0130/intunewin/internal/render"
+	"github.com/kenchan0130/intunewin/internal/unpack"
+	"github.com/spf13/cobra"
+)
+
+var analyzeCmd = &cobra.Command{
+	Use:   "analyze <file.intunewin|source-folder>",
+	Short: "Report where the size of a package goes",
+	Long: `Analyze reports the largest files, the compression ratio of every file
+extension and the space wasted by identical copies of a file, to help trim a
+package before uploading it.
+
+The input is an intunewin file, which is decrypted to a temporary folder, or a
+source folder. Compressed sizes are measured by deflating each file at the
+default level, as pack does.
+
+Example:
+  intunewin analyze ./myapp
+  intunewin analyze myapp.intunewin --top 20
+  intunewin analyze myapp.intunewin --output json`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeArgsUpTo(completePackage),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, err := outputFormat(cmd)
+		if err != nil {
+			return err
+		}
+		top, _ := cmd.Flags().GetInt("top")
+		if top < 0 {
+			return &usageError{err: fmt.Errorf("--top must not be negative, got %d", top)}
+		}
+
+		files, err := analyzeInput(cmd, args[0])
+		if err != nil {
+			return fmt.Errorf("failed to analyze %s: %w", args[0], err)
+		}
+		return writeOutput(format, analyzeResult{analyze.Analyze(files, top)})
+	},
+}
+
+// analyzeInput scans the files of a source folder, or of an intunewin file unpacked to a
+// temporary folder
+func analyzeInput(cmd *cobra.Command, input string) ([]analyze.File, error) {
+	info, err := os.Stat(input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to access %s: %w", input, err)
+	}
+	if info.IsDir() {
+		return analyze.Scan(input) //nolint:wrapcheck // wrapped by the caller
+	}
+
+	layout, err := dirs.Resolve()
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve directories: %w", err)
+	}
+	ws, err := layout.NewWorkspace("analyze-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create workspace: %w", err)
+	}
+	defer ws.Remove()
+	workspace := ws.Dir
+
+	if err := unpack.Unpack(input, workspace, unpackOptions(cmd)...); err != nil {
+		return nil, fmt.Errorf("failed to unpack: %w", err)
+	}
+	return analyze.Scan(workspace) //nolint:wrapcheck // wrapped by the caller
+}
+
+// analyzeResult is the output of the analyze command
+type analyzeResult struct {
+	*analyze.Report
+}
+
+// Table lists the totals, the largest files, the extensions and the duplicates, one per row
+func (r analyzeResult) Table() render.Table {
+	rows := [][]string{{"total", fmt.Sprintf("%d files", r.Files), progress.FormatBytes(r.Size), compressedDetail(r.CompressedSize, r.Ratio)}}
+	for _, file := range r.Largest {
+		rows = append(rows, []string{"largest", file.Path, progress.FormatBytes(file.Size), compressedDetail(file.CompressedSize, analyze.Ratio(file.CompressedSize, file.Size))})
+	}
+	for _, e := range r.Extensions {
+		name := e.Extension
+		if name == "" {
+			name = "(none)"
+		}
+		rows = append(rows, []string{"extension", name, progress.FormatBytes(e.Size), strconv.Itoa(e.Files) + " files, " + compressedDetail(e.CompressedSize, e.Ratio)})
+	}
+	for _, d := range r.Duplicates {
+		rows = append(rows, []string{"duplicate", strings.Join(d.Paths, ", "), progress.FormatBytes(d.Wasted), fmt.Sprintf("%d copies of %s", len(d.Paths), progress.FormatBytes(d.Size))})
+	}
+	if r.Wasted > 0 {
+		rows = append(rows, []string{"wasted", fmt.Sprintf("%d duplicates", len(r.Duplicates)), progress.FormatBytes(r.Wasted), ""})
+	}
+	return render.Table{Columns: []string{"Kind", "Name", "Size", "Detail"}, Rows: rows}
+}
+
+// compressedDetail describes a compressed size and its ratio to the original size
+func compressedDetail(compressed int64, ratio float64) string {
+	return fmt.Sprintf("%s compressed (%.0f%%)", progress.FormatBytes(compressed), ratio*100)
+}
+
+func init() {
+	analyzeCmd.Flags().Int("top", 10, "number of largest files to list")
+	analyzeCmd.Flags().Bool("strict", false, "fail on unrecognized Detection.xml versions and nonstandard packages instead of warning")
+	addOutputFlag(analyzeCmd)
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/kenchan0130/intunewin/internal/manifest"
+	"github.com/kenchan0130/intunewin/internal/msi"
+	"github.com/kenchan0130/intunewin/internal/pe"
+	"github.com/kenchan0130/intunewin/internal/unpack"
+	"github.com/spf13/cobra"
+)
+
+var appJSONCmd = &cobra.Command{
+	Use:   "appjson <file.intunewin>",
+	Short: "Generate the Graph request body of a Win32 app",
+	Long: `Appjson writes a #microsoft.graph.win32LobApp request body for an intunewin
+file, ready to POST to deviceAppManagement/mobileApps or to use in
+infrastructure-as-code tooling.
+
+The body is filled from the package metadata and its setup file:
+  - displayName and description from Detection.xml
+  - publisher from the MSI Manufacturer or the EXE company name
+  - install and uninstall command templates for the setup file type; MSIs are
+    uninstalled by product code, other setups get a "/uninstall" placeholder
+    to replace with --uninstall-command
+  - detection rules as "rules generate" derives them
+  - the default Intune return codes, customized with --return-code
+
+Example:
+  intunewin appjson myapp.intunewin -o app.json
+  intunewin appjson myapp.intunewin --publisher Contoso --uninstall-command '"setup.exe" /S /uninstall'`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeArgsUpTo(completePackage),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		inputFile := args[0]
+		outputFile, _ := cmd.Flags().GetString("output")
+
+		app, err := win32AppFromFlags(cmd, inputFile)
+		if err != nil {
+			return err
+		}
+		if app.Publisher == "" {
+			logger.Warn("the publisher is required by Intune, set it with --publisher")
+		}
+
+		data, err := app.ToJSON()
+		if err != nil {
+			return err //nolint:wrapcheck // manifest errors already describe the failure
+		}
+		data = append(data, '\n')
+
+		if outputFile == "" {
+			_, err = os.Stdout.Write(data)
+			return err
+		}
+		if err := os.WriteFile(outputFile, data, 0600); err != nil {
+			return fmt.Errorf("failed to write app body: %w", err)
+		}
+		return nil
+	},
+}
+
+// win32AppFromFlags builds the app body of the intunewin file inputFile from its metadata,
+// its setup file and the flags of addWin32AppFlags
+func win32AppFromFlags(cmd *cobra.Command, inputFile string) (*manifest.Win32LobApp, error) {
+	returnCodes, err := returnCodesFromFlags(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	appInfo, err := unpack.ReadApplicationInfo(inputFile, unpackOptions(cmd)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read metadata: %w", err)
+	}
+	rules, setup, err := detectionRulesFromFlags(cmd, inputFile)
+	if err != nil {
+		return nil, err
+	}
+
+	app := manifest.NewWin32LobApp(appInfo, rules, returnCodes)
+	if properties := setup.msiProperties; properties != nil {
+		app.Publisher = properties[msi.PropertyManufacturer]
+		app.SetMsiInformation(
+			properties[msi.PropertyProductCode],
+			properties[msi.PropertyProductVersion],
+			properties[msi.PropertyUpgradeCode],
+			properties[msi.PropertyProductName],
+			properties[msi.PropertyManufacturer],
+		)
+	} else if setup.version != nil {
+		app.Publisher = setup.version.Strings[pe.KeyCompanyName]
+	}
+
+	if cmd.Flags().Changed("display-name") {
+		app.DisplayName, _ = cmd.Flags().GetString("display-name")
+	}
+	if cmd.Flags().Changed("publisher") {
+		app.Publisher, _ = cmd.Flags().GetString("publisher")
+	}
+	if cmd.Flags().Changed("install-command") {
+		app.InstallCommandLine, _ = cmd.Flags().GetString("install-command")
+	}
+	if cmd.Flags().Changed("uninstall-command") {
+		app.UninstallCommandLine, _ = cmd.Flags().GetString("uninstall-command")
+	}
+	app.MinimumSupportedWindowsRelease, _ = cmd.Flags().GetString("minimum-os")
+	app.ApplicableArchitectures, _ = cmd.Flags().GetString("architectures")
+	return app, nil
+}
+
+// addWin32AppFlags adds the flags read by win32AppFromFlags
+func addWin32AppFlags(cmd *cobra.Command) {
+	cmd.Flags().String("display-name", "", "display name (default is the package name)")
+	cmd.Flags().String("publisher", "", "publisher (default is read from the setup file)")
+	cmd.Flags().String("install-command", "", "install command line (default depends on the setup file type)")
+	cmd.Flags().String("uninstall-command", "", "uninstall command line (default depends on the setup file type)")
+	cmd.Flags().String("minimum-os", manifest.DefaultMinimumWindowsRelease, "minimum supported Windows 10 release")
+	cmd.Flags().String("architectures", manifest.DefaultApplicableArchitectures, "comma-separated applicable architectures (x86, x64, arm64)")
+	cmd.Flags().StringArray("return-code", nil, "custom installer return code as <code>=<type> (repeatable)")
+	cmd.Flags().String("return-codes", "", "YAML or JSON file with a returnCodes list")
+	addDetectionRuleFlags(cmd)
+}
+
+func init() {
+	appJSONCmd.Flags().StringP("output", "o", "", "write the app body to this file instead of stdout")
+	addWin32AppFlags(appJSONCmd)
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var assignCmd = &cobra.Command{
+	Use:   "assign <app-id>",
+	Short: "Assign an Intune app to groups",
+	Long: `Assign creates assignments of an existing Intune app, such as one created by
+publish. Each --assign is <intent>:<target>[:<include|exclude>:<filter-id>]
+where the intent is required, available or uninstall and the target is the
+object ID of an Entra group, all-users or all-devices. The filter is the ID of
+an assignment filter that includes or excludes the matching devices.
+
+The IDs of the created assignments are written to stdout as JSON.
+
+Example:
+  intunewin assign <app-id> --assign required:<group-id>
+  intunewin assign <app-id> --assign available:all-users:exclude:<filter-id> --assign uninstall:<group-id>`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: cobra.NoFileCompletions,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		appID := args[0]
+
+		assignments, err := assignmentsFromFlags(cmd)
+		if err != nil {
+			return err
+		}
+		if len(assignments) == 0 {
+			return fmt.Errorf("no assignments, add at least one --assign")
+		}
+		client, err := graphClient(cmd)
+		if err != nil {
+			return err
+		}
+
+		ids := make([]string, 0, len(assignments))
+		for _, a := range assignments {
+			id, err := client.CreateAssignment(cmd.Context(), appID, a)
+			if err != nil {
+				return err //nolint:wrapcheck // graph errors already describe the failure
+			}
+			logger.Info("assigned app", "app", appID, "intent", a.Intent, "target", a.Target)
+			ids = append(ids, id)
+		}
+		return writeJSONResult(map[string][]string{"assignmentIds": ids})
+	},
+}
+
+func init() {
+	assignCmd.Flags().StringArray("assign", nil, "assignment as <intent>:<group-id|all-users|all-devices>[:<include|exclude>:<filter-id>] (repeatable)")
+	addGraphFlags(assignCmd)
+}
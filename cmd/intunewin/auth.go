@@ -0,0 +1,253 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/kenchan0130/intunewin/internal/auth"
+	"github.com/kenchan0130/intunewin/internal/dirs"
+	"github.com/spf13/cobra"
+)
+
+// Authentication methods of Graph commands
+const (
+	authDeviceCode        = "device-code"
+	authClientSecret      = "client-secret"
+	authClientCertificate = "client-certificate"
+	authWorkloadIdentity  = "workload-identity"
+	authAzureCLI          = "azure-cli"
+)
+
+// Environment variables read for app sign-in, as used by the Azure SDKs
+const (
+	envTenantID          = "AZURE_TENANT_ID"
+	envClientID          = "AZURE_CLIENT_ID"
+	envClientSecret      = "AZURE_CLIENT_SECRET"
+	envClientCertificate = "AZURE_CLIENT_CERTIFICATE_PATH"
+	envFederatedToken    = "AZURE_FEDERATED_TOKEN_FILE"
+	envAuthorityHost     = "AZURE_AUTHORITY_HOST"
+	// envGitHubTokenURL and envGitHubToken are set in GitHub Actions jobs with the
+	// id-token: write permission
+	envGitHubTokenURL = "ACTIONS_ID_TOKEN_REQUEST_URL"
+	envGitHubToken    = "ACTIONS_ID_TOKEN_REQUEST_TOKEN"
+)
+
+var authCmd = &cobra.Command{
+	Use:   "auth",
+	Short: "Manage the Microsoft Graph sign-in",
+}
+
+var authLoginCmd = &cobra.Command{
+	Use:   "login",
+	Short: "Sign in to Microsoft Graph",
+	Long: `Login signs in to Microsoft Graph and caches the tokens for later commands.
+
+With --auth device-code (the default) a code is printed that you enter at
+https://microsoft.com/devicelogin on any device. The tokens are cached in the
+per-user state directory, readable only by the current user, and refreshed
+silently until the refresh token expires.
+
+For CI pipelines, --auth client-secret and --auth client-certificate sign in
+as an app registration with application permissions. The tenant, client,
+secret and PEM certificate default to the AZURE_TENANT_ID, AZURE_CLIENT_ID,
+AZURE_CLIENT_SECRET and AZURE_CLIENT_CERTIFICATE_PATH environment variables;
+the secret can also be read from --client-secret-file. App tokens are not
+cached, so login only checks the credentials.
+
+Pipelines without long-lived secrets use --auth workload-identity, which
+exchanges the federated token of Azure Workload Identity
+(AZURE_FEDERATED_TOKEN_FILE, or --federated-token-file) or the OIDC token of
+a GitHub Actions job with the id-token: write permission, or --auth azure-cli,
+which uses the account signed in with az login.
+
+Example:
+  intunewin auth login --tenant-id contoso.onmicrosoft.com
+  AZURE_CLIENT_SECRET=... intunewin auth login --auth client-secret --tenant-id <tenant> --client-id <app-id>
+  intunewin auth login --auth azure-cli`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		source, err := tokenSource(cmd)
+		if err != nil {
+			return err
+		}
+		if _, err := source.Token(cmd.Context()); err != nil {
+			return fmt.Errorf("failed to sign in: %w", err)
+		}
+		logger.Info("signed in to Microsoft Graph")
+		return nil
+	},
+}
+
+var authLogoutCmd = &cobra.Command{
+	Use:   "logout",
+	Short: "Remove cached Microsoft Graph tokens",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cache, err := tokenCache()
+		if err != nil {
+			return err
+		}
+		if err := cache.Clear(); err != nil {
+			return err //nolint:wrapcheck // auth errors already describe the failure
+		}
+		logger.Info("removed cached tokens", "path", cache.Path())
+		return nil
+	},
+}
+
+// tokenCache returns the token cache in the per-user state directory
+func tokenCache() (*auth.Cache, error) {
+	layout, err := dirs.Resolve()
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve directories: %w", err)
+	}
+	return auth.NewCache(filepath.Join(layout.State, auth.CacheFileName)), nil
+}
+
+// addAuthFlags adds the flags read by tokenSource
+func addAuthFlags(cmd *cobra.Command) {
+	cmd.Flags().String("auth", authDeviceCode, "authentication method: device-code, client-secret, client-certificate, workload-identity or azure-cli")
+	registerFlagCompletion(cmd, "auth", authDeviceCode, authClientSecret, authClientCertificate, authWorkloadIdentity, authAzureCLI)
+	cmd.Flags().String("tenant-id", "", "Microsoft Entra tenant ID or domain (default is $"+envTenantID+", or the home tenant of the account)")
+	cmd.Flags().String("client-id", "", "application (client) ID (default is $"+envClientID+" for app sign-in, the Microsoft Graph PowerShell client otherwise)")
+	cmd.Flags().String("client-secret", "", "client secret; prefer $"+envClientSecret+" or --client-secret-file, as arguments are visible to other users")
+	cmd.Flags().String("client-secret-file", "", "file holding the client secret")
+	cmd.Flags().String("client-certificate", "", "PEM file with the private key and certificate of the app (default is $"+envClientCertificate+")")
+	cmd.Flags().String("federated-token-file", "", "file with the federated token for workload identity (default is $"+envFederatedToken+")")
+	cmd.Flags().String("authority", auth.DefaultAuthority, "identity platform endpoint, for national clouds (default is $"+envAuthorityHost+" when set)")
+	addNetworkFlags(cmd)
+}
+
+// tokenSource returns the Graph token source selected by the flags of addAuthFlags
+func tokenSource(cmd *cobra.Command) (auth.TokenSource, error) {
+	return scopedTokenSource(cmd, nil, "")
+}
+
+// keyVaultTokenSource returns a Key Vault token source signed in as the flags of addAuthFlags select
+func keyVaultTokenSource(cmd *cobra.Command) (auth.TokenSource, error) {
+	return scopedTokenSource(cmd, auth.KeyVaultScope, auth.KeyVaultDefaultScope)
+}
+
+// scopedTokenSource returns the token source selected by the flags of addAuthFlags, requesting
+// delegatedScopes when a user signs in and appScope for an app, or Graph when they are empty
+func scopedTokenSource(cmd *cobra.Command, delegatedScopes []string, appScope string) (auth.TokenSource, error) {
+	method, _ := cmd.Flags().GetString("auth")
+	tenantID := flagOrEnv(cmd, "tenant-id", envTenantID)
+	authority, _ := cmd.Flags().GetString("authority")
+	if host := os.Getenv(envAuthorityHost); host != "" && !cmd.Flags().Changed("authority") {
+		authority = host
+	}
+
+	client, err := httpClient(cmd)
+	if err != nil {
+		return nil, err
+	}
+	opts := []auth.Option{
+		auth.WithLogger(logger),
+		auth.WithAuthority(authority),
+		auth.WithHTTPClient(client),
+	}
+
+	appOpts := opts
+	if appScope != "" {
+		appOpts = append(opts[:len(opts):len(opts)], auth.WithScopes(appScope))
+	}
+
+	switch method {
+	case authDeviceCode:
+		cache, err := tokenCache()
+		if err != nil {
+			return nil, err
+		}
+		clientID, _ := cmd.Flags().GetString("client-id")
+		prompt := func(code *auth.DeviceCode) {
+			fmt.Fprintln(os.Stderr, code.Message)
+		}
+		opts = append(opts, auth.WithCache(cache), auth.WithPrompt(prompt))
+		if len(delegatedScopes) > 0 {
+			opts = append(opts, auth.WithScopes(delegatedScopes...))
+		}
+		return auth.NewDeviceCodeSource(tenantID, clientID, opts...), nil
+
+	case authClientSecret:
+		secret, err := clientSecret(cmd)
+		if err != nil {
+			return nil, err
+		}
+		return auth.NewClientSecretSource(tenantID, flagOrEnv(cmd, "client-id", envClientID), secret, appOpts...) //nolint:wrapcheck // auth errors already describe the failure
+
+	case authClientCertificate:
+		path := flagOrEnv(cmd, "client-certificate", envClientCertificate)
+		if path == "" {
+			return nil, fmt.Errorf("%w: set --client-certificate or %s", auth.ErrMissingCredential, envClientCertificate)
+		}
+		cert, err := auth.LoadCertificate(path)
+		if err != nil {
+			return nil, err //nolint:wrapcheck // auth errors already describe the failure
+		}
+		return auth.NewClientCertificateSource(tenantID, flagOrEnv(cmd, "client-id", envClientID), cert, appOpts...) //nolint:wrapcheck // auth errors already describe the failure
+
+	case authWorkloadIdentity:
+		assertion, err := federatedAssertion(cmd, client)
+		if err != nil {
+			return nil, err
+		}
+		return auth.NewFederatedSource(tenantID, flagOrEnv(cmd, "client-id", envClientID), assertion, appOpts...) //nolint:wrapcheck // auth errors already describe the failure
+
+	case authAzureCLI:
+		return auth.NewAzureCLISource(tenantID, appOpts...), nil
+
+	default:
+		return nil, fmt.Errorf("unknown authentication method %q (expected %s, %s, %s, %s or %s)",
+			method, authDeviceCode, authClientSecret, authClientCertificate, authWorkloadIdentity, authAzureCLI)
+	}
+}
+
+// federatedAssertion returns the source of federated tokens: the token file of Azure
+// Workload Identity, or the OIDC token of a GitHub Actions job
+func federatedAssertion(cmd *cobra.Command, client *http.Client) (auth.AssertionFunc, error) {
+	if path := flagOrEnv(cmd, "federated-token-file", envFederatedToken); path != "" {
+		return auth.FederatedTokenFile(path), nil
+	}
+	if requestURL, token := os.Getenv(envGitHubTokenURL), os.Getenv(envGitHubToken); requestURL != "" && token != "" {
+		return auth.GitHubActionsToken(requestURL, token, client), nil
+	}
+	return nil, fmt.Errorf("%w: set --federated-token-file or %s, or grant the GitHub Actions job the id-token: write permission",
+		auth.ErrMissingCredential, envFederatedToken)
+}
+
+// clientSecret returns the secret of --client-secret, --client-secret-file or the environment
+func clientSecret(cmd *cobra.Command) (string, error) {
+	if secret, _ := cmd.Flags().GetString("client-secret"); secret != "" {
+		return secret, nil
+	}
+	if path, _ := cmd.Flags().GetString("client-secret-file"); path != "" {
+		data, err := os.ReadFile(path) // #nosec G304 -- path is chosen by the user
+		if err != nil {
+			return "", fmt.Errorf("failed to read client secret: %w", err)
+		}
+		return strings.TrimSpace(string(data)), nil
+	}
+	if secret := os.Getenv(envClientSecret); secret != "" {
+		return secret, nil
+	}
+	return "", fmt.Errorf("%w: set %s or --client-secret-file", auth.ErrMissingCredential, envClientSecret)
+}
+
+// flagOrEnv returns the value of the flag name, or of the environment variable key when
+// the flag is empty
+func flagOrEnv(cmd *cobra.Command, name, key string) string {
+	if value, _ := cmd.Flags().GetString(name); value != "" {
+		return value
+	}
+	return os.Getenv(key)
+}
+
+func init() {
+	addAuthFlags(authLoginCmd)
+	authCmd.AddCommand(authLoginCmd)
+	authCmd.AddCommand(authLogoutCmd)
+}
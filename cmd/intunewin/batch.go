@@ -0,0 +1,140 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/kenchan0130/intunewin/internal/batch"
+	"github.com/kenchan0130/intunewin/internal/render"
+	"github.com/spf13/cobra"
+)
+
+var batchCmd = &cobra.Command{
+	Use:   "batch <apps.yaml>",
+	Short: "Pack every app listed in a manifest file",
+	Long: `Batch packs the apps listed in a YAML manifest, --jobs at a time. Each app
+has a source folder and optionally a name (default is the folder name), an
+output file (default is named by --output-template), a setup file (default is detected),
+a description and exclude patterns. Relative paths are relative to the folder
+of the manifest:
+
+  apps:
+    - source: apps/7zip
+    - name: Contoso Agent
+      source: apps/agent
+      output: dist/agent.intunewin
+      setupFile: install.cmd
+      exclude: ["*.log", docs]
+
+A failed app does not stop the others. The status of every app is reported
+when the batch is done, and the command fails when any app failed. Pack flags
+such as --reproducible and --exclude apply to every app.
+
+Example:
+  intunewin batch apps.yaml --jobs 4
+  intunewin batch apps.yaml --output json > results.json`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeArgsUpTo(completeExt("yaml", "yml")),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		manifestFile := args[0]
+
+		format, err := outputFormat(cmd)
+		if err != nil {
+			return err
+		}
+		jobs, _ := cmd.Flags().GetInt("jobs")
+		if jobs < 1 {
+			return fmt.Errorf("--jobs must be at least 1, got %d", jobs)
+		}
+		outputTemplate, _ := cmd.Flags().GetString("output-template")
+		m, err := batch.Load(manifestFile, outputTemplate)
+		if err != nil {
+			return err //nolint:wrapcheck // batch errors already describe the failure
+		}
+		opts, err := packOptions(cmd)
+		if err != nil {
+			return err
+		}
+
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		results := batch.Run(ctx, m,
+			batch.WithLogger(logger),
+			batch.WithConcurrency(jobs),
+			batch.WithPackOptions(opts...),
+			batch.WithOnResult(func(r batch.Result) {
+				if r.Err != nil {
+					logger.Error("failed to pack app", "app", r.App.Name, "status", r.Status, "error", r.Err)
+					return
+				}
+				logger.Info("packed app", "app", r.App.Name, "output", r.App.Output, "duration", r.Duration)
+			}),
+		)
+
+		if err := writeOutput(format, newBatchReport(results)); err != nil {
+			return err
+		}
+		if failed := batch.Failed(results); failed > 0 {
+			return fmt.Errorf("%d of %d apps were not packed", failed, len(results))
+		}
+		return nil
+	},
+}
+
+// batchReport is the per-app status written by batch
+type batchReport struct {
+	Apps []batchAppResult `json:"apps"`
+}
+
+// batchAppResult is the status of one app of a batch
+type batchAppResult struct {
+	Name            string  `json:"name"`
+	Source          string  `json:"source"`
+	Output          string  `json:"output"`
+	SetupFile       string  `json:"setupFile,omitempty"`
+	Status          string  `json:"status"`
+	SHA256          string  `json:"sha256,omitempty"`
+	DurationSeconds float64 `json:"durationSeconds"`
+	Error           string  `json:"error,omitempty"`
+}
+
+// newBatchReport describes the results of a batch
+func newBatchReport(results []batch.Result) batchReport {
+	report := batchReport{Apps: make([]batchAppResult, 0, len(results))}
+	for _, r := range results {
+		app := batchAppResult{
+			Name:            r.App.Name,
+			Source:          r.App.Source,
+			Output:          r.App.Output,
+			SetupFile:       r.SetupFile,
+			Status:          string(r.Status),
+			SHA256:          r.SHA256,
+			DurationSeconds: r.Duration.Seconds(),
+		}
+		if r.Err != nil {
+			app.Error = r.Err.Error()
+		}
+		report.Apps = append(report.Apps, app)
+	}
+	return report
+}
+
+// Table lists one app per row
+func (r batchReport) Table() render.Table {
+	t := render.Table{Columns: []string{"Name", "Status", "Output", "Duration", "Error"}}
+	for _, app := range r.Apps {
+		t.Rows = append(t.Rows, []string{
+			app.Name, app.Status, app.Output, fmt.Sprintf("%.3fs", app.DurationSeconds), app.Error,
+		})
+	}
+	return t
+}
+
+func init() {
+	batchCmd.Flags().Int("jobs", batch.DefaultConcurrency, "number of apps packed in parallel")
+	batchCmd.Flags().String("output-template", batch.DefaultOutputTemplate, "Go template naming the package of apps without an output, relative to the manifest (fields: .Name, .SetupFile, .Description)")
+	addOutputFlag(batchCmd)
+	addPackOptionFlags(batchCmd)
+}
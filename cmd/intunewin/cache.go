@@ -0,0 +1,42 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/kenchan0130/intunewin/internal/dirs"
+	"github.com/spf13/cobra"
+)
+
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Manage the intunewin cache directory",
+}
+
+var cacheCleanCmd = &cobra.Command{
+	Use:   "clean",
+	Short: "Remove everything from the cache directory",
+	Long: `Clean removes cached data and leftover temporary workspaces from the
+per-user cache directory. Configuration and state (such as tokens) are kept, and
+so are the workspaces of commands that are still running, such as edit.
+
+Example:
+  intunewin cache clean`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		layout, err := dirs.Resolve()
+		if err != nil {
+			return fmt.Errorf("failed to resolve directories: %w", err)
+		}
+
+		freed, err := layout.CleanCache()
+		if err != nil {
+			return fmt.Errorf("failed to clean cache: %w", err)
+		}
+		logger.Info("cleaned cache", "path", layout.Cache, "freedBytes", freed)
+		return nil
+	},
+}
+
+func init() {
+	cacheCmd.AddCommand(cacheCleanCmd)
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/kenchan0130/intunewin/internal/unpack"
+	"github.com/spf13/cobra"
+)
+
+var catCmd = &cobra.Command{
+	Use:   "cat <file.intunewin> <path-in-package>",
+	Short: "Print a packaged file to standard output",
+	Long: `Cat decrypts an intunewin file and writes the file at path-in-package to
+standard output without extracting anything else, such as to review the script
+a package will run. The path is matched case-insensitively with either kind of
+slash, as listed by the list command.
+
+Example:
+  intunewin cat myapp.intunewin Deploy-Application.ps1
+  intunewin cat myapp.intunewin Files/config.xml | less`,
+	Args:              cobra.ExactArgs(2),
+	ValidArgsFunction: completeArgsUpTo(completePackage, cobra.NoFileCompletions),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		buf, name, err := unpack.OpenFile(args[0], args[1], unpackOptions(cmd)...)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", args[1], err)
+		}
+		defer buf.Close()
+
+		logger.Debug("printing packaged file", "path", name)
+		if _, err := io.Copy(os.Stdout, buf); err != nil {
+			return fmt.Errorf("failed to write %s: %w", name, err)
+		}
+		return nil
+	},
+}
+
+func init() {
+	catCmd.Flags().Bool("strict", false, "fail on unrecognized Detection.xml versions and nonstandard packages instead of warning")
+}
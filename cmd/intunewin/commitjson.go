@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/kenchan0130/intunewin/internal/metadata"
+	"github.com/kenchan0130/intunewin/internal/unpack"
+	"github.com/spf13/cobra"
+)
+
+var commitJSONCmd = &cobra.Command{
+	Use:   "commitjson <file.intunewin>",
+	Short: "Generate the Graph commit request body of a package",
+	Long: `Commitjson writes the body of the Graph request that commits the content file
+of a Win32 app (.../contentVersions/{id}/files/{id}/commit) once the encrypted
+content has been uploaded.
+
+The fileEncryptionInfo values (encryptionKey, macKey, initializationVector,
+mac, profileIdentifier, fileDigest and fileDigestAlgorithm) are copied from
+Detection.xml, base64 encoded as Intune expects them, so that upload scripts
+in PowerShell, Terraform or other tooling can use intunewin as the packer.
+pack --commit-json writes the same body while packing.
+
+Example:
+  intunewin commitjson myapp.intunewin -o commit.json`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeArgsUpTo(completePackage),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		inputFile := args[0]
+		outputFile, _ := cmd.Flags().GetString("output")
+
+		appInfo, err := unpack.ReadApplicationInfo(inputFile, unpackOptions(cmd)...)
+		if err != nil {
+			return fmt.Errorf("failed to read metadata: %w", err)
+		}
+		if outputFile == "" {
+			outputFile = stdioArg
+		}
+		return writeCommitRequest(outputFile, appInfo)
+	},
+}
+
+// writeCommitRequest writes the commit request body of appInfo as JSON to target, a file
+// path or stdioArg
+func writeCommitRequest(target string, appInfo *metadata.ApplicationInfo) error {
+	req, err := metadata.NewCommitRequest(appInfo)
+	if err != nil {
+		return err //nolint:wrapcheck // metadata errors already describe the failure
+	}
+	data, err := req.ToJSON()
+	if err != nil {
+		return err //nolint:wrapcheck // metadata errors already describe the failure
+	}
+	data = append(data, '\n')
+
+	if target == stdioArg {
+		_, err = os.Stdout.Write(data)
+		return err //nolint:wrapcheck // stdout write errors need no context
+	}
+	if err := os.WriteFile(target, data, 0600); err != nil {
+		return fmt.Errorf("failed to write commit request: %w", err)
+	}
+	return nil
+}
+
+func init() {
+	commitJSONCmd.Flags().StringP("output", "o", "", "write the commit request body to this file instead of stdout")
+}
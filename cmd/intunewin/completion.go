@@ -0,0 +1,69 @@
+package main
+
+import (
+	"github.com/kenchan0130/intunewin/internal/i18n"
+	"github.com/kenchan0130/intunewin/internal/render"
+	"github.com/spf13/cobra"
+)
+
+// Shell completions of arguments. Cobra provides the completion command itself.
+var (
+	// completePackage completes .intunewin files
+	completePackage = completeExt("intunewin")
+	// completeDir completes folders
+	completeDir cobra.CompletionFunc = func(*cobra.Command, []string, string) ([]cobra.Completion, cobra.ShellCompDirective) {
+		return nil, cobra.ShellCompDirectiveFilterDirs
+	}
+)
+
+// completeExt completes files with one of exts, given without the dot, and folders
+func completeExt(exts ...string) cobra.CompletionFunc {
+	return func(*cobra.Command, []string, string) ([]cobra.Completion, cobra.ShellCompDirective) {
+		return exts, cobra.ShellCompDirectiveFilterFileExt
+	}
+}
+
+// completeArgs completes the argument at position i with completions[i], and the
+// arguments after the last one like the last one
+func completeArgs(completions ...cobra.CompletionFunc) cobra.CompletionFunc {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+		if len(completions) == 0 {
+			return cobra.NoFileCompletions(cmd, args, toComplete)
+		}
+		return completions[min(len(args), len(completions)-1)](cmd, args, toComplete)
+	}
+}
+
+// completeArgsUpTo is completeArgs for commands that take at most len(completions)
+// arguments, so that nothing is offered after the last one
+func completeArgsUpTo(completions ...cobra.CompletionFunc) cobra.CompletionFunc {
+	complete := completeArgs(completions...)
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+		if len(args) >= len(completions) {
+			return cobra.NoFileCompletions(cmd, args, toComplete)
+		}
+		return complete(cmd, args, toComplete)
+	}
+}
+
+// registerFlagCompletion completes the values of the flag name of cmd with values
+func registerFlagCompletion(cmd *cobra.Command, name string, values ...string) {
+	_ = cmd.RegisterFlagCompletionFunc(name, cobra.FixedCompletions(values, cobra.ShellCompDirectiveNoFileComp))
+}
+
+// outputFormatNames returns the names of the formats of --output
+func outputFormatNames() []string {
+	names := make([]string, 0, len(render.Formats))
+	for _, f := range render.Formats {
+		names = append(names, string(f))
+	}
+	return names
+}
+
+func init() {
+	registerFlagCompletion(rootCmd, "log-level", "debug", "info", "warn", "error")
+	registerFlagCompletion(rootCmd, "log-format", "text", "json")
+	registerFlagCompletion(rootCmd, "lang", i18n.Languages()...)
+	_ = rootCmd.RegisterFlagCompletionFunc("config", completeExt("yaml", "yml"))
+	_ = rootCmd.RegisterFlagCompletionFunc("tmpdir", completeDir)
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"strings"
+
+	"github.com/kenchan0130/intunewin/internal/config"
+	"github.com/kenchan0130/intunewin/internal/dirs"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// envConfig selects the configuration file when --config is not given
+const envConfig = config.EnvPrefix + "CONFIG"
+
+// mutuallyExclusiveAnnotation is the flag annotation of cobra's MarkFlagsMutuallyExclusive
+const mutuallyExclusiveAnnotation = "cobra_annotation_mutually_exclusive"
+
+// applyConfig sets the flags of cmd not given on the command line from INTUNEWIN_*
+// environment variables and the configuration file. A missing default configuration
+// file is not an error.
+func applyConfig(cmd *cobra.Command) error {
+	path, _ := cmd.Flags().GetString("config")
+	if path == "" {
+		path = os.Getenv(envConfig)
+	}
+	explicit := path != ""
+	if !explicit {
+		layout, err := dirs.Resolve()
+		if err != nil {
+			return err //nolint:wrapcheck // dirs errors already describe the failure
+		}
+		path = config.Path(layout.Config)
+	}
+
+	c, err := config.Load(path)
+	if errors.Is(err, fs.ErrNotExist) && !explicit {
+		c, err = &config.Config{}, nil
+	}
+	if err != nil {
+		return err //nolint:wrapcheck // config errors already describe the failure
+	}
+
+	commandPath := strings.TrimPrefix(cmd.CommandPath(), cmd.Root().Name()+" ")
+	return c.Apply(cmd.Flags(), commandPath, os.Getenv, func(f *pflag.Flag) bool { //nolint:wrapcheck // config errors already describe the failure
+		switch f.Name {
+		case "config", "help", "version":
+			return true
+		}
+		return conflictsWithChanged(cmd.Flags(), f)
+	})
+}
+
+// conflictsWithChanged reports whether f is mutually exclusive with a flag given on the
+// command line, so that defaults never conflict with explicit flags
+func conflictsWithChanged(flags *pflag.FlagSet, f *pflag.Flag) bool {
+	for _, group := range f.Annotations[mutuallyExclusiveAnnotation] {
+		for _, name := range strings.Split(group, " ") {
+			if other := flags.Lookup(name); other != nil && other != f && other.Changed {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/kenchan0130/intunewin/internal/crypto"
+	"github.com/kenchan0130/intunewin/internal/metadata"
+	"github.com/kenchan0130/intunewin/internal/unpack"
+	"github.com/spf13/cobra"
+)
+
+var decryptCmd = &cobra.Command{
+	Use:   "decrypt <IntunePackage.intunewin> <output-folder>",
+	Short: "Decrypt a raw encrypted contents file with supplied keys",
+	Long: `Decrypt extracts a raw encrypted contents file, the IntunePackage.intunewin
+entry of a package as it is uploaded to Intune, to the output folder.
+
+Without the outer package there is no Detection.xml to take the keys from, so
+they are supplied with --key and --mac-key (base64, as in the fileEncryptionInfo
+of the Graph content file), or read from a saved Detection.xml with
+--detection-xml. With --key-vault they are read from the Azure Key Vault
+secret that pack --key-vault stored for the package whose SHA256 is given by
+--package-sha256, signing in as for "auth login", so they are never written to
+disk. The HMAC is verified before decrypting.
+
+Example:
+  intunewin decrypt IntunePackage.intunewin ./extracted --key <base64> --mac-key <base64>
+  intunewin decrypt IntunePackage.intunewin ./extracted --detection-xml Detection.xml
+  intunewin decrypt IntunePackage.intunewin ./extracted --key-vault https://myvault.vault.azure.net --package-sha256 <sha256>`,
+	Args:              cobra.ExactArgs(2),
+	ValidArgsFunction: completeArgsUpTo(completePackage, completeDir),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		inputFile := args[0]
+		outputFolder := args[1]
+
+		encryptionKey, macKey, err := decryptionKeys(cmd)
+		if err != nil {
+			return err
+		}
+		defer crypto.Zero(encryptionKey, macKey)
+
+		logger.Info("decrypting", "input", inputFile, "output", outputFolder)
+		if err := unpack.UnpackContents(inputFile, outputFolder, encryptionKey, macKey, unpackOptions(cmd)...); err != nil {
+			return fmt.Errorf("failed to decrypt: %w", err)
+		}
+		logger.Info("successfully extracted contents", "output", outputFolder)
+		return nil
+	},
+}
+
+// decryptionKeys returns the encryption and MAC keys given by --key and --mac-key, read
+// from the file given by --detection-xml, or loaded from --key-vault
+func decryptionKeys(cmd *cobra.Command) ([]byte, []byte, error) {
+	vault, err := keyVaultClient(cmd)
+	if err != nil {
+		return nil, nil, err
+	}
+	if vault != nil {
+		digest, _ := cmd.Flags().GetString("package-sha256")
+		info, err := loadKeys(cmd.Context(), vault, digest)
+		if err != nil {
+			return nil, nil, err
+		}
+		encInfo, err := info.ToEncryptionInfo()
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: failed to parse encryption info from key vault: %w", unpack.ErrInvalidMetadata, err)
+		}
+		return encInfo.EncryptionKey, encInfo.MacKey, nil
+	}
+
+	detectionXML, _ := cmd.Flags().GetString("detection-xml")
+	if detectionXML == "" {
+		if !cmd.Flags().Changed("key") || !cmd.Flags().Changed("mac-key") {
+			return nil, nil, fmt.Errorf("%w: --key and --mac-key, or --detection-xml, are required", crypto.ErrInvalidKeys)
+		}
+		encryptionKey, _ := cmd.Flags().GetBytesBase64("key")
+		macKey, _ := cmd.Flags().GetBytesBase64("mac-key")
+		return encryptionKey, macKey, nil
+	}
+
+	data, err := os.ReadFile(detectionXML) // #nosec G304 -- path is chosen by the user
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read Detection.xml: %w", err)
+	}
+	appInfo, err := metadata.FromXMLBytes(data)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %w", unpack.ErrInvalidMetadata, err)
+	}
+	if appInfo.EncryptionInfo == nil {
+		return nil, nil, fmt.Errorf("%w: encryption info is missing", unpack.ErrInvalidMetadata)
+	}
+	encInfo, err := appInfo.EncryptionInfo.ToEncryptionInfo()
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: failed to parse encryption info: %w", unpack.ErrInvalidMetadata, err)
+	}
+	return encInfo.EncryptionKey, encInfo.MacKey, nil
+}
+
+func init() {
+	decryptCmd.Flags().BytesBase64("key", nil, "base64 encoded 32 byte AES encryption key")
+	decryptCmd.Flags().BytesBase64("mac-key", nil, "base64 encoded 32 byte HMAC key")
+	decryptCmd.Flags().String("detection-xml", "", "read the keys from this saved Detection.xml instead")
+	decryptCmd.MarkFlagsMutuallyExclusive("key", "detection-xml")
+	decryptCmd.MarkFlagsMutuallyExclusive("mac-key", "detection-xml")
+	addKeyVaultFlag(decryptCmd, "read the keys from this Azure Key Vault, such as https://myvault.vault.azure.net")
+	decryptCmd.Flags().String("package-sha256", "", "SHA256 of the package whose keys --key-vault holds")
+	decryptCmd.MarkFlagsRequiredTogether("key-vault", "package-sha256")
+	decryptCmd.MarkFlagsMutuallyExclusive("key", "key-vault")
+	decryptCmd.MarkFlagsMutuallyExclusive("mac-key", "key-vault")
+	decryptCmd.MarkFlagsMutuallyExclusive("detection-xml", "key-vault")
+	addAuthFlags(decryptCmd)
+	decryptCmd.Flags().Bool("no-preserve", false, "do not restore the file modes and modification times recorded in the contents")
+	decryptCmd.Flags().Bool("force", false, "replace files that already exist in the output folder (default)")
+	decryptCmd.Flags().Bool("skip-existing", false, "keep files that already exist in the output folder")
+	decryptCmd.Flags().Bool("fail-if-exists", false, "fail when a file already exists in the output folder")
+	decryptCmd.MarkFlagsMutuallyExclusive("force", "skip-existing", "fail-if-exists")
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"os"
+	"time"
+
+	"github.com/kenchan0130/intunewin/internal/docs"
+	"github.com/kenchan0130/intunewin/internal/pack"
+	"github.com/spf13/cobra"
+)
+
+var docsCmd = &cobra.Command{
+	Use:   "docs",
+	Short: "Generate reference documentation of the commands",
+	Long: `Docs writes the reference documentation of every command, generated from the
+binary itself so that it always matches the installed version.`,
+}
+
+var docsManCmd = &cobra.Command{
+	Use:   "man <output-folder>",
+	Short: "Generate man pages",
+	Long: `Man writes a man page for every command to the output folder, such as
+intunewin.1 and intunewin-pack.1. The pages are dated with SOURCE_DATE_EPOCH
+when it is set, for reproducible packages, and with the current date otherwise.
+
+Example:
+  intunewin docs man /usr/local/share/man/man1`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeArgsUpTo(completeDir),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		date := time.Now()
+		if os.Getenv("SOURCE_DATE_EPOCH") != "" {
+			var err error
+			if date, err = pack.SourceDateEpoch(); err != nil {
+				return err //nolint:wrapcheck // the error names the variable
+			}
+		}
+		n, err := docs.WriteMan(rootCmd, args[0], docs.ManHeader{
+			Section: "1",
+			Date:    date,
+			Source:  "intunewin " + version,
+			Manual:  "User Commands",
+		})
+		if err != nil {
+			return err //nolint:wrapcheck // docs errors already describe the failure
+		}
+		logger.Info("generated man pages", "pages", n, "output", args[0])
+		return nil
+	},
+}
+
+var docsMarkdownCmd = &cobra.Command{
+	Use:   "markdown <output-folder>",
+	Short: "Generate Markdown reference pages",
+	Long: `Markdown writes a Markdown page for every command to the output folder, such
+as intunewin.md and intunewin_pack.md, linked to each other for a documentation
+site.
+
+Example:
+  intunewin docs markdown ./docs/reference`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeArgsUpTo(completeDir),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		n, err := docs.WriteMarkdown(rootCmd, args[0])
+		if err != nil {
+			return err //nolint:wrapcheck // docs errors already describe the failure
+		}
+		logger.Info("generated Markdown pages", "pages", n, "output", args[0])
+		return nil
+	},
+}
+
+func init() {
+	docsCmd.AddCommand(docsManCmd)
+	docsCmd.AddCommand(docsMarkdownCmd)
+}
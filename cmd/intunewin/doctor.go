@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/kenchan0130/intunewin/internal/auth"
+	"github.com/kenchan0130/intunewin/internal/doctor"
+	"github.com/kenchan0130/intunewin/internal/graph"
+	"github.com/kenchan0130/intunewin/internal/render"
+	"github.com/kenchan0130/intunewin/internal/unpack"
+	"github.com/spf13/cobra"
+)
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor [file.intunewin]",
+	Short: "Check the environment and a package for common problems",
+	Long: `Doctor checks that the temporary directory has enough free space, that there is
+enough memory for the intermediate data kept in memory, and on Windows that
+long paths are enabled. When a Microsoft Graph sign-in is configured (cached
+tokens or AZURE_TENANT_ID / AZURE_CLIENT_ID), or with --graph, it also checks
+that the identity platform and Graph can be reached.
+
+Given an intunewin file, doctor sizes the checks for its content and verifies
+it as verify does, then reports Detection.xml values and deep paths that can
+fail on devices. Every problem comes with a hint on how to fix it.
+
+Example:
+  intunewin doctor
+  intunewin doctor myapp.intunewin
+  intunewin doctor --graph --proxy http://proxy.contoso.com:8080`,
+	Args:              cobra.MaximumNArgs(1),
+	ValidArgsFunction: completeArgsUpTo(completePackage),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, err := outputFormat(cmd)
+		if err != nil {
+			return err
+		}
+
+		tempDir, threshold := spillSettings(cmd)
+		freeSpace, contentSize := int64(doctor.DefaultMinFreeSpace), threshold
+		if len(args) == 1 {
+			appInfo, err := unpack.ReadApplicationInfo(args[0], unpackOptions(cmd)...)
+			if err != nil {
+				return fmt.Errorf("failed to read package: %w", err)
+			}
+			// Unpacking writes the decrypted content and the extracted files
+			contentSize = appInfo.UnencryptedContentSize
+			freeSpace = max(freeSpace, 2*contentSize)
+		}
+
+		checks := []doctor.Check{
+			doctor.TempSpace(tempDir, freeSpace),
+			doctor.Memory(contentSize, threshold),
+			doctor.LongPaths(),
+		}
+		if checkGraph, _ := cmd.Flags().GetBool("graph"); checkGraph || graphConfigured() {
+			client, err := httpClient(cmd)
+			if err != nil {
+				return err
+			}
+			authority := auth.DefaultAuthority
+			if host := os.Getenv(envAuthorityHost); host != "" {
+				authority = host
+			}
+			checks = append(checks, doctor.Connectivity(client, authority, graph.DefaultBaseURL))
+		}
+		if len(args) == 1 {
+			checks = append(checks, doctor.Package(args[0], unpackOptions(cmd)...))
+		}
+
+		results := doctor.Run(cmd.Context(), checks...)
+		if err := writeOutput(format, doctorResults(results)); err != nil {
+			return err
+		}
+		if doctor.HasFailures(results) {
+			return fmt.Errorf("%w: doctor found problems", errValidation)
+		}
+		return nil
+	},
+}
+
+// doctorResults is the output of the doctor command
+type doctorResults []doctor.Result
+
+// Table lists one check per row
+func (r doctorResults) Table() render.Table {
+	table := render.Table{Columns: []string{"Check", "Status", "Message", "Hint"}}
+	for _, result := range r {
+		table.Rows = append(table.Rows, []string{result.Check, string(result.Status), result.Message, result.Hint})
+	}
+	return table
+}
+
+// graphConfigured reports whether a Microsoft Graph sign-in is configured for this user
+func graphConfigured() bool {
+	if os.Getenv(envTenantID) != "" || os.Getenv(envClientID) != "" {
+		return true
+	}
+	cache, err := tokenCache()
+	if err != nil {
+		return false
+	}
+	_, err = os.Stat(cache.Path())
+	return err == nil
+}
+
+func init() {
+	addOutputFlag(doctorCmd)
+	addNetworkFlags(doctorCmd)
+	doctorCmd.Flags().Bool("graph", false, "check the connection to Microsoft Graph even when no sign-in is configured")
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+
+	"github.com/kenchan0130/intunewin/internal/buildinfo"
+	"github.com/kenchan0130/intunewin/internal/dirs"
+	"github.com/kenchan0130/intunewin/internal/journal"
+	"github.com/kenchan0130/intunewin/internal/metadata"
+	"github.com/kenchan0130/intunewin/internal/pack"
+	"github.com/kenchan0130/intunewin/internal/unpack"
+	"github.com/spf13/cobra"
+)
+
+var editCmd = &cobra.Command{
+	Use:   "edit <file.intunewin>",
+	Short: "Edit the contents of an intunewin file in a temporary workspace",
+	Long: `Edit extracts an intunewin file to a temporary workspace and opens a shell
+in it (or waits for Enter with --wait). When the shell exits, the files are
+compared with the extracted state and the package is repacked only if
+something changed. A summary of the changes is printed afterwards.
+
+Example:
+  intunewin edit myapp.intunewin
+  intunewin edit --wait --journal changes.txt myapp.intunewin`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeArgsUpTo(completePackage),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		inputFile := args[0]
+		outputFile, _ := cmd.Flags().GetString("output")
+		if outputFile == "" {
+			outputFile = inputFile
+		}
+		wait, _ := cmd.Flags().GetBool("wait")
+		shell, _ := cmd.Flags().GetString("shell")
+		journalFile, _ := cmd.Flags().GetString("journal")
+
+		appInfo, err := unpack.ReadApplicationInfo(inputFile)
+		if err != nil {
+			return fmt.Errorf("failed to read metadata: %w", err)
+		}
+
+		layout, err := dirs.Resolve()
+		if err != nil {
+			return fmt.Errorf("failed to resolve directories: %w", err)
+		}
+		ws, err := layout.NewWorkspace("edit-*")
+		if err != nil {
+			return fmt.Errorf("failed to create workspace: %w", err)
+		}
+		defer ws.Remove()
+		workspace := ws.Dir
+
+		if err := unpack.Unpack(inputFile, workspace, unpackOptions(cmd)...); err != nil {
+			return fmt.Errorf("failed to unpack: %w", err)
+		}
+
+		before, err := journal.Take(workspace)
+		if err != nil {
+			return fmt.Errorf("failed to record workspace state: %w", err)
+		}
+		packOpts := []pack.Option{
+			pack.WithLogger(logger),
+			pack.WithBuildInfo(buildinfo.New(version, false)),
+			pack.WithDescription(appInfo.Description),
+		}
+		if _, err := metadata.CheckToolVersion(appInfo.ToolVersion); err == nil {
+			packOpts = append(packOpts, pack.WithToolVersion(appInfo.ToolVersion))
+		}
+		// Packages with a setup file outside their content can still be edited
+		if _, err := os.Stat(filepath.Join(workspace, appInfo.SetupFile)); err != nil {
+			packOpts = append(packOpts, pack.WithSkipSetupFileCheck())
+		}
+
+		logger.Info("extracted package", "input", inputFile, "workspace", workspace)
+		if wait {
+			fmt.Fprint(os.Stderr, "Edit the files, then press Enter to continue...")
+			if _, err := bufio.NewReader(os.Stdin).ReadString('\n'); err != nil {
+				return fmt.Errorf("failed to wait for input: %w", err)
+			}
+		} else if err := runShell(shell, workspace); err != nil {
+			return err
+		}
+
+		after, err := journal.Take(workspace)
+		if err != nil {
+			return fmt.Errorf("failed to record workspace state: %w", err)
+		}
+
+		changes := journal.Compare(before, after)
+		summary := journal.Summary(changes)
+		if journalFile != "" {
+			if err := os.WriteFile(journalFile, []byte(summary), 0600); err != nil {
+				return fmt.Errorf("failed to write journal: %w", err)
+			}
+		}
+		fmt.Print(summary)
+
+		if len(changes) == 0 {
+			logger.Info("package was left untouched", "input", inputFile)
+			return nil
+		}
+
+		tempDir, threshold := spillSettings(cmd)
+		packOpts = append(packOpts, pack.WithTempDir(tempDir), pack.WithSpillThreshold(threshold), pack.WithMemoryLimit(memoryLimit()))
+		if err := pack.PackWithInfo(workspace, outputFile, appInfo.Name, appInfo.SetupFile, packOpts...); err != nil {
+			return fmt.Errorf("failed to pack: %w", err)
+		}
+		logger.Info("successfully repacked package", "output", outputFile)
+		return nil
+	},
+}
+
+// runShell starts an interactive shell in dir and waits for it to exit
+func runShell(shell, dir string) error {
+	if shell == "" {
+		shell = defaultShell()
+	}
+
+	infof("Starting %s, exit the shell to finish editing", shell)
+	c := exec.Command(shell) // #nosec G204 -- the shell is chosen by the user
+	c.Dir = dir
+	c.Stdin = os.Stdin
+	c.Stdout = os.Stdout
+	c.Stderr = os.Stderr
+	// A non-zero exit status only reflects the last command run in the shell
+	var exitErr *exec.ExitError
+	if err := c.Run(); err != nil && !errors.As(err, &exitErr) {
+		return fmt.Errorf("failed to run shell: %w", err)
+	}
+	return nil
+}
+
+// defaultShell returns the user's preferred shell for the current platform
+func defaultShell() string {
+	if runtime.GOOS == "windows" {
+		if comspec := os.Getenv("COMSPEC"); comspec != "" {
+			return comspec
+		}
+		return "cmd.exe"
+	}
+	if shell := os.Getenv("SHELL"); shell != "" {
+		return shell
+	}
+	return "/bin/sh"
+}
+
+func init() {
+	editCmd.Flags().StringP("output", "o", "", "write the repacked file here instead of overwriting the input")
+	editCmd.Flags().Bool("wait", false, "wait for Enter instead of starting a shell")
+	editCmd.Flags().String("shell", "", "shell to start in the workspace (defaults to $SHELL or %COMSPEC%)")
+	editCmd.Flags().String("journal", "", "also write the change summary to this file")
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/kenchan0130/intunewin/internal/charset"
+	"github.com/spf13/cobra"
+)
+
+// encodingValue is a flag holding the encoding of zip entry names, such as shift_jis
+type encodingValue charset.Encoding
+
+// String implements pflag.Value
+func (e *encodingValue) String() string {
+	return string(*e)
+}
+
+// Set implements pflag.Value
+func (e *encodingValue) Set(value string) error {
+	enc, ok := charset.Parse(value)
+	if !ok {
+		return fmt.Errorf("unknown encoding %q: must be auto, utf-8, shift_jis or cp437", value)
+	}
+	*e = encodingValue(enc)
+	return nil
+}
+
+// Type implements pflag.Value
+func (e *encodingValue) Type() string {
+	return "encoding"
+}
+
+// filenameEncoding is the value of --filename-encoding
+var filenameEncoding = encodingValue(charset.Auto)
+
+// addFilenameEncodingFlag adds --filename-encoding to cmd
+func addFilenameEncodingFlag(cmd *cobra.Command) {
+	cmd.Flags().Var(&filenameEncoding, "filename-encoding", "encoding of file names not flagged as UTF-8 in the content: auto, utf-8, shift_jis or cp437")
+	registerFlagCompletion(cmd, "filename-encoding", string(charset.Auto), string(charset.UTF8), string(charset.ShiftJIS), string(charset.CP437))
+}
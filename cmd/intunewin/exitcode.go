@@ -0,0 +1,172 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+
+	"github.com/kenchan0130/intunewin/internal/auth"
+	"github.com/kenchan0130/intunewin/internal/batch"
+	"github.com/kenchan0130/intunewin/internal/config"
+	"github.com/kenchan0130/intunewin/internal/crypto"
+	"github.com/kenchan0130/intunewin/internal/httpclient"
+	"github.com/kenchan0130/intunewin/internal/keyfile"
+	"github.com/kenchan0130/intunewin/internal/keyvault"
+	"github.com/kenchan0130/intunewin/internal/pack"
+	"github.com/kenchan0130/intunewin/internal/project"
+	"github.com/kenchan0130/intunewin/internal/scaffold"
+	"github.com/kenchan0130/intunewin/internal/signature"
+	"github.com/kenchan0130/intunewin/internal/unpack"
+	"github.com/kenchan0130/intunewin/internal/verify"
+	"github.com/spf13/cobra"
+)
+
+// Exit codes returned by the CLI
+const (
+	exitFailure = 1
+	// exitInvalidInput means an input file or folder is missing or unusable
+	exitInvalidInput = 2
+	// exitNotIntunewin means the input is not a valid intunewin package
+	exitNotIntunewin = 3
+	// exitIntegrity means the encrypted contents failed verification or decryption, or a signature did not match
+	exitIntegrity = 4
+	// exitUnsafeContent means the package contains entries that escape the output folder, symbolic
+	// links or more than the unpack limits allow
+	exitUnsafeContent = 5
+	// exitAuth means signing in to Microsoft Graph failed
+	exitAuth = 6
+	// exitUsage means the command line is invalid, such as an unknown flag or a missing argument
+	exitUsage = 7
+	// exitValidation means checks of the input, including pack hooks, reported problems
+	exitValidation = 8
+	// exitIO means reading or writing a file failed
+	exitIO = 9
+)
+
+// exitKinds names the exit codes in the --json error output
+var exitKinds = map[int]string{
+	exitFailure:       "failure",
+	exitInvalidInput:  "invalid-input",
+	exitNotIntunewin:  "not-intunewin",
+	exitIntegrity:     "integrity",
+	exitUnsafeContent: "unsafe-content",
+	exitAuth:          "auth",
+	exitUsage:         "usage",
+	exitValidation:    "validation",
+	exitIO:            "io",
+}
+
+// errValidation is returned when preflight checks or verification reported problems
+var errValidation = errors.New("validation failed")
+
+// usageError is an invalid command line, detected before the command runs
+type usageError struct {
+	err error
+}
+
+func (e *usageError) Error() string {
+	return e.err.Error()
+}
+
+func (e *usageError) Unwrap() error {
+	return e.err
+}
+
+// exitCode maps err to the exit code of the CLI
+func exitCode(err error) int {
+	switch {
+	case errors.As(err, new(*usageError)):
+		return exitUsage
+	case errors.Is(err, pack.ErrSourceNotFound),
+		errors.Is(err, pack.ErrSourceNotDirectory),
+		errors.Is(err, pack.ErrSourceNotFile),
+		errors.Is(err, pack.ErrPathCollision),
+		errors.Is(err, pack.ErrCaseConflict),
+		errors.Is(err, pack.ErrInvalidName),
+		errors.Is(err, pack.ErrNoCatalogs),
+		errors.Is(err, pack.ErrSymlink),
+		errors.Is(err, pack.ErrSymlinkLoop),
+		errors.Is(err, pack.ErrSetupFileNotFound),
+		errors.Is(err, pack.ErrSetupFileAmbiguous),
+		errors.Is(err, unpack.ErrInputNotFound),
+		errors.Is(err, unpack.ErrFileExists),
+		errors.Is(err, unpack.ErrFileNotInContent),
+		errors.Is(err, crypto.ErrInvalidKeys),
+		errors.Is(err, auth.ErrMissingCredential),
+		errors.Is(err, auth.ErrAzureCLINotFound),
+		errors.Is(err, httpclient.ErrNoCertificates),
+		errors.Is(err, keyvault.ErrInvalidVaultURL),
+		errors.Is(err, keyvault.ErrSecretNotFound),
+		errors.Is(err, keyfile.ErrInvalidKeyFile),
+		errors.Is(err, signature.ErrInvalidKey),
+		errors.Is(err, scaffold.ErrAppNameRequired),
+		errors.Is(err, scaffold.ErrExists),
+		errors.Is(err, config.ErrInvalidConfig),
+		errors.Is(err, project.ErrInvalidProject),
+		errors.Is(err, batch.ErrInvalidManifest),
+		errors.Is(err, unpack.ErrNoMatches),
+		errors.Is(err, path.ErrBadPattern):
+		return exitInvalidInput
+	case errors.Is(err, unpack.ErrNotIntunewin),
+		errors.Is(err, unpack.ErrMetadataMissing),
+		errors.Is(err, unpack.ErrContentsMissing),
+		errors.Is(err, unpack.ErrInvalidMetadata),
+		errors.Is(err, unpack.ErrUnsupportedVersion),
+		errors.Is(err, unpack.ErrNonstandard):
+		return exitNotIntunewin
+	case errors.Is(err, crypto.ErrHMACMismatch),
+		errors.Is(err, crypto.ErrInvalidPadding),
+		errors.Is(err, crypto.ErrInvalidLength),
+		errors.Is(err, verify.ErrSizeMismatch),
+		errors.Is(err, verify.ErrDigestMismatch),
+		errors.Is(err, signature.ErrInvalidSignature):
+		return exitIntegrity
+	case errors.Is(err, unpack.ErrPathTraversal),
+		errors.Is(err, unpack.ErrSymlinkEntry),
+		errors.Is(err, unpack.ErrLimitExceeded):
+		return exitUnsafeContent
+	case errors.Is(err, auth.ErrSignInDeclined),
+		errors.Is(err, auth.ErrDeviceCodeExpired),
+		errors.As(err, new(*auth.Error)):
+		return exitAuth
+	case errors.Is(err, errValidation),
+		errors.Is(err, pack.ErrHookFailed):
+		return exitValidation
+	case errors.As(err, new(*fs.PathError)),
+		errors.As(err, new(*os.LinkError)),
+		errors.As(err, new(*os.SyscallError)):
+		return exitIO
+	default:
+		return exitFailure
+	}
+}
+
+// errorReport is the error object written with --json
+type errorReport struct {
+	Error    string `json:"error"`
+	Kind     string `json:"kind"`
+	ExitCode int    `json:"exitCode"`
+	Command  string `json:"command,omitempty"`
+}
+
+// reportError writes err, which ended cmd with exit code code, to w as translated text or as a
+// JSON object; the JSON message is never translated so that scripts can match it
+func reportError(w io.Writer, cmd *cobra.Command, err error, code int, asJSON bool) {
+	if asJSON {
+		report := errorReport{Error: err.Error(), Kind: exitKinds[code], ExitCode: code}
+		if cmd != nil {
+			report.Command = cmd.CommandPath()
+		}
+		_ = json.NewEncoder(w).Encode(report)
+		return
+	}
+
+	fmt.Fprintf(w, "%s: %s\n", tr.T("Error"), tr.Error(err))
+	if code == exitUsage && cmd != nil {
+		fmt.Fprintln(w, tr.Sprintf("Run '%s --help' for usage.", cmd.CommandPath()))
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"crypto/md5" // #nosec G501 -- MD5 is printed for legacy detection rules, not used for security
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/kenchan0130/intunewin/internal/pack"
+	"github.com/kenchan0130/intunewin/internal/render"
+	"github.com/kenchan0130/intunewin/internal/unpack"
+	"github.com/spf13/cobra"
+)
+
+var hashCmd = &cobra.Command{
+	Use:   "hash <file.intunewin|source-folder> [path-in-package]",
+	Short: "Print the SHA256 and MD5 digests of a packaged file",
+	Long: `Hash prints the size, SHA256 and MD5 digests of the setup file, or of the file
+at path-in-package, for use in file detection rules and install scripts.
+
+The file is read from the decrypted content of an intunewin file or from a
+source folder, where the setup file is detected as by pack. MD5 is only
+provided for legacy rules.
+
+Example:
+  intunewin hash myapp.intunewin
+  intunewin hash myapp.intunewin bin/app.exe --output json
+  intunewin hash ./myapp`,
+	Args:              cobra.RangeArgs(1, 2),
+	ValidArgsFunction: completeArgsUpTo(completePackage, cobra.NoFileCompletions),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, err := outputFormat(cmd)
+		if err != nil {
+			return err
+		}
+
+		var name string
+		if len(args) == 2 {
+			name = args[1]
+		}
+		result, err := hashFile(cmd, args[0], name)
+		if err != nil {
+			return fmt.Errorf("failed to hash: %w", err)
+		}
+		return writeOutput(format, result)
+	},
+}
+
+// hashResult is the output of the hash command
+type hashResult struct {
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+	MD5    string `json:"md5"`
+}
+
+// Table lists the fields of r as rows
+func (r hashResult) Table() render.Table {
+	return render.Table{Columns: []string{"Field", "Value"}, Rows: [][]string{
+		{"Path", r.Path},
+		{"Size", fmt.Sprintf("%d bytes", r.Size)},
+		{"SHA256", r.SHA256},
+		{"MD5", r.MD5},
+	}}
+}
+
+// hashFile hashes the file at name, or the setup file when name is empty, in input
+func hashFile(cmd *cobra.Command, input, name string) (hashResult, error) {
+	info, err := os.Stat(input)
+	if err != nil {
+		return hashResult{}, fmt.Errorf("failed to access %s: %w", input, err)
+	}
+
+	if !info.IsDir() {
+		var buf io.ReadCloser
+		if name == "" {
+			buf, name, err = unpack.OpenSetupFile(input, unpackOptions(cmd)...)
+		} else {
+			buf, name, err = unpack.OpenFile(input, name, unpackOptions(cmd)...)
+		}
+		if err != nil {
+			return hashResult{}, err //nolint:wrapcheck // wrapped by the caller
+		}
+		defer buf.Close()
+		return digest(name, buf)
+	}
+
+	if name == "" {
+		if name, err = pack.DetectSetupFile(input); err != nil {
+			return hashResult{}, fmt.Errorf("failed to detect the setup file, pass the path in the package: %w", err)
+		}
+	}
+	f, err := os.Open(filepath.Join(input, filepath.FromSlash(name))) // #nosec G304 -- path is chosen by the user
+	if err != nil {
+		return hashResult{}, fmt.Errorf("failed to open %s: %w", name, err)
+	}
+	defer f.Close()
+	return digest(filepath.ToSlash(name), f)
+}
+
+// digest reads r and returns its size and digests
+func digest(name string, r io.Reader) (hashResult, error) {
+	sha := sha256.New()
+	md := md5.New() // #nosec G401 -- MD5 is printed for legacy detection rules, not used for security
+	size, err := io.Copy(io.MultiWriter(sha, md), r)
+	if err != nil {
+		return hashResult{}, fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	return hashResult{
+		Path:   name,
+		Size:   size,
+		SHA256: hex.EncodeToString(sha.Sum(nil)),
+		MD5:    hex.EncodeToString(md.Sum(nil)),
+	}, nil
+}
+
+func init() {
+	hashCmd.Flags().Bool("strict", false, "fail on unrecognized Detection.xml versions and nonstandard packages instead of warning")
+	addOutputFlag(hashCmd)
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/kenchan0130/intunewin/internal/manifest"
+	"github.com/kenchan0130/intunewin/internal/pack"
+	"github.com/kenchan0130/intunewin/internal/project"
+	"github.com/kenchan0130/intunewin/internal/scaffold"
+	"github.com/spf13/cobra"
+)
+
+var initCmd = &cobra.Command{
+	Use:   "init <dir>",
+	Short: "Create the skeleton of a new package",
+	Long: `Init writes intunewin.yaml to dir: the application name, setup file,
+excludes, install and uninstall commands and detection rules of the package,
+with comments describing each setting. Pack and publish read it from the
+source folder, or from --project, for every flag not given on the command
+line, so the packaging configuration can be reviewed in version control.
+
+The setup file is detected as by pack, and the name is taken from its version
+resource or the folder name, unless --setup-file or --name is given.
+
+Example:
+  intunewin init ./myapp
+  intunewin init ./myapp --name "My App" --setup-file bin/setup.exe
+  intunewin pack ./myapp myapp.intunewin`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeArgsUpTo(completeDir),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dir := args[0]
+		force, _ := cmd.Flags().GetBool("force")
+
+		var p project.Project
+		p.Name, _ = cmd.Flags().GetString("name")
+		p.SetupFile, _ = cmd.Flags().GetString("setup-file")
+		if p.SetupFile == "" {
+			setupFile, err := pack.DetectSetupFile(dir)
+			if err != nil {
+				logger.Warn("no setup file detected, edit setupFile in the project file", "error", err)
+				setupFile = "setup.exe"
+			}
+			p.SetupFile = setupFile
+		}
+		if p.Name == "" {
+			if version, err := pack.SetupFileVersion(dir, p.SetupFile); err == nil {
+				p.Name = version.ProductName()
+			}
+		}
+		if p.Name == "" {
+			abs, err := filepath.Abs(dir)
+			if err != nil {
+				return fmt.Errorf("failed to resolve %s: %w", dir, err)
+			}
+			p.Name = filepath.Base(abs)
+		}
+		p.InstallCommand, p.UninstallCommand = manifest.SetupCommands(p.SetupFile, "")
+
+		path, err := scaffold.WriteProject(dir, p, force)
+		if err != nil {
+			return fmt.Errorf("failed to create project file: %w", err)
+		}
+		logger.Info("created project file", "path", path)
+		return nil
+	},
+}
+
+var initPSADTCmd = &cobra.Command{
+	Use:   "psadt <dir> --app-name <name>",
+	Short: "Create a PowerShell App Deployment Toolkit skeleton",
+	Long: `Psadt lays down a PowerShell App Deployment Toolkit (PSADT) skeleton in dir:
+Deploy-Application.ps1 with the application variables and, with --installer,
+the install and uninstall commands filled in, and the Files and SupportFiles
+folders.
+
+Put the installer in Files, then copy the AppDeployToolkit folder and
+Deploy-Application.exe from a PSAppDeployToolkit 3.x release into dir and pack
+it; pack detects Deploy-Application.exe as the setup file.
+
+Example:
+  intunewin init psadt ./myapp --app-name "My App" --vendor Contoso --app-version 1.2.3
+  intunewin init psadt ./myapp --app-name "My App" --installer MyApp.msi
+  intunewin pack ./myapp myapp.intunewin`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeArgsUpTo(completeDir),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var p scaffold.PSADT
+		p.AppName, _ = cmd.Flags().GetString("app-name")
+		p.Vendor, _ = cmd.Flags().GetString("vendor")
+		p.Version, _ = cmd.Flags().GetString("app-version")
+		p.Arch, _ = cmd.Flags().GetString("arch")
+		p.Lang, _ = cmd.Flags().GetString("lang-code")
+		p.Author, _ = cmd.Flags().GetString("author")
+		p.Installer, _ = cmd.Flags().GetString("installer")
+		p.CloseApps, _ = cmd.Flags().GetString("close-apps")
+		force, _ := cmd.Flags().GetBool("force")
+
+		script, err := scaffold.WritePSADT(args[0], p, force)
+		if err != nil {
+			return fmt.Errorf("failed to create PSADT skeleton: %w", err)
+		}
+		logger.Info("created PSADT skeleton", "script", script)
+		fmt.Println("Next: put the installer in Files and copy AppDeployToolkit and Deploy-Application.exe from a PSAppDeployToolkit 3.x release into " + args[0])
+		return nil
+	},
+}
+
+func init() {
+	initCmd.Flags().String("name", "", "application name (default is the product name of an .exe setup file, or the name of the folder)")
+	initCmd.Flags().String("setup-file", "", "setup file, relative to dir (default is detected)")
+	initCmd.Flags().Bool("force", false, "replace an existing intunewin.yaml")
+
+	initPSADTCmd.Flags().String("app-name", "", "name of the application")
+	initPSADTCmd.Flags().String("vendor", "", "publisher of the application")
+	initPSADTCmd.Flags().String("app-version", "", "version of the application")
+	initPSADTCmd.Flags().String("arch", "x64", "architecture of the application")
+	initPSADTCmd.Flags().String("lang-code", "EN", "language of the application")
+	initPSADTCmd.Flags().String("author", "", "author of the deployment script")
+	initPSADTCmd.Flags().String("installer", "", "installer in the Files folder, installed with Execute-MSI (.msi) or Execute-Process")
+	initPSADTCmd.Flags().String("close-apps", "", "comma separated processes to close before installing, such as iexplore,winword")
+	initPSADTCmd.Flags().Bool("force", false, "replace an existing Deploy-Application.ps1")
+	_ = initPSADTCmd.MarkFlagRequired("app-name")
+	registerFlagCompletion(initPSADTCmd, "arch", "x86", "x64", "arm64")
+	initCmd.AddCommand(initPSADTCmd)
+}
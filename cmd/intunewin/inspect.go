@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/kenchan0130/intunewin/internal/buildinfo"
+	"github.com/kenchan0130/intunewin/internal/manifest"
+	"github.com/kenchan0130/intunewin/internal/pe"
+	"github.com/kenchan0130/intunewin/internal/render"
+	"github.com/kenchan0130/intunewin/internal/unpack"
+	"github.com/spf13/cobra"
+)
+
+var inspectCmd = &cobra.Command{
+	Use:   "inspect <file.intunewin>",
+	Short: "Show the metadata of an intunewin file",
+	Long: `Inspect prints the metadata of an intunewin file without decrypting it,
+including the estimated disk space needed to install it on a device and, for
+packages created by this tool, the build environment and options. With
+--setup-version the content is decrypted to read the product name and version
+of an .exe setup file.
+
+Example:
+  intunewin inspect myapp.intunewin
+  intunewin inspect myapp.intunewin --footprint-multiplier 4
+  intunewin inspect myapp.intunewin --setup-version
+  intunewin inspect myapp.intunewin --output json`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeArgsUpTo(completePackage),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, err := outputFormat(cmd)
+		if err != nil {
+			return err
+		}
+
+		appInfo, err := unpack.ReadApplicationInfo(args[0], unpackOptions(cmd)...)
+		if err != nil {
+			return fmt.Errorf("failed to read metadata: %w", err)
+		}
+
+		buildInfo, err := unpack.ReadBuildInfo(args[0])
+		if err != nil {
+			return fmt.Errorf("failed to read build info: %w", err)
+		}
+
+		multiplier, _ := cmd.Flags().GetFloat64("footprint-multiplier")
+		footprint, err := manifest.EstimateFootprint(appInfo.UnencryptedContentSize, multiplier)
+		if err != nil {
+			return err
+		}
+
+		var setupVersion *pe.VersionInfo
+		if show, _ := cmd.Flags().GetBool("setup-version"); show {
+			setupVersion, err = unpack.SetupFileVersion(args[0], unpackOptions(cmd)...)
+			if err != nil {
+				return fmt.Errorf("failed to read setup file version: %w", err)
+			}
+		}
+
+		return writeOutput(format, inspectResult{
+			Name:                   appInfo.Name,
+			Description:            appInfo.Description,
+			SetupFile:              appInfo.SetupFile,
+			FileName:               appInfo.FileName,
+			ToolVersion:            appInfo.ToolVersion,
+			UnencryptedContentSize: appInfo.UnencryptedContentSize,
+			InstallFootprint:       footprint,
+			BuildInfo:              buildInfo,
+			SetupVersion:           setupVersion,
+		})
+	},
+}
+
+// inspectResult is the output of the inspect command
+type inspectResult struct {
+	Name                   string              `json:"name"`
+	Description            string              `json:"description,omitempty"`
+	SetupFile              string              `json:"setupFile"`
+	FileName               string              `json:"fileName"`
+	ToolVersion            string              `json:"toolVersion"`
+	UnencryptedContentSize int64               `json:"unencryptedContentSize"`
+	InstallFootprint       *manifest.Footprint `json:"installFootprint"`
+	BuildInfo              *buildinfo.Info     `json:"buildInfo,omitempty"`
+	SetupVersion           *pe.VersionInfo     `json:"setupVersion,omitempty"`
+}
+
+// Table lists the fields of r as rows
+func (r inspectResult) Table() render.Table {
+	rows := [][]string{{"Name", r.Name}}
+	if r.Description != "" {
+		rows = append(rows, []string{"Description", r.Description})
+	}
+	rows = append(rows,
+		[]string{"Setup file", r.SetupFile},
+	)
+	if v := r.SetupVersion; v != nil {
+		rows = append(rows, []string{"Setup product", v.ProductName()}, []string{"Setup version", v.DisplayVersion()})
+		if company := v.Strings[pe.KeyCompanyName]; company != "" {
+			rows = append(rows, []string{"Setup company", company})
+		}
+	}
+	rows = append(rows,
+		[]string{"File name", r.FileName},
+		[]string{"Tool version", r.ToolVersion},
+		[]string{"Unencrypted size", fmt.Sprintf("%d bytes", r.UnencryptedContentSize)},
+		[]string{"Install footprint", fmt.Sprintf("%d bytes (x%g)", r.InstallFootprint.EstimatedBytes, r.InstallFootprint.Multiplier)},
+		[]string{"Minimum free disk space", fmt.Sprintf("%d MB", r.InstallFootprint.MinimumFreeDiskSpaceInMB)},
+	)
+	if b := r.BuildInfo; b != nil {
+		rows = append(rows,
+			[]string{"Built with", fmt.Sprintf("%s %s (%s)", b.Tool, b.ToolVersion, b.GoVersion)},
+			[]string{"Built on", b.OS + "/" + b.Arch},
+		)
+		if b.Hostname != "" {
+			rows = append(rows, []string{"Build host", b.Hostname})
+		}
+		rows = append(rows, []string{"Built at", b.CreatedAt.Format(time.RFC3339)})
+		for _, key := range slices.Sorted(maps.Keys(b.Options)) {
+			rows = append(rows, []string{"Option " + key, b.Options[key]})
+		}
+	}
+	return render.Table{Columns: []string{"Field", "Value"}, Rows: rows}
+}
+
+func init() {
+	inspectCmd.Flags().Bool("strict", false, "fail on unrecognized Detection.xml versions and nonstandard packages instead of warning")
+	inspectCmd.Flags().Float64("footprint-multiplier", manifest.DefaultFootprintMultiplier, "factor applied to the unencrypted content size to estimate the install footprint")
+	inspectCmd.Flags().Bool("setup-version", false, "decrypt the content to show the product name and version of an .exe setup file")
+	addOutputFlag(inspectCmd)
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/kenchan0130/intunewin/internal/keyvault"
+	"github.com/kenchan0130/intunewin/internal/metadata"
+	"github.com/spf13/cobra"
+)
+
+// addKeyVaultFlag adds --key-vault, read by keyVaultClient, to cmd
+func addKeyVaultFlag(cmd *cobra.Command, usage string) {
+	cmd.Flags().String("key-vault", "", usage)
+}
+
+// keyVaultClient returns a client of the vault given by --key-vault, signed in as the
+// flags of addAuthFlags select, or nil when no vault is given
+func keyVaultClient(cmd *cobra.Command) (*keyvault.Client, error) {
+	vaultURL, _ := cmd.Flags().GetString("key-vault")
+	if vaultURL == "" {
+		return nil, nil
+	}
+	tokens, err := keyVaultTokenSource(cmd)
+	if err != nil {
+		return nil, err
+	}
+	client, err := httpClient(cmd)
+	if err != nil {
+		return nil, err
+	}
+	return keyvault.NewClient(vaultURL, tokens, keyvault.WithLogger(logger), keyvault.WithHTTPClient(client)) //nolint:wrapcheck // keyvault errors already describe the failure
+}
+
+// storeKeys stores the encryption info of appInfo in vault as the secret of the package with digest
+func storeKeys(ctx context.Context, vault *keyvault.Client, digest string, appInfo *metadata.ApplicationInfo) error {
+	if appInfo.EncryptionInfo == nil {
+		return fmt.Errorf("failed to store keys in key vault: %w: encryption info is missing", errValidation)
+	}
+	if err := vault.StoreKeys(ctx, digest, appInfo.EncryptionInfo); err != nil {
+		return fmt.Errorf("failed to store keys in key vault: %w", err)
+	}
+	logger.Info("stored encryption keys in key vault", "secret", keyvault.SecretName(digest))
+	return nil
+}
+
+// loadKeys returns the encryption info stored in vault for the package with digest
+func loadKeys(ctx context.Context, vault *keyvault.Client, digest string) (*metadata.XMLEncryptionInfo, error) {
+	info, err := vault.LoadKeys(ctx, digest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load keys from key vault: %w", err)
+	}
+	logger.Debug("loaded encryption keys from key vault", "secret", keyvault.SecretName(digest))
+	return info, nil
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"os"
+	"strings"
+
+	"github.com/kenchan0130/intunewin/internal/i18n"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// tr translates help and error messages into the language selected by --lang
+var tr = i18n.New(i18n.English)
+
+// usageTemplate is the English usage template of cobra, translated by localize
+var usageTemplate string
+
+// usageHeadings are the lines of usageTemplate that are translated
+var usageHeadings = []string{
+	"Usage:", "Aliases:", "Examples:", "Available Commands:", "Additional Commands:",
+	"Flags:", "Global Flags:", "Additional help topics:",
+}
+
+// language returns the language selected by --lang or, when it is not given, by the environment
+func language(cmd *cobra.Command) (string, error) {
+	if f := cmd.Flags().Lookup("lang"); f != nil && f.Changed {
+		return i18n.Parse(f.Value.String()) //nolint:wrapcheck // the error names the flag value
+	}
+	return i18n.Detect(os.Getenv), nil
+}
+
+// setLanguage translates error messages into the language selected for cmd.
+// An unsupported --lang falls back to the environment.
+func setLanguage(cmd *cobra.Command) {
+	lang, err := language(cmd)
+	if err != nil {
+		lang = i18n.Detect(os.Getenv)
+	}
+	tr = i18n.New(lang)
+}
+
+// localize translates the usage template, command summaries and flag usages of the
+// command tree. Generated reference documentation stays in English as it never calls this.
+func localize(root *cobra.Command) {
+	template := usageTemplate
+	for _, heading := range usageHeadings {
+		template = strings.Replace(template, heading, tr.T(heading), 1)
+	}
+	const more = `Use "%s [command] --help" for more information about a command.`
+	template = strings.Replace(template, strings.Replace(more, "%s", "{{.CommandPath}}", 1),
+		tr.Sprintf(more, "{{.CommandPath}}"), 1)
+	root.SetUsageTemplate(template)
+
+	var walk func(c *cobra.Command)
+	walk = func(c *cobra.Command) {
+		c.Short = tr.T(c.Short)
+		translate := func(f *pflag.Flag) {
+			switch f.Name {
+			case "help":
+				f.Usage = tr.Sprintf("help for %s", c.Name())
+			case "version":
+				f.Usage = tr.Sprintf("version for %s", c.Name())
+			default:
+				f.Usage = tr.T(f.Usage)
+			}
+		}
+		c.LocalNonPersistentFlags().VisitAll(translate)
+		c.PersistentFlags().VisitAll(translate)
+		for _, child := range c.Commands() {
+			walk(child)
+		}
+	}
+	walk(root)
+}
+
+func init() {
+	usageTemplate = rootCmd.UsageTemplate()
+
+	// Help is printed before the pre-run hook, so the language is selected here
+	help := rootCmd.HelpFunc()
+	rootCmd.SetHelpFunc(func(cmd *cobra.Command, args []string) {
+		setLanguage(cmd)
+		if tr.Lang() != i18n.English {
+			localize(cmd.Root())
+		}
+		help(cmd, args)
+	})
+}
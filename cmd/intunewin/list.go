@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/kenchan0130/intunewin/internal/render"
+	"github.com/kenchan0130/intunewin/internal/unpack"
+	"github.com/spf13/cobra"
+)
+
+var listCmd = &cobra.Command{
+	Use:   "list <file.intunewin>",
+	Short: "List the files in an intunewin file",
+	Long: `List decrypts an intunewin file and prints the files it contains without
+extracting them.
+
+Example:
+  intunewin list myapp.intunewin
+  intunewin list myapp.intunewin --output json`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeArgsUpTo(completePackage),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, err := outputFormat(cmd)
+		if err != nil {
+			return err
+		}
+
+		entries, err := unpack.List(args[0], unpackOptions(cmd)...)
+		if err != nil {
+			return fmt.Errorf("failed to list %s: %w", args[0], err)
+		}
+		return writeOutput(format, listResult(entries))
+	},
+}
+
+// listResult is the output of the list command
+type listResult []unpack.Entry
+
+// Table lists one file per row
+func (r listResult) Table() render.Table {
+	rows := make([][]string, 0, len(r))
+	for _, entry := range r {
+		rows = append(rows, []string{
+			entry.Mode.String(),
+			fmt.Sprintf("%d", entry.Size),
+			entry.Modified.UTC().Format(time.RFC3339),
+			entry.Path,
+		})
+	}
+	return render.Table{Columns: []string{"Mode", "Size", "Modified", "Path"}, Rows: rows}
+}
+
+func init() {
+	listCmd.Flags().Bool("strict", false, "fail on unrecognized Detection.xml versions and nonstandard packages instead of warning")
+	addFilenameEncodingFlag(listCmd)
+	addOutputFlag(listCmd)
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// logger receives the diagnostic messages of every command.
+// It is configured from --log-level and --log-format before a command runs.
+var logger = slog.New(slog.DiscardHandler)
+
+// verbosity is the amount of output selected with --quiet (-1) and --verbose (1 or more)
+var verbosity int
+
+// logLevel returns the log level for --log-level as adjusted by --quiet and --verbose
+func logLevel(level string, quiet bool, verbose int) string {
+	switch {
+	case quiet:
+		return "warn"
+	case verbose > 0:
+		return "debug"
+	default:
+		return level
+	}
+}
+
+// infof writes an informational message to stderr unless --quiet is given
+func infof(format string, args ...any) {
+	if verbosity < 0 {
+		return
+	}
+	fmt.Fprintf(os.Stderr, format+"\n", args...)
+}
+
+// newLogger creates a logger writing to w with the given level and format
+func newLogger(w io.Writer, level, format string) (*slog.Logger, error) {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return nil, fmt.Errorf("invalid log level %q: must be debug, info, warn or error", level)
+	}
+	handlerOpts := &slog.HandlerOptions{Level: lvl}
+
+	switch strings.ToLower(format) {
+	case "text":
+		// Timestamps only add noise to interactive output
+		handlerOpts.ReplaceAttr = func(groups []string, a slog.Attr) slog.Attr {
+			if len(groups) == 0 && a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		}
+		return slog.New(slog.NewTextHandler(w, handlerOpts)), nil
+	case "json":
+		return slog.New(slog.NewJSONHandler(w, handlerOpts)), nil
+	default:
+		return nil, fmt.Errorf("invalid log format %q: must be text or json", format)
+	}
+}
@@ -1,45 +1,360 @@
 package main
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/kenchan0130/intunewin/internal/buildinfo"
+	"github.com/kenchan0130/intunewin/internal/charset"
+	"github.com/kenchan0130/intunewin/internal/crypto"
+	"github.com/kenchan0130/intunewin/internal/hook"
+	"github.com/kenchan0130/intunewin/internal/keyfile"
+	"github.com/kenchan0130/intunewin/internal/keyvault"
+	"github.com/kenchan0130/intunewin/internal/metadata"
 	"github.com/kenchan0130/intunewin/internal/pack"
+	"github.com/kenchan0130/intunewin/internal/pathutil"
+	"github.com/kenchan0130/intunewin/internal/preflight"
+	"github.com/kenchan0130/intunewin/internal/progress"
+	"github.com/kenchan0130/intunewin/internal/project"
+	"github.com/kenchan0130/intunewin/internal/spill"
 	"github.com/kenchan0130/intunewin/internal/unpack"
 	"github.com/spf13/cobra"
 )
 
+// Set by the release build with -ldflags
+var (
+	version = "dev"
+	commit  = "none"
+	date    = "unknown"
+)
+
+// started is set once the command line has been parsed and validated
+var started bool
+
 var rootCmd = &cobra.Command{
 	Use:   "intunewin",
 	Short: "A CLI tool for creating and extracting intunewin files",
 	Long: `intunewin is a CLI tool that allows you to create and extract .intunewin files.
 It provides a simple interface for packaging folders into intunewin format
 and extracting intunewin files back to folders.`,
+	SilenceErrors: true,
+	SilenceUsage:  true,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		started = true
+		if err := applyProject(cmd, args); err != nil {
+			return err
+		}
+		if err := applyConfig(cmd); err != nil {
+			return err
+		}
+		// Cobra checks these after the hook so that configured values count; check them
+		// here as well to report them as usage errors
+		if err := cmd.ValidateRequiredFlags(); err != nil {
+			return &usageError{err: err}
+		}
+		if err := cmd.ValidateFlagGroups(); err != nil {
+			return &usageError{err: err}
+		}
+		if _, err := language(cmd); err != nil {
+			return &usageError{err: err}
+		}
+		setLanguage(cmd)
+		level, _ := cmd.Flags().GetString("log-level")
+		format, _ := cmd.Flags().GetString("log-format")
+		quiet, _ := cmd.Flags().GetBool("quiet")
+		verbose, _ := cmd.Flags().GetCount("verbose")
+		verbosity = verbose
+		if quiet {
+			verbosity = -1
+		}
+		l, err := newLogger(os.Stderr, logLevel(level, quiet, verbose), format)
+		if err != nil {
+			return &usageError{err: err}
+		}
+		logger = l
+		return nil
+	},
 }
 
 var packCmd = &cobra.Command{
-	Use:   "pack <source-folder> <output-file.intunewin>",
+	Use:   "pack <source-folder|setup-file> <output-file.intunewin>",
 	Short: "Package a folder into an intunewin file",
 	Long: `Pack creates an intunewin file from a source folder.
 The source folder will be compressed, encrypted, and packaged
 into the specified output file.
 
+A single file, such as an .msi or a self-contained .exe, can be given in
+place of the folder: it becomes the only content and the setup file, so no
+folder has to be staged for it.
+
+Pass "-" as the source folder to read a zip archive (or a tar archive with
+--format tar) from standard input, and "-" as the output file to write the
+package to standard output.
+
+With --reproducible, entries are sorted and all timestamps are set to
+SOURCE_DATE_EPOCH (or 1980-01-01). Supplying --encryption-key, --mac-key
+and --iv as well makes the output byte-identical for the same input.
+
+--export-keys writes the encryption info to a separate JSON file, so upload
+scripts can get the keys without opening the package. A .age, .gpg or .asc
+file is encrypted with the age or gpg command to --export-keys-recipient.
+The keys are then left out of the Detection.xml of the package, which unpack
+can only extract with --key-file.
+--key-vault stores the encryption info in Azure Key Vault instead, signing in
+as for "auth login", so that unpack, decrypt and publish can read it from
+there. It is left out of the Detection.xml of the package as well.
+
 Example:
-  intunewin pack ./myapp ./dist/myapp.intunewin`,
-	Args: cobra.ExactArgs(2),
+  intunewin pack ./myapp ./dist/myapp.intunewin
+  intunewin pack ./Setup.msi ./dist/setup.intunewin
+  zip -r - ./myapp | intunewin pack - - > myapp.intunewin
+  tar -C ./myapp -cf - . | intunewin pack --format tar - myapp.intunewin
+  intunewin pack ./myapp ./dist/myapp.intunewin --export-keys keys.json.age --export-keys-recipient age1...`,
+	Args:              cobra.ExactArgs(2),
+	ValidArgsFunction: completeArgsUpTo(completeExt("msi", "exe"), completePackage),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		sourceFolder := args[0]
 		outputFile := args[1]
 
-		fmt.Printf("Packing %s to %s...\n", sourceFolder, outputFile)
-		if err := pack.Pack(sourceFolder, outputFile); err != nil {
+		opts, err := packOptions(cmd)
+		if err != nil {
+			return err
+		}
+		format, err := archiveFormat(cmd, sourceFolder)
+		if err != nil {
+			return err
+		}
+		if adds, _ := cmd.Flags().GetStringArray("add"); len(adds) > 0 && sourceFolder == stdioArg {
+			return &usageError{err: errors.New("--add cannot be used when the content is read from standard input")}
+		}
+		outputJSON, _ := cmd.Flags().GetString("output-json")
+		if outputJSON == stdioArg && outputFile == stdioArg {
+			return fmt.Errorf("--output-json %s cannot be used when the package is written to standard output", stdioArg)
+		}
+		commitJSON, _ := cmd.Flags().GetString("commit-json")
+		if commitJSON == stdioArg && (outputFile == stdioArg || outputJSON == stdioArg) {
+			return fmt.Errorf("--commit-json %s cannot share standard output with the package or --output-json", stdioArg)
+		}
+		exportKeys, _ := cmd.Flags().GetString("export-keys")
+		recipients, _ := cmd.Flags().GetStringArray("export-keys-recipient")
+		if exportKeys != "" && keyfile.FormatOf(exportKeys) != keyfile.FormatPlain && len(recipients) == 0 {
+			return &usageError{err: fmt.Errorf("--export-keys %s: %w; use --export-keys-recipient", exportKeys, keyfile.ErrRecipientRequired)}
+		}
+		name, setupFile, opts, err := packInfo(cmd, sourceFolder, opts)
+		if err != nil {
+			return err
+		}
+		if catalogFolder, _ := cmd.Flags().GetString("catalog-folder"); catalogFolder != "" {
+			opts = append(opts, pack.WithCatalogFolder(catalogFolder))
+		}
+
+		if skip, _ := cmd.Flags().GetBool("skip-preflight"); !skip && sourceFolder != stdioArg {
+			report, err := preflight.Run(sourceFolder)
+			if err != nil {
+				return fmt.Errorf("failed to run preflight checks: %w", err)
+			}
+			report.Print(os.Stderr)
+			if report.HasErrors() {
+				return fmt.Errorf("%w: preflight checks reported errors", errValidation)
+			}
+		}
+
+		reporter, finish, err := progressReporter(cmd)
+		if err != nil {
+			return err
+		}
+		if reporter != nil {
+			opts = append(opts, pack.WithProgress(reporter))
+		}
+
+		opts = append(opts, pack.WithLogger(logger))
+
+		var summary pack.Summary
+		opts = append(opts, pack.WithSummary(&summary))
+		vault, err := keyVaultClient(cmd)
+		if err != nil {
+			return err
+		}
+		if commitJSON != "" || exportKeys != "" || vault != nil {
+			opts = append(opts, pack.WithRetainKeys())
+		}
+		if exportKeys != "" || vault != nil {
+			opts = append(opts, pack.WithOmitEncryptionInfo())
+		}
+
+		logger.Info("packing", "source", sourceFolder, "output", outputFile, "name", name, "setupFile", setupFile)
+		start := time.Now()
+		err = runPack(sourceFolder, outputFile, name, setupFile, format, opts)
+		finish()
+		if err != nil {
 			return fmt.Errorf("failed to pack: %w", err)
 		}
-		fmt.Printf("Successfully created %s\n", outputFile)
+		logger.Info("successfully created package", "output", outputFile)
+
+		if outputJSON != "" {
+			result := newPackResult(outputFile, name, setupFile, &summary, time.Since(start))
+			if err := writePackResult(outputJSON, result); err != nil {
+				return fmt.Errorf("failed to write pack summary: %w", err)
+			}
+		}
+		if commitJSON != "" {
+			if err := writeCommitRequest(commitJSON, summary.ApplicationInfo); err != nil {
+				return fmt.Errorf("failed to write commit request: %w", err)
+			}
+		}
+		if exportKeys != "" {
+			keys, err := keyfile.New(summary.ApplicationInfo, summary.SHA256)
+			if err != nil {
+				return fmt.Errorf("failed to export keys: %w", err)
+			}
+			if err := keys.Write(cmd.Context(), exportKeys, recipients); err != nil {
+				removeOutput(outputFile)
+				return fmt.Errorf("failed to export keys: %w", err)
+			}
+			logger.Info("exported encryption keys", "output", exportKeys)
+		}
+		if vault != nil {
+			if err := storeKeys(cmd.Context(), vault, summary.SHA256, summary.ApplicationInfo); err != nil {
+				removeOutput(outputFile)
+				return err
+			}
+		}
 		return nil
 	},
 }
 
+// removeOutput removes the package written to outputFile, unless it went to standard output,
+// when the keys it leaves out of its Detection.xml could not be kept, as it cannot be
+// decrypted without them
+func removeOutput(outputFile string) {
+	if outputFile != stdioArg {
+		_ = os.Remove(outputFile)
+	}
+}
+
+// packInfo returns the application name and setup file to record for sourceFolder.
+// Without --setup-file the setup file is detected in the source folder; a package read
+// from standard input is then recorded with its name as the setup file, unchecked.
+// The name and description default to the product name and version of an .exe setup file.
+func packInfo(cmd *cobra.Command, sourceFolder string, opts []pack.Option) (string, string, []pack.Option, error) {
+	name, _ := cmd.Flags().GetString("name")
+	description, _ := cmd.Flags().GetString("description")
+	setupFile, _ := cmd.Flags().GetString("setup-file")
+
+	if sourceFolder == stdioArg {
+		if name == "" {
+			name = stdinPackageName
+		}
+		if setupFile == "" {
+			setupFile = name
+			opts = append(opts, pack.WithSkipSetupFileCheck())
+		}
+		return name, setupFile, append(opts, pack.WithDescription(description)), nil
+	}
+
+	setupDir := sourceFolder
+	if isSourceFile(sourceFolder) {
+		base := filepath.Base(sourceFolder)
+		if setupFile != "" && setupFile != base {
+			return "", "", nil, &usageError{err: fmt.Errorf("--setup-file %s: a single file is packed as its own setup file", setupFile)}
+		}
+		setupFile = base
+		setupDir = filepath.Dir(sourceFolder)
+	}
+
+	if setupFile == "" {
+		var err error
+		setupFile, err = pack.DetectSetupFile(sourceFolder)
+		if errors.Is(err, pack.ErrSetupFileNotFound) || errors.Is(err, pack.ErrSetupFileAmbiguous) {
+			return "", "", nil, fmt.Errorf("failed to detect the setup file, pass --setup-file: %w", err)
+		}
+		if err != nil {
+			return "", "", nil, fmt.Errorf("failed to pack: %w", err)
+		}
+		infof("Detected setup file: %s", setupFile)
+	}
+
+	if name == "" || description == "" {
+		if version, err := pack.SetupFileVersion(setupDir, setupFile); err == nil {
+			product := version.ProductName()
+			if name == "" {
+				name = product
+			}
+			if description == "" {
+				description = strings.TrimSpace(product + " " + version.DisplayVersion())
+			}
+		}
+	}
+	if name == "" {
+		name = strings.TrimSuffix(filepath.Base(sourceFolder), filepath.Ext(setupFile))
+	}
+	if _, err := os.Stat(filepath.Join(sourceFolder, project.FileName)); err == nil {
+		opts = append(opts, pack.WithExclude("/"+project.FileName))
+	}
+	return name, setupFile, append(opts, pack.WithDescription(description)), nil
+}
+
+// splitAddition splits an --add value into the file or folder to pack and its path in the
+// package, empty for its base name. A drive letter such as C: is part of the path.
+func splitAddition(value string) (string, string) {
+	i := strings.LastIndex(value, ":")
+	if i <= 1 {
+		return value, ""
+	}
+	return value[:i], value[i+1:]
+}
+
+// isSourceFile reports whether the pack source is a single regular file, such as an .msi,
+// rather than a folder
+func isSourceFile(source string) bool {
+	if source == stdioArg {
+		return false
+	}
+	info, err := os.Stat(source)
+	return err == nil && info.Mode().IsRegular()
+}
+
+// runPack packs sourceFolder, a folder or a single file, to outputFile, either of which
+// may be stdioArg. Standard input is read as an archive of the given format.
+func runPack(sourceFolder, outputFile, name, setupFile, format string, opts []pack.Option) error {
+	single := isSourceFile(sourceFolder)
+	if outputFile != stdioArg && sourceFolder != stdioArg {
+		if single {
+			return pack.PackFileWithInfo(sourceFolder, outputFile, name, opts...) //nolint:wrapcheck // wrapped by the caller
+		}
+		return pack.PackWithInfo(sourceFolder, outputFile, name, setupFile, opts...) //nolint:wrapcheck // wrapped by the caller
+	}
+
+	packTo := func(w io.Writer) error {
+		switch {
+		case single:
+			return pack.PackFileWithInfoTo(sourceFolder, w, name, opts...) //nolint:wrapcheck // wrapped by the caller
+		case sourceFolder != stdioArg:
+			return pack.PackWithInfoTo(sourceFolder, w, name, setupFile, opts...) //nolint:wrapcheck // wrapped by the caller
+		case format == archiveTar:
+			return pack.PackTarTo(os.Stdin, w, name, setupFile, opts...) //nolint:wrapcheck // wrapped by the caller
+		default:
+			return pack.PackZipTo(os.Stdin, w, name, setupFile, opts...) //nolint:wrapcheck // wrapped by the caller
+		}
+	}
+
+	if outputFile == stdioArg {
+		w, err := binaryStdout()
+		if err != nil {
+			return err
+		}
+		return packTo(w)
+	}
+
+	return pack.WriteFile(outputFile, packTo) //nolint:wrapcheck // wrapped by the caller
+}
+
 var unpackCmd = &cobra.Command{
 	Use:   "unpack <input-file.intunewin> <output-folder>",
 	Short: "Extract an intunewin file to a folder",
@@ -47,30 +362,501 @@ var unpackCmd = &cobra.Command{
 The file will be decrypted, decompressed, and extracted
 to the output folder.
 
+Pass "-" as the input file to read the package from standard input, and "-"
+as the output folder to write the decrypted content zip (or a tar archive with
+--format tar) to standard output instead of extracting it.
+
+With --metadata-only, only Detection.xml, a JSON rendering of it and the build
+information are written, without decrypting the contents.
+
+With --only, only the entries matching one of the patterns are extracted. A
+pattern without a slash matches a file or folder name at any depth, otherwise
+the path in the package; "**" matches any number of folders.
+
+--key-file decrypts a package packed with --export-keys, whose Detection.xml
+does not hold the keys, with the key file written by that pack. An encrypted
+key file is decrypted with age or gpg first and passed as "-" on standard input.
+--key-vault decrypts a package packed with --key-vault with the keys stored
+for it in Azure Key Vault, signing in as for "auth login".
+
 Example:
-  intunewin unpack myapp.intunewin ./extracted`,
-	Args: cobra.ExactArgs(2),
+  intunewin unpack myapp.intunewin ./extracted
+  intunewin unpack myapp.intunewin - > content.zip
+  intunewin unpack --format tar myapp.intunewin - | tar -xf - -C ./extracted
+  intunewin unpack --metadata-only myapp.intunewin ./review
+  intunewin unpack myapp.intunewin ./scripts --only "*.ps1"
+  intunewin unpack myapp.intunewin ./extracted --key-file keys.json
+  age -d -i key.txt keys.json.age | intunewin unpack myapp.intunewin ./extracted --key-file -
+  intunewin unpack myapp.intunewin ./extracted --key-vault https://myvault.vault.azure.net`,
+	Args:              cobra.ExactArgs(2),
+	ValidArgsFunction: completeArgsUpTo(completePackage, completeDir),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		inputFile := args[0]
 		outputFolder := args[1]
 
-		fmt.Printf("Unpacking %s to %s...\n", inputFile, outputFolder)
-		if err := unpack.Unpack(inputFile, outputFolder); err != nil {
+		opts := unpackOptions(cmd)
+		format, err := archiveFormat(cmd, outputFolder)
+		if err != nil {
+			return err
+		}
+
+		if metadataOnly, _ := cmd.Flags().GetBool("metadata-only"); metadataOnly {
+			if inputFile == stdioArg || outputFolder == stdioArg {
+				return fmt.Errorf("--metadata-only does not support standard input or output")
+			}
+			logger.Info("extracting metadata", "input", inputFile, "output", outputFolder)
+			if err := unpack.ExtractMetadata(inputFile, outputFolder, opts...); err != nil {
+				return fmt.Errorf("failed to extract metadata: %w", err)
+			}
+			logger.Info("successfully extracted metadata", "output", outputFolder)
+			return nil
+		}
+
+		if only, _ := cmd.Flags().GetStringArray("only"); len(only) > 0 && outputFolder == stdioArg {
+			return &usageError{err: errors.New("--only cannot be used when the content is written to standard output")}
+		}
+
+		keyOpt, err := keysOption(cmd, inputFile)
+		if err != nil {
+			return err
+		}
+		if keyOpt != nil {
+			opts = append(opts, keyOpt)
+		}
+		logger.Info("unpacking", "input", inputFile, "output", outputFolder)
+		if err := runUnpack(inputFile, outputFolder, format, opts); err != nil {
 			return fmt.Errorf("failed to unpack: %w", err)
 		}
-		fmt.Printf("Successfully extracted to %s\n", outputFolder)
+		logger.Info("successfully extracted package", "output", outputFolder)
 		return nil
 	},
 }
 
+// runUnpack extracts inputFile to outputFolder, either of which may be stdioArg.
+// With standard output the decrypted content is written as is, or as a tar archive
+// for archiveTar.
+func runUnpack(inputFile, outputFolder, format string, opts []unpack.Option) error {
+	if outputFolder != stdioArg {
+		if inputFile == stdioArg {
+			return unpack.UnpackReader(os.Stdin, outputFolder, opts...) //nolint:wrapcheck // wrapped by the caller
+		}
+		return unpack.Unpack(inputFile, outputFolder, opts...) //nolint:wrapcheck // wrapped by the caller
+	}
+
+	w, err := binaryStdout()
+	if err != nil {
+		return err
+	}
+	switch {
+	case format == archiveTar && inputFile == stdioArg:
+		return unpack.UnpackReaderToTar(os.Stdin, w, opts...) //nolint:wrapcheck // wrapped by the caller
+	case format == archiveTar:
+		return unpack.UnpackToTar(inputFile, w, opts...) //nolint:wrapcheck // wrapped by the caller
+	case inputFile == stdioArg:
+		_, err = unpack.DecryptReader(os.Stdin, w, opts...)
+	default:
+		_, err = unpack.Decrypt(inputFile, w, opts...)
+	}
+	return err //nolint:wrapcheck // wrapped by the caller
+}
+
+// keysOption returns an option decrypting inputFile with the keys of the file given by
+// --key-file, read from standard input for stdioArg, or with the keys stored for it in the
+// vault given by --key-vault. It returns nil when neither is given.
+func keysOption(cmd *cobra.Command, inputFile string) (unpack.Option, error) {
+	vault, err := keyVaultClient(cmd)
+	if err != nil {
+		return nil, err
+	}
+	if vault != nil {
+		if inputFile == stdioArg {
+			return nil, &usageError{err: errors.New("--key-vault cannot be used when the package is read from standard input")}
+		}
+		digest, err := keyvault.PackageDigest(inputFile)
+		if err != nil {
+			return nil, err //nolint:wrapcheck // keyvault errors already describe the failure
+		}
+		info, err := loadKeys(cmd.Context(), vault, digest)
+		if err != nil {
+			return nil, err
+		}
+		return unpack.WithEncryptionInfo(info), nil
+	}
+
+	path, _ := cmd.Flags().GetString("key-file")
+	if path == "" {
+		return nil, nil
+	}
+	if path == stdioArg && inputFile == stdioArg {
+		return nil, &usageError{err: fmt.Errorf("--key-file %s cannot be used when the package is read from standard input", stdioArg)}
+	}
+
+	r := io.Reader(os.Stdin)
+	if path != stdioArg {
+		f, err := os.Open(pathutil.Long(path))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read key file: %w", err)
+		}
+		defer f.Close()
+		r = f
+	}
+	keys, err := keyfile.Read(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key file %s: %w", path, err)
+	}
+	return unpack.WithEncryptionInfo(keys.EncryptionInfo), nil
+}
+
+// unpackOptions builds unpack options from the flags of cmd
+func unpackOptions(cmd *cobra.Command) []unpack.Option {
+	tempDir, threshold := spillSettings(cmd)
+	opts := []unpack.Option{
+		unpack.WithLogger(logger),
+		unpack.WithTempDir(tempDir),
+		unpack.WithSpillThreshold(threshold),
+		unpack.WithMemoryLimit(memoryLimit()),
+	}
+	if strict, _ := cmd.Flags().GetBool("strict"); strict {
+		opts = append(opts, unpack.WithStrict())
+	}
+	if noVerify, _ := cmd.Flags().GetBool("no-verify"); noVerify {
+		opts = append(opts, unpack.WithNoVerify())
+	}
+	if noPreserve, _ := cmd.Flags().GetBool("no-preserve"); noPreserve {
+		opts = append(opts, unpack.WithNoPreserve())
+	}
+	if skip, _ := cmd.Flags().GetBool("skip-existing"); skip {
+		opts = append(opts, unpack.WithOverwrite(unpack.OverwriteSkip))
+	}
+	if fail, _ := cmd.Flags().GetBool("fail-if-exists"); fail {
+		opts = append(opts, unpack.WithOverwrite(unpack.OverwriteFail))
+	}
+	if only, _ := cmd.Flags().GetStringArray("only"); len(only) > 0 {
+		opts = append(opts, unpack.WithOnly(only...))
+	}
+	if cmd.Flags().Lookup("filename-encoding") != nil {
+		opts = append(opts, unpack.WithFilenameEncoding(charset.Encoding(filenameEncoding)))
+	}
+	if allow, _ := cmd.Flags().GetBool("allow-symlinks"); allow {
+		opts = append(opts, unpack.WithAllowSymlinks())
+	}
+	if cmd.Flags().Lookup("max-total-size") != nil {
+		maxEntries, _ := cmd.Flags().GetInt("max-entries")
+		opts = append(opts, unpack.WithLimits(unpack.Limits{
+			MaxTotalSize: int64(maxTotalSize),
+			MaxFileSize:  int64(maxFileSize),
+			MaxEntries:   maxEntries,
+		}))
+	}
+	return opts
+}
+
+// packOptions builds pack options from the flags of cmd
+func packOptions(cmd *cobra.Command) ([]pack.Option, error) {
+	tempDir, threshold := spillSettings(cmd)
+	opts := []pack.Option{
+		pack.WithTempDir(tempDir),
+		pack.WithSpillThreshold(threshold),
+		pack.WithMemoryLimit(memoryLimit()),
+	}
+
+	if reproducible, _ := cmd.Flags().GetBool("reproducible"); reproducible {
+		modTime, err := pack.SourceDateEpoch()
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, pack.WithReproducible(modTime))
+	}
+
+	if cmd.Flags().Changed("compression-level") {
+		level, _ := cmd.Flags().GetInt("compression-level")
+		opts = append(opts, pack.WithCompressionLevel(level))
+	}
+
+	if cmd.Flags().Changed("threads") {
+		threads, _ := cmd.Flags().GetInt("threads")
+		opts = append(opts, pack.WithThreads(threads))
+	}
+
+	if excludes, _ := cmd.Flags().GetStringArray("exclude"); len(excludes) > 0 {
+		opts = append(opts, pack.WithExclude(excludes...))
+	}
+
+	adds, _ := cmd.Flags().GetStringArray("add")
+	for _, add := range adds {
+		source, dest := splitAddition(add)
+		opts = append(opts, pack.WithAdd(source, dest))
+	}
+
+	if skip, _ := cmd.Flags().GetBool("skip-symlinks"); skip {
+		opts = append(opts, pack.WithSymlinks(pack.SymlinkSkip))
+	}
+	if fail, _ := cmd.Flags().GetBool("error-on-symlinks"); fail {
+		opts = append(opts, pack.WithSymlinks(pack.SymlinkError))
+	}
+
+	if dedupe, _ := cmd.Flags().GetBool("dedupe-case"); dedupe {
+		opts = append(opts, pack.WithCaseConflicts(pack.CaseConflictFirst))
+	}
+	if toolVersion, _ := cmd.Flags().GetString("tool-version"); toolVersion != "" {
+		if _, err := metadata.CheckToolVersion(toolVersion); err != nil {
+			return nil, &usageError{err: fmt.Errorf("--tool-version: %w", err)}
+		}
+		opts = append(opts, pack.WithToolVersion(toolVersion))
+	}
+
+	if prune, _ := cmd.Flags().GetBool("prune-empty-dirs"); prune {
+		opts = append(opts, pack.WithPruneEmptyDirs())
+	}
+	if posixModes, _ := cmd.Flags().GetBool("posix-modes"); posixModes {
+		opts = append(opts, pack.WithPOSIXModes())
+	}
+	if noDescriptors, _ := cmd.Flags().GetBool("no-data-descriptors"); noDescriptors {
+		opts = append(opts, pack.WithNoDataDescriptors())
+	}
+	if allow, _ := cmd.Flags().GetBool("allow-invalid-names"); allow {
+		opts = append(opts, pack.WithInvalidNames(pack.InvalidNameWarn))
+	}
+
+	storeCompressed, _ := cmd.Flags().GetBool("store-compressed")
+	storeExts, _ := cmd.Flags().GetStringSlice("store-ext")
+	switch {
+	case !storeCompressed:
+		opts = append(opts, pack.WithStoreExtensions())
+	case len(storeExts) > 0:
+		opts = append(opts, pack.WithStoreExtensions(append(pack.DefaultStoreExtensions(), storeExts...)...))
+	}
+
+	if noBuildInfo, _ := cmd.Flags().GetBool("no-build-info"); !noBuildInfo {
+		recordHostname, _ := cmd.Flags().GetBool("record-hostname")
+		opts = append(opts, pack.WithBuildInfo(buildinfo.New(version, recordHostname)))
+	}
+
+	if cmd.Flags().Changed("encryption-key") || cmd.Flags().Changed("mac-key") || cmd.Flags().Changed("iv") {
+		encKey, _ := cmd.Flags().GetBytesBase64("encryption-key")
+		macKey, _ := cmd.Flags().GetBytesBase64("mac-key")
+		iv, _ := cmd.Flags().GetBytesBase64("iv")
+		if err := crypto.ValidateKeys(encKey, macKey, iv); err != nil {
+			return nil, fmt.Errorf("failed to use --encryption-key, --mac-key and --iv: %w", err)
+		}
+		opts = append(opts, pack.WithEncryptionKeys(encKey, macKey, iv))
+	}
+
+	preHooks, _ := cmd.Flags().GetStringArray("pre-pack-hook")
+	for _, line := range preHooks {
+		opts = append(opts, pack.WithPrePackHook(hook.Command(line, os.Stderr)))
+	}
+	postHooks, _ := cmd.Flags().GetStringArray("post-pack-hook")
+	for _, line := range postHooks {
+		opts = append(opts, pack.WithPostPackHook(hook.Command(line, os.Stderr)))
+	}
+
+	return opts, nil
+}
+
+// spillSettings returns the temporary directory and spill threshold selected by --tmpdir and --spill-threshold
+func spillSettings(cmd *cobra.Command) (string, int64) {
+	tempDir, _ := cmd.Flags().GetString("tmpdir")
+	threshold, _ := cmd.Flags().GetInt64("spill-threshold")
+	return tempDir, threshold
+}
+
+// maxMemory is the value of --max-memory
+var maxMemory sizeValue
+
+// maxTotalSize and maxFileSize are the values of --max-total-size and --max-file-size
+var maxTotalSize, maxFileSize sizeValue
+
+// memoryLimit returns the limit selected by --max-memory, shared by everything the command buffers,
+// or nil when memory is not capped
+var memoryLimit = sync.OnceValue(func() *spill.Limit {
+	if maxMemory <= 0 {
+		return nil
+	}
+	return spill.NewLimit(int64(maxMemory))
+})
+
+// progressReporter creates the progress reporter selected by --progress, --progress-json and -vv.
+// The returned function must be called when packing is done.
+func progressReporter(cmd *cobra.Command) (progress.Reporter, func(), error) {
+	var reporters []progress.Reporter
+	var finishers []func()
+	finish := func() {
+		for _, f := range finishers {
+			f()
+		}
+	}
+
+	if showProgress, _ := cmd.Flags().GetBool("progress"); showProgress && isTerminal(os.Stdout) {
+		bar := progress.NewBar(os.Stdout)
+		reporters = append(reporters, bar)
+		finishers = append(finishers, bar.Finish)
+	}
+
+	if target, _ := cmd.Flags().GetString("progress-json"); target != "" {
+		w := os.Stderr
+		if target != "-" {
+			// A named pipe is opened as is, any other path is created
+			f, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600) // #nosec G304 -- path is chosen by the user
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to open progress output: %w", err)
+			}
+			w = f
+			finishers = append(finishers, func() { f.Close() })
+		}
+		reporters = append(reporters, progress.NewJSON(w))
+	}
+
+	if verbosity >= 2 {
+		l := progress.NewLog(logger)
+		reporters = append(reporters, l)
+		finishers = append(finishers, l.Finish)
+	}
+
+	switch len(reporters) {
+	case 0:
+		return nil, finish, nil
+	case 1:
+		return reporters[0], finish, nil
+	default:
+		return progress.Multi(reporters...), finish, nil
+	}
+}
+
+// addPackFlags adds the flags read by packOptions and packInfo to cmd
+func addPackFlags(cmd *cobra.Command) {
+	cmd.Flags().String("name", "", "application name recorded in Detection.xml (default is the product name of an .exe setup file, or the name of the source folder)")
+	cmd.Flags().String("description", "", "description recorded in Detection.xml (default is the product name and version of an .exe setup file)")
+	cmd.Flags().String("setup-file", "", "setup file, relative to the source folder, recorded in Detection.xml; it must exist in the content (default is detected)")
+	addPackOptionFlags(cmd)
+}
+
+// addPackOptionFlags adds the flags read by packOptions to cmd
+func addPackOptionFlags(cmd *cobra.Command) {
+	cmd.Flags().StringArray("exclude", nil, "leave files and folders matching this pattern out of the package, such as *.log or docs/drafts (repeatable)")
+	cmd.Flags().StringArray("add", nil, "also pack a file or folder, as <path>[:<dest>] with dest a path in the package (repeatable)")
+	cmd.Flags().Int("compression-level", -1, "deflate level from 0 (fastest) to 9 (smallest), -1 for the default")
+	cmd.Flags().Int("threads", 0, "number of files read and compressed concurrently (0 uses the number of CPUs)")
+	cmd.Flags().Bool("follow-symlinks", false, "pack the files and folders symbolic links point to (default)")
+	cmd.Flags().Bool("skip-symlinks", false, "leave symbolic links out of the package")
+	cmd.Flags().Bool("error-on-symlinks", false, "fail when the source folder contains a symbolic link")
+	cmd.MarkFlagsMutuallyExclusive("follow-symlinks", "skip-symlinks", "error-on-symlinks")
+	cmd.Flags().String("tool-version", "", "ToolVersion recorded in Detection.xml, such as 1.8.6.0 (default "+metadata.ToolVersion+")")
+	cmd.Flags().Bool("keep-empty-dirs", false, "pack folders that have no file below them, for installers that expect them (default)")
+	cmd.Flags().Bool("prune-empty-dirs", false, "leave out folders that have no file below them")
+	cmd.MarkFlagsMutuallyExclusive("keep-empty-dirs", "prune-empty-dirs")
+	cmd.Flags().Bool("posix-modes", false, "record Unix file modes such as the executable bit for round trips on Linux and macOS, in addition to MS-DOS attributes")
+	cmd.Flags().Bool("no-data-descriptors", false, "write the CRC and sizes of every entry in its local header, as the Microsoft tool does, for consumers that mishandle data descriptors")
+	cmd.Flags().Bool("dedupe-case", false, "keep the first of entries whose paths differ only in case, such as Readme.txt and README.TXT, instead of failing")
+	cmd.Flags().Bool("allow-invalid-names", false, "pack names Windows cannot create, such as CON, aux.txt or a name ending in a dot, with a warning instead of failing")
+	cmd.Flags().Bool("store-compressed", true, "store already compressed files (.msi, .cab, .zip, ...) without recompressing them")
+	cmd.Flags().StringSlice("store-ext", nil, "additional file extensions to store without compression")
+	cmd.Flags().Bool("reproducible", false, "produce deterministic output (honors SOURCE_DATE_EPOCH)")
+	cmd.Flags().Bool("no-build-info", false, "do not record the build environment and options in the package")
+	cmd.Flags().Bool("record-hostname", false, "include the host name of the packaging machine in the build information")
+	cmd.Flags().BytesBase64("encryption-key", nil, "base64 encoded 32 byte AES key to use instead of a random one")
+	cmd.Flags().BytesBase64("mac-key", nil, "base64 encoded 32 byte HMAC key to use instead of a random one")
+	cmd.Flags().BytesBase64("iv", nil, "base64 encoded 16 byte IV to use instead of a random one")
+	cmd.Flags().StringArray("pre-pack-hook", nil, "shell command to run before packing; a non-zero exit status aborts the pack (repeatable)")
+	cmd.Flags().StringArray("post-pack-hook", nil, "shell command to run after the package is written; a non-zero exit status fails the pack (repeatable)")
+}
+
 func init() {
+	rootCmd.PersistentFlags().String("log-level", "info", "minimum level of log messages: debug, info, warn or error")
+	rootCmd.PersistentFlags().String("log-format", "text", "format of log messages written to stderr: text or json")
+	rootCmd.PersistentFlags().Bool("json", false, "write errors to stderr as a JSON object with the message, kind and exit code")
+	rootCmd.PersistentFlags().String("lang", "", "language of help and error messages: en or ja (default is taken from LC_ALL, LC_MESSAGES or LANG)")
+	rootCmd.PersistentFlags().BoolP("quiet", "q", false, "only print warnings and errors (same as --log-level warn)")
+	rootCmd.PersistentFlags().CountP("verbose", "v", "print debug messages; repeat (-vv) to also log every processed file and the duration of each stage")
+	rootCmd.MarkFlagsMutuallyExclusive("quiet", "verbose")
+	rootCmd.PersistentFlags().String("config", "", "configuration file with default flag values (default is $"+envConfig+", or config.yaml in the user configuration directory)")
+	rootCmd.PersistentFlags().String("tmpdir", "", "directory for temporary files (default is the system temporary directory)")
+	rootCmd.PersistentFlags().Int64("spill-threshold", spill.DefaultThreshold, "size in bytes above which intermediate data is written to temporary files instead of memory")
+	rootCmd.PersistentFlags().Var(&maxMemory, "max-memory", "total size of intermediate data kept in memory before writing to temporary files, such as 512MB (default no limit)")
+
+	packCmd.Flags().Bool("progress", false, "show a progress bar when stdout is a terminal")
+	packCmd.Flags().String("catalog-folder", "", "folder of signed .cat files to add to the package, for apps deployed to Windows in S mode")
+	addProjectFlag(packCmd)
+	_ = packCmd.RegisterFlagCompletionFunc("catalog-folder", completeDir)
+	unpackCmd.Flags().Bool("strict", false, "fail on unrecognized Detection.xml versions and nonstandard packages instead of warning")
+	unpackCmd.Flags().Bool("no-verify", false, "do not check the size and digest of the decrypted content against Detection.xml")
+	unpackCmd.Flags().Bool("no-preserve", false, "do not restore the file modes and modification times recorded in the package")
+	unpackCmd.Flags().Bool("force", false, "replace files that already exist in the output folder (default)")
+	unpackCmd.Flags().Bool("skip-existing", false, "keep files that already exist in the output folder")
+	unpackCmd.Flags().Bool("fail-if-exists", false, "fail when a file already exists in the output folder")
+	unpackCmd.MarkFlagsMutuallyExclusive("force", "skip-existing", "fail-if-exists")
+	unpackCmd.Flags().String("format", archiveZip, "format of the content written to standard output: zip or tar")
+	registerFlagCompletion(unpackCmd, "format", archiveZip, archiveTar)
+	unpackCmd.Flags().StringArray("only", nil, "only extract entries matching this pattern, such as Files/** or *.ps1 (repeatable)")
+	addFilenameEncodingFlag(unpackCmd)
+	unpackCmd.Flags().Bool("allow-symlinks", false, "extract symbolic links that point inside the output folder instead of failing")
+	unpackCmd.Flags().Var(&maxTotalSize, "max-total-size", "fail when the extracted files total more than this size, such as 10GB (default no limit)")
+	unpackCmd.Flags().Var(&maxFileSize, "max-file-size", "fail when an extracted file is larger than this size, such as 2GB (default no limit)")
+	unpackCmd.Flags().Int("max-entries", 0, "fail when the content has more than this number of files and folders to extract (default no limit)")
+	unpackCmd.Flags().String("key-file", "", "decrypt with the keys of this file written by pack --export-keys, or - for standard input")
+	addKeyVaultFlag(unpackCmd, "decrypt with the keys pack --key-vault stored for the package in this Azure Key Vault, such as https://myvault.vault.azure.net")
+	unpackCmd.MarkFlagsMutuallyExclusive("key-file", "key-vault")
+	addAuthFlags(unpackCmd)
+	unpackCmd.Flags().Bool("metadata-only", false, "only write Detection.xml and its JSON rendering without decrypting the contents")
+
+	packCmd.Flags().String("progress-json", "", "write newline-delimited JSON progress events to this file or named pipe (stderr when given without a value)")
+	packCmd.Flags().Lookup("progress-json").NoOptDefVal = "-"
+	packCmd.Flags().String("output-json", "", "write a JSON summary of the package (path, SHA256, sizes, name, duration) to this file, or - for standard output")
+	packCmd.Flags().String("commit-json", "", "write the Graph commit request body (fileEncryptionInfo) to this file, or - for standard output")
+	packCmd.Flags().String("export-keys", "", "write the encryption info to this JSON file, encrypted with age (.age) or gpg (.gpg, .asc) to --export-keys-recipient")
+	packCmd.Flags().StringArray("export-keys-recipient", nil, "age recipient or gpg key ID the --export-keys file is encrypted to (repeatable)")
+	addKeyVaultFlag(packCmd, "store the encryption info in this Azure Key Vault, such as https://myvault.vault.azure.net, as a secret named after the package SHA256")
+	addAuthFlags(packCmd)
+	packCmd.Flags().String("format", archiveZip, "format of the archive read from standard input: zip or tar")
+	registerFlagCompletion(packCmd, "format", archiveZip, archiveTar)
+	addPackFlags(packCmd)
+	packCmd.Flags().Bool("skip-preflight", false, "do not run preflight checks before packing")
+
 	rootCmd.AddCommand(packCmd)
 	rootCmd.AddCommand(unpackCmd)
+	rootCmd.AddCommand(editCmd)
+	rootCmd.AddCommand(cacheCmd)
+	rootCmd.AddCommand(preflightCmd)
+	rootCmd.AddCommand(manifestCmd)
+	rootCmd.AddCommand(inspectCmd)
+	rootCmd.AddCommand(listCmd)
+	rootCmd.AddCommand(catCmd)
+	rootCmd.AddCommand(treeCmd)
+	rootCmd.AddCommand(analyzeCmd)
+	rootCmd.AddCommand(verifyCmd)
+	rootCmd.AddCommand(signCmd)
+	rootCmd.AddCommand(verifySignatureCmd)
+	rootCmd.AddCommand(sbomCmd)
+	rootCmd.AddCommand(initCmd)
+	rootCmd.AddCommand(iconCmd)
+	rootCmd.AddCommand(hashCmd)
+	rootCmd.AddCommand(rulesCmd)
+	rootCmd.AddCommand(appJSONCmd)
+	rootCmd.AddCommand(authCmd)
+	rootCmd.AddCommand(commitJSONCmd)
+	rootCmd.AddCommand(decryptCmd)
+	rootCmd.AddCommand(publishCmd)
+	rootCmd.AddCommand(assignCmd)
+	rootCmd.AddCommand(serveCmd)
+	rootCmd.AddCommand(watchCmd)
+	rootCmd.AddCommand(batchCmd)
+	rootCmd.AddCommand(docsCmd)
+	rootCmd.AddCommand(versionCmd)
+	rootCmd.AddCommand(doctorCmd)
 }
 
 func main() {
-	if err := rootCmd.Execute(); err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+	cmd, err := rootCmd.ExecuteC()
+	if err == nil {
+		return
+	}
+	// Cobra reports invalid flags, arguments and unknown commands before the pre-run hook
+	if !started {
+		err = &usageError{err: err}
 	}
+	setLanguage(cmd)
+	asJSON, _ := rootCmd.PersistentFlags().GetBool("json")
+	code := exitCode(err)
+	reportError(os.Stderr, cmd, err, code, asJSON)
+	os.Exit(code)
 }
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/kenchan0130/intunewin/internal/manifest"
+	"github.com/kenchan0130/intunewin/internal/unpack"
+	"github.com/spf13/cobra"
+)
+
+var manifestCmd = &cobra.Command{
+	Use:   "manifest <file.intunewin>",
+	Short: "Export a JSON manifest describing an intunewin file",
+	Long: `Manifest reads the metadata of an intunewin file and writes a JSON manifest
+for upload tooling. Installer return codes default to the values Intune uses
+for new Win32 apps and can be customized with --return-code or a YAML/JSON
+file with a top-level returnCodes list.
+
+The manifest also contains an estimate of the disk space needed on the device,
+the unencrypted content size times --footprint-multiplier, to use as the free
+disk space requirement.
+
+Example:
+  intunewin manifest myapp.intunewin --return-code 3010=success --return-code 5=retry
+  intunewin manifest myapp.intunewin --return-codes codes.yaml -o manifest.json`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeArgsUpTo(completePackage),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		inputFile := args[0]
+		outputFile, _ := cmd.Flags().GetString("output")
+
+		returnCodes, err := returnCodesFromFlags(cmd)
+		if err != nil {
+			return err
+		}
+
+		appInfo, err := unpack.ReadApplicationInfo(inputFile)
+		if err != nil {
+			return fmt.Errorf("failed to read metadata: %w", err)
+		}
+
+		multiplier, _ := cmd.Flags().GetFloat64("footprint-multiplier")
+		footprint, err := manifest.EstimateFootprint(appInfo.UnencryptedContentSize, multiplier)
+		if err != nil {
+			return err
+		}
+
+		data, err := manifest.New(appInfo, returnCodes, footprint).ToJSON()
+		if err != nil {
+			return err
+		}
+		data = append(data, '\n')
+
+		if outputFile == "" {
+			_, err = os.Stdout.Write(data)
+			return err
+		}
+		if err := os.WriteFile(outputFile, data, 0600); err != nil {
+			return fmt.Errorf("failed to write manifest: %w", err)
+		}
+		return nil
+	},
+}
+
+// returnCodesFromFlags merges the default return codes with the ones given on the command line
+func returnCodesFromFlags(cmd *cobra.Command) ([]manifest.ReturnCode, error) {
+	returnCodes := manifest.DefaultReturnCodes()
+
+	if path, _ := cmd.Flags().GetString("return-codes"); path != "" {
+		codes, err := manifest.LoadReturnCodes(path)
+		if err != nil {
+			return nil, err
+		}
+		returnCodes = manifest.MergeReturnCodes(returnCodes, codes)
+	}
+
+	values, _ := cmd.Flags().GetStringArray("return-code")
+	codes := make([]manifest.ReturnCode, 0, len(values))
+	for _, value := range values {
+		rc, err := manifest.ParseReturnCode(value)
+		if err != nil {
+			return nil, err
+		}
+		codes = append(codes, rc)
+	}
+	return manifest.MergeReturnCodes(returnCodes, codes), nil
+}
+
+func init() {
+	manifestCmd.Flags().StringP("output", "o", "", "write the manifest to this file instead of stdout")
+	manifestCmd.Flags().StringArray("return-code", nil, "custom installer return code as <code>=<type> (repeatable)")
+	manifestCmd.Flags().String("return-codes", "", "YAML or JSON file with a returnCodes list")
+	manifestCmd.Flags().Float64("footprint-multiplier", manifest.DefaultFootprintMultiplier, "factor applied to the unencrypted content size to estimate the install footprint")
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/kenchan0130/intunewin/internal/httpclient"
+	"github.com/spf13/cobra"
+)
+
+// addNetworkFlags adds the flags read by httpClient
+func addNetworkFlags(cmd *cobra.Command) {
+	cmd.Flags().String("proxy", "", "proxy URL for all requests (default is $HTTPS_PROXY or $HTTP_PROXY, except for hosts in $NO_PROXY)")
+	cmd.Flags().String("ca-bundle", "", "PEM file with CA certificates to trust in addition to the system roots, such as the CA of a TLS-intercepting proxy")
+	_ = cmd.RegisterFlagCompletionFunc("ca-bundle", completeExt("pem", "crt", "cer"))
+}
+
+// httpClient returns the HTTP client of network requests configured by the flags of
+// addNetworkFlags
+func httpClient(cmd *cobra.Command) (*http.Client, error) {
+	proxy, _ := cmd.Flags().GetString("proxy")
+	caBundle, _ := cmd.Flags().GetString("ca-bundle")
+	client, err := httpclient.New(httpclient.Config{Proxy: proxy, CABundle: caBundle})
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure network: %w", err)
+	}
+	return client, nil
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"os"
+
+	"github.com/kenchan0130/intunewin/internal/render"
+	"github.com/spf13/cobra"
+)
+
+// addOutputFlag adds the --output flag shared by read-only commands
+func addOutputFlag(cmd *cobra.Command) {
+	cmd.Flags().String("output", string(render.FormatTable), "output format: table, json, yaml or csv")
+	registerFlagCompletion(cmd, "output", outputFormatNames()...)
+}
+
+// outputFormat returns the format selected with --output
+func outputFormat(cmd *cobra.Command) (render.Format, error) {
+	value, _ := cmd.Flags().GetString("output")
+	return render.ParseFormat(value) //nolint:wrapcheck // render errors already describe the failure
+}
+
+// writeOutput renders v to stdout in format
+func writeOutput(format render.Format, v render.Tabular) error {
+	return render.Write(os.Stdout, format, v) //nolint:wrapcheck // render errors already describe the failure
+}
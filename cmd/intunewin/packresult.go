@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/kenchan0130/intunewin/internal/pack"
+	"github.com/kenchan0130/intunewin/internal/render"
+)
+
+// packResult is the JSON summary written by pack --output-json
+type packResult struct {
+	Output          string  `json:"output"`
+	SHA256          string  `json:"sha256"`
+	UnencryptedSize int64   `json:"unencryptedSize"`
+	FileCount       int     `json:"fileCount"`
+	Name            string  `json:"name"`
+	SetupFile       string  `json:"setupFile"`
+	DurationSeconds float64 `json:"durationSeconds"`
+}
+
+// newPackResult describes the package written to outputFile
+func newPackResult(outputFile, name, setupFile string, summary *pack.Summary, duration time.Duration) packResult {
+	return packResult{
+		Output:          outputFile,
+		SHA256:          summary.SHA256,
+		UnencryptedSize: summary.UnencryptedSize,
+		FileCount:       summary.Files,
+		Name:            name,
+		SetupFile:       setupFile,
+		DurationSeconds: duration.Seconds(),
+	}
+}
+
+// Table lists the fields of r as rows
+func (r packResult) Table() render.Table {
+	return render.Table{Columns: []string{"Field", "Value"}, Rows: [][]string{
+		{"Output", r.Output},
+		{"SHA256", r.SHA256},
+		{"Unencrypted size", fmt.Sprintf("%d bytes", r.UnencryptedSize)},
+		{"File count", fmt.Sprintf("%d", r.FileCount)},
+		{"Name", r.Name},
+		{"Setup file", r.SetupFile},
+		{"Duration", fmt.Sprintf("%.3fs", r.DurationSeconds)},
+	}}
+}
+
+// writePackResult writes r as JSON to target, a file path or stdioArg
+func writePackResult(target string, r packResult) error {
+	if target == stdioArg {
+		return render.Write(os.Stdout, render.FormatJSON, r) //nolint:wrapcheck // render errors already describe the failure
+	}
+
+	f, err := os.Create(target) // #nosec G304 -- path is chosen by the user
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", target, err)
+	}
+	defer f.Close()
+	if err := render.Write(f, render.FormatJSON, r); err != nil {
+		return err //nolint:wrapcheck // render errors already describe the failure
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", target, err)
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/kenchan0130/intunewin/internal/preflight"
+	"github.com/spf13/cobra"
+)
+
+var preflightCmd = &cobra.Command{
+	Use:   "preflight <source-folder>",
+	Short: "Check a source folder for common packaging problems",
+	Long: `Preflight scans a source folder for problems that make packages fail on
+managed devices, such as scripts that reference user profile paths or
+machine names of the packaging machine. The same checks run before pack.
+
+Example:
+  intunewin preflight ./myapp`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeArgsUpTo(completeDir),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		report, err := preflight.Run(args[0])
+		if err != nil {
+			return fmt.Errorf("failed to run preflight checks: %w", err)
+		}
+
+		report.Print(os.Stdout)
+		if report.HasErrors() {
+			return fmt.Errorf("%w: preflight checks reported errors", errValidation)
+		}
+		if len(report.Findings) == 0 {
+			fmt.Println("No problems found")
+		}
+		return nil
+	},
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/kenchan0130/intunewin/internal/config"
+	"github.com/kenchan0130/intunewin/internal/manifest"
+	"github.com/kenchan0130/intunewin/internal/project"
+	"github.com/spf13/cobra"
+)
+
+// addProjectFlag adds the --project flag read by applyProject to cmd
+func addProjectFlag(cmd *cobra.Command) {
+	cmd.Flags().String("project", "", "project file with the packaging configuration (default is intunewin.yaml in the source folder)")
+	_ = cmd.RegisterFlagCompletionFunc("project", completeExt("yaml", "yml"))
+}
+
+// applyProject sets the flags of cmd not given on the command line from the project file
+// given by --project, or from the intunewin.yaml of the source folder in args. --project
+// is set to the file used, so that later steps such as detectionRulesFromFlags read it too.
+func applyProject(cmd *cobra.Command, args []string) error {
+	flag := cmd.Flags().Lookup("project")
+	if flag == nil {
+		return nil
+	}
+	path := flag.Value.String()
+	if path == "" {
+		if len(args) == 0 || args[0] == stdioArg {
+			return nil
+		}
+		if info, err := os.Stat(args[0]); err != nil || !info.IsDir() {
+			return nil
+		}
+		path = filepath.Join(args[0], project.FileName)
+		if _, err := os.Stat(path); errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+	}
+
+	p, err := project.Load(path)
+	if err != nil {
+		return err //nolint:wrapcheck // project errors already describe the failure
+	}
+	if err := cmd.Flags().Set("project", path); err != nil {
+		return err //nolint:wrapcheck // cannot fail for a string flag
+	}
+	for name, values := range p.Flags() {
+		f := cmd.Flags().Lookup(name)
+		if f == nil || f.Changed || conflictsWithChanged(cmd.Flags(), f) {
+			continue
+		}
+		if err := config.Set(cmd.Flags(), f, values); err != nil {
+			return fmt.Errorf("%w: %s: %s: %w", project.ErrInvalidProject, path, name, err)
+		}
+	}
+	return nil
+}
+
+// projectDetectionRules returns the detection rules of the project file applied to cmd
+func projectDetectionRules(cmd *cobra.Command) ([]manifest.DetectionRule, error) {
+	path, _ := cmd.Flags().GetString("project")
+	if path == "" {
+		return nil, nil
+	}
+	p, err := project.Load(path)
+	if err != nil {
+		return nil, err //nolint:wrapcheck // project errors already describe the failure
+	}
+	return p.Detection.Rules() //nolint:wrapcheck // manifest errors already describe the failure
+}
@@ -0,0 +1,253 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/kenchan0130/intunewin/internal/dirs"
+	"github.com/kenchan0130/intunewin/internal/graph"
+	"github.com/kenchan0130/intunewin/internal/keyvault"
+	"github.com/kenchan0130/intunewin/internal/pack"
+	"github.com/kenchan0130/intunewin/internal/publish"
+	"github.com/kenchan0130/intunewin/internal/retry"
+	"github.com/kenchan0130/intunewin/internal/unpack"
+	"github.com/spf13/cobra"
+)
+
+var publishCmd = &cobra.Command{
+	Use:   "publish <file.intunewin|source-folder>",
+	Short: "Upload a package to Intune as a Win32 app",
+	Long: `Publish creates a Win32 app in Intune from an intunewin file, uploads its
+encrypted contents and commits them, so the app is ready to deploy. A source
+folder is packed first, with its setup file detected unless --setup-file is
+given.
+
+The app is described as appjson describes it and accepts the same flags. With
+--assign the app is assigned once its content is committed, so one command
+takes a folder to a targeted deployment. Each --assign is
+<intent>:<target>[:<include|exclude>:<filter-id>] where the intent is
+required, available or uninstall and the target is the object ID of an Entra
+group, all-users or all-devices.
+
+The IDs of the created app, content version and assignments are written to
+stdout as JSON. Signing in works as for "auth login".
+
+When the upload of an intunewin file is interrupted, such as by a dropped
+connection, publishing the same file again resumes the upload to the app
+created before; --no-resume starts over with a new app instead.
+
+With --key-vault the content is committed with the keys pack --key-vault
+stored in Azure Key Vault for the package, and the keys of a packed source
+folder are stored there.
+
+Example:
+  intunewin publish ./myapp --publisher Contoso --assign required:<group-id>
+  intunewin publish myapp.intunewin --auth client-secret --assign available:all-users:include:<filter-id>`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeArgsUpTo(completePackage),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		input := args[0]
+
+		assignments, err := assignmentsFromFlags(cmd)
+		if err != nil {
+			return err
+		}
+		graphAPI, err := graphClient(cmd)
+		if err != nil {
+			return err
+		}
+		vault, err := keyVaultClient(cmd)
+		if err != nil {
+			return err
+		}
+
+		info, err := os.Stat(input)
+		if err != nil {
+			return fmt.Errorf("failed to access %s: %w", input, err)
+		}
+		packageFile := input
+		if info.IsDir() {
+			tempDir, _ := spillSettings(cmd)
+			dir, err := os.MkdirTemp(tempDir, "intunewin-publish-*")
+			if err != nil {
+				return fmt.Errorf("failed to create temporary directory: %w", err)
+			}
+			defer os.RemoveAll(dir)
+
+			if packageFile, err = packForPublish(cmd, input, dir); err != nil {
+				return err
+			}
+		}
+
+		app, err := win32AppFromFlags(cmd, packageFile)
+		if err != nil {
+			return err
+		}
+		if app.Publisher == "" {
+			return fmt.Errorf("the publisher is required by Intune, set it with --publisher")
+		}
+
+		reporter, finish, err := progressReporter(cmd)
+		if err != nil {
+			return err
+		}
+		client, err := httpClient(cmd)
+		if err != nil {
+			return err
+		}
+		tempDir, threshold := spillSettings(cmd)
+		opts := []publish.Option{
+			publish.WithHTTPClient(client),
+			publish.WithLogger(logger),
+			publish.WithProgress(reporter),
+			publish.WithAssignments(assignments...),
+			publish.WithRetry(retryPolicy(cmd)),
+			publish.WithTempDir(tempDir),
+			publish.WithSpillThreshold(threshold),
+		}
+		if noResume, _ := cmd.Flags().GetBool("no-resume"); !noResume {
+			layout, err := dirs.Resolve()
+			if err != nil {
+				return fmt.Errorf("failed to resolve directories: %w", err)
+			}
+			opts = append(opts, publish.WithResumeDir(filepath.Join(layout.State, "uploads")))
+		}
+		if vault != nil {
+			if err := keyVaultKeys(cmd, vault, packageFile, info.IsDir(), &opts); err != nil {
+				return err
+			}
+		}
+
+		result, err := publish.Publish(cmd.Context(), graphAPI, packageFile, app, opts...)
+		finish()
+		if err != nil {
+			if result != nil {
+				logger.Warn("the app was created but publishing did not finish; publish the package again to resume, or delete the app", "app", result.AppID)
+			}
+			return fmt.Errorf("failed to publish: %w", err)
+		}
+		logger.Info("successfully published app", "app", result.AppID)
+		return writeJSONResult(result)
+	},
+}
+
+// packForPublish packs sourceFolder into dir and returns the path of the package
+func packForPublish(cmd *cobra.Command, sourceFolder, dir string) (string, error) {
+	tempDir, threshold := spillSettings(cmd)
+	opts := []pack.Option{
+		pack.WithLogger(logger),
+		pack.WithTempDir(tempDir),
+		pack.WithSpillThreshold(threshold),
+		pack.WithMemoryLimit(memoryLimit()),
+	}
+	if excludes, _ := cmd.Flags().GetStringArray("exclude"); len(excludes) > 0 {
+		opts = append(opts, pack.WithExclude(excludes...))
+	}
+	name, setupFile, opts, err := packInfo(cmd, sourceFolder, opts)
+	if err != nil {
+		return "", err
+	}
+
+	packageFile := filepath.Join(dir, filepath.Base(sourceFolder)+".intunewin")
+	logger.Info("packing", "source", sourceFolder, "name", name, "setupFile", setupFile)
+	if err := pack.PackWithInfo(sourceFolder, packageFile, name, setupFile, opts...); err != nil {
+		return "", fmt.Errorf("failed to pack: %w", err)
+	}
+	return packageFile, nil
+}
+
+// keyVaultKeys stores the keys of a package packed from a source folder in vault, or adds
+// the keys vault holds for an existing package to opts
+func keyVaultKeys(cmd *cobra.Command, vault *keyvault.Client, packageFile string, packed bool, opts *[]publish.Option) error {
+	digest, err := keyvault.PackageDigest(packageFile)
+	if err != nil {
+		return err //nolint:wrapcheck // keyvault errors already describe the failure
+	}
+	if packed {
+		appInfo, err := unpack.ReadApplicationInfo(packageFile)
+		if err != nil {
+			return fmt.Errorf("failed to read metadata: %w", err)
+		}
+		return storeKeys(cmd.Context(), vault, digest, appInfo)
+	}
+	info, err := loadKeys(cmd.Context(), vault, digest)
+	if err != nil {
+		return err
+	}
+	*opts = append(*opts, publish.WithEncryptionInfo(info))
+	return nil
+}
+
+// graphClient returns a Graph client signed in as selected by the flags of addGraphFlags
+func graphClient(cmd *cobra.Command) (*graph.Client, error) {
+	tokens, err := tokenSource(cmd)
+	if err != nil {
+		return nil, err
+	}
+	client, err := httpClient(cmd)
+	if err != nil {
+		return nil, err
+	}
+	baseURL, _ := cmd.Flags().GetString("graph-url")
+	return graph.NewClient(tokens,
+		graph.WithLogger(logger),
+		graph.WithHTTPClient(client),
+		graph.WithBaseURL(baseURL),
+		graph.WithRetry(retryPolicy(cmd)),
+	), nil
+}
+
+// retryPolicy returns the retry policy of Graph and upload requests set by --max-retries
+// and --max-retry-delay
+func retryPolicy(cmd *cobra.Command) retry.Policy {
+	policy := retry.DefaultPolicy
+	policy.MaxRetries, _ = cmd.Flags().GetInt("max-retries")
+	policy.MaxDelay, _ = cmd.Flags().GetDuration("max-retry-delay")
+	return policy
+}
+
+// addGraphFlags adds the flags read by graphClient
+func addGraphFlags(cmd *cobra.Command) {
+	addAuthFlags(cmd)
+	cmd.Flags().String("graph-url", graph.DefaultBaseURL, "Microsoft Graph endpoint, for national clouds")
+	cmd.Flags().Int("max-retries", retry.DefaultPolicy.MaxRetries, "how many times a throttled or failed request is sent again")
+	cmd.Flags().Duration("max-retry-delay", retry.DefaultPolicy.MaxDelay, "longest wait between two attempts of a request, even when the service asks for more")
+}
+
+// assignmentsFromFlags parses the --assign flags
+func assignmentsFromFlags(cmd *cobra.Command) ([]graph.Assignment, error) {
+	values, _ := cmd.Flags().GetStringArray("assign")
+	assignments := make([]graph.Assignment, 0, len(values))
+	for _, value := range values {
+		a, err := graph.ParseAssignment(value)
+		if err != nil {
+			return nil, err //nolint:wrapcheck // graph errors already describe the failure
+		}
+		assignments = append(assignments, a)
+	}
+	return assignments, nil
+}
+
+// writeJSONResult writes v as indented JSON to stdout
+func writeJSONResult(v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal result: %w", err)
+	}
+	_, err = os.Stdout.Write(append(data, '\n'))
+	return err //nolint:wrapcheck // stdout write errors need no context
+}
+
+func init() {
+	publishCmd.Flags().String("setup-file", "", "setup file, relative to the source folder (default is detected)")
+	publishCmd.Flags().StringArray("exclude", nil, "leave files and folders matching this pattern out when packing a source folder (repeatable)")
+	addProjectFlag(publishCmd)
+	publishCmd.Flags().StringArray("assign", nil, "assignment as <intent>:<group-id|all-users|all-devices>[:<include|exclude>:<filter-id>] (repeatable)")
+	publishCmd.Flags().Bool("progress", false, "show a progress bar when stdout is a terminal")
+	publishCmd.Flags().Bool("no-resume", false, "create a new app and upload everything even when an earlier upload of the package was interrupted")
+	addKeyVaultFlag(publishCmd, "commit the keys stored in this Azure Key Vault for the package, or store them there when a source folder is packed")
+	addWin32AppFlags(publishCmd)
+	addGraphFlags(publishCmd)
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/kenchan0130/intunewin/internal/manifest"
+	"github.com/kenchan0130/intunewin/internal/msi"
+	"github.com/kenchan0130/intunewin/internal/pack"
+	"github.com/kenchan0130/intunewin/internal/pe"
+	"github.com/kenchan0130/intunewin/internal/unpack"
+	"github.com/spf13/cobra"
+)
+
+var rulesCmd = &cobra.Command{
+	Use:   "rules",
+	Short: "Generate Intune app rules",
+}
+
+var rulesGenerateCmd = &cobra.Command{
+	Use:   "generate <file.intunewin|source-folder>",
+	Short: "Generate Graph detection rules for a package",
+	Long: `Generate writes the detectionRules JSON array of a Graph win32LobApp for the
+setup file of an intunewin file or source folder:
+
+  - an MSI is detected by its product code (and with --msi-version, its
+    ProductVersion or later)
+  - an EXE is detected by a file in --install-path (default
+    %ProgramFiles%\<product name>) named --file-name (default the setup file
+    name), at its file version or later when it has version information
+
+File and registry rules from a YAML or JSON --config file with top-level files
+and registry lists are appended; use --no-setup-rule to only write those.
+
+Example:
+  intunewin rules generate myapp.intunewin
+  intunewin rules generate ./myapp --install-path '%ProgramFiles%\Contoso' --file-name app.exe
+  intunewin rules generate myapp.intunewin --config rules.yaml -o rules.json`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeArgsUpTo(completePackage),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		outputFile, _ := cmd.Flags().GetString("output")
+
+		rules, _, err := detectionRulesFromFlags(cmd, args[0])
+		if err != nil {
+			return err
+		}
+
+		data, err := manifest.DetectionRulesToJSON(rules)
+		if err != nil {
+			return err //nolint:wrapcheck // manifest errors already describe the failure
+		}
+		data = append(data, '\n')
+
+		if outputFile == "" {
+			_, err = os.Stdout.Write(data)
+			return err
+		}
+		if err := os.WriteFile(outputFile, data, 0600); err != nil {
+			return fmt.Errorf("failed to write detection rules: %w", err)
+		}
+		return nil
+	},
+}
+
+// setupFileReader is the content of a setup file
+type setupFileReader interface {
+	io.ReaderAt
+	io.Closer
+}
+
+// openSetupFile opens the setup file of an intunewin file or source folder and returns
+// it with its name and the application name
+func openSetupFile(cmd *cobra.Command, input string) (setupFileReader, string, string, error) {
+	info, err := os.Stat(input)
+	if err != nil {
+		return nil, "", "", fmt.Errorf("failed to access %s: %w", input, err)
+	}
+
+	if !info.IsDir() {
+		appInfo, err := unpack.ReadApplicationInfo(input, unpackOptions(cmd)...)
+		if err != nil {
+			return nil, "", "", err //nolint:wrapcheck // wrapped by the caller
+		}
+		r, name, err := unpack.OpenFile(input, appInfo.SetupFile, unpackOptions(cmd)...)
+		if err != nil {
+			return nil, "", "", err //nolint:wrapcheck // wrapped by the caller
+		}
+		return r, name, appInfo.Name, nil
+	}
+
+	setupFile, _ := cmd.Flags().GetString("setup-file")
+	if setupFile == "" {
+		if setupFile, err = pack.DetectSetupFile(input); err != nil {
+			return nil, "", "", fmt.Errorf("failed to detect the setup file, pass --setup-file: %w", err)
+		}
+	}
+	f, err := os.Open(filepath.Join(input, filepath.FromSlash(setupFile))) // #nosec G304 -- path is chosen by the user
+	if err != nil {
+		return nil, "", "", fmt.Errorf("failed to open setup file: %w", err)
+	}
+	return f, filepath.ToSlash(setupFile), filepath.Base(input), nil
+}
+
+// setupInfo is what a setup file tells about the app it installs
+type setupInfo struct {
+	// file is the setup file relative to the content root
+	file string
+	// appName is the name of the package or source folder
+	appName string
+	// msiProperties is the Property table of an MSI setup
+	msiProperties map[string]string
+	// version is the version information of an EXE setup, when it has one
+	version *pe.VersionInfo
+}
+
+// readSetupInfo reads the MSI properties or the version information of the setup file
+// of input
+func readSetupInfo(cmd *cobra.Command, input string) (*setupInfo, error) {
+	r, setupFile, appName, err := openSetupFile(cmd, input)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	setup := &setupInfo{file: setupFile, appName: appName}
+	switch strings.ToLower(path.Ext(setupFile)) {
+	case ".msi":
+		if setup.msiProperties, err = msi.ReadProperties(r); err != nil {
+			return nil, err //nolint:wrapcheck // wrapped by the caller
+		}
+	case ".exe":
+		info, err := pe.ReadVersion(r)
+		switch {
+		case err == nil:
+			setup.version = info
+		case !errors.Is(err, pe.ErrNoVersion):
+			logger.Warn("failed to read setup file version", "setupFile", setupFile, "error", err)
+		}
+	}
+	return setup, nil
+}
+
+// setupDetectionRule derives a detection rule from a setup file
+func setupDetectionRule(cmd *cobra.Command, setup *setupInfo) (manifest.DetectionRule, error) {
+	switch strings.ToLower(path.Ext(setup.file)) {
+	case ".msi":
+		productCode := setup.msiProperties[msi.PropertyProductCode]
+		if productCode == "" {
+			return nil, fmt.Errorf("%s has no ProductCode", setup.file)
+		}
+		var version string
+		if matchVersion, _ := cmd.Flags().GetBool("msi-version"); matchVersion {
+			version = setup.msiProperties[msi.PropertyProductVersion]
+		}
+		return manifest.NewProductCodeDetection(productCode, version), nil
+
+	case ".exe":
+		appName := setup.appName
+		var version string
+		if setup.version != nil {
+			version = setup.version.FileVersion
+			if product := setup.version.ProductName(); product != "" {
+				appName = product
+			}
+		}
+
+		folder, _ := cmd.Flags().GetString("install-path")
+		if folder == "" {
+			folder = `%ProgramFiles%\` + appName
+		}
+		fileName, _ := cmd.Flags().GetString("file-name")
+		if fileName == "" {
+			fileName = path.Base(setup.file)
+		}
+		return manifest.NewFileDetection(folder, fileName, version), nil
+
+	default:
+		return nil, fmt.Errorf("no detection rule can be derived from %s, use --config with --no-setup-rule", setup.file)
+	}
+}
+
+// detectionRulesFromFlags returns the rule derived from the setup file of input unless
+// --no-setup-rule is set, followed by the rules of --config
+func detectionRulesFromFlags(cmd *cobra.Command, input string) ([]manifest.DetectionRule, *setupInfo, error) {
+	setup, err := readSetupInfo(cmd, input)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read setup file: %w", err)
+	}
+
+	var rules []manifest.DetectionRule
+	if skip, _ := cmd.Flags().GetBool("no-setup-rule"); !skip {
+		rule, err := setupDetectionRule(cmd, setup)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to generate detection rule: %w", err)
+		}
+		rules = append(rules, rule)
+	}
+	if config, _ := cmd.Flags().GetString("config"); config != "" {
+		configRules, err := manifest.LoadDetectionRules(config)
+		if err != nil {
+			return nil, nil, err //nolint:wrapcheck // manifest errors already describe the failure
+		}
+		rules = append(rules, configRules...)
+	}
+	projectRules, err := projectDetectionRules(cmd)
+	if err != nil {
+		return nil, nil, err
+	}
+	return append(rules, projectRules...), setup, nil
+}
+
+// addDetectionRuleFlags adds the flags read by detectionRulesFromFlags
+func addDetectionRuleFlags(cmd *cobra.Command) {
+	cmd.Flags().String("config", "", "YAML or JSON file with files and registry detection rule lists to append")
+	cmd.Flags().Bool("no-setup-rule", false, "only use the detection rules of --config")
+	cmd.Flags().Bool("msi-version", false, "require the ProductVersion of the MSI or later")
+	cmd.Flags().String("install-path", "", `folder of the installed file for EXE setups (default %ProgramFiles%\<product name>)`)
+	cmd.Flags().String("file-name", "", "installed file to detect for EXE setups (default is the setup file name)")
+	cmd.Flags().Bool("strict", false, "fail on unrecognized Detection.xml versions and nonstandard packages instead of warning")
+}
+
+func init() {
+	rulesGenerateCmd.Flags().StringP("output", "o", "", "write the rules to this file instead of stdout")
+	rulesGenerateCmd.Flags().String("setup-file", "", "setup file, relative to the source folder (default is detected)")
+	addDetectionRuleFlags(rulesGenerateCmd)
+	rulesCmd.AddCommand(rulesGenerateCmd)
+}
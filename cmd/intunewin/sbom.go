@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/kenchan0130/intunewin/internal/dirs"
+	"github.com/kenchan0130/intunewin/internal/pathutil"
+	"github.com/kenchan0130/intunewin/internal/sbom"
+	"github.com/kenchan0130/intunewin/internal/unpack"
+	"github.com/spf13/cobra"
+)
+
+var sbomCmd = &cobra.Command{
+	Use:   "sbom <file.intunewin|source-folder>",
+	Short: "Generate a software bill of materials of the packaged files",
+	Long: `Sbom writes a software bill of materials listing every packaged file with its
+size and SHA-1 and SHA-256 digests. Executables and MSI installers also carry
+their product name, version and supplier, read from the version resource or
+the Property table.
+
+The input is an intunewin file, which is decrypted to a temporary folder, or a
+source folder. The document is CycloneDX 1.5 JSON, or SPDX 2.3 JSON with
+--format spdx.
+
+Example:
+  intunewin sbom myapp.intunewin > myapp.cdx.json
+  intunewin sbom myapp.intunewin --format spdx -o myapp.spdx.json
+  intunewin sbom ./myapp`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeArgsUpTo(completePackage),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		formatName, _ := cmd.Flags().GetString("format")
+		format, err := sbom.ParseFormat(formatName)
+		if err != nil {
+			return &usageError{err: err}
+		}
+		outputFile, _ := cmd.Flags().GetString("output")
+
+		files, opts, err := scanInput(cmd, args[0])
+		if err != nil {
+			return fmt.Errorf("failed to generate SBOM: %w", err)
+		}
+		opts = append(opts, sbom.WithToolVersion(version))
+
+		var w io.Writer = os.Stdout
+		if outputFile != "" {
+			f, err := os.Create(outputFile) // #nosec G304 -- path is chosen by the user
+			if err != nil {
+				return fmt.Errorf("failed to create output file: %w", err)
+			}
+			defer f.Close()
+			w = f
+		}
+		if err := sbom.Write(w, format, files, opts...); err != nil {
+			return err //nolint:wrapcheck // sbom errors already describe the failure
+		}
+		logger.Debug("generated SBOM", "input", args[0], "files", len(files), "format", format)
+		return nil
+	},
+}
+
+// scanInput scans the files of a source folder, or of an intunewin file unpacked to a
+// temporary folder, and returns the options naming the application they belong to
+func scanInput(cmd *cobra.Command, input string) ([]sbom.File, []sbom.Option, error) {
+	info, err := os.Stat(input)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to access %s: %w", input, err)
+	}
+	if info.IsDir() {
+		files, err := sbom.Scan(input)
+		if err != nil {
+			return nil, nil, err //nolint:wrapcheck // wrapped by the caller
+		}
+		abs, err := filepath.Abs(input)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to resolve %s: %w", input, err)
+		}
+		return files, []sbom.Option{sbom.WithName(filepath.Base(abs))}, nil
+	}
+
+	appInfo, err := unpack.ReadApplicationInfo(input, unpackOptions(cmd)...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read metadata: %w", err)
+	}
+	layout, err := dirs.Resolve()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to resolve directories: %w", err)
+	}
+	ws, err := layout.NewWorkspace("sbom-*")
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create workspace: %w", err)
+	}
+	defer ws.Remove()
+	workspace := ws.Dir
+
+	if err := unpack.Unpack(input, workspace, unpackOptions(cmd)...); err != nil {
+		return nil, nil, fmt.Errorf("failed to unpack: %w", err)
+	}
+	files, err := sbom.Scan(workspace)
+	if err != nil {
+		return nil, nil, err //nolint:wrapcheck // wrapped by the caller
+	}
+
+	opts := []sbom.Option{sbom.WithName(appInfo.Name)}
+	setupFile, _ := pathutil.Clean(appInfo.SetupFile)
+	for _, file := range files {
+		if strings.EqualFold(file.Path, setupFile) {
+			opts = append(opts, sbom.WithVersion(file.Version))
+		}
+	}
+	return files, opts, nil
+}
+
+func init() {
+	sbomCmd.Flags().String("format", string(sbom.FormatCycloneDX), "SBOM format: cyclonedx or spdx")
+	sbomCmd.Flags().StringP("output", "o", "", "write the SBOM to this file instead of stdout")
+	sbomCmd.Flags().Bool("strict", false, "fail on unrecognized Detection.xml versions and nonstandard packages instead of warning")
+	registerFlagCompletion(sbomCmd, "format", string(sbom.FormatCycloneDX), string(sbom.FormatSPDX))
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/kenchan0130/intunewin/internal/server"
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc"
+)
+
+// shutdownTimeout is how long in-flight requests may run after a shutdown signal
+const shutdownTimeout = 30 * time.Second
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve packing, unpacking and inspection over HTTP",
+	Long: `Serve runs an HTTP server exposing packing as a service, so that build systems
+can create packages without installing the tool:
+
+  POST /v1/pack     zip (application/zip), tar (application/x-tar) or multipart
+                    folder upload to an intunewin package; the name, setupFile
+                    and description query parameters set the metadata, and
+                    setupFile is detected for folder uploads
+  POST /v1/unpack   intunewin package to a zip of its decrypted content
+  POST /v1/inspect  intunewin package to its Detection.xml metadata as JSON
+  GET  /healthz     liveness check
+
+Errors are returned as {"error": "..."} with a 4xx status for invalid requests
+and a 5xx status otherwise. The server stops gracefully on SIGINT or SIGTERM.
+
+With --grpc-listen the same operations are also served over gRPC as the
+PackagingService of api/proto/intunewin/v1/packaging.proto, with streamed
+chunks in place of request and response bodies.
+
+Example:
+  intunewin serve --listen :8080
+  intunewin serve --listen :8080 --grpc-listen :9090
+  curl -fsS --data-binary @app.zip -H 'Content-Type: application/zip' \
+    'http://localhost:8080/v1/pack?name=MyApp&setupFile=setup.exe' -o MyApp.intunewin`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		listen, _ := cmd.Flags().GetString("listen")
+		maxBodySize, _ := cmd.Flags().GetInt64("max-body-size")
+		if maxBodySize <= 0 {
+			return fmt.Errorf("invalid --max-body-size %d", maxBodySize)
+		}
+		tempDir, threshold := spillSettings(cmd)
+
+		listener, err := net.Listen("tcp", listen)
+		if err != nil {
+			return fmt.Errorf("failed to listen on %s: %w", listen, err)
+		}
+		opts := []server.Option{
+			server.WithLogger(logger),
+			server.WithMaxBodySize(maxBodySize),
+			server.WithTempDir(tempDir),
+			server.WithSpillThreshold(threshold),
+			server.WithMemoryLimit(memoryLimit()),
+		}
+		srv := &http.Server{
+			Handler:           server.NewHandler(opts...),
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		errs := make(chan error, 1)
+		go func() {
+			errs <- srv.Serve(listener)
+		}()
+		logger.Info("listening", "address", listener.Addr().String())
+
+		var grpcSrv *grpc.Server
+		// grpcErrs stays nil, and never ready, without a gRPC server
+		var grpcErrs chan error
+		if grpcListen, _ := cmd.Flags().GetString("grpc-listen"); grpcListen != "" {
+			grpcListener, err := net.Listen("tcp", grpcListen)
+			if err != nil {
+				_ = srv.Close()
+				return fmt.Errorf("failed to listen on %s: %w", grpcListen, err)
+			}
+			grpcSrv = grpc.NewServer()
+			server.RegisterGRPC(grpcSrv, opts...)
+			grpcErrs = make(chan error, 1)
+			go func() {
+				grpcErrs <- grpcSrv.Serve(grpcListener)
+			}()
+			logger.Info("listening for gRPC", "address", grpcListener.Addr().String())
+		}
+
+		select {
+		case err := <-errs:
+			if grpcSrv != nil {
+				grpcSrv.Stop()
+			}
+			return fmt.Errorf("failed to serve: %w", err)
+		case err := <-grpcErrs:
+			_ = srv.Close()
+			return fmt.Errorf("failed to serve gRPC: %w", err)
+		case <-ctx.Done():
+		}
+		logger.Info("shutting down")
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if grpcSrv != nil {
+			go stopGRPC(shutdownCtx, grpcSrv)
+		}
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			return fmt.Errorf("failed to shut down: %w", err)
+		}
+		if err := <-errs; !errors.Is(err, http.ErrServerClosed) {
+			return fmt.Errorf("failed to serve: %w", err)
+		}
+		if grpcSrv != nil {
+			if err := <-grpcErrs; err != nil {
+				return fmt.Errorf("failed to serve gRPC: %w", err)
+			}
+		}
+		return nil
+	},
+}
+
+// stopGRPC lets the in-flight calls of srv finish, cancelling those still running when
+// ctx is done
+func stopGRPC(ctx context.Context, srv *grpc.Server) {
+	stopped := make(chan struct{})
+	go func() {
+		srv.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		srv.Stop()
+	}
+}
+
+func init() {
+	serveCmd.Flags().String("listen", "localhost:8080", "address to listen on")
+	serveCmd.Flags().Int64("max-body-size", server.DefaultMaxBodySize, "largest request body accepted, in bytes")
+	serveCmd.Flags().String("grpc-listen", "", "also serve the operations over gRPC on this address, such as :9090")
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"strconv"
+
+	"github.com/kenchan0130/intunewin/internal/spill"
+)
+
+// sizeValue is a flag holding a size in bytes written with an optional unit such as 512MB
+type sizeValue int64
+
+// String implements pflag.Value
+func (s *sizeValue) String() string {
+	return strconv.FormatInt(int64(*s), 10)
+}
+
+// Set implements pflag.Value
+func (s *sizeValue) Set(value string) error {
+	n, err := spill.ParseSize(value)
+	if err != nil {
+		return err //nolint:wrapcheck // pflag adds the flag name
+	}
+	*s = sizeValue(n)
+	return nil
+}
+
+// Type implements pflag.Value
+func (s *sizeValue) Type() string {
+	return "size"
+}
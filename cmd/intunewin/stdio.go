@@ -0,0 +1,45 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+// stdioArg stands for standard input or standard output in place of a path
+const stdioArg = "-"
+
+// Archive formats accepted by the --format flag of pack and unpack
+const (
+	archiveZip = "zip"
+	archiveTar = "tar"
+)
+
+// archiveFormat returns the --format flag of cmd, which only applies to standard input or
+// output given as arg
+func archiveFormat(cmd *cobra.Command, arg string) (string, error) {
+	format, _ := cmd.Flags().GetString("format")
+	switch format {
+	case archiveZip, archiveTar:
+	default:
+		return "", fmt.Errorf("invalid format %q: must be %s or %s", format, archiveZip, archiveTar)
+	}
+	if format != archiveZip && arg != stdioArg {
+		return "", fmt.Errorf("--format %s requires %q in place of the path", format, stdioArg)
+	}
+	return format, nil
+}
+
+// stdinPackageName is the default application name of a package packed from standard input
+const stdinPackageName = "IntunePackage"
+
+// binaryStdout returns standard output for writing binary data, which is refused when it is a terminal
+func binaryStdout() (io.Writer, error) {
+	if isTerminal(os.Stdout) {
+		return nil, errors.New("refusing to write binary data to a terminal, redirect standard output")
+	}
+	return os.Stdout, nil
+}
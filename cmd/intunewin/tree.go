@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/kenchan0130/intunewin/internal/progress"
+	"github.com/kenchan0130/intunewin/internal/render"
+	"github.com/kenchan0130/intunewin/internal/unpack"
+	"github.com/spf13/cobra"
+)
+
+var treeCmd = &cobra.Command{
+	Use:   "tree <file.intunewin>",
+	Short: "Show the folder hierarchy of an intunewin file",
+	Long: `Tree decrypts an intunewin file and prints its content as a tree, with the
+total size and number of files of every folder, without extracting it.
+
+With --depth, folders below that depth are collapsed into their totals.
+
+Example:
+  intunewin tree myapp.intunewin
+  intunewin tree myapp.intunewin --depth 1
+  intunewin tree myapp.intunewin --output json`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeArgsUpTo(completePackage),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, err := outputFormat(cmd)
+		if err != nil {
+			return err
+		}
+		depth, _ := cmd.Flags().GetInt("depth")
+		if depth < 0 {
+			return &usageError{err: fmt.Errorf("--depth must not be negative, got %d", depth)}
+		}
+
+		entries, err := unpack.List(args[0], unpackOptions(cmd)...)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", args[0], err)
+		}
+		return writeOutput(format, treeResult{Node: collapse(unpack.Tree(entries), depth)})
+	},
+}
+
+// treeResult is the output of the tree command
+type treeResult struct {
+	*unpack.Node
+}
+
+// Table lists one file or folder per row, drawing the hierarchy in the path column
+func (r treeResult) Table() render.Table {
+	rows := [][]string{treeRow(r.Node, ".")}
+	var walk func(n *unpack.Node, indent string)
+	walk = func(n *unpack.Node, indent string) {
+		for i, child := range n.Children {
+			branch, next := "├── ", "│   "
+			if i == len(n.Children)-1 {
+				branch, next = "└── ", "    "
+			}
+			name := child.Name
+			if child.IsDir {
+				name += "/"
+			}
+			rows = append(rows, treeRow(child, indent+branch+name))
+			walk(child, indent+next)
+		}
+	}
+	walk(r.Node, "")
+	return render.Table{Columns: []string{"Size", "Files", "Path"}, Rows: rows}
+}
+
+// treeRow returns the row of n with the path column drawn as label
+func treeRow(n *unpack.Node, label string) []string {
+	files := ""
+	if n.IsDir {
+		files = strconv.Itoa(n.Files)
+	}
+	return []string{progress.FormatBytes(n.Size), files, label}
+}
+
+// collapse returns n with only depth levels of files and folders below it, keeping the
+// totals of collapsed folders; a depth of 0 keeps the whole tree
+func collapse(n *unpack.Node, depth int) *unpack.Node {
+	if depth == 0 {
+		return n
+	}
+	return prune(n, depth)
+}
+
+// prune returns a copy of n with levels levels of children
+func prune(n *unpack.Node, levels int) *unpack.Node {
+	copied := *n
+	copied.Children = nil
+	if levels > 0 {
+		for _, child := range n.Children {
+			copied.Children = append(copied.Children, prune(child, levels-1))
+		}
+	}
+	return &copied
+}
+
+func init() {
+	treeCmd.Flags().Int("depth", 0, "only show this many levels of folders, 0 for all")
+	treeCmd.Flags().Bool("strict", false, "fail on unrecognized Detection.xml versions and nonstandard packages instead of warning")
+	addFilenameEncodingFlag(treeCmd)
+	addOutputFlag(treeCmd)
+}
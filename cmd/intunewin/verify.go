@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+
+	"github.com/kenchan0130/intunewin/internal/render"
+	"github.com/kenchan0130/intunewin/internal/verify"
+	"github.com/spf13/cobra"
+)
+
+var verifyCmd = &cobra.Command{
+	Use:   "verify <file.intunewin>...",
+	Short: "Verify the integrity of intunewin files",
+	Long: `Verify checks that intunewin files are intact: Detection.xml must be valid,
+the encrypted contents must pass HMAC verification, the decrypted size and
+SHA256 digest must match Detection.xml and every file in the content zip must
+be readable. Files are verified concurrently.
+
+Example:
+  intunewin verify myapp.intunewin
+  intunewin verify ./packages/*.intunewin --jobs 8
+  intunewin verify ./packages/*.intunewin --output csv`,
+	Args:              cobra.MinimumNArgs(1),
+	ValidArgsFunction: completeArgs(completePackage),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, err := outputFormat(cmd)
+		if err != nil {
+			return err
+		}
+
+		jobs, _ := cmd.Flags().GetInt("jobs")
+		if jobs < 1 {
+			return fmt.Errorf("--jobs must be at least 1, got %d", jobs)
+		}
+
+		results := verify.Files(args, jobs, unpackOptions(cmd)...)
+
+		output := make(verifyResults, 0, len(results))
+		failed := 0
+		for _, result := range results {
+			r := verifyResult{Path: result.Path, Status: "PASS"}
+			if result.Err != nil {
+				failed++
+				r.Status = "FAIL"
+				r.Error = result.Err.Error()
+			}
+			output = append(output, r)
+		}
+		if err := writeOutput(format, output); err != nil {
+			return err
+		}
+		if format == render.FormatTable {
+			fmt.Printf("%d passed, %d failed\n", len(results)-failed, failed)
+		}
+
+		if failed > 0 {
+			return fmt.Errorf("%w: %d of %d files failed verification", errValidation, failed, len(results))
+		}
+		return nil
+	},
+}
+
+// verifyResult is the outcome of verifying one file
+type verifyResult struct {
+	Path   string `json:"path"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// verifyResults is the output of the verify command
+type verifyResults []verifyResult
+
+// Table lists one file per row
+func (r verifyResults) Table() render.Table {
+	rows := make([][]string, 0, len(r))
+	for _, result := range r {
+		rows = append(rows, []string{result.Status, result.Path, result.Error})
+	}
+	return render.Table{Columns: []string{"Status", "Path", "Error"}, Rows: rows}
+}
+
+func init() {
+	verifyCmd.Flags().IntP("jobs", "j", runtime.NumCPU(), "number of files to verify concurrently")
+	addOutputFlag(verifyCmd)
+}
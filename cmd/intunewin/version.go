@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+
+	"github.com/kenchan0130/intunewin/internal/metadata"
+	"github.com/kenchan0130/intunewin/internal/render"
+	"github.com/spf13/cobra"
+)
+
+var versionCmd = &cobra.Command{
+	Use:   "version",
+	Short: "Print the version and build information",
+	Long: `Version prints the version of intunewin, the commit and date it was built from,
+the Go version and platform, and the ToolVersion written into Detection.xml.
+Include the output in bug reports.
+
+Example:
+  intunewin version
+  intunewin version --output json`,
+	Args:              cobra.NoArgs,
+	ValidArgsFunction: cobra.NoFileCompletions,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, err := outputFormat(cmd)
+		if err != nil {
+			return err
+		}
+		return writeOutput(format, versionInfo{
+			Version:     version,
+			Commit:      commit,
+			Date:        date,
+			GoVersion:   runtime.Version(),
+			Platform:    runtime.GOOS + "/" + runtime.GOARCH,
+			ToolVersion: metadata.ToolVersion,
+		})
+	},
+}
+
+// versionInfo is the output of the version command
+type versionInfo struct {
+	Version     string `json:"version"`
+	Commit      string `json:"commit"`
+	Date        string `json:"date"`
+	GoVersion   string `json:"goVersion"`
+	Platform    string `json:"platform"`
+	ToolVersion string `json:"toolVersion"`
+}
+
+// Table lists the fields of v as rows
+func (v versionInfo) Table() render.Table {
+	return render.Table{Columns: []string{"Field", "Value"}, Rows: [][]string{
+		{"Version", v.Version},
+		{"Commit", v.Commit},
+		{"Built", v.Date},
+		{"Go version", v.GoVersion},
+		{"Platform", v.Platform},
+		{"ToolVersion", v.ToolVersion},
+	}}
+}
+
+// readBuildInfo fills in the version, commit and date from the module and VCS
+// information of the binary when they were not set with -ldflags, as in
+// binaries built with go install
+func readBuildInfo() {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return
+	}
+	if version == "dev" && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		version = info.Main.Version
+	}
+	modified := false
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			if commit == "none" {
+				commit = setting.Value
+			}
+		case "vcs.time":
+			if date == "unknown" {
+				date = setting.Value
+			}
+		case "vcs.modified":
+			modified = setting.Value == "true"
+		}
+	}
+	if modified && commit != "none" {
+		commit += "-dirty"
+	}
+}
+
+func init() {
+	readBuildInfo()
+	rootCmd.Version = fmt.Sprintf("%s (commit %s, built %s)", version, commit, date)
+	addOutputFlag(versionCmd)
+}
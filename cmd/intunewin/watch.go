@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/kenchan0130/intunewin/internal/pack"
+	"github.com/kenchan0130/intunewin/internal/watch"
+	"github.com/spf13/cobra"
+)
+
+var watchCmd = &cobra.Command{
+	Use:   "watch <source-folder> <output-file.intunewin>",
+	Short: "Repack a folder whenever its files change",
+	Long: `Watch packs the source folder, then keeps the package current by packing it
+again whenever files in the folder are added, removed or modified. Changes are
+collected until the folder stays unchanged for --debounce, so saving several
+scripts at once triggers a single rebuild.
+
+Changes are reported by the operating system. With --poll, or where the folder
+cannot be watched, it is scanned every --interval instead, which also works on
+network shares and mounted volumes that deliver no notifications. The package
+is written to a temporary file
+and renamed, so it is never seen half written; when a rebuild fails the error
+is logged and the previous package is kept. Press Ctrl+C to stop.
+
+Example:
+  intunewin watch ./myapp ./dist/myapp.intunewin
+  intunewin watch --setup-file Deploy-Application.exe --debounce 2s ./psadt ./dist/psadt.intunewin
+  intunewin watch --poll --interval 2s //server/share/myapp ./dist/myapp.intunewin`,
+	Args:              cobra.ExactArgs(2),
+	ValidArgsFunction: completeArgsUpTo(completeDir, completePackage),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		sourceFolder := args[0]
+		outputFile := args[1]
+
+		interval, _ := cmd.Flags().GetDuration("interval")
+		debounce, _ := cmd.Flags().GetDuration("debounce")
+		polling, _ := cmd.Flags().GetBool("poll")
+		if interval <= 0 {
+			return fmt.Errorf("invalid --interval %s", interval)
+		}
+		absOutput, err := filepath.Abs(outputFile)
+		if err != nil {
+			return fmt.Errorf("failed to resolve output file: %w", err)
+		}
+		absSource, err := filepath.Abs(sourceFolder)
+		if err != nil {
+			return fmt.Errorf("failed to resolve source folder: %w", err)
+		}
+		// A package written into the source folder would change it on every build
+		if rel, err := filepath.Rel(absSource, absOutput); err == nil && filepath.IsLocal(rel) {
+			return fmt.Errorf("the output file %s must be outside the source folder", outputFile)
+		}
+
+		rebuild := func(context.Context) error {
+			start := time.Now()
+			if err := repack(cmd, absSource, absOutput); err != nil {
+				logger.Error("failed to pack", "error", err)
+				return nil
+			}
+			logger.Info("packed", "output", outputFile, "duration", time.Since(start).Round(time.Millisecond))
+			return nil
+		}
+		// The first build fails fast on invalid flags or a missing setup file
+		if err := repack(cmd, absSource, absOutput); err != nil {
+			return fmt.Errorf("failed to pack: %w", err)
+		}
+		logger.Info("packed, watching for changes", "source", sourceFolder, "output", outputFile)
+
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		watchOpts := []watch.Option{
+			watch.WithLogger(logger),
+			watch.WithInterval(interval),
+			watch.WithDebounce(debounce),
+		}
+		if polling {
+			watchOpts = append(watchOpts, watch.WithPolling())
+		}
+		return watch.Watch(ctx, absSource, rebuild, watchOpts...) //nolint:wrapcheck // watch errors already describe the failure
+	},
+}
+
+// repack packs sourceFolder to outputFile with the pack flags of cmd
+func repack(cmd *cobra.Command, sourceFolder, outputFile string) error {
+	opts, err := packOptions(cmd)
+	if err != nil {
+		return err
+	}
+	name, setupFile, opts, err := packInfo(cmd, sourceFolder, opts)
+	if err != nil {
+		return err
+	}
+	opts = append(opts, pack.WithLogger(logger))
+
+	// pack replaces the output only once the new package is complete, so a
+	// failed repack keeps the previous package usable
+	return pack.PackWithInfo(sourceFolder, outputFile, name, setupFile, opts...) //nolint:wrapcheck // wrapped by the caller
+}
+
+func init() {
+	watchCmd.Flags().Bool("poll", false, "scan the source folder for changes instead of being notified of them, for network shares")
+	watchCmd.Flags().Duration("interval", watch.DefaultInterval, "how often the source folder is scanned for changes when polling")
+	watchCmd.Flags().Duration("debounce", watch.DefaultDebounce, "how long the source folder must stay unchanged before it is packed again")
+	addPackFlags(watchCmd)
+}
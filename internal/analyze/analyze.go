@@ -0,0 +1,197 @@
+// Package analyze reports where the size of a package goes: its largest files, how well
+// each file type compresses and the space wasted by identical copies of a file.
+package analyze
+
+import (
+	"compress/flate"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// File is a file of the analyzed content
+type File struct {
+	// Path is the slash separated path relative to the analyzed folder
+	Path string `json:"path"`
+	Size int64  `json:"size"`
+	// CompressedSize is the size of the file deflated at the default level, as pack stores it
+	CompressedSize int64  `json:"compressedSize"`
+	SHA256         string `json:"sha256"`
+}
+
+// Extension totals the files with the same extension
+type Extension struct {
+	// Extension is the lower case extension with its dot, empty for files without one
+	Extension      string  `json:"extension"`
+	Files          int     `json:"files"`
+	Size           int64   `json:"size"`
+	CompressedSize int64   `json:"compressedSize"`
+	Ratio          float64 `json:"ratio"`
+}
+
+// Duplicate is a set of files with identical content
+type Duplicate struct {
+	SHA256 string `json:"sha256"`
+	// Size is the size of each copy
+	Size  int64    `json:"size"`
+	Paths []string `json:"paths"`
+	// Wasted is the size of every copy but the first
+	Wasted int64 `json:"wasted"`
+}
+
+// Report is the size analysis of a package
+type Report struct {
+	Files          int   `json:"files"`
+	Size           int64 `json:"size"`
+	CompressedSize int64 `json:"compressedSize"`
+	// Ratio is the compressed size divided by the size
+	Ratio float64 `json:"ratio"`
+	// Largest lists the largest files, largest first
+	Largest []File `json:"largest"`
+	// Extensions lists the totals per extension, largest first
+	Extensions []Extension `json:"extensions"`
+	// Duplicates lists the sets of identical files, most wasted space first
+	Duplicates []Duplicate `json:"duplicates"`
+	// Wasted is the total size of the duplicate copies
+	Wasted int64 `json:"wasted"`
+}
+
+// Scan hashes and deflates every file under root. Files are returned sorted by path.
+func Scan(root string) ([]File, error) {
+	var files []File
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err //nolint:wrapcheck // wrapped below
+		}
+		file, err := scanFile(p, filepath.ToSlash(rel))
+		if err != nil {
+			return err
+		}
+		files = append(files, file)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan %s: %w", root, err)
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+	return files, nil
+}
+
+// scanFile hashes the file at p and measures its deflated size in one pass
+func scanFile(p, rel string) (File, error) {
+	f, err := os.Open(p) // #nosec G304 -- path is found by walking the scanned folder
+	if err != nil {
+		return File{}, fmt.Errorf("failed to open %s: %w", rel, err)
+	}
+	defer f.Close()
+
+	compressed := &counter{}
+	deflater, err := flate.NewWriter(compressed, flate.DefaultCompression)
+	if err != nil {
+		return File{}, fmt.Errorf("failed to create deflater: %w", err)
+	}
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(hash, deflater), f)
+	if err != nil {
+		return File{}, fmt.Errorf("failed to read %s: %w", rel, err)
+	}
+	if err := deflater.Close(); err != nil {
+		return File{}, fmt.Errorf("failed to compress %s: %w", rel, err)
+	}
+	return File{Path: rel, Size: size, CompressedSize: compressed.n, SHA256: hex.EncodeToString(hash.Sum(nil))}, nil
+}
+
+// counter counts the bytes written to it
+type counter struct {
+	n int64
+}
+
+// Write implements io.Writer
+func (c *counter) Write(p []byte) (int, error) {
+	c.n += int64(len(p))
+	return len(p), nil
+}
+
+// Analyze builds the report of files, listing the top largest files
+func Analyze(files []File, top int) *Report {
+	r := &Report{Files: len(files), Largest: []File{}, Extensions: []Extension{}, Duplicates: []Duplicate{}}
+
+	extensions := map[string]*Extension{}
+	byHash := map[string][]File{}
+	for _, file := range files {
+		r.Size += file.Size
+		r.CompressedSize += file.CompressedSize
+
+		ext := strings.ToLower(path.Ext(file.Path))
+		e, ok := extensions[ext]
+		if !ok {
+			e = &Extension{Extension: ext}
+			extensions[ext] = e
+		}
+		e.Files++
+		e.Size += file.Size
+		e.CompressedSize += file.CompressedSize
+
+		// Empty files take no space however many there are
+		if file.Size > 0 {
+			byHash[file.SHA256] = append(byHash[file.SHA256], file)
+		}
+	}
+	r.Ratio = Ratio(r.CompressedSize, r.Size)
+
+	largest := append([]File(nil), files...)
+	sort.SliceStable(largest, func(i, j int) bool { return largest[i].Size > largest[j].Size })
+	r.Largest = append(r.Largest, largest[:min(top, len(largest))]...)
+
+	for _, e := range extensions {
+		e.Ratio = Ratio(e.CompressedSize, e.Size)
+		r.Extensions = append(r.Extensions, *e)
+	}
+	sort.Slice(r.Extensions, func(i, j int) bool {
+		if r.Extensions[i].Size != r.Extensions[j].Size {
+			return r.Extensions[i].Size > r.Extensions[j].Size
+		}
+		return r.Extensions[i].Extension < r.Extensions[j].Extension
+	})
+
+	for hash, copies := range byHash {
+		if len(copies) < 2 {
+			continue
+		}
+		d := Duplicate{SHA256: hash, Size: copies[0].Size, Wasted: copies[0].Size * int64(len(copies)-1)}
+		for _, file := range copies {
+			d.Paths = append(d.Paths, file.Path)
+		}
+		r.Duplicates = append(r.Duplicates, d)
+		r.Wasted += d.Wasted
+	}
+	sort.Slice(r.Duplicates, func(i, j int) bool {
+		if r.Duplicates[i].Wasted != r.Duplicates[j].Wasted {
+			return r.Duplicates[i].Wasted > r.Duplicates[j].Wasted
+		}
+		return r.Duplicates[i].Paths[0] < r.Duplicates[j].Paths[0]
+	})
+	return r
+}
+
+// Ratio returns compressed divided by size, 0 for no content
+func Ratio(compressed, size int64) float64 {
+	if size == 0 {
+		return 0
+	}
+	return float64(compressed) / float64(size)
+}
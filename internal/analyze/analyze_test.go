@@ -0,0 +1,58 @@
+package analyze
+
+import (
+	"bytes"
+	"crypto/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScanAndAnalyze(t *testing.T) {
+	root := t.TempDir()
+	random := make([]byte, 4096)
+	_, err := rand.Read(random)
+	require.NoError(t, err)
+	files := map[string][]byte{
+		"setup.exe":       random,
+		"lib/a.dll":       bytes.Repeat([]byte("a"), 2000),
+		"lib/copy/a.dll":  bytes.Repeat([]byte("a"), 2000),
+		"docs/readme.txt": []byte("hello"),
+		"docs/empty.txt":  nil,
+		"docs/empty2.txt": nil,
+		"LICENSE":         []byte("MIT"),
+	}
+	for name, data := range files {
+		require.NoError(t, os.MkdirAll(filepath.Join(root, filepath.Dir(name)), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(root, name), data, 0600))
+	}
+
+	scanned, err := Scan(root)
+	require.NoError(t, err)
+	require.Len(t, scanned, 7)
+	assert.Equal(t, "LICENSE", scanned[0].Path)
+
+	r := Analyze(scanned, 2)
+	assert.Equal(t, 7, r.Files)
+	assert.Equal(t, int64(4096+2000+2000+5+3), r.Size)
+	assert.Less(t, r.Ratio, 1.0)
+
+	require.Len(t, r.Largest, 2)
+	assert.Equal(t, "setup.exe", r.Largest[0].Path)
+	assert.Equal(t, "lib/a.dll", r.Largest[1].Path)
+
+	require.Len(t, r.Extensions, 4)
+	assert.Equal(t, ".exe", r.Extensions[0].Extension)
+	assert.Greater(t, r.Extensions[0].Ratio, 0.9)
+	assert.Equal(t, ".dll", r.Extensions[1].Extension)
+	assert.Equal(t, 2, r.Extensions[1].Files)
+	assert.Less(t, r.Extensions[1].Ratio, 0.1)
+
+	require.Len(t, r.Duplicates, 1)
+	assert.Equal(t, []string{"lib/a.dll", "lib/copy/a.dll"}, r.Duplicates[0].Paths)
+	assert.Equal(t, int64(2000), r.Duplicates[0].Wasted)
+	assert.Equal(t, int64(2000), r.Wasted)
+}
@@ -0,0 +1,109 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// GraphResource is the resource Azure CLI issues Graph tokens for
+const GraphResource = "https://graph.microsoft.com"
+
+// azureCLIExpiresOnLayout is the local time format of expiresOn in older Azure CLI versions
+const azureCLIExpiresOnLayout = "2006-01-02 15:04:05.999999"
+
+// ErrAzureCLINotFound is returned when the az command is not installed
+var ErrAzureCLINotFound = errors.New("az command not found; install Azure CLI and run az login")
+
+// runAzureCLI runs az with args and returns its standard output
+var runAzureCLI = func(ctx context.Context, args ...string) ([]byte, error) {
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "az", args...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if errors.Is(err, exec.ErrNotFound) {
+		return nil, ErrAzureCLINotFound
+	}
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%w: %s", err, msg)
+		}
+		return nil, err //nolint:wrapcheck // wrapped by the caller
+	}
+	return out, nil
+}
+
+// AzureCLISource uses the account signed in to Azure CLI, so that pipelines with an Azure
+// login step (such as azure/login in GitHub Actions) need no further credentials
+type AzureCLISource struct {
+	tenantID string
+	opts     *Options
+
+	mu    sync.Mutex
+	token *Token
+}
+
+// NewAzureCLISource returns a token source that runs az account get-access-token, in
+// tenantID when it is not empty. Tokens are issued for Graph unless WithScopes is given.
+func NewAzureCLISource(tenantID string, opts ...Option) *AzureCLISource {
+	return &AzureCLISource{tenantID: tenantID, opts: newOptions(opts)}
+}
+
+// azureCLIToken is the output of az account get-access-token
+type azureCLIToken struct {
+	AccessToken string `json:"accessToken"`
+	ExpiresOn   string `json:"expiresOn"`
+	// ExpiresOnUnix is only printed by Azure CLI 2.54 and later
+	ExpiresOnUnix int64 `json:"expires_on"`
+}
+
+// Token returns a valid access token, running Azure CLI when it is about to expire
+func (s *AzureCLISource) Token(ctx context.Context) (*Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.token.Valid() {
+		return s.token, nil
+	}
+
+	args := []string{"account", "get-access-token", "--resource", GraphResource}
+	if len(s.opts.Scopes) > 0 {
+		args = []string{"account", "get-access-token", "--scope", strings.Join(s.opts.Scopes, " ")}
+	}
+	args = append(args, "--output", "json")
+	if s.tenantID != "" {
+		args = append(args, "--tenant", s.tenantID)
+	}
+	out, err := runAzureCLI(ctx, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get token from Azure CLI: %w", err)
+	}
+
+	var resp azureCLIToken
+	if err := json.Unmarshal(out, &resp); err != nil {
+		return nil, fmt.Errorf("failed to parse Azure CLI token: %w", err)
+	}
+	if resp.AccessToken == "" {
+		return nil, errors.New("az returned no access token")
+	}
+
+	token := &Token{AccessToken: resp.AccessToken}
+	switch {
+	case resp.ExpiresOnUnix > 0:
+		token.ExpiresAt = time.Unix(resp.ExpiresOnUnix, 0)
+	default:
+		token.ExpiresAt, err = time.ParseInLocation(azureCLIExpiresOnLayout, resp.ExpiresOn, time.Local)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse Azure CLI token expiry: %w", err)
+		}
+	}
+	s.opts.Logger.Debug("acquired Azure CLI token", "expiresAt", token.ExpiresAt)
+	s.token = token
+	return token, nil
+}
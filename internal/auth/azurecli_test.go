@@ -0,0 +1,80 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeAzureCLI replaces runAzureCLI for the duration of a test
+func fakeAzureCLI(t *testing.T, run func(args []string) ([]byte, error)) *[][]string {
+	t.Helper()
+	var calls [][]string
+	orig := runAzureCLI
+	runAzureCLI = func(_ context.Context, args ...string) ([]byte, error) {
+		calls = append(calls, args)
+		return run(args)
+	}
+	t.Cleanup(func() { runAzureCLI = orig })
+	return &calls
+}
+
+func TestAzureCLISource(t *testing.T) {
+	expires := time.Now().Add(time.Hour).Truncate(time.Second)
+	calls := fakeAzureCLI(t, func([]string) ([]byte, error) {
+		return []byte(`{"accessToken":"cli","expiresOn":"2000-01-01 00:00:00.000000","expires_on":` +
+			strconv.FormatInt(expires.Unix(), 10) + `}`), nil
+	})
+
+	source := NewAzureCLISource("tenant")
+	for range 2 {
+		token, err := source.Token(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, "cli", token.AccessToken)
+		assert.True(t, expires.Equal(token.ExpiresAt))
+	}
+	require.Len(t, *calls, 1, "the token is reused until it expires")
+	assert.Equal(t, []string{
+		"account", "get-access-token", "--resource", GraphResource, "--output", "json", "--tenant", "tenant",
+	}, (*calls)[0])
+}
+
+func TestAzureCLISourceScopes(t *testing.T) {
+	calls := fakeAzureCLI(t, func([]string) ([]byte, error) {
+		return []byte(`{"accessToken":"cli","expires_on":` + strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10) + `}`), nil
+	})
+
+	_, err := NewAzureCLISource("", WithScopes(KeyVaultDefaultScope)).Token(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{{"account", "get-access-token", "--scope", KeyVaultDefaultScope, "--output", "json"}}, *calls)
+}
+
+func TestAzureCLISourceLocalExpiry(t *testing.T) {
+	expires := time.Now().Add(time.Hour).Truncate(time.Second)
+	fakeAzureCLI(t, func([]string) ([]byte, error) {
+		return []byte(`{"accessToken":"cli","expiresOn":"` + expires.Format(azureCLIExpiresOnLayout) + `"}`), nil
+	})
+
+	token, err := NewAzureCLISource("").Token(context.Background())
+	require.NoError(t, err)
+	assert.True(t, expires.Equal(token.ExpiresAt))
+}
+
+func TestAzureCLISourceError(t *testing.T) {
+	fakeAzureCLI(t, func([]string) ([]byte, error) {
+		return nil, ErrAzureCLINotFound
+	})
+	_, err := NewAzureCLISource("").Token(context.Background())
+	assert.ErrorIs(t, err, ErrAzureCLINotFound)
+
+	fakeAzureCLI(t, func([]string) ([]byte, error) {
+		return nil, errors.New("exit status 1: Please run 'az login' to setup account.")
+	})
+	_, err = NewAzureCLISource("").Token(context.Background())
+	assert.ErrorContains(t, err, "az login")
+}
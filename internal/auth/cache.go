@@ -0,0 +1,114 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// CacheFileName is the name of the token cache in the state directory
+const CacheFileName = "tokens.json"
+
+// Cache stores tokens in a JSON file that only the current user can read
+type Cache struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewCache returns a cache stored at path
+func NewCache(path string) *Cache {
+	return &Cache{path: path}
+}
+
+// Path returns the location of the cache file
+func (c *Cache) Path() string {
+	return c.path
+}
+
+// Load returns the token stored for key, or nil when there is none
+func (c *Cache) Load(key string) (*Token, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	tokens, err := c.read()
+	if err != nil {
+		return nil, err
+	}
+	return tokens[key], nil
+}
+
+// Store saves token for key, or removes the entry when token is nil
+func (c *Cache) Store(key string, token *Token) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	tokens, err := c.read()
+	if err != nil {
+		return err
+	}
+	if token == nil {
+		delete(tokens, key)
+	} else {
+		tokens[key] = token
+	}
+	return c.write(tokens)
+}
+
+// Clear removes the cache file
+func (c *Cache) Clear() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := os.Remove(c.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove token cache: %w", err)
+	}
+	return nil
+}
+
+// read loads all cached tokens
+func (c *Cache) read() (map[string]*Token, error) {
+	tokens := map[string]*Token{}
+	data, err := os.ReadFile(c.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return tokens, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read token cache: %w", err)
+	}
+	if err := json.Unmarshal(data, &tokens); err != nil {
+		return nil, fmt.Errorf("failed to parse token cache %s: %w", c.path, err)
+	}
+	return tokens, nil
+}
+
+// write replaces the cache file with tokens. The file is written next to the cache and
+// renamed so that a concurrent read never sees a partial file.
+func (c *Cache) write(tokens map[string]*Token) error {
+	data, err := json.Marshal(tokens)
+	if err != nil {
+		return fmt.Errorf("failed to marshal token cache: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0700); err != nil {
+		return fmt.Errorf("failed to create token cache directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(c.path), ".tokens-*")
+	if err != nil {
+		return fmt.Errorf("failed to write token cache: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write token cache: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write token cache: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), c.path); err != nil {
+		return fmt.Errorf("failed to write token cache: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,234 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1" // #nosec G505 -- the x5t header is defined as the SHA-1 thumbprint
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// GraphDefaultScope requests the application permissions granted to an app registration
+const GraphDefaultScope = "https://graph.microsoft.com/.default"
+
+// KeyVaultDefaultScope requests the Key Vault access granted to an app registration
+const KeyVaultDefaultScope = "https://vault.azure.net/.default"
+
+// clientAssertionType is the client_assertion_type of a signed JWT
+const clientAssertionType = "urn:ietf:params:oauth:client-assertion-type:jwt-bearer"
+
+// assertionLifetime is how long a client assertion is valid
+const assertionLifetime = 10 * time.Minute
+
+// ErrMissingCredential is returned when the tenant, client or credential of an app is not set
+var ErrMissingCredential = errors.New("missing client credential")
+
+// AssertionFunc returns a signed JWT that proves the identity of an app, such as a
+// federated token issued by a workload identity provider
+type AssertionFunc func(ctx context.Context) (string, error)
+
+// Certificate is the private key and certificate an app registration signs in with
+type Certificate struct {
+	key        *rsa.PrivateKey
+	thumbprint []byte
+}
+
+// LoadCertificate reads a PEM file holding an RSA private key and its certificate
+func LoadCertificate(path string) (*Certificate, error) {
+	data, err := os.ReadFile(path) // #nosec G304 -- path is provided by the user
+	if err != nil {
+		return nil, fmt.Errorf("failed to read client certificate: %w", err)
+	}
+	return ParseCertificate(data)
+}
+
+// ParseCertificate parses PEM data holding an RSA private key, in PKCS #1 or unencrypted
+// PKCS #8 form, and the certificate registered for the app
+func ParseCertificate(data []byte) (*Certificate, error) {
+	var cert Certificate
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		switch block.Type {
+		case "CERTIFICATE":
+			if cert.thumbprint == nil {
+				sum := sha1.Sum(block.Bytes) // #nosec G401 -- the x5t header is defined as the SHA-1 thumbprint
+				cert.thumbprint = sum[:]
+			}
+		case "RSA PRIVATE KEY":
+			key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse client certificate key: %w", err)
+			}
+			cert.key = key
+		case "PRIVATE KEY":
+			key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse client certificate key: %w", err)
+			}
+			rsaKey, ok := key.(*rsa.PrivateKey)
+			if !ok {
+				return nil, fmt.Errorf("client certificate key is %T, only RSA keys are supported", key)
+			}
+			cert.key = rsaKey
+		}
+	}
+	if cert.key == nil || cert.thumbprint == nil {
+		return nil, fmt.Errorf("%w: the PEM data needs a private key and a certificate", ErrMissingCredential)
+	}
+	return &cert, nil
+}
+
+// Thumbprint returns the SHA-1 thumbprint of the certificate as shown in the Entra portal
+func (c *Certificate) Thumbprint() string {
+	return fmt.Sprintf("%X", c.thumbprint)
+}
+
+// assertion returns a client assertion JWT for the token endpoint audience
+func (c *Certificate) assertion(clientID, audience string) (string, error) {
+	jti := make([]byte, 16)
+	if _, err := rand.Read(jti); err != nil {
+		return "", fmt.Errorf("failed to generate assertion ID: %w", err)
+	}
+	now := time.Now()
+
+	header, err := json.Marshal(map[string]string{
+		"alg": "RS256",
+		"typ": "JWT",
+		"x5t": base64.RawURLEncoding.EncodeToString(c.thumbprint),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal assertion: %w", err)
+	}
+	claims, err := json.Marshal(map[string]any{
+		"aud": audience,
+		"iss": clientID,
+		"sub": clientID,
+		"jti": hex.EncodeToString(jti),
+		"nbf": now.Unix(),
+		"iat": now.Unix(),
+		"exp": now.Add(assertionLifetime).Unix(),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal assertion: %w", err)
+	}
+
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(signed))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, c.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("failed to sign assertion: %w", err)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// ClientCredentialSource signs in as an app registration with a client secret, a
+// certificate or a federated token, for non-interactive use such as CI pipelines. Tokens
+// are only kept in memory.
+type ClientCredentialSource struct {
+	tenantID  string
+	clientID  string
+	secret    string
+	cert      *Certificate
+	assertion AssertionFunc
+	opts      *Options
+
+	mu    sync.Mutex
+	token *Token
+}
+
+// NewClientSecretSource returns a token source that signs in as clientID with secret
+func NewClientSecretSource(tenantID, clientID, secret string, opts ...Option) (*ClientCredentialSource, error) {
+	if secret == "" {
+		return nil, fmt.Errorf("%w: client secret is empty", ErrMissingCredential)
+	}
+	return newClientCredentialSource(tenantID, clientID, &ClientCredentialSource{secret: secret}, opts)
+}
+
+// NewClientCertificateSource returns a token source that signs in as clientID with cert
+func NewClientCertificateSource(tenantID, clientID string, cert *Certificate, opts ...Option) (*ClientCredentialSource, error) {
+	if cert == nil {
+		return nil, fmt.Errorf("%w: client certificate is not set", ErrMissingCredential)
+	}
+	return newClientCredentialSource(tenantID, clientID, &ClientCredentialSource{cert: cert}, opts)
+}
+
+// NewFederatedSource returns a token source that signs in as clientID with the federated
+// tokens returned by assertion, as configured for workload identity federation
+func NewFederatedSource(tenantID, clientID string, assertion AssertionFunc, opts ...Option) (*ClientCredentialSource, error) {
+	if assertion == nil {
+		return nil, fmt.Errorf("%w: federated token is not set", ErrMissingCredential)
+	}
+	return newClientCredentialSource(tenantID, clientID, &ClientCredentialSource{assertion: assertion}, opts)
+}
+
+// newClientCredentialSource completes s after checking the tenant and client
+func newClientCredentialSource(tenantID, clientID string, s *ClientCredentialSource, opts []Option) (*ClientCredentialSource, error) {
+	if tenantID == "" || clientID == "" {
+		return nil, fmt.Errorf("%w: app sign-in needs a tenant ID and a client ID", ErrMissingCredential)
+	}
+	s.tenantID, s.clientID = tenantID, clientID
+	s.opts = newOptions(opts)
+	if len(s.opts.Scopes) == 0 {
+		s.opts.Scopes = []string{GraphDefaultScope}
+	}
+	return s, nil
+}
+
+// Token returns a valid access token, requesting a new one when it is about to expire
+func (s *ClientCredentialSource) Token(ctx context.Context) (*Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.token.Valid() {
+		return s.token, nil
+	}
+
+	form := url.Values{
+		"grant_type": {"client_credentials"},
+		"client_id":  {s.clientID},
+		"scope":      {strings.Join(s.opts.Scopes, " ")},
+	}
+	switch {
+	case s.cert != nil:
+		assertion, err := s.cert.assertion(s.clientID, s.opts.endpoint(s.tenantID, "token"))
+		if err != nil {
+			return nil, err
+		}
+		form.Set("client_assertion_type", clientAssertionType)
+		form.Set("client_assertion", assertion)
+	case s.assertion != nil:
+		assertion, err := s.assertion(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get federated token: %w", err)
+		}
+		form.Set("client_assertion_type", clientAssertionType)
+		form.Set("client_assertion", assertion)
+	default:
+		form.Set("client_secret", s.secret)
+	}
+
+	token, err := s.opts.requestToken(ctx, s.tenantID, form)
+	if err != nil {
+		return nil, err
+	}
+	s.opts.Logger.Debug("acquired app token", "tenantID", s.tenantID, "clientID", s.clientID, "expiresAt", token.ExpiresAt)
+	s.token = token
+	return token, nil
+}
@@ -0,0 +1,138 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestCertificate returns a PEM file with a self-signed certificate and its PKCS #8 key
+func newTestCertificate(t *testing.T) ([]byte, *rsa.PrivateKey) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "intunewin"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+
+	data := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER})
+	return append(data, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})...), key
+}
+
+// newClientCredentialServer serves client credential token requests, checking them with check
+func newClientCredentialServer(t *testing.T, check func(form map[string][]string)) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		assert.Equal(t, "/tenant/oauth2/v2.0/token", r.URL.Path)
+		assert.Equal(t, "client_credentials", r.PostForm.Get("grant_type"))
+		assert.Equal(t, "client", r.PostForm.Get("client_id"))
+		assert.Equal(t, GraphDefaultScope, r.PostForm.Get("scope"))
+		requests.Add(1)
+		if r.PostForm.Get("client_secret") == "wrong" {
+			writeJSON(w, http.StatusUnauthorized, map[string]any{
+				"error": "invalid_client", "error_description": "AADSTS7000215: Invalid client secret provided.",
+			})
+			return
+		}
+		check(r.PostForm)
+		writeJSON(w, http.StatusOK, map[string]any{"access_token": "app", "expires_in": 3599})
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func TestClientSecretSource(t *testing.T) {
+	server, requests := newClientCredentialServer(t, func(form map[string][]string) {
+		assert.Equal(t, []string{"secret"}, form["client_secret"])
+	})
+
+	source, err := NewClientSecretSource("tenant", "client", "secret", WithAuthority(server.URL))
+	require.NoError(t, err)
+	for range 2 {
+		token, err := source.Token(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, "app", token.AccessToken)
+	}
+	assert.Equal(t, int32(1), requests.Load(), "the token is reused until it expires")
+
+	source, err = NewClientSecretSource("tenant", "client", "wrong", WithAuthority(server.URL))
+	require.NoError(t, err)
+	_, err = source.Token(context.Background())
+	var authErr *Error
+	require.ErrorAs(t, err, &authErr)
+	assert.Equal(t, "invalid_client", authErr.Code)
+	assert.Contains(t, err.Error(), "AADSTS7000215")
+}
+
+func TestClientCertificateSource(t *testing.T) {
+	data, key := newTestCertificate(t)
+	cert, err := ParseCertificate(data)
+	require.NoError(t, err)
+	assert.Len(t, cert.Thumbprint(), 40)
+
+	var server *httptest.Server
+	server, _ = newClientCredentialServer(t, func(form map[string][]string) {
+		assert.Equal(t, []string{clientAssertionType}, form["client_assertion_type"])
+		parts := strings.Split(form["client_assertion"][0], ".")
+		require.Len(t, parts, 3)
+
+		signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+		require.NoError(t, err)
+		digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+		require.NoError(t, rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, digest[:], signature))
+
+		claimsJSON, err := base64.RawURLEncoding.DecodeString(parts[1])
+		require.NoError(t, err)
+		var claims map[string]any
+		require.NoError(t, json.Unmarshal(claimsJSON, &claims))
+		assert.Equal(t, "client", claims["iss"])
+		assert.Equal(t, "client", claims["sub"])
+		assert.Equal(t, server.URL+"/tenant/oauth2/v2.0/token", claims["aud"])
+	})
+
+	source, err := NewClientCertificateSource("tenant", "client", cert, WithAuthority(server.URL))
+	require.NoError(t, err)
+	token, err := source.Token(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "app", token.AccessToken)
+}
+
+func TestClientCredentialSourceMissing(t *testing.T) {
+	_, err := NewClientSecretSource("tenant", "client", "")
+	require.ErrorIs(t, err, ErrMissingCredential)
+	_, err = NewClientSecretSource("", "client", "secret")
+	require.ErrorIs(t, err, ErrMissingCredential)
+	_, err = NewClientCertificateSource("tenant", "client", nil)
+	require.ErrorIs(t, err, ErrMissingCredential)
+
+	data, _ := newTestCertificate(t)
+	keyOnly, _ := pem.Decode(data)
+	_, err = ParseCertificate(pem.EncodeToMemory(keyOnly))
+	assert.ErrorIs(t, err, ErrMissingCredential)
+}
@@ -0,0 +1,203 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// DefaultClientID is the public client of Microsoft Graph PowerShell, which is allowed to
+// request Intune permissions with the device code flow in every tenant
+const DefaultClientID = "14d82eec-204b-4c2f-b7e8-296a70dab67e"
+
+// defaultPollInterval is used when the device code response has no interval
+const defaultPollInterval = 5 * time.Second
+
+// ErrDeviceCodeExpired is returned when the user did not sign in before the code expired
+var ErrDeviceCodeExpired = errors.New("device code expired before sign-in completed")
+
+// ErrSignInDeclined is returned when the user declined the sign-in request
+var ErrSignInDeclined = errors.New("sign-in was declined")
+
+// DeviceCode is the code the user enters at VerificationURI to sign in
+type DeviceCode struct {
+	DeviceCode      string `json:"device_code"`
+	UserCode        string `json:"user_code"`
+	VerificationURI string `json:"verification_uri"`
+	ExpiresIn       int64  `json:"expires_in"`
+	Interval        int64  `json:"interval"`
+	// Message tells the user where to enter the code
+	Message string `json:"message"`
+}
+
+// sleep waits for d or until ctx is done
+var sleep = func(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// DeviceCodeSource signs in interactively with the OAuth 2.0 device code flow. Tokens are
+// cached and refreshed so that the user only signs in again when the refresh token expires.
+type DeviceCodeSource struct {
+	tenantID string
+	clientID string
+	opts     *Options
+	token    *Token
+}
+
+// NewDeviceCodeSource returns a token source that signs in to tenantID with the public
+// client clientID, DefaultClientID when empty
+func NewDeviceCodeSource(tenantID, clientID string, opts ...Option) *DeviceCodeSource {
+	if clientID == "" {
+		clientID = DefaultClientID
+	}
+	if tenantID == "" {
+		tenantID = "organizations"
+	}
+	o := newOptions(opts)
+	if len(o.Scopes) == 0 {
+		o.Scopes = GraphScope
+	}
+	return &DeviceCodeSource{tenantID: tenantID, clientID: clientID, opts: o}
+}
+
+// cacheKey identifies the tokens of this source in the cache
+func (s *DeviceCodeSource) cacheKey() string {
+	return "device-code|" + s.tenantID + "|" + s.clientID + "|" + strings.Join(s.opts.Scopes, " ")
+}
+
+// Token returns a valid access token, from the cache, by redeeming the refresh token or by
+// asking the user to sign in
+func (s *DeviceCodeSource) Token(ctx context.Context) (*Token, error) {
+	if s.token.Valid() {
+		return s.token, nil
+	}
+
+	cached := s.token
+	if cached == nil && s.opts.Cache != nil {
+		var err error
+		if cached, err = s.opts.Cache.Load(s.cacheKey()); err != nil {
+			s.opts.Logger.Warn("ignoring token cache", "error", err)
+		}
+	}
+
+	var token *Token
+	switch {
+	case cached.Valid():
+		token = cached
+	case cached != nil && cached.RefreshToken != "":
+		refreshed, err := s.refresh(ctx, cached.RefreshToken)
+		if err == nil {
+			token = refreshed
+			break
+		}
+		s.opts.Logger.Debug("failed to refresh token, signing in again", "error", err)
+		fallthrough
+	default:
+		signedIn, err := s.signIn(ctx)
+		if err != nil {
+			return nil, err
+		}
+		token = signedIn
+	}
+
+	s.token = token
+	if s.opts.Cache != nil && token != cached {
+		if err := s.opts.Cache.Store(s.cacheKey(), token); err != nil {
+			s.opts.Logger.Warn("failed to cache token", "error", err)
+		}
+	}
+	return token, nil
+}
+
+// SignOut forgets the tokens of this source
+func (s *DeviceCodeSource) SignOut() error {
+	s.token = nil
+	if s.opts.Cache == nil {
+		return nil
+	}
+	return s.opts.Cache.Store(s.cacheKey(), nil)
+}
+
+// refresh redeems a refresh token
+func (s *DeviceCodeSource) refresh(ctx context.Context, refreshToken string) (*Token, error) {
+	token, err := s.opts.requestToken(ctx, s.tenantID, url.Values{
+		"grant_type":    {"refresh_token"},
+		"client_id":     {s.clientID},
+		"refresh_token": {refreshToken},
+		"scope":         {strings.Join(s.opts.Scopes, " ")},
+	})
+	if err != nil {
+		return nil, err
+	}
+	// The identity platform may not return a new refresh token
+	if token.RefreshToken == "" {
+		token.RefreshToken = refreshToken
+	}
+	return token, nil
+}
+
+// signIn runs the device code flow: it requests a device code, shows it to the user and
+// polls the token endpoint until the user signed in
+func (s *DeviceCodeSource) signIn(ctx context.Context) (*Token, error) {
+	var code DeviceCode
+	err := s.opts.postForm(ctx, s.opts.endpoint(s.tenantID, "devicecode"), url.Values{
+		"client_id": {s.clientID},
+		"scope":     {strings.Join(s.opts.Scopes, " ")},
+	}, &code)
+	if err != nil {
+		return nil, fmt.Errorf("failed to request device code: %w", err)
+	}
+	if code.Message == "" {
+		code.Message = fmt.Sprintf("To sign in, open %s and enter the code %s", code.VerificationURI, code.UserCode)
+	}
+	s.opts.Prompt(&code)
+
+	interval := defaultPollInterval
+	if code.Interval > 0 {
+		interval = time.Duration(code.Interval) * time.Second
+	}
+	deadline := time.Now().Add(time.Duration(code.ExpiresIn) * time.Second)
+
+	for {
+		if err := sleep(ctx, interval); err != nil {
+			return nil, err
+		}
+		token, err := s.opts.requestToken(ctx, s.tenantID, url.Values{
+			"grant_type":  {"urn:ietf:params:oauth:grant-type:device_code"},
+			"client_id":   {s.clientID},
+			"device_code": {code.DeviceCode},
+		})
+		if err == nil {
+			return token, nil
+		}
+
+		var authErr *Error
+		if !errors.As(err, &authErr) {
+			return nil, err
+		}
+		switch authErr.Code {
+		case "authorization_pending":
+		case "slow_down":
+			interval += defaultPollInterval
+		case "authorization_declined", "access_denied":
+			return nil, ErrSignInDeclined
+		case "expired_token", "code_expired":
+			return nil, ErrDeviceCodeExpired
+		default:
+			return nil, err
+		}
+		if code.ExpiresIn > 0 && time.Now().After(deadline) {
+			return nil, ErrDeviceCodeExpired
+		}
+	}
+}
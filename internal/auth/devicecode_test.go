@@ -0,0 +1,167 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// noSleep makes polling immediate for the duration of a test
+func noSleep(t *testing.T) {
+	t.Helper()
+	orig := sleep
+	sleep = func(ctx context.Context, _ time.Duration) error { return ctx.Err() }
+	t.Cleanup(func() { sleep = orig })
+}
+
+// writeJSON writes v as a JSON response with status
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// newDeviceCodeServer serves the device code flow; the token endpoint answers
+// authorization_pending pending times, then finalErr or a token
+func newDeviceCodeServer(t *testing.T, pending int, finalErr string) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var polls, refreshes atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		switch r.URL.Path {
+		case "/tenant/oauth2/v2.0/devicecode":
+			assert.Equal(t, DefaultClientID, r.PostForm.Get("client_id"))
+			writeJSON(w, http.StatusOK, map[string]any{
+				"device_code": "device", "user_code": "ABCD", "verification_uri": "https://example.com/device",
+				"expires_in": 900, "interval": 1, "message": "Enter ABCD",
+			})
+		case "/tenant/oauth2/v2.0/token":
+			switch r.PostForm.Get("grant_type") {
+			case "refresh_token":
+				refreshes.Add(1)
+				if r.PostForm.Get("refresh_token") != "refresh" {
+					writeJSON(w, http.StatusBadRequest, map[string]any{"error": "invalid_grant"})
+					return
+				}
+				writeJSON(w, http.StatusOK, map[string]any{"access_token": "refreshed", "expires_in": 3600})
+			default:
+				assert.Equal(t, "device", r.PostForm.Get("device_code"))
+				if int(polls.Add(1)) <= pending {
+					writeJSON(w, http.StatusBadRequest, map[string]any{"error": "authorization_pending"})
+					return
+				}
+				if finalErr != "" {
+					writeJSON(w, http.StatusBadRequest, map[string]any{"error": finalErr})
+					return
+				}
+				writeJSON(w, http.StatusOK, map[string]any{
+					"access_token": "access", "refresh_token": "refresh", "expires_in": 3600,
+				})
+			}
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, &refreshes
+}
+
+func TestDeviceCodeSource(t *testing.T) {
+	noSleep(t)
+	server, refreshes := newDeviceCodeServer(t, 2, "")
+	cache := NewCache(filepath.Join(t.TempDir(), CacheFileName))
+
+	var prompted *DeviceCode
+	source := NewDeviceCodeSource("tenant", "",
+		WithAuthority(server.URL), WithCache(cache),
+		WithPrompt(func(code *DeviceCode) { prompted = code }))
+
+	token, err := source.Token(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "access", token.AccessToken)
+	require.NotNil(t, prompted)
+	assert.Equal(t, "ABCD", prompted.UserCode)
+
+	// A new source signs in silently with the cached token
+	prompted = nil
+	token, err = NewDeviceCodeSource("tenant", "", WithAuthority(server.URL), WithCache(cache),
+		WithPrompt(func(code *DeviceCode) { prompted = code })).Token(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "access", token.AccessToken)
+	assert.Nil(t, prompted)
+
+	// An expired token is refreshed and keeps the refresh token
+	key := source.cacheKey()
+	require.NoError(t, cache.Store(key, &Token{AccessToken: "old", RefreshToken: "refresh", ExpiresAt: time.Now()}))
+	token, err = NewDeviceCodeSource("tenant", "", WithAuthority(server.URL), WithCache(cache)).Token(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "refreshed", token.AccessToken)
+	assert.Equal(t, "refresh", token.RefreshToken)
+	assert.Equal(t, int32(1), refreshes.Load())
+
+	cached, err := cache.Load(key)
+	require.NoError(t, err)
+	assert.Equal(t, "refreshed", cached.AccessToken)
+
+	require.NoError(t, source.SignOut())
+	cached, err = cache.Load(key)
+	require.NoError(t, err)
+	assert.Nil(t, cached)
+}
+
+func TestDeviceCodeSourceErrors(t *testing.T) {
+	noSleep(t)
+	tests := []struct {
+		name     string
+		finalErr string
+		want     error
+	}{
+		{name: "declined", finalErr: "authorization_declined", want: ErrSignInDeclined},
+		{name: "expired", finalErr: "expired_token", want: ErrDeviceCodeExpired},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, _ := newDeviceCodeServer(t, 1, tt.finalErr)
+			_, err := NewDeviceCodeSource("tenant", "", WithAuthority(server.URL)).Token(context.Background())
+			assert.ErrorIs(t, err, tt.want)
+		})
+	}
+
+	t.Run("identity platform error", func(t *testing.T) {
+		server, _ := newDeviceCodeServer(t, 0, "invalid_client")
+		_, err := NewDeviceCodeSource("tenant", "", WithAuthority(server.URL)).Token(context.Background())
+		var authErr *Error
+		require.ErrorAs(t, err, &authErr)
+		assert.Equal(t, "invalid_client", authErr.Code)
+	})
+}
+
+func TestCache(t *testing.T) {
+	cache := NewCache(filepath.Join(t.TempDir(), "state", CacheFileName))
+
+	token, err := cache.Load("key")
+	require.NoError(t, err)
+	assert.Nil(t, token)
+
+	want := &Token{AccessToken: "access", ExpiresAt: time.Now().Add(time.Hour).Round(0).UTC()}
+	require.NoError(t, cache.Store("key", want))
+	token, err = cache.Load("key")
+	require.NoError(t, err)
+	assert.Equal(t, want.AccessToken, token.AccessToken)
+	assert.True(t, want.ExpiresAt.Equal(token.ExpiresAt))
+	assert.True(t, token.Valid())
+
+	require.NoError(t, cache.Clear())
+	require.NoError(t, cache.Clear())
+	token, err = cache.Load("key")
+	require.NoError(t, err)
+	assert.Nil(t, token)
+}
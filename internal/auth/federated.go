@@ -0,0 +1,79 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// FederatedAudience is the audience Microsoft Entra ID expects in federated tokens
+const FederatedAudience = "api://AzureADTokenExchange"
+
+// FederatedTokenFile returns an AssertionFunc that reads the federated token from path,
+// as projected into pods by Azure Workload Identity. The file is read for every token
+// request because it is rotated.
+func FederatedTokenFile(path string) AssertionFunc {
+	return func(context.Context) (string, error) {
+		data, err := os.ReadFile(path) // #nosec G304 -- path is provided by the user
+		if err != nil {
+			return "", fmt.Errorf("failed to read federated token file: %w", err)
+		}
+		token := strings.TrimSpace(string(data))
+		if token == "" {
+			return "", fmt.Errorf("federated token file %s is empty", path)
+		}
+		return token, nil
+	}
+}
+
+// GitHubActionsToken returns an AssertionFunc that requests an OIDC token for
+// FederatedAudience from GitHub Actions. requestURL and requestToken are the
+// ACTIONS_ID_TOKEN_REQUEST_URL and ACTIONS_ID_TOKEN_REQUEST_TOKEN of a job with the
+// id-token: write permission.
+func GitHubActionsToken(requestURL, requestToken string, client *http.Client) AssertionFunc {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return func(ctx context.Context) (string, error) {
+		u, err := url.Parse(requestURL)
+		if err != nil {
+			return "", fmt.Errorf("invalid GitHub Actions token request URL: %w", err)
+		}
+		query := u.Query()
+		query.Set("audience", FederatedAudience)
+		u.RawQuery = query.Encode()
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+		if err != nil {
+			return "", fmt.Errorf("failed to create GitHub Actions token request: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+requestToken)
+		req.Header.Set("Accept", "application/json")
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return "", fmt.Errorf("failed to request GitHub Actions token: %w", err)
+		}
+		defer resp.Body.Close()
+
+		body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+		if err != nil {
+			return "", fmt.Errorf("failed to read GitHub Actions token: %w", err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			return "", fmt.Errorf("GitHub Actions token request failed with status %s", resp.Status)
+		}
+		var token struct {
+			Value string `json:"value"`
+		}
+		if err := json.Unmarshal(body, &token); err != nil || token.Value == "" {
+			return "", fmt.Errorf("GitHub Actions token response has no token")
+		}
+		return token.Value, nil
+	}
+}
@@ -0,0 +1,71 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFederatedTokenFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(path, []byte("first\n"), 0600))
+	assertion := FederatedTokenFile(path)
+
+	token, err := assertion(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "first", token)
+
+	// The file is read again for every request
+	require.NoError(t, os.WriteFile(path, []byte("second"), 0600))
+	token, err = assertion(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "second", token)
+
+	require.NoError(t, os.WriteFile(path, nil, 0600))
+	_, err = assertion(context.Background())
+	assert.Error(t, err)
+}
+
+func TestGitHubActionsToken(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer request-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		assert.Equal(t, "value", r.URL.Query().Get("existing"))
+		assert.Equal(t, FederatedAudience, r.URL.Query().Get("audience"))
+		writeJSON(w, http.StatusOK, map[string]any{"value": "oidc"})
+	}))
+	defer server.Close()
+
+	token, err := GitHubActionsToken(server.URL+"/?existing=value", "request-token", nil)(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "oidc", token)
+
+	_, err = GitHubActionsToken(server.URL, "wrong", nil)(context.Background())
+	assert.Error(t, err)
+}
+
+func TestFederatedSource(t *testing.T) {
+	server, _ := newClientCredentialServer(t, func(form map[string][]string) {
+		assert.Equal(t, []string{clientAssertionType}, form["client_assertion_type"])
+		assert.Equal(t, []string{"federated"}, form["client_assertion"])
+	})
+
+	source, err := NewFederatedSource("tenant", "client", func(context.Context) (string, error) {
+		return "federated", nil
+	}, WithAuthority(server.URL))
+	require.NoError(t, err)
+	token, err := source.Token(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "app", token.AccessToken)
+
+	_, err = NewFederatedSource("tenant", "client", nil)
+	assert.ErrorIs(t, err, ErrMissingCredential)
+}
@@ -0,0 +1,87 @@
+package auth
+
+import (
+	"log/slog"
+	"net/http"
+)
+
+// DefaultAuthority is the Microsoft Entra ID endpoint of the global cloud
+const DefaultAuthority = "https://login.microsoftonline.com"
+
+// Options holds the settings shared by token sources
+type Options struct {
+	// Logger receives diagnostic messages
+	Logger *slog.Logger
+	// Authority is the base URL of the identity platform, DefaultAuthority when empty
+	Authority string
+	// HTTPClient sends the token requests, http.DefaultClient when nil
+	HTTPClient *http.Client
+	// Cache keeps tokens between runs; tokens are only kept in memory when nil
+	Cache *Cache
+	// Scopes are the permissions requested for the token
+	Scopes []string
+	// Prompt shows the device code to the user
+	Prompt func(code *DeviceCode)
+}
+
+// Option configures Options
+type Option func(*Options)
+
+// WithLogger sends diagnostic messages to logger
+func WithLogger(logger *slog.Logger) Option {
+	return func(o *Options) {
+		o.Logger = logger
+	}
+}
+
+// WithAuthority requests tokens from authority instead of DefaultAuthority, for national clouds
+func WithAuthority(authority string) Option {
+	return func(o *Options) {
+		o.Authority = authority
+	}
+}
+
+// WithHTTPClient sends token requests with client
+func WithHTTPClient(client *http.Client) Option {
+	return func(o *Options) {
+		o.HTTPClient = client
+	}
+}
+
+// WithCache keeps tokens in cache between runs
+func WithCache(cache *Cache) Option {
+	return func(o *Options) {
+		o.Cache = cache
+	}
+}
+
+// WithScopes requests scopes instead of the default Graph permissions
+func WithScopes(scopes ...string) Option {
+	return func(o *Options) {
+		o.Scopes = scopes
+	}
+}
+
+// WithPrompt calls prompt with the code the user enters to sign in with the device code flow
+func WithPrompt(prompt func(code *DeviceCode)) Option {
+	return func(o *Options) {
+		o.Prompt = prompt
+	}
+}
+
+// newOptions applies opts over the defaults
+func newOptions(opts []Option) *Options {
+	o := &Options{Authority: DefaultAuthority, HTTPClient: http.DefaultClient}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.Logger == nil {
+		o.Logger = slog.New(slog.DiscardHandler)
+	}
+	if o.Prompt == nil {
+		o.Prompt = func(code *DeviceCode) {
+			o.Logger.Info(code.Message)
+		}
+	}
+	return o
+}
@@ -0,0 +1,122 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// GraphScope requests the Graph permissions needed to manage Win32 apps, and a refresh
+// token so that later runs can sign in silently
+var GraphScope = []string{
+	"https://graph.microsoft.com/DeviceManagementApps.ReadWrite.All",
+	"offline_access",
+}
+
+// KeyVaultScope requests access to Azure Key Vault as the signed-in user, and a refresh token
+var KeyVaultScope = []string{
+	"https://vault.azure.net/user_impersonation",
+	"offline_access",
+}
+
+// expiryDelta is how long before its expiry a token is no longer used
+const expiryDelta = 2 * time.Minute
+
+// Token is an OAuth 2.0 access token
+type Token struct {
+	AccessToken  string    `json:"accessToken"`
+	RefreshToken string    `json:"refreshToken,omitempty"`
+	ExpiresAt    time.Time `json:"expiresAt"`
+}
+
+// Valid reports whether the access token can still be used
+func (t *Token) Valid() bool {
+	return t != nil && t.AccessToken != "" && time.Now().Add(expiryDelta).Before(t.ExpiresAt)
+}
+
+// TokenSource returns access tokens for Graph requests
+type TokenSource interface {
+	Token(ctx context.Context) (*Token, error)
+}
+
+// Error is an error response of the identity platform
+type Error struct {
+	// Code is the OAuth 2.0 error code such as invalid_client
+	Code string `json:"error"`
+	// Description explains the error, starting with an AADSTS code
+	Description string `json:"error_description"`
+}
+
+func (e *Error) Error() string {
+	if e.Description == "" {
+		return e.Code
+	}
+	return e.Code + ": " + e.Description
+}
+
+// tokenResponse is the body of a successful token request
+type tokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"`
+}
+
+// endpoint returns the URL of an OAuth 2.0 v2 endpoint of tenantID
+func (o *Options) endpoint(tenantID, name string) string {
+	return strings.TrimSuffix(o.Authority, "/") + "/" + url.PathEscape(tenantID) + "/oauth2/v2.0/" + name
+}
+
+// postForm sends form to endpoint and decodes the JSON response into v. Error responses
+// are returned as *Error.
+func (o *Options) postForm(ctx context.Context, endpoint string, form url.Values, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("failed to create identity platform request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := o.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send identity platform request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return fmt.Errorf("failed to read identity platform response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		var authErr Error
+		if json.Unmarshal(body, &authErr) == nil && authErr.Code != "" {
+			return &authErr
+		}
+		return fmt.Errorf("identity platform request failed with status %s", resp.Status)
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("failed to parse identity platform response: %w", err)
+	}
+	return nil
+}
+
+// requestToken sends a token request and converts the response to a Token
+func (o *Options) requestToken(ctx context.Context, tenantID string, form url.Values) (*Token, error) {
+	var resp tokenResponse
+	if err := o.postForm(ctx, o.endpoint(tenantID, "token"), form, &resp); err != nil {
+		return nil, err
+	}
+	if resp.AccessToken == "" {
+		return nil, errors.New("token response has no access token")
+	}
+	return &Token{
+		AccessToken:  resp.AccessToken,
+		RefreshToken: resp.RefreshToken,
+		ExpiresAt:    time.Now().Add(time.Duration(resp.ExpiresIn) * time.Second),
+	}, nil
+}
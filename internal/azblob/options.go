@@ -0,0 +1,127 @@
+package azblob
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	"github.com/kenchan0130/intunewin/internal/progress"
+	"github.com/kenchan0130/intunewin/internal/retry"
+)
+
+// DefaultChunkSize is the size of the blocks Intune upload scripts use
+const DefaultChunkSize = 6 * 1024 * 1024
+
+// DefaultConcurrency is the number of blocks uploaded at the same time
+const DefaultConcurrency = 4
+
+// RenewFunc returns a new SAS URI for the blob when the current one is about to expire
+type RenewFunc func(ctx context.Context) (string, error)
+
+// Options holds settings for Upload
+type Options struct {
+	// Logger receives diagnostic messages
+	Logger *slog.Logger
+	// HTTPClient sends the storage requests, http.DefaultClient when nil
+	HTTPClient *http.Client
+	// ChunkSize is the size of each block
+	ChunkSize int64
+	// Concurrency is the number of blocks uploaded in parallel
+	Concurrency int
+	// Renew is called when the SAS URI expires; expired uploads fail when nil
+	Renew RenewFunc
+	// Progress receives the number of bytes uploaded
+	Progress progress.Reporter
+	// Retry bounds the retries of a request, including the ones after a SAS renewal
+	Retry retry.Policy
+	// Uploaded are the indexes of blocks an interrupted upload of the same content already put
+	Uploaded []int
+	// BlockUploaded is called with the index of every block once it is put, one call at a time
+	BlockUploaded func(block int) error
+}
+
+// Option configures Options
+type Option func(*Options)
+
+// WithLogger sends diagnostic messages to logger
+func WithLogger(logger *slog.Logger) Option {
+	return func(o *Options) {
+		o.Logger = logger
+	}
+}
+
+// WithHTTPClient sends storage requests with client
+func WithHTTPClient(client *http.Client) Option {
+	return func(o *Options) {
+		o.HTTPClient = client
+	}
+}
+
+// WithChunkSize uploads blocks of size bytes instead of DefaultChunkSize
+func WithChunkSize(size int64) Option {
+	return func(o *Options) {
+		o.ChunkSize = size
+	}
+}
+
+// WithConcurrency uploads n blocks in parallel instead of DefaultConcurrency
+func WithConcurrency(n int) Option {
+	return func(o *Options) {
+		o.Concurrency = n
+	}
+}
+
+// WithRenew calls renew for a new SAS URI when the current one expires during the upload
+func WithRenew(renew RenewFunc) Option {
+	return func(o *Options) {
+		o.Renew = renew
+	}
+}
+
+// WithProgress reports the uploaded bytes to reporter in the progress.Upload stage
+func WithProgress(reporter progress.Reporter) Option {
+	return func(o *Options) {
+		o.Progress = reporter
+	}
+}
+
+// WithRetry retries failed requests as policy allows instead of retry.DefaultPolicy
+func WithRetry(policy retry.Policy) Option {
+	return func(o *Options) {
+		o.Retry = policy
+	}
+}
+
+// WithUploaded skips the blocks at the given indexes, put by an interrupted upload of the
+// same content with the same chunk size, and commits them with the others. Uncommitted
+// blocks are kept by Azure Storage for a week.
+func WithUploaded(blocks []int) Option {
+	return func(o *Options) {
+		o.Uploaded = blocks
+	}
+}
+
+// WithBlockUploaded calls fn with the index of every block once it is put, such as to save
+// the progress of the upload for WithUploaded; an error of fn stops the upload
+func WithBlockUploaded(fn func(block int) error) Option {
+	return func(o *Options) {
+		o.BlockUploaded = fn
+	}
+}
+
+// newOptions applies opts over the defaults
+func newOptions(opts []Option) *Options {
+	o := &Options{
+		HTTPClient:  http.DefaultClient,
+		ChunkSize:   DefaultChunkSize,
+		Concurrency: DefaultConcurrency,
+		Retry:       retry.DefaultPolicy,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.Logger == nil {
+		o.Logger = slog.New(slog.DiscardHandler)
+	}
+	return o
+}
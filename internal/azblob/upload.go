@@ -0,0 +1,368 @@
+package azblob
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kenchan0130/intunewin/internal/progress"
+	"github.com/kenchan0130/intunewin/internal/retry"
+)
+
+// storageVersion is the Azure Storage REST API version of the requests
+const storageVersion = "2021-08-06"
+
+// maxBlocks is the largest number of blocks a block blob can have
+const maxBlocks = 50000
+
+// renewMargin is how long before its expiry a SAS URI is renewed
+const renewMargin = time.Minute
+
+var (
+	// ErrSASExpired is returned when the SAS URI expired and cannot be renewed
+	ErrSASExpired = errors.New("SAS URI expired")
+	// ErrInvalidBlockList is returned when the blob is missing blocks of the block list, such
+	// as blocks of the Uploaded option that Azure Storage discarded while uncommitted
+	ErrInvalidBlockList = errors.New("blob is missing blocks of the block list")
+)
+
+// StorageError is an error response of Azure Storage
+type StorageError struct {
+	StatusCode int
+	Code       string `xml:"Code"`
+	Message    string `xml:"Message"`
+	// RetryAfter is the delay the service asked for before the request is sent again
+	RetryAfter time.Duration `xml:"-"`
+}
+
+func (e *StorageError) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("storage request failed with status %d", e.StatusCode)
+	}
+	return fmt.Sprintf("storage request failed with status %d: %s", e.StatusCode, e.Code)
+}
+
+// authFailed reports whether the request was rejected because of its SAS signature
+func (e *StorageError) authFailed() bool {
+	return e.StatusCode == http.StatusForbidden && e.Code == "AuthenticationFailed"
+}
+
+// sleep waits for d or until ctx is done
+var sleep = func(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// uploader uploads the blocks of one blob
+type uploader struct {
+	opts *Options
+
+	mu       sync.Mutex
+	sasURI   string
+	expiry   time.Time
+	renewals int
+
+	// reported serializes the calls of BlockUploaded
+	reported sync.Mutex
+}
+
+// Upload writes size bytes of r to the block blob at sasURI: the content is split into
+// blocks that are uploaded in parallel and committed with a block list. The SAS URI is
+// renewed with the Renew option when it expires during the upload, and the blocks of the
+// Uploaded option are not uploaded again.
+func Upload(ctx context.Context, sasURI string, r io.ReaderAt, size int64, opts ...Option) error {
+	o := newOptions(opts)
+	if o.ChunkSize <= 0 {
+		return fmt.Errorf("invalid chunk size %d", o.ChunkSize)
+	}
+	blocks := int((size + o.ChunkSize - 1) / o.ChunkSize)
+	if blocks > maxBlocks {
+		return fmt.Errorf("%d bytes need %d blocks of %d bytes, more than the %d blocks of a blob",
+			size, blocks, o.ChunkSize, maxBlocks)
+	}
+
+	u := &uploader{opts: o}
+	if err := u.setSAS(sasURI); err != nil {
+		return err
+	}
+
+	ids := make([]string, blocks)
+	for i := range ids {
+		ids[i] = blockID(i)
+	}
+	counter := progress.NewCounter(o.Progress, progress.Upload, size)
+	skip := make(map[int]bool, len(o.Uploaded))
+	for _, i := range o.Uploaded {
+		if i < 0 || i >= blocks || skip[i] {
+			continue
+		}
+		skip[i] = true
+		counter.Add(min(o.ChunkSize, size-int64(i)*o.ChunkSize))
+	}
+	if len(skip) > 0 {
+		o.Logger.Debug("resuming upload", "uploadedBlocks", len(skip), "blocks", blocks)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	jobs := make(chan int)
+	errs := make(chan error, max(1, o.Concurrency))
+	var wg sync.WaitGroup
+	for range max(1, o.Concurrency) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			buf := make([]byte, o.ChunkSize)
+			for i := range jobs {
+				offset := int64(i) * o.ChunkSize
+				data := buf[:min(o.ChunkSize, size-offset)]
+				if _, err := r.ReadAt(data, offset); err != nil && !errors.Is(err, io.EOF) {
+					errs <- fmt.Errorf("failed to read block %d: %w", i, err)
+					cancel()
+					return
+				}
+				if err := u.putBlock(ctx, ids[i], data); err != nil {
+					errs <- fmt.Errorf("failed to upload block %d: %w", i, err)
+					cancel()
+					return
+				}
+				if err := u.blockUploaded(i); err != nil {
+					errs <- fmt.Errorf("failed to record block %d: %w", i, err)
+					cancel()
+					return
+				}
+				counter.Add(int64(len(data)))
+			}
+		}()
+	}
+
+feed:
+	for i := range ids {
+		if skip[i] {
+			continue
+		}
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	select {
+	case err := <-errs:
+		return err
+	default:
+	}
+	if err := ctx.Err(); err != nil {
+		return err //nolint:wrapcheck // context errors are returned as is
+	}
+
+	if err := u.commit(ctx, ids); err != nil {
+		var storageErr *StorageError
+		if errors.As(err, &storageErr) && storageErr.Code == "InvalidBlockList" {
+			return fmt.Errorf("failed to commit block list: %w: %w", ErrInvalidBlockList, err)
+		}
+		return fmt.Errorf("failed to commit block list: %w", err)
+	}
+	o.Logger.Debug("uploaded blob", "blocks", blocks, "bytes", size, "renewals", u.renewals)
+	return nil
+}
+
+// blockUploaded reports the put block i to the BlockUploaded option
+func (u *uploader) blockUploaded(i int) error {
+	if u.opts.BlockUploaded == nil {
+		return nil
+	}
+	u.reported.Lock()
+	defer u.reported.Unlock()
+	return u.opts.BlockUploaded(i)
+}
+
+// blockID returns the ID of block i. All IDs of a blob must have the same length.
+func blockID(i int) string {
+	return base64.StdEncoding.EncodeToString(fmt.Appendf(nil, "block-%08d", i))
+}
+
+// setSAS records a new SAS URI and its expiry
+func (u *uploader) setSAS(sasURI string) error {
+	parsed, err := url.Parse(sasURI)
+	if err != nil {
+		return fmt.Errorf("invalid SAS URI: %w", err)
+	}
+	u.sasURI = sasURI
+	u.expiry = time.Time{}
+	if se := parsed.Query().Get("se"); se != "" {
+		if expiry, err := time.Parse(time.RFC3339, se); err == nil {
+			u.expiry = expiry
+		}
+	}
+	return nil
+}
+
+// currentSAS returns the SAS URI to use for the next request, renewing it first when it
+// is about to expire
+func (u *uploader) currentSAS(ctx context.Context) (string, error) {
+	u.mu.Lock()
+	sasURI, expiry := u.sasURI, u.expiry
+	u.mu.Unlock()
+
+	if u.opts.Renew != nil && !expiry.IsZero() && time.Until(expiry) < renewMargin {
+		return u.renew(ctx, sasURI)
+	}
+	return sasURI, nil
+}
+
+// renew replaces stale with a new SAS URI, unless another block already renewed it
+func (u *uploader) renew(ctx context.Context, stale string) (string, error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if u.sasURI != stale {
+		return u.sasURI, nil
+	}
+	if u.opts.Renew == nil {
+		return "", ErrSASExpired
+	}
+	renewed, err := u.opts.Renew(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to renew SAS URI: %w", err)
+	}
+	if err := u.setSAS(renewed); err != nil {
+		return "", err
+	}
+	u.renewals++
+	u.opts.Logger.Debug("renewed SAS URI", "expiresAt", u.expiry)
+	return renewed, nil
+}
+
+// putBlock uploads one block
+func (u *uploader) putBlock(ctx context.Context, id string, data []byte) error {
+	return u.do(ctx, func(sasURI string) (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPut,
+			withQuery(sasURI, "comp=block&blockid="+url.QueryEscape(id)), bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create block request: %w", err)
+		}
+		req.ContentLength = int64(len(data))
+		return req, nil
+	})
+}
+
+// blockList is the body of a Put Block List request
+type blockList struct {
+	XMLName xml.Name `xml:"BlockList"`
+	Latest  []string `xml:"Latest"`
+}
+
+// commit writes the block list that makes the uploaded blocks the content of the blob
+func (u *uploader) commit(ctx context.Context, ids []string) error {
+	body, err := xml.Marshal(blockList{Latest: ids})
+	if err != nil {
+		return fmt.Errorf("failed to marshal block list: %w", err)
+	}
+	body = append([]byte(xml.Header), body...)
+
+	return u.do(ctx, func(sasURI string) (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPut, withQuery(sasURI, "comp=blocklist"), bytes.NewReader(body))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create block list request: %w", err)
+		}
+		req.ContentLength = int64(len(body))
+		req.Header.Set("Content-Type", "application/xml")
+		return req, nil
+	})
+}
+
+// do sends the request built by newRequest, renewing the SAS URI when it was rejected and
+// retrying transient failures as the retry policy allows
+func (u *uploader) do(ctx context.Context, newRequest func(sasURI string) (*http.Request, error)) error {
+	policy := u.opts.Retry
+	for attempt := 1; ; attempt++ {
+		sasURI, err := u.currentSAS(ctx)
+		if err != nil {
+			return err
+		}
+		req, err := newRequest(sasURI)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("x-ms-version", storageVersion)
+
+		err = u.send(req)
+		if err == nil {
+			return nil
+		}
+
+		var storageErr *StorageError
+		switch {
+		case attempt > policy.MaxRetries || ctx.Err() != nil:
+			return err
+		case errors.As(err, &storageErr) && storageErr.authFailed():
+			if _, renewErr := u.renew(ctx, sasURI); renewErr != nil {
+				if errors.Is(renewErr, ErrSASExpired) {
+					return fmt.Errorf("%w: %w", ErrSASExpired, err)
+				}
+				return renewErr
+			}
+			continue
+		case errors.As(err, &storageErr) && !retry.Status(storageErr.StatusCode):
+			return err
+		}
+
+		var retryAfter time.Duration
+		if storageErr != nil {
+			retryAfter = storageErr.RetryAfter
+		}
+		delay := policy.Delay(attempt, retryAfter)
+		u.opts.Logger.Debug("retrying storage request", "attempt", attempt, "delay", delay, "error", err)
+		if err := sleep(ctx, delay); err != nil {
+			return err //nolint:wrapcheck // context errors are returned as is
+		}
+	}
+}
+
+// send sends req and converts error responses to *StorageError
+func (u *uploader) send(req *http.Request) error {
+	resp, err := u.opts.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send storage request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusCreated || resp.StatusCode == http.StatusOK {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	storageErr := &StorageError{StatusCode: resp.StatusCode, RetryAfter: retry.RetryAfter(resp.Header, time.Now())}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	_ = xml.Unmarshal(body, storageErr)
+	if storageErr.Code == "" {
+		storageErr.Code = resp.Header.Get("x-ms-error-code")
+	}
+	return storageErr
+}
+
+// withQuery appends query to the query of sasURI without re-encoding the signature
+func withQuery(sasURI, query string) string {
+	if strings.Contains(sasURI, "?") {
+		return sasURI + "&" + query
+	}
+	return sasURI + "?" + query
+}
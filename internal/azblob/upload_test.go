@@ -0,0 +1,271 @@
+package azblob
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/kenchan0130/intunewin/internal/progress"
+	"github.com/kenchan0130/intunewin/internal/retry"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// noSleep makes retries immediate for the duration of a test
+func noSleep(t *testing.T) {
+	t.Helper()
+	orig := sleep
+	sleep = func(ctx context.Context, _ time.Duration) error { return ctx.Err() }
+	t.Cleanup(func() { sleep = orig })
+}
+
+// fakeBlob is a block blob endpoint that accepts requests signed with the current signature
+type fakeBlob struct {
+	t *testing.T
+
+	mu        sync.Mutex
+	signature string
+	blocks    map[string][]byte
+	committed []byte
+	// failures is the number of block requests that fail with a server error
+	failures int
+}
+
+func newFakeBlob(t *testing.T) (*fakeBlob, *httptest.Server) {
+	t.Helper()
+	blob := &fakeBlob{t: t, signature: "sig1", blocks: map[string][]byte{}}
+	server := httptest.NewServer(blob)
+	t.Cleanup(server.Close)
+	return blob, server
+}
+
+func (b *fakeBlob) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	require.NoError(b.t, err)
+	assert.Equal(b.t, http.MethodPut, r.Method)
+	assert.Equal(b.t, storageVersion, r.Header.Get("x-ms-version"))
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	query := r.URL.Query()
+	if query.Get("sig") != b.signature {
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = io.WriteString(w, `<?xml version="1.0" encoding="utf-8"?><Error><Code>AuthenticationFailed</Code>`+
+			`<Message>Signed expiry time has to be after signed start time</Message></Error>`)
+		return
+	}
+
+	switch query.Get("comp") {
+	case "block":
+		if b.failures > 0 {
+			b.failures--
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		b.blocks[query.Get("blockid")] = body
+	case "blocklist":
+		var list blockList
+		require.NoError(b.t, xml.Unmarshal(body, &list))
+		var content []byte
+		for _, id := range list.Latest {
+			block, ok := b.blocks[id]
+			if !ok {
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = io.WriteString(w, `<Error><Code>InvalidBlockList</Code></Error>`)
+				return
+			}
+			content = append(content, block...)
+		}
+		b.committed = content
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+}
+
+// sasURI returns the URI of the blob signed with signature, expiring at expiry
+func sasURI(server *httptest.Server, signature string, expiry time.Time) string {
+	return fmt.Sprintf("%s/container/blob?sv=2021-08-06&se=%s&sr=b&sp=w&sig=%s",
+		server.URL, expiry.UTC().Format(time.RFC3339), signature)
+}
+
+func TestUpload(t *testing.T) {
+	blob, server := newFakeBlob(t)
+	content := bytes.Repeat([]byte("0123456789"), 1000)
+
+	var uploaded atomic.Int64
+	reporter := progress.ReporterFunc(func(stage progress.Stage, current, total int64) {
+		assert.Equal(t, progress.Upload, stage)
+		assert.Equal(t, int64(len(content)), total)
+		uploaded.Store(current)
+	})
+
+	err := Upload(context.Background(), sasURI(server, "sig1", time.Now().Add(time.Hour)),
+		bytes.NewReader(content), int64(len(content)),
+		WithChunkSize(1024), WithConcurrency(3), WithProgress(reporter))
+	require.NoError(t, err)
+	assert.Equal(t, content, blob.committed)
+	assert.Len(t, blob.blocks, 10)
+	assert.Equal(t, int64(len(content)), uploaded.Load())
+}
+
+func TestUploadResumes(t *testing.T) {
+	blob, server := newFakeBlob(t)
+	content := bytes.Repeat([]byte("0123456789"), 1000)
+	uri := sasURI(server, "sig1", time.Now().Add(time.Hour))
+
+	// The first upload stops after four blocks, as when the connection drops
+	var uploaded []int
+	stop := errors.New("connection dropped")
+	err := Upload(context.Background(), uri, bytes.NewReader(content), int64(len(content)),
+		WithChunkSize(1024), WithConcurrency(1), WithBlockUploaded(func(block int) error {
+			uploaded = append(uploaded, block)
+			if len(uploaded) == 4 {
+				return stop
+			}
+			return nil
+		}))
+	require.ErrorIs(t, err, stop)
+	assert.Nil(t, blob.committed)
+
+	var resumed []int
+	var current int64
+	reporter := progress.ReporterFunc(func(_ progress.Stage, n, _ int64) { current = n })
+	err = Upload(context.Background(), uri, bytes.NewReader(content), int64(len(content)),
+		WithChunkSize(1024), WithConcurrency(1), WithUploaded(uploaded), WithProgress(reporter),
+		WithBlockUploaded(func(block int) error {
+			resumed = append(resumed, block)
+			return nil
+		}))
+	require.NoError(t, err)
+	assert.Equal(t, []int{4, 5, 6, 7, 8, 9}, resumed)
+	assert.Equal(t, content, blob.committed)
+	assert.Equal(t, int64(len(content)), current)
+}
+
+func TestUploadRenewsExpiredSAS(t *testing.T) {
+	blob, server := newFakeBlob(t)
+	content := bytes.Repeat([]byte("x"), 5000)
+
+	var renewals atomic.Int32
+	renew := func(context.Context) (string, error) {
+		renewals.Add(1)
+		return sasURI(server, "sig2", time.Now().Add(time.Hour)), nil
+	}
+
+	// The server already rotated its signature, as if the SAS URI expired mid-upload
+	blob.signature = "sig2"
+	err := Upload(context.Background(), sasURI(server, "sig1", time.Now().Add(time.Hour)),
+		bytes.NewReader(content), int64(len(content)),
+		WithChunkSize(1000), WithConcurrency(4), WithRenew(renew))
+	require.NoError(t, err)
+	assert.Equal(t, content, blob.committed)
+	assert.Equal(t, int32(1), renewals.Load(), "concurrent blocks share one renewal")
+}
+
+func TestUploadRenewsBeforeExpiry(t *testing.T) {
+	blob, server := newFakeBlob(t)
+	blob.signature = "sig2"
+	content := []byte("content")
+
+	renew := func(context.Context) (string, error) {
+		return sasURI(server, "sig2", time.Now().Add(time.Hour)), nil
+	}
+	err := Upload(context.Background(), sasURI(server, "sig1", time.Now().Add(10*time.Second)),
+		bytes.NewReader(content), int64(len(content)), WithRenew(renew))
+	require.NoError(t, err)
+	assert.Equal(t, content, blob.committed)
+}
+
+func TestUploadRetriesServerErrors(t *testing.T) {
+	noSleep(t)
+	blob, server := newFakeBlob(t)
+	blob.failures = 2
+	content := []byte("content")
+
+	err := Upload(context.Background(), sasURI(server, "sig1", time.Now().Add(time.Hour)),
+		bytes.NewReader(content), int64(len(content)))
+	require.NoError(t, err)
+	assert.Equal(t, content, blob.committed)
+}
+
+func TestUploadHonorsRetryAfter(t *testing.T) {
+	var delays []time.Duration
+	orig := sleep
+	sleep = func(_ context.Context, d time.Duration) error {
+		delays = append(delays, d)
+		return nil
+	}
+	t.Cleanup(func() { sleep = orig })
+
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) == 1 {
+			w.Header().Set("Retry-After", "3")
+			w.Header().Set("x-ms-error-code", "ServerBusy")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	t.Cleanup(server.Close)
+
+	content := []byte("content")
+	err := Upload(context.Background(), sasURI(server, "sig1", time.Now().Add(time.Hour)),
+		bytes.NewReader(content), int64(len(content)))
+	require.NoError(t, err)
+	assert.Equal(t, []time.Duration{3 * time.Second}, delays)
+}
+
+func TestUploadErrors(t *testing.T) {
+	noSleep(t)
+	content := []byte("content")
+
+	t.Run("expired without renewal", func(t *testing.T) {
+		blob, server := newFakeBlob(t)
+		blob.signature = "sig2"
+		err := Upload(context.Background(), sasURI(server, "sig1", time.Now().Add(time.Hour)),
+			bytes.NewReader(content), int64(len(content)))
+		assert.ErrorIs(t, err, ErrSASExpired)
+		var storageErr *StorageError
+		require.ErrorAs(t, err, &storageErr)
+		assert.Equal(t, "AuthenticationFailed", storageErr.Code)
+	})
+
+	t.Run("persistent server errors", func(t *testing.T) {
+		blob, server := newFakeBlob(t)
+		blob.failures = 3
+		err := Upload(context.Background(), sasURI(server, "sig1", time.Now().Add(time.Hour)),
+			bytes.NewReader(content), int64(len(content)), WithRetry(retry.Policy{MaxRetries: 2}))
+		var storageErr *StorageError
+		require.ErrorAs(t, err, &storageErr)
+		assert.Equal(t, http.StatusServiceUnavailable, storageErr.StatusCode)
+	})
+
+	t.Run("discarded blocks", func(t *testing.T) {
+		_, server := newFakeBlob(t)
+		err := Upload(context.Background(), sasURI(server, "sig1", time.Now().Add(time.Hour)),
+			bytes.NewReader(content), int64(len(content)), WithUploaded([]int{0}))
+		assert.ErrorIs(t, err, ErrInvalidBlockList)
+	})
+
+	t.Run("too many blocks", func(t *testing.T) {
+		err := Upload(context.Background(), "https://example.com/blob", bytes.NewReader(nil), maxBlocks*2, WithChunkSize(1))
+		assert.Error(t, err)
+	})
+}
+
+func TestBlockID(t *testing.T) {
+	assert.Len(t, blockID(0), len(blockID(maxBlocks-1)))
+	assert.NotEqual(t, blockID(1), blockID(2))
+}
@@ -0,0 +1,122 @@
+package batch
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/kenchan0130/intunewin/internal/pack"
+)
+
+// Status is the outcome of packing one app
+type Status string
+
+const (
+	// StatusPacked means the package was written
+	StatusPacked Status = "packed"
+	// StatusFailed means packing failed
+	StatusFailed Status = "failed"
+	// StatusCanceled means the batch was canceled before the app was packed
+	StatusCanceled Status = "canceled"
+)
+
+// Result is the outcome of packing one app
+type Result struct {
+	App       App
+	Status    Status
+	SetupFile string
+	// SHA256 is the hex encoded digest of the package when it was written
+	SHA256   string
+	Duration time.Duration
+	Err      error
+}
+
+// Run packs every app of m, Concurrency at a time, and returns their results in the
+// order of the manifest. A failed app does not stop the others; apps that were not
+// started when ctx is done are canceled.
+func Run(ctx context.Context, m *Manifest, opts ...Option) []Result {
+	o := newOptions(opts)
+	results := make([]Result, len(m.Apps))
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range max(1, o.Concurrency) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = packApp(m.Apps[i], o)
+				o.OnResult(results[i])
+			}
+		}()
+	}
+
+	next := 0
+feed:
+	for ; next < len(m.Apps) && ctx.Err() == nil; next++ {
+		select {
+		case jobs <- next:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	for i := next; i < len(m.Apps); i++ {
+		results[i] = Result{App: m.Apps[i], Status: StatusCanceled, Err: ctx.Err()}
+		o.OnResult(results[i])
+	}
+	return results
+}
+
+// packApp packs one app of the batch
+func packApp(app App, o *Options) Result {
+	start := time.Now()
+	result := Result{App: app, SetupFile: app.SetupFile}
+	o.Logger.Info("packing", "app", app.Name, "source", app.Source, "output", app.Output)
+
+	err := func() error {
+		if result.SetupFile == "" {
+			setupFile, err := pack.DetectSetupFile(app.Source)
+			if err != nil {
+				return fmt.Errorf("failed to detect the setup file, set setupFile: %w", err)
+			}
+			result.SetupFile = setupFile
+		}
+
+		var summary pack.Summary
+		opts := append([]pack.Option{}, o.PackOptions...)
+		opts = append(opts,
+			pack.WithLogger(o.Logger.With("app", app.Name)),
+			pack.WithExclude(app.Exclude...),
+			pack.WithDescription(app.Description),
+			pack.WithSummary(&summary),
+		)
+		if err := pack.PackWithInfo(app.Source, app.Output, app.Name, result.SetupFile, opts...); err != nil {
+			return err //nolint:wrapcheck // pack errors already describe the failure
+		}
+		result.SHA256 = summary.SHA256
+		return nil
+	}()
+
+	result.Duration = time.Since(start)
+	if err != nil {
+		result.Status, result.Err = StatusFailed, err
+		return result
+	}
+	result.Status = StatusPacked
+	return result
+}
+
+// Failed returns the number of results that are not StatusPacked
+func Failed(results []Result) int {
+	n := 0
+	for _, r := range results {
+		if r.Status != StatusPacked {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,143 @@
+package batch
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/kenchan0130/intunewin/internal/unpack"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeFiles creates files below dir, by slash separated path
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0750))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0600))
+	}
+}
+
+func TestParse(t *testing.T) {
+	dir := filepath.FromSlash("/work")
+	m, err := Parse([]byte(`
+apps:
+  - source: apps/7zip
+  - name: Contoso Agent
+    source: /src/agent
+    output: dist/agent.intunewin
+    setupFile: install.cmd
+    description: Agent 1.0
+    exclude: ["*.log", docs]
+`), dir, DefaultOutputTemplate)
+	require.NoError(t, err)
+	require.Len(t, m.Apps, 2)
+
+	assert.Equal(t, App{
+		Name:   "7zip",
+		Source: filepath.Join(dir, "apps", "7zip"),
+		Output: filepath.Join(dir, "7zip.intunewin"),
+	}, m.Apps[0])
+	assert.Equal(t, App{
+		Name:        "Contoso Agent",
+		Source:      filepath.Clean("/src/agent"),
+		Output:      filepath.Join(dir, "dist", "agent.intunewin"),
+		SetupFile:   "install.cmd",
+		Description: "Agent 1.0",
+		Exclude:     []string{"*.log", "docs"},
+	}, m.Apps[1])
+}
+
+func TestParseOutputTemplate(t *testing.T) {
+	dir := filepath.FromSlash("/work")
+	m, err := Parse([]byte("apps:\n  - source: a\n    setupFile: setup.msi"), dir, "dist/{{.Name}}-{{.SetupFile}}.intunewin")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "dist", "a-setup.msi.intunewin"), m.Apps[0].Output)
+
+	_, err = Parse([]byte("apps:\n  - source: a"), dir, "{{.Version}}.intunewin")
+	assert.Error(t, err)
+	_, err = Parse([]byte("apps:\n  - source: a"), dir, "{{")
+	assert.Error(t, err)
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := map[string]string{
+		"empty":            ``,
+		"no apps":          `apps: []`,
+		"missing source":   "apps:\n  - name: a",
+		"unknown field":    "apps:\n  - source: a\n    setup: b",
+		"duplicate output": "apps:\n  - source: a\n    output: x.intunewin\n  - source: b\n    output: x.intunewin",
+	}
+	for name, manifest := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := Parse([]byte(manifest), t.TempDir(), DefaultOutputTemplate)
+			assert.ErrorIs(t, err, ErrInvalidManifest)
+		})
+	}
+}
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"a/setup.exe":   "a",
+		"a/debug.log":   "log",
+		"b/install.cmd": "b",
+		"b/other.exe":   "other",
+		"c/readme.txt":  "no setup file",
+	})
+	m, err := Parse([]byte(`
+apps:
+  - source: a
+    exclude: ["*.log"]
+  - source: b
+    setupFile: install.cmd
+    output: out/b.intunewin
+  - source: c
+`), dir, DefaultOutputTemplate)
+	require.NoError(t, err)
+
+	var mu sync.Mutex
+	var reported []string
+	results := Run(context.Background(), m, WithConcurrency(2), WithOnResult(func(r Result) {
+		mu.Lock()
+		defer mu.Unlock()
+		reported = append(reported, r.App.Name)
+	}))
+
+	require.Len(t, results, 3)
+	assert.Equal(t, StatusPacked, results[0].Status, results[0].Err)
+	assert.Equal(t, "setup.exe", results[0].SetupFile)
+	assert.Len(t, results[0].SHA256, 64)
+	assert.Equal(t, StatusPacked, results[1].Status, results[1].Err)
+	assert.Equal(t, StatusFailed, results[2].Status)
+	assert.Error(t, results[2].Err)
+	assert.Equal(t, 1, Failed(results))
+	assert.ElementsMatch(t, []string{"a", "b", "c"}, reported)
+
+	entries, err := unpack.List(filepath.Join(dir, "a.intunewin"))
+	require.NoError(t, err)
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Path)
+	}
+	assert.Equal(t, []string{"setup.exe"}, names, "excluded files are not packed")
+	assert.FileExists(t, filepath.Join(dir, "out", "b.intunewin"))
+}
+
+func TestRunCanceled(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"a/setup.exe": "a"})
+	m, err := Parse([]byte("apps:\n  - source: a"), dir, DefaultOutputTemplate)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	results := Run(ctx, m)
+	require.Len(t, results, 1)
+	assert.Equal(t, StatusCanceled, results[0].Status)
+	assert.ErrorIs(t, results[0].Err, context.Canceled)
+}
@@ -0,0 +1,109 @@
+// Package batch packs the apps listed in a manifest file in parallel.
+package batch
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultOutputTemplate names the package of an app without an output
+const DefaultOutputTemplate = "{{.Name}}.intunewin"
+
+// ErrInvalidManifest is returned for a manifest that cannot be packed
+var ErrInvalidManifest = errors.New("invalid batch manifest")
+
+// Manifest lists the apps of a batch
+type Manifest struct {
+	Apps []App `yaml:"apps"`
+}
+
+// App describes one package of a batch. Relative paths are relative to the folder of
+// the manifest file.
+type App struct {
+	// Name is recorded in Detection.xml, the name of the source folder when empty
+	Name string `yaml:"name"`
+	// Source is the folder to pack
+	Source string `yaml:"source"`
+	// Output is the package to write, named by the output template when empty
+	Output string `yaml:"output"`
+	// SetupFile is relative to Source and detected when empty
+	SetupFile string `yaml:"setupFile"`
+	// Description is recorded in Detection.xml when set
+	Description string `yaml:"description"`
+	// Exclude lists patterns of files left out of the package, as pack.WithExclude takes them
+	Exclude []string `yaml:"exclude"`
+}
+
+// Load reads the manifest at path and resolves the paths of its apps. Apps without an
+// output are named by outputTemplate, a text/template executed with the App.
+func Load(path, outputTemplate string) (*Manifest, error) {
+	data, err := os.ReadFile(path) // #nosec G304 -- path is chosen by the user
+	if err != nil {
+		return nil, fmt.Errorf("failed to read batch manifest: %w", err)
+	}
+	m, err := Parse(data, filepath.Dir(path), outputTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return m, nil
+}
+
+// Parse parses a YAML manifest whose relative paths are relative to dir, fills in the
+// defaults of its apps and validates them
+func Parse(data []byte, dir, outputTemplate string) (*Manifest, error) {
+	tmpl, err := template.New("output").Option("missingkey=error").Parse(outputTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid output template: %w", err)
+	}
+
+	var m Manifest
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&m); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidManifest, err)
+	}
+	if len(m.Apps) == 0 {
+		return nil, fmt.Errorf("%w: no apps", ErrInvalidManifest)
+	}
+
+	outputs := map[string]int{}
+	for i := range m.Apps {
+		app := &m.Apps[i]
+		if app.Source == "" {
+			return nil, fmt.Errorf("%w: app %d has no source", ErrInvalidManifest, i+1)
+		}
+		app.Source = resolve(dir, app.Source)
+		if app.Name == "" {
+			app.Name = filepath.Base(app.Source)
+		}
+		if app.Output == "" {
+			var output strings.Builder
+			if err := tmpl.Execute(&output, app); err != nil {
+				return nil, fmt.Errorf("failed to name the output of app %d: %w", i+1, err)
+			}
+			app.Output = output.String()
+		}
+		app.Output = resolve(dir, app.Output)
+		if j, ok := outputs[app.Output]; ok {
+			return nil, fmt.Errorf("%w: apps %d and %d are both written to %s", ErrInvalidManifest, j+1, i+1, app.Output)
+		}
+		outputs[app.Output] = i
+	}
+	return &m, nil
+}
+
+// resolve returns path, relative to dir unless it is absolute
+func resolve(dir, path string) string {
+	if filepath.IsAbs(path) {
+		return filepath.Clean(path)
+	}
+	return filepath.Join(dir, path)
+}
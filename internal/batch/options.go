@@ -0,0 +1,68 @@
+package batch
+
+import (
+	"log/slog"
+
+	"github.com/kenchan0130/intunewin/internal/pack"
+)
+
+// DefaultConcurrency is the number of apps packed at the same time
+const DefaultConcurrency = 2
+
+// Options holds settings for Run
+type Options struct {
+	// Logger receives diagnostic messages
+	Logger *slog.Logger
+	// Concurrency is the number of apps packed in parallel
+	Concurrency int
+	// PackOptions are applied to every app before its own settings
+	PackOptions []pack.Option
+	// OnResult is called with the result of each app as soon as it is done
+	OnResult func(Result)
+}
+
+// Option configures Options
+type Option func(*Options)
+
+// WithLogger sends diagnostic messages to logger
+func WithLogger(logger *slog.Logger) Option {
+	return func(o *Options) {
+		o.Logger = logger
+	}
+}
+
+// WithConcurrency packs n apps in parallel instead of DefaultConcurrency
+func WithConcurrency(n int) Option {
+	return func(o *Options) {
+		o.Concurrency = n
+	}
+}
+
+// WithPackOptions applies opts when packing every app
+func WithPackOptions(opts ...pack.Option) Option {
+	return func(o *Options) {
+		o.PackOptions = append(o.PackOptions, opts...)
+	}
+}
+
+// WithOnResult calls f with the result of each app as soon as it is done
+func WithOnResult(f func(Result)) Option {
+	return func(o *Options) {
+		o.OnResult = f
+	}
+}
+
+// newOptions applies opts over the defaults
+func newOptions(opts []Option) *Options {
+	o := &Options{Concurrency: DefaultConcurrency}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.Logger == nil {
+		o.Logger = slog.New(slog.DiscardHandler)
+	}
+	if o.OnResult == nil {
+		o.OnResult = func(Result) {}
+	}
+	return o
+}
@@ -0,0 +1,68 @@
+package buildinfo
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"runtime"
+	"time"
+)
+
+// Path is the location of the build information inside an intunewin package.
+// Intune only reads Detection.xml and the encrypted contents, so the entry is ignored on upload.
+const Path = "IntuneWinPackage/Metadata/BuildInfo.json"
+
+// Info describes the environment and options that produced a package
+type Info struct {
+	Tool        string `json:"tool"`
+	ToolVersion string `json:"toolVersion"`
+	OS          string `json:"os"`
+	Arch        string `json:"arch"`
+	GoVersion   string `json:"goVersion"`
+	// Hostname is only recorded when requested, as it can be sensitive
+	Hostname  string    `json:"hostname,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+	// Options lists the pack options that affect the output, without key material
+	Options map[string]string `json:"options,omitempty"`
+}
+
+// hostname returns the name of the packaging machine
+var hostname = os.Hostname
+
+// New describes the current environment. The hostname is recorded when withHostname is set.
+func New(toolVersion string, withHostname bool) *Info {
+	info := &Info{
+		Tool:        "intunewin",
+		ToolVersion: toolVersion,
+		OS:          runtime.GOOS,
+		Arch:        runtime.GOARCH,
+		GoVersion:   runtime.Version(),
+	}
+	if withHostname {
+		if name, err := hostname(); err == nil {
+			info.Hostname = name
+		}
+	}
+	return info
+}
+
+// ToJSON converts the build information to indented JSON
+func (i *Info) ToJSON() ([]byte, error) {
+	data, err := json.MarshalIndent(i, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal build info to JSON: %w", err)
+	}
+	return data, nil
+}
+
+// Parse parses build information written by ToJSON
+func Parse(data []byte) (*Info, error) {
+	var info Info
+	if err := json.Unmarshal(data, &info); err != nil {
+		return nil, fmt.Errorf("failed to parse build info: %w", err)
+	}
+	if info.Tool == "" {
+		return nil, fmt.Errorf("failed to parse build info: tool is missing")
+	}
+	return &info, nil
+}
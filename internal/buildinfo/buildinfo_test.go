@@ -0,0 +1,45 @@
+package buildinfo
+
+import (
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	original := hostname
+	hostname = func() (string, error) { return "build-agent-07", nil }
+	t.Cleanup(func() { hostname = original })
+
+	info := New("1.2.3", false)
+	assert.Equal(t, "intunewin", info.Tool)
+	assert.Equal(t, "1.2.3", info.ToolVersion)
+	assert.Equal(t, runtime.GOOS, info.OS)
+	assert.Empty(t, info.Hostname)
+
+	assert.Equal(t, "build-agent-07", New("1.2.3", true).Hostname)
+}
+
+func TestRoundTrip(t *testing.T) {
+	info := New("1.2.3", false)
+	info.CreatedAt = time.Date(2024, time.May, 1, 12, 0, 0, 0, time.UTC)
+	info.Options = map[string]string{"reproducible": "true"}
+
+	data, err := info.ToJSON()
+	require.NoError(t, err)
+
+	parsed, err := Parse(data)
+	require.NoError(t, err)
+	assert.Equal(t, info, parsed)
+}
+
+func TestParseInvalid(t *testing.T) {
+	_, err := Parse([]byte(`{"os":"linux"}`))
+	assert.Error(t, err)
+
+	_, err = Parse([]byte(`not json`))
+	assert.Error(t, err)
+}
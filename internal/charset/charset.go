@@ -0,0 +1,111 @@
+// Package charset decodes the legacy code pages that zip archivers use for file names
+// without the UTF-8 flag: Shift-JIS (as Windows-31J, written by Japanese Windows) and
+// CP437, the original zip code page.
+package charset
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/japanese"
+)
+
+// cp437High maps the bytes 0x80-0xFF of CP437 to runes; the lower half is ASCII
+const cp437High = "ÇüéâäàåçêëèïîìÄÅÉæÆôöòûùÿÖÜ¢£¥₧ƒáíóúñÑªº¿⌐¬½¼¡«»░▒▓│┤╡╢╖╕╣║╗╝╜╛┐└┴┬├─┼╞╟╚╔╩╦╠═╬╧╨╤╥╙╘╒╓╫╪┘┌█▄▌▐▀αßΓπΣσµτΦΘΩδ∞φε∩≡±≥≤⌠⌡÷≈°∙·√ⁿ²■\u00a0"
+
+// cp437 is cp437High as runes
+var cp437 = []rune(cp437High)
+
+// DecodeCP437 returns s, read as CP437, as UTF-8. Every byte sequence is valid CP437.
+func DecodeCP437(s string) string {
+	var b strings.Builder
+	b.Grow(len(s) * 2)
+	for i := range len(s) {
+		if c := s[i]; c < 0x80 {
+			b.WriteByte(c)
+		} else {
+			b.WriteRune(cp437[c-0x80])
+		}
+	}
+	return b.String()
+}
+
+// DecodeShiftJIS returns s, read as Windows-31J, as UTF-8, and false when s is not valid
+// Windows-31J. User-defined characters (lead bytes 0xF0-0xF9) are not decoded.
+func DecodeShiftJIS(s string) (string, bool) {
+	decoded, err := japanese.ShiftJIS.NewDecoder().String(s)
+	// Invalid and unmapped codes are decoded as U+FFFD, which no Windows-31J code maps to
+	if err != nil || strings.ContainsRune(decoded, utf8.RuneError) {
+		return "", false
+	}
+	return decoded, true
+}
+
+// Encoding is a character encoding of zip entry names
+type Encoding string
+
+const (
+	// Auto detects the encoding of the names, see Detect
+	Auto Encoding = "auto"
+	// UTF8 reads names as UTF-8, as written by most current archivers
+	UTF8 Encoding = "utf-8"
+	// ShiftJIS reads names as Windows-31J, as written on Japanese Windows
+	ShiftJIS Encoding = "shift_jis"
+	// CP437 reads names as CP437, the code page of the zip format
+	CP437 Encoding = "cp437"
+)
+
+// Parse returns the encoding named name, ignoring case and accepting common aliases such as
+// "sjis", "cp932" and "ibm437", and false for an unknown name
+func Parse(name string) (Encoding, bool) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", "auto":
+		return Auto, true
+	case "utf-8", "utf8":
+		return UTF8, true
+	case "shift_jis", "shift-jis", "sjis", "cp932", "windows-31j", "ms932":
+		return ShiftJIS, true
+	case "cp437", "ibm437", "437":
+		return CP437, true
+	default:
+		return "", false
+	}
+}
+
+// Detect returns the encoding that decodes every name: UTF8 when all names are valid UTF-8,
+// as ASCII names are, else ShiftJIS when all are valid Windows-31J, else CP437
+func Detect(names []string) Encoding {
+	allUTF8, allShiftJIS := true, true
+	for _, name := range names {
+		if allUTF8 && !utf8.ValidString(name) {
+			allUTF8 = false
+		}
+		if allShiftJIS {
+			if _, ok := DecodeShiftJIS(name); !ok {
+				allShiftJIS = false
+			}
+		}
+	}
+	switch {
+	case allUTF8:
+		return UTF8
+	case allShiftJIS:
+		return ShiftJIS
+	default:
+		return CP437
+	}
+}
+
+// Decode returns name, read in encoding enc, as UTF-8. Names that are not valid in enc are
+// returned as they are; Auto is read as UTF8.
+func Decode(enc Encoding, name string) string {
+	switch enc {
+	case ShiftJIS:
+		if decoded, ok := DecodeShiftJIS(name); ok {
+			return decoded
+		}
+	case CP437:
+		return DecodeCP437(name)
+	}
+	return name
+}
@@ -0,0 +1,67 @@
+package charset
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecodeShiftJIS(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+		ok    bool
+	}{
+		{input: "setup.exe", want: "setup.exe", ok: true},
+		// The trail bytes of ソ and 表 are 0x5C, the byte of a backslash
+		{input: "\x83\x5c\x83t\x83g/\x95\x5c\x8e\xa6.exe", want: "ソフト/表示.exe", ok: true},
+		{input: "\xb1\xcc\xdf\xd8.txt", want: "ｱﾌﾟﾘ.txt", ok: true},
+		{input: "\xfb\xfc\x8b\xb4.txt", want: "髙橋.txt", ok: true},
+		{input: "\x87\x40", want: "①", ok: true},
+		{input: "\x83", ok: false},
+		{input: "\x82.txt", ok: false},
+		{input: "\xf0\x40", ok: false},
+	}
+	for _, tt := range tests {
+		got, ok := DecodeShiftJIS(tt.input)
+		assert.Equal(t, tt.ok, ok, tt.input)
+		assert.Equal(t, tt.want, got, tt.input)
+	}
+}
+
+func TestDecodeCP437(t *testing.T) {
+	assert.Equal(t, "Ünïcode.txt", DecodeCP437("\x9an\x8bcode.txt"))
+	assert.Equal(t, "café\u00a0", DecodeCP437("caf\x82\xff"))
+}
+
+func TestDetect(t *testing.T) {
+	assert.Equal(t, UTF8, Detect([]string{"setup.exe", "日本語.txt"}))
+	assert.Equal(t, ShiftJIS, Detect([]string{"setup.exe", "\x95\x5c\x8e\xa6.exe"}))
+	assert.Equal(t, CP437, Detect([]string{"\x95\x5c\x8e\xa6.exe", "caf\x82.txt"}))
+	assert.Equal(t, UTF8, Detect(nil))
+}
+
+func TestParse(t *testing.T) {
+	for name, want := range map[string]Encoding{
+		"":          Auto,
+		"AUTO":      Auto,
+		"utf8":      UTF8,
+		"SJIS":      ShiftJIS,
+		"cp932":     ShiftJIS,
+		"shift_jis": ShiftJIS,
+		"IBM437":    CP437,
+	} {
+		enc, ok := Parse(name)
+		assert.True(t, ok, name)
+		assert.Equal(t, want, enc, name)
+	}
+	_, ok := Parse("latin1")
+	assert.False(t, ok)
+}
+
+func TestDecode(t *testing.T) {
+	assert.Equal(t, "表示.exe", Decode(ShiftJIS, "\x95\x5c\x8e\xa6.exe"))
+	assert.Equal(t, "caf\x82.txt", Decode(ShiftJIS, "caf\x82.txt"))
+	assert.Equal(t, "café.txt", Decode(CP437, "caf\x82.txt"))
+	assert.Equal(t, "caf\x82.txt", Decode(UTF8, "caf\x82.txt"))
+}
@@ -0,0 +1,160 @@
+// Package config reads default flag values from a configuration file and from
+// INTUNEWIN_* environment variables.
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+)
+
+// FileName is the name of the configuration file in the configuration directory
+const FileName = "config.yaml"
+
+// EnvPrefix is the prefix of the environment variables that set flags
+const EnvPrefix = "INTUNEWIN_"
+
+// ErrInvalidConfig is returned for a configuration file that cannot be applied
+var ErrInvalidConfig = errors.New("invalid configuration")
+
+// Config holds default flag values by flag name. Values at the top level apply to every
+// command with the flag, values below commands only to the named command, such as
+// "pack" or "auth login".
+type Config struct {
+	Commands map[string]map[string]any `yaml:"commands"`
+	Defaults map[string]any            `yaml:",inline"`
+}
+
+// Path returns the configuration file in configDir
+func Path(configDir string) string {
+	return filepath.Join(configDir, FileName)
+}
+
+// Load reads the configuration file at path. The error wraps fs.ErrNotExist when there
+// is no such file.
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path) // #nosec G304 -- path is chosen by the user
+	if err != nil {
+		return nil, fmt.Errorf("failed to read configuration: %w", err)
+	}
+	c, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return c, nil
+}
+
+// Parse parses a YAML configuration
+func Parse(data []byte) (*Config, error) {
+	var c Config
+	if err := yaml.NewDecoder(bytes.NewReader(data)).Decode(&c); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidConfig, err)
+	}
+	return &c, nil
+}
+
+// EnvName returns the environment variable that sets the flag name, such as
+// INTUNEWIN_COMPRESSION_LEVEL for compression-level
+func EnvName(name string) string {
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+// Apply sets the flags of fs that were not given on the command line, for the command
+// at path: from their environment variable first, then from the configuration. List
+// flags take YAML lists, or comma separated environment variables. Flags for which
+// skip returns true are left alone.
+func (c *Config) Apply(fs *pflag.FlagSet, path string, getenv func(string) string, skip func(*pflag.Flag) bool) error {
+	var errs []error
+	fs.VisitAll(func(f *pflag.Flag) {
+		if f.Changed || skip(f) {
+			return
+		}
+		if value := getenv(EnvName(f.Name)); value != "" {
+			if err := Set(fs, f, splitList(f, value)); err != nil {
+				errs = append(errs, fmt.Errorf("%w: %s: %w", ErrInvalidConfig, EnvName(f.Name), err))
+			}
+			return
+		}
+		value, ok := c.lookup(path, f.Name)
+		if !ok {
+			return
+		}
+		values, err := toStrings(value)
+		if err == nil {
+			err = Set(fs, f, values)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%w: %s: %w", ErrInvalidConfig, f.Name, err))
+		}
+	})
+	return errors.Join(errs...)
+}
+
+// lookup returns the value of the flag name for the command at path
+func (c *Config) lookup(path, name string) (any, bool) {
+	if c == nil {
+		return nil, false
+	}
+	if value, ok := c.Commands[path][name]; ok {
+		return value, true
+	}
+	value, ok := c.Defaults[name]
+	return value, ok
+}
+
+// Set replaces the value of the flag f of fs with values and marks it as changed. Only
+// list flags take several values.
+func Set(fs *pflag.FlagSet, f *pflag.Flag, values []string) error {
+	if slice, ok := f.Value.(pflag.SliceValue); ok {
+		if err := slice.Replace(values); err != nil {
+			return err //nolint:wrapcheck // wrapped by the caller
+		}
+		f.Changed = true
+		return nil
+	}
+	if len(values) != 1 {
+		return fmt.Errorf("--%s takes a single value, got %d", f.Name, len(values))
+	}
+	return fs.Set(f.Name, values[0]) //nolint:wrapcheck // wrapped by the caller
+}
+
+// splitList splits the comma separated environment variable value of a list flag
+func splitList(f *pflag.Flag, value string) []string {
+	if _, ok := f.Value.(pflag.SliceValue); !ok {
+		return []string{value}
+	}
+	values := strings.Split(value, ",")
+	for i := range values {
+		values[i] = strings.TrimSpace(values[i])
+	}
+	return values
+}
+
+// toStrings converts a YAML scalar or list to flag values
+func toStrings(value any) ([]string, error) {
+	switch v := value.(type) {
+	case []any:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			s, err := toStrings(item)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, s...)
+		}
+		return values, nil
+	case map[string]any:
+		return nil, errors.New("expected a value or a list, got a mapping")
+	case nil:
+		return []string{""}, nil
+	default:
+		return []string{fmt.Sprint(v)}, nil
+	}
+}
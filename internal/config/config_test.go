@@ -0,0 +1,119 @@
+package config
+
+import (
+	"io/fs"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newFlags returns a flag set with flags of every kind used by the commands
+func newFlags() *pflag.FlagSet {
+	fs := pflag.NewFlagSet("pack", pflag.ContinueOnError)
+	fs.Int("compression-level", -1, "")
+	fs.String("tenant-id", "", "")
+	fs.Bool("reproducible", false, "")
+	fs.StringArray("exclude", nil, "")
+	fs.StringSlice("store-ext", nil, "")
+	fs.Int("jobs", 2, "")
+	return fs
+}
+
+func noSkip(*pflag.Flag) bool { return false }
+
+func env(values map[string]string) func(string) string {
+	return func(key string) string { return values[key] }
+}
+
+func TestApply(t *testing.T) {
+	c, err := Parse([]byte(`
+compression-level: 9
+tenant-id: contoso.onmicrosoft.com
+reproducible: true
+exclude: ["*.log", docs]
+jobs: 3
+commands:
+  batch:
+    jobs: 8
+`))
+	require.NoError(t, err)
+
+	t.Run("configuration", func(t *testing.T) {
+		fs := newFlags()
+		require.NoError(t, c.Apply(fs, "pack", env(nil), noSkip))
+		level, _ := fs.GetInt("compression-level")
+		assert.Equal(t, 9, level)
+		reproducible, _ := fs.GetBool("reproducible")
+		assert.True(t, reproducible)
+		excludes, _ := fs.GetStringArray("exclude")
+		assert.Equal(t, []string{"*.log", "docs"}, excludes)
+		jobs, _ := fs.GetInt("jobs")
+		assert.Equal(t, 3, jobs)
+		assert.True(t, fs.Changed("exclude"))
+	})
+
+	t.Run("command section", func(t *testing.T) {
+		fs := newFlags()
+		require.NoError(t, c.Apply(fs, "batch", env(nil), noSkip))
+		jobs, _ := fs.GetInt("jobs")
+		assert.Equal(t, 8, jobs)
+	})
+
+	t.Run("environment over configuration", func(t *testing.T) {
+		fs := newFlags()
+		require.NoError(t, c.Apply(fs, "pack", env(map[string]string{
+			"INTUNEWIN_COMPRESSION_LEVEL": "1",
+			"INTUNEWIN_STORE_EXT":         ".bin, .dat",
+		}), noSkip))
+		level, _ := fs.GetInt("compression-level")
+		assert.Equal(t, 1, level)
+		exts, _ := fs.GetStringSlice("store-ext")
+		assert.Equal(t, []string{".bin", ".dat"}, exts)
+	})
+
+	t.Run("flags over environment and configuration", func(t *testing.T) {
+		fs := newFlags()
+		require.NoError(t, fs.Parse([]string{"--compression-level", "5", "--exclude", "*.tmp"}))
+		require.NoError(t, c.Apply(fs, "pack", env(map[string]string{"INTUNEWIN_COMPRESSION_LEVEL": "1"}), noSkip))
+		level, _ := fs.GetInt("compression-level")
+		assert.Equal(t, 5, level)
+		excludes, _ := fs.GetStringArray("exclude")
+		assert.Equal(t, []string{"*.tmp"}, excludes)
+	})
+
+	t.Run("skipped flags", func(t *testing.T) {
+		fs := newFlags()
+		require.NoError(t, c.Apply(fs, "pack", env(nil), func(f *pflag.Flag) bool { return f.Name == "reproducible" }))
+		assert.False(t, fs.Changed("reproducible"))
+	})
+}
+
+func TestApplyErrors(t *testing.T) {
+	c, err := Parse([]byte("compression-level: high\nreproducible: [true, false]\ntenant-id: {a: b}\n"))
+	require.NoError(t, err)
+	err = c.Apply(newFlags(), "pack", env(map[string]string{"INTUNEWIN_JOBS": "many"}), noSkip)
+	require.ErrorIs(t, err, ErrInvalidConfig)
+	for _, name := range []string{"compression-level", "reproducible", "tenant-id", "INTUNEWIN_JOBS"} {
+		assert.Contains(t, err.Error(), name)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	_, err := Load(filepath.Join(t.TempDir(), FileName))
+	require.ErrorIs(t, err, fs.ErrNotExist)
+
+	_, err = Parse([]byte("- not a mapping"))
+	require.ErrorIs(t, err, ErrInvalidConfig)
+
+	c, err := Parse(nil)
+	require.NoError(t, err)
+	require.NoError(t, c.Apply(newFlags(), "pack", env(nil), noSkip))
+}
+
+func TestEnvName(t *testing.T) {
+	assert.Equal(t, "INTUNEWIN_COMPRESSION_LEVEL", EnvName("compression-level"))
+	assert.Equal(t, "INTUNEWIN_TENANT_ID", EnvName("tenant-id"))
+}
@@ -1,13 +1,33 @@
 package crypto
 
 import (
+	"bytes"
 	"crypto/aes"
 	"crypto/cipher"
 	"crypto/hmac"
 	"crypto/rand"
 	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
 	"fmt"
+	"hash"
 	"io"
+	"sync"
+
+	"github.com/kenchan0130/intunewin/internal/spill"
+)
+
+var (
+	// ErrInvalidKeys is returned when key material has the wrong size
+	ErrInvalidKeys = errors.New("invalid encryption keys")
+	// ErrHMACMismatch is returned when the HMAC of encrypted data does not match,
+	// because the data is corrupt or the MAC key is wrong
+	ErrHMACMismatch = errors.New("HMAC verification failed")
+	// ErrInvalidPadding is returned when decrypted data has invalid PKCS7 padding,
+	// usually because the encryption key is wrong
+	ErrInvalidPadding = errors.New("invalid padding")
+	// ErrInvalidLength is returned when encrypted data is too short or not made of whole blocks
+	ErrInvalidLength = errors.New("invalid encrypted data length")
 )
 
 // EncryptionInfo contains encryption metadata
@@ -21,6 +41,21 @@ type EncryptionInfo struct {
 	FileDigestAlgorithm  string
 }
 
+// Zero overwrites key material with zeros once it is no longer needed. This is best effort:
+// copies made by the runtime, or strings such as the base64 values of Detection.xml, remain.
+func Zero(buffers ...[]byte) {
+	for _, b := range buffers {
+		clear(b)
+	}
+}
+
+// Zero overwrites the encryption key, MAC key and IV of info with zeros
+func (info *EncryptionInfo) Zero() {
+	if info != nil {
+		Zero(info.EncryptionKey, info.MacKey, info.InitializationVector)
+	}
+}
+
 // GenerateKeys generates encryption key, MAC key, and IV
 func GenerateKeys() (encryptionKey, macKey, iv []byte, err error) {
 	// Generate 256-bit AES key for encryption
@@ -44,32 +79,73 @@ func GenerateKeys() (encryptionKey, macKey, iv []byte, err error) {
 	return encryptionKey, macKey, iv, nil
 }
 
+// ValidateKeys checks that caller supplied key material has the sizes used by Intune
+func ValidateKeys(encryptionKey, macKey, iv []byte) error {
+	if err := ValidateDecryptionKeys(encryptionKey, macKey); err != nil {
+		return err
+	}
+	if len(iv) != aes.BlockSize {
+		return fmt.Errorf("%w: IV must be %d bytes, got %d", ErrInvalidKeys, aes.BlockSize, len(iv))
+	}
+	return nil
+}
+
+// ValidateDecryptionKeys checks the sizes of the keys Decrypt needs; the IV is read from
+// the encrypted data
+func ValidateDecryptionKeys(encryptionKey, macKey []byte) error {
+	if len(encryptionKey) != 32 {
+		return fmt.Errorf("%w: encryption key must be 32 bytes, got %d", ErrInvalidKeys, len(encryptionKey))
+	}
+	if len(macKey) != 32 {
+		return fmt.Errorf("%w: MAC key must be 32 bytes, got %d", ErrInvalidKeys, len(macKey))
+	}
+	return nil
+}
+
+// encryptChunkSize is the amount of plaintext encrypted at a time, a multiple of aes.BlockSize
+const encryptChunkSize = 64 * 1024
+
+// chunkPool holds the buffers chunks are encrypted and decrypted in, with room for a block
+// of padding after a full chunk so the last one is padded in place
+var chunkPool = sync.Pool{
+	New: func() any {
+		buf := make([]byte, encryptChunkSize, encryptChunkSize+aes.BlockSize)
+		return &buf
+	},
+}
+
+// getChunk takes a chunk buffer from the pool
+func getChunk() *[]byte {
+	return chunkPool.Get().(*[]byte)
+}
+
+// putChunk returns a chunk buffer taken with getChunk to the pool
+func putChunk(buf *[]byte) {
+	chunkPool.Put(buf)
+}
+
 // Encrypt encrypts data using AES-256-CBC and writes to output with HMAC
 // Format: [HMAC(32 bytes)][IV(16 bytes)][Encrypted Data]
+// When output is an io.WriteSeeker (such as *os.File) the data is streamed and the HMAC
+// is written afterwards; otherwise the encrypted data is buffered in memory.
 func Encrypt(input io.Reader, output io.Writer, encryptionKey, macKey, iv []byte) ([]byte, error) {
 	block, err := aes.NewCipher(encryptionKey)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create cipher: %w", err)
 	}
 
-	// Read all input data
-	plaintext, err := io.ReadAll(input)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read input: %w", err)
-	}
-
-	// Apply PKCS7 padding
-	plaintext = pkcs7Pad(plaintext, aes.BlockSize)
-
-	// Encrypt data
-	mode := cipher.NewCBCEncrypter(block, iv)
-	ciphertext := make([]byte, len(plaintext))
-	mode.CryptBlocks(ciphertext, plaintext)
-
 	// Compute HMAC over IV + encrypted data
 	h := hmac.New(sha256.New, macKey)
 	h.Write(iv)
-	h.Write(ciphertext)
+
+	if ws, ok := output.(io.WriteSeeker); ok {
+		return encryptSeekable(input, ws, block, h, iv)
+	}
+
+	ciphertext := new(bytes.Buffer)
+	if err := encryptStream(input, io.MultiWriter(ciphertext, h), block, iv); err != nil {
+		return nil, err
+	}
 	mac := h.Sum(nil)
 
 	// Write to output: [HMAC][IV][Encrypted Data]
@@ -79,97 +155,217 @@ func Encrypt(input io.Reader, output io.Writer, encryptionKey, macKey, iv []byte
 	if _, err := output.Write(iv); err != nil {
 		return nil, fmt.Errorf("failed to write IV: %w", err)
 	}
-	if _, err := output.Write(ciphertext); err != nil {
+	if _, err := ciphertext.WriteTo(output); err != nil {
 		return nil, fmt.Errorf("failed to write encrypted data: %w", err)
 	}
 
 	return mac, nil
 }
 
-// pkcs7Pad adds PKCS7 padding to data
+// encryptSeekable streams the encrypted data to output after a placeholder for the HMAC,
+// then seeks back to fill in the HMAC
+func encryptSeekable(input io.Reader, output io.WriteSeeker, block cipher.Block, h hash.Hash, iv []byte) ([]byte, error) {
+	start, err := output.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get output position: %w", err)
+	}
+
+	if _, err := output.Write(make([]byte, h.Size())); err != nil {
+		return nil, fmt.Errorf("failed to write HMAC: %w", err)
+	}
+	if _, err := output.Write(iv); err != nil {
+		return nil, fmt.Errorf("failed to write IV: %w", err)
+	}
+	if err := encryptStream(input, io.MultiWriter(output, h), block, iv); err != nil {
+		return nil, err
+	}
+	mac := h.Sum(nil)
+
+	end, err := output.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get output position: %w", err)
+	}
+	if _, err := output.Seek(start, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("failed to seek to HMAC: %w", err)
+	}
+	if _, err := output.Write(mac); err != nil {
+		return nil, fmt.Errorf("failed to write HMAC: %w", err)
+	}
+	if _, err := output.Seek(end, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("failed to seek to end of output: %w", err)
+	}
+
+	return mac, nil
+}
+
+// encryptStream encrypts input in chunks with PKCS7 padding applied to the last one
+func encryptStream(input io.Reader, output io.Writer, block cipher.Block, iv []byte) error {
+	mode := cipher.NewCBCEncrypter(block, iv)
+	pooled := getChunk()
+	defer putChunk(pooled)
+	buf := *pooled
+	for {
+		n, err := io.ReadFull(input, buf)
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			last := pkcs7Pad(buf[:n], aes.BlockSize)
+			mode.CryptBlocks(last, last)
+			if _, err := output.Write(last); err != nil {
+				return fmt.Errorf("failed to write encrypted data: %w", err)
+			}
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read input: %w", err)
+		}
+
+		mode.CryptBlocks(buf, buf)
+		if _, err := output.Write(buf); err != nil {
+			return fmt.Errorf("failed to write encrypted data: %w", err)
+		}
+	}
+}
+
+// pkcs7Pad adds PKCS7 padding to data, in place when data has the capacity for it
 func pkcs7Pad(data []byte, blockSize int) []byte {
 	padding := blockSize - (len(data) % blockSize)
-	padText := make([]byte, padding)
-	for i := range padText {
-		padText[i] = byte(padding)
+	for range padding {
+		data = append(data, byte(padding))
 	}
-	return append(data, padText...)
+	return data
 }
 
 // Decrypt decrypts data using AES-256-CBC
 // Format: [HMAC(32 bytes)][IV(16 bytes)][Encrypted Data]
+// The HMAC is verified before the data is decrypted, so the padding of data that was not
+// created with macKey is never inspected and nothing is written to output. When input is an
+// io.ReadSeeker (such as *os.File) it is verified in a first pass and the data is
+// decrypted in a second; otherwise the encrypted data is verified while it is copied
+// to a buffer that moves to a temporary file beyond spill.DefaultThreshold, so neither
+// has to fit in memory.
 func Decrypt(input io.Reader, output io.Writer, encryptionKey, macKey []byte) error {
-	// Read HMAC
-	storedMac := make([]byte, 32)
-	if _, err := io.ReadFull(input, storedMac); err != nil {
-		return fmt.Errorf("failed to read HMAC: %w", err)
+	if err := ValidateDecryptionKeys(encryptionKey, macKey); err != nil {
+		return err
 	}
 
-	// Read IV
-	iv := make([]byte, aes.BlockSize)
-	if _, err := io.ReadFull(input, iv); err != nil {
-		return fmt.Errorf("failed to read IV: %w", err)
+	storedMac, iv, err := readHeader(input)
+	if err != nil {
+		return err
 	}
 
-	// Read all encrypted data for HMAC verification
-	encryptedData, err := io.ReadAll(input)
+	pooled := getChunk()
+	defer putChunk(pooled)
+
+	rs, ok := input.(io.ReadSeeker)
+	if !ok {
+		buffer := spill.New("", spill.DefaultThreshold, "intunewin-encrypted-*")
+		defer buffer.Close()
+		size, err := verifyHMAC(input, buffer, macKey, storedMac, iv, *pooled)
+		if err != nil {
+			return err
+		}
+		if _, err := buffer.Seek(0, io.SeekStart); err != nil {
+			return fmt.Errorf("failed to rewind encrypted data: %w", err)
+		}
+		return decryptVerified(buffer, output, encryptionKey, iv, size, *pooled)
+	}
+
+	dataStart, err := rs.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return fmt.Errorf("failed to get input position: %w", err)
+	}
+	size, err := verifyHMAC(rs, io.Discard, macKey, storedMac, iv, *pooled)
 	if err != nil {
-		return fmt.Errorf("failed to read encrypted data: %w", err)
+		return err
+	}
+	if _, err := rs.Seek(dataStart, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to rewind encrypted data: %w", err)
 	}
+	return decryptVerified(rs, output, encryptionKey, iv, size, *pooled)
+}
 
-	// Verify HMAC
+// readHeader reads the HMAC and IV preceding the encrypted data
+func readHeader(input io.Reader) (storedMac, iv []byte, err error) {
+	storedMac = make([]byte, sha256.Size)
+	if _, err := io.ReadFull(input, storedMac); err != nil {
+		return nil, nil, fmt.Errorf("failed to read HMAC: %w", err)
+	}
+	iv = make([]byte, aes.BlockSize)
+	if _, err := io.ReadFull(input, iv); err != nil {
+		return nil, nil, fmt.Errorf("failed to read IV: %w", err)
+	}
+	return storedMac, iv, nil
+}
+
+// verifyHMAC computes the HMAC over iv and the rest of input while copying input to copyTo,
+// compares it with storedMac and returns the size of the encrypted data
+func verifyHMAC(input io.Reader, copyTo io.Writer, macKey, storedMac, iv, buf []byte) (int64, error) {
 	h := hmac.New(sha256.New, macKey)
 	h.Write(iv)
-	h.Write(encryptedData)
-	computedMac := h.Sum(nil)
-
-	if !hmac.Equal(storedMac, computedMac) {
-		return fmt.Errorf("HMAC verification failed")
+	// Hide any WriterTo so buf is used instead of a new buffer
+	size, err := io.CopyBuffer(io.MultiWriter(h, copyTo), struct{ io.Reader }{input}, buf)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read encrypted data: %w", err)
+	}
+	if !hmac.Equal(storedMac, h.Sum(nil)) {
+		return 0, ErrHMACMismatch
 	}
+	return size, nil
+}
 
-	// Decrypt data
+// decryptVerified decrypts size bytes of input whose HMAC has been verified
+func decryptVerified(input io.Reader, output io.Writer, encryptionKey, iv []byte, size int64, buf []byte) error {
 	block, err := aes.NewCipher(encryptionKey)
 	if err != nil {
 		return fmt.Errorf("failed to create cipher: %w", err)
 	}
-
-	if len(encryptedData)%aes.BlockSize != 0 {
-		return fmt.Errorf("encrypted data length is not a multiple of block size")
+	// Padding always adds at least one block
+	if size < aes.BlockSize || size%aes.BlockSize != 0 {
+		return fmt.Errorf("%w: %d bytes is not a positive multiple of the %d byte block size", ErrInvalidLength, size, aes.BlockSize)
 	}
+	return decryptStream(input, output, block, iv, size, buf)
+}
 
+// decryptStream decrypts size bytes of input in chunks of buf and removes the PKCS7 padding from the last one
+func decryptStream(input io.Reader, output io.Writer, block cipher.Block, iv []byte, size int64, buf []byte) error {
 	mode := cipher.NewCBCDecrypter(block, iv)
-	plaintext := make([]byte, len(encryptedData))
-	mode.CryptBlocks(plaintext, encryptedData)
-
-	// Remove PKCS7 padding
-	plaintext, err = pkcs7Unpad(plaintext, aes.BlockSize)
-	if err != nil {
-		return fmt.Errorf("failed to remove padding: %w", err)
-	}
+	for remaining := size; remaining > 0; {
+		chunk := buf[:min(int64(len(buf)), remaining)]
+		if _, err := io.ReadFull(input, chunk); err != nil {
+			return fmt.Errorf("failed to read encrypted data: %w", err)
+		}
+		mode.CryptBlocks(chunk, chunk)
+		remaining -= int64(len(chunk))
 
-	if _, err := output.Write(plaintext); err != nil {
-		return fmt.Errorf("failed to write decrypted data: %w", err)
+		if remaining == 0 {
+			var err error
+			if chunk, err = pkcs7Unpad(chunk, aes.BlockSize); err != nil {
+				return fmt.Errorf("failed to remove padding: %w", err)
+			}
+		}
+		if _, err := output.Write(chunk); err != nil {
+			return fmt.Errorf("failed to write decrypted data: %w", err)
+		}
 	}
-
 	return nil
 }
 
-// pkcs7Unpad removes PKCS7 padding from data
+// pkcs7Unpad removes PKCS7 padding from data, which must be whole blocks.
+// The padding is checked in constant time so its validity cannot be learned from timing.
 func pkcs7Unpad(data []byte, blockSize int) ([]byte, error) {
-	if len(data) == 0 {
-		return nil, fmt.Errorf("data is empty")
+	if len(data) == 0 || len(data)%blockSize != 0 {
+		return nil, fmt.Errorf("%w: %d bytes is not a positive multiple of the %d byte block size", ErrInvalidLength, len(data), blockSize)
 	}
 
-	padding := int(data[len(data)-1])
-	if padding > blockSize || padding == 0 {
-		return nil, fmt.Errorf("invalid padding")
+	last := data[len(data)-blockSize:]
+	padding := int(last[blockSize-1])
+	good := subtle.ConstantTimeLessOrEq(1, padding) & subtle.ConstantTimeLessOrEq(padding, blockSize)
+	for i, b := range last {
+		// Every byte within the padding must hold its length
+		inPadding := subtle.ConstantTimeLessOrEq(blockSize-i, padding)
+		good &= subtle.ConstantTimeByteEq(b, byte(padding)) | (inPadding ^ 1)
 	}
-
-	// Verify padding
-	for i := len(data) - padding; i < len(data); i++ {
-		if data[i] != byte(padding) {
-			return nil, fmt.Errorf("invalid padding")
-		}
+	if good != 1 {
+		return nil, ErrInvalidPadding
 	}
 
 	return data[:len(data)-padding], nil
@@ -2,6 +2,12 @@ package crypto
 
 import (
 	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -17,6 +23,20 @@ func TestGenerateKeys(t *testing.T) {
 	assert.Len(t, iv, 16, "IV should be 16 bytes")
 }
 
+func TestZero(t *testing.T) {
+	encKey, macKey, iv, err := GenerateKeys()
+	require.NoError(t, err)
+	info := &EncryptionInfo{EncryptionKey: encKey, MacKey: macKey, InitializationVector: iv}
+
+	info.Zero()
+	assert.Equal(t, make([]byte, 32), encKey)
+	assert.Equal(t, make([]byte, 32), macKey)
+	assert.Equal(t, make([]byte, 16), iv)
+
+	var nilInfo *EncryptionInfo
+	assert.NotPanics(t, nilInfo.Zero)
+}
+
 func TestEncryptDecrypt(t *testing.T) {
 	// Generate keys
 	encKey, macKey, iv, err := GenerateKeys()
@@ -42,6 +62,36 @@ func TestEncryptDecrypt(t *testing.T) {
 	assert.Equal(t, plaintext, decrypted.Bytes(), "Decrypted data should match original plaintext")
 }
 
+func TestEncryptKnownAnswer(t *testing.T) {
+	// CBC-AES256 vector F.2.5 of NIST SP 800-38A, followed by a full block of padding
+	decode := func(s string) []byte {
+		b, err := hex.DecodeString(s)
+		require.NoError(t, err)
+		return b
+	}
+	encKey := decode("603deb1015ca71be2b73aef0857d77811f352c073b6108d72d9810a30914dff4")
+	macKey := bytes.Repeat([]byte{0x42}, 32)
+	iv := decode("000102030405060708090a0b0c0d0e0f")
+	plaintext := decode("6bc1bee22e409f96e93d7e117393172aae2d8a571e03ac9c9eb76fac45af8e51" +
+		"30c81c46a35ce411e5fbc1191a0a52eff69f2445df4f9b17ad2b417be66c3710")
+	ciphertext := decode("f58c4c04d6e5f1ba779eabfb5f7bfbd69cfc4e967edb808d679f777bc6702c7d" +
+		"39f23369a9d9bacfa530e26304231461b2eb05e2c39be9fcda6c19078c6a9d1b")
+
+	encrypted := new(bytes.Buffer)
+	mac, err := Encrypt(bytes.NewReader(plaintext), encrypted, encKey, macKey, iv)
+	require.NoError(t, err)
+
+	out := encrypted.Bytes()
+	require.Len(t, out, 32+16+len(ciphertext)+16)
+	assert.Equal(t, mac, out[:32])
+	assert.Equal(t, iv, out[32:48])
+	assert.Equal(t, ciphertext, out[48:48+len(ciphertext)])
+
+	h := hmac.New(sha256.New, macKey)
+	h.Write(out[32:])
+	assert.Equal(t, h.Sum(nil), mac, "the HMAC covers the IV and the encrypted data")
+}
+
 func TestDecryptWithWrongKey(t *testing.T) {
 	// Generate keys
 	encKey, macKey, iv, err := GenerateKeys()
@@ -82,7 +132,7 @@ func TestDecryptWithWrongMacKey(t *testing.T) {
 	decrypted := new(bytes.Buffer)
 	err = Decrypt(bytes.NewReader(encrypted.Bytes()), decrypted, encKey, wrongMacKey)
 	assert.Error(t, err, "Decryption should fail with wrong MAC key")
-	assert.Contains(t, err.Error(), "HMAC verification failed")
+	assert.ErrorIs(t, err, ErrHMACMismatch)
 }
 
 func TestComputeFileDigest(t *testing.T) {
@@ -136,3 +186,192 @@ func TestPKCS7Padding(t *testing.T) {
 		})
 	}
 }
+
+func TestPKCS7UnpadInvalid(t *testing.T) {
+	block := func(tail ...byte) []byte {
+		return append(make([]byte, 16-len(tail)), tail...)
+	}
+	tests := []struct {
+		name string
+		data []byte
+		err  error
+	}{
+		{"Empty", nil, ErrInvalidLength},
+		{"Partial block", make([]byte, 15), ErrInvalidLength},
+		{"Zero padding", block(0), ErrInvalidPadding},
+		{"Padding longer than a block", block(17), ErrInvalidPadding},
+		{"Inconsistent padding", block(3, 2, 3), ErrInvalidPadding},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := pkcs7Unpad(tt.data, 16)
+			assert.ErrorIs(t, err, tt.err)
+		})
+	}
+
+	unpadded, err := pkcs7Unpad(block(16, 16, 16, 16, 16, 16, 16, 16, 16, 16, 16, 16, 16, 16, 16, 16), 16)
+	require.NoError(t, err)
+	assert.Empty(t, unpadded)
+}
+
+func TestDecryptInvalidLength(t *testing.T) {
+	encKey, macKey, iv, err := GenerateKeys()
+	require.NoError(t, err)
+
+	// A valid HMAC over data that is not whole blocks
+	data := make([]byte, 8)
+	h := hmac.New(sha256.New, macKey)
+	h.Write(iv)
+	h.Write(data)
+	encrypted := append(append(h.Sum(nil), iv...), data...)
+
+	decrypted := new(bytes.Buffer)
+	err = Decrypt(bytes.NewReader(encrypted), decrypted, encKey, macKey)
+	assert.ErrorIs(t, err, ErrInvalidLength)
+	assert.Zero(t, decrypted.Len())
+
+	assert.ErrorIs(t, Decrypt(bytes.NewReader(encrypted), decrypted, encKey[:16], macKey), ErrInvalidKeys)
+}
+
+func TestEncryptSeekableOutput(t *testing.T) {
+	encKey, macKey, iv, err := GenerateKeys()
+	require.NoError(t, err)
+
+	// Cross several chunk boundaries with a partial last chunk
+	plaintext := make([]byte, encryptChunkSize*3+123)
+	for i := range plaintext {
+		plaintext[i] = byte(i)
+	}
+
+	buffered := new(bytes.Buffer)
+	bufferedMac, err := Encrypt(bytes.NewReader(plaintext), buffered, encKey, macKey, iv)
+	require.NoError(t, err)
+
+	// Write after some existing content to check the HMAC lands at the right offset
+	f, err := os.Create(filepath.Join(t.TempDir(), "encrypted"))
+	require.NoError(t, err)
+	defer f.Close()
+	_, err = f.Write([]byte("prefix"))
+	require.NoError(t, err)
+
+	streamedMac, err := Encrypt(bytes.NewReader(plaintext), f, encKey, macKey, iv)
+	require.NoError(t, err)
+	assert.Equal(t, bufferedMac, streamedMac)
+
+	streamed, err := os.ReadFile(f.Name())
+	require.NoError(t, err)
+	assert.Equal(t, append([]byte("prefix"), buffered.Bytes()...), streamed)
+
+	decrypted := new(bytes.Buffer)
+	require.NoError(t, Decrypt(bytes.NewReader(streamed[len("prefix"):]), decrypted, encKey, macKey))
+	assert.Equal(t, plaintext, decrypted.Bytes())
+}
+
+func TestEncryptChunkAligned(t *testing.T) {
+	encKey, macKey, iv, err := GenerateKeys()
+	require.NoError(t, err)
+
+	// A chunk-aligned input still gets a full block of padding
+	plaintext := make([]byte, encryptChunkSize)
+	encrypted := new(bytes.Buffer)
+	_, err = Encrypt(bytes.NewReader(plaintext), encrypted, encKey, macKey, iv)
+	require.NoError(t, err)
+	assert.Equal(t, 32+16+encryptChunkSize+16, encrypted.Len())
+
+	decrypted := new(bytes.Buffer)
+	require.NoError(t, Decrypt(bytes.NewReader(encrypted.Bytes()), decrypted, encKey, macKey))
+	assert.Equal(t, plaintext, decrypted.Bytes())
+}
+
+func TestDecryptSeekableInput(t *testing.T) {
+	encKey, macKey, iv, err := GenerateKeys()
+	require.NoError(t, err)
+
+	plaintext := make([]byte, encryptChunkSize*2+7)
+	for i := range plaintext {
+		plaintext[i] = byte(i)
+	}
+	encrypted := new(bytes.Buffer)
+	_, err = Encrypt(bytes.NewReader(plaintext), encrypted, encKey, macKey, iv)
+	require.NoError(t, err)
+
+	// Decrypt from a file positioned after some existing content
+	path := filepath.Join(t.TempDir(), "encrypted")
+	require.NoError(t, os.WriteFile(path, append([]byte("prefix"), encrypted.Bytes()...), 0600))
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+	_, err = f.Seek(int64(len("prefix")), io.SeekStart)
+	require.NoError(t, err)
+
+	decrypted := new(bytes.Buffer)
+	require.NoError(t, Decrypt(f, decrypted, encKey, macKey))
+	assert.Equal(t, plaintext, decrypted.Bytes())
+
+	// Tampered data is rejected before anything is written
+	tampered := bytes.Clone(encrypted.Bytes())
+	tampered[len(tampered)-1] ^= 0xFF
+	decrypted.Reset()
+	err = Decrypt(bytes.NewReader(tampered), decrypted, encKey, macKey)
+	assert.ErrorIs(t, err, ErrHMACMismatch)
+	assert.Zero(t, decrypted.Len())
+}
+
+func TestAllocationsDoNotGrowWithSize(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping allocation measurements in short mode")
+	}
+	encKey, macKey, iv, err := GenerateKeys()
+	require.NoError(t, err)
+
+	f, err := os.Create(filepath.Join(t.TempDir(), "encrypted"))
+	require.NoError(t, err)
+	defer f.Close()
+
+	allocs := func(size int) (encrypt, decrypt float64) {
+		plaintext := make([]byte, size)
+		encrypted := new(bytes.Buffer)
+		_, err := Encrypt(bytes.NewReader(plaintext), encrypted, encKey, macKey, iv)
+		require.NoError(t, err)
+
+		encrypt = testing.AllocsPerRun(5, func() {
+			_, _ = f.Seek(0, io.SeekStart)
+			_, _ = Encrypt(bytes.NewReader(plaintext), f, encKey, macKey, iv)
+		})
+		decrypt = testing.AllocsPerRun(5, func() {
+			_ = Decrypt(bytes.NewReader(encrypted.Bytes()), io.Discard, encKey, macKey)
+		})
+		return encrypt, decrypt
+	}
+
+	smallEncrypt, smallDecrypt := allocs(encryptChunkSize + 1)
+	largeEncrypt, largeDecrypt := allocs(encryptChunkSize*16 + 1)
+	assert.Equal(t, smallEncrypt, largeEncrypt, "encryption should reuse its chunk buffer")
+	assert.Equal(t, smallDecrypt, largeDecrypt, "decryption should reuse its chunk buffer")
+}
+
+func TestDecryptNonSeekableInput(t *testing.T) {
+	encKey, macKey, iv, err := GenerateKeys()
+	require.NoError(t, err)
+
+	plaintext := make([]byte, encryptChunkSize*2+7)
+	for i := range plaintext {
+		plaintext[i] = byte(i)
+	}
+	encrypted := new(bytes.Buffer)
+	_, err = Encrypt(bytes.NewReader(plaintext), encrypted, encKey, macKey, iv)
+	require.NoError(t, err)
+
+	// Hide Seek so the data is verified while it is buffered
+	decrypted := new(bytes.Buffer)
+	require.NoError(t, Decrypt(struct{ io.Reader }{bytes.NewReader(encrypted.Bytes())}, decrypted, encKey, macKey))
+	assert.Equal(t, plaintext, decrypted.Bytes())
+
+	tampered := bytes.Clone(encrypted.Bytes())
+	tampered[len(tampered)/2] ^= 0xFF
+	decrypted.Reset()
+	err = Decrypt(struct{ io.Reader }{bytes.NewReader(tampered)}, decrypted, encKey, macKey)
+	assert.ErrorIs(t, err, ErrHMACMismatch)
+	assert.Zero(t, decrypted.Len())
+}
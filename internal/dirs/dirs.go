@@ -0,0 +1,249 @@
+package dirs
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// appName is the directory name used below each base directory
+const appName = "intunewin"
+
+// Layout holds the per-user directories used by intunewin
+type Layout struct {
+	// Cache holds data that can be recreated at any time
+	Cache string
+	// Config holds user configuration
+	Config string
+	// State holds data that should persist between runs, such as tokens
+	State string
+}
+
+// Resolve returns the directory layout for the current user.
+// XDG base directories are used on Linux and macOS, Known Folders on Windows.
+func Resolve() (*Layout, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to determine home directory: %w", err)
+	}
+	return resolve(runtime.GOOS, os.Getenv, home), nil
+}
+
+// resolve computes the layout for goos using getenv to look up environment variables
+func resolve(goos string, getenv func(string) string, home string) *Layout {
+	if goos == "windows" {
+		localAppData := envOr(getenv, "LOCALAPPDATA", filepath.Join(home, "AppData", "Local"))
+		roamingAppData := envOr(getenv, "APPDATA", filepath.Join(home, "AppData", "Roaming"))
+		return &Layout{
+			Cache:  filepath.Join(localAppData, appName, "Cache"),
+			Config: filepath.Join(roamingAppData, appName, "Config"),
+			State:  filepath.Join(localAppData, appName, "State"),
+		}
+	}
+
+	return &Layout{
+		Cache:  filepath.Join(envOr(getenv, "XDG_CACHE_HOME", filepath.Join(home, ".cache")), appName),
+		Config: filepath.Join(envOr(getenv, "XDG_CONFIG_HOME", filepath.Join(home, ".config")), appName),
+		State:  filepath.Join(envOr(getenv, "XDG_STATE_HOME", filepath.Join(home, ".local", "state")), appName),
+	}
+}
+
+// envOr returns the value of key, or fallback when it is unset or not absolute
+func envOr(getenv func(string) string, key, fallback string) string {
+	// The XDG specification requires relative paths to be ignored
+	if value := getenv(key); value != "" && filepath.IsAbs(value) {
+		return value
+	}
+	return fallback
+}
+
+// TempDir returns the directory for temporary workspaces inside the cache
+func (l *Layout) TempDir() string {
+	return filepath.Join(l.Cache, "tmp")
+}
+
+// lockSuffix is appended to the path of a workspace to name its lock file
+const lockSuffix = ".lock"
+
+// errLocked is returned by lockFile when another open file holds the lock
+var errLocked = errors.New("file is locked")
+
+// lockGrace is how long a new lock file is left alone, since it is created before it is locked
+const lockGrace = time.Minute
+
+// Workspace is a temporary directory inside TempDir. Its lock file is held until Remove,
+// so CleanCache leaves the workspace alone while it is in use.
+type Workspace struct {
+	// Dir is the path of the workspace
+	Dir  string
+	lock *os.File
+}
+
+// NewWorkspace creates a new, uniquely named workspace inside TempDir.
+// It is safe to call from concurrent processes.
+func (l *Layout) NewWorkspace(pattern string) (*Workspace, error) {
+	if err := os.MkdirAll(l.TempDir(), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	if !strings.Contains(pattern, "*") {
+		pattern += "*"
+	}
+	// The lock file comes first, so CleanCache never finds the workspace without it
+	lock, err := os.CreateTemp(l.TempDir(), pattern+lockSuffix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	w := &Workspace{Dir: strings.TrimSuffix(lock.Name(), lockSuffix), lock: lock}
+	if err := lockFile(lock); err != nil && !errors.Is(err, errors.ErrUnsupported) {
+		_ = w.Remove()
+		return nil, err
+	}
+	if err := os.Mkdir(w.Dir, 0700); err != nil {
+		_ = w.Remove()
+		return nil, fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	return w, nil
+}
+
+// Remove deletes the workspace and its lock file
+func (w *Workspace) Remove() error {
+	err := os.RemoveAll(w.Dir)
+	_ = w.lock.Close()
+	if rmErr := os.Remove(w.lock.Name()); rmErr != nil && !os.IsNotExist(rmErr) && err == nil {
+		err = rmErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to remove workspace: %w", err)
+	}
+	return nil
+}
+
+// CleanCache removes everything inside the cache directory except the workspaces in use,
+// and returns the number of bytes freed
+func (l *Layout) CleanCache() (int64, error) {
+	entries, err := os.ReadDir(l.Cache)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("failed to read cache directory: %w", err)
+	}
+
+	var freed int64
+	for _, entry := range entries {
+		path := filepath.Join(l.Cache, entry.Name())
+		var size int64
+		if path == l.TempDir() {
+			size, err = cleanTempDir(path)
+		} else {
+			size, err = removeAll(path)
+		}
+		freed += size
+		if err != nil {
+			return freed, err
+		}
+	}
+	return freed, nil
+}
+
+// cleanTempDir removes the workspaces in dir that are not in use and returns the number
+// of bytes freed
+func cleanTempDir(dir string) (int64, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read temp directory: %w", err)
+	}
+
+	var freed int64
+	seen := make(map[string]bool)
+	for _, entry := range entries {
+		workspace := filepath.Join(dir, strings.TrimSuffix(entry.Name(), lockSuffix))
+		if seen[workspace] {
+			continue
+		}
+		seen[workspace] = true
+		size, err := removeUnused(workspace)
+		freed += size
+		if err != nil {
+			return freed, err
+		}
+	}
+	return freed, nil
+}
+
+// removeUnused removes workspace and its lock file unless another process holds the lock,
+// and returns the number of bytes freed
+func removeUnused(workspace string) (int64, error) {
+	lock, err := os.OpenFile(workspace+lockSuffix, os.O_RDWR, 0)
+	if os.IsNotExist(err) {
+		// Leftovers without a lock file are not in use
+		return removeAll(workspace)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to open lock file: %w", err)
+	}
+	defer lock.Close()
+
+	info, err := lock.Stat()
+	if err != nil {
+		return 0, fmt.Errorf("failed to stat lock file: %w", err)
+	}
+	if time.Since(info.ModTime()) < lockGrace {
+		return 0, nil
+	}
+	if err := lockFile(lock); err != nil {
+		if errors.Is(err, errLocked) || errors.Is(err, errors.ErrUnsupported) {
+			return 0, nil
+		}
+		return 0, err
+	}
+
+	size, err := removeAll(workspace)
+	if err != nil {
+		return size, err
+	}
+	// Windows cannot remove a file that is still open
+	_ = lock.Close()
+	if err := os.Remove(lock.Name()); err != nil && !os.IsNotExist(err) {
+		return size, fmt.Errorf("failed to remove %s: %w", lock.Name(), err)
+	}
+	return size, nil
+}
+
+// removeAll removes path and returns the number of bytes freed
+func removeAll(path string) (int64, error) {
+	size, err := diskUsage(path)
+	if err != nil {
+		return 0, err
+	}
+	if err := os.RemoveAll(path); err != nil {
+		return 0, fmt.Errorf("failed to remove %s: %w", path, err)
+	}
+	return size, nil
+}
+
+// diskUsage returns the total size of the regular files below path
+func diskUsage(path string) (int64, error) {
+	var size int64
+	err := filepath.Walk(path, func(_ string, info os.FileInfo, err error) error {
+		if err != nil {
+			// Entries may disappear while another process cleans up its workspace
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if info.Mode().IsRegular() {
+			size += info.Size()
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to compute size of %s: %w", path, err)
+	}
+	return size, nil
+}
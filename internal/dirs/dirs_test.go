@@ -0,0 +1,175 @@
+package dirs
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolve(t *testing.T) {
+	home := filepath.Join(string(filepath.Separator), "home", "user")
+	env := func(values map[string]string) func(string) string {
+		return func(key string) string { return values[key] }
+	}
+
+	tests := []struct {
+		name string
+		goos string
+		env  map[string]string
+		want *Layout
+	}{
+		{
+			name: "Linux defaults",
+			goos: "linux",
+			env:  map[string]string{},
+			want: &Layout{
+				Cache:  filepath.Join(home, ".cache", "intunewin"),
+				Config: filepath.Join(home, ".config", "intunewin"),
+				State:  filepath.Join(home, ".local", "state", "intunewin"),
+			},
+		},
+		{
+			name: "XDG variables",
+			goos: "darwin",
+			env: map[string]string{
+				"XDG_CACHE_HOME":  filepath.Join(home, "cache"),
+				"XDG_CONFIG_HOME": filepath.Join(home, "config"),
+				"XDG_STATE_HOME":  filepath.Join(home, "state"),
+			},
+			want: &Layout{
+				Cache:  filepath.Join(home, "cache", "intunewin"),
+				Config: filepath.Join(home, "config", "intunewin"),
+				State:  filepath.Join(home, "state", "intunewin"),
+			},
+		},
+		{
+			name: "Relative XDG variables are ignored",
+			goos: "linux",
+			env:  map[string]string{"XDG_CACHE_HOME": "relative"},
+			want: &Layout{
+				Cache:  filepath.Join(home, ".cache", "intunewin"),
+				Config: filepath.Join(home, ".config", "intunewin"),
+				State:  filepath.Join(home, ".local", "state", "intunewin"),
+			},
+		},
+		{
+			name: "Windows known folders",
+			goos: "windows",
+			env: map[string]string{
+				"LOCALAPPDATA": filepath.Join(home, "Local"),
+				"APPDATA":      filepath.Join(home, "Roaming"),
+			},
+			want: &Layout{
+				Cache:  filepath.Join(home, "Local", "intunewin", "Cache"),
+				Config: filepath.Join(home, "Roaming", "intunewin", "Config"),
+				State:  filepath.Join(home, "Local", "intunewin", "State"),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, resolve(tt.goos, env(tt.env), home))
+		})
+	}
+}
+
+func TestNewWorkspaceConcurrent(t *testing.T) {
+	layout := &Layout{Cache: filepath.Join(t.TempDir(), "cache")}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	created := make(map[string]bool)
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			workspace, err := layout.NewWorkspace("test-*")
+			if !assert.NoError(t, err) {
+				return
+			}
+			t.Cleanup(func() { _ = workspace.Remove() })
+			assert.DirExists(t, workspace.Dir)
+			mu.Lock()
+			created[workspace.Dir] = true
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	assert.Len(t, created, 10)
+}
+
+func TestCleanCache(t *testing.T) {
+	layout := &Layout{Cache: filepath.Join(t.TempDir(), "cache")}
+
+	// Cleaning a missing cache is not an error
+	freed, err := layout.CleanCache()
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), freed)
+
+	dir := filepath.Join(layout.Cache, "data")
+	require.NoError(t, os.MkdirAll(dir, 0700))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "file.bin"), make([]byte, 100), 0600))
+	workspace, err := layout.NewWorkspace("test-*")
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(workspace.Dir, "file.bin"), make([]byte, 50), 0600))
+	require.NoError(t, workspace.Remove())
+
+	freed, err = layout.CleanCache()
+	require.NoError(t, err)
+	assert.Equal(t, int64(100), freed)
+
+	entries, err := os.ReadDir(layout.Cache)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "tmp", entries[0].Name())
+	entries, err = os.ReadDir(layout.TempDir())
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestCleanCacheKeepsOpenWorkspace(t *testing.T) {
+	layout := &Layout{Cache: filepath.Join(t.TempDir(), "cache")}
+	old := time.Now().Add(-time.Hour)
+
+	open, err := layout.NewWorkspace("open-*")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = open.Remove() })
+	require.NoError(t, os.WriteFile(filepath.Join(open.Dir, "file.bin"), make([]byte, 10), 0600))
+	require.NoError(t, os.Chtimes(open.Dir+lockSuffix, old, old))
+
+	// A process that exited without removing its workspace leaves it unlocked
+	abandoned, err := layout.NewWorkspace("abandoned-*")
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(abandoned.Dir, "file.bin"), make([]byte, 20), 0600))
+	require.NoError(t, abandoned.lock.Close())
+	require.NoError(t, os.Chtimes(abandoned.Dir+lockSuffix, old, old))
+
+	// A workspace whose lock file was just created may not be locked yet
+	created, err := layout.NewWorkspace("created-*")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = created.Remove() })
+	require.NoError(t, created.lock.Close())
+
+	leftover := filepath.Join(layout.TempDir(), "leftover")
+	require.NoError(t, os.Mkdir(leftover, 0700))
+	require.NoError(t, os.WriteFile(filepath.Join(leftover, "file.bin"), make([]byte, 40), 0600))
+
+	freed, err := layout.CleanCache()
+	require.NoError(t, err)
+	assert.Equal(t, int64(60), freed)
+	assert.FileExists(t, filepath.Join(open.Dir, "file.bin"))
+	assert.DirExists(t, created.Dir)
+	assert.NoDirExists(t, abandoned.Dir)
+	assert.NoFileExists(t, abandoned.Dir+lockSuffix)
+	assert.NoDirExists(t, leftover)
+
+	require.NoError(t, open.Remove())
+	assert.NoFileExists(t, open.Dir+lockSuffix)
+}
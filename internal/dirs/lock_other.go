@@ -0,0 +1,14 @@
+//go:build !linux && !darwin && !freebsd && !windows
+
+package dirs
+
+import (
+	"errors"
+	"os"
+)
+
+// lockFile is not implemented on this platform, so workspaces with a lock file are
+// never cleaned
+func lockFile(*os.File) error {
+	return errors.ErrUnsupported
+}
@@ -0,0 +1,21 @@
+//go:build linux || darwin || freebsd
+
+package dirs
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// lockFile takes an exclusive lock on f without waiting for it
+func lockFile(f *os.File) error {
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil { // #nosec G115 -- file descriptors fit in int
+		if errors.Is(err, syscall.EWOULDBLOCK) {
+			return errLocked
+		}
+		return fmt.Errorf("failed to lock %s: %w", f.Name(), err)
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+package dirs
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	kernel32       = syscall.NewLazyDLL("kernel32.dll")
+	procLockFileEx = kernel32.NewProc("LockFileEx")
+)
+
+const (
+	lockfileFailImmediately = 0x1
+	lockfileExclusiveLock   = 0x2
+	// errorLockViolation is returned when another handle holds the lock
+	errorLockViolation syscall.Errno = 33
+)
+
+// lockFile takes an exclusive lock on the first byte of f without waiting for it
+func lockFile(f *os.File) error {
+	var overlapped syscall.Overlapped
+	// #nosec G103 -- the pointer is passed to the Win32 API as documented
+	r, _, err := procLockFileEx.Call(f.Fd(), lockfileExclusiveLock|lockfileFailImmediately, 0, 1, 0, uintptr(unsafe.Pointer(&overlapped)))
+	if r == 0 {
+		if errors.Is(err, errorLockViolation) {
+			return errLocked
+		}
+		return fmt.Errorf("failed to lock %s: %w", f.Name(), err)
+	}
+	return nil
+}
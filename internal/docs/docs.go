@@ -0,0 +1,70 @@
+// Package docs generates reference documentation of a command tree, as Markdown for
+// documentation sites and as man pages for distributions.
+package docs
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// commands returns cmd and the commands below it that are documented
+func commands(cmd *cobra.Command) []*cobra.Command {
+	cmds := []*cobra.Command{cmd}
+	for _, child := range cmd.Commands() {
+		if !documented(child) {
+			continue
+		}
+		cmds = append(cmds, commands(child)...)
+	}
+	return cmds
+}
+
+// documented reports whether cmd gets a page: hidden, deprecated and help commands do not
+func documented(cmd *cobra.Command) bool {
+	return (cmd.IsAvailableCommand() || cmd.IsAdditionalHelpTopicCommand()) && cmd.Deprecated == ""
+}
+
+// children returns the documented subcommands of cmd
+func children(cmd *cobra.Command) []*cobra.Command {
+	var cmds []*cobra.Command
+	for _, child := range cmd.Commands() {
+		if documented(child) {
+			cmds = append(cmds, child)
+		}
+	}
+	return cmds
+}
+
+// baseName returns the file name of the page of cmd without extension, such as
+// intunewin_auth_login for "intunewin auth login"
+func baseName(cmd *cobra.Command, sep string) string {
+	return strings.ReplaceAll(cmd.CommandPath(), " ", sep)
+}
+
+// description returns the long description of cmd, or its short one
+func description(cmd *cobra.Command) string {
+	if cmd.Long != "" {
+		return cmd.Long
+	}
+	return cmd.Short
+}
+
+// writeTree writes a page for cmd and every documented command below it to dir, as
+// render renders them
+func writeTree(cmd *cobra.Command, dir string, name func(*cobra.Command) string, render func(*cobra.Command) string) (int, error) {
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return 0, fmt.Errorf("failed to create documentation folder: %w", err)
+	}
+	cmds := commands(cmd)
+	for _, c := range cmds {
+		path := filepath.Join(dir, name(c))
+		if err := os.WriteFile(path, []byte(render(c)), 0600); err != nil {
+			return 0, fmt.Errorf("failed to write %s: %w", path, err)
+		}
+	}
+	return len(cmds), nil
+}
@@ -0,0 +1,94 @@
+package docs
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTree returns a command tree with a subcommand group, flags and a hidden command
+func newTree() *cobra.Command {
+	root := &cobra.Command{Use: "tool", Short: "A tool"}
+	root.PersistentFlags().String("log-level", "info", "minimum level of log messages")
+
+	pack := &cobra.Command{
+		Use:   "pack <source-folder> <output-file>",
+		Short: "Pack a folder",
+		Long: `Pack creates a package.
+.dotted lines and back\slashes are escaped.
+
+Example:
+  tool pack ./app app.pkg`,
+		Run: func(*cobra.Command, []string) {},
+	}
+	pack.Flags().StringP("name", "n", "", "application name")
+	pack.Flags().Int("threads", 4, "number of workers")
+
+	auth := &cobra.Command{Use: "auth", Short: "Manage sign-in"}
+	login := &cobra.Command{Use: "login", Short: "Sign in", Run: func(*cobra.Command, []string) {}}
+	hidden := &cobra.Command{Use: "secret", Short: "Hidden", Hidden: true, Run: func(*cobra.Command, []string) {}}
+	auth.AddCommand(login)
+	root.AddCommand(pack, auth, hidden)
+	return root
+}
+
+func TestMarkdown(t *testing.T) {
+	root := newTree()
+	pack, _, err := root.Find([]string{"pack"})
+	require.NoError(t, err)
+
+	page := Markdown(pack)
+	assert.Contains(t, page, "## tool pack\n\nPack a folder\n")
+	assert.Contains(t, page, "```\ntool pack <source-folder> <output-file> [flags]\n```")
+	assert.Contains(t, page, "```\n  tool pack ./app app.pkg\n```", "indented lines become code blocks")
+	assert.Contains(t, page, "-n, --name string")
+	assert.Contains(t, page, "### Options inherited from parent commands")
+	assert.Contains(t, page, "* [tool](tool.md) - A tool")
+
+	rootPage := Markdown(root)
+	assert.Contains(t, rootPage, "* [tool auth](tool_auth.md) - Manage sign-in")
+	assert.NotContains(t, rootPage, "secret")
+}
+
+func TestMan(t *testing.T) {
+	root := newTree()
+	pack, _, err := root.Find([]string{"pack"})
+	require.NoError(t, err)
+
+	page := Man(pack, ManHeader{Section: "1", Date: time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC), Source: "tool 1.0", Manual: "User Commands"})
+	assert.Contains(t, page, `.TH "TOOL\-PACK" "1" "Oct 2026" "tool 1.0" "User Commands"`)
+	assert.Contains(t, page, ".SH NAME\ntool\\-pack \\- Pack a folder\n")
+	assert.Contains(t, page, "\\&.dotted lines and back\\eslashes are escaped.")
+	assert.Contains(t, page, ".nf\n  tool pack ./app app.pkg\n.fi\n")
+	assert.Contains(t, page, ".TP\n\\fB\\-n\\fP, \\fB\\-\\-name\\fP \\fIstring\\fP\napplication name\n")
+	assert.Contains(t, page, "number of workers (default 4)")
+	assert.Contains(t, page, ".SH SEE ALSO\n\\fBtool\\fP(1)\n")
+}
+
+func TestWriteTrees(t *testing.T) {
+	root := newTree()
+	dir := t.TempDir()
+
+	n, err := WriteMarkdown(root, filepath.Join(dir, "md"))
+	require.NoError(t, err)
+	assert.Equal(t, 4, n)
+	for _, name := range []string{"tool.md", "tool_pack.md", "tool_auth.md", "tool_auth_login.md"} {
+		assert.FileExists(t, filepath.Join(dir, "md", name))
+	}
+
+	n, err = WriteMan(root, filepath.Join(dir, "man"), ManHeader{Section: "1"})
+	require.NoError(t, err)
+	assert.Equal(t, 4, n)
+	entries, err := os.ReadDir(filepath.Join(dir, "man"))
+	require.NoError(t, err)
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	assert.ElementsMatch(t, []string{"tool.1", "tool-pack.1", "tool-auth.1", "tool-auth-login.1"}, names)
+}
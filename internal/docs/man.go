@@ -0,0 +1,144 @@
+package docs
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// ManHeader is the title line of man pages
+type ManHeader struct {
+	// Section is the manual section, 1 for user commands
+	Section string
+	// Date is the date of the pages
+	Date time.Time
+	// Source is the name and version of the program, such as "intunewin 1.2.0"
+	Source string
+	// Manual is the title of the manual, such as "User Commands"
+	Manual string
+}
+
+// WriteMan writes a man page for cmd and every command below it to dir and returns the
+// number of pages
+func WriteMan(cmd *cobra.Command, dir string, header ManHeader) (int, error) {
+	return writeTree(cmd, dir, func(c *cobra.Command) string {
+		return baseName(c, "-") + "." + header.Section
+	}, func(c *cobra.Command) string {
+		return Man(c, header)
+	})
+}
+
+// Man renders the man page of cmd in roff
+func Man(cmd *cobra.Command, header ManHeader) string {
+	name := baseName(cmd, "-")
+	var b strings.Builder
+	fmt.Fprintf(&b, ".TH \"%s\" \"%s\" \"%s\" \"%s\" \"%s\"\n", roff(strings.ToUpper(name)), header.Section,
+		header.Date.UTC().Format("Jan 2006"), roff(header.Source), roff(header.Manual))
+	fmt.Fprintf(&b, ".SH NAME\n%s \\- %s\n", roff(name), roff(cmd.Short))
+	if cmd.Runnable() {
+		use := strings.TrimPrefix(cmd.UseLine(), cmd.CommandPath())
+		fmt.Fprintf(&b, ".SH SYNOPSIS\n\\fB%s\\fP%s\n", roff(cmd.CommandPath()), roff(use))
+	}
+	fmt.Fprintf(&b, ".SH DESCRIPTION\n%s", manText(description(cmd)))
+	if cmd.Example != "" {
+		fmt.Fprintf(&b, ".SH EXAMPLES\n.PP\n.nf\n%s\n.fi\n", roff(cmd.Example))
+	}
+	if flags := cmd.NonInheritedFlags(); flags.HasAvailableFlags() {
+		fmt.Fprintf(&b, ".SH OPTIONS\n%s", manFlags(flags))
+	}
+	if flags := cmd.InheritedFlags(); flags.HasAvailableFlags() {
+		fmt.Fprintf(&b, ".SH OPTIONS INHERITED FROM PARENT COMMANDS\n%s", manFlags(flags))
+	}
+
+	var seeAlso []string
+	if cmd.HasParent() {
+		seeAlso = append(seeAlso, fmt.Sprintf("\\fB%s\\fP(%s)", roff(baseName(cmd.Parent(), "-")), header.Section))
+	}
+	for _, child := range children(cmd) {
+		seeAlso = append(seeAlso, fmt.Sprintf("\\fB%s\\fP(%s)", roff(baseName(child, "-")), header.Section))
+	}
+	if len(seeAlso) > 0 {
+		fmt.Fprintf(&b, ".SH SEE ALSO\n%s\n", strings.Join(seeAlso, ", "))
+	}
+	return b.String()
+}
+
+// manText renders the paragraphs of a description; indented lines, such as the
+// examples, are kept without filling
+func manText(text string) string {
+	var b strings.Builder
+	b.WriteString(".PP\n")
+	inNoFill := false
+	for _, line := range strings.Split(strings.TrimRight(text, "\n"), "\n") {
+		indented := strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")
+		switch {
+		case line == "" && !inNoFill:
+			b.WriteString(".PP\n")
+			continue
+		case indented && !inNoFill:
+			b.WriteString(".nf\n")
+			inNoFill = true
+		case !indented && inNoFill && line != "":
+			b.WriteString(".fi\n")
+			inNoFill = false
+		}
+		b.WriteString(roff(line))
+		b.WriteString("\n")
+	}
+	if inNoFill {
+		b.WriteString(".fi\n")
+	}
+	return b.String()
+}
+
+// manFlags renders the flags of fs as a tagged paragraph each
+func manFlags(fs *pflag.FlagSet) string {
+	var b strings.Builder
+	fs.VisitAll(func(f *pflag.Flag) {
+		if f.Hidden {
+			return
+		}
+		b.WriteString(".TP\n")
+		if f.Shorthand != "" {
+			fmt.Fprintf(&b, "\\fB\\-%s\\fP, ", roff(f.Shorthand))
+		}
+		fmt.Fprintf(&b, "\\fB\\-\\-%s\\fP", roff(f.Name))
+		varName, usage := pflag.UnquoteUsage(f)
+		if varName != "" {
+			fmt.Fprintf(&b, " \\fI%s\\fP", roff(varName))
+		}
+		b.WriteString("\n")
+		b.WriteString(roff(usage))
+		if defaultValue(f) {
+			fmt.Fprintf(&b, " (default %s)", roff(f.DefValue))
+		}
+		b.WriteString("\n")
+	})
+	return b.String()
+}
+
+// defaultValue reports whether the default value of f is worth showing
+func defaultValue(f *pflag.Flag) bool {
+	switch f.DefValue {
+	case "", "false", "0", "[]":
+		return false
+	}
+	return true
+}
+
+// roff escapes text for roff: backslashes and dashes are escaped, and lines starting
+// with a control character are protected
+func roff(text string) string {
+	text = strings.ReplaceAll(text, `\`, `\e`)
+	text = strings.ReplaceAll(text, "-", `\-`)
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		if strings.HasPrefix(line, ".") || strings.HasPrefix(line, "'") {
+			lines[i] = `\&` + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
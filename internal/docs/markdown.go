@@ -0,0 +1,75 @@
+package docs
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// WriteMarkdown writes a Markdown page for cmd and every command below it to dir and
+// returns the number of pages. Pages link to their parent and subcommands.
+func WriteMarkdown(cmd *cobra.Command, dir string) (int, error) {
+	return writeTree(cmd, dir, markdownName, Markdown)
+}
+
+// markdownName returns the file name of the Markdown page of cmd
+func markdownName(cmd *cobra.Command) string {
+	return baseName(cmd, "_") + ".md"
+}
+
+// Markdown renders the reference page of cmd
+func Markdown(cmd *cobra.Command) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "## %s\n\n%s\n\n", cmd.CommandPath(), cmd.Short)
+	fmt.Fprintf(&b, "### Synopsis\n\n%s\n\n", markdownText(description(cmd)))
+	if cmd.Runnable() {
+		fmt.Fprintf(&b, "```\n%s\n```\n\n", cmd.UseLine())
+	}
+	if cmd.Example != "" {
+		fmt.Fprintf(&b, "### Examples\n\n```\n%s\n```\n\n", cmd.Example)
+	}
+	if flags := cmd.NonInheritedFlags(); flags.HasAvailableFlags() {
+		fmt.Fprintf(&b, "### Options\n\n```\n%s```\n\n", flags.FlagUsages())
+	}
+	if flags := cmd.InheritedFlags(); flags.HasAvailableFlags() {
+		fmt.Fprintf(&b, "### Options inherited from parent commands\n\n```\n%s```\n\n", flags.FlagUsages())
+	}
+
+	var seeAlso []string
+	if cmd.HasParent() {
+		parent := cmd.Parent()
+		seeAlso = append(seeAlso, fmt.Sprintf("* [%s](%s) - %s", parent.CommandPath(), markdownName(parent), parent.Short))
+	}
+	for _, child := range children(cmd) {
+		seeAlso = append(seeAlso, fmt.Sprintf("* [%s](%s) - %s", child.CommandPath(), markdownName(child), child.Short))
+	}
+	if len(seeAlso) > 0 {
+		fmt.Fprintf(&b, "### See also\n\n%s\n", strings.Join(seeAlso, "\n"))
+	}
+	return b.String()
+}
+
+// markdownText renders the plain text of a description: indented lines, such as the
+// examples, become code blocks and the other lines are kept as is
+func markdownText(text string) string {
+	var b strings.Builder
+	inCode := false
+	for _, line := range strings.Split(strings.TrimRight(text, "\n"), "\n") {
+		indented := strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")
+		switch {
+		case indented && !inCode:
+			b.WriteString("```\n")
+			inCode = true
+		case !indented && inCode && line != "":
+			b.WriteString("```\n")
+			inCode = false
+		}
+		b.WriteString(line)
+		b.WriteString("\n")
+	}
+	if inCode {
+		b.WriteString("```\n")
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
@@ -0,0 +1,28 @@
+package doctor
+
+import (
+	"fmt"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	kernel32                = syscall.NewLazyDLL("kernel32.dll")
+	procGetDiskFreeSpaceExW = kernel32.NewProc("GetDiskFreeSpaceExW")
+	procGlobalMemoryStatus  = kernel32.NewProc("GlobalMemoryStatusEx")
+)
+
+// freeSpace returns the bytes available to the current user on the volume of dir
+func freeSpace(dir string) (int64, error) {
+	path, err := syscall.UTF16PtrFromString(dir)
+	if err != nil {
+		return 0, fmt.Errorf("invalid directory: %w", err)
+	}
+	var available uint64
+	// #nosec G103 -- the pointers are passed to the Win32 API as documented
+	r, _, err := procGetDiskFreeSpaceExW.Call(uintptr(unsafe.Pointer(path)), uintptr(unsafe.Pointer(&available)), 0, 0)
+	if r == 0 {
+		return 0, fmt.Errorf("failed to get free disk space: %w", err)
+	}
+	return int64(available), nil // #nosec G115 -- disk sizes fit in int64
+}
@@ -0,0 +1,196 @@
+// Package doctor checks the environment and packages for problems that make
+// packing, unpacking or publishing fail, with hints on how to fix them.
+package doctor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/kenchan0130/intunewin/internal/progress"
+	"github.com/kenchan0130/intunewin/internal/unpack"
+	"github.com/kenchan0130/intunewin/internal/verify"
+)
+
+// Status is the outcome of a check
+type Status string
+
+const (
+	// OK means no problem was found
+	OK Status = "ok"
+	// Warning means a problem may make some operations fail
+	Warning Status = "warning"
+	// Failed means a problem makes operations fail
+	Failed Status = "failed"
+	// Skipped means the check does not apply to this environment
+	Skipped Status = "skipped"
+)
+
+// DefaultMinFreeSpace is the free space in the temporary directory below which
+// TempSpace warns when no package size is known
+const DefaultMinFreeSpace = 1 << 30
+
+// maxEntryPath is the length of a path in a package above which it may exceed
+// MAX_PATH once the Intune Management Extension extracts it
+const maxEntryPath = 200
+
+// Result is the outcome of one check
+type Result struct {
+	Check   string `json:"check"`
+	Status  Status `json:"status"`
+	Message string `json:"message"`
+	// Hint tells how to fix a warning or failure
+	Hint string `json:"hint,omitempty"`
+}
+
+// Check runs one check
+type Check func(ctx context.Context) Result
+
+// Run runs checks in order and returns their results
+func Run(ctx context.Context, checks ...Check) []Result {
+	results := make([]Result, 0, len(checks))
+	for _, check := range checks {
+		results = append(results, check(ctx))
+	}
+	return results
+}
+
+// HasFailures reports whether any check failed
+func HasFailures(results []Result) bool {
+	for _, r := range results {
+		if r.Status == Failed {
+			return true
+		}
+	}
+	return false
+}
+
+// TempSpace checks that dir, or the system temporary directory when it is empty,
+// has at least need bytes free
+func TempSpace(dir string, need int64) Check {
+	return func(context.Context) Result {
+		const name = "temp-space"
+		if dir == "" {
+			dir = os.TempDir()
+		}
+		free, err := freeSpace(dir)
+		if errors.Is(err, errors.ErrUnsupported) {
+			return Result{Check: name, Status: Skipped, Message: "free space cannot be determined on this platform"}
+		}
+		if err != nil {
+			return Result{Check: name, Status: Failed,
+				Message: fmt.Sprintf("temporary directory %s is not usable: %v", dir, err),
+				Hint:    "create the directory or pass --tmpdir with a writable directory"}
+		}
+		message := fmt.Sprintf("%s free in %s", progress.FormatBytes(free), dir)
+		if free < need {
+			return Result{Check: name, Status: Warning,
+				Message: fmt.Sprintf("%s, %s recommended", message, progress.FormatBytes(need)),
+				Hint:    "free up space or pass --tmpdir with a directory on a larger disk"}
+		}
+		return Result{Check: name, Status: OK, Message: message}
+	}
+}
+
+// Memory checks that the memory available is enough to hold the intermediate data of
+// size bytes, of which at most spillThreshold bytes per buffer are kept in memory
+func Memory(size, spillThreshold int64) Check {
+	return func(context.Context) Result {
+		const name = "memory"
+		available, err := availableMemory()
+		if err != nil {
+			return Result{Check: name, Status: Skipped, Message: "available memory cannot be determined on this platform"}
+		}
+		// Packing and unpacking hold the content and its encrypted copy at the same time
+		need := 2 * min(size, spillThreshold)
+		message := fmt.Sprintf("%s available, up to %s needed", progress.FormatBytes(available), progress.FormatBytes(need))
+		if available < need {
+			return Result{Check: name, Status: Warning, Message: message,
+				Hint: fmt.Sprintf("pass --spill-threshold %d or lower so that intermediate data is written to disk", available/4)}
+		}
+		return Result{Check: name, Status: OK, Message: message}
+	}
+}
+
+// LongPaths checks that Windows accepts paths longer than MAX_PATH (260 characters)
+func LongPaths() Check {
+	return func(context.Context) Result {
+		const name = "long-paths"
+		enabled, err := longPathsEnabled()
+		if errors.Is(err, errors.ErrUnsupported) {
+			return Result{Check: name, Status: Skipped, Message: "only applies to Windows"}
+		}
+		if err != nil {
+			return Result{Check: name, Status: Warning, Message: fmt.Sprintf("failed to read the long path setting: %v", err)}
+		}
+		if !enabled {
+			return Result{Check: name, Status: Warning,
+				Message: "paths longer than 260 characters are not enabled",
+				Hint: `set HKLM\SYSTEM\CurrentControlSet\Control\FileSystem\LongPathsEnabled to 1 ` +
+					`(or the "Enable Win32 long paths" group policy) so that installers and scripts can use deep folders`}
+		}
+		return Result{Check: name, Status: OK, Message: "paths longer than 260 characters are enabled"}
+	}
+}
+
+// Connectivity checks that every endpoint answers HTTPS requests sent with client.
+// Any HTTP response counts, as the requests are not authenticated.
+func Connectivity(client *http.Client, endpoints ...string) Check {
+	return func(ctx context.Context) Result {
+		const name = "graph"
+		for _, endpoint := range endpoints {
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+			if err != nil {
+				return Result{Check: name, Status: Failed, Message: fmt.Sprintf("invalid endpoint %s: %v", endpoint, err)}
+			}
+			resp, err := client.Do(req)
+			if err != nil {
+				return Result{Check: name, Status: Failed,
+					Message: fmt.Sprintf("cannot reach %s: %v", endpoint, err),
+					Hint:    "check the network, pass --proxy when a proxy is required and --ca-bundle when it intercepts TLS"}
+			}
+			resp.Body.Close()
+		}
+		return Result{Check: name, Status: OK, Message: "reached " + strings.Join(endpoints, ", ")}
+	}
+}
+
+// Package verifies the intunewin file at path as verify does and reports
+// Detection.xml values and content paths that may cause problems on devices
+func Package(path string, opts ...unpack.Option) Check {
+	return func(context.Context) Result {
+		const name = "package"
+		if err := verify.File(path, opts...); err != nil {
+			return Result{Check: name, Status: Failed, Message: err.Error(),
+				Hint: "repack the source folder; a package that fails verification is rejected by Intune or by devices"}
+		}
+
+		appInfo, err := unpack.ReadApplicationInfo(path, opts...)
+		if err != nil {
+			return Result{Check: name, Status: Failed, Message: err.Error()}
+		}
+		var problems []string
+		for _, w := range appInfo.CheckCompatibility() {
+			problems = append(problems, w.String())
+		}
+
+		entries, err := unpack.List(path, opts...)
+		if err != nil {
+			return Result{Check: name, Status: Failed, Message: err.Error()}
+		}
+		for _, e := range entries {
+			if len(e.Path) > maxEntryPath {
+				problems = append(problems, fmt.Sprintf("%s is %d characters long", e.Path, len(e.Path)))
+			}
+		}
+
+		if len(problems) > 0 {
+			return Result{Check: name, Status: Warning, Message: strings.Join(problems, "; "),
+				Hint: "repack with this tool to record known Detection.xml values, and shorten deep folder names"}
+		}
+		return Result{Check: name, Status: OK, Message: path + " passed verification"}
+	}
+}
@@ -0,0 +1,87 @@
+package doctor
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/kenchan0130/intunewin/internal/pack"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTempSpace(t *testing.T) {
+	switch runtime.GOOS {
+	case "linux", "darwin", "freebsd", "windows":
+	default:
+		t.Skip("free space is not available on this platform")
+	}
+	dir := t.TempDir()
+
+	result := TempSpace(dir, 0)(context.Background())
+	assert.Equal(t, OK, result.Status, result.Message)
+	assert.Contains(t, result.Message, dir)
+
+	result = TempSpace(dir, 1<<62)(context.Background())
+	assert.Equal(t, Warning, result.Status)
+	assert.NotEmpty(t, result.Hint)
+
+	result = TempSpace(filepath.Join(dir, "missing"), 0)(context.Background())
+	assert.Equal(t, Failed, result.Status)
+}
+
+func TestLongPaths(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the result depends on the machine")
+	}
+	assert.Equal(t, Skipped, LongPaths()(context.Background()).Status)
+}
+
+func TestConnectivity(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	t.Cleanup(server.Close)
+
+	result := Connectivity(server.Client(), server.URL)(context.Background())
+	assert.Equal(t, OK, result.Status, result.Message)
+
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+	result = Connectivity(server.Client(), server.URL, closed.URL)(context.Background())
+	assert.Equal(t, Failed, result.Status)
+	assert.Contains(t, result.Message, closed.URL)
+	assert.NotEmpty(t, result.Hint)
+}
+
+func TestPackage(t *testing.T) {
+	sourceDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(sourceDir, "setup.exe"), []byte("setup"), 0600))
+	deep := filepath.Join(sourceDir, strings.Repeat("a", 100), strings.Repeat("b", 100))
+	require.NoError(t, os.MkdirAll(deep, 0700))
+	require.NoError(t, os.WriteFile(filepath.Join(deep, "file.txt"), []byte("deep"), 0600))
+	path := filepath.Join(t.TempDir(), "app.intunewin")
+	require.NoError(t, pack.PackWithInfo(sourceDir, path, "app", "setup.exe"))
+
+	result := Package(path)(context.Background())
+	assert.Equal(t, Warning, result.Status, result.Message)
+	assert.Contains(t, result.Message, "file.txt is 210 characters long")
+
+	require.NoError(t, os.WriteFile(path, []byte("not a package"), 0600))
+	assert.Equal(t, Failed, Package(path)(context.Background()).Status)
+}
+
+func TestHasFailures(t *testing.T) {
+	results := Run(context.Background(),
+		func(context.Context) Result { return Result{Check: "a", Status: OK} },
+		func(context.Context) Result { return Result{Check: "b", Status: Warning} },
+	)
+	require.Len(t, results, 2)
+	assert.False(t, HasFailures(results))
+	assert.True(t, HasFailures(append(results, Result{Check: "c", Status: Failed})))
+}
@@ -0,0 +1,10 @@
+//go:build !windows
+
+package doctor
+
+import "errors"
+
+// longPathsEnabled only applies to Windows
+func longPathsEnabled() (bool, error) {
+	return false, errors.ErrUnsupported
+}
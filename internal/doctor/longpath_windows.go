@@ -0,0 +1,37 @@
+package doctor
+
+import (
+	"errors"
+	"fmt"
+	"syscall"
+	"unsafe"
+)
+
+// longPathsEnabled reads the LongPathsEnabled setting of the file system
+func longPathsEnabled() (bool, error) {
+	key, err := syscall.UTF16PtrFromString(`SYSTEM\CurrentControlSet\Control\FileSystem`)
+	if err != nil {
+		return false, err //nolint:wrapcheck // the key name is constant
+	}
+	var handle syscall.Handle
+	if err := syscall.RegOpenKeyEx(syscall.HKEY_LOCAL_MACHINE, key, 0, syscall.KEY_READ, &handle); err != nil {
+		return false, fmt.Errorf("failed to open registry key: %w", err)
+	}
+	defer syscall.RegCloseKey(handle)
+
+	name, err := syscall.UTF16PtrFromString("LongPathsEnabled")
+	if err != nil {
+		return false, err //nolint:wrapcheck // the value name is constant
+	}
+	var valueType, value uint32
+	size := uint32(unsafe.Sizeof(value))
+	// #nosec G103 -- the pointers are passed to the Win32 API as documented
+	err = syscall.RegQueryValueEx(handle, name, nil, &valueType, (*byte)(unsafe.Pointer(&value)), &size)
+	if errors.Is(err, syscall.ERROR_FILE_NOT_FOUND) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to read registry value: %w", err)
+	}
+	return valueType == syscall.REG_DWORD && value == 1, nil
+}
@@ -0,0 +1,37 @@
+package doctor
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// availableMemory returns the memory available for new allocations without swapping
+func availableMemory() (int64, error) {
+	data, err := os.ReadFile("/proc/meminfo")
+	if err != nil {
+		return 0, fmt.Errorf("failed to read memory information: %w", err)
+	}
+	return parseMemInfo(data)
+}
+
+// parseMemInfo returns MemAvailable of /proc/meminfo in bytes
+func parseMemInfo(data []byte) (int64, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		value, ok := strings.CutPrefix(scanner.Text(), "MemAvailable:")
+		if !ok {
+			continue
+		}
+		kb, err := strconv.ParseInt(strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(value), "kB")), 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid MemAvailable: %w", err)
+		}
+		return kb * 1024, nil
+	}
+	return 0, errors.New("MemAvailable not found")
+}
@@ -0,0 +1,17 @@
+package doctor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMemInfo(t *testing.T) {
+	available, err := parseMemInfo([]byte("MemTotal:       16318412 kB\nMemFree:         1034292 kB\nMemAvailable:    8159208 kB\n"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(8159208*1024), available)
+
+	_, err = parseMemInfo([]byte("MemTotal: 1 kB\n"))
+	assert.Error(t, err)
+}
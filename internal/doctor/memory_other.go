@@ -0,0 +1,10 @@
+//go:build !linux && !windows
+
+package doctor
+
+import "errors"
+
+// availableMemory is not implemented on this platform
+func availableMemory() (int64, error) {
+	return 0, errors.ErrUnsupported
+}
@@ -0,0 +1,31 @@
+package doctor
+
+import (
+	"fmt"
+	"unsafe"
+)
+
+// memoryStatusEx is the MEMORYSTATUSEX structure of GlobalMemoryStatusEx
+type memoryStatusEx struct {
+	length               uint32
+	memoryLoad           uint32
+	totalPhys            uint64
+	availPhys            uint64
+	totalPageFile        uint64
+	availPageFile        uint64
+	totalVirtual         uint64
+	availVirtual         uint64
+	availExtendedVirtual uint64
+}
+
+// availableMemory returns the physical memory available for new allocations
+func availableMemory() (int64, error) {
+	status := memoryStatusEx{}
+	status.length = uint32(unsafe.Sizeof(status))
+	// #nosec G103 -- the pointer is passed to the Win32 API as documented
+	r, _, err := procGlobalMemoryStatus.Call(uintptr(unsafe.Pointer(&status)))
+	if r == 0 {
+		return 0, fmt.Errorf("failed to get memory status: %w", err)
+	}
+	return int64(status.availPhys), nil // #nosec G115 -- memory sizes fit in int64
+}
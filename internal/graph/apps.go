@@ -0,0 +1,176 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/kenchan0130/intunewin/internal/metadata"
+)
+
+// Win32LobAppODataType is the Graph type of Win32 apps
+const Win32LobAppODataType = "#microsoft.graph.win32LobApp"
+
+// Upload states of a content file that the publish steps wait for
+const (
+	StateAzureStorageURIRequestSuccess = "azureStorageUriRequestSuccess"
+	StateAzureStorageURIRenewalSuccess = "azureStorageUriRenewalSuccess"
+	StateCommitFileSuccess             = "commitFileSuccess"
+)
+
+// ErrContentFileFailed is returned when Intune reports a failed or timed out upload state
+var ErrContentFileFailed = errors.New("content file processing failed")
+
+// sleep waits for d or until ctx is done
+var sleep = func(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// ContentFile is a mobileAppContentFile, the slot the encrypted contents of a package are
+// uploaded to
+type ContentFile struct {
+	ODataType string `json:"@odata.type,omitempty"`
+	ID        string `json:"id,omitempty"`
+	// Name is the name of the encrypted contents file
+	Name string `json:"name"`
+	// Size is the size of the unencrypted content zip
+	Size int64 `json:"size"`
+	// SizeEncrypted is the size of the encrypted contents file
+	SizeEncrypted int64 `json:"sizeEncrypted"`
+	// Manifest is not used by Win32 apps and is sent as null
+	Manifest     *string `json:"manifest"`
+	IsDependency bool    `json:"isDependency"`
+	// UploadState and AzureStorageURI are set by Intune
+	UploadState     string `json:"uploadState,omitempty"`
+	AzureStorageURI string `json:"azureStorageUri,omitempty"`
+}
+
+// NewContentFile describes the encrypted contents file of a package
+func NewContentFile(name string, size, sizeEncrypted int64) *ContentFile {
+	return &ContentFile{
+		ODataType:     "#microsoft.graph.mobileAppContentFile",
+		Name:          name,
+		Size:          size,
+		SizeEncrypted: sizeEncrypted,
+	}
+}
+
+// entity is the part of a created Graph entity that is read back
+type entity struct {
+	ID string `json:"id"`
+}
+
+// appPath returns the path of the mobile app appID
+func appPath(appID string) string {
+	return "/deviceAppManagement/mobileApps/" + url.PathEscape(appID)
+}
+
+// contentVersionPath returns the path of the content version versionID of the Win32 app appID
+func contentVersionPath(appID, versionID string) string {
+	return appPath(appID) + "/microsoft.graph.win32LobApp/contentVersions/" + url.PathEscape(versionID)
+}
+
+// contentFilePath returns the path of the content file fileID
+func contentFilePath(appID, versionID, fileID string) string {
+	return contentVersionPath(appID, versionID) + "/files/" + url.PathEscape(fileID)
+}
+
+// CreateApp creates a mobile app from body, such as a manifest.Win32LobApp, and returns its ID
+func (c *Client) CreateApp(ctx context.Context, body any) (string, error) {
+	var created entity
+	if err := c.do(ctx, http.MethodPost, "/deviceAppManagement/mobileApps", body, &created); err != nil {
+		return "", fmt.Errorf("failed to create app: %w", err)
+	}
+	return created.ID, nil
+}
+
+// CreateContentVersion creates a content version of the Win32 app appID and returns its ID
+func (c *Client) CreateContentVersion(ctx context.Context, appID string) (string, error) {
+	var created entity
+	path := appPath(appID) + "/microsoft.graph.win32LobApp/contentVersions"
+	if err := c.do(ctx, http.MethodPost, path, struct{}{}, &created); err != nil {
+		return "", fmt.Errorf("failed to create content version: %w", err)
+	}
+	return created.ID, nil
+}
+
+// CreateContentFile adds file to a content version and returns it as created by Intune
+func (c *Client) CreateContentFile(ctx context.Context, appID, versionID string, file *ContentFile) (*ContentFile, error) {
+	var created ContentFile
+	if err := c.do(ctx, http.MethodPost, contentVersionPath(appID, versionID)+"/files", file, &created); err != nil {
+		return nil, fmt.Errorf("failed to create content file: %w", err)
+	}
+	return &created, nil
+}
+
+// ContentFile returns the current state of a content file
+func (c *Client) ContentFile(ctx context.Context, appID, versionID, fileID string) (*ContentFile, error) {
+	var file ContentFile
+	if err := c.do(ctx, http.MethodGet, contentFilePath(appID, versionID, fileID), nil, &file); err != nil {
+		return nil, fmt.Errorf("failed to get content file: %w", err)
+	}
+	return &file, nil
+}
+
+// WaitForContentFile polls a content file until its upload state is state, and fails
+// when Intune reports an error instead
+func (c *Client) WaitForContentFile(ctx context.Context, appID, versionID, fileID, state string) (*ContentFile, error) {
+	for {
+		file, err := c.ContentFile(ctx, appID, versionID, fileID)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case file.UploadState == state:
+			return file, nil
+		case file.UploadState == "error" || strings.HasSuffix(file.UploadState, "Failed") ||
+			strings.HasSuffix(file.UploadState, "TimedOut"):
+			return nil, fmt.Errorf("%w: upload state is %s", ErrContentFileFailed, file.UploadState)
+		}
+		c.opts.Logger.Debug("waiting for content file", "state", file.UploadState, "want", state)
+		if err := sleep(ctx, c.opts.PollInterval); err != nil {
+			return nil, err //nolint:wrapcheck // context errors are returned as is
+		}
+	}
+}
+
+// RenewUpload requests a new SAS URI for a content file whose upload takes longer than
+// the URI is valid; wait for StateAzureStorageURIRenewalSuccess before using it
+func (c *Client) RenewUpload(ctx context.Context, appID, versionID, fileID string) error {
+	if err := c.do(ctx, http.MethodPost, contentFilePath(appID, versionID, fileID)+"/renewUpload", struct{}{}, nil); err != nil {
+		return fmt.Errorf("failed to renew upload: %w", err)
+	}
+	return nil
+}
+
+// CommitContentFile commits an uploaded content file with the encryption info of its package
+func (c *Client) CommitContentFile(ctx context.Context, appID, versionID, fileID string, commit *metadata.CommitRequest) error {
+	if err := c.do(ctx, http.MethodPost, contentFilePath(appID, versionID, fileID)+"/commit", commit, nil); err != nil {
+		return fmt.Errorf("failed to commit content file: %w", err)
+	}
+	return nil
+}
+
+// SetCommittedContentVersion makes versionID the content version Intune installs for the
+// Win32 app appID
+func (c *Client) SetCommittedContentVersion(ctx context.Context, appID, versionID string) error {
+	body := map[string]string{
+		"@odata.type":             Win32LobAppODataType,
+		"committedContentVersion": versionID,
+	}
+	if err := c.do(ctx, http.MethodPatch, appPath(appID), body, nil); err != nil {
+		return fmt.Errorf("failed to set committed content version: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package graph
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/kenchan0130/intunewin/internal/metadata"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testFilePath = "/beta/deviceAppManagement/mobileApps/app1/microsoft.graph.win32LobApp/contentVersions/1/files/file1"
+
+func TestWaitForContentFile(t *testing.T) {
+	noSleep(t)
+	states := []string{"azureStorageUriRequestPending", "azureStorageUriRequestPending", StateAzureStorageURIRequestSuccess}
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodGet, r.Method)
+		assert.Equal(t, testFilePath, r.URL.Path)
+		state := states[0]
+		states = states[1:]
+		writeJSON(t, w, http.StatusOK, ContentFile{ID: "file1", UploadState: state, AzureStorageURI: "https://blob/sas"})
+	})
+
+	file, err := client.WaitForContentFile(context.Background(), "app1", "1", "file1", StateAzureStorageURIRequestSuccess)
+	require.NoError(t, err)
+	assert.Equal(t, "https://blob/sas", file.AzureStorageURI)
+	assert.Empty(t, states)
+}
+
+func TestWaitForContentFileFailure(t *testing.T) {
+	noSleep(t)
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(t, w, http.StatusOK, ContentFile{ID: "file1", UploadState: "commitFileFailed"})
+	})
+
+	_, err := client.WaitForContentFile(context.Background(), "app1", "1", "file1", StateCommitFileSuccess)
+	assert.ErrorIs(t, err, ErrContentFileFailed)
+	assert.ErrorContains(t, err, "commitFileFailed")
+}
+
+func TestContentFileRequests(t *testing.T) {
+	var requests []string
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		var body map[string]any
+		if r.Method != http.MethodGet {
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		}
+		switch r.URL.Path {
+		case "/beta/deviceAppManagement/mobileApps/app1/microsoft.graph.win32LobApp/contentVersions":
+			writeJSON(t, w, http.StatusCreated, map[string]string{"id": "1"})
+		case "/beta/deviceAppManagement/mobileApps/app1/microsoft.graph.win32LobApp/contentVersions/1/files":
+			assert.Equal(t, "IntunePackage.intunewin", body["name"])
+			assert.Contains(t, body, "manifest")
+			writeJSON(t, w, http.StatusCreated, ContentFile{ID: "file1", UploadState: "azureStorageUriRequestPending"})
+		case testFilePath + "/commit":
+			assert.Contains(t, body, "fileEncryptionInfo")
+			w.WriteHeader(http.StatusOK)
+		case "/beta/deviceAppManagement/mobileApps/app1":
+			assert.Equal(t, map[string]any{"@odata.type": Win32LobAppODataType, "committedContentVersion": "1"}, body)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	ctx := context.Background()
+	versionID, err := client.CreateContentVersion(ctx, "app1")
+	require.NoError(t, err)
+	assert.Equal(t, "1", versionID)
+	file, err := client.CreateContentFile(ctx, "app1", versionID, NewContentFile("IntunePackage.intunewin", 100, 148))
+	require.NoError(t, err)
+	assert.Equal(t, "file1", file.ID)
+	require.NoError(t, client.CommitContentFile(ctx, "app1", versionID, file.ID,
+		&metadata.CommitRequest{FileEncryptionInfo: &metadata.FileEncryptionInfo{}}))
+	require.NoError(t, client.SetCommittedContentVersion(ctx, "app1", versionID))
+	assert.Equal(t, []string{
+		"POST /beta/deviceAppManagement/mobileApps/app1/microsoft.graph.win32LobApp/contentVersions",
+		"POST /beta/deviceAppManagement/mobileApps/app1/microsoft.graph.win32LobApp/contentVersions/1/files",
+		"POST " + testFilePath + "/commit",
+		"PATCH /beta/deviceAppManagement/mobileApps/app1",
+	}, requests)
+}
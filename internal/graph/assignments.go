@@ -0,0 +1,163 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Intent is the install intent of an app assignment
+type Intent string
+
+// Intents of app assignments
+const (
+	// IntentRequired installs the app on the targeted devices
+	IntentRequired Intent = "required"
+	// IntentAvailable offers the app in Company Portal to the targeted users
+	IntentAvailable Intent = "available"
+	// IntentUninstall removes the app from the targeted devices
+	IntentUninstall Intent = "uninstall"
+)
+
+// FilterMode is how an assignment filter narrows the targeted devices
+type FilterMode string
+
+// Modes of assignment filters
+const (
+	FilterNone    FilterMode = "none"
+	FilterInclude FilterMode = "include"
+	FilterExclude FilterMode = "exclude"
+)
+
+// Targets of assignments that are not groups
+const (
+	TargetAllUsers   = "all-users"
+	TargetAllDevices = "all-devices"
+)
+
+// Assignment targets an Entra group, all users or all devices with an intent
+type Assignment struct {
+	Intent Intent
+	// Target is the object ID of an Entra group, TargetAllUsers or TargetAllDevices
+	Target string
+	// FilterID is the ID of an assignment filter, empty for none
+	FilterID string
+	// FilterMode includes or excludes the devices matched by the filter
+	FilterMode FilterMode
+}
+
+// ParseIntent parses an intent name case-insensitively
+func ParseIntent(s string) (Intent, error) {
+	for _, intent := range []Intent{IntentRequired, IntentAvailable, IntentUninstall} {
+		if strings.EqualFold(s, string(intent)) {
+			return intent, nil
+		}
+	}
+	return "", fmt.Errorf("unknown intent %q (expected required, available or uninstall)", s)
+}
+
+// ParseAssignment parses an assignment in the form "<intent>:<target>", optionally
+// followed by ":<include|exclude>:<filter-id>", such as "required:all-devices" or
+// "available:<group-id>:include:<filter-id>"
+func ParseAssignment(s string) (Assignment, error) {
+	parts := strings.Split(s, ":")
+	if len(parts) != 2 && len(parts) != 4 {
+		return Assignment{}, fmt.Errorf("invalid assignment %q (expected <intent>:<target>[:<include|exclude>:<filter-id>])", s)
+	}
+	intent, err := ParseIntent(strings.TrimSpace(parts[0]))
+	if err != nil {
+		return Assignment{}, fmt.Errorf("invalid assignment %q: %w", s, err)
+	}
+	a := Assignment{Intent: intent, Target: strings.TrimSpace(parts[1]), FilterMode: FilterNone}
+	if len(parts) == 4 {
+		switch mode := FilterMode(strings.ToLower(strings.TrimSpace(parts[2]))); mode {
+		case FilterInclude, FilterExclude:
+			a.FilterMode = mode
+		default:
+			return Assignment{}, fmt.Errorf("invalid assignment %q: unknown filter mode %q (expected include or exclude)", s, parts[2])
+		}
+		a.FilterID = strings.TrimSpace(parts[3])
+	}
+	if err := a.Validate(); err != nil {
+		return Assignment{}, fmt.Errorf("invalid assignment %q: %w", s, err)
+	}
+	return a, nil
+}
+
+// Validate checks that Intune accepts the assignment
+func (a Assignment) Validate() error {
+	switch {
+	case a.Target == "":
+		return fmt.Errorf("target is empty")
+	case a.Intent == IntentAvailable && a.Target == TargetAllDevices:
+		return fmt.Errorf("available apps can only be assigned to users")
+	case a.FilterID == "" && a.FilterMode != "" && a.FilterMode != FilterNone:
+		return fmt.Errorf("filter mode %s needs a filter ID", a.FilterMode)
+	case a.FilterID != "" && a.FilterMode != FilterInclude && a.FilterMode != FilterExclude:
+		return fmt.Errorf("filter %s needs the include or exclude mode", a.FilterID)
+	}
+	return nil
+}
+
+// assignmentBody is a mobileAppAssignment request body
+type assignmentBody struct {
+	ODataType string             `json:"@odata.type"`
+	Intent    Intent             `json:"intent"`
+	Target    assignmentTarget   `json:"target"`
+	Settings  assignmentSettings `json:"settings"`
+}
+
+// assignmentTarget is the target of a mobileAppAssignment
+type assignmentTarget struct {
+	ODataType  string     `json:"@odata.type"`
+	GroupID    string     `json:"groupId,omitempty"`
+	FilterID   *string    `json:"deviceAndAppManagementAssignmentFilterId"`
+	FilterType FilterMode `json:"deviceAndAppManagementAssignmentFilterType"`
+}
+
+// assignmentSettings are the Win32 app settings of a mobileAppAssignment
+type assignmentSettings struct {
+	ODataType     string `json:"@odata.type"`
+	Notifications string `json:"notifications"`
+}
+
+// body converts a to its Graph request body
+func (a Assignment) body() assignmentBody {
+	target := assignmentTarget{FilterType: FilterNone}
+	switch a.Target {
+	case TargetAllUsers:
+		target.ODataType = "#microsoft.graph.allLicensedUsersAssignmentTarget"
+	case TargetAllDevices:
+		target.ODataType = "#microsoft.graph.allDevicesAssignmentTarget"
+	default:
+		target.ODataType = "#microsoft.graph.groupAssignmentTarget"
+		target.GroupID = a.Target
+	}
+	if a.FilterID != "" {
+		filterID := a.FilterID
+		target.FilterID = &filterID
+		target.FilterType = a.FilterMode
+	}
+	return assignmentBody{
+		ODataType: "#microsoft.graph.mobileAppAssignment",
+		Intent:    a.Intent,
+		Target:    target,
+		Settings: assignmentSettings{
+			ODataType:     "#microsoft.graph.win32LobAppAssignmentSettings",
+			Notifications: "showAll",
+		},
+	}
+}
+
+// CreateAssignment assigns the app appID as described by a and returns the assignment ID
+func (c *Client) CreateAssignment(ctx context.Context, appID string, a Assignment) (string, error) {
+	if err := a.Validate(); err != nil {
+		return "", fmt.Errorf("invalid assignment: %w", err)
+	}
+	var created entity
+	if err := c.do(ctx, http.MethodPost, appPath(appID)+"/assignments", a.body(), &created); err != nil {
+		return "", fmt.Errorf("failed to assign app to %s: %w", a.Target, err)
+	}
+	return created.ID, nil
+}
@@ -0,0 +1,76 @@
+package graph
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAssignment(t *testing.T) {
+	tests := []struct {
+		input string
+		want  Assignment
+	}{
+		{"required:all-devices", Assignment{Intent: IntentRequired, Target: TargetAllDevices, FilterMode: FilterNone}},
+		{"Available:group1", Assignment{Intent: IntentAvailable, Target: "group1", FilterMode: FilterNone}},
+		{"uninstall:all-users:exclude:filter1", Assignment{Intent: IntentUninstall, Target: TargetAllUsers, FilterMode: FilterExclude, FilterID: "filter1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseAssignment(tt.input)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	for _, input := range []string{
+		"required",
+		"install:group1",
+		"required:",
+		"required:group1:include",
+		"required:group1:only:filter1",
+		"required:group1:include:",
+		"available:all-devices",
+	} {
+		t.Run(input, func(t *testing.T) {
+			_, err := ParseAssignment(input)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestCreateAssignment(t *testing.T) {
+	var body map[string]any
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/beta/deviceAppManagement/mobileApps/app1/assignments", r.URL.Path)
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		writeJSON(t, w, http.StatusCreated, map[string]string{"id": "assignment1"})
+	})
+
+	id, err := client.CreateAssignment(context.Background(), "app1",
+		Assignment{Intent: IntentRequired, Target: "group1", FilterMode: FilterInclude, FilterID: "filter1"})
+	require.NoError(t, err)
+	assert.Equal(t, "assignment1", id)
+	assert.Equal(t, "required", body["intent"])
+	assert.Equal(t, map[string]any{
+		"@odata.type": "#microsoft.graph.groupAssignmentTarget",
+		"groupId":     "group1",
+		"deviceAndAppManagementAssignmentFilterId":   "filter1",
+		"deviceAndAppManagementAssignmentFilterType": "include",
+	}, body["target"])
+}
+
+func TestAssignmentBodyWithoutFilter(t *testing.T) {
+	body := Assignment{Intent: IntentAvailable, Target: TargetAllUsers}.body()
+	data, err := json.Marshal(body.Target)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"@odata.type": "#microsoft.graph.allLicensedUsersAssignmentTarget",
+		"deviceAndAppManagementAssignmentFilterId": null,
+		"deviceAndAppManagementAssignmentFilterType": "none"
+	}`, string(data))
+}
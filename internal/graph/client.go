@@ -0,0 +1,178 @@
+package graph
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/kenchan0130/intunewin/internal/auth"
+	"github.com/kenchan0130/intunewin/internal/retry"
+)
+
+// Error is an error response of Microsoft Graph
+type Error struct {
+	StatusCode int
+	// Code is the Graph error code such as BadRequest or ResourceNotFound
+	Code    string
+	Message string
+	// RequestID identifies the request for Microsoft support
+	RequestID string
+	// RetryAfter is the delay Graph asked for before the request is sent again, such as
+	// when it is throttled
+	RetryAfter time.Duration
+}
+
+func (e *Error) Error() string {
+	msg := fmt.Sprintf("graph request failed with status %d", e.StatusCode)
+	if e.Code != "" {
+		msg += ": " + e.Code
+	}
+	if e.Message != "" {
+		msg += ": " + e.Message
+	}
+	if e.RequestID != "" {
+		msg += " (request ID " + e.RequestID + ")"
+	}
+	return msg
+}
+
+// errorResponse is the body of a Graph error response
+type errorResponse struct {
+	Error struct {
+		Code       string `json:"code"`
+		Message    string `json:"message"`
+		InnerError struct {
+			RequestID string `json:"request-id"`
+		} `json:"innerError"`
+	} `json:"error"`
+}
+
+// Client sends requests to Microsoft Graph with tokens of a token source
+type Client struct {
+	tokens auth.TokenSource
+	opts   *Options
+}
+
+// NewClient returns a Graph client that signs requests with tokens
+func NewClient(tokens auth.TokenSource, opts ...Option) *Client {
+	return &Client{tokens: tokens, opts: newOptions(opts)}
+}
+
+// do sends a request with the JSON encoding of body to path, relative to the base URL,
+// and decodes the JSON response into out when it is not nil. Throttled and failed requests
+// are retried as the retry policy allows, after the delay asked for with Retry-After.
+func (c *Client) do(ctx context.Context, method, path string, body, out any) error {
+	var data []byte
+	if body != nil {
+		var err error
+		if data, err = json.Marshal(body); err != nil {
+			return fmt.Errorf("failed to marshal graph request: %w", err)
+		}
+	}
+
+	policy := c.opts.Retry
+	for attempt := 1; ; attempt++ {
+		token, err := c.tokens.Token(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to get access token: %w", err)
+		}
+
+		err = c.send(ctx, method, path, token.AccessToken, data, out)
+		if err == nil {
+			return nil
+		}
+		var graphErr *Error
+		isGraphErr := errors.As(err, &graphErr)
+		switch {
+		case attempt > policy.MaxRetries || ctx.Err() != nil:
+			return err
+		case isGraphErr && !retry.Status(graphErr.StatusCode):
+			return err
+		case !isGraphErr && !idempotent(method):
+			// The request may have been processed, sending it again could create duplicates
+			return err
+		}
+
+		var retryAfter time.Duration
+		if isGraphErr {
+			retryAfter = graphErr.RetryAfter
+		}
+		delay := policy.Delay(attempt, retryAfter)
+		c.opts.Logger.Debug("retrying graph request", "method", method, "path", path, "attempt", attempt, "delay", delay, "error", err)
+		if err := sleep(ctx, delay); err != nil {
+			return err //nolint:wrapcheck // context errors are returned as is
+		}
+	}
+}
+
+// send sends one attempt of a request and converts error responses to *Error
+func (c *Client) send(ctx context.Context, method, path, accessToken string, data []byte, out any) error {
+	var reader io.Reader
+	if data != nil {
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(c.opts.BaseURL, "/")+path, reader)
+	if err != nil {
+		return fmt.Errorf("failed to create graph request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("Accept", "application/json")
+	if data != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	c.opts.Logger.Debug("sending graph request", "method", method, "path", path)
+	resp, err := c.opts.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send graph request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return newError(resp)
+	}
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to parse graph response: %w", err)
+	}
+	return nil
+}
+
+// idempotent reports whether a request with method can be sent again after a network
+// error without side effects
+func idempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+// newError converts an error response to *Error
+func newError(resp *http.Response) error {
+	graphErr := &Error{
+		StatusCode: resp.StatusCode,
+		RequestID:  resp.Header.Get("request-id"),
+		RetryAfter: retry.RetryAfter(resp.Header, time.Now()),
+	}
+	var body errorResponse
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if json.Unmarshal(data, &body) == nil {
+		graphErr.Code = body.Error.Code
+		graphErr.Message = body.Error.Message
+		if body.Error.InnerError.RequestID != "" {
+			graphErr.RequestID = body.Error.InnerError.RequestID
+		}
+	}
+	return graphErr
+}
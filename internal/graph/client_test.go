@@ -0,0 +1,172 @@
+package graph
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/kenchan0130/intunewin/internal/auth"
+	"github.com/kenchan0130/intunewin/internal/retry"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// staticTokens always returns the same access token
+type staticTokens struct{}
+
+func (staticTokens) Token(context.Context) (*auth.Token, error) {
+	return &auth.Token{AccessToken: "token", ExpiresAt: time.Now().Add(time.Hour)}, nil
+}
+
+// failingTokens cannot sign in
+type failingTokens struct{}
+
+func (failingTokens) Token(context.Context) (*auth.Token, error) {
+	return nil, &auth.Error{Code: "invalid_client"}
+}
+
+// noSleep makes polling immediate for the duration of a test
+func noSleep(t *testing.T) {
+	t.Helper()
+	orig := sleep
+	sleep = func(ctx context.Context, _ time.Duration) error { return ctx.Err() }
+	t.Cleanup(func() { sleep = orig })
+}
+
+// newTestClient returns a client of a Graph server handled by handler
+func newTestClient(t *testing.T, handler http.HandlerFunc) *Client {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return NewClient(staticTokens{}, WithBaseURL(server.URL+"/beta/"))
+}
+
+func writeJSON(t *testing.T, w http.ResponseWriter, status int, v any) {
+	t.Helper()
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	require.NoError(t, json.NewEncoder(w).Encode(v))
+}
+
+func TestClientSendsToken(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+		assert.Equal(t, "/beta/deviceAppManagement/mobileApps", r.URL.Path)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		writeJSON(t, w, http.StatusCreated, map[string]string{"id": "app1"})
+	})
+
+	id, err := client.CreateApp(context.Background(), map[string]string{"displayName": "MyApp"})
+	require.NoError(t, err)
+	assert.Equal(t, "app1", id)
+}
+
+func TestClientErrors(t *testing.T) {
+	noSleep(t)
+	t.Run("graph error", func(t *testing.T) {
+		client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+			writeJSON(t, w, http.StatusForbidden, map[string]any{"error": map[string]any{
+				"code":       "Forbidden",
+				"message":    "Application is not authorized to perform this operation",
+				"innerError": map[string]string{"request-id": "req1"},
+			}})
+		})
+		_, err := client.CreateApp(context.Background(), struct{}{})
+		var graphErr *Error
+		require.ErrorAs(t, err, &graphErr)
+		assert.Equal(t, http.StatusForbidden, graphErr.StatusCode)
+		assert.Equal(t, "Forbidden", graphErr.Code)
+		assert.Equal(t, "req1", graphErr.RequestID)
+		assert.Contains(t, err.Error(), "not authorized")
+	})
+
+	t.Run("empty error body", func(t *testing.T) {
+		client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadGateway)
+		})
+		_, err := client.CreateApp(context.Background(), struct{}{})
+		var graphErr *Error
+		require.ErrorAs(t, err, &graphErr)
+		assert.Equal(t, "graph request failed with status 502", graphErr.Error())
+	})
+
+	t.Run("sign-in failure", func(t *testing.T) {
+		client := NewClient(failingTokens{}, WithBaseURL("http://127.0.0.1:0"))
+		_, err := client.CreateApp(context.Background(), struct{}{})
+		var authErr *auth.Error
+		assert.True(t, errors.As(err, &authErr))
+	})
+}
+
+func TestClientRetries(t *testing.T) {
+	var delays []time.Duration
+	orig := sleep
+	sleep = func(_ context.Context, d time.Duration) error {
+		delays = append(delays, d)
+		return nil
+	}
+	t.Cleanup(func() { sleep = orig })
+
+	t.Run("throttled", func(t *testing.T) {
+		delays = nil
+		var requests atomic.Int32
+		client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+			if requests.Add(1) < 3 {
+				w.Header().Set("Retry-After", "7")
+				writeJSON(t, w, http.StatusTooManyRequests, map[string]any{"error": map[string]string{"code": "TooManyRequests"}})
+				return
+			}
+			writeJSON(t, w, http.StatusCreated, map[string]string{"id": "app1"})
+		})
+		id, err := client.CreateApp(context.Background(), struct{}{})
+		require.NoError(t, err)
+		assert.Equal(t, "app1", id)
+		assert.Equal(t, []time.Duration{7 * time.Second, 7 * time.Second}, delays)
+	})
+
+	t.Run("retry limit", func(t *testing.T) {
+		delays = nil
+		var requests atomic.Int32
+		client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+			requests.Add(1)
+			w.WriteHeader(http.StatusServiceUnavailable)
+		})
+		client.opts.Retry.MaxRetries = 2
+		_, err := client.ContentFile(context.Background(), "app1", "1", "file1")
+		var graphErr *Error
+		require.ErrorAs(t, err, &graphErr)
+		assert.Equal(t, http.StatusServiceUnavailable, graphErr.StatusCode)
+		assert.Equal(t, int32(3), requests.Load())
+	})
+
+	t.Run("client errors are not retried", func(t *testing.T) {
+		var requests atomic.Int32
+		client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+			requests.Add(1)
+			w.WriteHeader(http.StatusBadRequest)
+		})
+		_, err := client.CreateApp(context.Background(), struct{}{})
+		assert.Error(t, err)
+		assert.Equal(t, int32(1), requests.Load())
+	})
+
+	t.Run("network errors", func(t *testing.T) {
+		server := httptest.NewServer(http.NotFoundHandler())
+		server.Close()
+		delays = nil
+		client := NewClient(staticTokens{}, WithBaseURL(server.URL), WithRetry(retry.Policy{MaxRetries: 2}))
+
+		_, err := client.CreateApp(context.Background(), struct{}{})
+		assert.Error(t, err)
+		assert.Empty(t, delays, "POST is not sent again after a network error")
+
+		_, err = client.ContentFile(context.Background(), "app1", "1", "file1")
+		assert.Error(t, err)
+		assert.Len(t, delays, 2)
+	})
+}
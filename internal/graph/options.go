@@ -0,0 +1,85 @@
+package graph
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/kenchan0130/intunewin/internal/retry"
+)
+
+// DefaultBaseURL is the Graph endpoint of Win32 app management. The beta version is
+// used because detection rules of win32LobApp are not available in v1.0.
+const DefaultBaseURL = "https://graph.microsoft.com/beta"
+
+// DefaultPollInterval is how often the state of a content file is checked
+const DefaultPollInterval = 5 * time.Second
+
+// Options holds settings for Client
+type Options struct {
+	// Logger receives diagnostic messages
+	Logger *slog.Logger
+	// HTTPClient sends the Graph requests, http.DefaultClient when nil
+	HTTPClient *http.Client
+	// BaseURL is the Graph endpoint, for national clouds
+	BaseURL string
+	// PollInterval is the time between checks of a content file state
+	PollInterval time.Duration
+	// Retry bounds the retries of throttled and failed requests
+	Retry retry.Policy
+}
+
+// Option configures Options
+type Option func(*Options)
+
+// WithLogger sends diagnostic messages to logger
+func WithLogger(logger *slog.Logger) Option {
+	return func(o *Options) {
+		o.Logger = logger
+	}
+}
+
+// WithHTTPClient sends Graph requests with client
+func WithHTTPClient(client *http.Client) Option {
+	return func(o *Options) {
+		o.HTTPClient = client
+	}
+}
+
+// WithBaseURL sends requests to baseURL instead of DefaultBaseURL
+func WithBaseURL(baseURL string) Option {
+	return func(o *Options) {
+		o.BaseURL = baseURL
+	}
+}
+
+// WithPollInterval checks content file states every d instead of DefaultPollInterval
+func WithPollInterval(d time.Duration) Option {
+	return func(o *Options) {
+		o.PollInterval = d
+	}
+}
+
+// WithRetry retries throttled and failed requests as policy allows instead of retry.DefaultPolicy
+func WithRetry(policy retry.Policy) Option {
+	return func(o *Options) {
+		o.Retry = policy
+	}
+}
+
+// newOptions applies opts over the defaults
+func newOptions(opts []Option) *Options {
+	o := &Options{
+		HTTPClient:   http.DefaultClient,
+		BaseURL:      DefaultBaseURL,
+		PollInterval: DefaultPollInterval,
+		Retry:        retry.DefaultPolicy,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.Logger == nil {
+		o.Logger = slog.New(slog.DiscardHandler)
+	}
+	return o
+}
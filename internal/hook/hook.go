@@ -0,0 +1,72 @@
+// Package hook runs shell commands as pack hooks.
+package hook
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+
+	"github.com/kenchan0130/intunewin/internal/pack"
+)
+
+// EnvPrefix is the prefix of the environment variables that describe the package to a hook.
+// It differs from the configuration prefix so that a hook running intunewin does not pick
+// them up as flag values.
+const EnvPrefix = "INTUNEWIN_HOOK_"
+
+// runShell runs line with the shell of the platform, feeding it input and writing its
+// output to output
+var runShell = func(line string, env []string, input []byte, output io.Writer) error {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", line) // #nosec G204 -- hooks are configured by the user
+	} else {
+		cmd = exec.Command("sh", "-c", line) // #nosec G204 -- hooks are configured by the user
+	}
+	cmd.Env = append(os.Environ(), env...)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = output
+	cmd.Stderr = output
+	return cmd.Run() //nolint:wrapcheck // wrapped by the caller
+}
+
+// Command returns a pack hook that runs line with the shell of the platform (sh -c, or
+// cmd /C on Windows). The hook receives the HookInfo as JSON on standard input and as
+// INTUNEWIN_HOOK_* environment variables, and its output is written to output. A non-zero
+// exit status fails the hook.
+func Command(line string, output io.Writer) pack.Hook {
+	return func(info pack.HookInfo) error {
+		input, err := json.Marshal(info)
+		if err != nil {
+			return fmt.Errorf("failed to encode hook input: %w", err)
+		}
+		if err := runShell(line, Env(info), append(input, '\n'), output); err != nil {
+			return fmt.Errorf("%q: %w", line, err)
+		}
+		return nil
+	}
+}
+
+// Env returns the environment variables describing info, such as INTUNEWIN_HOOK_STAGE
+func Env(info pack.HookInfo) []string {
+	env := []string{
+		EnvPrefix + "STAGE=" + string(info.Stage),
+		EnvPrefix + "SOURCE_FOLDER=" + info.SourceFolder,
+		EnvPrefix + "OUTPUT_FILE=" + info.OutputFile,
+		EnvPrefix + "NAME=" + info.Name,
+		EnvPrefix + "SETUP_FILE=" + info.SetupFile,
+	}
+	if info.Stage == pack.HookPostPack {
+		env = append(env,
+			EnvPrefix+"FILES="+strconv.Itoa(info.Files),
+			EnvPrefix+"UNENCRYPTED_SIZE="+strconv.FormatInt(info.UnencryptedSize, 10),
+			EnvPrefix+"SHA256="+info.SHA256,
+		)
+	}
+	return env
+}
@@ -0,0 +1,70 @@
+package hook
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/kenchan0130/intunewin/internal/pack"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommand(t *testing.T) {
+	var gotLine string
+	var gotEnv []string
+	var gotInput pack.HookInfo
+	original := runShell
+	runShell = func(line string, env []string, input []byte, output io.Writer) error {
+		gotLine, gotEnv = line, env
+		require.NoError(t, json.Unmarshal(input, &gotInput))
+		_, err := output.Write([]byte("scanned\n"))
+		return err
+	}
+	t.Cleanup(func() { runShell = original })
+
+	info := pack.HookInfo{Stage: pack.HookPostPack, SourceFolder: "src", OutputFile: "app.intunewin", Name: "App", SetupFile: "setup.exe", Files: 2, UnencryptedSize: 10, SHA256: "abc"}
+	var output bytes.Buffer
+	require.NoError(t, Command("upload.sh", &output)(info))
+
+	assert.Equal(t, "upload.sh", gotLine)
+	assert.Equal(t, info, gotInput)
+	assert.Contains(t, gotEnv, "INTUNEWIN_HOOK_STAGE=post-pack")
+	assert.Contains(t, gotEnv, "INTUNEWIN_HOOK_OUTPUT_FILE=app.intunewin")
+	assert.Contains(t, gotEnv, "INTUNEWIN_HOOK_SHA256=abc")
+	assert.Equal(t, "scanned\n", output.String())
+}
+
+func TestCommandFailure(t *testing.T) {
+	original := runShell
+	runShell = func(string, []string, []byte, io.Writer) error { return errors.New("exit status 1") }
+	t.Cleanup(func() { runShell = original })
+
+	err := Command("scan", io.Discard)(pack.HookInfo{Stage: pack.HookPrePack})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `"scan": exit status 1`)
+}
+
+func TestEnvPrePack(t *testing.T) {
+	env := Env(pack.HookInfo{Stage: pack.HookPrePack, Name: "App"})
+	assert.Contains(t, env, "INTUNEWIN_HOOK_NAME=App")
+	for _, v := range env {
+		assert.False(t, strings.HasPrefix(v, "INTUNEWIN_HOOK_SHA256="), v)
+	}
+}
+
+func TestCommandRunsShell(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+	var output bytes.Buffer
+	hook := Command(`test "$INTUNEWIN_HOOK_STAGE" = pre-pack && grep -q '"name":"App"' && echo ok`, &output)
+	require.NoError(t, hook(pack.HookInfo{Stage: pack.HookPrePack, Name: "App"}))
+	assert.Equal(t, "ok\n", output.String())
+
+	require.Error(t, Command("exit 3", io.Discard)(pack.HookInfo{}))
+}
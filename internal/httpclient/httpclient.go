@@ -0,0 +1,72 @@
+package httpclient
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+)
+
+// ErrNoCertificates is returned when a CA bundle holds no PEM certificate
+var ErrNoCertificates = errors.New("no PEM certificates found")
+
+// Config describes how network requests leave the machine
+type Config struct {
+	// Proxy is the URL of the proxy of all requests. When empty, HTTP_PROXY, HTTPS_PROXY
+	// and NO_PROXY are honored.
+	Proxy string
+	// CABundle is a PEM file with certificates trusted in addition to the system roots,
+	// such as the CA of a TLS-intercepting proxy
+	CABundle string
+	// Transport sends the requests instead of a transport built from Proxy and CABundle
+	Transport http.RoundTripper
+}
+
+// New returns an HTTP client for c
+func New(c Config) (*http.Client, error) {
+	if c.Transport != nil {
+		return &http.Client{Transport: c.Transport}, nil
+	}
+
+	transport, ok := http.DefaultTransport.(*http.Transport)
+	if !ok {
+		return nil, errors.New("default transport is not an *http.Transport")
+	}
+	transport = transport.Clone()
+
+	if c.Proxy != "" {
+		proxyURL, err := url.Parse(c.Proxy)
+		if err != nil || proxyURL.Scheme == "" || proxyURL.Host == "" {
+			return nil, fmt.Errorf("invalid proxy URL %q", c.Proxy)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	if c.CABundle != "" {
+		pool, err := certPool(c.CABundle)
+		if err != nil {
+			return nil, err
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+	}
+	return &http.Client{Transport: transport}, nil
+}
+
+// certPool returns the system roots together with the certificates of the PEM file path
+func certPool(path string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(path) // #nosec G304 -- path is chosen by the user
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA bundle: %w", err)
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("%w in %s", ErrNoCertificates, path)
+	}
+	return pool, nil
+}
@@ -0,0 +1,90 @@
+package httpclient
+
+import (
+	"encoding/pem"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// roundTripperFunc adapts a function to http.RoundTripper
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+func TestNewProxy(t *testing.T) {
+	var proxied atomic.Int32
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied.Add(1)
+		assert.Equal(t, "http://intune.example/path", r.URL.String())
+		_, _ = io.WriteString(w, "via proxy")
+	}))
+	t.Cleanup(proxy.Close)
+
+	client, err := New(Config{Proxy: proxy.URL})
+	require.NoError(t, err)
+	resp, err := client.Get("http://intune.example/path")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "via proxy", string(body))
+	assert.Equal(t, int32(1), proxied.Load())
+}
+
+func TestNewCABundle(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(server.Close)
+
+	client, err := New(Config{})
+	require.NoError(t, err)
+	_, err = client.Get(server.URL)
+	require.Error(t, err, "the test server is not trusted by default")
+
+	bundle := filepath.Join(t.TempDir(), "ca.pem")
+	data := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	require.NoError(t, os.WriteFile(bundle, data, 0600))
+
+	client, err = New(Config{CABundle: bundle})
+	require.NoError(t, err)
+	resp, err := client.Get(server.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+}
+
+func TestNewTransport(t *testing.T) {
+	transport := roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusTeapot, Body: http.NoBody, Request: r}, nil
+	})
+	client, err := New(Config{Transport: transport, Proxy: "::invalid"})
+	require.NoError(t, err)
+	resp, err := client.Get("http://intune.example")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusTeapot, resp.StatusCode)
+}
+
+func TestNewErrors(t *testing.T) {
+	_, err := New(Config{Proxy: "proxy:8080"})
+	assert.ErrorContains(t, err, "invalid proxy URL")
+
+	_, err = New(Config{CABundle: filepath.Join(t.TempDir(), "missing.pem")})
+	assert.Error(t, err)
+
+	bundle := filepath.Join(t.TempDir(), "empty.pem")
+	require.NoError(t, os.WriteFile(bundle, []byte("not a certificate"), 0600))
+	_, err = New(Config{CABundle: bundle})
+	assert.ErrorIs(t, err, ErrNoCertificates)
+}
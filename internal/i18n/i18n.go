@@ -0,0 +1,101 @@
+// Package i18n translates the help and error messages of the CLI.
+// Messages are looked up by their English text, so a message missing from a
+// catalog is shown in English.
+package i18n
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Supported languages
+const (
+	English  = "en"
+	Japanese = "ja"
+)
+
+// ErrUnsupportedLanguage is returned by Parse for languages without a catalog
+var ErrUnsupportedLanguage = errors.New("unsupported language")
+
+// catalogs maps each language except English to its translations
+var catalogs = map[string]map[string]string{
+	Japanese: ja,
+}
+
+// Languages returns the supported languages
+func Languages() []string {
+	return []string{English, Japanese}
+}
+
+// Parse returns the language of a tag such as ja, ja-JP or ja_JP.UTF-8
+func Parse(tag string) (string, error) {
+	lang := strings.ToLower(tag)
+	if i := strings.IndexAny(lang, "_-.@"); i >= 0 {
+		lang = lang[:i]
+	}
+	if lang == English {
+		return English, nil
+	}
+	if _, ok := catalogs[lang]; ok {
+		return lang, nil
+	}
+	return "", fmt.Errorf("%w %q: must be one of %s", ErrUnsupportedLanguage, tag, strings.Join(Languages(), ", "))
+}
+
+// Detect returns the language selected by the LC_ALL, LC_MESSAGES and LANG
+// environment variables, or English when none selects a supported language
+func Detect(getenv func(string) string) string {
+	for _, name := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		value := getenv(name)
+		if value == "" {
+			continue
+		}
+		// The first variable set wins, as in the POSIX locale lookup
+		if lang, err := Parse(value); err == nil {
+			return lang
+		}
+		return English
+	}
+	return English
+}
+
+// Translator translates messages into one language
+type Translator struct {
+	lang    string
+	catalog map[string]string
+}
+
+// New creates a Translator for lang, which must be a language returned by Parse or Detect
+func New(lang string) *Translator {
+	return &Translator{lang: lang, catalog: catalogs[lang]}
+}
+
+// Lang returns the language of the translator
+func (t *Translator) Lang() string {
+	return t.lang
+}
+
+// T returns the translation of msg, or msg when it has none
+func (t *Translator) T(msg string) string {
+	if translated, ok := t.catalog[msg]; ok {
+		return translated
+	}
+	return msg
+}
+
+// Sprintf formats args with the translation of format
+func (t *Translator) Sprintf(format string, args ...any) string {
+	return fmt.Sprintf(t.T(format), args...)
+}
+
+// Error translates the message of err. Wrapped errors read "outer: inner", so
+// every colon separated part is translated on its own; parts with file names or
+// other details stay as they are.
+func (t *Translator) Error(err error) string {
+	parts := strings.Split(err.Error(), ": ")
+	for i, part := range parts {
+		parts[i] = t.T(part)
+	}
+	return strings.Join(parts, ": ")
+}
@@ -0,0 +1,59 @@
+package i18n
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	for tag, want := range map[string]string{
+		"en":          English,
+		"ja":          Japanese,
+		"ja-JP":       Japanese,
+		"ja_JP.UTF-8": Japanese,
+		"JA":          Japanese,
+		"en_US.UTF-8": English,
+	} {
+		lang, err := Parse(tag)
+		require.NoError(t, err, tag)
+		assert.Equal(t, want, lang, tag)
+	}
+
+	_, err := Parse("fr")
+	assert.ErrorIs(t, err, ErrUnsupportedLanguage)
+}
+
+func TestDetect(t *testing.T) {
+	env := func(vars map[string]string) func(string) string {
+		return func(name string) string { return vars[name] }
+	}
+
+	assert.Equal(t, English, Detect(env(nil)))
+	assert.Equal(t, Japanese, Detect(env(map[string]string{"LANG": "ja_JP.UTF-8"})))
+	assert.Equal(t, English, Detect(env(map[string]string{"LC_ALL": "C", "LANG": "ja_JP.UTF-8"})))
+	assert.Equal(t, Japanese, Detect(env(map[string]string{"LC_MESSAGES": "ja_JP", "LANG": "en_US"})))
+}
+
+func TestTranslator(t *testing.T) {
+	tr := New(Japanese)
+	assert.Equal(t, "フラグ:", tr.T("Flags:"))
+	assert.Equal(t, "untranslated", tr.T("untranslated"))
+	assert.Equal(t, "使い方は 'intunewin pack --help' で確認できます。", tr.Sprintf("Run '%s --help' for usage.", "intunewin pack"))
+
+	err := fmt.Errorf("failed to pack: %w", fmt.Errorf("%w: /tmp/app", errors.New("source folder does not exist")))
+	assert.Equal(t, "パッケージ化に失敗しました: ソースフォルダーが存在しません: /tmp/app", tr.Error(err))
+	assert.Equal(t, err.Error(), New(English).Error(err))
+}
+
+func TestCatalogsKeepFormatVerbs(t *testing.T) {
+	for lang, catalog := range catalogs {
+		for msg, translated := range catalog {
+			assert.Equal(t, strings.Count(msg, "%s"), strings.Count(translated, "%s"), "%s: %q", lang, msg)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package i18n
+
+// ja is the Japanese catalog
+var ja = map[string]string{
+	// Help
+	"Usage:":                  "使い方:",
+	"Aliases:":                "別名:",
+	"Examples:":               "例:",
+	"Available Commands:":     "利用可能なコマンド:",
+	"Additional Commands:":    "その他のコマンド:",
+	"Flags:":                  "フラグ:",
+	"Global Flags:":           "グローバルフラグ:",
+	"Additional help topics:": "その他のヘルプトピック:",
+	`Use "%s [command] --help" for more information about a command.`: `コマンドの詳細は "%s [command] --help" で確認できます。`,
+	"help for %s":    "%s のヘルプを表示します",
+	"version for %s": "%s のバージョンを表示します",
+
+	// Commands
+	"A CLI tool for creating and extracting intunewin files":          "intunewin ファイルを作成・展開する CLI ツール",
+	"Package a folder into an intunewin file":                         "フォルダーを intunewin ファイルにパッケージ化します",
+	"Extract an intunewin file to a folder":                           "intunewin ファイルをフォルダーに展開します",
+	"Show the metadata of an intunewin file":                          "intunewin ファイルのメタデータを表示します",
+	"List the files in an intunewin file":                             "intunewin ファイル内のファイルを一覧表示します",
+	"Print a packaged file to standard output":                        "パッケージ内のファイルを標準出力に書き出します",
+	"Show the folder hierarchy of an intunewin file":                  "intunewin ファイルのフォルダー階層を表示します",
+	"Report where the size of a package goes":                         "パッケージのサイズの内訳を報告します",
+	"Generate a software bill of materials of the packaged files":     "パッケージ化されたファイルのソフトウェア部品表 (SBOM) を生成します",
+	"Create the skeleton of a new package":                            "新しいパッケージのひな形を作成します",
+	"Create a PowerShell App Deployment Toolkit skeleton":             "PowerShell App Deployment Toolkit のひな形を作成します",
+	"Create a detached signature of a package":                        "パッケージの分離署名を作成します",
+	"Verify the detached signature of a package":                      "パッケージの分離署名を検証します",
+	"Verify the integrity of intunewin files":                         "intunewin ファイルの整合性を検証します",
+	"Print the SHA256 and MD5 digests of a packaged file":             "パッケージ内のファイルの SHA256 と MD5 ダイジェストを表示します",
+	"Export a JSON manifest describing an intunewin file":             "intunewin ファイルを記述する JSON マニフェストを出力します",
+	"Edit the contents of an intunewin file in a temporary workspace": "一時ワークスペースで intunewin ファイルの内容を編集します",
+	"Decrypt a raw encrypted contents file with supplied keys":        "指定した鍵で暗号化されたコンテンツファイルを復号します",
+	"Check a source folder for common packaging problems":             "ソースフォルダーによくあるパッケージ化の問題がないか確認します",
+	"Extract the icon of a setup file as a PNG app logo":              "セットアップファイルのアイコンを PNG のアプリロゴとして抽出します",
+	"Generate Intune app rules":                                       "Intune アプリのルールを生成します",
+	"Generate Graph detection rules for a package":                    "パッケージの Graph 検出ルールを生成します",
+	"Generate the Graph request body of a Win32 app":                  "Win32 アプリの Graph リクエスト本文を生成します",
+	"Generate the Graph commit request body of a package":             "パッケージの Graph コミットリクエスト本文を生成します",
+	"Upload a package to Intune as a Win32 app":                       "パッケージを Win32 アプリとして Intune にアップロードします",
+	"Assign an Intune app to groups":                                  "Intune アプリをグループに割り当てます",
+	"Manage the Microsoft Graph sign-in":                              "Microsoft Graph へのサインインを管理します",
+	"Sign in to Microsoft Graph":                                      "Microsoft Graph にサインインします",
+	"Remove cached Microsoft Graph tokens":                            "キャッシュされた Microsoft Graph トークンを削除します",
+	"Manage the intunewin cache directory":                            "intunewin のキャッシュディレクトリを管理します",
+	"Remove everything from the cache directory":                      "キャッシュディレクトリの内容をすべて削除します",
+	"Serve packing, unpacking and inspection over HTTP":               "パッケージ化・展開・検査を HTTP で提供します",
+	"Repack a folder whenever its files change":                       "フォルダー内のファイルが変更されるたびに再パッケージ化します",
+	"Pack every app listed in a manifest file":                        "マニフェストファイルに列挙されたすべてのアプリをパッケージ化します",
+	"Generate reference documentation of the commands":                "コマンドのリファレンスドキュメントを生成します",
+	"Generate man pages":                                              "man ページを生成します",
+	"Generate Markdown reference pages":                               "Markdown のリファレンスページを生成します",
+	"Print the version and build information":                         "バージョンとビルド情報を表示します",
+	"Check the environment and a package for common problems":         "環境とパッケージによくある問題がないか確認します",
+	"Help about any command":                                          "コマンドのヘルプを表示します",
+	"Generate the autocompletion script for the specified shell":      "指定したシェル用の補完スクリプトを生成します",
+
+	// Global flags
+	"minimum level of log messages: debug, info, warn or error":                                                                      "出力するログメッセージの最小レベル: debug、info、warn、error",
+	"format of log messages written to stderr: text or json":                                                                         "標準エラー出力に書き込むログメッセージの形式: text または json",
+	"write errors to stderr as a JSON object with the message, kind and exit code":                                                   "エラーをメッセージ・種類・終了コードを含む JSON オブジェクトとして標準エラー出力に書き込みます",
+	"only print warnings and errors (same as --log-level warn)":                                                                      "警告とエラーのみ表示します (--log-level warn と同じ)",
+	"print debug messages; repeat (-vv) to also log every processed file and the duration of each stage":                             "デバッグメッセージを表示します。繰り返す (-vv) と処理した各ファイルと各段階の所要時間も記録します",
+	"language of help and error messages: en or ja (default is taken from LC_ALL, LC_MESSAGES or LANG)":                              "ヘルプとエラーメッセージの言語: en または ja (既定値は LC_ALL、LC_MESSAGES、LANG から決まります)",
+	"configuration file with default flag values (default is $INTUNEWIN_CONFIG, or config.yaml in the user configuration directory)": "フラグの既定値を記述した設定ファイル (既定値は $INTUNEWIN_CONFIG、またはユーザー設定ディレクトリの config.yaml)",
+	"directory for temporary files (default is the system temporary directory)":                                                      "一時ファイル用のディレクトリ (既定値はシステムの一時ディレクトリ)",
+	"size in bytes above which intermediate data is written to temporary files instead of memory":                                    "中間データをメモリではなく一時ファイルに書き込むしきい値のバイト数",
+	"total size of intermediate data kept in memory before writing to temporary files, such as 512MB (default no limit)":             "一時ファイルに書き込む前にメモリに保持する中間データの合計サイズ (例: 512MB、既定値は無制限)",
+
+	// Errors
+	"Error":                                                      "エラー",
+	"Run '%s --help' for usage.":                                 "使い方は '%s --help' で確認できます。",
+	"failed to pack":                                             "パッケージ化に失敗しました",
+	"failed to unpack":                                           "展開に失敗しました",
+	"failed to read metadata":                                    "メタデータの読み込みに失敗しました",
+	"failed to read Detection.xml":                               "Detection.xml の読み込みに失敗しました",
+	"failed to read encrypted contents":                          "暗号化されたコンテンツの読み込みに失敗しました",
+	"failed to decrypt contents":                                 "コンテンツの復号に失敗しました",
+	"failed to create output directory":                          "出力ディレクトリの作成に失敗しました",
+	"failed to create output file":                               "出力ファイルの作成に失敗しました",
+	"failed to write output file":                                "出力ファイルの書き込みに失敗しました",
+	"failed to open input file":                                  "入力ファイルを開けませんでした",
+	"failed to read input":                                       "入力の読み込みに失敗しました",
+	"failed to access source folder":                             "ソースフォルダーにアクセスできませんでした",
+	"failed to walk source folder":                               "ソースフォルダーの走査に失敗しました",
+	"failed to run preflight checks":                             "事前チェックの実行に失敗しました",
+	"failed to detect the setup file, pass --setup-file":         "セットアップファイルを検出できませんでした。--setup-file を指定してください",
+	"failed to read configuration":                               "設定の読み込みに失敗しました",
+	"failed to read batch manifest":                              "バッチマニフェストの読み込みに失敗しました",
+	"failed to use custom encryption keys":                       "指定された暗号化鍵を使用できませんでした",
+	"failed to export keys":                                      "鍵のエクスポートに失敗しました",
+	"at least one recipient is required to encrypt the key file": "鍵ファイルを暗号化するには少なくとも 1 つの受信者が必要です",
+	"failed to generate SBOM":                                    "SBOM の生成に失敗しました",
+	"failed to create project file":                              "プロジェクトファイルの作成に失敗しました",
+	"failed to read project file":                                "プロジェクトファイルの読み込みに失敗しました",
+	"invalid project file":                                       "プロジェクトファイルが不正です",
+	"failed to create PSADT skeleton":                            "PSADT のひな形の作成に失敗しました",
+	"an application name is required":                            "アプリケーション名が必要です",
+	"failed to sign":                                             "署名に失敗しました",
+	"failed to verify signature":                                 "署名の検証に失敗しました",
+	"failed to read signing key":                                 "署名鍵の読み込みに失敗しました",
+	"failed to read public key":                                  "公開鍵の読み込みに失敗しました",
+	"failed to remove padding":                                   "パディングの除去に失敗しました",
+	"source folder does not exist":                               "ソースフォルダーが存在しません",
+	"source path is not a directory":                             "ソースのパスがディレクトリではありません",
+	"source path is not a file":                                  "ソースのパスがファイルではありません",
+	"path collision":                                             "パスが衝突しています",
+	"paths differ only in case":                                  "大文字と小文字だけが異なるパスがあります",
+	"name cannot be created on Windows":                          "Windows で作成できない名前です",
+	"no .cat files in the catalog folder":                        "カタログフォルダーに .cat ファイルがありません",
+	"source folder contains a symbolic link":                     "ソースフォルダーにシンボリックリンクが含まれています",
+	"symbolic link loop":                                         "シンボリックリンクがループしています",
+	"setup file not found in the content":                        "コンテンツにセットアップファイルが見つかりません",
+	"several setup file candidates":                              "セットアップファイルの候補が複数あります",
+	"input file does not exist":                                  "入力ファイルが存在しません",
+	"not an intunewin package":                                   "intunewin パッケージではありません",
+	"detection.xml not found in intunewin package":               "intunewin パッケージに Detection.xml が見つかりません",
+	"encrypted contents not found in intunewin package":          "intunewin パッケージに暗号化されたコンテンツが見つかりません",
+	"invalid Detection.xml":                                      "Detection.xml が不正です",
+	"unsupported intunewin format version":                       "サポートされていない intunewin 形式のバージョンです",
+	"nonstandard intunewin package":                              "標準と異なる intunewin パッケージです",
+	"decrypted content is not a zip archive":                     "復号したコンテンツが zip アーカイブではありません",
+	"path escapes the output folder":                             "パスが出力フォルダーの外を指しています",
+	"file already exists":                                        "ファイルがすでに存在します",
+	"no entries match the selection":                             "選択に一致するエントリがありません",
+	"file is not in the content":                                 "ファイルがコンテンツにありません",
+	"invalid encryption keys":                                    "暗号化鍵が不正です",
+	"HMAC verification failed":                                   "HMAC の検証に失敗しました",
+	"unsupported SBOM format":                                    "サポートされていない SBOM 形式です",
+	"pack hook failed":                                           "パッケージ化のフックが失敗しました",
+	"invalid signature":                                          "署名が不正です",
+	"invalid Ed25519 key":                                        "Ed25519 鍵が不正です",
+	"invalid padding":                                            "パディングが不正です",
+	"content size does not match Detection.xml":                  "コンテンツのサイズが Detection.xml と一致しません",
+	"content digest does not match Detection.xml":                "コンテンツのダイジェストが Detection.xml と一致しません",
+	"invalid configuration":                                      "設定が不正です",
+	"invalid batch manifest":                                     "バッチマニフェストが不正です",
+	"missing client credential":                                  "クライアント資格情報がありません",
+	"sign-in was declined":                                       "サインインが拒否されました",
+	"device code expired before sign-in completed":               "サインインが完了する前にデバイスコードの有効期限が切れました",
+	"validation failed":                                          "検証に失敗しました",
+	"preflight checks reported errors":                           "事前チェックでエラーが見つかりました",
+	"unsupported language":                                       "サポートされていない言語です",
+	"refusing to write binary data to a terminal, redirect standard output": "バイナリデータは端末に書き込めません。標準出力をリダイレクトしてください",
+}
@@ -0,0 +1,113 @@
+package icon
+
+import (
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+)
+
+// decodeDIB decodes the BITMAPINFOHEADER based image stored in icon resources.
+// The bitmap holds the color (XOR) image followed by a 1-bit transparency (AND) mask,
+// so the header height is twice the icon height.
+func decodeDIB(data []byte) (image.Image, error) {
+	if len(data) < 40 {
+		return nil, fmt.Errorf("icon bitmap is too short")
+	}
+	headerSize := binary.LittleEndian.Uint32(data[0:])
+	width := int(int32(binary.LittleEndian.Uint32(data[4:])))      // #nosec G115 -- sign is checked below
+	height := int(int32(binary.LittleEndian.Uint32(data[8:]))) / 2 // #nosec G115 -- sign is checked below
+	bitCount := int(binary.LittleEndian.Uint16(data[14:]))
+	compression := binary.LittleEndian.Uint32(data[16:])
+	colorsUsed := int(binary.LittleEndian.Uint32(data[32:]))
+
+	if headerSize < 40 || uint64(headerSize) > uint64(len(data)) {
+		return nil, fmt.Errorf("invalid bitmap header size %d", headerSize)
+	}
+	if width <= 0 || height <= 0 || width > 1024 || height > 1024 {
+		return nil, fmt.Errorf("invalid icon dimensions %dx%d", width, height)
+	}
+	if compression != 0 {
+		return nil, fmt.Errorf("compressed icon bitmaps are not supported")
+	}
+
+	offset := int(headerSize)
+	var palette []color.NRGBA
+	if bitCount <= 8 {
+		if colorsUsed == 0 {
+			colorsUsed = 1 << bitCount
+		}
+		if offset+colorsUsed*4 > len(data) {
+			return nil, fmt.Errorf("icon palette is truncated")
+		}
+		palette = make([]color.NRGBA, colorsUsed)
+		for i := range palette {
+			p := data[offset+i*4:]
+			palette[i] = color.NRGBA{R: p[2], G: p[1], B: p[0], A: 0xFF}
+		}
+		offset += colorsUsed * 4
+	}
+
+	switch bitCount {
+	case 1, 4, 8, 24, 32:
+	default:
+		return nil, fmt.Errorf("unsupported icon bit count %d", bitCount)
+	}
+
+	stride := (width*bitCount + 31) / 32 * 4
+	maskStride := (width + 31) / 32 * 4
+	if offset+stride*height > len(data) {
+		return nil, fmt.Errorf("icon bitmap is truncated")
+	}
+	maskOffset := offset + stride*height
+	hasMask := maskOffset+maskStride*height <= len(data)
+
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	hasAlpha := false
+	for y := range height {
+		// Rows are stored bottom-up
+		row := data[offset+(height-1-y)*stride:]
+		for x := range width {
+			var c color.NRGBA
+			switch bitCount {
+			case 32:
+				c = color.NRGBA{R: row[x*4+2], G: row[x*4+1], B: row[x*4], A: row[x*4+3]}
+				hasAlpha = hasAlpha || c.A != 0
+			case 24:
+				c = color.NRGBA{R: row[x*3+2], G: row[x*3+1], B: row[x*3], A: 0xFF}
+			default:
+				index := paletteIndex(row, x, bitCount)
+				if index >= len(palette) {
+					return nil, fmt.Errorf("icon palette index %d is out of range", index)
+				}
+				c = palette[index]
+			}
+			img.SetNRGBA(x, y, c)
+		}
+	}
+
+	// Only use the AND mask when the image has no alpha channel of its own
+	if hasMask && !hasAlpha {
+		for y := range height {
+			row := data[maskOffset+(height-1-y)*maskStride:]
+			for x := range width {
+				transparent := row[x/8]&(0x80>>(x%8)) != 0
+				c := img.NRGBAAt(x, y)
+				c.A = 0xFF
+				if transparent {
+					c.A = 0
+				}
+				img.SetNRGBA(x, y, c)
+			}
+		}
+	}
+	return img, nil
+}
+
+// paletteIndex reads the palette index of pixel x from a row with 1, 4 or 8 bits per pixel
+func paletteIndex(row []byte, x, bitCount int) int {
+	bit := x * bitCount
+	b := row[bit/8]
+	shift := 8 - bitCount - bit%8
+	return int(b>>shift) & (1<<bitCount - 1)
+}
@@ -0,0 +1,168 @@
+package icon
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/kenchan0130/intunewin/internal/pe"
+)
+
+// pngSignature starts every PNG file
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// entry describes one image of an icon group or ICO file
+type entry struct {
+	Width    int
+	Height   int
+	BitCount int
+	// ID is the RT_ICON resource ID in a group, Offset the image position in an ICO file
+	ID     uint16
+	Offset uint32
+	Size   uint32
+}
+
+// better reports whether e should be preferred over other: larger, then more colors
+func (e entry) better(other entry) bool {
+	if e.Width*e.Height != other.Width*other.Height {
+		return e.Width*e.Height > other.Width*other.Height
+	}
+	return e.BitCount > other.BitCount
+}
+
+// FromFile extracts the main icon of an EXE, DLL or ICO file as PNG
+func FromFile(path string) ([]byte, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".msi":
+		return nil, fmt.Errorf("extracting icons from MSI files is not supported")
+	case ".ico":
+		data, err := os.ReadFile(path) // #nosec G304 -- path is provided by the user
+		if err != nil {
+			return nil, fmt.Errorf("failed to read icon file: %w", err)
+		}
+		return FromICO(data)
+	}
+
+	f, err := os.Open(path) // #nosec G304 -- path is provided by the user
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer f.Close()
+	return FromPE(f)
+}
+
+// FromPE extracts the main icon of a PE file as PNG.
+// The main icon is the first icon group; its largest, most colorful image is used.
+func FromPE(r io.ReaderAt) ([]byte, error) {
+	f, err := pe.Open(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read resources: %w", err)
+	}
+
+	groups, err := f.Resources(pe.TypeGroupIcon)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read icon groups: %w", err)
+	}
+	if len(groups) == 0 {
+		return nil, fmt.Errorf("file has no icons")
+	}
+
+	best, err := bestEntry(groups[0].Data, 14)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse icon group: %w", err)
+	}
+
+	icons, err := f.Resources(pe.TypeIcon)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read icons: %w", err)
+	}
+	for _, i := range icons {
+		if i.ID == uint32(best.ID) {
+			return toPNG(i.Data)
+		}
+	}
+	return nil, fmt.Errorf("icon %d referenced by the icon group is missing", best.ID)
+}
+
+// FromICO converts the largest image of an ICO file to PNG
+func FromICO(data []byte) ([]byte, error) {
+	best, err := bestEntry(data, 16)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse icon file: %w", err)
+	}
+
+	end := uint64(best.Offset) + uint64(best.Size)
+	if end > uint64(len(data)) {
+		return nil, fmt.Errorf("icon image is out of bounds")
+	}
+	return toPNG(data[best.Offset:end])
+}
+
+// bestEntry parses an icon directory (GRPICONDIR or ICONDIR) whose entries are
+// entrySize bytes long and returns the preferred image
+func bestEntry(data []byte, entrySize int) (entry, error) {
+	if len(data) < 6 {
+		return entry{}, fmt.Errorf("icon directory is too short")
+	}
+	if binary.LittleEndian.Uint16(data[2:]) != 1 {
+		return entry{}, fmt.Errorf("not an icon directory")
+	}
+	count := int(binary.LittleEndian.Uint16(data[4:]))
+	if count == 0 {
+		return entry{}, fmt.Errorf("icon directory is empty")
+	}
+	if len(data) < 6+count*entrySize {
+		return entry{}, fmt.Errorf("icon directory is truncated")
+	}
+
+	var best entry
+	for i := range count {
+		raw := data[6+i*entrySize:]
+		e := entry{
+			Width:    int(raw[0]),
+			Height:   int(raw[1]),
+			BitCount: int(binary.LittleEndian.Uint16(raw[6:])),
+			Size:     binary.LittleEndian.Uint32(raw[8:]),
+		}
+		// A dimension of 0 means 256 pixels
+		if e.Width == 0 {
+			e.Width = 256
+		}
+		if e.Height == 0 {
+			e.Height = 256
+		}
+		if entrySize == 14 {
+			e.ID = binary.LittleEndian.Uint16(raw[12:])
+		} else {
+			e.Offset = binary.LittleEndian.Uint32(raw[12:])
+		}
+
+		if i == 0 || e.better(best) {
+			best = e
+		}
+	}
+	return best, nil
+}
+
+// toPNG returns icon image data as PNG, converting device independent bitmaps
+func toPNG(data []byte) ([]byte, error) {
+	if bytes.HasPrefix(data, pngSignature) {
+		return data, nil
+	}
+
+	img, err := decodeDIB(data)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := new(bytes.Buffer)
+	if err := png.Encode(buf, img); err != nil {
+		return nil, fmt.Errorf("failed to encode PNG: %w", err)
+	}
+	return buf.Bytes(), nil
+}
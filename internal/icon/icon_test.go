@@ -0,0 +1,148 @@
+package icon
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// buildDIB creates an icon bitmap from a palette, bottom-up pixel rows and an AND mask
+func buildDIB(width, height, bitCount int, palette []color.NRGBA, pixels []byte, mask []byte) []byte {
+	header := make([]byte, 40)
+	binary.LittleEndian.PutUint32(header[0:], 40)
+	binary.LittleEndian.PutUint32(header[4:], uint32(width))
+	binary.LittleEndian.PutUint32(header[8:], uint32(height*2))
+	binary.LittleEndian.PutUint16(header[12:], 1)
+	binary.LittleEndian.PutUint16(header[14:], uint16(bitCount))
+	binary.LittleEndian.PutUint32(header[32:], uint32(len(palette)))
+
+	data := header
+	for _, c := range palette {
+		data = append(data, c.B, c.G, c.R, 0)
+	}
+	data = append(data, pixels...)
+	return append(data, mask...)
+}
+
+// buildICO wraps images in an ICO file
+func buildICO(sizes []int, images [][]byte) []byte {
+	data := make([]byte, 6+16*len(images))
+	binary.LittleEndian.PutUint16(data[2:], 1)
+	binary.LittleEndian.PutUint16(data[4:], uint16(len(images)))
+	for i, img := range images {
+		e := data[6+i*16:]
+		e[0] = byte(sizes[i])
+		e[1] = byte(sizes[i])
+		binary.LittleEndian.PutUint16(e[6:], 32)
+		binary.LittleEndian.PutUint32(e[8:], uint32(len(img)))
+		binary.LittleEndian.PutUint32(e[12:], uint32(len(data)))
+		data = append(data, img...)
+	}
+	return data
+}
+
+func decodePNG(t *testing.T, data []byte) image.Image {
+	t.Helper()
+	img, err := png.Decode(bytes.NewReader(data))
+	require.NoError(t, err)
+	return img
+}
+
+func TestDecodeDIB32WithAlpha(t *testing.T) {
+	// 2x1 image: blue opaque pixel, red half transparent pixel
+	pixels := []byte{0xFF, 0, 0, 0xFF, 0, 0, 0xFF, 0x80}
+	mask := []byte{0, 0, 0, 0}
+	img, err := decodeDIB(buildDIB(2, 1, 32, nil, pixels, mask))
+	require.NoError(t, err)
+
+	assert.Equal(t, image.Rect(0, 0, 2, 1), img.Bounds())
+	assert.Equal(t, color.NRGBA{B: 0xFF, A: 0xFF}, img.At(0, 0))
+	assert.Equal(t, color.NRGBA{R: 0xFF, A: 0x80}, img.At(1, 0))
+}
+
+func TestDecodeDIBPaletteWithMask(t *testing.T) {
+	palette := []color.NRGBA{{R: 0xFF, A: 0xFF}, {G: 0xFF, A: 0xFF}}
+	// 2x2, 1 bit per pixel, rows padded to 4 bytes and stored bottom-up
+	pixels := []byte{
+		0b01000000, 0, 0, 0, // bottom row: red, green
+		0b10000000, 0, 0, 0, // top row: green, red
+	}
+	mask := []byte{
+		0b00000000, 0, 0, 0, // bottom row opaque
+		0b01000000, 0, 0, 0, // top row: second pixel transparent
+	}
+	img, err := decodeDIB(buildDIB(2, 2, 1, palette, pixels, mask))
+	require.NoError(t, err)
+
+	assert.Equal(t, color.NRGBA{G: 0xFF, A: 0xFF}, img.At(0, 0))
+	assert.Equal(t, color.NRGBA{R: 0xFF, A: 0}, img.At(1, 0))
+	assert.Equal(t, color.NRGBA{R: 0xFF, A: 0xFF}, img.At(0, 1))
+	assert.Equal(t, color.NRGBA{G: 0xFF, A: 0xFF}, img.At(1, 1))
+}
+
+func TestDecodeDIBInvalid(t *testing.T) {
+	_, err := decodeDIB([]byte("short"))
+	assert.Error(t, err)
+
+	_, err = decodeDIB(buildDIB(2, 2, 16, nil, make([]byte, 16), nil))
+	assert.Error(t, err)
+
+	// Truncated pixel data
+	_, err = decodeDIB(buildDIB(16, 16, 32, nil, make([]byte, 10), nil))
+	assert.Error(t, err)
+}
+
+func TestFromICOPicksLargestImage(t *testing.T) {
+	small := buildDIB(1, 1, 32, nil, []byte{0, 0, 0xFF, 0xFF}, make([]byte, 4))
+	large := buildDIB(2, 2, 32, nil, bytes.Repeat([]byte{0, 0xFF, 0, 0xFF}, 4), make([]byte, 8))
+
+	data, err := FromICO(buildICO([]int{1, 2}, [][]byte{small, large}))
+	require.NoError(t, err)
+
+	img := decodePNG(t, data)
+	assert.Equal(t, image.Rect(0, 0, 2, 2), img.Bounds())
+	r, g, b, a := img.At(0, 0).RGBA()
+	assert.Equal(t, []uint32{0, 0xFFFF, 0, 0xFFFF}, []uint32{r, g, b, a})
+}
+
+func TestFromICOWithPNGImage(t *testing.T) {
+	buf := new(bytes.Buffer)
+	require.NoError(t, png.Encode(buf, image.NewNRGBA(image.Rect(0, 0, 256, 256))))
+
+	data, err := FromICO(buildICO([]int{0}, [][]byte{buf.Bytes()}))
+	require.NoError(t, err)
+	assert.Equal(t, buf.Bytes(), data)
+}
+
+func TestBestEntryGroup(t *testing.T) {
+	// GRPICONDIR with 16x16x32, 256x256x32 (size 0) and 48x48x8 images
+	group := make([]byte, 6+3*14)
+	binary.LittleEndian.PutUint16(group[2:], 1)
+	binary.LittleEndian.PutUint16(group[4:], 3)
+	for i, e := range []struct {
+		size     byte
+		bitCount uint16
+		id       uint16
+	}{{16, 32, 1}, {0, 32, 2}, {48, 8, 3}} {
+		raw := group[6+i*14:]
+		raw[0], raw[1] = e.size, e.size
+		binary.LittleEndian.PutUint16(raw[6:], e.bitCount)
+		binary.LittleEndian.PutUint16(raw[12:], e.id)
+	}
+
+	best, err := bestEntry(group, 14)
+	require.NoError(t, err)
+	assert.Equal(t, uint16(2), best.ID)
+	assert.Equal(t, 256, best.Width)
+}
+
+func TestFromPEInvalid(t *testing.T) {
+	_, err := FromPE(bytes.NewReader([]byte("MZ not really")))
+	assert.Error(t, err)
+}
@@ -0,0 +1,80 @@
+package icon
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+)
+
+// PortalSize is the edge length in pixels of app logos shown in the Intune portal
+const PortalSize = 256
+
+// Resize scales PNG data to fit a size by size square, keeping the aspect ratio and
+// centering the image on a transparent background. Data that already has the requested
+// size is returned unchanged.
+func Resize(data []byte, size int) ([]byte, error) {
+	if size <= 0 {
+		return nil, fmt.Errorf("invalid icon size %d", size)
+	}
+	src, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode PNG: %w", err)
+	}
+	bounds := src.Bounds()
+	if bounds.Dx() == size && bounds.Dy() == size {
+		return data, nil
+	}
+
+	// Fit the longer side to size
+	width, height := size, size
+	if bounds.Dx() > bounds.Dy() {
+		height = max(1, bounds.Dy()*size/bounds.Dx())
+	} else if bounds.Dy() > bounds.Dx() {
+		width = max(1, bounds.Dx()*size/bounds.Dy())
+	}
+
+	dst := image.NewNRGBA(image.Rect(0, 0, size, size))
+	offsetX, offsetY := (size-width)/2, (size-height)/2
+	for y := range height {
+		for x := range width {
+			dst.SetNRGBA(offsetX+x, offsetY+y, sample(src, x, y, width, height))
+		}
+	}
+
+	buf := new(bytes.Buffer)
+	if err := png.Encode(buf, dst); err != nil {
+		return nil, fmt.Errorf("failed to encode PNG: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// sample returns the color of pixel (x, y) of src scaled to width by height: the average
+// of the source pixels it covers when shrinking, the nearest source pixel when enlarging
+func sample(src image.Image, x, y, width, height int) color.NRGBA {
+	b := src.Bounds()
+	x0, x1 := b.Min.X+x*b.Dx()/width, b.Min.X+(x+1)*b.Dx()/width
+	y0, y1 := b.Min.Y+y*b.Dy()/height, b.Min.Y+(y+1)*b.Dy()/height
+	x1, y1 = max(x1, x0+1), max(y1, y0+1)
+
+	// Average premultiplied values so transparent pixels do not darken the edges
+	var r, g, bl, a, n uint64
+	for sy := y0; sy < y1; sy++ {
+		for sx := x0; sx < x1; sx++ {
+			pr, pg, pb, pa := src.At(sx, sy).RGBA()
+			r, g, bl, a = r+uint64(pr), g+uint64(pg), bl+uint64(pb), a+uint64(pa)
+			n++
+		}
+	}
+	if a == 0 {
+		return color.NRGBA{}
+	}
+	// #nosec G115 -- every average is at most 0xFFFF
+	return color.NRGBA{
+		R: uint8(r * 0xFF / a),
+		G: uint8(g * 0xFF / a),
+		B: uint8(bl * 0xFF / a),
+		A: uint8(a / n >> 8),
+	}
+}
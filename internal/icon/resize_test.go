@@ -0,0 +1,74 @@
+package icon
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// encodePNG encodes an image filled by fill as PNG
+func encodePNG(t *testing.T, width, height int, fill func(x, y int) color.NRGBA) []byte {
+	t.Helper()
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := range height {
+		for x := range width {
+			img.SetNRGBA(x, y, fill(x, y))
+		}
+	}
+	buf := new(bytes.Buffer)
+	require.NoError(t, png.Encode(buf, img))
+	return buf.Bytes()
+}
+
+func TestResizeShrinks(t *testing.T) {
+	red := color.NRGBA{R: 255, A: 255}
+	data := encodePNG(t, 4, 4, func(x, y int) color.NRGBA {
+		// The left half is opaque red, the right half transparent
+		if x < 2 {
+			return red
+		}
+		return color.NRGBA{}
+	})
+
+	resized, err := Resize(data, 2)
+	require.NoError(t, err)
+	img := decodePNG(t, resized)
+	assert.Equal(t, image.Rect(0, 0, 2, 2), img.Bounds())
+	assert.Equal(t, red, color.NRGBAModel.Convert(img.At(0, 1)))
+	assert.Equal(t, color.NRGBA{}, color.NRGBAModel.Convert(img.At(1, 0)))
+}
+
+func TestResizeEnlargesAndCenters(t *testing.T) {
+	blue := color.NRGBA{B: 255, A: 128}
+	data := encodePNG(t, 2, 1, func(int, int) color.NRGBA { return blue })
+
+	resized, err := Resize(data, 4)
+	require.NoError(t, err)
+	img := decodePNG(t, resized)
+	assert.Equal(t, image.Rect(0, 0, 4, 4), img.Bounds())
+	// A 4x2 image centered vertically
+	assert.Equal(t, color.NRGBA{}, color.NRGBAModel.Convert(img.At(0, 0)))
+	assert.Equal(t, blue, color.NRGBAModel.Convert(img.At(0, 1)))
+	assert.Equal(t, blue, color.NRGBAModel.Convert(img.At(3, 2)))
+	assert.Equal(t, color.NRGBA{}, color.NRGBAModel.Convert(img.At(3, 3)))
+}
+
+func TestResizeUnchanged(t *testing.T) {
+	data := encodePNG(t, 3, 3, func(int, int) color.NRGBA { return color.NRGBA{G: 255, A: 255} })
+	resized, err := Resize(data, 3)
+	require.NoError(t, err)
+	assert.Equal(t, data, resized)
+}
+
+func TestResizeInvalid(t *testing.T) {
+	_, err := Resize([]byte("not a PNG"), PortalSize)
+	assert.Error(t, err)
+
+	_, err = Resize(encodePNG(t, 1, 1, func(int, int) color.NRGBA { return color.NRGBA{} }), 0)
+	assert.Error(t, err)
+}
@@ -0,0 +1,128 @@
+package journal
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/kenchan0130/intunewin/internal/pathutil"
+)
+
+// directoryDigest is the digest recorded for directories in a Snapshot
+const directoryDigest = "<dir>"
+
+// Kind describes how a path changed between two snapshots
+type Kind string
+
+const (
+	// Added means the path exists only in the later snapshot
+	Added Kind = "added"
+	// Modified means the path exists in both snapshots with different content
+	Modified Kind = "modified"
+	// Removed means the path exists only in the earlier snapshot
+	Removed Kind = "removed"
+)
+
+// Change records a single changed path
+type Change struct {
+	Path string
+	Kind Kind
+}
+
+// Snapshot maps slash-separated relative paths to the SHA256 digest of their content
+type Snapshot map[string]string
+
+// Take records the state of every file and directory under root
+func Take(root string) (Snapshot, error) {
+	snapshot := make(Snapshot)
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		relPath, err := filepath.Rel(root, path)
+		if err != nil {
+			return fmt.Errorf("failed to get relative path: %w", err)
+		}
+		if relPath == "." {
+			return nil
+		}
+		relPath = pathutil.ToArchive(relPath)
+
+		if info.IsDir() {
+			snapshot[relPath] = directoryDigest
+			return nil
+		}
+
+		digest, err := fileDigest(path)
+		if err != nil {
+			return err
+		}
+		snapshot[relPath] = digest
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to snapshot %s: %w", root, err)
+	}
+	return snapshot, nil
+}
+
+// fileDigest computes the hex encoded SHA256 digest of a file
+func fileDigest(path string) (string, error) {
+	f, err := os.Open(path) // #nosec G304 -- path comes from walking the workspace
+	if err != nil {
+		return "", fmt.Errorf("failed to open file %s: %w", path, err)
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("failed to read file %s: %w", path, err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Compare returns the changes between two snapshots sorted by path
+func Compare(before, after Snapshot) []Change {
+	var changes []Change
+	for path, digest := range after {
+		previous, ok := before[path]
+		switch {
+		case !ok:
+			changes = append(changes, Change{Path: path, Kind: Added})
+		case previous != digest:
+			changes = append(changes, Change{Path: path, Kind: Modified})
+		}
+	}
+	for path := range before {
+		if _, ok := after[path]; !ok {
+			changes = append(changes, Change{Path: path, Kind: Removed})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Path < changes[j].Path
+	})
+	return changes
+}
+
+// Summary renders changes as a human readable report
+func Summary(changes []Change) string {
+	if len(changes) == 0 {
+		return "No changes\n"
+	}
+
+	var b strings.Builder
+	counts := make(map[Kind]int)
+	for _, change := range changes {
+		counts[change.Kind]++
+		fmt.Fprintf(&b, "%-8s %s\n", change.Kind, change.Path)
+	}
+	fmt.Fprintf(&b, "%d added, %d modified, %d removed\n", counts[Added], counts[Modified], counts[Removed])
+	return b.String()
+}
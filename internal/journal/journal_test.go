@@ -0,0 +1,61 @@
+package journal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTakeAndCompare(t *testing.T) {
+	tempDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "keep.txt"), []byte("keep"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "edit.ps1"), []byte("Write-Host 1"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "remove.txt"), []byte("remove"), 0600))
+
+	before, err := Take(tempDir)
+	require.NoError(t, err)
+	assert.Len(t, before, 3)
+
+	// Modify, remove and add files
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "edit.ps1"), []byte("Write-Host 2"), 0600))
+	require.NoError(t, os.Remove(filepath.Join(tempDir, "remove.txt")))
+	require.NoError(t, os.MkdirAll(filepath.Join(tempDir, "subdir"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "subdir", "new.txt"), []byte("new"), 0600))
+
+	after, err := Take(tempDir)
+	require.NoError(t, err)
+
+	changes := Compare(before, after)
+	assert.Equal(t, []Change{
+		{Path: "edit.ps1", Kind: Modified},
+		{Path: "remove.txt", Kind: Removed},
+		{Path: "subdir", Kind: Added},
+		{Path: "subdir/new.txt", Kind: Added},
+	}, changes)
+}
+
+func TestCompareNoChanges(t *testing.T) {
+	tempDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "test.txt"), []byte("test"), 0600))
+
+	before, err := Take(tempDir)
+	require.NoError(t, err)
+	after, err := Take(tempDir)
+	require.NoError(t, err)
+
+	assert.Empty(t, Compare(before, after))
+	assert.Equal(t, "No changes\n", Summary(nil))
+}
+
+func TestSummary(t *testing.T) {
+	summary := Summary([]Change{
+		{Path: "a.txt", Kind: Added},
+		{Path: "b.txt", Kind: Modified},
+	})
+	assert.Contains(t, summary, "added    a.txt")
+	assert.Contains(t, summary, "modified b.txt")
+	assert.Contains(t, summary, "1 added, 1 modified, 0 removed")
+}
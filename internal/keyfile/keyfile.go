@@ -0,0 +1,165 @@
+package keyfile
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/kenchan0130/intunewin/internal/metadata"
+)
+
+// Format is how a key file is protected, chosen from its extension
+type Format string
+
+const (
+	// FormatPlain is an unencrypted JSON file
+	FormatPlain Format = "plain"
+	// FormatAge is encrypted with age (.age)
+	FormatAge Format = "age"
+	// FormatGPG is encrypted with GnuPG (.gpg, or ASCII armored .asc)
+	FormatGPG Format = "gpg"
+)
+
+var (
+	// ErrRecipientRequired is returned when an encrypted key file is requested without recipients
+	ErrRecipientRequired = errors.New("at least one recipient is required to encrypt the key file")
+	// ErrEncrypterNotFound is returned when the age or gpg command is not installed
+	ErrEncrypterNotFound = errors.New("encryption command not found")
+	// errMissingKeys is returned when the metadata has no encryption info to export
+	errMissingKeys = errors.New("no encryption keys to export")
+	// ErrInvalidKeyFile is returned when a key file is not the JSON written by Write, such as
+	// a file that is still encrypted
+	ErrInvalidKeyFile = errors.New("invalid key file")
+)
+
+// File is the encryption info of a package kept apart from the package itself
+type File struct {
+	// Name is the application name recorded in Detection.xml
+	Name string `json:"name"`
+	// SHA256 is the hex encoded digest of the package the keys belong to
+	SHA256 string `json:"sha256,omitempty"`
+	// EncryptionInfo holds the base64 encoded values of Detection.xml
+	EncryptionInfo *metadata.XMLEncryptionInfo `json:"encryptionInfo"`
+}
+
+// New returns the key file of the package described by appInfo with digest sha256
+func New(appInfo *metadata.ApplicationInfo, sha256 string) (*File, error) {
+	if appInfo.EncryptionInfo == nil {
+		return nil, fmt.Errorf("%w: encryption info is missing", errMissingKeys)
+	}
+	return &File{Name: appInfo.Name, SHA256: sha256, EncryptionInfo: appInfo.EncryptionInfo}, nil
+}
+
+// Read parses a plain key file from r. Encrypted key files are decrypted with age or gpg
+// first, such as by piping their output to r.
+func Read(r io.Reader) (*File, error) {
+	var f File
+	if err := json.NewDecoder(r).Decode(&f); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidKeyFile, err)
+	}
+	if f.EncryptionInfo == nil {
+		return nil, fmt.Errorf("%w: encryption info is missing", ErrInvalidKeyFile)
+	}
+	return &f, nil
+}
+
+// FormatOf returns the format selected by the extension of path
+func FormatOf(path string) Format {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".age":
+		return FormatAge
+	case ".gpg", ".asc":
+		return FormatGPG
+	default:
+		return FormatPlain
+	}
+}
+
+// runEncrypter runs name with args, feeding it input, and returns its standard output
+var runEncrypter = func(ctx context.Context, input []byte, name string, args ...string) ([]byte, error) {
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, name, args...) // #nosec G204 -- name is age or gpg
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if errors.Is(err, exec.ErrNotFound) {
+		return nil, fmt.Errorf("%w: install %s or use a .json key file", ErrEncrypterNotFound, name)
+	}
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%w: %s", err, msg)
+		}
+		return nil, err //nolint:wrapcheck // wrapped by the caller
+	}
+	return out, nil
+}
+
+// Write writes f as JSON to path, encrypted to recipients with age or gpg when the
+// extension of path asks for it. The file is only readable by its owner.
+func (f *File) Write(ctx context.Context, path string, recipients []string) error {
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal key file: %w", err)
+	}
+	data = append(data, '\n')
+
+	format := FormatOf(path)
+	if format != FormatPlain {
+		if len(recipients) == 0 {
+			return ErrRecipientRequired
+		}
+		if data, err = encrypt(ctx, format, path, data, recipients); err != nil {
+			return err
+		}
+	}
+
+	// A temporary file is private from the start, and renaming it over an existing key file
+	// does not keep the looser mode of that file
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".intunewin-keys-*")
+	if err != nil {
+		return fmt.Errorf("failed to write key file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+	if _, err := tmp.Write(data); err != nil {
+		return fmt.Errorf("failed to write key file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write key file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write key file: %w", err)
+	}
+	return nil
+}
+
+// encrypt encrypts data to recipients with the command of format
+func encrypt(ctx context.Context, format Format, path string, data []byte, recipients []string) ([]byte, error) {
+	var name string
+	var args []string
+	switch format {
+	case FormatAge:
+		name, args = "age", []string{"--encrypt"}
+	case FormatGPG:
+		name, args = "gpg", []string{"--batch", "--yes", "--encrypt"}
+		if strings.EqualFold(filepath.Ext(path), ".asc") {
+			args = append(args, "--armor")
+		}
+	}
+	for _, r := range recipients {
+		args = append(args, "--recipient", r)
+	}
+
+	out, err := runEncrypter(ctx, data, name, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt key file with %s: %w", name, err)
+	}
+	return out, nil
+}
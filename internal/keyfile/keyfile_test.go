@@ -0,0 +1,135 @@
+package keyfile
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kenchan0130/intunewin/internal/metadata"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeEncrypter replaces runEncrypter for the duration of a test and records its command line
+func fakeEncrypter(t *testing.T, commandLine *[]string) {
+	t.Helper()
+	orig := runEncrypter
+	runEncrypter = func(_ context.Context, input []byte, name string, args ...string) ([]byte, error) {
+		*commandLine = append([]string{name}, args...)
+		return append([]byte("encrypted:"), input...), nil
+	}
+	t.Cleanup(func() { runEncrypter = orig })
+}
+
+func testFile(t *testing.T) *File {
+	t.Helper()
+	appInfo := &metadata.ApplicationInfo{
+		Name:           "app",
+		EncryptionInfo: &metadata.XMLEncryptionInfo{EncryptionKey: "ZW5j", MacKey: "bWFj"},
+	}
+	f, err := New(appInfo, "abc123")
+	require.NoError(t, err)
+	return f
+}
+
+func TestNewWithoutKeys(t *testing.T) {
+	_, err := New(&metadata.ApplicationInfo{Name: "app"}, "")
+	assert.ErrorIs(t, err, errMissingKeys)
+}
+
+func TestFormatOf(t *testing.T) {
+	assert.Equal(t, FormatPlain, FormatOf("keys.json"))
+	assert.Equal(t, FormatAge, FormatOf("keys.json.age"))
+	assert.Equal(t, FormatGPG, FormatOf("keys.json.gpg"))
+	assert.Equal(t, FormatGPG, FormatOf("keys.JSON.ASC"))
+}
+
+func TestWritePlain(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.json")
+	require.NoError(t, testFile(t).Write(context.Background(), path, nil))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	var got File
+	require.NoError(t, json.Unmarshal(data, &got))
+	assert.Equal(t, "app", got.Name)
+	assert.Equal(t, "abc123", got.SHA256)
+	assert.Equal(t, "ZW5j", got.EncryptionInfo.EncryptionKey)
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	if filepath.Separator == '/' {
+		assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+	}
+}
+
+func TestWriteReplacesLooseMode(t *testing.T) {
+	if filepath.Separator != '/' {
+		t.Skip("file modes are not enforced on Windows")
+	}
+	path := filepath.Join(t.TempDir(), "keys.json")
+	require.NoError(t, os.WriteFile(path, []byte("old"), 0600))
+	require.NoError(t, os.Chmod(path, 0644))
+
+	require.NoError(t, testFile(t).Write(context.Background(), path, nil))
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+	entries, err := os.ReadDir(filepath.Dir(path))
+	require.NoError(t, err)
+	assert.Len(t, entries, 1, "the temporary file should be renamed")
+}
+
+func TestRead(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.json")
+	require.NoError(t, testFile(t).Write(context.Background(), path, nil))
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	got, err := Read(f)
+	require.NoError(t, err)
+	assert.Equal(t, testFile(t), got)
+
+	_, err = Read(strings.NewReader("-----BEGIN AGE ENCRYPTED FILE-----"))
+	assert.ErrorIs(t, err, ErrInvalidKeyFile)
+	_, err = Read(strings.NewReader(`{"name": "app"}`))
+	assert.ErrorIs(t, err, ErrInvalidKeyFile)
+}
+
+func TestWriteEncrypted(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		want []string
+	}{
+		{"age", "keys.json.age", []string{"age", "--encrypt", "--recipient", "r1", "--recipient", "r2"}},
+		{"gpg", "keys.json.gpg", []string{"gpg", "--batch", "--yes", "--encrypt", "--recipient", "r1", "--recipient", "r2"}},
+		{"armored gpg", "keys.json.asc", []string{"gpg", "--batch", "--yes", "--encrypt", "--armor", "--recipient", "r1", "--recipient", "r2"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var commandLine []string
+			fakeEncrypter(t, &commandLine)
+
+			path := filepath.Join(t.TempDir(), tt.file)
+			require.NoError(t, testFile(t).Write(context.Background(), path, []string{"r1", "r2"}))
+			assert.Equal(t, tt.want, commandLine)
+
+			data, err := os.ReadFile(path)
+			require.NoError(t, err)
+			assert.Contains(t, string(data), "encrypted:{")
+		})
+	}
+}
+
+func TestWriteEncryptedWithoutRecipients(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.json.age")
+	assert.ErrorIs(t, testFile(t).Write(context.Background(), path, nil), ErrRecipientRequired)
+	assert.NoFileExists(t, path)
+}
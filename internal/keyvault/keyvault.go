@@ -0,0 +1,196 @@
+package keyvault
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/kenchan0130/intunewin/internal/auth"
+	"github.com/kenchan0130/intunewin/internal/metadata"
+)
+
+// APIVersion is the Key Vault REST API version the client uses
+const APIVersion = "7.4"
+
+// secretPrefix starts the name of every secret holding the keys of a package
+const secretPrefix = "intunewin-"
+
+var (
+	// ErrSecretNotFound is returned when the vault has no secret of the requested name
+	ErrSecretNotFound = errors.New("secret not found in key vault")
+	// ErrInvalidVaultURL is returned when the vault URL is not an https URL
+	ErrInvalidVaultURL = errors.New("invalid key vault URL")
+)
+
+// Error is an error response of Key Vault
+type Error struct {
+	StatusCode int
+	// Code is the Key Vault error code such as Forbidden or SecretNotFound
+	Code    string
+	Message string
+}
+
+func (e *Error) Error() string {
+	msg := fmt.Sprintf("key vault request failed with status %d", e.StatusCode)
+	if e.Code != "" {
+		msg += ": " + e.Code
+	}
+	if e.Message != "" {
+		msg += ": " + e.Message
+	}
+	return msg
+}
+
+// Unwrap makes a missing secret match ErrSecretNotFound
+func (e *Error) Unwrap() error {
+	if e.StatusCode == http.StatusNotFound {
+		return ErrSecretNotFound
+	}
+	return nil
+}
+
+// errorResponse is the body of a Key Vault error response
+type errorResponse struct {
+	Error struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// secretBundle is the secret sent to and returned by Key Vault
+type secretBundle struct {
+	Value       string            `json:"value"`
+	ContentType string            `json:"contentType,omitempty"`
+	Tags        map[string]string `json:"tags,omitempty"`
+}
+
+// Client reads and writes the secrets of one Azure Key Vault with tokens of a token source
+type Client struct {
+	vaultURL string
+	tokens   auth.TokenSource
+	opts     *Options
+}
+
+// NewClient returns a client of the vault at vaultURL, such as https://myvault.vault.azure.net,
+// that signs requests with tokens for the Key Vault resource
+func NewClient(vaultURL string, tokens auth.TokenSource, opts ...Option) (*Client, error) {
+	u, err := url.Parse(vaultURL)
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return nil, fmt.Errorf("%w: %q, expected https://<name>.vault.azure.net", ErrInvalidVaultURL, vaultURL)
+	}
+	return &Client{vaultURL: "https://" + u.Host, tokens: tokens, opts: newOptions(opts)}, nil
+}
+
+// SecretName returns the name of the secret holding the keys of the package with the hex
+// encoded SHA256 digest
+func SecretName(digest string) string {
+	return secretPrefix + strings.ToLower(digest)
+}
+
+// PackageDigest returns the hex encoded SHA256 digest of the package at path, which keys its secret
+func PackageDigest(path string) (string, error) {
+	f, err := os.Open(path) // #nosec G304 -- path is chosen by the caller
+	if err != nil {
+		return "", fmt.Errorf("failed to open package: %w", err)
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("failed to read package: %w", err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// StoreKeys stores info as the secret of the package with digest, replacing any previous version
+func (c *Client) StoreKeys(ctx context.Context, digest string, info *metadata.XMLEncryptionInfo) error {
+	data, err := json.Marshal(info)
+	if err != nil {
+		return fmt.Errorf("failed to marshal encryption info: %w", err)
+	}
+	secret := &secretBundle{
+		Value:       string(data),
+		ContentType: "application/json",
+		Tags:        map[string]string{"sha256": strings.ToLower(digest)},
+	}
+	return c.do(ctx, http.MethodPut, SecretName(digest), secret, nil)
+}
+
+// LoadKeys returns the encryption info stored for the package with digest
+func (c *Client) LoadKeys(ctx context.Context, digest string) (*metadata.XMLEncryptionInfo, error) {
+	var secret secretBundle
+	if err := c.do(ctx, http.MethodGet, SecretName(digest), nil, &secret); err != nil {
+		return nil, err
+	}
+	var info metadata.XMLEncryptionInfo
+	if err := json.Unmarshal([]byte(secret.Value), &info); err != nil {
+		return nil, fmt.Errorf("failed to parse secret %s: %w", SecretName(digest), err)
+	}
+	return &info, nil
+}
+
+// do sends a request with the JSON encoding of body for the secret name and decodes the
+// JSON response into out when it is not nil
+func (c *Client) do(ctx context.Context, method, name string, body, out any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to marshal key vault request: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	token, err := c.tokens.Token(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get access token: %w", err)
+	}
+
+	endpoint := c.vaultURL + "/secrets/" + url.PathEscape(name) + "?api-version=" + APIVersion
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, reader)
+	if err != nil {
+		return fmt.Errorf("failed to create key vault request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token.AccessToken)
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	c.opts.Logger.Debug("sending key vault request", "method", method, "secret", name)
+	resp, err := c.opts.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send key vault request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return newError(resp)
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode key vault response: %w", err)
+	}
+	return nil
+}
+
+// newError converts an error response to *Error
+func newError(resp *http.Response) error {
+	e := &Error{StatusCode: resp.StatusCode}
+	var body errorResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&body); err == nil {
+		e.Code = body.Error.Code
+		e.Message = body.Error.Message
+	}
+	return e
+}
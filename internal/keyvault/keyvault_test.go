@@ -0,0 +1,126 @@
+package keyvault
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kenchan0130/intunewin/internal/auth"
+	"github.com/kenchan0130/intunewin/internal/metadata"
+	"github.com/kenchan0130/intunewin/internal/pack"
+	"github.com/kenchan0130/intunewin/internal/unpack"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// staticTokens always returns the same access token
+type staticTokens struct{}
+
+func (staticTokens) Token(context.Context) (*auth.Token, error) {
+	return &auth.Token{AccessToken: "token", ExpiresAt: time.Now().Add(time.Hour)}, nil
+}
+
+// fakeVault serves secrets from memory like the Key Vault secrets API
+func fakeVault(t *testing.T) (*Client, map[string]secretBundle) {
+	t.Helper()
+	secrets := map[string]secretBundle{}
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+		assert.Equal(t, APIVersion, r.URL.Query().Get("api-version"))
+		name := r.URL.Path[len("/secrets/"):]
+		switch r.Method {
+		case http.MethodPut:
+			var secret secretBundle
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&secret))
+			secrets[name] = secret
+			_ = json.NewEncoder(w).Encode(secret)
+		case http.MethodGet:
+			secret, ok := secrets[name]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				_, _ = w.Write([]byte(`{"error":{"code":"SecretNotFound","message":"A secret with (name/id) ` + name + ` was not found in this key vault."}}`))
+				return
+			}
+			_ = json.NewEncoder(w).Encode(secret)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	client, err := NewClient(server.URL, staticTokens{}, WithHTTPClient(server.Client()))
+	require.NoError(t, err)
+	return client, secrets
+}
+
+func TestStoreAndLoadKeys(t *testing.T) {
+	client, secrets := fakeVault(t)
+	info := &metadata.XMLEncryptionInfo{EncryptionKey: "ZW5j", MacKey: "bWFj", InitializationVector: "aXY="}
+
+	require.NoError(t, client.StoreKeys(context.Background(), "ABC123", info))
+	require.Contains(t, secrets, "intunewin-abc123")
+	assert.Equal(t, "application/json", secrets["intunewin-abc123"].ContentType)
+	assert.Equal(t, "abc123", secrets["intunewin-abc123"].Tags["sha256"])
+
+	loaded, err := client.LoadKeys(context.Background(), "abc123")
+	require.NoError(t, err)
+	assert.Equal(t, info, loaded)
+}
+
+func TestKeysOnlyInVault(t *testing.T) {
+	client, _ := fakeVault(t)
+	sourceDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(sourceDir, "setup.exe"), []byte("setup"), 0600))
+	packageFile := filepath.Join(t.TempDir(), "app.intunewin")
+
+	// As pack --key-vault does
+	var summary pack.Summary
+	require.NoError(t, pack.Pack(sourceDir, packageFile, pack.WithSummary(&summary), pack.WithRetainKeys(), pack.WithOmitEncryptionInfo()))
+	require.NoError(t, client.StoreKeys(context.Background(), summary.SHA256, summary.ApplicationInfo.EncryptionInfo))
+
+	appInfo, err := unpack.ReadApplicationInfo(packageFile)
+	require.NoError(t, err)
+	assert.Nil(t, appInfo.EncryptionInfo, "the packaged Detection.xml should not hold the keys")
+
+	// As unpack --key-vault does
+	digest, err := PackageDigest(packageFile)
+	require.NoError(t, err)
+	assert.Equal(t, summary.SHA256, digest)
+	info, err := client.LoadKeys(context.Background(), digest)
+	require.NoError(t, err)
+	extractDir := t.TempDir()
+	require.NoError(t, unpack.Unpack(packageFile, extractDir, unpack.WithEncryptionInfo(info)))
+	assert.FileExists(t, filepath.Join(extractDir, "setup.exe"))
+}
+
+func TestLoadKeysNotFound(t *testing.T) {
+	client, _ := fakeVault(t)
+
+	_, err := client.LoadKeys(context.Background(), "missing")
+	assert.ErrorIs(t, err, ErrSecretNotFound)
+	var vaultErr *Error
+	require.ErrorAs(t, err, &vaultErr)
+	assert.Equal(t, "SecretNotFound", vaultErr.Code)
+}
+
+func TestPackageDigest(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.intunewin")
+	require.NoError(t, os.WriteFile(path, []byte("package"), 0600))
+
+	digest, err := PackageDigest(path)
+	require.NoError(t, err)
+	sum := sha256.Sum256([]byte("package"))
+	assert.Equal(t, hex.EncodeToString(sum[:]), digest)
+}
+
+func TestNewClientInvalidURL(t *testing.T) {
+	for _, vaultURL := range []string{"", "http://myvault.vault.azure.net", "myvault"} {
+		_, err := NewClient(vaultURL, staticTokens{})
+		assert.ErrorIs(t, err, ErrInvalidVaultURL, vaultURL)
+	}
+}
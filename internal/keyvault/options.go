@@ -0,0 +1,46 @@
+package keyvault
+
+import (
+	"log/slog"
+	"net/http"
+)
+
+// Options holds settings for Client
+type Options struct {
+	// Logger receives diagnostic messages
+	Logger *slog.Logger
+	// HTTPClient sends the Key Vault requests, http.DefaultClient when nil
+	HTTPClient *http.Client
+}
+
+// Option configures Options
+type Option func(*Options)
+
+// WithLogger sends diagnostic messages to logger
+func WithLogger(logger *slog.Logger) Option {
+	return func(o *Options) {
+		o.Logger = logger
+	}
+}
+
+// WithHTTPClient sends Key Vault requests with client
+func WithHTTPClient(client *http.Client) Option {
+	return func(o *Options) {
+		o.HTTPClient = client
+	}
+}
+
+// newOptions applies opts over the defaults
+func newOptions(opts []Option) *Options {
+	o := &Options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.Logger == nil {
+		o.Logger = slog.New(slog.DiscardHandler)
+	}
+	if o.HTTPClient == nil {
+		o.HTTPClient = http.DefaultClient
+	}
+	return o
+}
@@ -0,0 +1,189 @@
+package manifest
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Graph types of detection rules
+const (
+	productCodeDetectionODataType = "#microsoft.graph.win32LobAppProductCodeDetection"
+	fileSystemDetectionODataType  = "#microsoft.graph.win32LobAppFileSystemDetection"
+	registryDetectionODataType    = "#microsoft.graph.win32LobAppRegistryDetection"
+)
+
+// Operators comparing a detected value
+const (
+	OperatorNotConfigured      = "notConfigured"
+	OperatorEqual              = "equal"
+	OperatorNotEqual           = "notEqual"
+	OperatorGreaterThan        = "greaterThan"
+	OperatorGreaterThanOrEqual = "greaterThanOrEqual"
+	OperatorLessThan           = "lessThan"
+	OperatorLessThanOrEqual    = "lessThanOrEqual"
+)
+
+// operators lists the valid operators
+var operators = []string{
+	OperatorNotConfigured, OperatorEqual, OperatorNotEqual, OperatorGreaterThan,
+	OperatorGreaterThanOrEqual, OperatorLessThan, OperatorLessThanOrEqual,
+}
+
+// fileDetectionTypes and registryDetectionTypes list the valid detectionType values
+var (
+	fileDetectionTypes     = []string{"exists", "doesNotExist", "modifiedDate", "createdDate", "version", "sizeInMB"}
+	registryDetectionTypes = []string{"exists", "doesNotExist", "string", "integer", "version"}
+)
+
+// DetectionRule is an entry of the detectionRules array of a win32LobApp
+type DetectionRule interface {
+	detectionRule()
+}
+
+// ProductCodeDetection detects an MSI by its product code and optionally its version
+type ProductCodeDetection struct {
+	ODataType              string  `json:"@odata.type"`
+	ProductCode            string  `json:"productCode"`
+	ProductVersionOperator string  `json:"productVersionOperator"`
+	ProductVersion         *string `json:"productVersion"`
+}
+
+// FileSystemDetection detects a file or folder
+type FileSystemDetection struct {
+	ODataType            string `json:"@odata.type" yaml:"-"`
+	Path                 string `json:"path" yaml:"path"`
+	FileOrFolderName     string `json:"fileOrFolderName" yaml:"fileOrFolderName"`
+	Check32BitOn64System bool   `json:"check32BitOn64System" yaml:"check32BitOn64System"`
+	DetectionType        string `json:"detectionType" yaml:"detectionType"`
+	Operator             string `json:"operator" yaml:"operator"`
+	DetectionValue       string `json:"detectionValue,omitempty" yaml:"detectionValue"`
+}
+
+// RegistryDetection detects a registry key or value
+type RegistryDetection struct {
+	ODataType            string `json:"@odata.type" yaml:"-"`
+	KeyPath              string `json:"keyPath" yaml:"keyPath"`
+	ValueName            string `json:"valueName,omitempty" yaml:"valueName"`
+	Check32BitOn64System bool   `json:"check32BitOn64System" yaml:"check32BitOn64System"`
+	DetectionType        string `json:"detectionType" yaml:"detectionType"`
+	Operator             string `json:"operator" yaml:"operator"`
+	DetectionValue       string `json:"detectionValue,omitempty" yaml:"detectionValue"`
+}
+
+func (ProductCodeDetection) detectionRule() {}
+func (FileSystemDetection) detectionRule()  {}
+func (RegistryDetection) detectionRule()    {}
+
+// NewProductCodeDetection detects the MSI with productCode, at least at version when it
+// is not empty
+func NewProductCodeDetection(productCode, version string) ProductCodeDetection {
+	rule := ProductCodeDetection{
+		ODataType:              productCodeDetectionODataType,
+		ProductCode:            productCode,
+		ProductVersionOperator: OperatorNotConfigured,
+	}
+	if version != "" {
+		rule.ProductVersionOperator = OperatorGreaterThanOrEqual
+		rule.ProductVersion = &version
+	}
+	return rule
+}
+
+// NewFileDetection detects the file name in folder, at least at version when it is not empty
+func NewFileDetection(folder, name, version string) FileSystemDetection {
+	rule := FileSystemDetection{
+		ODataType:        fileSystemDetectionODataType,
+		Path:             folder,
+		FileOrFolderName: name,
+		DetectionType:    "exists",
+		Operator:         OperatorNotConfigured,
+	}
+	if version != "" {
+		rule.DetectionType = "version"
+		rule.Operator = OperatorGreaterThanOrEqual
+		rule.DetectionValue = version
+	}
+	return rule
+}
+
+// DetectionRules are file and registry detection rules as written in a configuration file
+type DetectionRules struct {
+	Files    []FileSystemDetection `yaml:"files"`
+	Registry []RegistryDetection   `yaml:"registry"`
+}
+
+// LoadDetectionRules reads file and registry rules from a YAML or JSON file with
+// top-level files and registry lists. The operator defaults to notConfigured.
+func LoadDetectionRules(path string) ([]DetectionRule, error) {
+	data, err := os.ReadFile(path) // #nosec G304 -- path is provided by the user
+	if err != nil {
+		return nil, fmt.Errorf("failed to read detection rules file: %w", err)
+	}
+
+	var file DetectionRules
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse detection rules file: %w", err)
+	}
+	return file.Rules()
+}
+
+// Rules validates the rules and returns them as detection rules of a win32LobApp.
+// The operator defaults to notConfigured.
+func (d DetectionRules) Rules() ([]DetectionRule, error) {
+	rules := make([]DetectionRule, 0, len(d.Files)+len(d.Registry))
+	for _, rule := range d.Files {
+		rule.ODataType = fileSystemDetectionODataType
+		if err := checkDetection(rule.DetectionType, fileDetectionTypes, &rule.Operator, rule.DetectionValue); err != nil {
+			return nil, fmt.Errorf("invalid file rule for %s: %w", rule.FileOrFolderName, err)
+		}
+		if rule.Path == "" || rule.FileOrFolderName == "" {
+			return nil, fmt.Errorf("invalid file rule: path and fileOrFolderName are required")
+		}
+		rules = append(rules, rule)
+	}
+	for _, rule := range d.Registry {
+		rule.ODataType = registryDetectionODataType
+		if err := checkDetection(rule.DetectionType, registryDetectionTypes, &rule.Operator, rule.DetectionValue); err != nil {
+			return nil, fmt.Errorf("invalid registry rule for %s: %w", rule.KeyPath, err)
+		}
+		if rule.KeyPath == "" {
+			return nil, fmt.Errorf("invalid registry rule: keyPath is required")
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// checkDetection validates a detection type and operator, defaulting the operator
+func checkDetection(detectionType string, detectionTypes []string, operator *string, value string) error {
+	if !slices.Contains(detectionTypes, detectionType) {
+		return fmt.Errorf("unknown detectionType %q (expected one of %v)", detectionType, detectionTypes)
+	}
+	if *operator == "" {
+		*operator = OperatorNotConfigured
+	}
+	if !slices.Contains(operators, *operator) {
+		return fmt.Errorf("unknown operator %q (expected one of %v)", *operator, operators)
+	}
+	compares := detectionType != "exists" && detectionType != "doesNotExist"
+	if compares && (*operator == OperatorNotConfigured || value == "") {
+		return fmt.Errorf("detectionType %s needs an operator and a detectionValue", detectionType)
+	}
+	return nil
+}
+
+// DetectionRulesToJSON converts rules to an indented JSON array
+func DetectionRulesToJSON(rules []DetectionRule) ([]byte, error) {
+	if rules == nil {
+		rules = []DetectionRule{}
+	}
+	data, err := json.MarshalIndent(rules, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal detection rules to JSON: %w", err)
+	}
+	return data, nil
+}
@@ -0,0 +1,114 @@
+package manifest
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetectionRulesToJSON(t *testing.T) {
+	rules := []DetectionRule{
+		NewProductCodeDetection("{GUID}", ""),
+		NewProductCodeDetection("{GUID}", "1.2.3"),
+		NewFileDetection(`%ProgramFiles%\My App`, "app.exe", "1.2.3.4"),
+		NewFileDetection(`%ProgramFiles%\My App`, "app.exe", ""),
+	}
+
+	data, err := DetectionRulesToJSON(rules)
+	require.NoError(t, err)
+
+	var got []map[string]any
+	require.NoError(t, json.Unmarshal(data, &got))
+	require.Len(t, got, 4)
+	assert.Equal(t, map[string]any{
+		"@odata.type":            "#microsoft.graph.win32LobAppProductCodeDetection",
+		"productCode":            "{GUID}",
+		"productVersionOperator": "notConfigured",
+		"productVersion":         nil,
+	}, got[0])
+	assert.Equal(t, "greaterThanOrEqual", got[1]["productVersionOperator"])
+	assert.Equal(t, "1.2.3", got[1]["productVersion"])
+	assert.Equal(t, map[string]any{
+		"@odata.type":          "#microsoft.graph.win32LobAppFileSystemDetection",
+		"path":                 `%ProgramFiles%\My App`,
+		"fileOrFolderName":     "app.exe",
+		"check32BitOn64System": false,
+		"detectionType":        "version",
+		"operator":             "greaterThanOrEqual",
+		"detectionValue":       "1.2.3.4",
+	}, got[2])
+	assert.Equal(t, "exists", got[3]["detectionType"])
+	assert.NotContains(t, got[3], "detectionValue")
+
+	empty, err := DetectionRulesToJSON(nil)
+	require.NoError(t, err)
+	assert.Equal(t, "[]", string(empty))
+}
+
+func TestLoadDetectionRules(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+files:
+  - path: '%ProgramFiles%\My App'
+    fileOrFolderName: app.exe
+    detectionType: exists
+registry:
+  - keyPath: 'HKEY_LOCAL_MACHINE\SOFTWARE\Contoso\My App'
+    valueName: Version
+    detectionType: version
+    operator: greaterThanOrEqual
+    detectionValue: 1.2.0
+    check32BitOn64System: true
+`), 0600))
+
+	rules, err := LoadDetectionRules(path)
+	require.NoError(t, err)
+	assert.Equal(t, []DetectionRule{
+		FileSystemDetection{
+			ODataType:        fileSystemDetectionODataType,
+			Path:             `%ProgramFiles%\My App`,
+			FileOrFolderName: "app.exe",
+			DetectionType:    "exists",
+			Operator:         OperatorNotConfigured,
+		},
+		RegistryDetection{
+			ODataType:            registryDetectionODataType,
+			KeyPath:              `HKEY_LOCAL_MACHINE\SOFTWARE\Contoso\My App`,
+			ValueName:            "Version",
+			Check32BitOn64System: true,
+			DetectionType:        "version",
+			Operator:             OperatorGreaterThanOrEqual,
+			DetectionValue:       "1.2.0",
+		},
+	}, rules)
+}
+
+func TestLoadDetectionRulesInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{name: "unknown detection type", content: "registry:\n  - keyPath: HKLM\\X\n    detectionType: bogus\n"},
+		{name: "unknown operator", content: "registry:\n  - keyPath: HKLM\\X\n    detectionType: string\n    operator: like\n    detectionValue: a\n"},
+		{name: "missing value", content: "registry:\n  - keyPath: HKLM\\X\n    detectionType: string\n    operator: equal\n"},
+		{name: "missing key path", content: "registry:\n  - detectionType: exists\n"},
+		{name: "missing file name", content: "files:\n  - path: C:\\\n    detectionType: exists\n"},
+		{name: "registry type for a file", content: "files:\n  - path: C:\\\n    fileOrFolderName: a\n    detectionType: string\n"},
+		{name: "not YAML", content: "files: [\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "rules.yaml")
+			require.NoError(t, os.WriteFile(path, []byte(tt.content), 0600))
+			_, err := LoadDetectionRules(path)
+			assert.Error(t, err)
+		})
+	}
+
+	_, err := LoadDetectionRules(filepath.Join(t.TempDir(), "missing.yaml"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,40 @@
+package manifest
+
+import (
+	"fmt"
+	"math"
+)
+
+// DefaultFootprintMultiplier accounts for the downloaded encrypted file, the decrypted
+// content zip and the extracted files that exist on the device at the same time
+const DefaultFootprintMultiplier = 3.0
+
+const bytesPerMB = 1024 * 1024
+
+// Footprint is the estimated disk space an app needs on the device during installation
+type Footprint struct {
+	// Multiplier is the factor applied to the unencrypted content size
+	Multiplier float64 `json:"multiplier"`
+	// EstimatedBytes is the estimated disk usage in bytes
+	EstimatedBytes int64 `json:"estimatedBytes"`
+	// MinimumFreeDiskSpaceInMB is EstimatedBytes rounded up to whole megabytes,
+	// ready for the minimumFreeDiskSpaceInMB requirement of a Win32 app
+	MinimumFreeDiskSpaceInMB int64 `json:"minimumFreeDiskSpaceInMB"`
+}
+
+// EstimateFootprint estimates the install footprint from the unencrypted content size
+func EstimateFootprint(unencryptedContentSize int64, multiplier float64) (*Footprint, error) {
+	if multiplier < 1 || math.IsInf(multiplier, 0) || math.IsNaN(multiplier) {
+		return nil, fmt.Errorf("footprint multiplier must be at least 1, got %v", multiplier)
+	}
+	if unencryptedContentSize < 0 {
+		return nil, fmt.Errorf("invalid unencrypted content size %d", unencryptedContentSize)
+	}
+
+	estimated := int64(math.Ceil(float64(unencryptedContentSize) * multiplier))
+	return &Footprint{
+		Multiplier:               multiplier,
+		EstimatedBytes:           estimated,
+		MinimumFreeDiskSpaceInMB: (estimated + bytesPerMB - 1) / bytesPerMB,
+	}, nil
+}
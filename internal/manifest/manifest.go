@@ -0,0 +1,44 @@
+package manifest
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/kenchan0130/intunewin/internal/metadata"
+)
+
+// Manifest describes a packaged app for upload tooling such as Graph scripts
+type Manifest struct {
+	Name                   string       `json:"name"`
+	Description            string       `json:"description,omitempty"`
+	SetupFile              string       `json:"setupFile"`
+	FileName               string       `json:"fileName"`
+	UnencryptedContentSize int64        `json:"unencryptedContentSize"`
+	ToolVersion            string       `json:"toolVersion"`
+	ReturnCodes            []ReturnCode `json:"returnCodes"`
+	// InstallFootprint is the estimated disk space needed on the device
+	InstallFootprint *Footprint `json:"installFootprint"`
+}
+
+// New creates a Manifest from package metadata
+func New(appInfo *metadata.ApplicationInfo, returnCodes []ReturnCode, footprint *Footprint) *Manifest {
+	return &Manifest{
+		Name:                   appInfo.Name,
+		Description:            appInfo.Description,
+		SetupFile:              appInfo.SetupFile,
+		FileName:               appInfo.FileName,
+		UnencryptedContentSize: appInfo.UnencryptedContentSize,
+		ToolVersion:            appInfo.ToolVersion,
+		ReturnCodes:            returnCodes,
+		InstallFootprint:       footprint,
+	}
+}
+
+// ToJSON converts the manifest to indented JSON
+func (m *Manifest) ToJSON() ([]byte, error) {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal manifest to JSON: %w", err)
+	}
+	return data, nil
+}
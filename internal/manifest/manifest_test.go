@@ -0,0 +1,158 @@
+package manifest
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kenchan0130/intunewin/internal/metadata"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseReturnCode(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    ReturnCode
+		wantErr bool
+	}{
+		{input: "3010=softReboot", want: newReturnCode(3010, SoftReboot)},
+		{input: "5 = RETRY", want: newReturnCode(5, Retry)},
+		{input: "-1=failed", want: newReturnCode(-1, Failed)},
+		{input: "3010", wantErr: true},
+		{input: "abc=success", wantErr: true},
+		{input: "1=unknown", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			rc, err := ParseReturnCode(tt.input)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, rc)
+		})
+	}
+}
+
+func TestMergeReturnCodes(t *testing.T) {
+	merged := MergeReturnCodes(DefaultReturnCodes(), []ReturnCode{
+		newReturnCode(3010, Success),
+		newReturnCode(5, Retry),
+	})
+
+	assert.Equal(t, []ReturnCode{
+		newReturnCode(0, Success),
+		newReturnCode(5, Retry),
+		newReturnCode(1618, Retry),
+		newReturnCode(1641, HardReboot),
+		newReturnCode(1707, Success),
+		newReturnCode(3010, Success),
+	}, merged)
+}
+
+func TestLoadReturnCodes(t *testing.T) {
+	tempDir := t.TempDir()
+
+	yamlFile := filepath.Join(tempDir, "codes.yaml")
+	require.NoError(t, os.WriteFile(yamlFile, []byte("returnCodes:\n  - returnCode: 3010\n    type: success\n"), 0600))
+	codes, err := LoadReturnCodes(yamlFile)
+	require.NoError(t, err)
+	assert.Equal(t, []ReturnCode{newReturnCode(3010, Success)}, codes)
+
+	jsonFile := filepath.Join(tempDir, "codes.json")
+	require.NoError(t, os.WriteFile(jsonFile, []byte(`{"returnCodes": [{"returnCode": 1, "type": "hardreboot"}]}`), 0600))
+	codes, err = LoadReturnCodes(jsonFile)
+	require.NoError(t, err)
+	assert.Equal(t, []ReturnCode{newReturnCode(1, HardReboot)}, codes)
+
+	invalidFile := filepath.Join(tempDir, "invalid.yaml")
+	require.NoError(t, os.WriteFile(invalidFile, []byte("returnCodes:\n  - returnCode: 1\n    type: reboot\n"), 0600))
+	_, err = LoadReturnCodes(invalidFile)
+	assert.Error(t, err)
+}
+
+func TestManifestToJSON(t *testing.T) {
+	appInfo := &metadata.ApplicationInfo{
+		Name:                   "MyApp",
+		SetupFile:              "setup.exe",
+		FileName:               "IntunePackage.intunewin",
+		UnencryptedContentSize: 1000,
+		ToolVersion:            "1.4.0.0",
+	}
+
+	footprint, err := EstimateFootprint(appInfo.UnencryptedContentSize, DefaultFootprintMultiplier)
+	require.NoError(t, err)
+
+	data, err := New(appInfo, DefaultReturnCodes(), footprint).ToJSON()
+	require.NoError(t, err)
+
+	var decoded map[string]any
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, "MyApp", decoded["name"])
+	assert.Equal(t, "setup.exe", decoded["setupFile"])
+	assert.Equal(t, map[string]any{
+		"multiplier":               float64(3),
+		"estimatedBytes":           float64(3000),
+		"minimumFreeDiskSpaceInMB": float64(1),
+	}, decoded["installFootprint"])
+
+	returnCodes, ok := decoded["returnCodes"].([]any)
+	require.True(t, ok)
+	require.Len(t, returnCodes, 5)
+	assert.Equal(t, map[string]any{
+		"@odata.type": "#microsoft.graph.win32LobAppReturnCode",
+		"returnCode":  float64(0),
+		"type":        "success",
+	}, returnCodes[0])
+}
+
+func TestEstimateFootprint(t *testing.T) {
+	tests := []struct {
+		name       string
+		size       int64
+		multiplier float64
+		want       *Footprint
+		wantErr    bool
+	}{
+		{
+			name:       "Rounds up to whole megabytes",
+			size:       100 * bytesPerMB,
+			multiplier: 2.5,
+			want:       &Footprint{Multiplier: 2.5, EstimatedBytes: 250 * bytesPerMB, MinimumFreeDiskSpaceInMB: 250},
+		},
+		{
+			name:       "Partial megabyte",
+			size:       bytesPerMB + 1,
+			multiplier: 1,
+			want:       &Footprint{Multiplier: 1, EstimatedBytes: bytesPerMB + 1, MinimumFreeDiskSpaceInMB: 2},
+		},
+		{
+			name:       "Empty content",
+			size:       0,
+			multiplier: DefaultFootprintMultiplier,
+			want:       &Footprint{Multiplier: DefaultFootprintMultiplier},
+		},
+		{
+			name:       "Multiplier below one",
+			size:       1000,
+			multiplier: 0.5,
+			wantErr:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			footprint, err := EstimateFootprint(tt.size, tt.multiplier)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, footprint)
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package manifest
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ReturnCodeType is the action Intune takes when an installer exits with a return code
+type ReturnCodeType string
+
+const (
+	// Failed marks the installation as failed
+	Failed ReturnCodeType = "failed"
+	// Success marks the installation as successful
+	Success ReturnCodeType = "success"
+	// SoftReboot marks the installation as successful and asks the user to reboot
+	SoftReboot ReturnCodeType = "softReboot"
+	// HardReboot marks the installation as successful and reboots the device
+	HardReboot ReturnCodeType = "hardReboot"
+	// Retry retries the installation later
+	Retry ReturnCodeType = "retry"
+)
+
+// returnCodeODataType is the Graph type of a return code entry
+const returnCodeODataType = "#microsoft.graph.win32LobAppReturnCode"
+
+// ReturnCode maps an installer exit code to a ReturnCodeType
+type ReturnCode struct {
+	ODataType  string         `json:"@odata.type" yaml:"-"`
+	ReturnCode int            `json:"returnCode" yaml:"returnCode"`
+	Type       ReturnCodeType `json:"type" yaml:"type"`
+}
+
+// returnCodesFile is the structure of a return code configuration file
+type returnCodesFile struct {
+	ReturnCodes []ReturnCode `yaml:"returnCodes"`
+}
+
+// DefaultReturnCodes returns the return codes Intune assigns to new Win32 apps
+func DefaultReturnCodes() []ReturnCode {
+	return []ReturnCode{
+		newReturnCode(0, Success),
+		newReturnCode(1707, Success),
+		newReturnCode(3010, SoftReboot),
+		newReturnCode(1641, HardReboot),
+		newReturnCode(1618, Retry),
+	}
+}
+
+// newReturnCode creates a ReturnCode with its Graph type set
+func newReturnCode(code int, t ReturnCodeType) ReturnCode {
+	return ReturnCode{ODataType: returnCodeODataType, ReturnCode: code, Type: t}
+}
+
+// ParseReturnCodeType parses a return code type, ignoring case
+func ParseReturnCodeType(s string) (ReturnCodeType, error) {
+	for _, t := range []ReturnCodeType{Failed, Success, SoftReboot, HardReboot, Retry} {
+		if strings.EqualFold(s, string(t)) {
+			return t, nil
+		}
+	}
+	return "", fmt.Errorf("unknown return code type %q (expected failed, success, softReboot, hardReboot or retry)", s)
+}
+
+// ParseReturnCode parses a return code in the form "3010=softReboot"
+func ParseReturnCode(s string) (ReturnCode, error) {
+	codePart, typePart, ok := strings.Cut(s, "=")
+	if !ok {
+		return ReturnCode{}, fmt.Errorf("invalid return code %q (expected <code>=<type>)", s)
+	}
+	code, err := strconv.Atoi(strings.TrimSpace(codePart))
+	if err != nil {
+		return ReturnCode{}, fmt.Errorf("invalid return code %q: %w", s, err)
+	}
+	t, err := ParseReturnCodeType(strings.TrimSpace(typePart))
+	if err != nil {
+		return ReturnCode{}, err
+	}
+	return newReturnCode(code, t), nil
+}
+
+// LoadReturnCodes reads return codes from a YAML or JSON file with a top-level returnCodes list
+func LoadReturnCodes(path string) ([]ReturnCode, error) {
+	data, err := os.ReadFile(path) // #nosec G304 -- path is provided by the user
+	if err != nil {
+		return nil, fmt.Errorf("failed to read return codes file: %w", err)
+	}
+
+	var file returnCodesFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse return codes file: %w", err)
+	}
+
+	codes := make([]ReturnCode, 0, len(file.ReturnCodes))
+	for _, rc := range file.ReturnCodes {
+		t, err := ParseReturnCodeType(string(rc.Type))
+		if err != nil {
+			return nil, fmt.Errorf("invalid return code %d: %w", rc.ReturnCode, err)
+		}
+		codes = append(codes, newReturnCode(rc.ReturnCode, t))
+	}
+	return codes, nil
+}
+
+// MergeReturnCodes returns base with overrides applied; an override replaces the entry with the same code.
+// The result is sorted by code.
+func MergeReturnCodes(base, overrides []ReturnCode) []ReturnCode {
+	byCode := make(map[int]ReturnCode, len(base)+len(overrides))
+	for _, rc := range base {
+		byCode[rc.ReturnCode] = rc
+	}
+	for _, rc := range overrides {
+		byCode[rc.ReturnCode] = rc
+	}
+
+	merged := make([]ReturnCode, 0, len(byCode))
+	for _, rc := range byCode {
+		merged = append(merged, rc)
+	}
+	sort.Slice(merged, func(i, j int) bool {
+		return merged[i].ReturnCode < merged[j].ReturnCode
+	})
+	return merged
+}
@@ -0,0 +1,134 @@
+package manifest
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/kenchan0130/intunewin/internal/metadata"
+)
+
+// Graph types of a Win32 app body
+const (
+	win32LobAppODataType       = "#microsoft.graph.win32LobApp"
+	installExperienceODataType = "#microsoft.graph.win32LobAppInstallExperience"
+	msiInformationODataType    = "#microsoft.graph.win32LobAppMsiInformation"
+)
+
+// Requirements of a new Win32 app
+const (
+	// DefaultMinimumWindowsRelease is the oldest Windows 10 release Intune offers
+	DefaultMinimumWindowsRelease = "1607"
+	// DefaultApplicableArchitectures lets the app install on 32 and 64-bit Windows
+	DefaultApplicableArchitectures = "x86,x64"
+)
+
+// Win32LobApp is the request body that creates a Win32 app with Graph
+type Win32LobApp struct {
+	ODataType                      string                     `json:"@odata.type"`
+	DisplayName                    string                     `json:"displayName"`
+	Description                    string                     `json:"description"`
+	Publisher                      string                     `json:"publisher"`
+	FileName                       string                     `json:"fileName"`
+	SetupFilePath                  string                     `json:"setupFilePath"`
+	InstallCommandLine             string                     `json:"installCommandLine"`
+	UninstallCommandLine           string                     `json:"uninstallCommandLine"`
+	ApplicableArchitectures        string                     `json:"applicableArchitectures"`
+	MinimumSupportedWindowsRelease string                     `json:"minimumSupportedWindowsRelease"`
+	MinimumFreeDiskSpaceInMB       *int64                     `json:"minimumFreeDiskSpaceInMB,omitempty"`
+	InstallExperience              InstallExperience          `json:"installExperience"`
+	DetectionRules                 []DetectionRule            `json:"detectionRules"`
+	ReturnCodes                    []ReturnCode               `json:"returnCodes"`
+	MsiInformation                 *Win32LobAppMsiInformation `json:"msiInformation,omitempty"`
+}
+
+// InstallExperience controls the account and restart behavior of an installation
+type InstallExperience struct {
+	ODataType             string `json:"@odata.type"`
+	RunAsAccount          string `json:"runAsAccount"`
+	DeviceRestartBehavior string `json:"deviceRestartBehavior"`
+}
+
+// Win32LobAppMsiInformation describes the MSI installed by a Win32 app
+type Win32LobAppMsiInformation struct {
+	ODataType      string `json:"@odata.type"`
+	ProductCode    string `json:"productCode"`
+	ProductVersion string `json:"productVersion"`
+	UpgradeCode    string `json:"upgradeCode,omitempty"`
+	ProductName    string `json:"productName"`
+	Publisher      string `json:"publisher"`
+	RequiresReboot bool   `json:"requiresReboot"`
+	PackageType    string `json:"packageType"`
+}
+
+// NewWin32LobApp creates a Win32 app body from package metadata with the default install
+// commands for its setup file, the given detection rules and return codes
+func NewWin32LobApp(appInfo *metadata.ApplicationInfo, rules []DetectionRule, returnCodes []ReturnCode) *Win32LobApp {
+	if rules == nil {
+		rules = []DetectionRule{}
+	}
+	install, uninstall := SetupCommands(appInfo.SetupFile, "")
+	return &Win32LobApp{
+		ODataType:                      win32LobAppODataType,
+		DisplayName:                    appInfo.Name,
+		Description:                    appInfo.Description,
+		FileName:                       appInfo.FileName,
+		SetupFilePath:                  appInfo.SetupFile,
+		InstallCommandLine:             install,
+		UninstallCommandLine:           uninstall,
+		ApplicableArchitectures:        DefaultApplicableArchitectures,
+		MinimumSupportedWindowsRelease: DefaultMinimumWindowsRelease,
+		InstallExperience: InstallExperience{
+			ODataType:             installExperienceODataType,
+			RunAsAccount:          "system",
+			DeviceRestartBehavior: "basedOnReturnCode",
+		},
+		DetectionRules: rules,
+		ReturnCodes:    returnCodes,
+	}
+}
+
+// SetMsiInformation records the product of an MSI setup and uninstalls it by its
+// product code
+func (a *Win32LobApp) SetMsiInformation(productCode, productVersion, upgradeCode, productName, publisher string) {
+	a.MsiInformation = &Win32LobAppMsiInformation{
+		ODataType:      msiInformationODataType,
+		ProductCode:    productCode,
+		ProductVersion: productVersion,
+		UpgradeCode:    upgradeCode,
+		ProductName:    productName,
+		Publisher:      publisher,
+		PackageType:    "perMachine",
+	}
+	_, a.UninstallCommandLine = SetupCommands(a.SetupFilePath, productCode)
+}
+
+// SetupCommands returns install and uninstall command line templates for a setup file.
+// MSI setups are uninstalled by productCode when it is not empty; the uninstall command
+// of other setups is a placeholder to adjust for the installer.
+func SetupCommands(setupFile, productCode string) (string, string) {
+	quoted := `"` + strings.ReplaceAll(setupFile, "/", `\`) + `"`
+	switch strings.ToLower(path.Ext(setupFile)) {
+	case ".msi":
+		target := quoted
+		if productCode != "" {
+			target = `"` + productCode + `"`
+		}
+		return "msiexec /i " + quoted + " /qn", "msiexec /x " + target + " /qn"
+	case ".ps1":
+		install := `powershell.exe -NoProfile -ExecutionPolicy Bypass -File ".\` + quoted[1:]
+		return install, install + " -Uninstall"
+	default:
+		return quoted, quoted + " /uninstall"
+	}
+}
+
+// ToJSON converts the app body to indented JSON
+func (a *Win32LobApp) ToJSON() ([]byte, error) {
+	data, err := json.MarshalIndent(a, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal app body to JSON: %w", err)
+	}
+	return data, nil
+}
@@ -0,0 +1,85 @@
+package manifest
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/kenchan0130/intunewin/internal/metadata"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetupCommands(t *testing.T) {
+	tests := []struct {
+		setupFile     string
+		productCode   string
+		wantInstall   string
+		wantUninstall string
+	}{
+		{
+			setupFile:     "setup.msi",
+			wantInstall:   `msiexec /i "setup.msi" /qn`,
+			wantUninstall: `msiexec /x "setup.msi" /qn`,
+		},
+		{
+			setupFile:     "Setup.MSI",
+			productCode:   "{GUID}",
+			wantInstall:   `msiexec /i "Setup.MSI" /qn`,
+			wantUninstall: `msiexec /x "{GUID}" /qn`,
+		},
+		{
+			setupFile:     "scripts/install.ps1",
+			wantInstall:   `powershell.exe -NoProfile -ExecutionPolicy Bypass -File ".\scripts\install.ps1"`,
+			wantUninstall: `powershell.exe -NoProfile -ExecutionPolicy Bypass -File ".\scripts\install.ps1" -Uninstall`,
+		},
+		{
+			setupFile:     "setup.exe",
+			wantInstall:   `"setup.exe"`,
+			wantUninstall: `"setup.exe" /uninstall`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.setupFile, func(t *testing.T) {
+			install, uninstall := SetupCommands(tt.setupFile, tt.productCode)
+			assert.Equal(t, tt.wantInstall, install)
+			assert.Equal(t, tt.wantUninstall, uninstall)
+		})
+	}
+}
+
+func TestNewWin32LobApp(t *testing.T) {
+	appInfo := &metadata.ApplicationInfo{
+		Name:        "My App",
+		Description: "My App 1.0",
+		FileName:    "IntunePackage.intunewin",
+		SetupFile:   "setup.msi",
+	}
+	app := NewWin32LobApp(appInfo, nil, DefaultReturnCodes())
+	app.SetMsiInformation("{GUID}", "1.0", "", "My App", "Contoso")
+
+	data, err := app.ToJSON()
+	require.NoError(t, err)
+
+	var got map[string]any
+	require.NoError(t, json.Unmarshal(data, &got))
+	assert.Equal(t, "#microsoft.graph.win32LobApp", got["@odata.type"])
+	assert.Equal(t, "My App", got["displayName"])
+	assert.Equal(t, "My App 1.0", got["description"])
+	assert.Equal(t, "setup.msi", got["setupFilePath"])
+	assert.Equal(t, `msiexec /i "setup.msi" /qn`, got["installCommandLine"])
+	assert.Equal(t, `msiexec /x "{GUID}" /qn`, got["uninstallCommandLine"])
+	assert.Equal(t, DefaultMinimumWindowsRelease, got["minimumSupportedWindowsRelease"])
+	assert.Equal(t, []any{}, got["detectionRules"])
+	assert.Len(t, got["returnCodes"], len(DefaultReturnCodes()))
+	assert.NotContains(t, got, "minimumFreeDiskSpaceInMB")
+	assert.Equal(t, map[string]any{
+		"@odata.type":    "#microsoft.graph.win32LobAppMsiInformation",
+		"productCode":    "{GUID}",
+		"productVersion": "1.0",
+		"productName":    "My App",
+		"publisher":      "Contoso",
+		"requiresReboot": false,
+		"packageType":    "perMachine",
+	}, got["msiInformation"])
+}
@@ -0,0 +1,58 @@
+package metadata
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// fileEncryptionInfoODataType is the Graph type of the encryption info of a content file
+const fileEncryptionInfoODataType = "microsoft.graph.fileEncryptionInfo"
+
+// CommitRequest is the body of the Graph request that commits the content file of a
+// Win32 app once its encrypted content has been uploaded
+type CommitRequest struct {
+	FileEncryptionInfo *FileEncryptionInfo `json:"fileEncryptionInfo"`
+}
+
+// FileEncryptionInfo is the encryption info of a content file in Graph format. Every
+// value is base64 encoded, as in Detection.xml.
+type FileEncryptionInfo struct {
+	ODataType            string `json:"@odata.type"`
+	EncryptionKey        string `json:"encryptionKey"`
+	MacKey               string `json:"macKey"`
+	InitializationVector string `json:"initializationVector"`
+	Mac                  string `json:"mac"`
+	ProfileIdentifier    string `json:"profileIdentifier"`
+	FileDigest           string `json:"fileDigest"`
+	FileDigestAlgorithm  string `json:"fileDigestAlgorithm"`
+}
+
+// NewCommitRequest creates the commit request for the package described by a. The
+// encryption info is validated first, as Intune rejects the content otherwise.
+func NewCommitRequest(a *ApplicationInfo) (*CommitRequest, error) {
+	if err := a.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid metadata: %w", err)
+	}
+	info := a.EncryptionInfo
+	return &CommitRequest{
+		FileEncryptionInfo: &FileEncryptionInfo{
+			ODataType:            fileEncryptionInfoODataType,
+			EncryptionKey:        info.EncryptionKey,
+			MacKey:               info.MacKey,
+			InitializationVector: info.InitializationVector,
+			Mac:                  info.Mac,
+			ProfileIdentifier:    info.ProfileIdentifier,
+			FileDigest:           info.FileDigest,
+			FileDigestAlgorithm:  info.FileDigestAlgorithm,
+		},
+	}, nil
+}
+
+// ToJSON converts the commit request to indented JSON
+func (c *CommitRequest) ToJSON() ([]byte, error) {
+	output, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal commit request to JSON: %w", err)
+	}
+	return output, nil
+}
@@ -0,0 +1,92 @@
+package metadata
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidToolVersion is returned for a ToolVersion that is not a dotted version such as 1.8.6.0
+var ErrInvalidToolVersion = errors.New("invalid tool version")
+
+const (
+	// ToolVersion is the version of the Microsoft Win32 Content Prep Tool recorded in Detection.xml
+	// by default. Intune validates this field when a package is uploaded, so it is only changed to
+	// a version Intune is known to accept; packages can override it with a newer one.
+	ToolVersion = "1.4.0.0"
+	// knownToolMajorVersion is the major ToolVersion written by every known release of the Microsoft tool
+	knownToolMajorVersion = 1
+	// KnownProfileIdentifier is the only encryption profile known to Intune
+	KnownProfileIdentifier = "ProfileVersion1"
+	// KnownFileDigestAlgorithm is the only file digest algorithm known to Intune
+	KnownFileDigestAlgorithm = "SHA256"
+)
+
+// Warning describes a Detection.xml value that this tool does not recognize
+type Warning struct {
+	Field    string
+	Value    string
+	Expected string
+}
+
+// String formats the warning for display
+func (w Warning) String() string {
+	if w.Expected == "" {
+		return fmt.Sprintf("unrecognized %s %q", w.Field, w.Value)
+	}
+	return fmt.Sprintf("unrecognized %s %q (expected %s)", w.Field, w.Value, w.Expected)
+}
+
+// CheckCompatibility returns a warning for every value that suggests the package was
+// created by a newer or unknown version of the format
+func (a *ApplicationInfo) CheckCompatibility() []Warning {
+	var warnings []Warning
+	if !isKnownToolVersion(a.ToolVersion) {
+		warnings = append(warnings, Warning{
+			Field:    "ToolVersion",
+			Value:    a.ToolVersion,
+			Expected: fmt.Sprintf("%d.x", knownToolMajorVersion),
+		})
+	}
+	if a.EncryptionInfo != nil {
+		if a.EncryptionInfo.ProfileIdentifier != KnownProfileIdentifier {
+			warnings = append(warnings, Warning{
+				Field:    "ProfileIdentifier",
+				Value:    a.EncryptionInfo.ProfileIdentifier,
+				Expected: KnownProfileIdentifier,
+			})
+		}
+		if a.EncryptionInfo.FileDigestAlgorithm != KnownFileDigestAlgorithm {
+			warnings = append(warnings, Warning{
+				Field:    "FileDigestAlgorithm",
+				Value:    a.EncryptionInfo.FileDigestAlgorithm,
+				Expected: KnownFileDigestAlgorithm,
+			})
+		}
+	}
+	return warnings
+}
+
+// isKnownToolVersion reports whether version is a dotted version with a known major version
+func isKnownToolVersion(version string) bool {
+	major, _, _ := strings.Cut(version, ".")
+	n, err := strconv.Atoi(major)
+	return err == nil && n == knownToolMajorVersion
+}
+
+// CheckToolVersion returns ErrInvalidToolVersion unless version is one to four dot separated
+// numbers, the form of the versions written by the Microsoft tool, and reports whether its
+// major version is the known one
+func CheckToolVersion(version string) (bool, error) {
+	parts := strings.Split(version, ".")
+	if len(parts) > 4 {
+		return false, fmt.Errorf("%w %q: more than four numbers", ErrInvalidToolVersion, version)
+	}
+	for _, part := range parts {
+		if _, err := strconv.ParseUint(part, 10, 16); err != nil {
+			return false, fmt.Errorf("%w %q: want numbers such as %s", ErrInvalidToolVersion, version, ToolVersion)
+		}
+	}
+	return isKnownToolVersion(version), nil
+}
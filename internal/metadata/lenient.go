@@ -0,0 +1,138 @@
+package metadata
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strings"
+)
+
+// element is an element of Detection.xml as written by the Microsoft tool
+type element struct {
+	name     string
+	children []element
+}
+
+// detectionXML is the element tree of Detection.xml
+var detectionXML = element{name: "ApplicationInfo", children: []element{
+	{name: "Name"},
+	{name: "Description"},
+	{name: "UnencryptedContentSize"},
+	{name: "FileName"},
+	{name: "SetupFile"},
+	{name: "EncryptionInfo", children: []element{
+		{name: "EncryptionKey"},
+		{name: "MacKey"},
+		{name: "InitializationVector"},
+		{name: "Mac"},
+		{name: "ProfileIdentifier"},
+		{name: "FileDigest"},
+		{name: "FileDigestAlgorithm"},
+	}},
+}}
+
+// ParseXML parses Detection.xml like FromXMLBytes, but also accepts the quirks of packages
+// written by other tools: element names that differ from the Microsoft format only in case
+// are read as the known element, and elements the format does not define are ignored.
+// Every quirk is returned as a Warning so that callers can reject them in strict mode.
+func ParseXML(data []byte) (*ApplicationInfo, []Warning, error) {
+	fixed, warnings := canonicalizeElements(data)
+	appInfo, err := FromXMLBytes(fixed)
+	if err != nil {
+		return nil, nil, err
+	}
+	return appInfo, warnings, nil
+}
+
+// openElement is an element of the document being scanned
+type openElement struct {
+	// known is the matching element of the format, nil for unknown elements and their children
+	known *element
+	// rename is the name written over the end tag when the start tag was renamed
+	rename string
+}
+
+// canonicalizeElements returns a copy of data in which element names that match a known
+// element regardless of case are spelled like the format, and a warning for every renamed
+// or unknown element. Malformed documents are returned unchanged for the parser to report.
+func canonicalizeElements(data []byte) ([]byte, []Warning) {
+	fixed := bytes.Clone(data)
+	var warnings []Warning
+	var stack []openElement
+	var path []string
+
+	d := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		offset := d.InputOffset()
+		token, err := d.RawToken()
+		if err == io.EOF {
+			return fixed, warnings
+		}
+		if err != nil {
+			return data, nil
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			var candidates []element
+			switch {
+			case len(stack) == 0:
+				candidates = []element{detectionXML}
+			case stack[len(stack)-1].known != nil:
+				candidates = stack[len(stack)-1].known.children
+			}
+			path = append(path, t.Name.Local)
+			if candidates == nil {
+				stack = append(stack, openElement{})
+				continue
+			}
+
+			known, exact := matchElement(candidates, t.Name.Local)
+			open := openElement{known: known}
+			switch {
+			case known == nil:
+				warnings = append(warnings, Warning{Field: "element", Value: strings.Join(path, "/")})
+			case !exact:
+				copy(fixed[nameOffset(offset+1, t.Name):], known.name)
+				open.rename = known.name
+				warnings = append(warnings, Warning{Field: "element", Value: strings.Join(path, "/"), Expected: known.name})
+			}
+			stack = append(stack, open)
+		case xml.EndElement:
+			if len(stack) == 0 {
+				return data, nil
+			}
+			open := stack[len(stack)-1]
+			stack, path = stack[:len(stack)-1], path[:len(path)-1]
+			// The end of a self-closing element is not in the input
+			if open.rename != "" && d.InputOffset() > offset {
+				copy(fixed[nameOffset(offset+2, t.Name):], open.rename)
+			}
+		}
+	}
+}
+
+// matchElement returns the element of candidates named name, matched exactly or else
+// regardless of case, and whether the match is exact
+func matchElement(candidates []element, name string) (*element, bool) {
+	for i := range candidates {
+		if candidates[i].name == name {
+			return &candidates[i], true
+		}
+	}
+	for i := range candidates {
+		// Names of the same length keep the offsets of the rest of the document
+		if len(candidates[i].name) == len(name) && strings.EqualFold(candidates[i].name, name) {
+			return &candidates[i], false
+		}
+	}
+	return nil, false
+}
+
+// nameOffset returns the offset of the local part of name in a tag whose name starts at start
+func nameOffset(start int64, name xml.Name) int64 {
+	if name.Space != "" {
+		return start + int64(len(name.Space)) + 1
+	}
+	return start
+}
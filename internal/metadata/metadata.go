@@ -25,7 +25,7 @@ func New(fileName string, unencryptedSize int64, encInfo *crypto.EncryptionInfo)
 	baseName := strings.TrimSuffix(fileName, filepath.Ext(fileName))
 
 	return &Metadata{
-		ToolVersion:         "1.4.0.0",
+		ToolVersion:         ToolVersion,
 		Name:                fileName,
 		Description:         "",
 		UnencryptedFileSize: unencryptedSize,
@@ -38,6 +38,7 @@ func New(fileName string, unencryptedSize int64, encInfo *crypto.EncryptionInfo)
 // ToXML converts metadata to XML
 func (m *Metadata) ToXML() ([]byte, error) {
 	appInfo := NewApplicationInfo(m.Name, m.SetupFile, m.UnencryptedFileSize, m.EncryptionInfo)
+	appInfo.Description = NormalizeText(m.Description)
 	return appInfo.ToXML()
 }
 
@@ -1,6 +1,7 @@
 package metadata
 
 import (
+	"encoding/json"
 	"testing"
 
 	"github.com/kenchan0130/intunewin/internal/crypto"
@@ -155,3 +156,210 @@ func TestValidate(t *testing.T) {
 		})
 	}
 }
+
+func TestNormalizeText(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{name: "ASCII", input: "My App", want: "My App"},
+		{name: "Japanese", input: "テストアプリ", want: "テストアプリ"},
+		{name: "Markup characters are kept", input: `A & B <C> "D" 'E'`, want: `A & B <C> "D" 'E'`},
+		{name: "CRLF line endings", input: "line1\r\nline2\rline3", want: "line1\nline2\nline3"},
+		{name: "Control characters are removed", input: "a\x00b\x1bc\x7f", want: "abc\x7f"},
+		{name: "Invalid UTF-8 is replaced", input: "a\xffb", want: "a�b"},
+		{name: "Noncharacters are removed", input: "a￾b", want: "ab"},
+		{name: "Emoji", input: "App 📦", want: "App 📦"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, NormalizeText(tt.input))
+		})
+	}
+}
+
+func TestXMLRoundTripEscaping(t *testing.T) {
+	encInfo := &crypto.EncryptionInfo{
+		EncryptionKey:        make([]byte, 32),
+		MacKey:               make([]byte, 32),
+		InitializationVector: make([]byte, 16),
+	}
+
+	tests := []struct {
+		name        string
+		appName     string
+		description string
+		setupFile   string
+		wantName    string
+	}{
+		{name: "Japanese", appName: "日本語アプリ", description: "説明文", setupFile: "セットアップ.exe"},
+		{name: "Ampersand", appName: "Foo & Bar", description: "Q&A", setupFile: "setup&run.cmd"},
+		{name: "Angle brackets", appName: "<script>alert(1)</script>", description: "a < b > c", setupFile: "setup.exe"},
+		{name: "Quotes", appName: `"Quoted" 'App'`, description: `He said "hi"`, setupFile: "install.ps1"},
+		{name: "CDATA terminator", appName: "]]> trick", description: "<![CDATA[x]]>", setupFile: "setup.exe"},
+		{name: "Multiline description", appName: "App", description: "line1\r\nline2", setupFile: "setup.exe"},
+		{name: "Control characters", appName: "App\x01Name", description: "desc", setupFile: "setup.exe", wantName: "AppName"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			meta := New("test.zip", 1000, encInfo)
+			meta.Name = tt.appName
+			meta.Description = tt.description
+			meta.SetupFile = tt.setupFile
+
+			xmlData, err := meta.ToXML()
+			require.NoError(t, err)
+
+			parsed, err := FromXML(xmlData)
+			require.NoError(t, err)
+
+			wantName := tt.wantName
+			if wantName == "" {
+				wantName = tt.appName
+			}
+			assert.Equal(t, wantName, parsed.Name)
+			assert.Equal(t, NormalizeText(tt.description), parsed.Description)
+			assert.Equal(t, tt.setupFile, parsed.SetupFile)
+		})
+	}
+}
+
+func TestApplicationInfoValidate(t *testing.T) {
+	valid := func() *ApplicationInfo {
+		return NewApplicationInfo("MyApp", "setup.exe", 1000, &crypto.EncryptionInfo{
+			EncryptionKey:        make([]byte, 32),
+			MacKey:               make([]byte, 32),
+			InitializationVector: make([]byte, 16),
+			Mac:                  make([]byte, 32),
+			FileDigest:           make([]byte, 32),
+			ProfileIdentifier:    "ProfileVersion1",
+			FileDigestAlgorithm:  "SHA256",
+		})
+	}
+
+	tests := []struct {
+		name    string
+		modify  func(a *ApplicationInfo)
+		wantErr string
+	}{
+		{name: "Valid", modify: func(a *ApplicationInfo) {}},
+		{name: "Missing name", modify: func(a *ApplicationInfo) { a.Name = "" }, wantErr: "name is required"},
+		{name: "Missing setup file", modify: func(a *ApplicationInfo) { a.SetupFile = "" }, wantErr: "setupFile is required"},
+		{name: "Zero size", modify: func(a *ApplicationInfo) { a.UnencryptedContentSize = 0 }, wantErr: "unencryptedContentSize must be positive"},
+		{name: "Missing encryption info", modify: func(a *ApplicationInfo) { a.EncryptionInfo = nil }, wantErr: "encryptionInfo is required"},
+		{name: "Invalid base64", modify: func(a *ApplicationInfo) { a.EncryptionInfo.MacKey = "!" }, wantErr: "failed to decode MAC key"},
+		{name: "Short key", modify: func(a *ApplicationInfo) { a.EncryptionInfo.EncryptionKey = "AAAA" }, wantErr: "encryption key must be 32 bytes"},
+		{name: "Unknown digest algorithm", modify: func(a *ApplicationInfo) { a.EncryptionInfo.FileDigestAlgorithm = "MD5" }, wantErr: `unsupported file digest algorithm "MD5"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			appInfo := valid()
+			tt.modify(appInfo)
+			err := appInfo.Validate()
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestCheckCompatibility(t *testing.T) {
+	appInfo := NewApplicationInfo("MyApp", "setup.exe", 1000, &crypto.EncryptionInfo{
+		ProfileIdentifier:   "ProfileVersion1",
+		FileDigestAlgorithm: "SHA256",
+	})
+	assert.Empty(t, appInfo.CheckCompatibility())
+
+	appInfo.ToolVersion = "1.8.6.0"
+	assert.Empty(t, appInfo.CheckCompatibility())
+
+	appInfo.ToolVersion = "2.0.0.0"
+	appInfo.EncryptionInfo.ProfileIdentifier = "ProfileVersion2"
+	warnings := appInfo.CheckCompatibility()
+	require.Len(t, warnings, 2)
+	assert.Equal(t, `unrecognized ToolVersion "2.0.0.0" (expected 1.x)`, warnings[0].String())
+	assert.Equal(t, Warning{Field: "ProfileIdentifier", Value: "ProfileVersion2", Expected: "ProfileVersion1"}, warnings[1])
+}
+
+func TestParseXML(t *testing.T) {
+	data := []byte(`<ApplicationInfo ToolVersion="1.8.6.0"><name>MyApp</name><SetupFile>setup.exe</SetupFile>` +
+		`<Publisher><Name>Contoso</Name></Publisher><encryptioninfo><MACKEY>a2V5</MACKEY></encryptioninfo>` +
+		`<x:FileName xmlns:x="urn:x">IntunePackage.intunewin</x:FileName></ApplicationInfo>`)
+
+	appInfo, warnings, err := ParseXML(data)
+	require.NoError(t, err)
+	assert.Equal(t, "MyApp", appInfo.Name)
+	assert.Equal(t, "setup.exe", appInfo.SetupFile)
+	assert.Equal(t, "IntunePackage.intunewin", appInfo.FileName)
+	require.NotNil(t, appInfo.EncryptionInfo)
+	assert.Equal(t, "a2V5", appInfo.EncryptionInfo.MacKey)
+	assert.Equal(t, []Warning{
+		{Field: "element", Value: "ApplicationInfo/name", Expected: "Name"},
+		{Field: "element", Value: "ApplicationInfo/Publisher"},
+		{Field: "element", Value: "ApplicationInfo/encryptioninfo", Expected: "EncryptionInfo"},
+		{Field: "element", Value: "ApplicationInfo/encryptioninfo/MACKEY", Expected: "MacKey"},
+	}, warnings)
+	assert.Equal(t, `unrecognized element "ApplicationInfo/Publisher"`, warnings[1].String())
+
+	appInfo, warnings, err = ParseXML([]byte(`<ApplicationInfo><Name>MyApp</Name><Description/></ApplicationInfo>`))
+	require.NoError(t, err)
+	assert.Equal(t, "MyApp", appInfo.Name)
+	assert.Empty(t, warnings)
+
+	_, _, err = ParseXML([]byte(`<ApplicationInfo><Name>MyApp</ApplicationInfo>`))
+	assert.Error(t, err)
+}
+
+func TestNewCommitRequest(t *testing.T) {
+	appInfo := NewApplicationInfo("MyApp", "setup.exe", 1000, &crypto.EncryptionInfo{
+		EncryptionKey:        make([]byte, 32),
+		MacKey:               make([]byte, 32),
+		InitializationVector: make([]byte, 16),
+		Mac:                  make([]byte, 32),
+		FileDigest:           make([]byte, 32),
+		ProfileIdentifier:    "ProfileVersion1",
+		FileDigestAlgorithm:  "SHA256",
+	})
+
+	req, err := NewCommitRequest(appInfo)
+	require.NoError(t, err)
+	data, err := req.ToJSON()
+	require.NoError(t, err)
+
+	var got map[string]map[string]string
+	require.NoError(t, json.Unmarshal(data, &got))
+	assert.Equal(t, map[string]string{
+		"@odata.type":          "microsoft.graph.fileEncryptionInfo",
+		"encryptionKey":        appInfo.EncryptionInfo.EncryptionKey,
+		"macKey":               appInfo.EncryptionInfo.MacKey,
+		"initializationVector": appInfo.EncryptionInfo.InitializationVector,
+		"mac":                  appInfo.EncryptionInfo.Mac,
+		"profileIdentifier":    "ProfileVersion1",
+		"fileDigest":           appInfo.EncryptionInfo.FileDigest,
+		"fileDigestAlgorithm":  "SHA256",
+	}, got["fileEncryptionInfo"])
+
+	appInfo.EncryptionInfo = nil
+	_, err = NewCommitRequest(appInfo)
+	assert.Error(t, err)
+}
+
+func TestCheckToolVersion(t *testing.T) {
+	for version, known := range map[string]bool{ToolVersion: true, "1.8.6.0": true, "1.8": true, "2.0.0.0": false} {
+		got, err := CheckToolVersion(version)
+		require.NoError(t, err, version)
+		assert.Equal(t, known, got, version)
+	}
+
+	for _, version := range []string{"", "1.8.6.0.1", "v1.8", "1..0", "1.8.6-beta", "1.70000"} {
+		_, err := CheckToolVersion(version)
+		assert.ErrorIs(t, err, ErrInvalidToolVersion, version)
+	}
+}
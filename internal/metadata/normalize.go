@@ -0,0 +1,30 @@
+package metadata
+
+import (
+	"strings"
+)
+
+// NormalizeText prepares a string for Detection.xml so that it survives a round trip
+// through the XML encoder and decoder unchanged: invalid UTF-8 is replaced with U+FFFD,
+// characters not allowed in XML 1.0 are removed, and line endings become "\n"
+// (XML parsers normalize "\r\n" and "\r" while reading).
+// Markup characters such as & and < are escaped by the encoder, not here.
+func NormalizeText(s string) string {
+	s = strings.ToValidUTF8(s, "�")
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	s = strings.ReplaceAll(s, "\r", "\n")
+	return strings.Map(func(r rune) rune {
+		if isXMLChar(r) {
+			return r
+		}
+		return -1
+	}, s)
+}
+
+// isXMLChar reports whether r is in the XML 1.0 Char production
+func isXMLChar(r rune) bool {
+	return r == '\t' || r == '\n' || r == '\r' ||
+		r >= 0x20 && r <= 0xD7FF ||
+		r >= 0xE000 && r <= 0xFFFD ||
+		r >= 0x10000 && r <= 0x10FFFF
+}
@@ -1,7 +1,9 @@
 package metadata
 
 import (
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/json"
 	"encoding/xml"
 	"fmt"
 
@@ -10,27 +12,27 @@ import (
 
 // ApplicationInfo represents the XML structure for Detection.xml
 type ApplicationInfo struct {
-	XMLName                xml.Name           `xml:"ApplicationInfo"`
-	XMLXSD                 string             `xml:"xmlns:xsd,attr"`
-	XMLXSI                 string             `xml:"xmlns:xsi,attr"`
-	ToolVersion            string             `xml:"ToolVersion,attr"`
-	Name                   string             `xml:"Name"`
-	Description            string             `xml:"Description,omitempty"`
-	UnencryptedContentSize int64              `xml:"UnencryptedContentSize"`
-	FileName               string             `xml:"FileName"`
-	SetupFile              string             `xml:"SetupFile"`
-	EncryptionInfo         *XMLEncryptionInfo `xml:"EncryptionInfo"`
+	XMLName                xml.Name           `xml:"ApplicationInfo" json:"-"`
+	XMLXSD                 string             `xml:"xmlns:xsd,attr" json:"-"`
+	XMLXSI                 string             `xml:"xmlns:xsi,attr" json:"-"`
+	ToolVersion            string             `xml:"ToolVersion,attr" json:"toolVersion"`
+	Name                   string             `xml:"Name" json:"name"`
+	Description            string             `xml:"Description,omitempty" json:"description,omitempty"`
+	UnencryptedContentSize int64              `xml:"UnencryptedContentSize" json:"unencryptedContentSize"`
+	FileName               string             `xml:"FileName" json:"fileName"`
+	SetupFile              string             `xml:"SetupFile" json:"setupFile"`
+	EncryptionInfo         *XMLEncryptionInfo `xml:"EncryptionInfo" json:"encryptionInfo"`
 }
 
 // XMLEncryptionInfo represents the encryption information in XML format
 type XMLEncryptionInfo struct {
-	EncryptionKey        string `xml:"EncryptionKey"`
-	MacKey               string `xml:"MacKey"`
-	InitializationVector string `xml:"InitializationVector"`
-	Mac                  string `xml:"Mac"`
-	ProfileIdentifier    string `xml:"ProfileIdentifier"`
-	FileDigest           string `xml:"FileDigest"`
-	FileDigestAlgorithm  string `xml:"FileDigestAlgorithm"`
+	EncryptionKey        string `xml:"EncryptionKey" json:"encryptionKey"`
+	MacKey               string `xml:"MacKey" json:"macKey"`
+	InitializationVector string `xml:"InitializationVector" json:"initializationVector"`
+	Mac                  string `xml:"Mac" json:"mac"`
+	ProfileIdentifier    string `xml:"ProfileIdentifier" json:"profileIdentifier"`
+	FileDigest           string `xml:"FileDigest" json:"fileDigest"`
+	FileDigestAlgorithm  string `xml:"FileDigestAlgorithm" json:"fileDigestAlgorithm"`
 }
 
 // NewApplicationInfo creates ApplicationInfo from encryption info
@@ -38,11 +40,11 @@ func NewApplicationInfo(name, setupFile string, unencryptedSize int64, encInfo *
 	return &ApplicationInfo{
 		XMLXSD:                 "http://www.w3.org/2001/XMLSchema",
 		XMLXSI:                 "http://www.w3.org/2001/XMLSchema-instance",
-		ToolVersion:            "1.4.0.0",
-		Name:                   name,
+		ToolVersion:            ToolVersion,
+		Name:                   NormalizeText(name),
 		UnencryptedContentSize: unencryptedSize,
 		FileName:               "IntunePackage.intunewin",
-		SetupFile:              setupFile,
+		SetupFile:              NormalizeText(setupFile),
 		EncryptionInfo: &XMLEncryptionInfo{
 			EncryptionKey:        base64.StdEncoding.EncodeToString(encInfo.EncryptionKey),
 			MacKey:               base64.StdEncoding.EncodeToString(encInfo.MacKey),
@@ -55,6 +57,14 @@ func NewApplicationInfo(name, setupFile string, unencryptedSize int64, encInfo *
 	}
 }
 
+// WithoutEncryptionInfo returns a copy of a without the encryption info, so the key
+// material is not kept alive by callers that only need the other fields
+func (a *ApplicationInfo) WithoutEncryptionInfo() *ApplicationInfo {
+	stripped := *a
+	stripped.EncryptionInfo = nil
+	return &stripped
+}
+
 // ToXML converts ApplicationInfo to XML bytes
 func (a *ApplicationInfo) ToXML() ([]byte, error) {
 	output, err := xml.MarshalIndent(a, "", "  ")
@@ -65,6 +75,15 @@ func (a *ApplicationInfo) ToXML() ([]byte, error) {
 	return output, nil
 }
 
+// ToJSON converts ApplicationInfo to indented JSON with the same fields as the XML
+func (a *ApplicationInfo) ToJSON() ([]byte, error) {
+	output, err := json.MarshalIndent(a, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal ApplicationInfo to JSON: %w", err)
+	}
+	return output, nil
+}
+
 // FromXMLBytes parses ApplicationInfo from XML bytes
 func FromXMLBytes(data []byte) (*ApplicationInfo, error) {
 	var appInfo ApplicationInfo
@@ -74,6 +93,44 @@ func FromXMLBytes(data []byte) (*ApplicationInfo, error) {
 	return &appInfo, nil
 }
 
+// Validate checks that the ApplicationInfo has every field Intune needs and that the
+// encryption info decodes to key material of the expected sizes
+func (a *ApplicationInfo) Validate() error {
+	if a.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if a.SetupFile == "" {
+		return fmt.Errorf("setupFile is required")
+	}
+	if a.FileName == "" {
+		return fmt.Errorf("fileName is required")
+	}
+	if a.UnencryptedContentSize <= 0 {
+		return fmt.Errorf("unencryptedContentSize must be positive")
+	}
+	if a.EncryptionInfo == nil {
+		return fmt.Errorf("encryptionInfo is required")
+	}
+
+	encInfo, err := a.EncryptionInfo.ToEncryptionInfo()
+	if err != nil {
+		return fmt.Errorf("invalid encryptionInfo: %w", err)
+	}
+	if err := crypto.ValidateKeys(encInfo.EncryptionKey, encInfo.MacKey, encInfo.InitializationVector); err != nil {
+		return fmt.Errorf("invalid encryptionInfo: %w", err)
+	}
+	if len(encInfo.Mac) != sha256.Size {
+		return fmt.Errorf("invalid encryptionInfo: MAC must be %d bytes, got %d", sha256.Size, len(encInfo.Mac))
+	}
+	if encInfo.FileDigestAlgorithm != KnownFileDigestAlgorithm {
+		return fmt.Errorf("invalid encryptionInfo: unsupported file digest algorithm %q", encInfo.FileDigestAlgorithm)
+	}
+	if len(encInfo.FileDigest) != sha256.Size {
+		return fmt.Errorf("invalid encryptionInfo: file digest must be %d bytes, got %d", sha256.Size, len(encInfo.FileDigest))
+	}
+	return nil
+}
+
 // ToEncryptionInfo converts XMLEncryptionInfo to crypto.EncryptionInfo
 func (x *XMLEncryptionInfo) ToEncryptionInfo() (*crypto.EncryptionInfo, error) {
 	encKey, err := base64.StdEncoding.DecodeString(x.EncryptionKey)
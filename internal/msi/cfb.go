@@ -0,0 +1,252 @@
+package msi
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"unicode/utf16"
+)
+
+// cfbSignature starts every compound file (OLE2 structured storage)
+var cfbSignature = []byte{0xD0, 0xCF, 0x11, 0xE0, 0xA1, 0xB1, 0x1A, 0xE1}
+
+// Special sector numbers of the allocation tables
+const (
+	endOfChain = 0xFFFFFFFE
+	freeSector = 0xFFFFFFFF
+	noStream   = 0xFFFFFFFF
+)
+
+// Directory entry types
+const (
+	entryStream = 2
+	entryRoot   = 5
+)
+
+// headerDIFATEntries is the number of FAT sector numbers stored in the header
+const headerDIFATEntries = 109
+
+// maxSectors bounds sector chains so that cyclic tables are detected
+const maxSectors = 1 << 24
+
+// dirEntry is a 128 byte directory entry of a compound file
+type dirEntry struct {
+	name        string
+	typ         byte
+	left, right uint32
+	child       uint32
+	start       uint32
+	size        uint64
+}
+
+// compoundFile reads the streams of a compound file
+type compoundFile struct {
+	r             io.ReaderAt
+	sectorSize    int64
+	miniSector    int64
+	miniCutoff    uint64
+	fat           []uint32
+	miniFAT       []uint32
+	entries       []dirEntry
+	miniStream    []byte
+	miniStreamErr error
+}
+
+// openCompoundFile parses the header, allocation tables and directory of a compound file
+func openCompoundFile(r io.ReaderAt) (*compoundFile, error) {
+	header := make([]byte, 512)
+	if _, err := r.ReadAt(header, 0); err != nil {
+		return nil, fmt.Errorf("failed to read compound file header: %w", err)
+	}
+	if !bytes.Equal(header[:8], cfbSignature) {
+		return nil, fmt.Errorf("not a compound file")
+	}
+
+	sectorShift := binary.LittleEndian.Uint16(header[0x1E:])
+	miniShift := binary.LittleEndian.Uint16(header[0x20:])
+	if sectorShift != 9 && sectorShift != 12 || miniShift != 6 {
+		return nil, fmt.Errorf("unsupported compound file sector size")
+	}
+	cf := &compoundFile{
+		r:          r,
+		sectorSize: 1 << sectorShift,
+		miniSector: 1 << miniShift,
+		miniCutoff: uint64(binary.LittleEndian.Uint32(header[0x38:])),
+	}
+
+	if err := cf.readFAT(header); err != nil {
+		return nil, err
+	}
+
+	dir, err := cf.readChain(binary.LittleEndian.Uint32(header[0x30:]), cf.fat, cf.readSector)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read directory: %w", err)
+	}
+	for offset := 0; offset+128 <= len(dir); offset += 128 {
+		e := parseDirEntry(dir[offset : offset+128])
+		// Version 3 files only use the low 32 bits of the stream size
+		if cf.sectorSize == 512 {
+			e.size &= 0xFFFFFFFF
+		}
+		cf.entries = append(cf.entries, e)
+	}
+	if len(cf.entries) == 0 || cf.entries[0].typ != entryRoot {
+		return nil, fmt.Errorf("compound file has no root entry")
+	}
+
+	if miniFATStart := binary.LittleEndian.Uint32(header[0x3C:]); miniFATStart != endOfChain {
+		data, err := cf.readChain(miniFATStart, cf.fat, cf.readSector)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read mini FAT: %w", err)
+		}
+		cf.miniFAT = toSectors(data)
+	}
+	return cf, nil
+}
+
+// readFAT collects the FAT sectors listed in the header and the DIFAT chain
+func (cf *compoundFile) readFAT(header []byte) error {
+	fatSectors := binary.LittleEndian.Uint32(header[0x2C:])
+	difat := toSectors(header[0x4C:])[:headerDIFATEntries]
+
+	next := binary.LittleEndian.Uint32(header[0x44:])
+	perSector := int(cf.sectorSize/4) - 1
+	for i := 0; next != endOfChain && next != freeSector; i++ {
+		if i >= maxSectors || uint32(len(difat)) >= fatSectors+uint32(perSector) {
+			return fmt.Errorf("DIFAT chain is too long")
+		}
+		data, err := cf.readSector(next)
+		if err != nil {
+			return fmt.Errorf("failed to read DIFAT: %w", err)
+		}
+		sectors := toSectors(data)
+		difat = append(difat, sectors[:perSector]...)
+		next = sectors[perSector]
+	}
+	if uint32(len(difat)) < fatSectors {
+		return fmt.Errorf("DIFAT lists %d of %d FAT sectors", len(difat), fatSectors)
+	}
+
+	for _, sector := range difat[:fatSectors] {
+		data, err := cf.readSector(sector)
+		if err != nil {
+			return fmt.Errorf("failed to read FAT: %w", err)
+		}
+		cf.fat = append(cf.fat, toSectors(data)...)
+	}
+	return nil
+}
+
+// readSector reads the regular sector n
+func (cf *compoundFile) readSector(n uint32) ([]byte, error) {
+	data := make([]byte, cf.sectorSize)
+	if _, err := cf.r.ReadAt(data, (int64(n)+1)*cf.sectorSize); err != nil {
+		return nil, fmt.Errorf("failed to read sector %d: %w", n, err)
+	}
+	return data, nil
+}
+
+// readMiniSector reads the mini sector n from the mini stream
+func (cf *compoundFile) readMiniSector(n uint32) ([]byte, error) {
+	if cf.miniStream == nil && cf.miniStreamErr == nil {
+		root := cf.entries[0]
+		cf.miniStream, cf.miniStreamErr = cf.readChain(root.start, cf.fat, cf.readSector)
+	}
+	if cf.miniStreamErr != nil {
+		return nil, fmt.Errorf("failed to read mini stream: %w", cf.miniStreamErr)
+	}
+	start := int64(n) * cf.miniSector
+	if start+cf.miniSector > int64(len(cf.miniStream)) {
+		return nil, fmt.Errorf("mini sector %d is out of bounds", n)
+	}
+	return cf.miniStream[start : start+cf.miniSector], nil
+}
+
+// readChain concatenates the sectors of the chain starting at start
+func (cf *compoundFile) readChain(start uint32, table []uint32, read func(uint32) ([]byte, error)) ([]byte, error) {
+	var data []byte
+	for n, i := start, 0; n != endOfChain; i++ {
+		if i >= maxSectors || i > len(table) {
+			return nil, fmt.Errorf("sector chain is cyclic")
+		}
+		if n >= uint32(len(table)) {
+			return nil, fmt.Errorf("sector %d is out of bounds", n)
+		}
+		sector, err := read(n)
+		if err != nil {
+			return nil, err
+		}
+		data = append(data, sector...)
+		n = table[n]
+	}
+	return data, nil
+}
+
+// stream returns the content of the stream entry e
+func (cf *compoundFile) stream(e dirEntry) ([]byte, error) {
+	var data []byte
+	var err error
+	if e.size < cf.miniCutoff {
+		data, err = cf.readChain(e.start, cf.miniFAT, cf.readMiniSector)
+	} else {
+		data, err = cf.readChain(e.start, cf.fat, cf.readSector)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read stream: %w", err)
+	}
+	if uint64(len(data)) < e.size {
+		return nil, fmt.Errorf("stream is truncated")
+	}
+	return data[:e.size], nil
+}
+
+// rootStreams returns the streams stored directly in the root storage by name
+func (cf *compoundFile) rootStreams() map[string]dirEntry {
+	streams := map[string]dirEntry{}
+	visited := map[uint32]bool{}
+	var walk func(id uint32)
+	walk = func(id uint32) {
+		if id == noStream || id >= uint32(len(cf.entries)) || visited[id] {
+			return
+		}
+		visited[id] = true
+		e := cf.entries[id]
+		if e.typ == entryStream {
+			streams[e.name] = e
+		}
+		walk(e.left)
+		walk(e.right)
+	}
+	walk(cf.entries[0].child)
+	return streams
+}
+
+// parseDirEntry decodes a directory entry
+func parseDirEntry(b []byte) dirEntry {
+	nameLength := min(int(binary.LittleEndian.Uint16(b[64:])), 64)
+	units := make([]uint16, 0, nameLength/2)
+	for i := 0; i+2 <= nameLength; i += 2 {
+		if unit := binary.LittleEndian.Uint16(b[i:]); unit != 0 {
+			units = append(units, unit)
+		}
+	}
+	return dirEntry{
+		name:  string(utf16.Decode(units)),
+		typ:   b[66],
+		left:  binary.LittleEndian.Uint32(b[68:]),
+		right: binary.LittleEndian.Uint32(b[72:]),
+		child: binary.LittleEndian.Uint32(b[76:]),
+		start: binary.LittleEndian.Uint32(b[116:]),
+		size:  binary.LittleEndian.Uint64(b[120:]),
+	}
+}
+
+// toSectors decodes little endian sector numbers
+func toSectors(b []byte) []uint32 {
+	sectors := make([]uint32, len(b)/4)
+	for i := range sectors {
+		sectors[i] = binary.LittleEndian.Uint32(b[i*4:])
+	}
+	return sectors
+}
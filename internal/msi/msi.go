@@ -0,0 +1,197 @@
+package msi
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Well-known properties of the Property table
+const (
+	PropertyProductCode    = "ProductCode"
+	PropertyProductName    = "ProductName"
+	PropertyProductVersion = "ProductVersion"
+	PropertyManufacturer   = "Manufacturer"
+	PropertyUpgradeCode    = "UpgradeCode"
+)
+
+// ErrNoProperties is returned when a database has no Property table
+var ErrNoProperties = errors.New("MSI database has no Property table")
+
+// utf8CodePage is the code page of string pools stored as UTF-8
+const utf8CodePage = 65001
+
+// longStringRefs is set in the string pool header when string references take 3 bytes
+const longStringRefs = 0x8000
+
+// ReadProperties returns the Property table of the MSI database in r
+func ReadProperties(r io.ReaderAt) (map[string]string, error) {
+	cf, err := openCompoundFile(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read MSI database: %w", err)
+	}
+
+	streams := map[string]dirEntry{}
+	for name, e := range cf.rootStreams() {
+		streams[decodeStreamName(name)] = e
+	}
+	read := func(name string) ([]byte, error) {
+		e, ok := streams[name]
+		if !ok {
+			return nil, fmt.Errorf("MSI database has no %s stream", strings.TrimPrefix(name, "!"))
+		}
+		return cf.stream(e)
+	}
+
+	poolData, err := read("!_StringPool")
+	if err != nil {
+		return nil, err
+	}
+	stringData, err := read("!_StringData")
+	if err != nil {
+		return nil, err
+	}
+	pool, refSize, err := parseStringPool(poolData, stringData)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := streams["!Property"]; !ok {
+		return nil, ErrNoProperties
+	}
+	table, err := read("!Property")
+	if err != nil {
+		return nil, err
+	}
+
+	// Tables are stored column by column: every Property reference, then every Value reference
+	rows := len(table) / (2 * refSize)
+	properties := make(map[string]string, rows)
+	for i := range rows {
+		name := pool.lookup(readRef(table[i*refSize:], refSize))
+		value := pool.lookup(readRef(table[(rows+i)*refSize:], refSize))
+		if name != "" {
+			properties[name] = value
+		}
+	}
+	return properties, nil
+}
+
+// stringPool holds the strings of a database, indexed from 1
+type stringPool []string
+
+// lookup returns the string with the given reference, or "" for null and invalid references
+func (p stringPool) lookup(ref uint32) string {
+	if ref == 0 || ref > uint32(len(p)) {
+		return ""
+	}
+	return p[ref-1]
+}
+
+// parseStringPool decodes the _StringPool and _StringData streams and returns the size
+// of string references in table streams
+func parseStringPool(pool, data []byte) (stringPool, int, error) {
+	if len(pool) < 4 {
+		return nil, 0, fmt.Errorf("string pool is too short")
+	}
+	codePage := binary.LittleEndian.Uint16(pool)
+	refSize := 2
+	if binary.LittleEndian.Uint16(pool[2:])&longStringRefs != 0 {
+		refSize = 3
+	}
+
+	var strs stringPool
+	offset := 0
+	for i := 4; i+4 <= len(pool); i += 4 {
+		length := int(binary.LittleEndian.Uint16(pool[i:]))
+		refs := binary.LittleEndian.Uint16(pool[i+2:])
+		if length == 0 && refs != 0 {
+			// A string of 64 KiB or more has a zero length and stores its length in
+			// the following entry, low word first
+			if i+8 > len(pool) {
+				return nil, 0, fmt.Errorf("string pool is truncated")
+			}
+			length = int(binary.LittleEndian.Uint16(pool[i+6:]))<<16 | int(binary.LittleEndian.Uint16(pool[i+4:]))
+			i += 4
+		}
+		if offset+length > len(data) {
+			return nil, 0, fmt.Errorf("string data is truncated")
+		}
+		strs = append(strs, decodeString(data[offset:offset+length], codePage))
+		offset += length
+	}
+	return strs, refSize, nil
+}
+
+// decodeString converts string data in the code page of the database to UTF-8.
+// Code pages other than UTF-8 are decoded as Latin-1, which is exact for the ASCII
+// values of properties such as ProductCode and ProductVersion.
+func decodeString(b []byte, codePage uint16) string {
+	if codePage == utf8CodePage || isASCII(b) {
+		return string(b)
+	}
+	runes := make([]rune, len(b))
+	for i, c := range b {
+		runes[i] = rune(c)
+	}
+	return string(runes)
+}
+
+// isASCII reports whether b only holds 7-bit characters
+func isASCII(b []byte) bool {
+	for _, c := range b {
+		if c >= 0x80 {
+			return false
+		}
+	}
+	return true
+}
+
+// readRef reads a little endian string reference of size bytes
+func readRef(b []byte, size int) uint32 {
+	ref := uint32(binary.LittleEndian.Uint16(b))
+	if size == 3 {
+		ref |= uint32(b[2]) << 16
+	}
+	return ref
+}
+
+// decodeStreamName expands the compressed stream names of MSI databases, where one
+// character in 0x3800-0x47FF packs two characters of a 64 character alphabet and
+// 0x4840 marks tables, which are returned with a "!" prefix
+func decodeStreamName(name string) string {
+	var b strings.Builder
+	for _, c := range name {
+		switch {
+		case c >= 0x3800 && c < 0x4800:
+			c -= 0x3800
+			b.WriteByte(streamNameChar(c & 0x3F))
+			b.WriteByte(streamNameChar((c >> 6) & 0x3F))
+		case c >= 0x4800 && c < 0x4840:
+			b.WriteByte(streamNameChar(c - 0x4800))
+		case c == 0x4840:
+			b.WriteByte('!')
+		default:
+			b.WriteRune(c)
+		}
+	}
+	return b.String()
+}
+
+// streamNameChar maps a 6-bit value to the alphabet of compressed stream names
+func streamNameChar(c rune) byte {
+	switch {
+	case c < 10:
+		return byte('0' + c)
+	case c < 36:
+		return byte('A' + c - 10)
+	case c < 62:
+		return byte('a' + c - 36)
+	case c == 62:
+		return '.'
+	default:
+		return '_'
+	}
+}
@@ -0,0 +1,309 @@
+package msi
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"testing"
+	"unicode/utf16"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testSectorSize = 512
+	testMiniSize   = 64
+	testMiniCutoff = 4096
+)
+
+// testStream is a stream of a compound file built by buildCompoundFile
+type testStream struct {
+	name string
+	data []byte
+}
+
+// encodeStreamName compresses an MSI stream name, the reverse of decodeStreamName
+func encodeStreamName(name string) string {
+	const alphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz._"
+	var runes []rune
+	if rest, ok := strings.CutPrefix(name, "!"); ok {
+		runes = append(runes, 0x4840)
+		name = rest
+	}
+	for i := 0; i < len(name); i += 2 {
+		c1 := rune(strings.IndexByte(alphabet, name[i]))
+		if i+1 == len(name) {
+			runes = append(runes, 0x4800+c1)
+			break
+		}
+		c2 := rune(strings.IndexByte(alphabet, name[i+1]))
+		runes = append(runes, 0x3800+c1+c2<<6)
+	}
+	return string(runes)
+}
+
+// buildCompoundFile lays out a version 3 compound file with 512 byte sectors whose root
+// storage holds streams; streams smaller than the cutoff are kept in the mini stream
+func buildCompoundFile(streams []testStream) []byte {
+	sectorsFor := func(size int) int { return (size + testSectorSize - 1) / testSectorSize }
+
+	var mini []byte
+	var miniFAT []uint32
+	starts := make([]uint32, len(streams))
+	for i, s := range streams {
+		if len(s.data) >= testMiniCutoff {
+			continue
+		}
+		starts[i] = uint32(len(mini) / testMiniSize)
+		count := max(1, (len(s.data)+testMiniSize-1)/testMiniSize)
+		for n := range count {
+			next := uint32(len(miniFAT) + 1)
+			if n == count-1 {
+				next = endOfChain
+			}
+			miniFAT = append(miniFAT, next)
+		}
+		padded := make([]byte, count*testMiniSize)
+		copy(padded, s.data)
+		mini = append(mini, padded...)
+	}
+
+	dirSectors := sectorsFor((len(streams) + 1) * 128)
+	miniFATSectors := sectorsFor(len(miniFAT) * 4)
+	miniSectors := sectorsFor(len(mini))
+	bigSectors := 0
+	for _, s := range streams {
+		if len(s.data) >= testMiniCutoff {
+			bigSectors += sectorsFor(len(s.data))
+		}
+	}
+	other := dirSectors + miniFATSectors + miniSectors + bigSectors
+	fatSectors := 1
+	for (other+fatSectors)*4 > fatSectors*testSectorSize {
+		fatSectors++
+	}
+
+	var fat []uint32
+	var body []byte
+	// chain allocates the next count sectors as one chain holding data
+	chain := func(count int, data []byte) uint32 {
+		if count == 0 {
+			return endOfChain
+		}
+		start := uint32(fatSectors + len(body)/testSectorSize)
+		for n := range count {
+			next := start + uint32(n) + 1
+			if n == count-1 {
+				next = endOfChain
+			}
+			fat = append(fat, next)
+		}
+		padded := make([]byte, count*testSectorSize)
+		copy(padded, data)
+		body = append(body, padded...)
+		return start
+	}
+	for range fatSectors {
+		fat = append(fat, 0xFFFFFFFD)
+	}
+
+	dir := make([]byte, dirSectors*testSectorSize)
+	writeEntry := func(i int, name string, typ byte, right, child, start uint32, size int) {
+		e := dir[i*128:]
+		units := utf16.Encode([]rune(name))
+		for j, u := range units {
+			binary.LittleEndian.PutUint16(e[j*2:], u)
+		}
+		binary.LittleEndian.PutUint16(e[64:], uint16((len(units)+1)*2))
+		e[66], e[67] = typ, 1
+		binary.LittleEndian.PutUint32(e[68:], noStream)
+		binary.LittleEndian.PutUint32(e[72:], right)
+		binary.LittleEndian.PutUint32(e[76:], child)
+		binary.LittleEndian.PutUint32(e[116:], start)
+		binary.LittleEndian.PutUint64(e[120:], uint64(size))
+	}
+	for i := range dirSectors * testSectorSize / 128 {
+		writeEntry(i, "", 0, noStream, noStream, endOfChain, 0)
+	}
+
+	dirStart := chain(dirSectors, nil)
+	miniFATData := make([]byte, len(miniFAT)*4)
+	for i, n := range miniFAT {
+		binary.LittleEndian.PutUint32(miniFATData[i*4:], n)
+	}
+	miniFATStart := chain(miniFATSectors, miniFATData)
+	miniStart := chain(miniSectors, mini)
+	for i, s := range streams {
+		if len(s.data) >= testMiniCutoff {
+			starts[i] = chain(sectorsFor(len(s.data)), s.data)
+		}
+	}
+
+	writeEntry(0, "Root Entry", entryRoot, noStream, 1, miniStart, len(mini))
+	for i, s := range streams {
+		right := uint32(i + 2)
+		if i == len(streams)-1 {
+			right = noStream
+		}
+		writeEntry(i+1, s.name, entryStream, right, noStream, starts[i], len(s.data))
+	}
+	copy(body, dir)
+
+	header := make([]byte, testSectorSize)
+	copy(header, cfbSignature)
+	binary.LittleEndian.PutUint16(header[0x18:], 0x3E)
+	binary.LittleEndian.PutUint16(header[0x1A:], 3)
+	binary.LittleEndian.PutUint16(header[0x1C:], 0xFFFE)
+	binary.LittleEndian.PutUint16(header[0x1E:], 9)
+	binary.LittleEndian.PutUint16(header[0x20:], 6)
+	binary.LittleEndian.PutUint32(header[0x2C:], uint32(fatSectors))
+	binary.LittleEndian.PutUint32(header[0x30:], dirStart)
+	binary.LittleEndian.PutUint32(header[0x38:], testMiniCutoff)
+	binary.LittleEndian.PutUint32(header[0x3C:], miniFATStart)
+	binary.LittleEndian.PutUint32(header[0x40:], uint32(miniFATSectors))
+	binary.LittleEndian.PutUint32(header[0x44:], endOfChain)
+	for i := range headerDIFATEntries {
+		sector := uint32(freeSector)
+		if i < fatSectors {
+			sector = uint32(i)
+		}
+		binary.LittleEndian.PutUint32(header[0x4C+i*4:], sector)
+	}
+
+	fatData := make([]byte, fatSectors*testSectorSize)
+	for i := range fatData[len(fat)*4:] {
+		fatData[len(fat)*4+i] = 0xFF
+	}
+	for i, n := range fat {
+		binary.LittleEndian.PutUint32(fatData[i*4:], n)
+	}
+
+	return append(append(header, fatData...), body...)
+}
+
+// buildDatabase creates an MSI database whose Property table holds properties in order
+func buildDatabase(properties [][2]string, extra ...testStream) []byte {
+	pool := []byte{0xE4, 0x04, 0, 0} // code page 1252
+	var data []byte
+	var names, values []byte
+	for i, p := range properties {
+		for _, s := range p {
+			pool = binary.LittleEndian.AppendUint16(pool, uint16(len(s)))
+			pool = binary.LittleEndian.AppendUint16(pool, 1)
+			data = append(data, s...)
+		}
+		names = binary.LittleEndian.AppendUint16(names, uint16(i*2+1))
+		values = binary.LittleEndian.AppendUint16(values, uint16(i*2+2))
+	}
+
+	streams := []testStream{
+		{name: encodeStreamName("!_StringPool"), data: pool},
+		{name: encodeStreamName("!_StringData"), data: data},
+		{name: encodeStreamName("!Property"), data: append(names, values...)},
+		{name: "\x05SummaryInformation", data: []byte("summary")},
+	}
+	return buildCompoundFile(append(streams, extra...))
+}
+
+func TestReadProperties(t *testing.T) {
+	image := buildDatabase([][2]string{
+		{PropertyProductCode, "{12345678-1234-1234-1234-123456789ABC}"},
+		{PropertyProductVersion, "1.2.3"},
+		{PropertyProductName, "My App"},
+		{PropertyManufacturer, "Contoso"},
+	}, testStream{name: encodeStreamName("Binary.Large"), data: bytes.Repeat([]byte("x"), 10000)})
+
+	properties, err := ReadProperties(bytes.NewReader(image))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		PropertyProductCode:    "{12345678-1234-1234-1234-123456789ABC}",
+		PropertyProductVersion: "1.2.3",
+		PropertyProductName:    "My App",
+		PropertyManufacturer:   "Contoso",
+	}, properties)
+}
+
+func TestReadPropertiesLargeStreams(t *testing.T) {
+	// Enough properties to move the string data and the Property table out of the mini stream
+	var properties [][2]string
+	want := map[string]string{}
+	for i := range 400 {
+		name := "Property" + strings.Repeat("x", i%7) + string(rune('A'+i%26)) + strings.Repeat("0", i/26)
+		properties = append(properties, [2]string{name, "value"})
+		want[name] = "value"
+	}
+
+	got, err := ReadProperties(bytes.NewReader(buildDatabase(properties)))
+	require.NoError(t, err)
+	assert.Equal(t, want, got)
+}
+
+func TestReadPropertiesWithoutPropertyTable(t *testing.T) {
+	image := buildCompoundFile([]testStream{
+		{name: encodeStreamName("!_StringPool"), data: []byte{0, 0, 0, 0}},
+		{name: encodeStreamName("!_StringData"), data: nil},
+	})
+	_, err := ReadProperties(bytes.NewReader(image))
+	assert.ErrorIs(t, err, ErrNoProperties)
+}
+
+func TestReadPropertiesInvalid(t *testing.T) {
+	image := buildDatabase([][2]string{{PropertyProductCode, "{GUID}"}})
+	cyclic := bytes.Clone(image)
+	// Point the directory chain back to itself
+	binary.LittleEndian.PutUint32(cyclic[testSectorSize+4:], 1)
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{name: "not a compound file", data: bytes.Repeat([]byte{0}, 1024)},
+		{name: "truncated", data: image[:testSectorSize+100]},
+		{name: "cyclic directory", data: cyclic},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ReadProperties(bytes.NewReader(tt.data))
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestParseStringPool(t *testing.T) {
+	long := strings.Repeat("a", 70000)
+	pool := []byte{0xE9, 0xFD, 0x00, 0x80} // UTF-8 with 3 byte references
+	pool = binary.LittleEndian.AppendUint16(pool, 0)
+	pool = binary.LittleEndian.AppendUint16(pool, 0)
+	pool = binary.LittleEndian.AppendUint16(pool, 0)
+	pool = binary.LittleEndian.AppendUint16(pool, 1)
+	pool = binary.LittleEndian.AppendUint16(pool, uint16(len(long)&0xFFFF))
+	pool = binary.LittleEndian.AppendUint16(pool, uint16(len(long)>>16))
+	pool = binary.LittleEndian.AppendUint16(pool, uint16(len("é")))
+	pool = binary.LittleEndian.AppendUint16(pool, 1)
+
+	strs, refSize, err := parseStringPool(pool, []byte(long+"é"))
+	require.NoError(t, err)
+	assert.Equal(t, 3, refSize)
+	require.Len(t, strs, 3)
+	assert.Empty(t, strs[0])
+	assert.Equal(t, long, strs[1])
+	assert.Equal(t, "é", strs[2])
+	assert.Equal(t, "é", strs.lookup(3))
+	assert.Empty(t, strs.lookup(4))
+
+	_, _, err = parseStringPool(pool, []byte("short"))
+	assert.Error(t, err)
+}
+
+func TestDecodeString(t *testing.T) {
+	assert.Equal(t, "Café", decodeString([]byte("Caf\xe9"), 1252))
+	assert.Equal(t, "Café", decodeString([]byte("Café"), utf8CodePage))
+}
+
+func TestDecodeStreamName(t *testing.T) {
+	for _, name := range []string{"!Property", "!_StringPool", "Binary.Icon", "odd"} {
+		assert.Equal(t, name, decodeStreamName(encodeStreamName(name)))
+	}
+	assert.Equal(t, "\x05SummaryInformation", decodeStreamName("\x05SummaryInformation"))
+}
@@ -0,0 +1,101 @@
+package pack
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/kenchan0130/intunewin/internal/pathutil"
+)
+
+// ErrPathCollision is returned when two sources put a file at the same path in the package
+var ErrPathCollision = errors.New("path collision")
+
+// Addition is a file or folder packed next to the content of the source
+type Addition struct {
+	// Source is the file or folder on disk
+	Source string
+	// Dest is the slash-separated path of the file or folder in the package
+	Dest string
+}
+
+// WithAdd packs the file or folder at source as dest in the package, in addition to the
+// source folder, so that content can be assembled from several places without staging
+// it first. dest defaults to the base name of source; folders are merged with folders
+// already at dest, while a file packed twice at the same path fails with ErrPathCollision.
+func WithAdd(source, dest string) Option {
+	return func(o *Options) {
+		if dest == "" {
+			dest = filepath.Base(source)
+		}
+		o.Additions = append(o.Additions, Addition{Source: source, Dest: dest})
+	}
+}
+
+// validateAdditions normalizes the destinations of o.Additions
+func (o *Options) validateAdditions() error {
+	for i, addition := range o.Additions {
+		dest, err := pathutil.Clean(addition.Dest)
+		if err == nil && dest == "." {
+			err = errors.New("the root of the package")
+		}
+		if err != nil {
+			return fmt.Errorf("invalid destination %q for %s: %w", addition.Dest, addition.Source, err)
+		}
+		o.Additions[i].Dest = dest
+	}
+	return nil
+}
+
+// addAdditions walks the additions of the options into the collected files
+func (w *walker) addAdditions() error {
+	for _, addition := range w.o.Additions {
+		info, err := os.Stat(pathutil.Long(addition.Source))
+		if err != nil {
+			return fmt.Errorf("failed to add %s: %w", addition.Source, err)
+		}
+		if err := w.addParents(addition.Dest, info); err != nil {
+			return err
+		}
+
+		w.root = addition.Source
+		w.prefix = addition.Dest
+		if err := w.add(addition.Source, info); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addParents records the folders above dest, so that every file of an addition is in
+// a folder of the package like the files of the source folder
+func (w *walker) addParents(dest string, info fs.FileInfo) error {
+	for dir := path.Dir(dest); dir != "."; dir = path.Dir(dir) {
+		if err := w.o.checkName(dir); err != nil {
+			return err
+		}
+		w.files = append(w.files, fileEntry{
+			Path:     dir,
+			Mode:     fs.ModeDir | 0755,
+			IsDir:    true,
+			Modified: info.ModTime(),
+		})
+	}
+	return nil
+}
+
+// archivePath returns the path in the package of relPath, relative to the root being walked
+func (w *walker) archivePath(relPath string) string {
+	return path.Join(w.prefix, pathutil.ToArchive(relPath))
+}
+
+// sourceName describes where an entry comes from in collision errors
+func sourceName(file fileEntry) string {
+	if file.Source == "" {
+		return "a parent folder of an addition"
+	}
+	return file.Source
+}
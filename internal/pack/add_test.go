@@ -0,0 +1,85 @@
+package pack
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeFiles creates the slash-separated files below dir, each holding its name
+func writeFiles(t *testing.T, dir string, names ...string) {
+	t.Helper()
+	for _, name := range names {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0750))
+		require.NoError(t, os.WriteFile(path, []byte(name), 0600))
+	}
+}
+
+func TestCollectFilesAdditions(t *testing.T) {
+	dir := t.TempDir()
+	build := filepath.Join(dir, "build")
+	scripts := filepath.Join(dir, "scripts")
+	writeFiles(t, build, "setup.exe", "tools/a.dll")
+	writeFiles(t, scripts, "install.ps1", "common/log.ps1")
+	writeFiles(t, dir, "LICENSE.txt")
+
+	o, err := newOptions([]Option{
+		WithAdd(scripts, "tools/scripts"),
+		WithAdd(filepath.Join(dir, "LICENSE.txt"), ""),
+		WithAdd(filepath.Join(scripts, "common"), "tools"),
+	})
+	require.NoError(t, err)
+	files, err := collectFiles(build, o)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"setup.exe", "tools", "tools/a.dll",
+		"tools/scripts", "tools/scripts/common", "tools/scripts/common/log.ps1", "tools/scripts/install.ps1",
+		"LICENSE.txt", "tools/log.ps1",
+	}, entryPaths(files))
+}
+
+func TestCollectFilesAdditionCollision(t *testing.T) {
+	dir := t.TempDir()
+	build := filepath.Join(dir, "build")
+	writeFiles(t, build, "setup.exe", "LICENSE.txt")
+	writeFiles(t, dir, "LICENSE.txt")
+
+	o, err := newOptions([]Option{WithAdd(filepath.Join(dir, "LICENSE.txt"), "")})
+	require.NoError(t, err)
+	_, err = collectFiles(build, o)
+	assert.ErrorIs(t, err, ErrPathCollision)
+	assert.ErrorContains(t, err, "LICENSE.txt is packed from both")
+}
+
+func TestCollectFilesAdditionMissing(t *testing.T) {
+	build := t.TempDir()
+
+	o, err := newOptions([]Option{WithAdd(filepath.Join(build, "missing"), "")})
+	require.NoError(t, err)
+	_, err = collectFiles(build, o)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestInvalidAdditionDestination(t *testing.T) {
+	for _, dest := range []string{"../outside", "/abs", "."} {
+		_, err := newOptions([]Option{WithAdd("file", dest)})
+		assert.ErrorContains(t, err, "invalid destination", dest)
+	}
+}
+
+func TestPackFileWithInfoAdditions(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, "Setup.msi", "scripts/detect.ps1")
+
+	var summary Summary
+	outputFile := filepath.Join(dir, "out.intunewin")
+	require.NoError(t, PackFileWithInfo(filepath.Join(dir, "Setup.msi"), outputFile, "App",
+		WithAdd(filepath.Join(dir, "scripts"), ""),
+		WithSummary(&summary),
+	))
+	assert.Equal(t, 2, summary.Files)
+}
@@ -0,0 +1,35 @@
+package pack
+
+import (
+	"archive/zip"
+	"os"
+)
+
+// MS-DOS attributes in the low byte of the external attributes of a zip entry
+const (
+	msdosReadOnly = 0x01
+	msdosDir      = 0x10
+	msdosArchive  = 0x20
+)
+
+// setMode records mode m on header. By default only MS-DOS attributes are written, as by the
+// Microsoft tool, so consumers that do not understand Unix modes see the attributes Windows
+// would give the entry; with POSIXModes the Unix mode is recorded as well.
+func (o *Options) setMode(header *zip.FileHeader, m os.FileMode) {
+	m = o.fileMode(m)
+	if o.POSIXModes {
+		header.SetMode(m)
+		return
+	}
+
+	attrs := uint32(msdosArchive)
+	if m.IsDir() {
+		attrs = msdosDir
+	}
+	if m&0200 == 0 {
+		attrs |= msdosReadOnly
+	}
+	// A creator of 0 (MS-DOS) in the high byte tells readers to ignore the Unix mode bits
+	header.CreatorVersion = 0
+	header.ExternalAttrs = attrs
+}
@@ -0,0 +1,52 @@
+package pack
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPackAttributes(t *testing.T) {
+	sourceDir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(sourceDir, "bin"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(sourceDir, "bin", "setup.exe"), []byte("setup"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(sourceDir, "readme.txt"), []byte("readme"), 0444))
+
+	encKey := bytes.Repeat([]byte{1}, 32)
+	macKey := bytes.Repeat([]byte{2}, 32)
+	keys := WithEncryptionKeys(encKey, macKey, bytes.Repeat([]byte{3}, 16))
+
+	outputFile := filepath.Join(t.TempDir(), "dos.intunewin")
+	require.NoError(t, PackWithInfo(sourceDir, outputFile, "app", "bin/setup.exe", keys))
+	attrs := map[string]uint32{}
+	for _, file := range readInnerZip(t, outputFile, encKey, macKey).File {
+		assert.Zero(t, file.CreatorVersion>>8, file.Name)
+		attrs[file.Name] = file.ExternalAttrs
+	}
+	assert.Equal(t, map[string]uint32{
+		"bin/":          msdosDir,
+		"bin/setup.exe": msdosArchive,
+		"readme.txt":    msdosArchive | msdosReadOnly,
+	}, attrs)
+
+	// Windows only records a read-only attribute
+	if runtime.GOOS == "windows" {
+		return
+	}
+	outputFile = filepath.Join(t.TempDir(), "posix.intunewin")
+	require.NoError(t, PackWithInfo(sourceDir, outputFile, "app", "bin/setup.exe", keys, WithPOSIXModes()))
+	modes := map[string]os.FileMode{}
+	for _, file := range readInnerZip(t, outputFile, encKey, macKey).File {
+		modes[file.Name] = file.Mode()
+	}
+	assert.Equal(t, map[string]os.FileMode{
+		"bin/":          os.ModeDir | 0755,
+		"bin/setup.exe": 0755,
+		"readme.txt":    0444,
+	}, modes)
+}
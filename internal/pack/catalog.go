@@ -0,0 +1,81 @@
+package pack
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/kenchan0130/intunewin/internal/pathutil"
+)
+
+// CatalogPath is the folder of the package holding the catalog files, next to Detection.xml
+const CatalogPath = "IntuneWinPackage/Metadata/Catalogs"
+
+// ErrNoCatalogs is returned when the catalog folder holds no .cat file
+var ErrNoCatalogs = errors.New("no .cat files in the catalog folder")
+
+// WithCatalogFolder adds the signed .cat files in the top level of dir to the package,
+// unencrypted under CatalogPath, like the catalog folder of IntuneWinAppUtil does for
+// apps deployed to Windows in S mode
+func WithCatalogFolder(dir string) Option {
+	return func(o *Options) {
+		o.CatalogFolder = dir
+	}
+}
+
+// catalogFiles returns the paths of the .cat files in the top level of dir in lexical order
+func catalogFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(pathutil.Long(dir))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read catalog folder: %w", err)
+	}
+	var files []string
+	for _, entry := range entries {
+		if entry.Type().IsRegular() && strings.EqualFold(filepath.Ext(entry.Name()), ".cat") {
+			files = append(files, filepath.Join(dir, entry.Name()))
+		}
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrNoCatalogs, dir)
+	}
+	return files, nil
+}
+
+// writeCatalogs adds the catalog files to the package
+func writeCatalogs(zipWriter *zipWriter, files []string, now time.Time, o *Options) error {
+	for _, file := range files {
+		name := path.Join(CatalogPath, filepath.Base(file))
+		writer, err := zipWriter.CreateHeader(&zip.FileHeader{
+			Name:     name,
+			Method:   zip.Deflate,
+			Modified: now,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create catalog entry: %w", err)
+		}
+		if err := copyCatalog(writer, file); err != nil {
+			return err
+		}
+		o.Logger.Debug("added catalog", "path", name)
+	}
+	return nil
+}
+
+// copyCatalog writes the content of the catalog file at path to w
+func copyCatalog(w io.Writer, path string) error {
+	f, err := os.Open(pathutil.Long(path)) // #nosec G304 -- path comes from the catalog folder chosen by the user
+	if err != nil {
+		return fmt.Errorf("failed to open catalog: %w", err)
+	}
+	defer f.Close()
+	if _, err := io.Copy(w, f); err != nil {
+		return fmt.Errorf("failed to write catalog %s: %w", path, err)
+	}
+	return nil
+}
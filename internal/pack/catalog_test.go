@@ -0,0 +1,52 @@
+package pack
+
+import (
+	"archive/zip"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPackCatalogFolder(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, "source/setup.exe", "catalogs/app.cat", "catalogs/Extra.CAT", "catalogs/readme.txt", "catalogs/sub/nested.cat")
+
+	outputFile := filepath.Join(dir, "app.intunewin")
+	require.NoError(t, PackWithInfo(filepath.Join(dir, "source"), outputFile, "App", "setup.exe",
+		WithCatalogFolder(filepath.Join(dir, "catalogs"))))
+
+	outer, err := zip.OpenReader(outputFile)
+	require.NoError(t, err)
+	defer outer.Close()
+	catalogs := map[string]string{}
+	for _, f := range outer.File {
+		if filepath.ToSlash(filepath.Dir(f.Name)) != CatalogPath {
+			continue
+		}
+		rc, err := f.Open()
+		require.NoError(t, err)
+		data, err := io.ReadAll(rc)
+		rc.Close()
+		require.NoError(t, err)
+		catalogs[filepath.Base(f.Name)] = string(data)
+	}
+	assert.Equal(t, map[string]string{"Extra.CAT": "catalogs/Extra.CAT", "app.cat": "catalogs/app.cat"}, catalogs)
+}
+
+func TestPackCatalogFolderWithoutCatalogs(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, "source/setup.exe", "catalogs/readme.txt")
+
+	outputFile := filepath.Join(dir, "app.intunewin")
+	err := PackWithInfo(filepath.Join(dir, "source"), outputFile, "App", "setup.exe",
+		WithCatalogFolder(filepath.Join(dir, "catalogs")))
+	assert.ErrorIs(t, err, ErrNoCatalogs)
+
+	err = PackWithInfo(filepath.Join(dir, "source"), outputFile, "App", "setup.exe",
+		WithCatalogFolder(filepath.Join(dir, "missing")))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
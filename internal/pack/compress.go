@@ -0,0 +1,156 @@
+package pack
+
+import (
+	"archive/zip"
+	"fmt"
+	"sync"
+
+	"github.com/kenchan0130/intunewin/internal/progress"
+	"github.com/kenchan0130/intunewin/internal/spill"
+)
+
+// compressedFile is a file compressed by a worker into a single entry zip archive
+type compressedFile struct {
+	archive *spill.Buffer
+	err     error
+}
+
+// compressor reads and compresses files on o.Threads workers. Results are handed
+// out in the order of the files, so the output does not depend on scheduling.
+type compressor struct {
+	results []chan compressedFile
+	// slots bounds the number of files compressed but not yet written
+	slots chan struct{}
+	done  chan struct{}
+	wg    sync.WaitGroup
+}
+
+// startCompressor starts compressing every file (directories are skipped) in the background
+func startCompressor(files []fileEntry, counter *progress.Counter, o *Options) *compressor {
+	window := 2 * o.Threads
+	c := &compressor{
+		results: make([]chan compressedFile, len(files)),
+		slots:   make(chan struct{}, window),
+		done:    make(chan struct{}),
+	}
+	for i := range c.results {
+		c.results[i] = make(chan compressedFile, 1)
+	}
+
+	// Split the spill threshold between the files in flight to bound memory use
+	entryOptions := *o
+	entryOptions.SpillThreshold = o.SpillThreshold / int64(window)
+
+	jobs := make(chan int)
+	go func() {
+		defer close(jobs)
+		for i, file := range files {
+			if file.IsDir {
+				continue
+			}
+			select {
+			case c.slots <- struct{}{}:
+			case <-c.done:
+				return
+			}
+			select {
+			case jobs <- i:
+			case <-c.done:
+				return
+			}
+		}
+	}()
+
+	for range o.Threads {
+		c.wg.Add(1)
+		go func() {
+			defer c.wg.Done()
+			for i := range jobs {
+				archive, err := compressFile(files[i], counter, &entryOptions)
+				c.results[i] <- compressedFile{archive: archive, err: err}
+			}
+		}()
+	}
+	return c
+}
+
+// next waits for the compressed file at index i. The caller must close the archive and call release.
+func (c *compressor) next(i int) compressedFile {
+	return <-c.results[i]
+}
+
+// release frees the slot of a file returned by next
+func (c *compressor) release() {
+	<-c.slots
+}
+
+// stop cancels pending work, waits for the workers and removes results that were not consumed
+func (c *compressor) stop() {
+	close(c.done)
+	c.wg.Wait()
+	for _, result := range c.results {
+		select {
+		case r := <-result:
+			if r.archive != nil {
+				r.archive.Close()
+			}
+		default:
+		}
+	}
+}
+
+// compressFile reads and compresses file into a single entry zip archive, so the entry
+// can be copied to the content zip exactly as if it had been written there directly
+func compressFile(file fileEntry, counter *progress.Counter, o *Options) (*spill.Buffer, error) {
+	archive := o.newBuffer("intunewin-entry-*.zip")
+	zipWriter := o.newZipWriter(archive)
+	// The entry is copied with copyEntry, which moves its sizes to the local header
+	zipWriter.buffered = false
+	if err := addFile(zipWriter, file, counter, o); err != nil {
+		archive.Close()
+		return nil, err
+	}
+	if err := zipWriter.Close(); err != nil {
+		archive.Close()
+		return nil, fmt.Errorf("failed to write file content %s: %w", file.Path, err)
+	}
+	return archive, nil
+}
+
+// addFile reads and compresses file into a new entry of zipWriter
+func addFile(zipWriter *zipWriter, file fileEntry, counter *progress.Counter, o *Options) error {
+	header := &zip.FileHeader{
+		Name:     file.Path,
+		Method:   o.methodFor(file.Path),
+		Modified: o.modTime(file.Modified),
+	}
+	o.Logger.Debug("adding file", "path", file.Path, "size", file.Size, "stored", header.Method == zip.Store)
+	o.setMode(header, file.Mode)
+
+	writer, err := zipWriter.CreateHeader(header)
+	if err != nil {
+		return fmt.Errorf("failed to create file entry %s: %w", file.Path, err)
+	}
+
+	counter.File(file.Path)
+	if err := copyFile(writer, file.Source, counter); err != nil {
+		return fmt.Errorf("failed to write file content %s: %w", file.Path, err)
+	}
+	return nil
+}
+
+// copyEntry copies the entry of a single entry zip archive to zipWriter without recompressing it
+func copyEntry(zipWriter *zipWriter, archive *spill.Buffer) error {
+	size, err := archive.Size()
+	if err != nil {
+		return err //nolint:wrapcheck // spill errors already describe the failure
+	}
+	zipReader, err := zip.NewReader(archive, size)
+	if err != nil {
+		return fmt.Errorf("failed to read compressed entry: %w", err)
+	}
+	if err := zipWriter.Copy(zipReader.File[0]); err != nil {
+		return fmt.Errorf("failed to copy compressed entry: %w", err)
+	}
+	return nil
+}
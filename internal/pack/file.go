@@ -0,0 +1,86 @@
+package pack
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/kenchan0130/intunewin/internal/pathutil"
+	"github.com/kenchan0130/intunewin/internal/progress"
+	"github.com/kenchan0130/intunewin/internal/spill"
+)
+
+// ErrSourceNotFile is returned when the path given to PackFileWithInfo is not a regular file
+var ErrSourceNotFile = errors.New("source path is not a file")
+
+// PackFileWithInfo creates an intunewin file whose content is the single file at path,
+// such as an .msi or a self-contained .exe, recorded as the setup file under its base name.
+// It saves staging a folder holding only the installer.
+func PackFileWithInfo(path, outputFile, name string, opts ...Option) error {
+	o, err := newOptions(opts)
+	if err != nil {
+		return fmt.Errorf("invalid options: %w", err)
+	}
+	info, err := sourceFileInfo(path, name)
+	if err != nil {
+		return err
+	}
+	info.OutputFile = outputFile
+	return packToFile(outputFile, info, o, func() (*spill.Buffer, error) {
+		return buildFileZip(path, o)
+	})
+}
+
+// PackFileWithInfoTo is like PackFileWithInfo but writes the package to w instead of a file
+func PackFileWithInfoTo(path string, w io.Writer, name string, opts ...Option) error {
+	o, err := newOptions(opts)
+	if err != nil {
+		return fmt.Errorf("invalid options: %w", err)
+	}
+	info, err := sourceFileInfo(path, name)
+	if err != nil {
+		return err
+	}
+	return packToWriter(w, info, o, func() (*spill.Buffer, error) {
+		return buildFileZip(path, o)
+	})
+}
+
+// sourceFileInfo checks that path is a regular file and returns the hook info of packing it
+func sourceFileInfo(path, name string) (HookInfo, error) {
+	stat, err := os.Stat(pathutil.Long(path))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return HookInfo{}, fmt.Errorf("%w: %s", ErrSourceNotFound, path)
+		}
+		return HookInfo{}, fmt.Errorf("failed to access source file: %w", err)
+	}
+	if !stat.Mode().IsRegular() {
+		return HookInfo{}, fmt.Errorf("%w: %s", ErrSourceNotFile, path)
+	}
+	return HookInfo{Stage: HookPrePack, SourceFolder: path, Name: name, SetupFile: filepath.Base(path)}, nil
+}
+
+// buildFileZip returns a content zip holding the file at path in the top level, and the additions
+func buildFileZip(path string, o *Options) (*spill.Buffer, error) {
+	stat, err := os.Stat(pathutil.Long(path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to access source file: %w", err)
+	}
+	w := &walker{
+		root:    filepath.Dir(path),
+		o:       o,
+		counter: progress.NewCounter(o.Progress, progress.Walk, 0),
+	}
+	if err := w.add(path, stat); err != nil {
+		return nil, err
+	}
+	files, err := w.entries()
+	if err != nil {
+		return nil, err
+	}
+	o.Logger.Debug("packing single file", "source", path, "entries", len(files))
+	return zipFiles(files, o)
+}
@@ -0,0 +1,67 @@
+package pack
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPackFileWithInfo(t *testing.T) {
+	tempDir := t.TempDir()
+	setupFile := filepath.Join(tempDir, "Setup.msi")
+	require.NoError(t, os.WriteFile(setupFile, []byte("msi content"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "other.txt"), []byte("left out"), 0600))
+
+	encKey := bytes.Repeat([]byte{1}, 32)
+	macKey := bytes.Repeat([]byte{2}, 32)
+	var hooked HookInfo
+	outputFile := filepath.Join(tempDir, "out", "app.intunewin")
+	require.NoError(t, PackFileWithInfo(setupFile, outputFile, "App",
+		WithEncryptionKeys(encKey, macKey, bytes.Repeat([]byte{3}, 16)),
+		WithPrePackHook(func(info HookInfo) error {
+			hooked = info
+			return nil
+		}),
+	))
+
+	inner := readInnerZip(t, outputFile, encKey, macKey)
+	require.Len(t, inner.File, 1)
+	assert.Equal(t, "Setup.msi", inner.File[0].Name)
+	rc, err := inner.File[0].Open()
+	require.NoError(t, err)
+	defer rc.Close()
+	content, err := io.ReadAll(rc)
+	require.NoError(t, err)
+	assert.Equal(t, "msi content", string(content))
+
+	assert.Equal(t, setupFile, hooked.SourceFolder)
+	assert.Equal(t, "Setup.msi", hooked.SetupFile)
+	assert.Equal(t, "App", hooked.Name)
+}
+
+func TestPackFileWithInfoTo(t *testing.T) {
+	tempDir := t.TempDir()
+	setupFile := filepath.Join(tempDir, "setup.exe")
+	require.NoError(t, os.WriteFile(setupFile, []byte("setup"), 0600))
+
+	var summary Summary
+	var output bytes.Buffer
+	require.NoError(t, PackFileWithInfoTo(setupFile, &output, "App", WithSummary(&summary)))
+	assert.Positive(t, output.Len())
+	assert.Equal(t, 1, summary.Files)
+}
+
+func TestPackFileWithInfoInvalidSource(t *testing.T) {
+	tempDir := t.TempDir()
+
+	err := PackFileWithInfo(filepath.Join(tempDir, "missing.msi"), filepath.Join(tempDir, "out.intunewin"), "App")
+	assert.ErrorIs(t, err, ErrSourceNotFound)
+
+	err = PackFileWithInfoTo(tempDir, io.Discard, "App")
+	assert.ErrorIs(t, err, ErrSourceNotFile)
+}
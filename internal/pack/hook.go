@@ -0,0 +1,92 @@
+package pack
+
+import (
+	"errors"
+	"fmt"
+)
+
+// HookStage is when a hook runs
+type HookStage string
+
+const (
+	// HookPrePack runs before the content is read
+	HookPrePack HookStage = "pre-pack"
+	// HookPostPack runs after the package has been written
+	HookPostPack HookStage = "post-pack"
+)
+
+// ErrHookFailed is returned when a hook fails, which aborts the pack
+var ErrHookFailed = errors.New("pack hook failed")
+
+// HookInfo describes the package being created to a hook
+type HookInfo struct {
+	// Stage is when the hook runs
+	Stage HookStage `json:"stage"`
+	// SourceFolder is the folder being packed, or the file with PackFileWithInfo, empty when a zip or tar stream is packed
+	SourceFolder string `json:"sourceFolder"`
+	// OutputFile is the package file, empty when the package is written to a stream
+	OutputFile string `json:"outputFile,omitempty"`
+	// Name is the application name recorded in Detection.xml
+	Name string `json:"name"`
+	// SetupFile is the setup file recorded in Detection.xml
+	SetupFile string `json:"setupFile"`
+	// Files is the number of packaged files, set after packing
+	Files int `json:"files,omitempty"`
+	// UnencryptedSize is the size of the content before encryption, set after packing
+	UnencryptedSize int64 `json:"unencryptedSize,omitempty"`
+	// SHA256 is the hex encoded digest of the package, set after packing
+	SHA256 string `json:"sha256,omitempty"`
+}
+
+// Hook is called before or after a package is created. An error aborts the pack.
+type Hook func(info HookInfo) error
+
+// WithPrePackHook runs hook before packing, such as to scan the source folder for malware
+// or to check the code signatures of its binaries. Hooks run in the order given.
+func WithPrePackHook(hook Hook) Option {
+	return func(o *Options) {
+		o.PrePackHooks = append(o.PrePackHooks, hook)
+	}
+}
+
+// WithPostPackHook runs hook after the package has been written, such as to upload it or
+// send a notification. The package is left in place when the hook fails.
+func WithPostPackHook(hook Hook) Option {
+	return func(o *Options) {
+		o.PostPackHooks = append(o.PostPackHooks, hook)
+	}
+}
+
+// runPrePackHooks runs the pre-pack hooks with info and requests the summary that
+// post-pack hooks receive
+func (o *Options) runPrePackHooks(info HookInfo) error {
+	if err := runHooks(o.PrePackHooks, info); err != nil {
+		return err
+	}
+	if len(o.PostPackHooks) > 0 && o.Summary == nil {
+		o.Summary = &Summary{}
+	}
+	return nil
+}
+
+// runPostPackHooks runs the post-pack hooks with info completed by the summary of the
+// written package
+func (o *Options) runPostPackHooks(info HookInfo) error {
+	info.Stage = HookPostPack
+	if o.Summary != nil {
+		info.Files = o.Summary.Files
+		info.UnencryptedSize = o.Summary.UnencryptedSize
+		info.SHA256 = o.Summary.SHA256
+	}
+	return runHooks(o.PostPackHooks, info)
+}
+
+// runHooks calls hooks in order with info and stops at the first failure
+func runHooks(hooks []Hook, info HookInfo) error {
+	for i, hook := range hooks {
+		if err := hook(info); err != nil {
+			return fmt.Errorf("%w: %s hook %d: %w", ErrHookFailed, info.Stage, i+1, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package pack
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHooks(t *testing.T) {
+	sourceDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(sourceDir, "setup.exe"), []byte("setup"), 0600))
+	outputFile := filepath.Join(t.TempDir(), "app.intunewin")
+
+	var calls []HookInfo
+	record := func(info HookInfo) error {
+		calls = append(calls, info)
+		return nil
+	}
+	require.NoError(t, PackWithInfo(sourceDir, outputFile, "App", "setup.exe", WithPrePackHook(record), WithPostPackHook(record)))
+
+	require.Len(t, calls, 2)
+	assert.Equal(t, HookInfo{Stage: HookPrePack, SourceFolder: sourceDir, OutputFile: outputFile, Name: "App", SetupFile: "setup.exe"}, calls[0])
+	assert.Equal(t, HookPostPack, calls[1].Stage)
+	assert.Equal(t, 1, calls[1].Files)
+	data, err := os.ReadFile(outputFile)
+	require.NoError(t, err)
+	digest := sha256.Sum256(data)
+	assert.Equal(t, hex.EncodeToString(digest[:]), calls[1].SHA256)
+	assert.Positive(t, calls[1].UnencryptedSize)
+}
+
+func TestPrePackHookAbortsPack(t *testing.T) {
+	sourceDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(sourceDir, "setup.exe"), []byte("setup"), 0600))
+	outputFile := filepath.Join(t.TempDir(), "app.intunewin")
+
+	errScan := errors.New("malware found")
+	postCalled := false
+	err := PackWithInfo(sourceDir, outputFile, "App", "setup.exe",
+		WithPrePackHook(func(HookInfo) error { return nil }),
+		WithPrePackHook(func(HookInfo) error { return errScan }),
+		WithPostPackHook(func(HookInfo) error { postCalled = true; return nil }))
+	require.ErrorIs(t, err, ErrHookFailed)
+	require.ErrorIs(t, err, errScan)
+	assert.Contains(t, err.Error(), "pre-pack hook 2")
+	assert.NoFileExists(t, outputFile)
+	assert.False(t, postCalled)
+}
+
+func TestPostPackHookWithWriter(t *testing.T) {
+	sourceDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(sourceDir, "setup.exe"), []byte("setup"), 0600))
+
+	var info HookInfo
+	var buf bytes.Buffer
+	err := PackWithInfoTo(sourceDir, &buf, "App", "setup.exe", WithPostPackHook(func(i HookInfo) error {
+		info = i
+		return errors.New("upload failed")
+	}))
+	require.ErrorIs(t, err, ErrHookFailed)
+	assert.Positive(t, buf.Len())
+	assert.Empty(t, info.OutputFile)
+	assert.NotEmpty(t, info.SHA256)
+}
+
+func TestHooksWithZipStream(t *testing.T) {
+	zipBuf := new(bytes.Buffer)
+	zipWriter := zip.NewWriter(zipBuf)
+	_, err := zipWriter.Create("setup.exe")
+	require.NoError(t, err)
+	require.NoError(t, zipWriter.Close())
+
+	var calls []HookInfo
+	record := func(info HookInfo) error {
+		calls = append(calls, info)
+		return nil
+	}
+	require.NoError(t, PackZipTo(zipBuf, io.Discard, "App", "setup.exe", WithPrePackHook(record), WithPostPackHook(record)))
+
+	require.Len(t, calls, 2)
+	assert.Equal(t, HookInfo{Stage: HookPrePack, Name: "App", SetupFile: "setup.exe"}, calls[0])
+	assert.Equal(t, 1, calls[1].Files)
+	assert.Len(t, calls[1].SHA256, 64)
+}
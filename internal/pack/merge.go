@@ -0,0 +1,81 @@
+package pack
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrCaseConflict is returned for entries whose paths differ only in case with CaseConflictError
+var ErrCaseConflict = errors.New("paths differ only in case")
+
+// CaseConflictPolicy selects how entries whose paths differ only in case, such as Readme.txt
+// and README.TXT, are packed. Windows extracts them to the same file, so only one survives
+// on the client and which one is unpredictable.
+type CaseConflictPolicy string
+
+const (
+	// CaseConflictError fails on the first such entry
+	CaseConflictError CaseConflictPolicy = "error"
+	// CaseConflictFirst keeps the entry walked first and leaves the others out with a warning
+	CaseConflictFirst CaseConflictPolicy = "first"
+)
+
+// WithCaseConflicts selects how entries whose paths differ only in case are packed.
+// The default is CaseConflictError. Folders differing only in case are merged by Windows
+// and never conflict with each other, but the files in them are checked.
+func WithCaseConflicts(policy CaseConflictPolicy) Option {
+	return func(o *Options) {
+		o.CaseConflicts = policy
+	}
+}
+
+// mergeEntries drops repeated folders, which come from merging sources, and returns
+// ErrPathCollision when a file shares its path with another entry. Entries whose paths
+// differ only in case are handled according to o.CaseConflicts.
+func (o *Options) mergeEntries(files []fileEntry) ([]fileEntry, error) {
+	seen := make(map[string]fileEntry, len(files))
+	folded := make(map[string]fileEntry, len(files))
+	var skipped []string
+	merged := files[:0]
+	for _, file := range files {
+		if previous, ok := seen[file.Path]; ok {
+			if previous.IsDir && file.IsDir {
+				continue
+			}
+			return nil, fmt.Errorf("%w: %s is packed from both %s and %s", ErrPathCollision, file.Path, sourceName(previous), sourceName(file))
+		}
+		if below(file.Path, skipped) {
+			continue
+		}
+
+		key := strings.ToLower(file.Path)
+		if previous, ok := folded[key]; ok && !(previous.IsDir && file.IsDir) {
+			if o.CaseConflicts != CaseConflictFirst {
+				return nil, fmt.Errorf("%w: %s and %s", ErrCaseConflict, previous.Path, file.Path)
+			}
+			o.Logger.Warn("leaving out entry whose path differs only in case from another", "path", file.Path, "kept", previous.Path)
+			if file.IsDir {
+				skipped = append(skipped, file.Path)
+			}
+			continue
+		}
+
+		seen[file.Path] = file
+		if _, ok := folded[key]; !ok {
+			folded[key] = file
+		}
+		merged = append(merged, file)
+	}
+	return merged, nil
+}
+
+// below reports whether archivePath is inside one of the folders
+func below(archivePath string, folders []string) bool {
+	for _, folder := range folders {
+		if strings.HasPrefix(archivePath, folder+"/") {
+			return true
+		}
+	}
+	return false
+}
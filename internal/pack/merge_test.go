@@ -0,0 +1,58 @@
+package pack
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// caseSource creates a source folder with entries whose paths differ only in case,
+// which needs a case-sensitive file system
+func caseSource(t *testing.T) string {
+	t.Helper()
+	if runtime.GOOS == "windows" || runtime.GOOS == "darwin" {
+		t.Skip("the file system is not case-sensitive")
+	}
+	dir := t.TempDir()
+	writeFiles(t, dir, "setup.exe", "Readme.txt", "README.TXT", "Docs/a.txt", "docs/a.txt", "docs/b.txt", "lib", "LIB/x.dll")
+	return dir
+}
+
+func TestCollectFilesCaseConflictError(t *testing.T) {
+	sourceDir := caseSource(t)
+
+	o, err := newOptions(nil)
+	require.NoError(t, err)
+	_, err = collectFiles(sourceDir, o)
+	assert.ErrorIs(t, err, ErrCaseConflict)
+}
+
+func TestCollectFilesCaseConflictFirst(t *testing.T) {
+	sourceDir := caseSource(t)
+
+	o, err := newOptions([]Option{WithCaseConflicts(CaseConflictFirst)})
+	require.NoError(t, err)
+	files, err := collectFiles(sourceDir, o)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Docs", "Docs/a.txt", "LIB", "LIB/x.dll", "README.TXT", "docs", "docs/b.txt", "setup.exe"}, entryPaths(files))
+}
+
+func TestMergeEntriesFoldersDifferingInCase(t *testing.T) {
+	o, err := newOptions(nil)
+	require.NoError(t, err)
+	files, err := o.mergeEntries([]fileEntry{
+		{Path: "Docs", IsDir: true},
+		{Path: "Docs/a.txt"},
+		{Path: "docs", IsDir: true},
+		{Path: "docs/b.txt"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Docs", "Docs/a.txt", "docs", "docs/b.txt"}, entryPaths(files))
+}
+
+func TestInvalidCaseConflictPolicy(t *testing.T) {
+	_, err := newOptions([]Option{WithCaseConflicts("rename")})
+	assert.ErrorContains(t, err, "invalid case conflict policy")
+}
@@ -0,0 +1,55 @@
+package pack
+
+import (
+	"errors"
+	"fmt"
+	"path"
+
+	"github.com/kenchan0130/intunewin/internal/pathutil"
+)
+
+// ErrInvalidName is returned for entries Windows cannot create with InvalidNameError
+var ErrInvalidName = errors.New("name cannot be created on Windows")
+
+// InvalidNamePolicy selects how entries whose names Windows cannot create, such as CON,
+// aux.txt, what?.txt or a name ending in a dot, are packed. Content packed on other
+// platforms may hold them, and they fail to extract on the managed device.
+type InvalidNamePolicy string
+
+const (
+	// InvalidNameError fails on the first such entry
+	InvalidNameError InvalidNamePolicy = "error"
+	// InvalidNameWarn packs them and logs a warning for each
+	InvalidNameWarn InvalidNamePolicy = "warn"
+)
+
+// WithInvalidNames selects how entries whose names Windows cannot create are packed.
+// The default is InvalidNameError.
+func WithInvalidNames(policy InvalidNamePolicy) Option {
+	return func(o *Options) {
+		o.InvalidNames = policy
+	}
+}
+
+// checkName applies the invalid name policy to the last component of archivePath
+func (o *Options) checkName(archivePath string) error {
+	err := pathutil.CheckWindowsName(path.Base(archivePath))
+	if err == nil {
+		return nil
+	}
+	if o.InvalidNames == InvalidNameWarn {
+		o.Logger.Warn("packing entry whose name cannot be created on Windows", "path", archivePath, "reason", err.Error())
+		return nil
+	}
+	return fmt.Errorf("%w: %s: %w", ErrInvalidName, archivePath, err)
+}
+
+// checkPath applies the invalid name policy to every component of archivePath
+func (o *Options) checkPath(archivePath string) error {
+	for dir := archivePath; dir != "."; dir = path.Dir(dir) {
+		if err := o.checkName(dir); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package pack
+
+import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"log/slog"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCollectFilesInvalidNames(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Windows cannot create the names")
+	}
+	sourceDir := t.TempDir()
+	writeFiles(t, sourceDir, "setup.exe", "logs/aux.log", "notes.")
+
+	o, err := newOptions(nil)
+	require.NoError(t, err)
+	_, err = collectFiles(sourceDir, o)
+	assert.ErrorIs(t, err, ErrInvalidName)
+	assert.ErrorContains(t, err, "logs/aux.log: reserved device name aux.log")
+
+	var logs bytes.Buffer
+	o, err = newOptions([]Option{
+		WithInvalidNames(InvalidNameWarn),
+		WithLogger(slog.New(slog.NewTextHandler(&logs, nil))),
+	})
+	require.NoError(t, err)
+	files, err := collectFiles(sourceDir, o)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"logs", "logs/aux.log", "notes.", "setup.exe"}, entryPaths(files))
+	assert.Contains(t, logs.String(), "path=notes.")
+
+	o, err = newOptions([]Option{WithExclude("aux.log", "notes.")})
+	require.NoError(t, err)
+	_, err = collectFiles(sourceDir, o)
+	assert.NoError(t, err, "excluded entries are not checked")
+}
+
+func TestPackTarToInvalidNames(t *testing.T) {
+	var archive bytes.Buffer
+	tw := tar.NewWriter(&archive)
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "PRN/", Typeflag: tar.TypeDir, Mode: 0755}))
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "PRN/setup.exe", Typeflag: tar.TypeReg, Mode: 0644, Size: 5}))
+	_, err := tw.Write([]byte("setup"))
+	require.NoError(t, err)
+	require.NoError(t, tw.Close())
+
+	err = PackTarTo(bytes.NewReader(archive.Bytes()), io.Discard, "App", "PRN/setup.exe")
+	if runtime.GOOS == "windows" {
+		// pathutil.Clean already rejects the name
+		assert.Error(t, err)
+		return
+	}
+	assert.ErrorIs(t, err, ErrInvalidName)
+}
+
+func TestInvalidNamePolicy(t *testing.T) {
+	_, err := newOptions([]Option{WithInvalidNames("rename")})
+	assert.ErrorContains(t, err, "invalid policy for invalid names")
+}
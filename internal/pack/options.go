@@ -0,0 +1,492 @@
+package pack
+
+import (
+	"archive/zip"
+	"compress/flate"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kenchan0130/intunewin/internal/buildinfo"
+	"github.com/kenchan0130/intunewin/internal/crypto"
+	"github.com/kenchan0130/intunewin/internal/metadata"
+	"github.com/kenchan0130/intunewin/internal/progress"
+	"github.com/kenchan0130/intunewin/internal/spill"
+)
+
+// zipEpoch is the earliest time representable in a zip file header
+var zipEpoch = time.Date(1980, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// Options configures how an intunewin package is created
+type Options struct {
+	// Reproducible sorts entries, normalizes file modes and uses ModTime for every entry
+	Reproducible bool
+	// ModTime is the modification time written to every entry in reproducible mode
+	ModTime time.Time
+	// EncryptionKey, MacKey and InitializationVector replace the randomly generated key material when set
+	EncryptionKey        []byte
+	MacKey               []byte
+	InitializationVector []byte
+	// KeyProvider supplies the key material of every package when set, see WithKeyProvider
+	KeyProvider KeyProvider
+	// CompressionLevel is the deflate level from 0 (store) to 9 (best), or -1 for the default
+	CompressionLevel int
+	// StoreExtensions lists lower case file extensions that are stored without compression
+	StoreExtensions map[string]bool
+	// Progress receives the bytes walked, compressed and encrypted
+	Progress progress.Reporter
+	// Logger receives diagnostic messages
+	Logger *slog.Logger
+	// BuildInfo is embedded in the package when set, completed with the creation time and options
+	BuildInfo *buildinfo.Info
+	// TempDir is where intermediate data larger than SpillThreshold is written, os.TempDir when empty
+	TempDir string
+	// SpillThreshold is the size above which intermediate data is written to a temporary file instead of memory
+	SpillThreshold int64
+	// MemoryLimit caps the intermediate data held in memory by all buffers sharing it, no cap when nil
+	MemoryLimit *spill.Limit
+	// Threads is the number of files read and compressed concurrently
+	Threads int
+	// Symlinks selects how symbolic links in the source folder are packed
+	Symlinks SymlinkPolicy
+	// POSIXModes records the Unix mode of every entry in addition to its MS-DOS attributes
+	POSIXModes bool
+	// NoDataDescriptors writes the CRC32 and sizes of every entry in its local header
+	NoDataDescriptors bool
+	// PruneEmptyDirs leaves out folders that have no file below them
+	PruneEmptyDirs bool
+	// Exclude lists patterns of entries left out of the package, see WithExclude
+	Exclude []string
+	// SkipSetupFileCheck packs content that does not contain the setup file
+	SkipSetupFileCheck bool
+	// ToolVersion is recorded in Detection.xml instead of metadata.ToolVersion when set
+	ToolVersion string
+	// Description is recorded in Detection.xml when set
+	Description string
+	// CatalogFolder holds signed .cat files added to the package metadata, see WithCatalogFolder
+	CatalogFolder string
+	// Summary receives the file count, content size and digest of the written package when set
+	Summary *Summary
+	// RetainKeys keeps the encryption info in Summary.ApplicationInfo
+	RetainKeys bool
+	// OmitEncryptionInfo leaves the encryption info out of the packaged Detection.xml
+	OmitEncryptionInfo bool
+	// CaseConflicts selects how entries whose paths differ only in case are packed
+	CaseConflicts CaseConflictPolicy
+	// InvalidNames selects how entries whose names Windows cannot create are packed
+	InvalidNames InvalidNamePolicy
+	// Additions are files and folders packed next to the content of the source, see WithAdd
+	Additions []Addition
+	// PrePackHooks run before a source folder is packed, see WithPrePackHook
+	PrePackHooks []Hook
+	// PostPackHooks run after a source folder has been packed, see WithPostPackHook
+	PostPackHooks []Hook
+}
+
+// Option configures Options
+type Option func(*Options)
+
+// WithReproducible makes packing deterministic: entries are sorted, file modes are
+// normalized and every timestamp is set to modTime. Combine with WithEncryptionKeys
+// to get byte-identical output for the same input.
+func WithReproducible(modTime time.Time) Option {
+	return func(o *Options) {
+		o.Reproducible = true
+		o.ModTime = modTime
+	}
+}
+
+// WithEncryptionKeys uses the given key material instead of generating random keys
+func WithEncryptionKeys(encryptionKey, macKey, iv []byte) Option {
+	return func(o *Options) {
+		o.EncryptionKey = encryptionKey
+		o.MacKey = macKey
+		o.InitializationVector = iv
+	}
+}
+
+// KeyProvider returns the key material of a package: a 32-byte encryption key, a 32-byte
+// MAC key and a 16-byte initialization vector
+type KeyProvider func() (encryptionKey, macKey, iv []byte, err error)
+
+// WithKeyProvider calls provider for the key material of every package instead of generating
+// random keys, such as to take keys from a hardware security module. Unlike WithEncryptionKeys,
+// each package gets fresh keys when options are reused for several packages. The keys belong
+// to the provider and are not cleared after packing.
+func WithKeyProvider(provider KeyProvider) Option {
+	return func(o *Options) {
+		o.KeyProvider = provider
+	}
+}
+
+// WithRetainKeys keeps the encryption info, including the keys, in Summary.ApplicationInfo.
+// By default it is left out so the key material does not outlive packing.
+func WithRetainKeys() Option {
+	return func(o *Options) {
+		o.RetainKeys = true
+	}
+}
+
+// WithOmitEncryptionInfo writes Detection.xml without the encryption info, for keys kept
+// apart from the package such as in a key file or a key vault. The package can then only be
+// unpacked with those keys; combine it with WithRetainKeys and WithSummary to get them.
+func WithOmitEncryptionInfo() Option {
+	return func(o *Options) {
+		o.OmitEncryptionInfo = true
+	}
+}
+
+// WithCompressionLevel sets the deflate level from 0 (no compression) to 9 (best compression)
+func WithCompressionLevel(level int) Option {
+	return func(o *Options) {
+		o.CompressionLevel = level
+	}
+}
+
+// WithStoreExtensions replaces the list of file extensions (such as ".msi") that are
+// stored without compression because their content is already compressed.
+// Pass no extensions to deflate every file.
+func WithStoreExtensions(exts ...string) Option {
+	return func(o *Options) {
+		o.StoreExtensions = make(map[string]bool, len(exts))
+		for _, ext := range exts {
+			o.StoreExtensions[normalizeExtension(ext)] = true
+		}
+	}
+}
+
+// WithProgress reports the progress of walking, compressing and encrypting to reporter
+func WithProgress(reporter progress.Reporter) Option {
+	return func(o *Options) {
+		o.Progress = reporter
+	}
+}
+
+// WithLogger sends diagnostic messages to logger
+func WithLogger(logger *slog.Logger) Option {
+	return func(o *Options) {
+		o.Logger = logger
+	}
+}
+
+// WithBuildInfo embeds info, completed with the creation time and the pack options, in the package
+func WithBuildInfo(info *buildinfo.Info) Option {
+	return func(o *Options) {
+		o.BuildInfo = info
+	}
+}
+
+// WithTempDir writes temporary files to dir instead of the default temporary directory
+func WithTempDir(dir string) Option {
+	return func(o *Options) {
+		o.TempDir = dir
+	}
+}
+
+// WithSpillThreshold keeps intermediate data of up to threshold bytes in memory and
+// writes larger data to temporary files. A threshold of 0 always uses temporary files.
+func WithSpillThreshold(threshold int64) Option {
+	return func(o *Options) {
+		o.SpillThreshold = threshold
+	}
+}
+
+// WithMemoryLimit writes intermediate data to temporary files once the buffers sharing
+// limit hold its maximum in memory, whatever the spill threshold
+func WithMemoryLimit(limit *spill.Limit) Option {
+	return func(o *Options) {
+		o.MemoryLimit = limit
+	}
+}
+
+// WithThreads reads and compresses up to n files concurrently. The output does not
+// depend on n. The default, 0, uses the number of CPUs.
+func WithThreads(n int) Option {
+	return func(o *Options) {
+		o.Threads = n
+	}
+}
+
+// WithSymlinks selects how symbolic links in the source folder are packed.
+// The default is SymlinkFollow.
+func WithSymlinks(policy SymlinkPolicy) Option {
+	return func(o *Options) {
+		o.Symlinks = policy
+	}
+}
+
+// WithPOSIXModes records the Unix mode of every entry, such as the executable bit, so that
+// unpacking on Linux or macOS restores it. By default only MS-DOS attributes (read-only,
+// directory and archive) are written, as by the Microsoft tool.
+func WithPOSIXModes() Option {
+	return func(o *Options) {
+		o.POSIXModes = true
+	}
+}
+
+// WithNoDataDescriptors writes the CRC32 and sizes of every entry in its local header, as the
+// Microsoft tool does, instead of in a data descriptor after the entry, for consumers that
+// mishandle data descriptors. Each entry is compressed to a temporary buffer first. Archives
+// read with PackZipTo are packed as is.
+func WithNoDataDescriptors() Option {
+	return func(o *Options) {
+		o.NoDataDescriptors = true
+	}
+}
+
+// WithExclude leaves entries of the source folder matching any of patterns out of the
+// package; archives read with PackZipTo and PackTarTo are packed as is. A pattern
+// without a slash matches the name of a file or folder at any depth, otherwise it
+// matches the slash separated path relative to the source folder, ignoring a leading
+// slash; both use the syntax of path.Match. Excluding a folder excludes everything below it.
+func WithExclude(patterns ...string) Option {
+	return func(o *Options) {
+		o.Exclude = append(o.Exclude, patterns...)
+	}
+}
+
+// WithPruneEmptyDirs leaves out folders of the source that have no file below them, for a
+// minimal package; archives read with PackZipTo and PackTarTo are packed as is. By default
+// empty folders are packed, for installers that expect the folder structure to exist.
+func WithPruneEmptyDirs() Option {
+	return func(o *Options) {
+		o.PruneEmptyDirs = true
+	}
+}
+
+// WithToolVersion records version as the ToolVersion in Detection.xml instead of
+// metadata.ToolVersion, such as to match the release of the Microsoft tool a pipeline
+// used before. It must be dotted numbers like 1.8.6.0; a major version other than 1 is
+// packed with a warning.
+func WithToolVersion(version string) Option {
+	return func(o *Options) {
+		o.ToolVersion = version
+	}
+}
+
+// WithDescription records description in Detection.xml
+func WithDescription(description string) Option {
+	return func(o *Options) {
+		o.Description = description
+	}
+}
+
+// newBuffer returns a buffer for intermediate data named after pattern
+func (o *Options) newBuffer(pattern string) *spill.Buffer {
+	return spill.NewLimited(o.TempDir, o.SpillThreshold, pattern, o.MemoryLimit)
+}
+
+// DefaultStoreExtensions returns the extensions of already compressed formats
+// that are stored without compression by default
+func DefaultStoreExtensions() []string {
+	return []string{
+		".7z", ".appx", ".appxbundle", ".bz2", ".cab", ".docx", ".gif", ".gz",
+		".intunewin", ".jar", ".jpeg", ".jpg", ".msi", ".msix", ".msixbundle",
+		".msp", ".mp3", ".mp4", ".nupkg", ".png", ".pptx", ".rar", ".tgz",
+		".wim", ".xlsx", ".xz", ".zip", ".zst",
+	}
+}
+
+// normalizeExtension lower cases ext and makes sure it starts with a dot
+func normalizeExtension(ext string) string {
+	ext = strings.ToLower(ext)
+	if !strings.HasPrefix(ext, ".") {
+		ext = "." + ext
+	}
+	return ext
+}
+
+// newOptions applies opts over the defaults and validates the result
+func newOptions(opts []Option) (*Options, error) {
+	o := &Options{
+		CompressionLevel: flate.DefaultCompression,
+		Progress:         progress.Nop,
+		SpillThreshold:   spill.DefaultThreshold,
+		Symlinks:         SymlinkFollow,
+		CaseConflicts:    CaseConflictError,
+		InvalidNames:     InvalidNameError,
+	}
+	WithStoreExtensions(DefaultStoreExtensions()...)(o)
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.Logger == nil {
+		o.Logger = slog.New(slog.DiscardHandler)
+	}
+	if o.Progress == nil {
+		o.Progress = progress.Nop
+	}
+	if o.Reproducible && o.ModTime.Before(zipEpoch) {
+		o.ModTime = zipEpoch
+	}
+	if o.CompressionLevel < flate.DefaultCompression || o.CompressionLevel > flate.BestCompression {
+		return nil, fmt.Errorf("compression level must be between 0 and 9, got %d", o.CompressionLevel)
+	}
+	switch o.Symlinks {
+	case SymlinkFollow, SymlinkSkip, SymlinkError:
+	default:
+		return nil, fmt.Errorf("invalid symlink policy %q", o.Symlinks)
+	}
+	switch o.CaseConflicts {
+	case CaseConflictError, CaseConflictFirst:
+	default:
+		return nil, fmt.Errorf("invalid case conflict policy %q", o.CaseConflicts)
+	}
+	switch o.InvalidNames {
+	case InvalidNameError, InvalidNameWarn:
+	default:
+		return nil, fmt.Errorf("invalid policy for invalid names %q", o.InvalidNames)
+	}
+	for _, pattern := range o.Exclude {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid exclude pattern %q: %w", pattern, err)
+		}
+	}
+	if o.KeyProvider != nil && (o.EncryptionKey != nil || o.MacKey != nil || o.InitializationVector != nil) {
+		return nil, errors.New("encryption keys and a key provider cannot be combined")
+	}
+	if o.Threads < 0 {
+		return nil, fmt.Errorf("threads must not be negative, got %d", o.Threads)
+	}
+	if o.Threads == 0 {
+		o.Threads = runtime.NumCPU()
+	}
+	if err := o.validateAdditions(); err != nil {
+		return nil, err
+	}
+	if o.ToolVersion != "" {
+		known, err := metadata.CheckToolVersion(o.ToolVersion)
+		if err != nil {
+			return nil, err //nolint:wrapcheck // the error names the version
+		}
+		if !known {
+			o.Logger.Warn("tool version has a major version Intune is not known to accept", "toolVersion", o.ToolVersion)
+		}
+	}
+	return o, nil
+}
+
+// excluded reports whether the entry at archivePath matches an exclude pattern
+func (o *Options) excluded(archivePath string) bool {
+	name := path.Base(archivePath)
+	for _, pattern := range o.Exclude {
+		target := name
+		if strings.Contains(pattern, "/") {
+			target = archivePath
+		}
+		if matched, _ := path.Match(strings.TrimPrefix(pattern, "/"), target); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// methodFor returns the zip compression method for the entry name
+func (o *Options) methodFor(name string) uint16 {
+	if o.StoreExtensions[strings.ToLower(path.Ext(name))] {
+		return zip.Store
+	}
+	return zip.Deflate
+}
+
+// newDeflateWriter creates a zip writer that deflates with the configured compression level
+func (o *Options) newDeflateWriter(w io.Writer) *zip.Writer {
+	zipWriter := zip.NewWriter(w)
+	if o.CompressionLevel != flate.DefaultCompression {
+		level := o.CompressionLevel
+		zipWriter.RegisterCompressor(zip.Deflate, func(out io.Writer) (io.WriteCloser, error) {
+			return flate.NewWriter(out, level)
+		})
+	}
+	return zipWriter
+}
+
+// encryptionKeys returns the caller supplied key material, or generates new keys
+func (o *Options) encryptionKeys() (encKey, macKey, iv []byte, err error) {
+	if o.KeyProvider != nil {
+		encKey, macKey, iv, err = o.KeyProvider()
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to get encryption keys: %w", err)
+		}
+		if err := crypto.ValidateKeys(encKey, macKey, iv); err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to use provided encryption keys: %w", err)
+		}
+		return encKey, macKey, iv, nil
+	}
+	if o.EncryptionKey == nil && o.MacKey == nil && o.InitializationVector == nil {
+		encKey, macKey, iv, err = crypto.GenerateKeys()
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to generate encryption keys: %w", err)
+		}
+		return encKey, macKey, iv, nil
+	}
+
+	if err := crypto.ValidateKeys(o.EncryptionKey, o.MacKey, o.InitializationVector); err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to use custom encryption keys: %w", err)
+	}
+	return o.EncryptionKey, o.MacKey, o.InitializationVector, nil
+}
+
+// describe lists the options that affect the output for the build information.
+// Key material is never included.
+func (o *Options) describe() map[string]string {
+	exts := make([]string, 0, len(o.StoreExtensions))
+	for ext := range o.StoreExtensions {
+		exts = append(exts, ext)
+	}
+	sort.Strings(exts)
+
+	return map[string]string{
+		"reproducible":     strconv.FormatBool(o.Reproducible),
+		"compressionLevel": strconv.Itoa(o.CompressionLevel),
+		"storeExtensions":  strings.Join(exts, ","),
+		"customKeys":       strconv.FormatBool(o.EncryptionKey != nil || o.KeyProvider != nil),
+	}
+}
+
+// modTime returns the timestamp to record for an entry modified at t
+func (o *Options) modTime(t time.Time) time.Time {
+	if o.Reproducible {
+		return o.ModTime.UTC()
+	}
+	return t
+}
+
+// fileMode returns the mode to record for an entry with mode m
+func (o *Options) fileMode(m os.FileMode) os.FileMode {
+	if !o.Reproducible {
+		return m
+	}
+	// Only keep the executable bit so the umask of the build machine doesn't matter
+	switch {
+	case m.IsDir():
+		return os.ModeDir | 0755
+	case m&0111 != 0:
+		return 0755
+	default:
+		return 0644
+	}
+}
+
+// SourceDateEpoch returns the time given by the SOURCE_DATE_EPOCH environment variable,
+// or the zip epoch (1980-01-01) when it is not set.
+// See https://reproducible-builds.org/specs/source-date-epoch/
+func SourceDateEpoch() (time.Time, error) {
+	value := os.Getenv("SOURCE_DATE_EPOCH")
+	if value == "" {
+		return zipEpoch, nil
+	}
+	seconds, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid SOURCE_DATE_EPOCH %q: %w", value, err)
+	}
+	return time.Unix(seconds, 0).UTC(), nil
+}
@@ -3,46 +3,142 @@ package pack
 import (
 	"archive/zip"
 	"bytes"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"sort"
 	"time"
 
+	"github.com/kenchan0130/intunewin/internal/buildinfo"
 	"github.com/kenchan0130/intunewin/internal/crypto"
 	"github.com/kenchan0130/intunewin/internal/metadata"
+	"github.com/kenchan0130/intunewin/internal/pathutil"
+	"github.com/kenchan0130/intunewin/internal/progress"
+	"github.com/kenchan0130/intunewin/internal/spill"
 )
 
+const (
+	detectionXMLPath = "IntuneWinPackage/Metadata/Detection.xml"
+	contentsPath     = "IntuneWinPackage/Contents/IntunePackage.intunewin"
+)
+
+var (
+	// ErrSourceNotFound is returned when the source folder does not exist
+	ErrSourceNotFound = errors.New("source folder does not exist")
+	// ErrSourceNotDirectory is returned when the source path is not a directory
+	ErrSourceNotDirectory = errors.New("source path is not a directory")
+)
+
+// fileEntry is a file or directory to be added to the content zip
+type fileEntry struct {
+	// Path is the slash-separated path inside the zip
+	Path string
+	// Source is the path of the file on disk
+	Source   string
+	Size     int64
+	Mode     os.FileMode
+	IsDir    bool
+	Modified time.Time
+}
+
 // PackReaderFromZip creates an intunewin package from a zip stream.
 // zipReader should contain a zip archive.
 // name is the application name for metadata.
 // setupFile is the setup file name within the content file.
 // Returns an io.Reader containing the intunewin package.
-func PackReaderFromZip(zipReader io.Reader, name, setupFile string) (io.Reader, error) {
-	// Read all zip data
-	sourceData, err := io.ReadAll(zipReader)
+// Large zip data is spilled to temporary files while encrypting, but the returned
+// package is held in memory; use PackZipTo to stream it instead.
+func PackReaderFromZip(zipReader io.Reader, name, setupFile string, opts ...Option) (io.Reader, error) {
+	outputBuf := new(bytes.Buffer)
+	if err := PackZipTo(zipReader, outputBuf, name, setupFile, opts...); err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(outputBuf.Bytes()), nil
+}
+
+// PackZipTo reads a zip archive from zipReader, such as standard input, and writes an
+// intunewin package of it to w
+func PackZipTo(zipReader io.Reader, w io.Writer, name, setupFile string, opts ...Option) error {
+	o, err := newOptions(opts)
+	if err != nil {
+		return fmt.Errorf("invalid options: %w", err)
+	}
+	info := HookInfo{Stage: HookPrePack, Name: name, SetupFile: setupFile}
+	if err := o.runPrePackHooks(info); err != nil {
+		return err
+	}
+
+	// Buffer the zip data, spilling large archives to a temporary file
+	content := o.newBuffer("intunewin-content-*.zip")
+	defer content.Close()
+	if _, err := io.Copy(content, zipReader); err != nil {
+		return fmt.Errorf("failed to read zip data: %w", err)
+	}
+
+	encrypted := o.newBuffer("intunewin-encrypted-*")
+	defer encrypted.Close()
+
+	if err := writePackage(w, content, encrypted, name, setupFile, o); err != nil {
+		return err
+	}
+	return o.runPostPackHooks(info)
+}
+
+// writePackage encrypts the zip archive in content and writes the intunewin package to output.
+// encrypted is scratch space for the encrypted content; when it is seekable (such as a
+// temporary file) the content is streamed instead of being held in memory.
+func writePackage(output io.Writer, content *spill.Buffer, encrypted io.ReadWriter, name, setupFile string, o *Options) error {
+	if err := checkSetupFile(content, setupFile, o); err != nil {
+		return err
+	}
+	var catalogs []string
+	if o.CatalogFolder != "" {
+		var err error
+		if catalogs, err = catalogFiles(o.CatalogFolder); err != nil {
+			return err
+		}
+	}
+
+	unencryptedSize, err := content.Seek(0, io.SeekEnd)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read zip data: %w", err)
+		return fmt.Errorf("failed to determine content size: %w", err)
+	}
+	if _, err := content.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to rewind content: %w", err)
 	}
-	unencryptedSize := int64(len(sourceData))
 
 	// Compute file digest before encryption
-	fileDigest, err := crypto.ComputeFileDigest(bytes.NewReader(sourceData))
+	fileDigest, err := crypto.ComputeFileDigest(content)
 	if err != nil {
-		return nil, fmt.Errorf("failed to compute file digest: %w", err)
+		return fmt.Errorf("failed to compute file digest: %w", err)
+	}
+	if _, err := content.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to rewind content: %w", err)
 	}
 
-	// Generate encryption keys
-	encKey, macKey, iv, err := crypto.GenerateKeys()
+	encKey, macKey, iv, err := o.encryptionKeys()
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate encryption keys: %w", err)
+		return err
+	}
+	if o.EncryptionKey == nil && o.KeyProvider == nil {
+		// Caller supplied keys belong to the caller, generated ones are not needed after packing
+		defer crypto.Zero(encKey, macKey, iv)
 	}
 
+	o.Logger.Debug("encrypting content", "size", unencryptedSize)
+
 	// Encrypt data
-	encryptedBuf := new(bytes.Buffer)
-	mac, err := crypto.Encrypt(bytes.NewReader(sourceData), encryptedBuf, encKey, macKey, iv)
+	counter := progress.NewCounter(o.Progress, progress.Encrypt, unencryptedSize)
+	mac, err := crypto.Encrypt(counter.Reader(content), encrypted, encKey, macKey, iv)
 	if err != nil {
-		return nil, fmt.Errorf("failed to encrypt data: %w", err)
+		return fmt.Errorf("failed to encrypt data: %w", err)
+	}
+	if seeker, ok := encrypted.(io.Seeker); ok {
+		if _, err := seeker.Seek(0, io.SeekStart); err != nil {
+			return fmt.Errorf("failed to rewind encrypted data: %w", err)
+		}
 	}
 
 	// Create encryption info
@@ -58,207 +154,358 @@ func PackReaderFromZip(zipReader io.Reader, name, setupFile string) (io.Reader,
 
 	// Create ApplicationInfo with XML metadata
 	appInfo := metadata.NewApplicationInfo(name, setupFile, unencryptedSize, encInfo)
-	metaXML, err := appInfo.ToXML()
+	appInfo.Description = metadata.NormalizeText(o.Description)
+	if o.ToolVersion != "" {
+		appInfo.ToolVersion = o.ToolVersion
+	}
+	packagedInfo := appInfo
+	if o.OmitEncryptionInfo {
+		packagedInfo = appInfo.WithoutEncryptionInfo()
+	}
+	metaXML, err := packagedInfo.ToXML()
 	if err != nil {
-		return nil, fmt.Errorf("failed to create metadata XML: %w", err)
+		return fmt.Errorf("failed to create metadata XML: %w", err)
 	}
 
 	// Create final intunewin package (zip archive with proper structure)
-	outputBuf := new(bytes.Buffer)
-	outputZipWriter := zip.NewWriter(outputBuf)
+	output, digest := summarize(output, content, unencryptedSize, o)
+	outputZipWriter := o.newZipWriter(output)
 
 	// Use current time for all files
-	now := time.Now()
+	now := o.modTime(time.Now())
 
 	// Add Detection.xml at IntuneWinPackage/Metadata/Detection.xml
 	metaHeader := &zip.FileHeader{
-		Name:     "IntuneWinPackage/Metadata/Detection.xml",
+		Name:     detectionXMLPath,
 		Method:   zip.Deflate,
 		Modified: now,
 	}
 	metaWriter, err := outputZipWriter.CreateHeader(metaHeader)
 	if err != nil {
 		outputZipWriter.Close()
-		return nil, fmt.Errorf("failed to create metadata entry: %w", err)
+		return fmt.Errorf("failed to create metadata entry: %w", err)
 	}
 	if _, err := metaWriter.Write(metaXML); err != nil {
 		outputZipWriter.Close()
-		return nil, fmt.Errorf("failed to write metadata: %w", err)
+		return fmt.Errorf("failed to write metadata: %w", err)
+	}
+
+	if o.BuildInfo != nil {
+		if err := writeBuildInfo(outputZipWriter, now, o); err != nil {
+			outputZipWriter.Close()
+			return err
+		}
+	}
+
+	if err := writeCatalogs(outputZipWriter, catalogs, now, o); err != nil {
+		outputZipWriter.Close()
+		return err
 	}
 
 	// Add encrypted contents at IntuneWinPackage/Contents/IntunePackage.intunewin
 	contentsHeader := &zip.FileHeader{
-		Name:     "IntuneWinPackage/Contents/IntunePackage.intunewin",
+		Name:     contentsPath,
 		Method:   zip.Deflate,
 		Modified: now,
 	}
 	contentsWriter, err := outputZipWriter.CreateHeader(contentsHeader)
 	if err != nil {
 		outputZipWriter.Close()
-		return nil, fmt.Errorf("failed to create contents entry: %w", err)
+		return fmt.Errorf("failed to create contents entry: %w", err)
 	}
-	if _, err := contentsWriter.Write(encryptedBuf.Bytes()); err != nil {
+	if _, err := io.Copy(contentsWriter, encrypted); err != nil {
 		outputZipWriter.Close()
-		return nil, fmt.Errorf("failed to write contents: %w", err)
+		return fmt.Errorf("failed to write contents: %w", err)
 	}
 
 	if err := outputZipWriter.Close(); err != nil {
-		return nil, fmt.Errorf("failed to close zip writer: %w", err)
+		return fmt.Errorf("failed to close zip writer: %w", err)
 	}
+	finishSummary(digest, appInfo, o)
 
-	return bytes.NewReader(outputBuf.Bytes()), nil
+	o.Logger.Debug("wrote intunewin package", "name", name, "setupFile", setupFile)
+	return nil
 }
 
-// Pack creates an intunewin file from a source folder
-func Pack(sourceFolder, outputFile string) error {
-	// Check if source folder exists
-	info, err := os.Stat(sourceFolder)
+// writeBuildInfo adds the build information entry to the package
+func writeBuildInfo(zipWriter *zipWriter, now time.Time, o *Options) error {
+	info := *o.BuildInfo
+	info.CreatedAt = now.UTC()
+	info.Options = o.describe()
+
+	data, err := info.ToJSON()
 	if err != nil {
-		if os.IsNotExist(err) {
-			return fmt.Errorf("source folder does not exist: %s", sourceFolder)
-		}
-		return fmt.Errorf("failed to access source folder: %w", err)
+		return fmt.Errorf("failed to create build info: %w", err)
 	}
-	if !info.IsDir() {
-		return fmt.Errorf("source path is not a directory: %s", sourceFolder)
+
+	writer, err := zipWriter.CreateHeader(&zip.FileHeader{
+		Name:     buildinfo.Path,
+		Method:   zip.Deflate,
+		Modified: now,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create build info entry: %w", err)
+	}
+	if _, err := writer.Write(data); err != nil {
+		return fmt.Errorf("failed to write build info: %w", err)
 	}
+	return nil
+}
 
-	// Create output directory if it doesn't exist
+// Pack creates an intunewin file from a source folder.
+// The application name and setup file are taken from the name of the folder, so the
+// setup file is not checked; use PackWithInfo to declare the setup file.
+func Pack(sourceFolder, outputFile string, opts ...Option) error {
+	// Determine name and setup file from source folder
+	name := filepath.Base(sourceFolder)
+	setupFile := name // Default to folder name, can be customized
+
+	return PackWithInfo(sourceFolder, outputFile, name, setupFile, append(opts, WithSkipSetupFileCheck())...)
+}
+
+// PackWithInfo creates an intunewin file from a source folder using the given
+// application name and setup file for the metadata.
+// ErrSetupFileNotFound is returned when setupFile, a path relative to the source folder,
+// is not a file in it.
+// Intermediate data larger than the spill threshold is written to temporary files,
+// so the size of the source folder is not limited by available memory.
+func PackWithInfo(sourceFolder, outputFile, name, setupFile string, opts ...Option) error {
+	o, err := newOptions(opts)
+	if err != nil {
+		return fmt.Errorf("invalid options: %w", err)
+	}
+	if err := checkSourceFolder(sourceFolder); err != nil {
+		return err
+	}
+	info := HookInfo{Stage: HookPrePack, SourceFolder: sourceFolder, OutputFile: outputFile, Name: name, SetupFile: setupFile}
+	return packToFile(outputFile, info, o, func() (*spill.Buffer, error) {
+		return buildContentZip(sourceFolder, o)
+	})
+}
+
+// packToFile runs the hooks around writing the content zip returned by build to outputFile
+func packToFile(outputFile string, info HookInfo, o *Options, build func() (*spill.Buffer, error)) error {
+	if err := o.runPrePackHooks(info); err != nil {
+		return err
+	}
+
+	zipFile, err := build()
+	if err != nil {
+		return err
+	}
+	defer zipFile.Close()
+
+	encryptedFile := o.newBuffer("intunewin-encrypted-*")
+	defer encryptedFile.Close()
+
+	err = WriteFile(outputFile, func(w io.Writer) error {
+		if err := writePackage(w, zipFile, encryptedFile, info.Name, info.SetupFile, o); err != nil {
+			return fmt.Errorf("failed to create intunewin package: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return o.runPostPackHooks(info)
+}
+
+// WriteFile writes the package that write produces to outputFile, creating its folder.
+// The package is written to a temporary file next to the output so that a failed pack
+// never leaves a truncated package behind or destroys the one being replaced.
+func WriteFile(outputFile string, write func(w io.Writer) error) error {
 	outputDir := filepath.Dir(outputFile)
-	if err := os.MkdirAll(outputDir, 0755); err != nil {
+	if err := os.MkdirAll(pathutil.Long(outputDir), 0755); err != nil {
 		return fmt.Errorf("failed to create output directory: %w", err)
 	}
 
-	// Collect files from folder into FileEntry slice
-	var files []struct {
-		Path     string
-		Content  io.Reader
-		Mode     os.FileMode
-		IsDir    bool
-		Modified time.Time
+	outFile, err := os.CreateTemp(pathutil.Long(outputDir), ".intunewin-pack-*")
+	if err != nil {
+		return fmt.Errorf("failed to create output file: %w", err)
 	}
-	err = filepath.Walk(sourceFolder, func(path string, fileInfo os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
+	defer os.Remove(outFile.Name())
+	defer outFile.Close()
 
-		// Get relative path
-		relPath, err := filepath.Rel(sourceFolder, path)
-		if err != nil {
-			return fmt.Errorf("failed to get relative path: %w", err)
-		}
+	if err := write(outFile); err != nil {
+		return err
+	}
 
-		// Skip root directory
-		if relPath == "." {
-			return nil
-		}
+	if err := outFile.Close(); err != nil {
+		return fmt.Errorf("failed to write output file: %w", err)
+	}
+	// Temporary files are private, packages get the mode os.Create would give them
+	if err := os.Chmod(outFile.Name(), 0644); err != nil { // #nosec G302 -- packages are not secret
+		return fmt.Errorf("failed to set output file mode: %w", err)
+	}
+	if err := os.Rename(outFile.Name(), pathutil.Long(outputFile)); err != nil {
+		return fmt.Errorf("failed to replace output file: %w", err)
+	}
+	return nil
+}
 
-		// Convert to slash path for zip
-		relPath = filepath.ToSlash(relPath)
-
-		if fileInfo.IsDir() {
-			// Add directory entry
-			files = append(files, struct {
-				Path     string
-				Content  io.Reader
-				Mode     os.FileMode
-				IsDir    bool
-				Modified time.Time
-			}{
-				Path:     relPath,
-				Mode:     fileInfo.Mode(),
-				IsDir:    true,
-				Modified: fileInfo.ModTime(),
-			})
-		} else {
-			// Read file content
-			content, err := os.ReadFile(path)
-			if err != nil {
-				return fmt.Errorf("failed to read file %s: %w", path, err)
-			}
+// PackTo writes an intunewin package of a source folder to w, such as standard output.
+// Like Pack, the application name and setup file default to the name of the folder.
+func PackTo(sourceFolder string, w io.Writer, opts ...Option) error {
+	name := filepath.Base(sourceFolder)
+	return PackWithInfoTo(sourceFolder, w, name, name, append(opts, WithSkipSetupFileCheck())...)
+}
 
-			files = append(files, struct {
-				Path     string
-				Content  io.Reader
-				Mode     os.FileMode
-				IsDir    bool
-				Modified time.Time
-			}{
-				Path:     relPath,
-				Content:  bytes.NewReader(content),
-				Mode:     fileInfo.Mode(),
-				IsDir:    false,
-				Modified: fileInfo.ModTime(),
-			})
+// PackWithInfoTo is like PackWithInfo but writes the package to w instead of a file
+func PackWithInfoTo(sourceFolder string, w io.Writer, name, setupFile string, opts ...Option) error {
+	o, err := newOptions(opts)
+	if err != nil {
+		return fmt.Errorf("invalid options: %w", err)
+	}
+	if err := checkSourceFolder(sourceFolder); err != nil {
+		return err
+	}
+	info := HookInfo{Stage: HookPrePack, SourceFolder: sourceFolder, Name: name, SetupFile: setupFile}
+	return packToWriter(w, info, o, func() (*spill.Buffer, error) {
+		return buildContentZip(sourceFolder, o)
+	})
+}
+
+// packToWriter runs the hooks around writing the content zip returned by build to w
+func packToWriter(w io.Writer, info HookInfo, o *Options, build func() (*spill.Buffer, error)) error {
+	if err := o.runPrePackHooks(info); err != nil {
+		return err
+	}
+
+	zipFile, err := build()
+	if err != nil {
+		return err
+	}
+	defer zipFile.Close()
+
+	encryptedFile := o.newBuffer("intunewin-encrypted-*")
+	defer encryptedFile.Close()
+
+	if err := writePackage(w, zipFile, encryptedFile, info.Name, info.SetupFile, o); err != nil {
+		return fmt.Errorf("failed to create intunewin package: %w", err)
+	}
+	return o.runPostPackHooks(info)
+}
+
+// checkSourceFolder returns ErrSourceNotFound or ErrSourceNotDirectory when sourceFolder
+// cannot be packed
+func checkSourceFolder(sourceFolder string) error {
+	info, err := os.Stat(pathutil.Long(sourceFolder))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("%w: %s", ErrSourceNotFound, sourceFolder)
 		}
+		return fmt.Errorf("failed to access source folder: %w", err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%w: %s", ErrSourceNotDirectory, sourceFolder)
+	}
+	return nil
+}
 
-		return nil
-	})
+// buildContentZip walks sourceFolder and returns the content zip in a buffer that
+// spills to a temporary file above the spill threshold. The caller must close it.
+func buildContentZip(sourceFolder string, o *Options) (*spill.Buffer, error) {
+	files, err := collectFiles(sourceFolder, o)
 	if err != nil {
-		return fmt.Errorf("failed to walk source folder: %w", err)
+		return nil, fmt.Errorf("failed to walk source folder: %w", err)
 	}
 
-	// Create zip from files
-	zipBuf := new(bytes.Buffer)
-	zipWriter := zip.NewWriter(zipBuf)
+	o.Logger.Debug("collected source files", "source", sourceFolder, "entries", len(files))
+	return zipFiles(files, o)
+}
+
+// zipFiles returns files as a content zip in a buffer that spills to a temporary file
+// above the spill threshold. The caller must close it.
+func zipFiles(files []fileEntry, o *Options) (*spill.Buffer, error) {
+	if o.Reproducible {
+		sort.Slice(files, func(i, j int) bool {
+			return files[i].Path < files[j].Path
+		})
+	}
 
+	zipFile := o.newBuffer("intunewin-content-*.zip")
+	if err := writeContentZip(zipFile, files, o); err != nil {
+		zipFile.Close()
+		return nil, err
+	}
+	return zipFile, nil
+}
+
+// writeContentZip writes files as a zip archive to w. Files are read and compressed
+// concurrently but written in order.
+func writeContentZip(w io.Writer, files []fileEntry, o *Options) error {
+	zipWriter := o.newZipWriter(w)
+
+	var total int64
 	for _, file := range files {
+		if !file.IsDir {
+			total += file.Size
+		}
+	}
+	compressed := progress.NewCounter(o.Progress, progress.Compress, total)
+
+	// A single thread writes directly, which avoids copying every entry
+	var c *compressor
+	if o.Threads > 1 {
+		c = startCompressor(files, compressed, o)
+		defer c.stop()
+	}
+
+	for i, file := range files {
 		if file.IsDir {
 			header := &zip.FileHeader{
 				Name:     file.Path + "/",
-				Modified: file.Modified,
+				Modified: o.modTime(file.Modified),
 			}
-			header.SetMode(file.Mode)
+			o.setMode(header, file.Mode)
 			_, err := zipWriter.CreateHeader(header)
 			if err != nil {
 				zipWriter.Close()
 				return fmt.Errorf("failed to create directory entry %s: %w", file.Path, err)
 			}
-		} else {
-			header := &zip.FileHeader{
-				Name:     file.Path,
-				Method:   zip.Deflate,
-				Modified: file.Modified,
-			}
-			header.SetMode(file.Mode)
+			continue
+		}
 
-			writer, err := zipWriter.CreateHeader(header)
-			if err != nil {
+		if c == nil {
+			if err := addFile(zipWriter, file, compressed, o); err != nil {
 				zipWriter.Close()
-				return fmt.Errorf("failed to create file entry %s: %w", file.Path, err)
+				return err
 			}
+			continue
+		}
 
-			if _, err := io.Copy(writer, file.Content); err != nil {
-				zipWriter.Close()
-				return fmt.Errorf("failed to write file content %s: %w", file.Path, err)
-			}
+		result := c.next(i)
+		if result.err != nil {
+			c.release()
+			zipWriter.Close()
+			return result.err
+		}
+		err := copyEntry(zipWriter, result.archive)
+		result.archive.Close()
+		c.release()
+		if err != nil {
+			zipWriter.Close()
+			return fmt.Errorf("failed to write file content %s: %w", file.Path, err)
 		}
 	}
 
 	if err := zipWriter.Close(); err != nil {
 		return fmt.Errorf("failed to close zip writer: %w", err)
 	}
+	return nil
+}
 
-	// Determine name and setup file from source folder
-	name := filepath.Base(sourceFolder)
-	setupFile := name // Default to folder name, can be customized
-
-	// Use PackReaderFromZip to create intunewin package
-	intunewinReader, err := PackReaderFromZip(bytes.NewReader(zipBuf.Bytes()), name, setupFile)
-	if err != nil {
-		return fmt.Errorf("failed to create intunewin package: %w", err)
-	}
-
-	// Write to output file
-	outFile, err := os.Create(outputFile)
+// copyFile streams the content of the file at path to w, adding the bytes read to counter
+func copyFile(w io.Writer, path string, counter *progress.Counter) error {
+	f, err := os.Open(pathutil.Long(path)) // #nosec G304 -- path comes from walking the source folder
 	if err != nil {
-		return fmt.Errorf("failed to create output file: %w", err)
+		return fmt.Errorf("failed to read file %s: %w", path, err)
 	}
-	defer outFile.Close()
+	defer f.Close()
 
-	if _, err := io.Copy(outFile, intunewinReader); err != nil {
-		return fmt.Errorf("failed to write output file: %w", err)
+	if _, err := io.Copy(w, counter.Reader(f)); err != nil {
+		return fmt.Errorf("failed to read file %s: %w", path, err)
 	}
-
 	return nil
 }
@@ -1,10 +1,23 @@
 package pack
 
 import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
+	"github.com/kenchan0130/intunewin/internal/buildinfo"
+	"github.com/kenchan0130/intunewin/internal/crypto"
+	"github.com/kenchan0130/intunewin/internal/metadata"
+	"github.com/kenchan0130/intunewin/internal/pathutil"
+	"github.com/kenchan0130/intunewin/internal/progress"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -38,8 +51,7 @@ func TestPackNonExistentSource(t *testing.T) {
 	outputFile := filepath.Join(tempDir, "output.intunewin")
 
 	err := Pack(sourceDir, outputFile)
-	assert.Error(t, err)
-	assert.Contains(t, err.Error(), "does not exist")
+	assert.ErrorIs(t, err, ErrSourceNotFound)
 }
 
 func TestPackFileInsteadOfDirectory(t *testing.T) {
@@ -50,6 +62,492 @@ func TestPackFileInsteadOfDirectory(t *testing.T) {
 	outputFile := filepath.Join(tempDir, "output.intunewin")
 
 	err := Pack(sourceFile, outputFile)
-	assert.Error(t, err)
+	assert.ErrorIs(t, err, ErrSourceNotDirectory)
 	assert.Contains(t, err.Error(), "not a directory")
 }
+
+func TestPackReproducible(t *testing.T) {
+	tempDir := t.TempDir()
+	sourceDir := filepath.Join(tempDir, "source")
+	require.NoError(t, os.MkdirAll(filepath.Join(sourceDir, "subdir"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(sourceDir, "b.txt"), []byte("b"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(sourceDir, "subdir", "a.txt"), []byte("a"), 0644))
+
+	encKey := bytes.Repeat([]byte{1}, 32)
+	macKey := bytes.Repeat([]byte{2}, 32)
+	iv := bytes.Repeat([]byte{3}, 16)
+	opts := []Option{
+		WithReproducible(time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)),
+		WithEncryptionKeys(encKey, macKey, iv),
+	}
+
+	first := filepath.Join(tempDir, "first.intunewin")
+	require.NoError(t, Pack(sourceDir, first, opts...))
+
+	// Touching files and changing modes must not change the output
+	later := time.Now().Add(time.Hour)
+	require.NoError(t, os.Chtimes(filepath.Join(sourceDir, "b.txt"), later, later))
+	require.NoError(t, os.Chmod(filepath.Join(sourceDir, "b.txt"), 0640))
+
+	second := filepath.Join(tempDir, "second.intunewin")
+	require.NoError(t, Pack(sourceDir, second, opts...))
+
+	firstData, err := os.ReadFile(first)
+	require.NoError(t, err)
+	secondData, err := os.ReadFile(second)
+	require.NoError(t, err)
+	assert.Equal(t, firstData, secondData)
+}
+
+func TestPackReaderFromZipInvalidKeys(t *testing.T) {
+	_, err := PackReaderFromZip(bytes.NewReader([]byte("data")), "test", "setup.exe", WithEncryptionKeys(make([]byte, 16), make([]byte, 32), make([]byte, 16)))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "encryption key must be 32 bytes")
+}
+
+func TestSourceDateEpoch(t *testing.T) {
+	t.Setenv("SOURCE_DATE_EPOCH", "")
+	epoch, err := SourceDateEpoch()
+	require.NoError(t, err)
+	assert.Equal(t, zipEpoch, epoch)
+
+	t.Setenv("SOURCE_DATE_EPOCH", "1700000000")
+	epoch, err = SourceDateEpoch()
+	require.NoError(t, err)
+	assert.Equal(t, time.Unix(1700000000, 0).UTC(), epoch)
+
+	t.Setenv("SOURCE_DATE_EPOCH", "yesterday")
+	_, err = SourceDateEpoch()
+	assert.Error(t, err)
+}
+
+func TestPackCompressionLevel(t *testing.T) {
+	tempDir := t.TempDir()
+	sourceDir := filepath.Join(tempDir, "source")
+	require.NoError(t, os.MkdirAll(sourceDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(sourceDir, "data.txt"), bytes.Repeat([]byte("compressible "), 10000), 0600))
+
+	stored := filepath.Join(tempDir, "stored.intunewin")
+	require.NoError(t, Pack(sourceDir, stored, WithCompressionLevel(0)))
+	best := filepath.Join(tempDir, "best.intunewin")
+	require.NoError(t, Pack(sourceDir, best, WithCompressionLevel(9)))
+
+	storedInfo, err := os.Stat(stored)
+	require.NoError(t, err)
+	bestInfo, err := os.Stat(best)
+	require.NoError(t, err)
+	assert.Greater(t, storedInfo.Size(), bestInfo.Size())
+}
+
+func TestPackInvalidCompressionLevel(t *testing.T) {
+	tempDir := t.TempDir()
+
+	err := Pack(tempDir, filepath.Join(tempDir, "out.intunewin"), WithCompressionLevel(10))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "compression level must be between 0 and 9")
+}
+
+func TestPackStoresCompressedFiles(t *testing.T) {
+	tempDir := t.TempDir()
+	sourceDir := filepath.Join(tempDir, "source")
+	require.NoError(t, os.MkdirAll(sourceDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(sourceDir, "setup.MSI"), []byte("msi"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(sourceDir, "install.ps1"), []byte("script"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(sourceDir, "data.bin"), []byte("data"), 0600))
+
+	tests := []struct {
+		name    string
+		opts    []Option
+		methods map[string]uint16
+	}{
+		{
+			name:    "Default extensions",
+			methods: map[string]uint16{"setup.MSI": zip.Store, "install.ps1": zip.Deflate, "data.bin": zip.Deflate},
+		},
+		{
+			name:    "Custom extensions",
+			opts:    []Option{WithStoreExtensions("bin")},
+			methods: map[string]uint16{"setup.MSI": zip.Deflate, "install.ps1": zip.Deflate, "data.bin": zip.Store},
+		},
+		{
+			name:    "No extensions",
+			opts:    []Option{WithStoreExtensions()},
+			methods: map[string]uint16{"setup.MSI": zip.Deflate, "install.ps1": zip.Deflate, "data.bin": zip.Deflate},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encKey := bytes.Repeat([]byte{1}, 32)
+			macKey := bytes.Repeat([]byte{2}, 32)
+			iv := bytes.Repeat([]byte{3}, 16)
+			outputFile := filepath.Join(t.TempDir(), "out.intunewin")
+			require.NoError(t, Pack(sourceDir, outputFile, append(tt.opts, WithEncryptionKeys(encKey, macKey, iv))...))
+
+			methods := map[string]uint16{}
+			for _, f := range readInnerZip(t, outputFile, encKey, macKey).File {
+				methods[f.Name] = f.Method
+			}
+			assert.Equal(t, tt.methods, methods)
+		})
+	}
+}
+
+// readInnerZip decrypts the content of an intunewin file and opens it as a zip
+func readInnerZip(t *testing.T, intunewinFile string, encKey, macKey []byte) *zip.Reader {
+	t.Helper()
+
+	data := readInnerZipBytes(t, intunewinFile, encKey, macKey)
+	inner, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	require.NoError(t, err)
+	return inner
+}
+
+// readInnerZipBytes decrypts the content of an intunewin file
+func readInnerZipBytes(t *testing.T, intunewinFile string, encKey, macKey []byte) []byte {
+	t.Helper()
+
+	outer, err := zip.OpenReader(intunewinFile)
+	require.NoError(t, err)
+	defer outer.Close()
+
+	for _, f := range outer.File {
+		if f.Name != "IntuneWinPackage/Contents/IntunePackage.intunewin" {
+			continue
+		}
+		rc, err := f.Open()
+		require.NoError(t, err)
+		defer rc.Close()
+
+		decrypted := new(bytes.Buffer)
+		require.NoError(t, crypto.Decrypt(rc, decrypted, encKey, macKey))
+		return decrypted.Bytes()
+	}
+	t.Fatal("encrypted contents not found")
+	return nil
+}
+
+func TestPackManyEntries(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping zip64 entry count test in short mode")
+	}
+
+	// More entries than fit in the 16-bit count of a classic zip end record
+	const count = 70000
+	sourceDir := t.TempDir()
+	for i := range count {
+		require.NoError(t, os.WriteFile(filepath.Join(sourceDir, fmt.Sprintf("file%05d.txt", i)), nil, 0600))
+	}
+
+	encKey, macKey, iv, err := crypto.GenerateKeys()
+	require.NoError(t, err)
+
+	outputFile := filepath.Join(t.TempDir(), "many.intunewin")
+	require.NoError(t, Pack(sourceDir, outputFile, WithEncryptionKeys(encKey, macKey, iv)))
+
+	assert.Len(t, readInnerZip(t, outputFile, encKey, macKey).File, count)
+}
+
+func TestPackLargeContent(t *testing.T) {
+	if os.Getenv("INTUNEWIN_TEST_LARGE") == "" {
+		t.Skip("set INTUNEWIN_TEST_LARGE=1 to pack more than 4 GiB of content")
+	}
+
+	sourceDir := t.TempDir()
+	large, err := os.Create(filepath.Join(sourceDir, "large.bin"))
+	require.NoError(t, err)
+	// A sparse file keeps disk usage low while the packed content exceeds 4 GiB
+	require.NoError(t, large.Truncate(4<<30+512<<20))
+	require.NoError(t, large.Close())
+
+	outputFile := filepath.Join(t.TempDir(), "large.intunewin")
+	require.NoError(t, Pack(sourceDir, outputFile, WithStoreExtensions(".bin")))
+
+	outer, err := zip.OpenReader(outputFile)
+	require.NoError(t, err)
+	defer outer.Close()
+
+	for _, f := range outer.File {
+		if f.Name == contentsPath {
+			assert.Greater(t, f.UncompressedSize64, uint64(1<<32))
+			return
+		}
+	}
+	t.Fatal("encrypted contents not found")
+}
+
+func TestPackProgress(t *testing.T) {
+	sourceDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(sourceDir, "setup.exe"), bytes.Repeat([]byte("a"), 3000), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(sourceDir, "install.ps1"), bytes.Repeat([]byte("b"), 1000), 0600))
+
+	last := make(map[progress.Stage][2]int64)
+	var stages []progress.Stage
+	reporter := progress.ReporterFunc(func(stage progress.Stage, current, total int64) {
+		if len(stages) == 0 || stages[len(stages)-1] != stage {
+			stages = append(stages, stage)
+		}
+		last[stage] = [2]int64{current, total}
+	})
+
+	outputFile := filepath.Join(t.TempDir(), "test.intunewin")
+	require.NoError(t, Pack(sourceDir, outputFile, WithProgress(reporter)))
+
+	assert.Equal(t, []progress.Stage{progress.Walk, progress.Compress, progress.Encrypt}, stages)
+	assert.Equal(t, [2]int64{4000, 0}, last[progress.Walk])
+	assert.Equal(t, [2]int64{4000, 4000}, last[progress.Compress])
+	assert.Equal(t, last[progress.Encrypt][1], last[progress.Encrypt][0])
+	assert.Positive(t, last[progress.Encrypt][1])
+}
+
+func TestPackLogger(t *testing.T) {
+	sourceDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(sourceDir, "setup.msi"), []byte("msi"), 0600))
+
+	logs := new(bytes.Buffer)
+	logger := slog.New(slog.NewTextHandler(logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	outputFile := filepath.Join(t.TempDir(), "test.intunewin")
+	require.NoError(t, Pack(sourceDir, outputFile, WithLogger(logger)))
+
+	assert.Contains(t, logs.String(), `msg="adding file" path=setup.msi size=3 stored=true`)
+	assert.Contains(t, logs.String(), `msg="encrypting content"`)
+}
+
+func TestPackBuildInfo(t *testing.T) {
+	sourceDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(sourceDir, "setup.exe"), []byte("setup"), 0600))
+	modTime := time.Date(2024, time.May, 1, 12, 0, 0, 0, time.UTC)
+
+	outputFile := filepath.Join(t.TempDir(), "test.intunewin")
+	require.NoError(t, Pack(sourceDir, outputFile,
+		WithBuildInfo(buildinfo.New("1.2.3", false)),
+		WithReproducible(modTime),
+		WithCompressionLevel(9),
+	))
+
+	outer, err := zip.OpenReader(outputFile)
+	require.NoError(t, err)
+	defer outer.Close()
+
+	for _, f := range outer.File {
+		if f.Name != buildinfo.Path {
+			continue
+		}
+		rc, err := f.Open()
+		require.NoError(t, err)
+		defer rc.Close()
+		data := new(bytes.Buffer)
+		_, err = data.ReadFrom(rc)
+		require.NoError(t, err)
+
+		info, err := buildinfo.Parse(data.Bytes())
+		require.NoError(t, err)
+		assert.Equal(t, "1.2.3", info.ToolVersion)
+		assert.Equal(t, modTime, info.CreatedAt)
+		assert.Equal(t, "true", info.Options["reproducible"])
+		assert.Equal(t, "9", info.Options["compressionLevel"])
+		assert.Equal(t, "false", info.Options["customKeys"])
+		return
+	}
+	t.Fatal("build info not found")
+}
+
+func TestPackThreads(t *testing.T) {
+	sourceDir := t.TempDir()
+	for i := range 50 {
+		dir := filepath.Join(sourceDir, fmt.Sprintf("dir%d", i%5))
+		require.NoError(t, os.MkdirAll(dir, 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, fmt.Sprintf("file%02d.txt", i)), bytes.Repeat([]byte(fmt.Sprintf("line %d\n", i)), i*100), 0600))
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(sourceDir, "setup.msi"), []byte("already compressed"), 0600))
+
+	opts := []Option{
+		WithReproducible(time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)),
+		WithEncryptionKeys(bytes.Repeat([]byte{1}, 32), bytes.Repeat([]byte{2}, 32), bytes.Repeat([]byte{3}, 16)),
+		// Exercise spilling of compressed entries as well
+		WithSpillThreshold(4096),
+	}
+
+	// The output must not depend on the number of threads
+	var outputs [][]byte
+	for _, threads := range []int{1, 2, 8} {
+		outputFile := filepath.Join(t.TempDir(), "app.intunewin")
+		require.NoError(t, Pack(sourceDir, outputFile, append(opts, WithThreads(threads))...))
+		data, err := os.ReadFile(outputFile)
+		require.NoError(t, err)
+		outputs = append(outputs, data)
+	}
+	assert.Equal(t, outputs[0], outputs[1])
+	assert.Equal(t, outputs[0], outputs[2])
+}
+
+func TestPackThreadsError(t *testing.T) {
+	sourceDir := t.TempDir()
+	for i := range 20 {
+		require.NoError(t, os.WriteFile(filepath.Join(sourceDir, fmt.Sprintf("file%02d.txt", i)), []byte("data"), 0600))
+	}
+	// A file removed after the walk cannot be read by the compressor
+	removed := false
+	reporter := progress.ReporterFunc(func(stage progress.Stage, _, _ int64) {
+		if stage == progress.Compress && !removed {
+			removed = true
+			require.NoError(t, os.Remove(filepath.Join(sourceDir, "file10.txt")))
+		}
+	})
+
+	tempDir := t.TempDir()
+	err := Pack(sourceDir, filepath.Join(t.TempDir(), "app.intunewin"), WithThreads(4), WithTempDir(tempDir), WithSpillThreshold(0), WithProgress(reporter))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "file10.txt")
+
+	entries, err := os.ReadDir(tempDir)
+	require.NoError(t, err)
+	assert.Empty(t, entries, "temporary files should be removed after a failure")
+}
+
+func TestPackDefaultThreads(t *testing.T) {
+	sourceDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(sourceDir, "test.txt"), []byte("data"), 0600))
+
+	assert.NoError(t, Pack(sourceDir, filepath.Join(t.TempDir(), "out.intunewin"), WithThreads(0)))
+}
+
+func TestPackInvalidThreads(t *testing.T) {
+	tempDir := t.TempDir()
+
+	err := Pack(tempDir, filepath.Join(tempDir, "out.intunewin"), WithThreads(-1))
+	assert.ErrorContains(t, err, "threads must not be negative")
+}
+
+func TestPackKeepsOutputOnFailure(t *testing.T) {
+	sourceDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(sourceDir, "test.txt"), []byte("data"), 0600))
+	outputDir := t.TempDir()
+	outputFile := filepath.Join(outputDir, "app.intunewin")
+	require.NoError(t, os.WriteFile(outputFile, []byte("previous package"), 0600))
+
+	err := PackWithInfo(sourceDir, outputFile, "app", "setup.exe")
+	require.ErrorIs(t, err, ErrSetupFileNotFound)
+
+	data, err := os.ReadFile(outputFile)
+	require.NoError(t, err)
+	assert.Equal(t, "previous package", string(data), "the existing package should be left untouched")
+	entries, err := os.ReadDir(outputDir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1, "no partial output should be left behind")
+}
+
+func TestPackReplacesOutput(t *testing.T) {
+	sourceDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(sourceDir, "test.txt"), []byte("data"), 0600))
+	outputFile := filepath.Join(t.TempDir(), "app.intunewin")
+	require.NoError(t, os.WriteFile(outputFile, []byte("previous package"), 0600))
+
+	require.NoError(t, Pack(sourceDir, outputFile))
+
+	zr, err := zip.OpenReader(outputFile)
+	require.NoError(t, err)
+	defer zr.Close()
+	_, err = zr.Open("IntuneWinPackage/Metadata/Detection.xml")
+	assert.NoError(t, err)
+}
+
+func TestWriteFile(t *testing.T) {
+	outputDir := t.TempDir()
+	outputFile := filepath.Join(outputDir, "app.intunewin")
+	require.NoError(t, os.WriteFile(outputFile, []byte("previous package"), 0600))
+
+	err := WriteFile(outputFile, func(w io.Writer) error {
+		_, _ = io.WriteString(w, "partial")
+		return errors.New("broken pipe")
+	})
+	require.ErrorContains(t, err, "broken pipe")
+	data, err := os.ReadFile(outputFile)
+	require.NoError(t, err)
+	assert.Equal(t, "previous package", string(data), "the existing package should be left untouched")
+	entries, err := os.ReadDir(outputDir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1, "no partial output should be left behind")
+
+	require.NoError(t, WriteFile(outputFile, func(w io.Writer) error {
+		_, err := io.WriteString(w, "new package")
+		return err
+	}))
+	data, err = os.ReadFile(outputFile)
+	require.NoError(t, err)
+	assert.Equal(t, "new package", string(data))
+}
+
+func TestPackTo(t *testing.T) {
+	sourceDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(sourceDir, "setup.exe"), []byte("setup"), 0600))
+
+	opts := []Option{
+		WithReproducible(time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)),
+		WithEncryptionKeys(bytes.Repeat([]byte{1}, 32), bytes.Repeat([]byte{2}, 32), bytes.Repeat([]byte{3}, 16)),
+	}
+	outputFile := filepath.Join(t.TempDir(), "app.intunewin")
+	require.NoError(t, Pack(sourceDir, outputFile, opts...))
+	expected, err := os.ReadFile(outputFile)
+	require.NoError(t, err)
+
+	var output bytes.Buffer
+	require.NoError(t, PackTo(sourceDir, &output, opts...))
+	assert.Equal(t, expected, output.Bytes())
+
+	assert.ErrorIs(t, PackTo(filepath.Join(sourceDir, "missing"), &output), ErrSourceNotFound)
+}
+
+func TestPackTarTo(t *testing.T) {
+	tarBuf := new(bytes.Buffer)
+	tw := tar.NewWriter(tarBuf)
+	modTime := time.Date(2024, time.May, 1, 12, 0, 0, 0, time.UTC)
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "./", Typeflag: tar.TypeDir, Mode: 0755, ModTime: modTime}))
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "./bin/", Typeflag: tar.TypeDir, Mode: 0755, ModTime: modTime}))
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "./bin/setup.exe", Typeflag: tar.TypeReg, Mode: 0755, Size: 5, ModTime: modTime}))
+	_, err := tw.Write([]byte("setup"))
+	require.NoError(t, err)
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "./bin/link", Typeflag: tar.TypeSymlink, Linkname: "setup.exe"}))
+	require.NoError(t, tw.Close())
+
+	encKey := bytes.Repeat([]byte{1}, 32)
+	macKey := bytes.Repeat([]byte{2}, 32)
+	outputFile := filepath.Join(t.TempDir(), "out.intunewin")
+	f, err := os.Create(outputFile)
+	require.NoError(t, err)
+	require.NoError(t, PackTarTo(bytes.NewReader(tarBuf.Bytes()), f, "app", "bin/setup.exe", WithEncryptionKeys(encKey, macKey, bytes.Repeat([]byte{3}, 16)), WithPOSIXModes()))
+	require.NoError(t, f.Close())
+
+	zipReader := readInnerZip(t, outputFile, encKey, macKey)
+	require.Len(t, zipReader.File, 2)
+	assert.Equal(t, "bin/", zipReader.File[0].Name)
+	assert.Equal(t, "bin/setup.exe", zipReader.File[1].Name)
+	assert.Equal(t, os.FileMode(0755), zipReader.File[1].Mode().Perm())
+	assert.True(t, modTime.Equal(zipReader.File[1].Modified))
+}
+
+func TestPackTarToRejectsUnsafeNames(t *testing.T) {
+	tarBuf := new(bytes.Buffer)
+	tw := tar.NewWriter(tarBuf)
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "../evil.dll", Typeflag: tar.TypeReg}))
+	require.NoError(t, tw.Close())
+
+	err := PackTarTo(bytes.NewReader(tarBuf.Bytes()), io.Discard, "app", "setup.exe")
+	assert.ErrorIs(t, err, pathutil.ErrUnsafePath)
+}
+
+func TestPackToolVersion(t *testing.T) {
+	sourceDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(sourceDir, "setup.exe"), []byte("setup"), 0600))
+
+	var summary Summary
+	require.NoError(t, PackTo(sourceDir, io.Discard, WithSummary(&summary)))
+	assert.Equal(t, metadata.ToolVersion, summary.ApplicationInfo.ToolVersion)
+
+	require.NoError(t, PackTo(sourceDir, io.Discard, WithSummary(&summary), WithToolVersion("1.8.6.0")))
+	assert.Equal(t, "1.8.6.0", summary.ApplicationInfo.ToolVersion)
+
+	err := PackTo(sourceDir, io.Discard, WithToolVersion("latest"))
+	assert.ErrorIs(t, err, metadata.ErrInvalidToolVersion)
+}
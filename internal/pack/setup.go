@@ -0,0 +1,132 @@
+package pack
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/kenchan0130/intunewin/internal/pathutil"
+	"github.com/kenchan0130/intunewin/internal/pe"
+	"github.com/kenchan0130/intunewin/internal/spill"
+)
+
+// ErrSetupFileNotFound is returned when the setup file is not part of the packaged content
+var ErrSetupFileNotFound = errors.New("setup file not found in the content")
+
+// setupExtensions are the extensions of setup files Intune runs directly
+var setupExtensions = map[string]bool{".msi": true, ".exe": true, ".ps1": true, ".cmd": true}
+
+// WithSkipSetupFileCheck packs content that does not contain the declared setup file,
+// such as content that is not a zip archive or is assembled by the caller
+func WithSkipSetupFileCheck() Option {
+	return func(o *Options) {
+		o.SkipSetupFileCheck = true
+	}
+}
+
+// checkSetupFile returns ErrSetupFileNotFound when the content zip has no file named
+// setupFile, and warns when setupFile is not an installer or script Intune runs directly.
+// Names are compared case-insensitively like on Windows. Content that is not a zip
+// archive cannot be checked and is accepted.
+func checkSetupFile(content *spill.Buffer, setupFile string, o *Options) error {
+	if o.SkipSetupFileCheck {
+		return nil
+	}
+
+	if ext := strings.ToLower(path.Ext(setupFile)); !setupExtensions[ext] {
+		o.Logger.Warn("setup file is not an .msi, .exe, .ps1 or .cmd file", "setupFile", setupFile)
+	}
+
+	name, err := pathutil.Clean(setupFile)
+	if err != nil || name == "." {
+		return fmt.Errorf("%w: invalid setup file name %q", ErrSetupFileNotFound, setupFile)
+	}
+
+	size, err := content.Size()
+	if err != nil {
+		return err //nolint:wrapcheck // spill errors already describe the failure
+	}
+	zipReader, err := zip.NewReader(content, size)
+	if err != nil {
+		o.Logger.Debug("content is not a zip archive, skipping the setup file check", "error", err)
+		return nil
+	}
+	for _, file := range zipReader.File {
+		if file.FileInfo().IsDir() {
+			continue
+		}
+		if entry, err := pathutil.Clean(file.Name); err == nil && strings.EqualFold(entry, name) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s", ErrSetupFileNotFound, setupFile)
+}
+
+// ErrSetupFileAmbiguous is returned by DetectSetupFile when several files could be the setup file
+var ErrSetupFileAmbiguous = errors.New("several setup file candidates")
+
+// conventionalSetupNames are the lower case names of setup programs detected by DetectSetupFile
+var conventionalSetupNames = map[string]bool{
+	"setup.exe":              true,
+	"install.exe":            true,
+	"deploy-application.exe": true,
+}
+
+// DetectSetupFile looks for the setup file in the top level of sourceFolder: an .msi
+// installer or a program conventionally named setup.exe, install.exe or
+// Deploy-Application.exe (PowerShell App Deployment Toolkit). Exactly one candidate must
+// exist; ErrSetupFileNotFound or ErrSetupFileAmbiguous is returned otherwise.
+func DetectSetupFile(sourceFolder string) (string, error) {
+	if err := checkSourceFolder(sourceFolder); err != nil {
+		return "", err
+	}
+	entries, err := os.ReadDir(pathutil.Long(sourceFolder))
+	if err != nil {
+		return "", fmt.Errorf("failed to read source folder: %w", err)
+	}
+
+	var candidates []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		name := strings.ToLower(entry.Name())
+		if path.Ext(name) == ".msi" || conventionalSetupNames[name] {
+			candidates = append(candidates, entry.Name())
+		}
+	}
+
+	switch len(candidates) {
+	case 0:
+		return "", fmt.Errorf("%w: no .msi, setup.exe, install.exe or Deploy-Application.exe in %s", ErrSetupFileNotFound, sourceFolder)
+	case 1:
+		return candidates[0], nil
+	default:
+		return "", fmt.Errorf("%w: %s", ErrSetupFileAmbiguous, strings.Join(candidates, ", "))
+	}
+}
+
+// SetupFileVersion reads the version resource of the setup file, a path relative to
+// sourceFolder. pe.ErrNoVersion is returned when the setup file is not an executable
+// with version information.
+func SetupFileVersion(sourceFolder, setupFile string) (*pe.VersionInfo, error) {
+	if ext := strings.ToLower(path.Ext(setupFile)); ext != ".exe" {
+		return nil, fmt.Errorf("%w: %s is not an executable", pe.ErrNoVersion, setupFile)
+	}
+
+	f, err := os.Open(pathutil.Long(filepath.Join(sourceFolder, filepath.FromSlash(setupFile)))) // #nosec G304 -- path is chosen by the user
+	if err != nil {
+		return nil, fmt.Errorf("failed to open setup file: %w", err)
+	}
+	defer f.Close()
+
+	info, err := pe.ReadVersion(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read setup file version: %w", err)
+	}
+	return info, nil
+}
@@ -0,0 +1,143 @@
+package pack
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kenchan0130/intunewin/internal/pe"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPackWithInfoSetupFile(t *testing.T) {
+	sourceDir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(sourceDir, "bin"), 0750))
+	require.NoError(t, os.WriteFile(filepath.Join(sourceDir, "bin", "Setup.exe"), []byte("setup"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(sourceDir, "readme.txt"), []byte("readme"), 0600))
+
+	tests := []struct {
+		name      string
+		setupFile string
+		wantErr   bool
+		wantWarn  bool
+	}{
+		{name: "relative path", setupFile: "bin/Setup.exe"},
+		{name: "backslashes and case", setupFile: `BIN\setup.EXE`},
+		{name: "missing", setupFile: "setup.exe", wantErr: true},
+		{name: "directory", setupFile: "bin", wantErr: true, wantWarn: true},
+		{name: "unusual extension", setupFile: "readme.txt", wantWarn: true},
+		{name: "escaping", setupFile: "../setup.exe", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var logs bytes.Buffer
+			logger := slog.New(slog.NewTextHandler(&logs, nil))
+			err := PackWithInfo(sourceDir, filepath.Join(t.TempDir(), "app.intunewin"), "app", tt.setupFile, WithLogger(logger))
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrSetupFileNotFound)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.wantWarn, bytes.Contains(logs.Bytes(), []byte("setup file is not")), logs.String())
+		})
+	}
+}
+
+func TestPackSkipSetupFileCheck(t *testing.T) {
+	sourceDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(sourceDir, "setup.exe"), []byte("setup"), 0600))
+
+	output := filepath.Join(t.TempDir(), "app.intunewin")
+	require.NoError(t, PackWithInfo(sourceDir, output, "app", "install.exe", WithSkipSetupFileCheck()))
+
+	// Pack names the setup file after the folder, so it is not checked
+	require.NoError(t, Pack(sourceDir, output))
+}
+
+func TestPackZipToSetupFile(t *testing.T) {
+	content := new(bytes.Buffer)
+	zw := zip.NewWriter(content)
+	_, err := zw.Create("setup.msi")
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+
+	require.NoError(t, PackZipTo(bytes.NewReader(content.Bytes()), new(bytes.Buffer), "app", "setup.msi"))
+	err = PackZipTo(bytes.NewReader(content.Bytes()), new(bytes.Buffer), "app", "install.msi")
+	assert.ErrorIs(t, err, ErrSetupFileNotFound)
+
+	// Content that is not a zip archive cannot be checked
+	require.NoError(t, PackZipTo(bytes.NewReader([]byte("MSCF")), new(bytes.Buffer), "app", "setup.exe"))
+}
+
+func TestDetectSetupFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   []string
+		want    string
+		wantErr error
+	}{
+		{name: "single msi", files: []string{"App.MSI", "readme.txt"}, want: "App.MSI"},
+		{name: "setup.exe", files: []string{"Setup.exe", "data.cab"}, want: "Setup.exe"},
+		{name: "install.exe", files: []string{"install.exe"}, want: "install.exe"},
+		{name: "PSADT", files: []string{"Deploy-Application.exe", "Deploy-Application.ps1"}, want: "Deploy-Application.exe"},
+		{name: "nested files are ignored", files: []string{"bin/setup.exe"}, wantErr: ErrSetupFileNotFound},
+		{name: "none", files: []string{"app.exe"}, wantErr: ErrSetupFileNotFound},
+		{name: "two msi", files: []string{"a.msi", "b.msi"}, wantErr: ErrSetupFileAmbiguous},
+		{name: "msi and setup.exe", files: []string{"app.msi", "setup.exe"}, wantErr: ErrSetupFileAmbiguous},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sourceDir := t.TempDir()
+			for _, name := range tt.files {
+				path := filepath.Join(sourceDir, filepath.FromSlash(name))
+				require.NoError(t, os.MkdirAll(filepath.Dir(path), 0750))
+				require.NoError(t, os.WriteFile(path, nil, 0600))
+			}
+
+			got, err := DetectSetupFile(sourceDir)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestSetupFileVersion(t *testing.T) {
+	sourceDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(sourceDir, "app.msi"), []byte("msi"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(sourceDir, "setup.exe"), []byte("not a PE file"), 0600))
+
+	_, err := SetupFileVersion(sourceDir, "app.msi")
+	assert.ErrorIs(t, err, pe.ErrNoVersion)
+
+	_, err = SetupFileVersion(sourceDir, "setup.exe")
+	assert.Error(t, err)
+
+	_, err = SetupFileVersion(sourceDir, "missing.exe")
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestPackDescription(t *testing.T) {
+	sourceDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(sourceDir, "setup.exe"), []byte("setup"), 0600))
+
+	output := new(bytes.Buffer)
+	require.NoError(t, PackWithInfoTo(sourceDir, output, "app", "setup.exe", WithDescription("My App 1.0")))
+
+	zr, err := zip.NewReader(bytes.NewReader(output.Bytes()), int64(output.Len()))
+	require.NoError(t, err)
+	rc, err := zr.Open("IntuneWinPackage/Metadata/Detection.xml")
+	require.NoError(t, err)
+	defer rc.Close()
+	detection, err := io.ReadAll(rc)
+	require.NoError(t, err)
+	assert.Contains(t, string(detection), "<Description>My App 1.0</Description>")
+}
@@ -0,0 +1,64 @@
+package pack
+
+import (
+	"archive/zip"
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"io"
+
+	"github.com/kenchan0130/intunewin/internal/metadata"
+	"github.com/kenchan0130/intunewin/internal/spill"
+)
+
+// Summary describes a package once it has been written
+type Summary struct {
+	// Files is the number of files in the content, or 1 when the content is not a zip archive
+	Files int
+	// UnencryptedSize is the size of the content before encryption
+	UnencryptedSize int64
+	// SHA256 is the hex encoded digest of the intunewin package
+	SHA256 string
+	// ApplicationInfo is the Detection.xml metadata, including the encryption info only with WithRetainKeys
+	ApplicationInfo *metadata.ApplicationInfo
+}
+
+// WithSummary fills s with the file count, content size, digest and metadata of the written package
+func WithSummary(s *Summary) Option {
+	return func(o *Options) {
+		o.Summary = s
+	}
+}
+
+// summarize counts the files of the content zip for o.Summary and returns a writer that
+// hashes the package written to output, or output itself when no summary is requested
+func summarize(output io.Writer, content *spill.Buffer, unencryptedSize int64, o *Options) (io.Writer, hash.Hash) {
+	if o.Summary == nil {
+		return output, nil
+	}
+
+	o.Summary.UnencryptedSize = unencryptedSize
+	o.Summary.Files = 1
+	if zr, err := zip.NewReader(content, unencryptedSize); err == nil {
+		o.Summary.Files = 0
+		for _, file := range zr.File {
+			if !file.FileInfo().IsDir() {
+				o.Summary.Files++
+			}
+		}
+	}
+
+	digest := sha256.New()
+	return io.MultiWriter(output, digest), digest
+}
+
+// finishSummary records the digest and metadata of the written package
+func finishSummary(digest hash.Hash, appInfo *metadata.ApplicationInfo, o *Options) {
+	if digest != nil {
+		o.Summary.SHA256 = hex.EncodeToString(digest.Sum(nil))
+		o.Summary.ApplicationInfo = appInfo
+		if !o.RetainKeys {
+			o.Summary.ApplicationInfo = appInfo.WithoutEncryptionInfo()
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package pack
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPackSummary(t *testing.T) {
+	sourceDir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(sourceDir, "sub"), 0750))
+	require.NoError(t, os.WriteFile(filepath.Join(sourceDir, "setup.exe"), []byte("setup"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(sourceDir, "sub", "data.txt"), []byte("data"), 0600))
+
+	var summary Summary
+	output := new(bytes.Buffer)
+	require.NoError(t, PackWithInfoTo(sourceDir, output, "app", "setup.exe", WithSummary(&summary)))
+
+	digest := sha256.Sum256(output.Bytes())
+	assert.Equal(t, hex.EncodeToString(digest[:]), summary.SHA256)
+	assert.Equal(t, 2, summary.Files)
+	assert.Positive(t, summary.UnencryptedSize)
+	require.NotNil(t, summary.ApplicationInfo)
+	assert.Equal(t, "app", summary.ApplicationInfo.Name)
+	assert.Nil(t, summary.ApplicationInfo.EncryptionInfo, "keys should only be kept on request")
+
+	output.Reset()
+	require.NoError(t, PackWithInfoTo(sourceDir, output, "app", "setup.exe", WithSummary(&summary), WithRetainKeys()))
+	assert.NoError(t, summary.ApplicationInfo.Validate())
+}
+
+func TestPackOmitEncryptionInfo(t *testing.T) {
+	var summary Summary
+	output := new(bytes.Buffer)
+	require.NoError(t, PackZipTo(bytes.NewReader([]byte("MSCF content")), output, "app", "setup.exe",
+		WithSummary(&summary), WithRetainKeys(), WithOmitEncryptionInfo()))
+	require.NoError(t, summary.ApplicationInfo.Validate(), "the summary should still carry the keys")
+
+	zr, err := zip.NewReader(bytes.NewReader(output.Bytes()), int64(output.Len()))
+	require.NoError(t, err)
+	f, err := zr.Open(detectionXMLPath)
+	require.NoError(t, err)
+	defer f.Close()
+	data, err := io.ReadAll(f)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "EncryptionInfo")
+	assert.NotContains(t, string(data), summary.ApplicationInfo.EncryptionInfo.EncryptionKey)
+}
+
+func TestPackKeepsCallerKeys(t *testing.T) {
+	encKey := bytes.Repeat([]byte{1}, 32)
+	macKey := bytes.Repeat([]byte{2}, 32)
+	iv := bytes.Repeat([]byte{3}, 16)
+	require.NoError(t, PackZipTo(bytes.NewReader([]byte("MSCF content")), new(bytes.Buffer), "app", "setup.exe",
+		WithEncryptionKeys(encKey, macKey, iv)))
+
+	// Only generated keys are zeroed after packing
+	assert.Equal(t, bytes.Repeat([]byte{1}, 32), encKey)
+	assert.Equal(t, bytes.Repeat([]byte{2}, 32), macKey)
+	assert.Equal(t, bytes.Repeat([]byte{3}, 16), iv)
+}
+
+func TestPackSummaryRawContent(t *testing.T) {
+	var summary Summary
+	require.NoError(t, PackZipTo(bytes.NewReader([]byte("MSCF content")), new(bytes.Buffer), "app", "setup.exe", WithSummary(&summary)))
+	assert.Equal(t, 1, summary.Files)
+	assert.Equal(t, int64(len("MSCF content")), summary.UnencryptedSize)
+	assert.Len(t, summary.SHA256, 64)
+}
+
+func TestPackKeyProvider(t *testing.T) {
+	calls := 0
+	provider := func() ([]byte, []byte, []byte, error) {
+		calls++
+		return bytes.Repeat([]byte{byte(calls)}, 32), bytes.Repeat([]byte{2}, 32), bytes.Repeat([]byte{3}, 16), nil
+	}
+
+	var first, second Summary
+	for _, summary := range []*Summary{&first, &second} {
+		require.NoError(t, PackZipTo(bytes.NewReader([]byte("MSCF content")), new(bytes.Buffer), "app", "setup.exe",
+			WithKeyProvider(provider), WithSummary(summary), WithRetainKeys()))
+	}
+	assert.Equal(t, 2, calls)
+	assert.NotEqual(t, first.ApplicationInfo.EncryptionInfo.EncryptionKey, second.ApplicationInfo.EncryptionInfo.EncryptionKey)
+
+	short := func() ([]byte, []byte, []byte, error) {
+		return make([]byte, 16), make([]byte, 32), make([]byte, 16), nil
+	}
+	err := PackZipTo(bytes.NewReader([]byte("MSCF content")), new(bytes.Buffer), "app", "setup.exe", WithKeyProvider(short))
+	assert.ErrorContains(t, err, "failed to use provided encryption keys")
+
+	err = PackZipTo(bytes.NewReader([]byte("MSCF content")), new(bytes.Buffer), "app", "setup.exe",
+		WithKeyProvider(provider), WithEncryptionKeys(make([]byte, 32), make([]byte, 32), make([]byte, 16)))
+	assert.ErrorContains(t, err, "cannot be combined")
+}
@@ -0,0 +1,110 @@
+package pack
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/kenchan0130/intunewin/internal/pathutil"
+	"github.com/kenchan0130/intunewin/internal/progress"
+)
+
+// PackTarTo reads a tar archive from tarReader, such as standard input, and writes an
+// intunewin package of it to w. The tar entries are converted to the content zip in
+// the order they appear; only regular files and directories are packed.
+func PackTarTo(tarReader io.Reader, w io.Writer, name, setupFile string, opts ...Option) error {
+	o, err := newOptions(opts)
+	if err != nil {
+		return fmt.Errorf("invalid options: %w", err)
+	}
+	info := HookInfo{Stage: HookPrePack, Name: name, SetupFile: setupFile}
+	if err := o.runPrePackHooks(info); err != nil {
+		return err
+	}
+
+	content := o.newBuffer("intunewin-content-*.zip")
+	defer content.Close()
+	if err := tarToZip(tarReader, content, o); err != nil {
+		return err
+	}
+
+	encrypted := o.newBuffer("intunewin-encrypted-*")
+	defer encrypted.Close()
+
+	if err := writePackage(w, content, encrypted, name, setupFile, o); err != nil {
+		return err
+	}
+	return o.runPostPackHooks(info)
+}
+
+// tarToZip converts the tar archive in r to a zip archive written to w
+func tarToZip(r io.Reader, w io.Writer, o *Options) error {
+	tarReader := tar.NewReader(r)
+	zipWriter := o.newZipWriter(w)
+	counter := progress.NewCounter(o.Progress, progress.Compress, 0)
+
+	for {
+		header, err := tarReader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			zipWriter.Close()
+			return fmt.Errorf("failed to read tar: %w", err)
+		}
+
+		name, err := pathutil.Clean(header.Name)
+		if err != nil {
+			zipWriter.Close()
+			return fmt.Errorf("invalid tar entry %s: %w", header.Name, err)
+		}
+		if name == "." {
+			continue
+		}
+		if err := o.checkPath(name); err != nil {
+			zipWriter.Close()
+			return err
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			zipHeader := &zip.FileHeader{
+				Name:     name + "/",
+				Modified: o.modTime(header.ModTime),
+			}
+			o.setMode(zipHeader, header.FileInfo().Mode())
+			if _, err := zipWriter.CreateHeader(zipHeader); err != nil {
+				zipWriter.Close()
+				return fmt.Errorf("failed to create directory entry %s: %w", name, err)
+			}
+		case tar.TypeReg:
+			zipHeader := &zip.FileHeader{
+				Name:     name,
+				Method:   o.methodFor(name),
+				Modified: o.modTime(header.ModTime),
+			}
+			o.setMode(zipHeader, header.FileInfo().Mode())
+			o.Logger.Debug("adding file", "path", name, "size", header.Size, "stored", zipHeader.Method == zip.Store)
+
+			writer, err := zipWriter.CreateHeader(zipHeader)
+			if err != nil {
+				zipWriter.Close()
+				return fmt.Errorf("failed to create file entry %s: %w", name, err)
+			}
+			counter.File(name)
+			if _, err := io.Copy(writer, counter.Reader(tarReader)); err != nil {
+				zipWriter.Close()
+				return fmt.Errorf("failed to write file content %s: %w", name, err)
+			}
+		default:
+			o.Logger.Warn("skipping unsupported tar entry", "path", header.Name, "type", string(header.Typeflag))
+		}
+	}
+
+	if err := zipWriter.Close(); err != nil {
+		return fmt.Errorf("failed to close zip writer: %w", err)
+	}
+	return nil
+}
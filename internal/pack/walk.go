@@ -0,0 +1,191 @@
+package pack
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/kenchan0130/intunewin/internal/pathutil"
+	"github.com/kenchan0130/intunewin/internal/progress"
+)
+
+var (
+	// ErrSymlink is returned for a symbolic link in the source folder with SymlinkError
+	ErrSymlink = errors.New("source folder contains a symbolic link")
+	// ErrSymlinkLoop is returned when following a symbolic link leads back to one of its parent folders
+	ErrSymlinkLoop = errors.New("symbolic link loop")
+)
+
+// SymlinkPolicy selects how symbolic links in the source folder are packed
+type SymlinkPolicy string
+
+const (
+	// SymlinkFollow packs the file or folder a link points to under the name of the link
+	SymlinkFollow SymlinkPolicy = "follow"
+	// SymlinkSkip leaves links out of the package
+	SymlinkSkip SymlinkPolicy = "skip"
+	// SymlinkError fails on the first link
+	SymlinkError SymlinkPolicy = "error"
+)
+
+// walker collects the entries below a source folder
+type walker struct {
+	root string
+	// prefix is the path in the package that root is packed as, empty for the top level
+	prefix  string
+	o       *Options
+	counter *progress.Counter
+	files   []fileEntry
+	// parents holds the folders being walked, to detect links that lead back to them
+	parents []os.FileInfo
+}
+
+// collectFiles walks sourceFolder and the additions and returns an entry for every file and
+// directory below them.
+// Entries are returned in lexical order within each folder, and symbolic links are handled
+// according to o.Symlinks.
+func collectFiles(sourceFolder string, o *Options) ([]fileEntry, error) {
+	info, err := os.Stat(pathutil.Long(sourceFolder))
+	if err != nil {
+		return nil, err //nolint:wrapcheck // wrapped by the caller
+	}
+
+	w := &walker{
+		root:    sourceFolder,
+		o:       o,
+		counter: progress.NewCounter(o.Progress, progress.Walk, 0),
+	}
+	if err := w.walkDir(sourceFolder, info); err != nil {
+		return nil, err
+	}
+	return w.entries()
+}
+
+// entries adds the additions to the walked entries and returns them merged, without
+// empty folders when o.PruneEmptyDirs is set
+func (w *walker) entries() ([]fileEntry, error) {
+	if err := w.addAdditions(); err != nil {
+		return nil, err
+	}
+	files, err := w.o.mergeEntries(w.files)
+	if err != nil {
+		return nil, err
+	}
+	if w.o.PruneEmptyDirs {
+		files = pruneEmptyDirs(files)
+	}
+	return files, nil
+}
+
+// pruneEmptyDirs drops the folders that have no file below them
+func pruneEmptyDirs(files []fileEntry) []fileEntry {
+	used := make(map[string]bool)
+	for _, file := range files {
+		if file.IsDir {
+			continue
+		}
+		for dir := path.Dir(file.Path); dir != "." && !used[dir]; dir = path.Dir(dir) {
+			used[dir] = true
+		}
+	}
+	pruned := files[:0]
+	for _, file := range files {
+		if !file.IsDir || used[file.Path] {
+			pruned = append(pruned, file)
+		}
+	}
+	return pruned
+}
+
+// walkDir adds the entries of the folder at dir, whose own info is info
+func (w *walker) walkDir(dir string, info os.FileInfo) error {
+	w.parents = append(w.parents, info)
+	defer func() { w.parents = w.parents[:len(w.parents)-1] }()
+
+	entries, err := os.ReadDir(pathutil.Long(dir))
+	if err != nil {
+		return err //nolint:wrapcheck // wrapped by the caller
+	}
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+		entryInfo, err := entry.Info()
+		if err != nil {
+			return err //nolint:wrapcheck // wrapped by the caller
+		}
+
+		if entryInfo.Mode()&fs.ModeSymlink != 0 {
+			entryInfo, err = w.resolveSymlink(path)
+			if err != nil {
+				return err
+			}
+			if entryInfo == nil {
+				continue
+			}
+		}
+
+		if err := w.add(path, entryInfo); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resolveSymlink applies the symlink policy to the link at path and returns the info of
+// its target, or nil when the link is skipped
+func (w *walker) resolveSymlink(path string) (os.FileInfo, error) {
+	switch w.o.Symlinks {
+	case SymlinkSkip:
+		w.o.Logger.Debug("skipping symbolic link", "path", path)
+		return nil, nil
+	case SymlinkError:
+		return nil, fmt.Errorf("%w: %s", ErrSymlink, path)
+	}
+
+	target, err := os.Stat(pathutil.Long(path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to follow symbolic link %s: %w", path, err)
+	}
+	if target.IsDir() {
+		for _, parent := range w.parents {
+			if os.SameFile(parent, target) {
+				return nil, fmt.Errorf("%w: %s", ErrSymlinkLoop, path)
+			}
+		}
+	}
+	w.o.Logger.Debug("following symbolic link", "path", path)
+	return target, nil
+}
+
+// add records the file or folder at path and walks into folders
+func (w *walker) add(path string, info os.FileInfo) error {
+	relPath, err := filepath.Rel(w.root, path)
+	if err != nil {
+		return fmt.Errorf("failed to get relative path: %w", err)
+	}
+	archivePath := w.archivePath(relPath)
+	if w.o.excluded(archivePath) {
+		w.o.Logger.Debug("excluding entry", "path", archivePath)
+		return nil
+	}
+	if err := w.o.checkName(archivePath); err != nil {
+		return err
+	}
+
+	w.files = append(w.files, fileEntry{
+		Path:     archivePath,
+		Source:   path,
+		Size:     info.Size(),
+		Mode:     info.Mode(),
+		IsDir:    info.IsDir(),
+		Modified: info.ModTime(),
+	})
+	if info.IsDir() {
+		return w.walkDir(path, info)
+	}
+	w.counter.File(archivePath)
+	w.counter.Add(info.Size())
+	return nil
+}
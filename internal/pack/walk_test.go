@@ -0,0 +1,150 @@
+package pack
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// symlinkSource creates a source folder with a linked file and a linked folder
+func symlinkSource(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	sourceDir := filepath.Join(dir, "source")
+	targetDir := filepath.Join(dir, "target")
+	require.NoError(t, os.MkdirAll(sourceDir, 0750))
+	require.NoError(t, os.MkdirAll(targetDir, 0750))
+	require.NoError(t, os.WriteFile(filepath.Join(sourceDir, "setup.exe"), []byte("setup"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(targetDir, "data.txt"), []byte("linked data"), 0600))
+
+	if err := os.Symlink(filepath.Join(targetDir, "data.txt"), filepath.Join(sourceDir, "file.lnk")); err != nil {
+		t.Skipf("symlinks are not supported: %v", err)
+	}
+	require.NoError(t, os.Symlink(targetDir, filepath.Join(sourceDir, "dir.lnk")))
+	return sourceDir
+}
+
+func entryPaths(files []fileEntry) []string {
+	paths := make([]string, 0, len(files))
+	for _, f := range files {
+		paths = append(paths, f.Path)
+	}
+	return paths
+}
+
+func TestCollectFilesFollowSymlinks(t *testing.T) {
+	sourceDir := symlinkSource(t)
+
+	o, err := newOptions([]Option{WithSymlinks(SymlinkFollow)})
+	require.NoError(t, err)
+	files, err := collectFiles(sourceDir, o)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"dir.lnk", "dir.lnk/data.txt", "file.lnk", "setup.exe"}, entryPaths(files))
+
+	for _, f := range files {
+		if f.Path == "file.lnk" {
+			assert.Equal(t, int64(len("linked data")), f.Size)
+			assert.True(t, f.Mode.IsRegular())
+		}
+	}
+}
+
+func TestCollectFilesSkipSymlinks(t *testing.T) {
+	sourceDir := symlinkSource(t)
+
+	o, err := newOptions([]Option{WithSymlinks(SymlinkSkip)})
+	require.NoError(t, err)
+	files, err := collectFiles(sourceDir, o)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"setup.exe"}, entryPaths(files))
+}
+
+func TestCollectFilesErrorOnSymlinks(t *testing.T) {
+	sourceDir := symlinkSource(t)
+
+	o, err := newOptions([]Option{WithSymlinks(SymlinkError)})
+	require.NoError(t, err)
+	_, err = collectFiles(sourceDir, o)
+	require.ErrorIs(t, err, ErrSymlink)
+	assert.Contains(t, err.Error(), "dir.lnk")
+}
+
+func TestCollectFilesSymlinkLoop(t *testing.T) {
+	sourceDir := t.TempDir()
+	subDir := filepath.Join(sourceDir, "sub")
+	require.NoError(t, os.MkdirAll(subDir, 0750))
+	if err := os.Symlink(sourceDir, filepath.Join(subDir, "loop")); err != nil {
+		t.Skipf("symlinks are not supported: %v", err)
+	}
+
+	o, err := newOptions(nil)
+	require.NoError(t, err)
+	_, err = collectFiles(sourceDir, o)
+	require.ErrorIs(t, err, ErrSymlinkLoop)
+}
+
+func TestCollectFilesDanglingSymlink(t *testing.T) {
+	sourceDir := t.TempDir()
+	if err := os.Symlink(filepath.Join(sourceDir, "missing"), filepath.Join(sourceDir, "dangling.lnk")); err != nil {
+		t.Skipf("symlinks are not supported: %v", err)
+	}
+
+	o, err := newOptions(nil)
+	require.NoError(t, err)
+	_, err = collectFiles(sourceDir, o)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "dangling.lnk")
+
+	o, err = newOptions([]Option{WithSymlinks(SymlinkSkip)})
+	require.NoError(t, err)
+	files, err := collectFiles(sourceDir, o)
+	require.NoError(t, err)
+	assert.Empty(t, files)
+}
+
+func TestInvalidSymlinkPolicy(t *testing.T) {
+	_, err := newOptions([]Option{WithSymlinks("sometimes")})
+	assert.ErrorContains(t, err, "invalid symlink policy")
+}
+
+func TestCollectFilesExclude(t *testing.T) {
+	sourceDir := t.TempDir()
+	for _, name := range []string{"setup.exe", "notes.md", "docs/readme.md", "logs/a.log", "sub/logs/b.txt", "sub/keep.txt"} {
+		require.NoError(t, os.MkdirAll(filepath.Join(sourceDir, filepath.Dir(name)), 0750))
+		require.NoError(t, os.WriteFile(filepath.Join(sourceDir, name), []byte(name), 0600))
+	}
+
+	o, err := newOptions([]Option{WithExclude("*.md", "sub/logs", "/logs")})
+	require.NoError(t, err)
+	files, err := collectFiles(sourceDir, o)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"docs", "setup.exe", "sub", "sub/keep.txt"}, entryPaths(files))
+}
+
+func TestInvalidExcludePattern(t *testing.T) {
+	_, err := newOptions([]Option{WithExclude("[")})
+	assert.Error(t, err)
+}
+
+func TestCollectFilesPruneEmptyDirs(t *testing.T) {
+	sourceDir := t.TempDir()
+	writeFiles(t, sourceDir, "setup.exe", "bin/lib/a.dll")
+	for _, dir := range []string{"logs", "bin/cache/tmp"} {
+		require.NoError(t, os.MkdirAll(filepath.Join(sourceDir, dir), 0750))
+	}
+
+	o, err := newOptions(nil)
+	require.NoError(t, err)
+	files, err := collectFiles(sourceDir, o)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"bin", "bin/cache", "bin/cache/tmp", "bin/lib", "bin/lib/a.dll", "logs", "setup.exe"}, entryPaths(files))
+
+	o, err = newOptions([]Option{WithPruneEmptyDirs()})
+	require.NoError(t, err)
+	files, err = collectFiles(sourceDir, o)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"bin", "bin/lib", "bin/lib/a.dll", "setup.exe"}, entryPaths(files))
+}
@@ -0,0 +1,93 @@
+package pack
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/kenchan0130/intunewin/internal/spill"
+)
+
+// dataDescriptorFlag is the general purpose bit telling that the CRC32 and sizes of an entry
+// follow its data instead of being in its local header
+const dataDescriptorFlag = 0x8
+
+// zipWriter is a zip.Writer that, when buffered, writes the CRC32 and sizes of every entry
+// in its local header. archive/zip streams entries with a data descriptor, so a buffered
+// entry is written to a single entry archive first and copied once it is complete.
+type zipWriter struct {
+	*zip.Writer
+	o        *Options
+	buffered bool
+	pending  *pendingEntry
+}
+
+// pendingEntry is a buffered entry being written
+type pendingEntry struct {
+	archive *spill.Buffer
+	writer  *zip.Writer
+}
+
+// newZipWriter creates a zip writer that deflates with the configured compression level and
+// buffers entries with NoDataDescriptors
+func (o *Options) newZipWriter(w io.Writer) *zipWriter {
+	return &zipWriter{Writer: o.newDeflateWriter(w), o: o, buffered: o.NoDataDescriptors}
+}
+
+// CreateHeader adds an entry like zip.Writer.CreateHeader, completing the previous entry
+func (z *zipWriter) CreateHeader(fh *zip.FileHeader) (io.Writer, error) {
+	if err := z.flush(); err != nil {
+		return nil, err
+	}
+	// archive/zip writes directories without a data descriptor
+	if !z.buffered || strings.HasSuffix(fh.Name, "/") {
+		return z.Writer.CreateHeader(fh) //nolint:wrapcheck // callers describe the entry
+	}
+
+	archive := z.o.newBuffer("intunewin-entry-*.zip")
+	writer := z.o.newDeflateWriter(archive)
+	w, err := writer.CreateHeader(fh)
+	if err != nil {
+		archive.Close()
+		return nil, err //nolint:wrapcheck // callers describe the entry
+	}
+	z.pending = &pendingEntry{archive: archive, writer: writer}
+	return w, nil
+}
+
+// Copy copies the entry f like zip.Writer.Copy, moving its CRC32 and sizes to the local
+// header when buffered
+func (z *zipWriter) Copy(f *zip.File) error {
+	if err := z.flush(); err != nil {
+		return err
+	}
+	if z.buffered {
+		f.Flags &^= dataDescriptorFlag
+	}
+	return z.Writer.Copy(f) //nolint:wrapcheck // callers describe the entry
+}
+
+// Close completes the last entry and writes the central directory
+func (z *zipWriter) Close() error {
+	if err := z.flush(); err != nil {
+		z.Writer.Close()
+		return err
+	}
+	return z.Writer.Close() //nolint:wrapcheck // callers describe the archive
+}
+
+// flush copies the pending entry, now that its CRC32 and sizes are known
+func (z *zipWriter) flush() error {
+	if z.pending == nil {
+		return nil
+	}
+	pending := z.pending
+	z.pending = nil
+	defer pending.archive.Close()
+
+	if err := pending.writer.Close(); err != nil {
+		return fmt.Errorf("failed to buffer entry: %w", err)
+	}
+	return copyEntry(z, pending.archive)
+}
@@ -0,0 +1,98 @@
+package pack
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// localHeader is the part of a zip local file header that data descriptors replace
+type localHeader struct {
+	name             string
+	flags            uint16
+	crc32            uint32
+	compressedSize   uint32
+	uncompressedSize uint32
+}
+
+// localHeaders walks the local headers of a zip archive written without data descriptors
+func localHeaders(t *testing.T, data []byte) []localHeader {
+	t.Helper()
+	var headers []localHeader
+	for len(data) >= 30 && binary.LittleEndian.Uint32(data) == 0x04034b50 {
+		h := localHeader{
+			flags:            binary.LittleEndian.Uint16(data[6:]),
+			crc32:            binary.LittleEndian.Uint32(data[14:]),
+			compressedSize:   binary.LittleEndian.Uint32(data[18:]),
+			uncompressedSize: binary.LittleEndian.Uint32(data[22:]),
+		}
+		nameLen := int(binary.LittleEndian.Uint16(data[26:]))
+		extraLen := int(binary.LittleEndian.Uint16(data[28:]))
+		h.name = string(data[30 : 30+nameLen])
+		headers = append(headers, h)
+		require.Zero(t, h.flags&dataDescriptorFlag, "%s has a data descriptor", h.name)
+		data = data[30+nameLen+extraLen+int(h.compressedSize):]
+	}
+	return headers
+}
+
+// assertLocalHeaders asserts that the local headers of the zip archive in data hold the
+// CRC32 and sizes of its central directory
+func assertLocalHeaders(t *testing.T, data []byte) {
+	t.Helper()
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	require.NoError(t, err)
+	headers := localHeaders(t, data)
+	require.Len(t, headers, len(zr.File))
+	for i, file := range zr.File {
+		assert.Equal(t, file.Name, headers[i].name)
+		assert.Equal(t, file.CRC32, headers[i].crc32, file.Name)
+		assert.Equal(t, file.CompressedSize64, uint64(headers[i].compressedSize), file.Name)
+		assert.Equal(t, file.UncompressedSize64, uint64(headers[i].uncompressedSize), file.Name)
+	}
+}
+
+func TestPackNoDataDescriptors(t *testing.T) {
+	sourceDir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(sourceDir, "bin"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(sourceDir, "bin", "setup.exe"), bytes.Repeat([]byte("setup"), 1000), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(sourceDir, "readme.txt"), []byte("readme"), 0600))
+
+	encKey := bytes.Repeat([]byte{1}, 32)
+	macKey := bytes.Repeat([]byte{2}, 32)
+	keys := WithEncryptionKeys(encKey, macKey, bytes.Repeat([]byte{3}, 16))
+
+	for _, threads := range []int{1, 4} {
+		outputFile := filepath.Join(t.TempDir(), "app.intunewin")
+		require.NoError(t, PackWithInfo(sourceDir, outputFile, "app", "bin/setup.exe", keys, WithThreads(threads), WithNoDataDescriptors()))
+
+		outer, err := os.ReadFile(outputFile)
+		require.NoError(t, err)
+		assertLocalHeaders(t, outer)
+		inner := readInnerZipBytes(t, outputFile, encKey, macKey)
+		assertLocalHeaders(t, inner)
+
+		content := readInnerZip(t, outputFile, encKey, macKey)
+		rc, err := content.Open("bin/setup.exe")
+		require.NoError(t, err)
+		data := new(bytes.Buffer)
+		_, err = data.ReadFrom(rc)
+		require.NoError(t, err)
+		rc.Close()
+		assert.Equal(t, bytes.Repeat([]byte("setup"), 1000), data.Bytes())
+	}
+
+	// By default entries are streamed with data descriptors
+	outputFile := filepath.Join(t.TempDir(), "default.intunewin")
+	require.NoError(t, PackWithInfo(sourceDir, outputFile, "app", "bin/setup.exe", keys))
+	zr, err := zip.OpenReader(outputFile)
+	require.NoError(t, err)
+	defer zr.Close()
+	assert.NotZero(t, zr.File[0].Flags&dataDescriptorFlag)
+}
@@ -0,0 +1,42 @@
+package pathutil
+
+import (
+	"path"
+	"strings"
+)
+
+// Match reports whether the slash-separated path name matches pattern. Each element of
+// pattern has the syntax of path.Match, and an element that is exactly "**" matches any
+// number of elements of name, including none. The only possible error is path.ErrBadPattern.
+func Match(pattern, name string) (bool, error) {
+	elems := strings.Split(pattern, "/")
+	// path.Match validates a whole element even when it fails to match early
+	for _, elem := range elems {
+		if _, err := path.Match(elem, ""); err != nil {
+			return false, err //nolint:wrapcheck // path.ErrBadPattern is the documented error
+		}
+	}
+	return matchElems(elems, strings.Split(name, "/")), nil
+}
+
+// matchElems matches the elements of a validated pattern against the elements of a path
+func matchElems(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchElems(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if matched, _ := path.Match(pattern[0], name[0]); !matched {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
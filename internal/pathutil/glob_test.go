@@ -0,0 +1,38 @@
+package pathutil
+
+import (
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		want    bool
+	}{
+		{"Files/**", "Files/a.txt", true},
+		{"Files/**", "Files/sub/a.txt", true},
+		{"Files/**", "Files", true},
+		{"Files/**", "Other/a.txt", false},
+		{"**/*.ps1", "deploy.ps1", true},
+		{"**/*.ps1", "scripts/x/deploy.ps1", true},
+		{"**/*.ps1", "scripts/deploy.ps1.bak", false},
+		{"scripts/*.ps1", "scripts/deploy.ps1", true},
+		{"scripts/*.ps1", "scripts/x/deploy.ps1", false},
+		{"a/**/b", "a/b", true},
+		{"a/**/b", "a/x/y/b", true},
+		{"a/**/b", "a/x/y/c", false},
+		{"setup.exe", "setup.exe", true},
+	}
+	for _, tt := range tests {
+		got, err := Match(tt.pattern, tt.name)
+		assert.NoError(t, err)
+		assert.Equal(t, tt.want, got, "%s ~ %s", tt.pattern, tt.name)
+	}
+
+	_, err := Match("Files/[a", "Other/x")
+	assert.ErrorIs(t, err, path.ErrBadPattern)
+}
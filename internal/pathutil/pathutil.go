@@ -0,0 +1,74 @@
+package pathutil
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ErrUnsafePath is returned for archive entry names that cannot be extracted safely
+var ErrUnsafePath = errors.New("unsafe path")
+
+// ToArchive converts a path relative to the source folder to the slash-separated
+// form stored in archives, on every platform
+func ToArchive(relPath string) string {
+	return filepath.ToSlash(relPath)
+}
+
+// Clean normalizes an archive entry name to a clean, slash-separated relative path.
+// Backslashes written by Windows tools are treated as separators on every platform.
+// Absolute names, drive letters, UNC prefixes and names that climb out with ".." are
+// rejected, as are components that are invalid on the current platform (such as
+// reserved device names and alternate data streams on Windows).
+// The root of the archive is returned as ".".
+func Clean(name string) (string, error) {
+	slashed := strings.ReplaceAll(name, `\`, "/")
+
+	switch {
+	case strings.HasPrefix(slashed, "//"):
+		return "", fmt.Errorf("%w: UNC path %s", ErrUnsafePath, name)
+	case strings.HasPrefix(slashed, "/"):
+		return "", fmt.Errorf("%w: absolute path %s", ErrUnsafePath, name)
+	case HasDriveLetter(slashed):
+		return "", fmt.Errorf("%w: drive letter in %s", ErrUnsafePath, name)
+	}
+
+	cleaned := path.Clean(slashed)
+	if cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return "", fmt.Errorf("%w: %s escapes the output folder", ErrUnsafePath, name)
+	}
+
+	for elem := range strings.SplitSeq(cleaned, "/") {
+		if err := checkComponent(elem); err != nil {
+			return "", fmt.Errorf("%w: %s: %w", ErrUnsafePath, name, err)
+		}
+	}
+	return cleaned, nil
+}
+
+// Join resolves the archive entry name below root, rejecting names that Clean rejects
+func Join(root, name string) (string, error) {
+	cleaned, err := Clean(name)
+	if err != nil {
+		return "", err
+	}
+
+	// #nosec G305 -- name is validated by Clean and the result is checked below
+	dest := filepath.Join(root, filepath.FromSlash(cleaned))
+	rel, err := filepath.Rel(root, dest)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%w: %s escapes the output folder", ErrUnsafePath, name)
+	}
+	return dest, nil
+}
+
+// HasDriveLetter reports whether name starts with a Windows drive letter such as "C:"
+func HasDriveLetter(name string) bool {
+	if len(name) < 2 || name[1] != ':' {
+		return false
+	}
+	c := name[0]
+	return ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z')
+}
@@ -0,0 +1,22 @@
+//go:build !windows
+
+package pathutil
+
+import (
+	"errors"
+	"strings"
+)
+
+// checkComponent rejects path components that cannot be created.
+// Names that are only special on Windows are allowed.
+func checkComponent(elem string) error {
+	if strings.ContainsRune(elem, 0) {
+		return errors.New("NUL character")
+	}
+	return nil
+}
+
+// Long returns path unchanged; only Windows limits the length of paths
+func Long(path string) string {
+	return path
+}
@@ -0,0 +1,26 @@
+//go:build !windows
+
+package pathutil
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCleanAllowsWindowsOnlyNames(t *testing.T) {
+	// Device names and colons are only special on Windows
+	for _, name := range []string{"CON", "bin/aux.c", "notes:1.txt"} {
+		got, err := Clean(name)
+		require.NoError(t, err, name)
+		assert.Equal(t, name, got)
+	}
+}
+
+func TestLongUnchanged(t *testing.T) {
+	long := "/tmp/" + strings.Repeat("a", 300)
+	assert.Equal(t, long, Long(long))
+	assert.Equal(t, "relative", Long("relative"))
+}
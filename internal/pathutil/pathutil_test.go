@@ -0,0 +1,71 @@
+package pathutil
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClean(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"setup.exe", "setup.exe"},
+		{"bin/setup.exe", "bin/setup.exe"},
+		{`bin\setup.exe`, "bin/setup.exe"},
+		{`bin\sub/setup.exe`, "bin/sub/setup.exe"},
+		{"bin/", "bin"},
+		{"./bin/../setup.exe", "setup.exe"},
+		{"./", "."},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Clean(tt.name)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestCleanRejectsUnsafeNames(t *testing.T) {
+	names := []string{
+		"../evil.exe",
+		`..\evil.exe`,
+		`bin\..\..\evil.exe`,
+		"/etc/passwd",
+		`\Windows\System32\evil.dll`,
+		`C:\Windows\evil.exe`,
+		"c:evil.exe",
+		`\\server\share\evil.exe`,
+		"//server/share/evil.exe",
+		"bin/\x00evil",
+	}
+	for _, name := range names {
+		t.Run(name, func(t *testing.T) {
+			_, err := Clean(name)
+			assert.ErrorIs(t, err, ErrUnsafePath)
+		})
+	}
+}
+
+func TestJoin(t *testing.T) {
+	root := t.TempDir()
+
+	got, err := Join(root, `bin\setup.exe`)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(root, "bin", "setup.exe"), got)
+
+	got, err = Join(root, "./")
+	require.NoError(t, err)
+	assert.Equal(t, root, got)
+
+	_, err = Join(root, "../../etc/passwd")
+	assert.ErrorIs(t, err, ErrUnsafePath)
+}
+
+func TestToArchive(t *testing.T) {
+	assert.Equal(t, "bin/setup.exe", ToArchive(filepath.Join("bin", "setup.exe")))
+}
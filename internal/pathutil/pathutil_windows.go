@@ -0,0 +1,42 @@
+package pathutil
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// maxShortPath is the longest path Windows APIs accept without the extended-length prefix.
+// It is MAX_PATH (260) minus room for an 8.3 file name, the limit for creating directories.
+const maxShortPath = 247
+
+// Prefixes of extended-length and device paths
+const (
+	extendedPrefix    = `\\?\`
+	extendedUNCPrefix = `\\?\UNC\`
+	devicePrefix      = `\\.\`
+)
+
+// checkComponent rejects path components that Windows cannot create or interprets specially
+func checkComponent(elem string) error {
+	if elem == "." {
+		return nil
+	}
+	return CheckWindowsName(elem)
+}
+
+// Long returns path in the extended-length \\?\ form when it is longer than Windows APIs
+// accept otherwise. The path is made absolute first because the prefix disables the
+// resolution of relative paths, "." and "..". Short paths are returned unchanged.
+func Long(path string) string {
+	if strings.HasPrefix(path, extendedPrefix) || strings.HasPrefix(path, devicePrefix) {
+		return path
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil || len(abs) <= maxShortPath {
+		return path
+	}
+	if strings.HasPrefix(abs, `\\`) {
+		return extendedUNCPrefix + abs[2:]
+	}
+	return extendedPrefix + abs
+}
@@ -0,0 +1,74 @@
+package pathutil
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCleanWindowsNames(t *testing.T) {
+	names := []string{
+		"CON",
+		"bin/nul.txt",
+		"bin/Com1.log",
+		"LPT9 .txt",
+		"setup.exe:stream",
+		"bin/what?.txt",
+		"bin/a|b",
+		"tab\there",
+	}
+	for _, name := range names {
+		t.Run(name, func(t *testing.T) {
+			_, err := Clean(name)
+			assert.ErrorIs(t, err, ErrUnsafePath)
+		})
+	}
+
+	// Names that merely contain a reserved word are fine
+	for _, name := range []string{"console.exe", "bin/nullable.dll", "COM10"} {
+		_, err := Clean(name)
+		assert.NoError(t, err, name)
+	}
+}
+
+func TestJoinWindowsRoots(t *testing.T) {
+	tests := []struct {
+		root string
+		name string
+		want string
+	}{
+		{`C:\out`, "bin/setup.exe", `C:\out\bin\setup.exe`},
+		{`C:\out\`, `bin\setup.exe`, `C:\out\bin\setup.exe`},
+		{`\\server\share\out`, "setup.exe", `\\server\share\out\setup.exe`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.root, func(t *testing.T) {
+			got, err := Join(tt.root, tt.name)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	_, err := Join(`\\server\share\out`, `..\..\other\evil.exe`)
+	assert.ErrorIs(t, err, ErrUnsafePath)
+}
+
+func TestLong(t *testing.T) {
+	long := `C:\` + strings.Repeat(`a\`, 150) + "setup.exe"
+	assert.Equal(t, `\\?\`+long, Long(long))
+	assert.Equal(t, `\\?\`+long, Long(`\\?\`+long), "already extended paths are kept")
+
+	unc := `\\server\share\` + strings.Repeat(`a\`, 150) + "setup.exe"
+	assert.Equal(t, `\\?\UNC\server\share\`+strings.Repeat(`a\`, 150)+"setup.exe", Long(unc))
+
+	assert.Equal(t, `C:\short\setup.exe`, Long(`C:\short\setup.exe`))
+
+	// Relative paths are made absolute before the prefix is added
+	relative := strings.Repeat(`a\`, 150) + "setup.exe"
+	abs, err := filepath.Abs(relative)
+	require.NoError(t, err)
+	assert.Equal(t, `\\?\`+abs, Long(relative))
+}
@@ -0,0 +1,39 @@
+package pathutil
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// reservedNames are device names that Windows resolves regardless of the folder or extension
+var reservedNames = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true,
+	"COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true,
+	"LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+}
+
+// CheckWindowsName returns why Windows cannot create a file or folder named elem: a
+// character that is invalid on NTFS, a reserved device name such as CON or COM1 with or
+// without an extension, or a trailing dot or space, which Windows strips. It is used on
+// every platform, so that content packed elsewhere extracts on the managed device.
+func CheckWindowsName(elem string) error {
+	if i := strings.IndexAny(elem, `<>:"|?*`); i >= 0 {
+		return fmt.Errorf("invalid character %q", elem[i])
+	}
+	for _, r := range elem {
+		if r < 0x20 {
+			return fmt.Errorf("control character %U", r)
+		}
+	}
+	base, _, _ := strings.Cut(elem, ".")
+	if reservedNames[strings.ToUpper(strings.TrimRight(base, " "))] {
+		return fmt.Errorf("reserved device name %s", elem)
+	}
+	if strings.HasSuffix(elem, ".") || strings.HasSuffix(elem, " ") {
+		return errors.New("trailing dot or space")
+	}
+	return nil
+}
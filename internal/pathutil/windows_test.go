@@ -0,0 +1,29 @@
+package pathutil
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckWindowsName(t *testing.T) {
+	invalid := map[string]string{
+		"CON":           "reserved device name",
+		"nul.txt":       "reserved device name",
+		"Com1.log":      "reserved device name",
+		"LPT9 .txt":     "reserved device name",
+		"what?.txt":     "invalid character",
+		"a|b":           "invalid character",
+		"setup.exe:ads": "invalid character",
+		"tab\there":     "control character",
+		"readme.":       "trailing dot or space",
+		"folder ":       "trailing dot or space",
+	}
+	for name, reason := range invalid {
+		assert.ErrorContains(t, CheckWindowsName(name), reason, name)
+	}
+
+	for _, name := range []string{"console.exe", "nullable.dll", "COM10", ".gitignore", "a b.txt"} {
+		assert.NoError(t, CheckWindowsName(name), name)
+	}
+}
@@ -0,0 +1,193 @@
+package pe
+
+import (
+	"debug/pe"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"unicode/utf16"
+)
+
+// Resource types used by intunewin
+const (
+	// TypeIcon is RT_ICON, a single icon image
+	TypeIcon uint32 = 3
+	// TypeGroupIcon is RT_GROUP_ICON, a directory of icon images
+	TypeGroupIcon uint32 = 14
+	// TypeVersion is RT_VERSION, the VS_VERSIONINFO structure
+	TypeVersion uint32 = 16
+)
+
+// maxResourceDepth is the depth of the type/name/language resource tree
+const maxResourceDepth = 3
+
+// Resource is a leaf of the resource tree of a PE file
+type Resource struct {
+	Type uint32
+	// ID is the numeric identifier, or 0 when the resource is named
+	ID uint32
+	// Name is the string identifier, or empty when the resource has a numeric ID
+	Name     string
+	Language uint32
+	Data     []byte
+}
+
+// File gives access to the resources of a PE (EXE or DLL) file
+type File struct {
+	// section holds the raw content of the section containing the resource directory
+	section []byte
+	// sectionRVA is the virtual address of section
+	sectionRVA uint32
+	// root is the offset of the resource directory within section
+	root uint32
+}
+
+// Open parses the headers of a PE file and locates its resource directory
+func Open(r io.ReaderAt) (*File, error) {
+	f, err := pe.NewFile(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse PE file: %w", err)
+	}
+	defer f.Close()
+
+	var dir pe.DataDirectory
+	switch oh := f.OptionalHeader.(type) {
+	case *pe.OptionalHeader32:
+		if oh.NumberOfRvaAndSizes > pe.IMAGE_DIRECTORY_ENTRY_RESOURCE {
+			dir = oh.DataDirectory[pe.IMAGE_DIRECTORY_ENTRY_RESOURCE]
+		}
+	case *pe.OptionalHeader64:
+		if oh.NumberOfRvaAndSizes > pe.IMAGE_DIRECTORY_ENTRY_RESOURCE {
+			dir = oh.DataDirectory[pe.IMAGE_DIRECTORY_ENTRY_RESOURCE]
+		}
+	}
+
+	for _, s := range f.Sections {
+		var found bool
+		var root uint32
+		if dir.VirtualAddress != 0 {
+			size := max(s.VirtualSize, s.Size)
+			found = dir.VirtualAddress >= s.VirtualAddress && dir.VirtualAddress < s.VirtualAddress+size
+			root = dir.VirtualAddress - s.VirtualAddress
+		} else {
+			found = s.Name == ".rsrc"
+		}
+		if !found {
+			continue
+		}
+
+		data, err := s.Data()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read resource section: %w", err)
+		}
+		return &File{section: data, sectionRVA: s.VirtualAddress, root: root}, nil
+	}
+
+	return nil, fmt.Errorf("PE file has no resources")
+}
+
+// Resources returns every resource of the given type in directory order
+func (f *File) Resources(typ uint32) ([]Resource, error) {
+	var resources []Resource
+	err := f.walk(f.root, 0, Resource{}, func(r Resource) {
+		if r.Type == typ {
+			resources = append(resources, r)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resources, nil
+}
+
+// walk visits the resource directory at offset, filling in one level of the path per depth
+func (f *File) walk(offset uint32, depth int, path Resource, visit func(Resource)) error {
+	if depth >= maxResourceDepth {
+		return fmt.Errorf("resource directory is nested too deeply")
+	}
+	if uint64(offset)+16 > uint64(len(f.section)) {
+		return fmt.Errorf("resource directory at %#x is out of bounds", offset)
+	}
+
+	named := binary.LittleEndian.Uint16(f.section[offset+12:])
+	ids := binary.LittleEndian.Uint16(f.section[offset+14:])
+	for i := range uint32(named) + uint32(ids) {
+		entry := uint64(offset) + 16 + uint64(i)*8
+		if entry+8 > uint64(len(f.section)) {
+			return fmt.Errorf("resource directory entry at %#x is out of bounds", entry)
+		}
+		nameField := binary.LittleEndian.Uint32(f.section[entry:])
+		dataField := binary.LittleEndian.Uint32(f.section[entry+4:])
+
+		current := path
+		var id uint32
+		var name string
+		if nameField&0x80000000 != 0 {
+			var err error
+			if name, err = f.readName(nameField & 0x7FFFFFFF); err != nil {
+				return err
+			}
+		} else {
+			id = nameField
+		}
+		switch depth {
+		case 0:
+			current.Type = id
+		case 1:
+			current.ID, current.Name = id, name
+		case 2:
+			current.Language = id
+		}
+
+		if dataField&0x80000000 != 0 {
+			if err := f.walk(dataField&0x7FFFFFFF, depth+1, current, visit); err != nil {
+				return err
+			}
+			continue
+		}
+		data, err := f.readData(dataField)
+		if err != nil {
+			return err
+		}
+		current.Data = data
+		visit(current)
+	}
+	return nil
+}
+
+// readName reads a length-prefixed UTF-16 resource name
+func (f *File) readName(offset uint32) (string, error) {
+	if uint64(offset)+2 > uint64(len(f.section)) {
+		return "", fmt.Errorf("resource name at %#x is out of bounds", offset)
+	}
+	length := uint64(binary.LittleEndian.Uint16(f.section[offset:]))
+	start := uint64(offset) + 2
+	if start+length*2 > uint64(len(f.section)) {
+		return "", fmt.Errorf("resource name at %#x is out of bounds", offset)
+	}
+
+	units := make([]uint16, length)
+	for i := range units {
+		units[i] = binary.LittleEndian.Uint16(f.section[start+uint64(i)*2:])
+	}
+	return string(utf16.Decode(units)), nil
+}
+
+// readData reads the bytes described by the resource data entry at offset
+func (f *File) readData(offset uint32) ([]byte, error) {
+	if uint64(offset)+16 > uint64(len(f.section)) {
+		return nil, fmt.Errorf("resource data entry at %#x is out of bounds", offset)
+	}
+	rva := binary.LittleEndian.Uint32(f.section[offset:])
+	size := binary.LittleEndian.Uint32(f.section[offset+4:])
+
+	if rva < f.sectionRVA {
+		return nil, fmt.Errorf("resource data at RVA %#x is outside the resource section", rva)
+	}
+	start := uint64(rva - f.sectionRVA)
+	end := start + uint64(size)
+	if end > uint64(len(f.section)) {
+		return nil, fmt.Errorf("resource data at RVA %#x is out of bounds", rva)
+	}
+	return f.section[start:end], nil
+}
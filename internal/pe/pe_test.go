@@ -0,0 +1,152 @@
+package pe
+
+import (
+	"bytes"
+	"encoding/binary"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testSectionRVA    = 0x1000
+	testSectionOffset = 0x200
+)
+
+// buildResourceSection lays out a type/ID/language resource tree for resources
+func buildResourceSection(resources []Resource) []byte {
+	byType := map[uint32][]Resource{}
+	var types []uint32
+	for _, r := range resources {
+		if _, ok := byType[r.Type]; !ok {
+			types = append(types, r.Type)
+		}
+		byType[r.Type] = append(byType[r.Type], r)
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
+
+	// Directory sizes: a 16 byte header plus 8 bytes per entry
+	dirSize := func(entries int) uint32 { return 16 + uint32(entries)*8 }
+	offset := dirSize(len(types))
+	typeDirs := map[uint32]uint32{}
+	for _, typ := range types {
+		typeDirs[typ] = offset
+		offset += dirSize(len(byType[typ]))
+	}
+	langDirs := make([]uint32, len(resources))
+	order := make([]Resource, 0, len(resources))
+	for _, typ := range types {
+		order = append(order, byType[typ]...)
+	}
+	for i := range order {
+		langDirs[i] = offset
+		offset += dirSize(1)
+	}
+	dataEntries := make([]uint32, len(order))
+	for i := range order {
+		dataEntries[i] = offset
+		offset += 16
+	}
+
+	section := make([]byte, offset)
+	writeDir := func(at uint32, entries int) {
+		binary.LittleEndian.PutUint16(section[at+14:], uint16(entries))
+	}
+	writeEntry := func(dir uint32, index int, id, target uint32) {
+		binary.LittleEndian.PutUint32(section[dir+16+uint32(index)*8:], id)
+		binary.LittleEndian.PutUint32(section[dir+16+uint32(index)*8+4:], target)
+	}
+
+	writeDir(0, len(types))
+	i := 0
+	for t, typ := range types {
+		writeEntry(0, t, typ, typeDirs[typ]|0x80000000)
+		writeDir(typeDirs[typ], len(byType[typ]))
+		for n, r := range byType[typ] {
+			writeEntry(typeDirs[typ], n, r.ID, langDirs[i]|0x80000000)
+			writeDir(langDirs[i], 1)
+			writeEntry(langDirs[i], 0, r.Language, dataEntries[i])
+
+			binary.LittleEndian.PutUint32(section[dataEntries[i]:], testSectionRVA+uint32(len(section)))
+			binary.LittleEndian.PutUint32(section[dataEntries[i]+4:], uint32(len(r.Data)))
+			section = append(section, r.Data...)
+			i++
+		}
+	}
+	return section
+}
+
+// buildPE creates a minimal PE32 image with a single .rsrc section holding resources
+func buildPE(resources []Resource) []byte {
+	section := buildResourceSection(resources)
+
+	image := make([]byte, testSectionOffset)
+	copy(image, "MZ")
+	binary.LittleEndian.PutUint32(image[0x3C:], 0x40)
+	copy(image[0x40:], "PE\x00\x00")
+
+	coff := image[0x44:]
+	binary.LittleEndian.PutUint16(coff[0:], 0x14C)  // i386
+	binary.LittleEndian.PutUint16(coff[2:], 1)      // NumberOfSections
+	binary.LittleEndian.PutUint16(coff[16:], 224)   // SizeOfOptionalHeader
+	binary.LittleEndian.PutUint16(coff[18:], 0x102) // Characteristics
+
+	optional := coff[20:]
+	binary.LittleEndian.PutUint16(optional[0:], 0x10B) // PE32
+	binary.LittleEndian.PutUint32(optional[92:], 16)   // NumberOfRvaAndSizes
+	binary.LittleEndian.PutUint32(optional[96+2*8:], testSectionRVA)
+	binary.LittleEndian.PutUint32(optional[96+2*8+4:], uint32(len(section)))
+
+	header := optional[224:]
+	copy(header, ".rsrc")
+	binary.LittleEndian.PutUint32(header[8:], uint32(len(section)))  // VirtualSize
+	binary.LittleEndian.PutUint32(header[12:], testSectionRVA)       // VirtualAddress
+	binary.LittleEndian.PutUint32(header[16:], uint32(len(section))) // SizeOfRawData
+	binary.LittleEndian.PutUint32(header[20:], testSectionOffset)    // PointerToRawData
+
+	return append(image, section...)
+}
+
+func TestResources(t *testing.T) {
+	image := buildPE([]Resource{
+		{Type: TypeIcon, ID: 1, Language: 1033, Data: []byte("icon1")},
+		{Type: TypeIcon, ID: 2, Language: 1033, Data: []byte("icon2")},
+		{Type: TypeGroupIcon, ID: 101, Language: 1033, Data: []byte("group")},
+	})
+
+	f, err := Open(bytes.NewReader(image))
+	require.NoError(t, err)
+
+	icons, err := f.Resources(TypeIcon)
+	require.NoError(t, err)
+	require.Len(t, icons, 2)
+	assert.Equal(t, Resource{Type: TypeIcon, ID: 1, Language: 1033, Data: []byte("icon1")}, icons[0])
+	assert.Equal(t, []byte("icon2"), icons[1].Data)
+
+	groups, err := f.Resources(TypeGroupIcon)
+	require.NoError(t, err)
+	require.Len(t, groups, 1)
+	assert.Equal(t, uint32(101), groups[0].ID)
+
+	versions, err := f.Resources(TypeVersion)
+	require.NoError(t, err)
+	assert.Empty(t, versions)
+}
+
+func TestOpenInvalid(t *testing.T) {
+	_, err := Open(bytes.NewReader([]byte("not a PE file")))
+	assert.Error(t, err)
+}
+
+func TestResourcesOutOfBounds(t *testing.T) {
+	image := buildPE([]Resource{{Type: TypeIcon, ID: 1, Data: []byte("icon")}})
+	f, err := Open(bytes.NewReader(image))
+	require.NoError(t, err)
+
+	// Point the first type entry past the end of the section
+	binary.LittleEndian.PutUint32(f.section[20:], 0x80FFFFFF)
+	_, err = f.Resources(TypeIcon)
+	assert.Error(t, err)
+}
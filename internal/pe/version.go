@@ -0,0 +1,196 @@
+package pe
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf16"
+)
+
+// ErrNoVersion is returned when a PE file has no usable version resource
+var ErrNoVersion = errors.New("PE file has no version information")
+
+// fixedFileInfoSignature is the dwSignature of VS_FIXEDFILEINFO
+const fixedFileInfoSignature = 0xFEEF04BD
+
+// Well-known keys of the StringFileInfo table
+const (
+	KeyProductName     = "ProductName"
+	KeyProductVersion  = "ProductVersion"
+	KeyFileDescription = "FileDescription"
+	KeyFileVersion     = "FileVersion"
+	KeyCompanyName     = "CompanyName"
+)
+
+// VersionInfo is the content of the VS_VERSIONINFO resource of a PE file
+type VersionInfo struct {
+	// FileVersion is the binary file version from VS_FIXEDFILEINFO, such as 1.2.3.4
+	FileVersion string `json:"fileVersion,omitempty"`
+	// ProductVersion is the binary product version from VS_FIXEDFILEINFO
+	ProductVersion string `json:"productVersion,omitempty"`
+	// Strings holds the StringFileInfo values, such as ProductName and CompanyName
+	Strings map[string]string `json:"strings,omitempty"`
+}
+
+// ProductName returns the ProductName string, falling back to FileDescription
+func (v *VersionInfo) ProductName() string {
+	if name := v.Strings[KeyProductName]; name != "" {
+		return name
+	}
+	return v.Strings[KeyFileDescription]
+}
+
+// DisplayVersion returns the FileVersion string, falling back to the binary file version
+func (v *VersionInfo) DisplayVersion() string {
+	if version := v.Strings[KeyFileVersion]; version != "" {
+		return version
+	}
+	return v.FileVersion
+}
+
+// ReadVersion parses the PE file in r and returns its version information
+func ReadVersion(r io.ReaderAt) (*VersionInfo, error) {
+	f, err := Open(r)
+	if err != nil {
+		return nil, err
+	}
+	return f.Version()
+}
+
+// Version returns the version information of the first RT_VERSION resource
+func (f *File) Version() (*VersionInfo, error) {
+	resources, err := f.Resources(TypeVersion)
+	if err != nil {
+		return nil, err
+	}
+	if len(resources) == 0 {
+		return nil, ErrNoVersion
+	}
+	return parseVersionInfo(resources[0].Data)
+}
+
+// versionBlock is a node of the VS_VERSIONINFO tree: a length, key, value and children
+type versionBlock struct {
+	key   string
+	value []byte
+	// children is the offset range of the child blocks within the resource data
+	children [2]int
+}
+
+// parseVersionInfo decodes VS_VERSIONINFO data
+func parseVersionInfo(data []byte) (*VersionInfo, error) {
+	root, _, err := readVersionBlock(data, 0)
+	if err != nil {
+		return nil, err
+	}
+	if root.key != "VS_VERSION_INFO" {
+		return nil, fmt.Errorf("%w: unexpected root key %q", ErrNoVersion, root.key)
+	}
+
+	info := &VersionInfo{Strings: map[string]string{}}
+	if len(root.value) >= 24 && binary.LittleEndian.Uint32(root.value) == fixedFileInfoSignature {
+		info.FileVersion = formatVersion(root.value[8:])
+		info.ProductVersion = formatVersion(root.value[16:])
+	}
+
+	err = eachVersionBlock(data, root.children, func(child versionBlock) error {
+		if child.key != "StringFileInfo" {
+			return nil
+		}
+		return eachVersionBlock(data, child.children, func(table versionBlock) error {
+			english := strings.HasPrefix(strings.ToLower(table.key), "0409")
+			return eachVersionBlock(data, table.children, func(s versionBlock) error {
+				// Prefer US English values when the file has several languages
+				if _, ok := info.Strings[s.key]; !ok || english {
+					info.Strings[s.key] = decodeUTF16String(s.value)
+				}
+				return nil
+			})
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// eachVersionBlock calls fn for every block in the given offset range of data
+func eachVersionBlock(data []byte, span [2]int, fn func(versionBlock) error) error {
+	for offset := span[0]; offset < span[1]; {
+		block, next, err := readVersionBlock(data[:span[1]], offset)
+		if err != nil {
+			return err
+		}
+		if err := fn(block); err != nil {
+			return err
+		}
+		offset = next
+	}
+	return nil
+}
+
+// readVersionBlock reads the block at offset and returns the offset of the next sibling.
+// Offsets are aligned to 32 bits relative to the start of the resource data.
+func readVersionBlock(data []byte, offset int) (versionBlock, int, error) {
+	if offset+6 > len(data) {
+		return versionBlock{}, 0, fmt.Errorf("version block at %#x is out of bounds", offset)
+	}
+	length := int(binary.LittleEndian.Uint16(data[offset:]))
+	valueLength := int(binary.LittleEndian.Uint16(data[offset+2:]))
+	text := binary.LittleEndian.Uint16(data[offset+4:]) == 1
+	end := offset + length
+	if length < 6 || end > len(data) {
+		return versionBlock{}, 0, fmt.Errorf("version block at %#x has invalid length %d", offset, length)
+	}
+
+	pos := offset + 6
+	var units []uint16
+	for ; ; pos += 2 {
+		if pos+2 > end {
+			return versionBlock{}, 0, fmt.Errorf("version block key at %#x is not terminated", offset)
+		}
+		unit := binary.LittleEndian.Uint16(data[pos:])
+		if unit == 0 {
+			pos += 2
+			break
+		}
+		units = append(units, unit)
+	}
+	pos = min(align4(pos), end)
+
+	// wValueLength counts characters for text values and bytes otherwise
+	if text {
+		valueLength *= 2
+	}
+	valueEnd := min(pos+valueLength, end)
+	block := versionBlock{key: string(utf16.Decode(units)), value: data[pos:valueEnd]}
+	block.children = [2]int{min(align4(valueEnd), end), end}
+	return block, align4(end), nil
+}
+
+// decodeUTF16String decodes a little endian UTF-16 value up to its terminator
+func decodeUTF16String(b []byte) string {
+	units := make([]uint16, 0, len(b)/2)
+	for i := 0; i+2 <= len(b); i += 2 {
+		unit := binary.LittleEndian.Uint16(b[i:])
+		if unit == 0 {
+			break
+		}
+		units = append(units, unit)
+	}
+	return strings.TrimSpace(string(utf16.Decode(units)))
+}
+
+// formatVersion formats the MS/LS dword pair at b as major.minor.build.revision
+func formatVersion(b []byte) string {
+	ms := binary.LittleEndian.Uint32(b)
+	ls := binary.LittleEndian.Uint32(b[4:])
+	return fmt.Sprintf("%d.%d.%d.%d", ms>>16, ms&0xFFFF, ls>>16, ls&0xFFFF)
+}
+
+// align4 rounds offset up to a multiple of 4
+func align4(offset int) int {
+	return (offset + 3) &^ 3
+}
@@ -0,0 +1,142 @@
+package pe
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"unicode/utf16"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// utf16z encodes s as NUL terminated little endian UTF-16
+func utf16z(s string) []byte {
+	units := append(utf16.Encode([]rune(s)), 0)
+	b := make([]byte, len(units)*2)
+	for i, u := range units {
+		binary.LittleEndian.PutUint16(b[i*2:], u)
+	}
+	return b
+}
+
+// pad4 appends zero bytes to b up to a multiple of 4
+func pad4(b []byte) []byte {
+	for len(b)%4 != 0 {
+		b = append(b, 0)
+	}
+	return b
+}
+
+// buildVersionBlock lays out a VS_VERSIONINFO style block with its children
+func buildVersionBlock(key string, value []byte, text bool, children ...[]byte) []byte {
+	b := make([]byte, 6)
+	valueLength := len(value)
+	if text {
+		valueLength /= 2
+		binary.LittleEndian.PutUint16(b[4:], 1)
+	}
+	binary.LittleEndian.PutUint16(b[2:], uint16(valueLength))
+	b = pad4(append(b, utf16z(key)...))
+	b = append(b, value...)
+	for _, child := range children {
+		b = append(pad4(b), child...)
+	}
+	binary.LittleEndian.PutUint16(b[0:], uint16(len(b)))
+	return b
+}
+
+// buildVersionInfo creates VS_VERSIONINFO data with the given fixed file version and string tables
+func buildVersionInfo(ms, ls uint32, tables map[string]map[string]string) []byte {
+	fixed := make([]byte, 52)
+	binary.LittleEndian.PutUint32(fixed[0:], fixedFileInfoSignature)
+	binary.LittleEndian.PutUint32(fixed[8:], ms)
+	binary.LittleEndian.PutUint32(fixed[12:], ls)
+	binary.LittleEndian.PutUint32(fixed[16:], ms)
+	binary.LittleEndian.PutUint32(fixed[20:], ls)
+
+	var stringTables [][]byte
+	for _, lang := range []string{"040904b0", "041104b0"} {
+		values, ok := tables[lang]
+		if !ok {
+			continue
+		}
+		var strs [][]byte
+		for _, key := range []string{KeyCompanyName, KeyFileDescription, KeyFileVersion, KeyProductName} {
+			if value, ok := values[key]; ok {
+				strs = append(strs, buildVersionBlock(key, utf16z(value), true))
+			}
+		}
+		stringTables = append(stringTables, buildVersionBlock(lang, nil, true, strs...))
+	}
+
+	return buildVersionBlock("VS_VERSION_INFO", fixed, false,
+		buildVersionBlock("StringFileInfo", nil, true, stringTables...),
+		buildVersionBlock("VarFileInfo", nil, true,
+			buildVersionBlock("Translation", []byte{0x09, 0x04, 0xb0, 0x04}, false)),
+	)
+}
+
+func TestVersion(t *testing.T) {
+	data := buildVersionInfo(0x00020001, 0x00040003, map[string]map[string]string{
+		"040904b0": {KeyProductName: "My App", KeyFileVersion: "2.1.4", KeyCompanyName: "Contoso"},
+	})
+	image := buildPE([]Resource{{Type: TypeVersion, ID: 1, Language: 1033, Data: data}})
+
+	info, err := ReadVersion(bytes.NewReader(image))
+	require.NoError(t, err)
+	assert.Equal(t, "2.1.4.3", info.FileVersion)
+	assert.Equal(t, "2.1.4.3", info.ProductVersion)
+	assert.Equal(t, map[string]string{
+		KeyProductName: "My App",
+		KeyFileVersion: "2.1.4",
+		KeyCompanyName: "Contoso",
+	}, info.Strings)
+	assert.Equal(t, "My App", info.ProductName())
+	assert.Equal(t, "2.1.4", info.DisplayVersion())
+}
+
+func TestVersionPrefersEnglish(t *testing.T) {
+	data := buildVersionInfo(0x00010000, 0, map[string]map[string]string{
+		"041104b0": {KeyProductName: "マイアプリ", KeyFileDescription: "説明"},
+		"040904b0": {KeyProductName: "My App"},
+	})
+
+	info, err := parseVersionInfo(data)
+	require.NoError(t, err)
+	assert.Equal(t, "My App", info.ProductName())
+	assert.Equal(t, "説明", info.Strings[KeyFileDescription])
+	assert.Equal(t, "1.0.0.0", info.DisplayVersion())
+}
+
+func TestVersionFallbacks(t *testing.T) {
+	info := &VersionInfo{FileVersion: "1.2.3.4", Strings: map[string]string{KeyFileDescription: "Setup"}}
+	assert.Equal(t, "Setup", info.ProductName())
+	assert.Equal(t, "1.2.3.4", info.DisplayVersion())
+}
+
+func TestVersionMissing(t *testing.T) {
+	image := buildPE([]Resource{{Type: TypeIcon, ID: 1, Data: []byte("icon")}})
+	_, err := ReadVersion(bytes.NewReader(image))
+	assert.ErrorIs(t, err, ErrNoVersion)
+}
+
+func TestVersionInvalid(t *testing.T) {
+	data := buildVersionInfo(0, 0, map[string]map[string]string{"040904b0": {KeyProductName: "App"}})
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{name: "truncated", data: data[:len(data)/2]},
+		{name: "too short", data: []byte{1, 0}},
+		{name: "wrong key", data: buildVersionBlock("Other", nil, false)},
+		{name: "unterminated key", data: []byte{8, 0, 0, 0, 0, 0, 'A', 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseVersionInfo(tt.data)
+			assert.Error(t, err)
+		})
+	}
+}
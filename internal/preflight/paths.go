@@ -0,0 +1,110 @@
+package preflight
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"unicode/utf16"
+)
+
+const machineSpecificPathsCheck = "machine-specific-path"
+
+var (
+	// userProfilePattern matches hardcoded user profile paths such as C:\Users\alice
+	userProfilePattern = regexp.MustCompile(`(?i)\b[a-z]:[\\/]users[\\/]([^\\/\s"'%$<>|*?:;,]+)`)
+	// uncPattern matches the server part of UNC paths such as \\BUILD01\share
+	uncPattern = regexp.MustCompile(`(?:^|[\s"'=(,])\\\\([A-Za-z0-9][A-Za-z0-9_-]{0,62})\\[^\\\s]`)
+
+	// sharedProfiles are profile folders that exist on every Windows machine.
+	// "Default User" and "All Users" are matched up to the space.
+	sharedProfiles = map[string]bool{
+		"public":  true,
+		"default": true,
+		"all":     true,
+	}
+
+	// hostname returns the name of the packaging machine
+	hostname = os.Hostname
+)
+
+// checkMachineSpecificPaths flags user profile paths, UNC server names and the
+// packaging machine's host name, which usually only resolve on the packager's machine
+func checkMachineSpecificPaths(path string, content []byte) []Finding {
+	text := decodeText(content)
+	host, _ := hostname()
+	host = strings.ToLower(host)
+	if len(host) < 3 || host == "localhost" {
+		host = ""
+	}
+
+	var findings []Finding
+	for i, line := range strings.Split(text, "\n") {
+		finding := func(format string, args ...any) {
+			findings = append(findings, Finding{
+				Check:    machineSpecificPathsCheck,
+				Severity: Warning,
+				Path:     path,
+				Line:     i + 1,
+				Message:  fmt.Sprintf(format, args...),
+			})
+		}
+
+		for _, m := range userProfilePattern.FindAllStringSubmatch(line, -1) {
+			if !sharedProfiles[strings.ToLower(m[1])] {
+				finding(`hardcoded user profile path "%s"`, m[0])
+			}
+		}
+		for _, m := range uncPattern.FindAllStringSubmatch(line, -1) {
+			finding("UNC path to machine %q", m[1])
+		}
+		if host != "" && containsWord(strings.ToLower(line), host) {
+			finding("reference to the packaging machine name %q", host)
+		}
+	}
+	return findings
+}
+
+// containsWord reports whether word appears in s delimited by non-alphanumeric characters
+func containsWord(s, word string) bool {
+	for offset := 0; ; {
+		i := strings.Index(s[offset:], word)
+		if i < 0 {
+			return false
+		}
+		start := offset + i
+		end := start + len(word)
+		if (start == 0 || !isWordChar(s[start-1])) && (end == len(s) || !isWordChar(s[end])) {
+			return true
+		}
+		offset = start + 1
+	}
+}
+
+// isWordChar reports whether c can be part of a host name
+func isWordChar(c byte) bool {
+	return c == '-' || c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// decodeText converts content to a string, decoding UTF-16 files marked with a byte order mark.
+// Windows scripts and .reg files are commonly saved as UTF-16.
+func decodeText(content []byte) string {
+	var order binary.ByteOrder
+	switch {
+	case bytes.HasPrefix(content, []byte{0xFF, 0xFE}):
+		order = binary.LittleEndian
+	case bytes.HasPrefix(content, []byte{0xFE, 0xFF}):
+		order = binary.BigEndian
+	default:
+		return strings.ReplaceAll(string(content), "\r\n", "\n")
+	}
+
+	content = content[2:]
+	units := make([]uint16, len(content)/2)
+	for i := range units {
+		units[i] = order.Uint16(content[i*2:])
+	}
+	return strings.ReplaceAll(string(utf16.Decode(units)), "\r\n", "\n")
+}
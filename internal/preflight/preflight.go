@@ -0,0 +1,121 @@
+package preflight
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/kenchan0130/intunewin/internal/pathutil"
+)
+
+// maxScanSize is the largest file whose content is scanned
+const maxScanSize = 10 * 1024 * 1024
+
+// Severity describes how serious a finding is
+type Severity string
+
+const (
+	// Warning findings are reported but do not stop packing
+	Warning Severity = "warning"
+	// Error findings stop packing
+	Error Severity = "error"
+)
+
+// Finding is a single issue found in the source folder
+type Finding struct {
+	Check    string
+	Severity Severity
+	// Path is the slash-separated path relative to the source folder
+	Path string
+	// Line is the 1-based line number, or 0 when the finding is not about file content
+	Line    int
+	Message string
+}
+
+// String formats the finding for display
+func (f Finding) String() string {
+	location := f.Path
+	if f.Line > 0 {
+		location = fmt.Sprintf("%s:%d", f.Path, f.Line)
+	}
+	return fmt.Sprintf("%s: %s: %s [%s]", f.Severity, location, f.Message, f.Check)
+}
+
+// Report collects the findings of a preflight run
+type Report struct {
+	Findings []Finding
+}
+
+// HasErrors reports whether any finding has Error severity
+func (r *Report) HasErrors() bool {
+	for _, f := range r.Findings {
+		if f.Severity == Error {
+			return true
+		}
+	}
+	return false
+}
+
+// Print writes every finding to w, one per line
+func (r *Report) Print(w io.Writer) {
+	for _, f := range r.Findings {
+		fmt.Fprintln(w, f.String())
+	}
+}
+
+// contentCheck inspects the content of a text file
+type contentCheck func(path string, content []byte) []Finding
+
+// contentChecks are run against every scannable text file
+var contentChecks = []contentCheck{
+	checkMachineSpecificPaths,
+}
+
+// scannableExtensions lists script and configuration file types whose content is checked
+var scannableExtensions = map[string]bool{
+	".bat": true, ".cfg": true, ".cmd": true, ".config": true, ".inf": true,
+	".ini": true, ".js": true, ".json": true, ".properties": true, ".ps1": true,
+	".psd1": true, ".psm1": true, ".reg": true, ".txt": true, ".vbs": true,
+	".wsf": true, ".xml": true, ".yaml": true, ".yml": true,
+}
+
+// Run checks the files below sourceFolder, or the single file it names, and returns the findings
+func Run(sourceFolder string) (*Report, error) {
+	report := &Report{}
+	err := filepath.Walk(sourceFolder, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() || info.Size() > maxScanSize {
+			return nil
+		}
+		if !scannableExtensions[strings.ToLower(filepath.Ext(path))] {
+			return nil
+		}
+
+		relPath, err := filepath.Rel(sourceFolder, path)
+		if err != nil {
+			return fmt.Errorf("failed to get relative path: %w", err)
+		}
+		if relPath == "." {
+			// sourceFolder is a single file being packed on its own
+			relPath = filepath.Base(path)
+		}
+
+		content, err := os.ReadFile(path) // #nosec G304 -- path comes from walking the source folder
+		if err != nil {
+			return fmt.Errorf("failed to read file %s: %w", path, err)
+		}
+
+		for _, check := range contentChecks {
+			report.Findings = append(report.Findings, check(pathutil.ToArchive(relPath), content)...)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan source folder: %w", err)
+	}
+	return report, nil
+}
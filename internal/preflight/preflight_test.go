@@ -0,0 +1,109 @@
+package preflight
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"unicode/utf16"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckMachineSpecificPaths(t *testing.T) {
+	original := hostname
+	hostname = func() (string, error) { return "BUILD-AGENT-07", nil }
+	t.Cleanup(func() { hostname = original })
+
+	tests := []struct {
+		name     string
+		content  string
+		messages []string
+	}{
+		{
+			name:     "User profile path",
+			content:  `Copy-Item "C:\Users\alice\Desktop\app.cfg" $dest`,
+			messages: []string{`hardcoded user profile path "C:\Users\alice"`},
+		},
+		{
+			name:     "Forward slash user profile path",
+			content:  `path=c:/users/bob/config.ini`,
+			messages: []string{`hardcoded user profile path "c:/users/bob"`},
+		},
+		{
+			name:    "Shared profiles are allowed",
+			content: `C:\Users\Public\Documents and C:\Users\Default\NTUSER.DAT`,
+		},
+		{
+			name:    "Environment variables are allowed",
+			content: `C:\Users\%USERNAME%\AppData and C:\Users\$env:USERNAME`,
+		},
+		{
+			name:     "UNC path",
+			content:  `xcopy \\fileserver01\share\setup.exe .`,
+			messages: []string{`UNC path to machine "fileserver01"`},
+		},
+		{
+			name:     "Packaging machine name",
+			content:  `$server = "build-agent-07"`,
+			messages: []string{`reference to the packaging machine name "build-agent-07"`},
+		},
+		{
+			name:    "Machine name as part of a longer word",
+			content: `$server = "build-agent-070"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			findings := checkMachineSpecificPaths("install.ps1", []byte(tt.content))
+			messages := make([]string, 0, len(findings))
+			for _, f := range findings {
+				assert.Equal(t, Warning, f.Severity)
+				assert.Equal(t, 1, f.Line)
+				messages = append(messages, f.Message)
+			}
+			if tt.messages == nil {
+				assert.Empty(t, messages)
+			} else {
+				assert.Equal(t, tt.messages, messages)
+			}
+		})
+	}
+}
+
+func TestDecodeTextUTF16(t *testing.T) {
+	units := utf16.Encode([]rune("line1\r\nC:\\Users\\alice"))
+	content := []byte{0xFF, 0xFE}
+	for _, u := range units {
+		content = append(content, byte(u), byte(u>>8))
+	}
+
+	assert.Equal(t, "line1\nC:\\Users\\alice", decodeText(content))
+}
+
+func TestRun(t *testing.T) {
+	sourceDir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(sourceDir, "scripts"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(sourceDir, "scripts", "install.cmd"), []byte("@echo off\r\ncopy C:\\Users\\carol\\app.ini ."), 0600))
+	// Binary files are not scanned
+	require.NoError(t, os.WriteFile(filepath.Join(sourceDir, "setup.exe"), []byte("C:\\Users\\carol"), 0600))
+
+	report, err := Run(sourceDir)
+	require.NoError(t, err)
+	require.Len(t, report.Findings, 1)
+	assert.Equal(t, "scripts/install.cmd", report.Findings[0].Path)
+	assert.Equal(t, 2, report.Findings[0].Line)
+	assert.False(t, report.HasErrors())
+	assert.Equal(t, `warning: scripts/install.cmd:2: hardcoded user profile path "C:\Users\carol" [machine-specific-path]`, report.Findings[0].String())
+}
+
+func TestRunSingleFile(t *testing.T) {
+	script := filepath.Join(t.TempDir(), "install.cmd")
+	require.NoError(t, os.WriteFile(script, []byte("copy C:\\Users\\carol\\app.ini ."), 0600))
+
+	report, err := Run(script)
+	require.NoError(t, err)
+	require.Len(t, report.Findings, 1)
+	assert.Equal(t, "install.cmd", report.Findings[0].Path)
+}
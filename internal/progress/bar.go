@@ -0,0 +1,134 @@
+package progress
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	barWidth       = 30
+	redrawInterval = 100 * time.Millisecond
+)
+
+// Bar renders progress updates as a single line progress bar with an ETA.
+// It is meant for terminals; each redraw overwrites the line with a carriage return.
+type Bar struct {
+	w   io.Writer
+	now func() time.Time
+
+	mu        sync.Mutex
+	stage     Stage
+	started   time.Time
+	lastDraw  time.Time
+	lineWidth int
+	// pending holds the last throttled line so it can be drawn before the stage changes
+	pending string
+}
+
+// NewBar creates a Bar writing to w
+func NewBar(w io.Writer) *Bar {
+	return &Bar{w: w, now: time.Now}
+}
+
+// Progress implements Reporter
+func (b *Bar) Progress(stage Stage, current, total int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	if stage != b.stage {
+		b.flush()
+		if b.stage != "" {
+			fmt.Fprintln(b.w)
+		}
+		b.stage = stage
+		b.started = now
+		b.lineWidth = 0
+	} else if now.Sub(b.lastDraw) < redrawInterval && (total == 0 || current < total) {
+		b.pending = b.line(current, total, now.Sub(b.started))
+		return
+	}
+	b.lastDraw = now
+	b.draw(b.line(current, total, now.Sub(b.started)))
+}
+
+// flush draws the last throttled line
+func (b *Bar) flush() {
+	if b.pending != "" {
+		b.draw(b.pending)
+	}
+}
+
+// Finish ends the progress line
+func (b *Bar) Finish() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.stage != "" {
+		b.flush()
+		fmt.Fprintln(b.w)
+		b.stage = ""
+	}
+}
+
+// line formats the progress of the current stage
+func (b *Bar) line(current, total int64, elapsed time.Duration) string {
+	if total <= 0 {
+		return fmt.Sprintf("%-8s %s", b.stage, FormatBytes(current))
+	}
+
+	ratio := float64(current) / float64(total)
+	if ratio > 1 {
+		ratio = 1
+	}
+	filled := int(ratio * barWidth)
+	bar := strings.Repeat("=", filled)
+	if filled < barWidth {
+		bar += ">" + strings.Repeat(" ", barWidth-filled-1)
+	}
+
+	eta := "--:--"
+	if current > 0 && elapsed > 0 {
+		remaining := time.Duration(float64(elapsed) * (1 - ratio) / ratio)
+		eta = formatDuration(remaining)
+	}
+	return fmt.Sprintf("%-8s [%s] %3.0f%% %s/%s ETA %s",
+		b.stage, bar, ratio*100, FormatBytes(current), FormatBytes(total), eta)
+}
+
+// draw overwrites the current line with line
+func (b *Bar) draw(line string) {
+	padding := ""
+	if len(line) < b.lineWidth {
+		padding = strings.Repeat(" ", b.lineWidth-len(line))
+	}
+	b.lineWidth = len(line)
+	b.pending = ""
+	fmt.Fprintf(b.w, "\r%s%s", line, padding)
+}
+
+// FormatBytes formats n with a binary unit, such as 1.5 KiB
+func FormatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// formatDuration formats d as minutes and seconds, or hours, minutes and seconds
+func formatDuration(d time.Duration) string {
+	seconds := int64(d.Round(time.Second).Seconds())
+	if seconds >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", seconds/3600, seconds/60%60, seconds%60)
+	}
+	return fmt.Sprintf("%02d:%02d", seconds/60, seconds%60)
+}
@@ -0,0 +1,86 @@
+package progress
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+// Event is a progress update written by JSON
+type Event struct {
+	Stage Stage `json:"stage"`
+	// Bytes is the number of bytes processed so far in the stage
+	Bytes int64 `json:"bytes"`
+	// Total is the total number of bytes of the stage, or 0 when it is not known yet
+	Total int64 `json:"total"`
+	// File is the slash-separated path of the file being processed, if any
+	File string `json:"file,omitempty"`
+}
+
+// JSON writes progress updates to w as newline-delimited JSON events.
+// Updates are throttled like Bar, but the first and last update of a stage and
+// every file change are always written.
+type JSON struct {
+	w   io.Writer
+	now func() time.Time
+
+	mu        sync.Mutex
+	enc       *json.Encoder
+	event     Event
+	pending   bool
+	lastWrite time.Time
+}
+
+// NewJSON creates a JSON reporter writing to w
+func NewJSON(w io.Writer) *JSON {
+	return &JSON{w: w, now: time.Now, enc: json.NewEncoder(w)}
+}
+
+// Progress implements Reporter
+func (j *JSON) Progress(stage Stage, current, total int64) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	now := j.now()
+	newStage := stage != j.event.Stage
+	if newStage {
+		j.startStage(stage, now)
+	}
+	j.event.Bytes = current
+	j.event.Total = total
+
+	if !newStage && now.Sub(j.lastWrite) < redrawInterval && (total == 0 || current < total) {
+		j.pending = true
+		return
+	}
+	j.write(now)
+}
+
+// File implements FileReporter
+func (j *JSON) File(stage Stage, path string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	now := j.now()
+	if stage != j.event.Stage {
+		j.startStage(stage, now)
+	}
+	j.event.File = path
+	j.write(now)
+}
+
+// startStage writes the last update of the previous stage if it was throttled and resets the event
+func (j *JSON) startStage(stage Stage, now time.Time) {
+	if j.pending {
+		j.write(now)
+	}
+	j.event = Event{Stage: stage}
+}
+
+// write encodes the current event; errors are ignored so that a closed pipe does not fail packing
+func (j *JSON) write(now time.Time) {
+	j.lastWrite = now
+	j.pending = false
+	_ = j.enc.Encode(j.event)
+}
@@ -0,0 +1,65 @@
+package progress
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Log writes every processed file and the duration of each stage to a logger at debug level
+type Log struct {
+	logger *slog.Logger
+	now    func() time.Time
+
+	mu      sync.Mutex
+	stage   Stage
+	start   time.Time
+	current int64
+}
+
+// NewLog creates a Log reporter writing to logger
+func NewLog(logger *slog.Logger) *Log {
+	return &Log{logger: logger, now: time.Now}
+}
+
+// Progress implements Reporter
+func (l *Log) Progress(stage Stage, current, total int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if stage != l.stage {
+		l.startStage(stage)
+	}
+	l.current = current
+}
+
+// File implements FileReporter
+func (l *Log) File(stage Stage, path string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if stage != l.stage {
+		l.startStage(stage)
+	}
+	l.logger.Debug("processing file", "stage", stage, "path", path,
+		"elapsed", l.now().Sub(l.start).Round(time.Millisecond))
+}
+
+// Finish logs the duration of the last stage
+func (l *Log) Finish() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.startStage("")
+}
+
+// startStage logs the duration of the current stage, if any, and starts timing stage
+func (l *Log) startStage(stage Stage) {
+	now := l.now()
+	if l.stage != "" {
+		l.logger.Debug("finished stage", "stage", l.stage, "bytes", l.current,
+			"duration", now.Sub(l.start).Round(time.Millisecond))
+	}
+	l.stage = stage
+	l.start = now
+	l.current = 0
+}
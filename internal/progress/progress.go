@@ -0,0 +1,123 @@
+package progress
+
+import (
+	"io"
+	"sync"
+)
+
+// Stage identifies a step of packing or publishing
+type Stage string
+
+const (
+	// Walk counts the bytes of the files found in the source folder
+	Walk Stage = "walk"
+	// Compress counts the bytes of source files written to the content zip
+	Compress Stage = "compress"
+	// Encrypt counts the bytes of the content zip that have been encrypted
+	Encrypt Stage = "encrypt"
+	// Upload counts the bytes of the encrypted content uploaded to Azure Storage
+	Upload Stage = "upload"
+)
+
+// Reporter receives progress updates
+type Reporter interface {
+	// Progress is called with the bytes processed so far in stage and the total
+	// for the stage, or 0 when the total is not known yet
+	Progress(stage Stage, current, total int64)
+}
+
+// FileReporter is implemented by reporters that also want to know which file is being processed
+type FileReporter interface {
+	// File is called when processing of path starts in stage
+	File(stage Stage, path string)
+}
+
+// ReportFile tells reporter that processing of path started, if it implements FileReporter
+func ReportFile(reporter Reporter, stage Stage, path string) {
+	if fr, ok := reporter.(FileReporter); ok {
+		fr.File(stage, path)
+	}
+}
+
+// ReporterFunc adapts a function to the Reporter interface
+type ReporterFunc func(stage Stage, current, total int64)
+
+// Progress calls f
+func (f ReporterFunc) Progress(stage Stage, current, total int64) {
+	f(stage, current, total)
+}
+
+// Nop is a Reporter that discards every update
+var Nop Reporter = ReporterFunc(func(Stage, int64, int64) {})
+
+// Multi returns a Reporter that forwards every update to all reporters
+func Multi(reporters ...Reporter) Reporter {
+	return multiReporter(reporters)
+}
+
+type multiReporter []Reporter
+
+func (m multiReporter) Progress(stage Stage, current, total int64) {
+	for _, r := range m {
+		r.Progress(stage, current, total)
+	}
+}
+
+func (m multiReporter) File(stage Stage, path string) {
+	for _, r := range m {
+		ReportFile(r, stage, path)
+	}
+}
+
+// Counter accumulates the bytes processed in a stage and reports each change.
+// It is safe for concurrent use; calls to the reporter are serialized.
+type Counter struct {
+	reporter Reporter
+	stage    Stage
+	total    int64
+
+	mu      sync.Mutex
+	current int64
+}
+
+// NewCounter creates a Counter for stage with the given total and reports the start of the stage
+func NewCounter(reporter Reporter, stage Stage, total int64) *Counter {
+	if reporter == nil {
+		reporter = Nop
+	}
+	reporter.Progress(stage, 0, total)
+	return &Counter{reporter: reporter, stage: stage, total: total}
+}
+
+// File reports that processing of path started
+func (c *Counter) File(path string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ReportFile(c.reporter, c.stage, path)
+}
+
+// Add records n more processed bytes
+func (c *Counter) Add(n int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.current += n
+	c.reporter.Progress(c.stage, c.current, c.total)
+}
+
+// Reader returns a reader that adds every byte read from r to the counter
+func (c *Counter) Reader(r io.Reader) io.Reader {
+	return &countingReader{r: r, counter: c}
+}
+
+type countingReader struct {
+	r       io.Reader
+	counter *Counter
+}
+
+func (cr *countingReader) Read(p []byte) (int, error) {
+	n, err := cr.r.Read(p)
+	if n > 0 {
+		cr.counter.Add(int64(n))
+	}
+	return n, err //nolint:wrapcheck // io.Reader errors such as io.EOF must not be wrapped
+}
@@ -0,0 +1,177 @@
+package progress
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type update struct {
+	stage          Stage
+	current, total int64
+}
+
+func TestCounterReader(t *testing.T) {
+	var updates []update
+	reporter := ReporterFunc(func(stage Stage, current, total int64) {
+		updates = append(updates, update{stage, current, total})
+	})
+
+	counter := NewCounter(reporter, Compress, 10)
+	_, err := io.Copy(io.Discard, counter.Reader(strings.NewReader("hello")))
+	require.NoError(t, err)
+	_, err = io.Copy(io.Discard, counter.Reader(strings.NewReader("world")))
+	require.NoError(t, err)
+
+	require.NotEmpty(t, updates)
+	assert.Equal(t, update{Compress, 10, 10}, updates[len(updates)-1])
+}
+
+func TestNewCounterNilReporter(t *testing.T) {
+	counter := NewCounter(nil, Encrypt, 5)
+	assert.NotPanics(t, func() { counter.Add(5) })
+}
+
+func TestBar(t *testing.T) {
+	out := new(bytes.Buffer)
+	bar := NewBar(out)
+	now := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	bar.now = func() time.Time { return now }
+
+	bar.Progress(Walk, 2048, 0)
+	assert.Equal(t, "\rwalk     2.0 KiB", out.String())
+
+	out.Reset()
+	bar.Progress(Encrypt, 0, 4<<20)
+	assert.Equal(t, "\n\rencrypt  [>                             ]   0% 0 B/4.0 MiB ETA --:--", out.String())
+
+	// Updates within the redraw interval are skipped
+	out.Reset()
+	now = now.Add(10 * time.Millisecond)
+	bar.Progress(Encrypt, 1<<20, 4<<20)
+	assert.Empty(t, out.String())
+
+	out.Reset()
+	now = now.Add(9990 * time.Millisecond)
+	bar.Progress(Encrypt, 1<<20, 4<<20)
+	assert.Equal(t, "\rencrypt  [=======>                      ]  25% 1.0 MiB/4.0 MiB ETA 00:30", out.String())
+
+	// Completion is always drawn
+	out.Reset()
+	now = now.Add(time.Millisecond)
+	bar.Progress(Encrypt, 4<<20, 4<<20)
+	assert.Contains(t, out.String(), "100%")
+
+	out.Reset()
+	bar.Finish()
+	assert.Equal(t, "\n", out.String())
+}
+
+func TestFormatBytes(t *testing.T) {
+	assert.Equal(t, "512 B", FormatBytes(512))
+	assert.Equal(t, "1.5 KiB", FormatBytes(1536))
+	assert.Equal(t, "6.0 GiB", FormatBytes(6<<30))
+}
+
+func TestFormatDuration(t *testing.T) {
+	assert.Equal(t, "00:05", formatDuration(5*time.Second))
+	assert.Equal(t, "02:03", formatDuration(123*time.Second))
+	assert.Equal(t, "1:01:01", formatDuration(3661*time.Second))
+}
+
+func TestJSON(t *testing.T) {
+	out := new(bytes.Buffer)
+	reporter := NewJSON(out)
+	now := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	reporter.now = func() time.Time { return now }
+
+	reporter.File(Compress, "bin/setup.exe")
+	reporter.Progress(Compress, 100, 1000)
+	// Throttled
+	reporter.Progress(Compress, 200, 1000)
+	now = now.Add(time.Second)
+	reporter.Progress(Compress, 1000, 1000)
+	reporter.Progress(Encrypt, 0, 2000)
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	assert.Equal(t, []string{
+		`{"stage":"compress","bytes":0,"total":0,"file":"bin/setup.exe"}`,
+		`{"stage":"compress","bytes":1000,"total":1000,"file":"bin/setup.exe"}`,
+		`{"stage":"encrypt","bytes":0,"total":2000}`,
+	}, lines)
+}
+
+func TestMulti(t *testing.T) {
+	var updates []update
+	first := ReporterFunc(func(stage Stage, current, total int64) {
+		updates = append(updates, update{stage, current, total})
+	})
+	second := NewJSON(new(bytes.Buffer))
+
+	reporter := Multi(first, second)
+	ReportFile(reporter, Walk, "a.txt")
+	reporter.Progress(Walk, 10, 0)
+
+	assert.Equal(t, []update{{Walk, 10, 0}}, updates)
+	assert.Equal(t, "a.txt", second.event.File)
+}
+
+func TestJSONFlushesThrottledUpdate(t *testing.T) {
+	out := new(bytes.Buffer)
+	reporter := NewJSON(out)
+	now := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	reporter.now = func() time.Time { return now }
+
+	walked := NewCounter(reporter, Walk, 0)
+	walked.Add(10)
+	walked.Add(20)
+	NewCounter(reporter, Compress, 30)
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	assert.Equal(t, []string{
+		`{"stage":"walk","bytes":0,"total":0}`,
+		`{"stage":"walk","bytes":30,"total":0}`,
+		`{"stage":"compress","bytes":0,"total":30}`,
+	}, lines)
+}
+
+func TestLog(t *testing.T) {
+	out := new(bytes.Buffer)
+	logger := slog.New(slog.NewTextHandler(out, &slog.HandlerOptions{
+		Level: slog.LevelDebug,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if len(groups) == 0 && a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}))
+	reporter := NewLog(logger)
+	now := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	reporter.now = func() time.Time { return now }
+
+	compressed := NewCounter(reporter, Compress, 30)
+	compressed.File("a.txt")
+	now = now.Add(time.Second)
+	compressed.Add(10)
+	compressed.File("b.txt")
+	compressed.Add(20)
+	now = now.Add(time.Second)
+	NewCounter(reporter, Encrypt, 30).Add(30)
+	now = now.Add(500 * time.Millisecond)
+	reporter.Finish()
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	assert.Equal(t, []string{
+		`level=DEBUG msg="processing file" stage=compress path=a.txt elapsed=0s`,
+		`level=DEBUG msg="processing file" stage=compress path=b.txt elapsed=1s`,
+		`level=DEBUG msg="finished stage" stage=compress bytes=30 duration=2s`,
+		`level=DEBUG msg="finished stage" stage=encrypt bytes=30 duration=500ms`,
+	}, lines)
+}
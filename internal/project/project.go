@@ -0,0 +1,93 @@
+// Package project reads intunewin.yaml, the packaging configuration kept in a source
+// folder so that it can be reviewed in version control.
+package project
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/kenchan0130/intunewin/internal/manifest"
+	"gopkg.in/yaml.v3"
+)
+
+// FileName is the name of the project file in a source folder
+const FileName = "intunewin.yaml"
+
+// ErrInvalidProject is returned for a project file that cannot be used
+var ErrInvalidProject = errors.New("invalid project file")
+
+// Project is the packaging configuration of a source folder. Each field provides the
+// default of the command line flag of the same name.
+type Project struct {
+	// Name is recorded in Detection.xml
+	Name string `yaml:"name,omitempty"`
+	// Description is recorded in Detection.xml
+	Description string `yaml:"description,omitempty"`
+	// SetupFile is relative to the source folder
+	SetupFile string `yaml:"setupFile,omitempty"`
+	// Exclude lists patterns of files left out of the package, as pack.WithExclude takes them
+	Exclude []string `yaml:"exclude,omitempty"`
+	// DisplayName is the display name of the Win32 app
+	DisplayName string `yaml:"displayName,omitempty"`
+	// Publisher is the publisher of the Win32 app
+	Publisher string `yaml:"publisher,omitempty"`
+	// InstallCommand is the install command line of the Win32 app
+	InstallCommand string `yaml:"installCommand,omitempty"`
+	// UninstallCommand is the uninstall command line of the Win32 app
+	UninstallCommand string `yaml:"uninstallCommand,omitempty"`
+	// Detection lists detection rules appended to the rule generated for the setup file
+	Detection manifest.DetectionRules `yaml:"detection,omitempty"`
+}
+
+// Load reads the project file at path
+func Load(path string) (*Project, error) {
+	data, err := os.ReadFile(path) // #nosec G304 -- path is chosen by the user
+	if err != nil {
+		return nil, fmt.Errorf("failed to read project file: %w", err)
+	}
+	p, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return p, nil
+}
+
+// Parse parses a YAML project file and validates its detection rules
+func Parse(data []byte) (*Project, error) {
+	var p Project
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&p); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidProject, err)
+	}
+	if _, err := p.Detection.Rules(); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidProject, err)
+	}
+	return &p, nil
+}
+
+// Flags returns the values of p by the name of the command line flag they set. Fields
+// that are not set are left out.
+func (p *Project) Flags() map[string][]string {
+	flags := map[string][]string{}
+	for name, value := range map[string]string{
+		"name":              p.Name,
+		"description":       p.Description,
+		"setup-file":        p.SetupFile,
+		"display-name":      p.DisplayName,
+		"publisher":         p.Publisher,
+		"install-command":   p.InstallCommand,
+		"uninstall-command": p.UninstallCommand,
+	} {
+		if value != "" {
+			flags[name] = []string{value}
+		}
+	}
+	if len(p.Exclude) > 0 {
+		flags["exclude"] = p.Exclude
+	}
+	return flags
+}
@@ -0,0 +1,57 @@
+package project
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kenchan0130/intunewin/internal/manifest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+	require.NoError(t, os.WriteFile(path, []byte(`
+name: My App
+setupFile: setup.msi
+exclude:
+  - "*.log"
+  - docs/drafts
+installCommand: msiexec /i "setup.msi" /qn
+detection:
+  registry:
+    - keyPath: HKEY_LOCAL_MACHINE\SOFTWARE\Contoso
+      detectionType: exists
+`), 0600))
+
+	p, err := Load(path)
+	require.NoError(t, err)
+	assert.Equal(t, "My App", p.Name)
+	assert.Equal(t, "setup.msi", p.SetupFile)
+	rules, err := p.Detection.Rules()
+	require.NoError(t, err)
+	require.Len(t, rules, 1)
+	assert.Equal(t, `HKEY_LOCAL_MACHINE\SOFTWARE\Contoso`, rules[0].(manifest.RegistryDetection).KeyPath)
+
+	assert.Equal(t, map[string][]string{
+		"name":            {"My App"},
+		"setup-file":      {"setup.msi"},
+		"install-command": {`msiexec /i "setup.msi" /qn`},
+		"exclude":         {"*.log", "docs/drafts"},
+	}, p.Flags())
+}
+
+func TestParseInvalid(t *testing.T) {
+	_, err := Parse([]byte("name: App\nsetup: setup.exe\n"))
+	require.ErrorIs(t, err, ErrInvalidProject)
+
+	_, err = Parse([]byte("detection:\n  files:\n    - path: C:\\\n      detectionType: nonsense\n"))
+	require.ErrorIs(t, err, ErrInvalidProject)
+}
+
+func TestParseEmpty(t *testing.T) {
+	p, err := Parse(nil)
+	require.NoError(t, err)
+	assert.Empty(t, p.Flags())
+}
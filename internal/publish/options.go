@@ -0,0 +1,136 @@
+package publish
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/kenchan0130/intunewin/internal/graph"
+	"github.com/kenchan0130/intunewin/internal/metadata"
+	"github.com/kenchan0130/intunewin/internal/progress"
+	"github.com/kenchan0130/intunewin/internal/retry"
+	"github.com/kenchan0130/intunewin/internal/spill"
+)
+
+// Options holds settings for Publish
+type Options struct {
+	// Logger receives diagnostic messages
+	Logger *slog.Logger
+	// HTTPClient sends the upload requests, http.DefaultClient when nil
+	HTTPClient *http.Client
+	// Progress receives the number of bytes uploaded
+	Progress progress.Reporter
+	// ChunkSize is the size of the uploaded blocks, azblob.DefaultChunkSize when zero
+	ChunkSize int64
+	// Concurrency is the number of blocks uploaded in parallel, azblob.DefaultConcurrency when zero
+	Concurrency int
+	// Retry bounds the retries of upload requests, retry.DefaultPolicy when nil
+	Retry *retry.Policy
+	// Assignments are created once the app content is committed
+	Assignments []graph.Assignment
+	// TempDir is where large encrypted contents are buffered, the system default when empty
+	TempDir string
+	// SpillThreshold is the size above which the encrypted contents are buffered in a file
+	SpillThreshold int64
+	// EncryptionInfo replaces the encryption info of Detection.xml in the commit request when set
+	EncryptionInfo *metadata.XMLEncryptionInfo
+	// ResumeDir is where the progress of uploads is saved to resume them, no resuming when empty
+	ResumeDir string
+}
+
+// Option configures Options
+type Option func(*Options)
+
+// WithLogger sends diagnostic messages to logger
+func WithLogger(logger *slog.Logger) Option {
+	return func(o *Options) {
+		o.Logger = logger
+	}
+}
+
+// WithHTTPClient sends upload requests with client; Graph requests are sent with the
+// client of the graph.Client
+func WithHTTPClient(client *http.Client) Option {
+	return func(o *Options) {
+		o.HTTPClient = client
+	}
+}
+
+// WithProgress reports the uploaded bytes to reporter in the progress.Upload stage
+func WithProgress(reporter progress.Reporter) Option {
+	return func(o *Options) {
+		o.Progress = reporter
+	}
+}
+
+// WithChunkSize uploads blocks of size bytes
+func WithChunkSize(size int64) Option {
+	return func(o *Options) {
+		o.ChunkSize = size
+	}
+}
+
+// WithConcurrency uploads n blocks in parallel
+func WithConcurrency(n int) Option {
+	return func(o *Options) {
+		o.Concurrency = n
+	}
+}
+
+// WithRetry retries failed upload requests as policy allows; Graph requests are retried
+// as configured on the client
+func WithRetry(policy retry.Policy) Option {
+	return func(o *Options) {
+		o.Retry = &policy
+	}
+}
+
+// WithAssignments assigns the published app as described by assignments
+func WithAssignments(assignments ...graph.Assignment) Option {
+	return func(o *Options) {
+		o.Assignments = append(o.Assignments, assignments...)
+	}
+}
+
+// WithTempDir buffers large encrypted contents in dir instead of the system temporary directory
+func WithTempDir(dir string) Option {
+	return func(o *Options) {
+		o.TempDir = dir
+	}
+}
+
+// WithEncryptionInfo commits the content with info, such as keys kept in a key vault,
+// instead of the encryption info of Detection.xml
+func WithEncryptionInfo(info *metadata.XMLEncryptionInfo) Option {
+	return func(o *Options) {
+		o.EncryptionInfo = info
+	}
+}
+
+// WithSpillThreshold buffers encrypted contents larger than threshold bytes in a temporary file
+func WithSpillThreshold(threshold int64) Option {
+	return func(o *Options) {
+		o.SpillThreshold = threshold
+	}
+}
+
+// WithResumeDir saves the progress of the upload in dir, so that publishing the same package
+// again after an interruption, such as a dropped connection, continues the upload of the
+// app created before instead of creating another app and uploading everything again.
+// The progress is removed once the content is committed.
+func WithResumeDir(dir string) Option {
+	return func(o *Options) {
+		o.ResumeDir = dir
+	}
+}
+
+// newOptions applies opts over the defaults
+func newOptions(opts []Option) *Options {
+	o := &Options{SpillThreshold: spill.DefaultThreshold}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.Logger == nil {
+		o.Logger = slog.New(slog.DiscardHandler)
+	}
+	return o
+}
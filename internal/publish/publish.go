@@ -0,0 +1,206 @@
+package publish
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/kenchan0130/intunewin/internal/azblob"
+	"github.com/kenchan0130/intunewin/internal/graph"
+	"github.com/kenchan0130/intunewin/internal/manifest"
+	"github.com/kenchan0130/intunewin/internal/metadata"
+	"github.com/kenchan0130/intunewin/internal/unpack"
+)
+
+// contentFileName is the name Intune records for the uploaded encrypted contents
+const contentFileName = "IntunePackage.intunewin"
+
+// Result describes a published app
+type Result struct {
+	// AppID is the ID of the created mobile app
+	AppID string `json:"appId"`
+	// ContentVersionID is the committed content version of the app
+	ContentVersionID string `json:"contentVersionId"`
+	// AssignmentIDs are the IDs of the created assignments
+	AssignmentIDs []string `json:"assignmentIds,omitempty"`
+}
+
+// Publish creates app in Intune, uploads and commits the encrypted contents of the
+// intunewin file at inputFile as its content, and creates the assignments of the
+// Assignments option. The result holds the IDs created before a failure. With the
+// ResumeDir option, publishing the same package again after an interrupted upload
+// continues the upload to the app created before instead of starting over.
+func Publish(ctx context.Context, client *graph.Client, inputFile string, app *manifest.Win32LobApp, opts ...Option) (*Result, error) {
+	o := newOptions(opts)
+
+	appInfo, err := unpack.ReadApplicationInfo(inputFile, unpack.WithLogger(o.Logger))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read metadata: %w", err)
+	}
+	if o.EncryptionInfo != nil {
+		appInfo.EncryptionInfo = o.EncryptionInfo
+	}
+	commit, err := metadata.NewCommitRequest(appInfo)
+	if err != nil {
+		return nil, err //nolint:wrapcheck // metadata errors already describe the failure
+	}
+	for _, a := range o.Assignments {
+		if err := a.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid assignment to %s: %w", a.Target, err)
+		}
+	}
+
+	contents, err := unpack.OpenContents(inputFile, unpack.WithTempDir(o.TempDir), unpack.WithSpillThreshold(o.SpillThreshold))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read encrypted contents: %w", err)
+	}
+	defer contents.Close()
+	size, err := contents.Size()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read encrypted contents: %w", err)
+	}
+
+	mac := commit.FileEncryptionInfo.Mac
+	chunkSize := o.ChunkSize
+	if chunkSize <= 0 {
+		chunkSize = azblob.DefaultChunkSize
+	}
+	var state *stateFile
+	resumed := false
+	if o.ResumeDir != "" {
+		state = openState(o.ResumeDir, mac)
+		if resumed, err = state.load(mac, size); err != nil {
+			return nil, err
+		}
+	}
+
+	result := &Result{}
+	var versionID string
+	var file *graph.ContentFile
+	if resumed && state.expired() {
+		o.Logger.Warn("the interrupted upload is too old to resume, publishing a new app; delete the old one",
+			"app", state.state.AppID, "started", state.state.CreatedAt)
+		resumed = false
+	}
+	if resumed {
+		result.AppID, versionID, chunkSize = state.state.AppID, state.state.ContentVersionID, state.state.ChunkSize
+		o.Logger.Info("resuming upload", "app", result.AppID, "uploadedBlocks", len(state.state.Blocks))
+		if file, err = renewUpload(ctx, client, result.AppID, versionID, state.state.FileID); err != nil {
+			o.Logger.Warn("the interrupted upload cannot be resumed, publishing a new app; delete the old one",
+				"app", result.AppID, "error", err)
+			resumed = false
+		}
+	}
+	if !resumed {
+		if result.AppID, err = client.CreateApp(ctx, app); err != nil {
+			return nil, err //nolint:wrapcheck // graph errors already describe the failure
+		}
+		o.Logger.Info("created app", "id", result.AppID, "displayName", app.DisplayName)
+
+		if versionID, err = client.CreateContentVersion(ctx, result.AppID); err != nil {
+			return result, err //nolint:wrapcheck // graph errors already describe the failure
+		}
+		file, err = client.CreateContentFile(ctx, result.AppID, versionID,
+			graph.NewContentFile(contentFileName, appInfo.UnencryptedContentSize, size))
+		if err != nil {
+			return result, err //nolint:wrapcheck // graph errors already describe the failure
+		}
+		file, err = client.WaitForContentFile(ctx, result.AppID, versionID, file.ID, graph.StateAzureStorageURIRequestSuccess)
+		if err != nil {
+			return result, fmt.Errorf("failed to get upload URI: %w", err)
+		}
+		if state != nil {
+			state.state = uploadState{
+				Mac:              mac,
+				Size:             size,
+				ChunkSize:        chunkSize,
+				AppID:            result.AppID,
+				ContentVersionID: versionID,
+				FileID:           file.ID,
+				CreatedAt:        time.Now(),
+			}
+			if err := state.save(); err != nil {
+				return result, err
+			}
+		}
+	}
+
+	renew := func(ctx context.Context) (string, error) {
+		renewed, err := renewUpload(ctx, client, result.AppID, versionID, file.ID)
+		if err != nil {
+			return "", err
+		}
+		return renewed.AzureStorageURI, nil
+	}
+	upload := func() error {
+		uploadOpts := []azblob.Option{
+			azblob.WithLogger(o.Logger),
+			azblob.WithRenew(renew),
+			azblob.WithProgress(o.Progress),
+			azblob.WithChunkSize(chunkSize),
+		}
+		if state != nil {
+			uploadOpts = append(uploadOpts, azblob.WithUploaded(state.state.Blocks), azblob.WithBlockUploaded(state.addBlock))
+		}
+		if o.Concurrency > 0 {
+			uploadOpts = append(uploadOpts, azblob.WithConcurrency(o.Concurrency))
+		}
+		if o.HTTPClient != nil {
+			uploadOpts = append(uploadOpts, azblob.WithHTTPClient(o.HTTPClient))
+		}
+		if o.Retry != nil {
+			uploadOpts = append(uploadOpts, azblob.WithRetry(*o.Retry))
+		}
+		return azblob.Upload(ctx, file.AzureStorageURI, contents, size, uploadOpts...) //nolint:wrapcheck // wrapped below
+	}
+	o.Logger.Info("uploading contents", "bytes", size)
+	err = upload()
+	if resumed && errors.Is(err, azblob.ErrInvalidBlockList) {
+		// The blocks put before the interruption were discarded, so they are all put again once
+		o.Logger.Warn("the blocks uploaded before the interruption are gone, uploading the contents again", "app", result.AppID)
+		if err := state.resetBlocks(); err != nil {
+			return result, err
+		}
+		err = upload()
+	}
+	if err != nil {
+		return result, fmt.Errorf("failed to upload contents: %w", err)
+	}
+
+	if err := client.CommitContentFile(ctx, result.AppID, versionID, file.ID, commit); err != nil {
+		return result, err //nolint:wrapcheck // graph errors already describe the failure
+	}
+	if _, err := client.WaitForContentFile(ctx, result.AppID, versionID, file.ID, graph.StateCommitFileSuccess); err != nil {
+		return result, fmt.Errorf("failed to commit contents: %w", err)
+	}
+	if err := client.SetCommittedContentVersion(ctx, result.AppID, versionID); err != nil {
+		return result, err //nolint:wrapcheck // graph errors already describe the failure
+	}
+	result.ContentVersionID = versionID
+	o.Logger.Info("committed content version", "app", result.AppID, "version", versionID)
+	if state != nil {
+		if err := state.remove(); err != nil {
+			o.Logger.Warn("failed to remove upload state", "error", err)
+		}
+	}
+
+	for _, a := range o.Assignments {
+		id, err := client.CreateAssignment(ctx, result.AppID, a)
+		if err != nil {
+			return result, err //nolint:wrapcheck // graph errors already describe the failure
+		}
+		result.AssignmentIDs = append(result.AssignmentIDs, id)
+		o.Logger.Info("assigned app", "intent", a.Intent, "target", a.Target)
+	}
+	return result, nil
+}
+
+// renewUpload requests a new SAS URI for the content file fileID and returns the file once
+// Intune issued it
+func renewUpload(ctx context.Context, client *graph.Client, appID, versionID, fileID string) (*graph.ContentFile, error) {
+	if err := client.RenewUpload(ctx, appID, versionID, fileID); err != nil {
+		return nil, err //nolint:wrapcheck // graph errors already describe the failure
+	}
+	return client.WaitForContentFile(ctx, appID, versionID, fileID, graph.StateAzureStorageURIRenewalSuccess) //nolint:wrapcheck // graph errors already describe the failure
+}
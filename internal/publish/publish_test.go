@@ -0,0 +1,326 @@
+package publish
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kenchan0130/intunewin/internal/auth"
+	"github.com/kenchan0130/intunewin/internal/graph"
+	"github.com/kenchan0130/intunewin/internal/manifest"
+	"github.com/kenchan0130/intunewin/internal/pack"
+	"github.com/kenchan0130/intunewin/internal/retry"
+	"github.com/kenchan0130/intunewin/internal/unpack"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// staticTokens always returns the same access token
+type staticTokens struct{}
+
+func (staticTokens) Token(context.Context) (*auth.Token, error) {
+	return &auth.Token{AccessToken: "token", ExpiresAt: time.Now().Add(time.Hour)}, nil
+}
+
+// fakeIntune serves the Graph requests of a publish and the blob its contents are uploaded to
+type fakeIntune struct {
+	t      *testing.T
+	server *httptest.Server
+
+	mu          sync.Mutex
+	app         map[string]any
+	uploadState string
+	blocks      map[string][]byte
+	blob        []byte
+	commit      map[string]any
+	committed   string
+	assignments []map[string]any
+	// apps and blockPuts count the created apps and the uploaded blocks
+	apps      int
+	blockPuts int
+}
+
+func newFakeIntune(t *testing.T) *fakeIntune {
+	t.Helper()
+	f := &fakeIntune{t: t, blocks: map[string][]byte{}}
+	f.server = httptest.NewServer(f)
+	t.Cleanup(f.server.Close)
+	return f
+}
+
+func (f *fakeIntune) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	body, err := io.ReadAll(r.Body)
+	require.NoError(f.t, err)
+	var decoded map[string]any
+	if len(body) > 0 && r.URL.Path != "/blob" {
+		require.NoError(f.t, json.Unmarshal(body, &decoded))
+	}
+
+	const files = "/deviceAppManagement/mobileApps/app1/microsoft.graph.win32LobApp/contentVersions/1/files"
+	switch path := r.URL.Path; {
+	case path == "/blob" && r.URL.Query().Get("comp") == "block":
+		f.blocks[r.URL.Query().Get("blockid")] = body
+		f.blockPuts++
+		w.WriteHeader(http.StatusCreated)
+	case path == "/blob" && r.URL.Query().Get("comp") == "blocklist":
+		var blob []byte
+		for _, id := range strings.Split(string(body), "<Latest>")[1:] {
+			block, ok := f.blocks[strings.Split(id, "</Latest>")[0]]
+			if !ok {
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = io.WriteString(w, `<Error><Code>InvalidBlockList</Code></Error>`)
+				return
+			}
+			blob = append(blob, block...)
+		}
+		f.blob = blob
+		w.WriteHeader(http.StatusCreated)
+	case path == "/deviceAppManagement/mobileApps" && r.Method == http.MethodPost:
+		f.app = decoded
+		f.apps++
+		f.writeJSON(w, map[string]string{"id": "app1"})
+	case path == "/deviceAppManagement/mobileApps/app1/microsoft.graph.win32LobApp/contentVersions":
+		f.writeJSON(w, map[string]string{"id": "1"})
+	case path == files:
+		f.uploadState = "azureStorageUriRequestPending"
+		f.writeJSON(w, map[string]string{"id": "file1", "uploadState": f.uploadState})
+	case path == files+"/file1" && r.Method == http.MethodGet:
+		// Intune finishes each step by the time it is polled
+		switch f.uploadState {
+		case "azureStorageUriRequestPending":
+			f.uploadState = graph.StateAzureStorageURIRequestSuccess
+		case "azureStorageUriRenewalPending":
+			f.uploadState = graph.StateAzureStorageURIRenewalSuccess
+		case "commitFilePending":
+			f.uploadState = graph.StateCommitFileSuccess
+		}
+		f.writeJSON(w, map[string]string{
+			"id":              "file1",
+			"uploadState":     f.uploadState,
+			"azureStorageUri": f.server.URL + "/blob?sv=2021-08-06&sig=sig",
+		})
+	case path == files+"/file1/renewUpload":
+		f.uploadState = "azureStorageUriRenewalPending"
+		w.WriteHeader(http.StatusOK)
+	case path == files+"/file1/commit":
+		f.commit = decoded
+		f.uploadState = "commitFilePending"
+		w.WriteHeader(http.StatusOK)
+	case path == "/deviceAppManagement/mobileApps/app1" && r.Method == http.MethodPatch:
+		f.committed, _ = decoded["committedContentVersion"].(string)
+		w.WriteHeader(http.StatusNoContent)
+	case path == "/deviceAppManagement/mobileApps/app1/assignments":
+		f.assignments = append(f.assignments, decoded)
+		f.writeJSON(w, map[string]string{"id": "assignment1"})
+	default:
+		f.t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (f *fakeIntune) writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	require.NoError(f.t, json.NewEncoder(w).Encode(v))
+}
+
+func TestPublish(t *testing.T) {
+	sourceDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(sourceDir, "setup.exe"), []byte("setup"), 0600))
+	packedFile := filepath.Join(t.TempDir(), "app.intunewin")
+	require.NoError(t, pack.PackWithInfo(sourceDir, packedFile, "MyApp", "setup.exe"))
+	appInfo, err := unpack.ReadApplicationInfo(packedFile)
+	require.NoError(t, err)
+
+	intune := newFakeIntune(t)
+	client := graph.NewClient(staticTokens{}, graph.WithBaseURL(intune.server.URL), graph.WithPollInterval(time.Millisecond))
+	app := manifest.NewWin32LobApp(appInfo, nil, manifest.DefaultReturnCodes())
+	assignment := graph.Assignment{Intent: graph.IntentRequired, Target: graph.TargetAllDevices}
+
+	result, err := Publish(context.Background(), client, packedFile, app, WithAssignments(assignment), WithChunkSize(64))
+	require.NoError(t, err)
+	assert.Equal(t, &Result{AppID: "app1", ContentVersionID: "1", AssignmentIDs: []string{"assignment1"}}, result)
+
+	assert.Equal(t, "MyApp", intune.app["displayName"])
+	contents, err := unpack.OpenContents(packedFile)
+	require.NoError(t, err)
+	defer contents.Close()
+	want, err := io.ReadAll(contents)
+	require.NoError(t, err)
+	assert.Equal(t, want, intune.blob)
+	info, ok := intune.commit["fileEncryptionInfo"].(map[string]any)
+	require.True(t, ok)
+	assert.Equal(t, appInfo.EncryptionInfo.Mac, info["mac"])
+	assert.Equal(t, "1", intune.committed)
+	require.Len(t, intune.assignments, 1)
+	assert.Equal(t, "required", intune.assignments[0]["intent"])
+}
+
+// dropAfter fails upload requests once n of them went through, as a dropped connection does
+type dropAfter struct {
+	n int
+}
+
+func (d *dropAfter) RoundTrip(req *http.Request) (*http.Response, error) {
+	if d.n == 0 {
+		return nil, errors.New("connection reset")
+	}
+	d.n--
+	return http.DefaultTransport.RoundTrip(req) //nolint:wrapcheck // test transport
+}
+
+func TestPublishResumesUpload(t *testing.T) {
+	sourceDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(sourceDir, "setup.exe"), bytes.Repeat([]byte("setup"), 1000), 0600))
+	packedFile := filepath.Join(t.TempDir(), "app.intunewin")
+	require.NoError(t, pack.PackWithInfo(sourceDir, packedFile, "MyApp", "setup.exe", pack.WithCompressionLevel(0)))
+	appInfo, err := unpack.ReadApplicationInfo(packedFile)
+	require.NoError(t, err)
+
+	intune := newFakeIntune(t)
+	client := graph.NewClient(staticTokens{}, graph.WithBaseURL(intune.server.URL), graph.WithPollInterval(time.Millisecond))
+	app := manifest.NewWin32LobApp(appInfo, nil, manifest.DefaultReturnCodes())
+	resumeDir := t.TempDir()
+	opts := []Option{WithChunkSize(512), WithConcurrency(1), WithResumeDir(resumeDir), WithRetry(retry.Policy{})}
+
+	// The connection drops after three blocks
+	dropped := &http.Client{Transport: &dropAfter{n: 3}}
+	result, err := Publish(context.Background(), client, packedFile, app, append(opts, WithHTTPClient(dropped))...)
+	require.Error(t, err)
+	assert.Equal(t, "app1", result.AppID)
+	assert.Equal(t, 3, intune.blockPuts)
+
+	result, err = Publish(context.Background(), client, packedFile, app, opts...)
+	require.NoError(t, err)
+	assert.Equal(t, "1", result.ContentVersionID)
+	assert.Equal(t, 1, intune.apps, "the app of the interrupted publish is reused")
+	assert.Len(t, intune.blocks, intune.blockPuts, "no block is uploaded twice")
+
+	contents, err := unpack.OpenContents(packedFile)
+	require.NoError(t, err)
+	defer contents.Close()
+	want, err := io.ReadAll(contents)
+	require.NoError(t, err)
+	assert.Equal(t, want, intune.blob)
+
+	// The progress is removed once committed, so publishing again creates a new app
+	entries, err := os.ReadDir(resumeDir)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+// interruptedPublish packs a package and publishes it to a fake Intune until the connection
+// drops after three blocks, leaving the upload state in the returned resume directory
+func interruptedPublish(t *testing.T) (*fakeIntune, func(opts ...Option) (*Result, error), string) {
+	t.Helper()
+	sourceDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(sourceDir, "setup.exe"), bytes.Repeat([]byte("setup"), 1000), 0600))
+	packedFile := filepath.Join(t.TempDir(), "app.intunewin")
+	require.NoError(t, pack.PackWithInfo(sourceDir, packedFile, "MyApp", "setup.exe", pack.WithCompressionLevel(0)))
+	appInfo, err := unpack.ReadApplicationInfo(packedFile)
+	require.NoError(t, err)
+
+	intune := newFakeIntune(t)
+	client := graph.NewClient(staticTokens{}, graph.WithBaseURL(intune.server.URL), graph.WithPollInterval(time.Millisecond))
+	app := manifest.NewWin32LobApp(appInfo, nil, manifest.DefaultReturnCodes())
+	resumeDir := t.TempDir()
+	publish := func(opts ...Option) (*Result, error) {
+		opts = append([]Option{WithChunkSize(512), WithConcurrency(1), WithResumeDir(resumeDir), WithRetry(retry.Policy{})}, opts...)
+		return Publish(context.Background(), client, packedFile, app, opts...)
+	}
+
+	_, err = publish(WithHTTPClient(&http.Client{Transport: &dropAfter{n: 3}}))
+	require.Error(t, err)
+	return intune, publish, resumeDir
+}
+
+func TestPublishReuploadsDiscardedBlocks(t *testing.T) {
+	intune, publish, _ := interruptedPublish(t)
+	// Azure Storage discarded the uncommitted blocks
+	intune.blocks = map[string][]byte{}
+	puts := intune.blockPuts
+
+	result, err := publish()
+	require.NoError(t, err)
+	assert.Equal(t, "1", result.ContentVersionID)
+	assert.Equal(t, 1, intune.apps, "the app of the interrupted publish is reused")
+	// The blocks missing from the first attempt, then every block once more
+	blocks := len(intune.blocks)
+	assert.Equal(t, blocks-3+blocks, intune.blockPuts-puts)
+	assert.NotEmpty(t, intune.blob)
+}
+
+func TestPublishIgnoresExpiredState(t *testing.T) {
+	intune, publish, resumeDir := interruptedPublish(t)
+	entries, err := os.ReadDir(resumeDir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	path := filepath.Join(resumeDir, entries[0].Name())
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	var state uploadState
+	require.NoError(t, json.Unmarshal(data, &state))
+	assert.WithinDuration(t, time.Now(), state.CreatedAt, time.Minute)
+	state.CreatedAt = time.Now().Add(-stateLifetime - time.Hour)
+	data, err = json.Marshal(state)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, data, 0600))
+
+	_, err = publish()
+	require.NoError(t, err)
+	assert.Equal(t, 2, intune.apps, "a new app is published instead of resuming the old one")
+}
+
+func TestPublishWithEncryptionInfo(t *testing.T) {
+	sourceDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(sourceDir, "setup.exe"), []byte("setup"), 0600))
+	packedFile := filepath.Join(t.TempDir(), "app.intunewin")
+	require.NoError(t, pack.PackWithInfo(sourceDir, packedFile, "MyApp", "setup.exe"))
+	appInfo, err := unpack.ReadApplicationInfo(packedFile)
+	require.NoError(t, err)
+
+	intune := newFakeIntune(t)
+	client := graph.NewClient(staticTokens{}, graph.WithBaseURL(intune.server.URL), graph.WithPollInterval(time.Millisecond))
+	app := manifest.NewWin32LobApp(appInfo, nil, manifest.DefaultReturnCodes())
+
+	// Keys from elsewhere, such as a key vault, replace those of Detection.xml
+	stored := *appInfo.EncryptionInfo
+	stored.Mac = base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{7}, 32))
+	_, err = Publish(context.Background(), client, packedFile, app, WithEncryptionInfo(&stored))
+	require.NoError(t, err)
+	info, ok := intune.commit["fileEncryptionInfo"].(map[string]any)
+	require.True(t, ok)
+	assert.Equal(t, stored.Mac, info["mac"])
+}
+
+func TestPublishInvalidAssignment(t *testing.T) {
+	sourceDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(sourceDir, "setup.exe"), []byte("setup"), 0600))
+	packedFile := filepath.Join(t.TempDir(), "app.intunewin")
+	require.NoError(t, pack.PackWithInfo(sourceDir, packedFile, "MyApp", "setup.exe"))
+	appInfo, err := unpack.ReadApplicationInfo(packedFile)
+	require.NoError(t, err)
+
+	intune := newFakeIntune(t)
+	client := graph.NewClient(staticTokens{}, graph.WithBaseURL(intune.server.URL))
+	app := manifest.NewWin32LobApp(appInfo, nil, manifest.DefaultReturnCodes())
+
+	_, err = Publish(context.Background(), client, packedFile, app,
+		WithAssignments(graph.Assignment{Intent: graph.IntentAvailable, Target: graph.TargetAllDevices}))
+	assert.Error(t, err)
+	assert.Nil(t, intune.app, "nothing is created for invalid assignments")
+}
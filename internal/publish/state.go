@@ -0,0 +1,120 @@
+package publish
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// stateLifetime is how long a saved state can be resumed; Azure Storage discards the
+// uncommitted blocks of a blob after seven days
+const stateLifetime = 7 * 24 * time.Hour
+
+// uploadState is the progress of a publish saved to resume its upload after an interruption
+type uploadState struct {
+	// Mac is the HMAC of the encrypted contents the state belongs to
+	Mac string `json:"mac"`
+	// Size is the size of the encrypted contents
+	Size int64 `json:"size"`
+	// ChunkSize is the size of the blocks, which must not change when resuming
+	ChunkSize int64 `json:"chunkSize"`
+	// AppID, ContentVersionID and FileID identify the content file being uploaded
+	AppID            string `json:"appId"`
+	ContentVersionID string `json:"contentVersionId"`
+	FileID           string `json:"fileId"`
+	// Blocks are the indexes of the blocks put so far
+	Blocks []int `json:"blocks"`
+	// CreatedAt is when the upload started
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// stateFile saves the uploadState of the encrypted contents with a given HMAC in a directory
+type stateFile struct {
+	path  string
+	state uploadState
+}
+
+// openState returns the state file of the encrypted contents with the HMAC mac in dir
+func openState(dir, mac string) *stateFile {
+	digest := sha256.Sum256([]byte(mac))
+	return &stateFile{path: filepath.Join(dir, hex.EncodeToString(digest[:16])+".json")}
+}
+
+// load reads the saved state and reports whether there is one for contents of size bytes
+func (f *stateFile) load(mac string, size int64) (bool, error) {
+	data, err := os.ReadFile(f.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to read upload state: %w", err)
+	}
+	var state uploadState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return false, fmt.Errorf("failed to parse upload state %s: %w", f.path, err)
+	}
+	if state.Mac != mac || state.Size != size || state.ChunkSize <= 0 || state.AppID == "" {
+		return false, nil
+	}
+	f.state = state
+	return true, nil
+}
+
+// expired reports whether the loaded state is too old to resume. A state saved without
+// its creation time is treated as expired.
+func (f *stateFile) expired() bool {
+	return time.Since(f.state.CreatedAt) > stateLifetime
+}
+
+// resetBlocks forgets the blocks put so far and saves the state, so they are all put again
+func (f *stateFile) resetBlocks() error {
+	f.state.Blocks = nil
+	return f.save()
+}
+
+// addBlock records block as put and saves the state
+func (f *stateFile) addBlock(block int) error {
+	f.state.Blocks = append(f.state.Blocks, block)
+	return f.save()
+}
+
+// save writes the state, replacing the previous one at once so an interruption never
+// leaves a partial file behind
+func (f *stateFile) save() error {
+	data, err := json.Marshal(f.state)
+	if err != nil {
+		return fmt.Errorf("failed to marshal upload state: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(f.path), 0700); err != nil {
+		return fmt.Errorf("failed to create upload state directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(f.path), ".upload-*.json")
+	if err != nil {
+		return fmt.Errorf("failed to save upload state: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to save upload state: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to save upload state: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), f.path); err != nil {
+		return fmt.Errorf("failed to save upload state: %w", err)
+	}
+	return nil
+}
+
+// remove deletes the saved state once the content is committed
+func (f *stateFile) remove() error {
+	if err := os.Remove(f.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove upload state: %w", err)
+	}
+	return nil
+}
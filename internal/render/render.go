@@ -0,0 +1,152 @@
+package render
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Format is an output format for command results
+type Format string
+
+const (
+	// FormatTable renders aligned columns for humans
+	FormatTable Format = "table"
+	// FormatJSON renders indented JSON
+	FormatJSON Format = "json"
+	// FormatYAML renders YAML with the same keys as FormatJSON
+	FormatYAML Format = "yaml"
+	// FormatCSV renders comma-separated values with a header row
+	FormatCSV Format = "csv"
+)
+
+// Formats lists every supported format
+var Formats = []Format{FormatTable, FormatJSON, FormatYAML, FormatCSV}
+
+// ParseFormat parses a format name
+func ParseFormat(s string) (Format, error) {
+	for _, f := range Formats {
+		if strings.EqualFold(s, string(f)) {
+			return f, nil
+		}
+	}
+	return "", fmt.Errorf("invalid output format %q: must be table, json, yaml or csv", s)
+}
+
+// Table is the tabular shape of a result, used by FormatTable and FormatCSV
+type Table struct {
+	Columns []string
+	Rows    [][]string
+}
+
+// Tabular is implemented by results that can be rendered in every format.
+// FormatJSON and FormatYAML encode the value itself, so it should have json tags.
+type Tabular interface {
+	Table() Table
+}
+
+// Write renders v to w in format f
+func Write(w io.Writer, f Format, v Tabular) error {
+	switch f {
+	case FormatTable:
+		return writeTable(w, v.Table())
+	case FormatCSV:
+		return writeCSV(w, v.Table())
+	case FormatJSON:
+		data, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to render JSON: %w", err)
+		}
+		return write(w, append(data, '\n'))
+	case FormatYAML:
+		data, err := toYAML(v)
+		if err != nil {
+			return err
+		}
+		return write(w, data)
+	default:
+		return fmt.Errorf("invalid output format %q", f)
+	}
+}
+
+// writeTable renders t as aligned columns with an upper case header
+func writeTable(w io.Writer, t Table) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	if len(t.Columns) > 0 {
+		header := make([]string, len(t.Columns))
+		for i, c := range t.Columns {
+			header[i] = strings.ToUpper(c)
+		}
+		fmt.Fprintln(tw, strings.Join(header, "\t"))
+	}
+	for _, row := range t.Rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	if err := tw.Flush(); err != nil {
+		return fmt.Errorf("failed to render table: %w", err)
+	}
+	return nil
+}
+
+// writeCSV renders t as CSV with the columns as header row
+func writeCSV(w io.Writer, t Table) error {
+	cw := csv.NewWriter(w)
+	if len(t.Columns) > 0 {
+		if err := cw.Write(t.Columns); err != nil {
+			return fmt.Errorf("failed to render CSV: %w", err)
+		}
+	}
+	if err := cw.WriteAll(t.Rows); err != nil {
+		return fmt.Errorf("failed to render CSV: %w", err)
+	}
+	return nil
+}
+
+// toYAML renders v as YAML with the keys and key order of its JSON encoding
+func toYAML(v any) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render YAML: %w", err)
+	}
+
+	// JSON is valid YAML; decoding into a node keeps the key order
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		return nil, fmt.Errorf("failed to render YAML: %w", err)
+	}
+	resetStyle(&node)
+
+	buf := new(bytes.Buffer)
+	enc := yaml.NewEncoder(buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&node); err != nil {
+		return nil, fmt.Errorf("failed to render YAML: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return nil, fmt.Errorf("failed to render YAML: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// resetStyle switches nodes decoded from JSON to block style, so strings are
+// only quoted where YAML requires it
+func resetStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		resetStyle(child)
+	}
+}
+
+// write writes data to w
+func write(w io.Writer, data []byte) error {
+	if _, err := w.Write(data); err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+	return nil
+}
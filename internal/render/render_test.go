@@ -0,0 +1,54 @@
+package render
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testResult struct {
+	Name    string `json:"name"`
+	Size    int64  `json:"size"`
+	Version string `json:"version"`
+}
+
+func (r testResult) Table() Table {
+	return Table{
+		Columns: []string{"Name", "Size", "Version"},
+		Rows:    [][]string{{r.Name, "1 KiB", r.Version}},
+	}
+}
+
+func TestWrite(t *testing.T) {
+	result := testResult{Name: "My App, Inc.", Size: 1024, Version: "1.0"}
+
+	tests := []struct {
+		format Format
+		want   string
+	}{
+		{FormatTable, "NAME          SIZE   VERSION\nMy App, Inc.  1 KiB  1.0\n"},
+		{FormatCSV, "Name,Size,Version\n\"My App, Inc.\",1 KiB,1.0\n"},
+		{FormatJSON, "{\n  \"name\": \"My App, Inc.\",\n  \"size\": 1024,\n  \"version\": \"1.0\"\n}\n"},
+		// Keys keep the JSON order and names; "1.0" stays a string
+		{FormatYAML, "name: My App, Inc.\nsize: 1024\nversion: \"1.0\"\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.format), func(t *testing.T) {
+			buf := new(bytes.Buffer)
+			require.NoError(t, Write(buf, tt.format, result))
+			assert.Equal(t, tt.want, buf.String())
+		})
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	f, err := ParseFormat("JSON")
+	require.NoError(t, err)
+	assert.Equal(t, FormatJSON, f)
+
+	_, err = ParseFormat("xml")
+	assert.Error(t, err)
+}
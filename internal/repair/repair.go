@@ -0,0 +1,261 @@
+package repair
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/aes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io"
+
+	"github.com/kenchan0130/intunewin/internal/crypto"
+	"github.com/kenchan0130/intunewin/internal/metadata"
+	"github.com/kenchan0130/intunewin/internal/unpack"
+)
+
+const (
+	detectionXMLPath = "IntuneWinPackage/Metadata/Detection.xml"
+	contentsPath     = "IntuneWinPackage/Contents/IntunePackage.intunewin"
+)
+
+// Result describes what Repair changed
+type Result struct {
+	// Fields names the Detection.xml fields that were recomputed
+	Fields []string
+	// PayloadMAC is true when the HMAC stored in front of the encrypted contents was rewritten
+	PayloadMAC bool
+}
+
+// Changed reports whether anything was repaired
+func (r *Result) Changed() bool {
+	return len(r.Fields) > 0 || r.PayloadMAC
+}
+
+// Repair reads a structurally intact intunewin package from input and writes it to output with
+// the MAC, file digest, size and IV in Detection.xml recomputed from the encrypted contents,
+// using the keys already in Detection.xml. Every other entry is copied unchanged.
+// Contents that cannot be decrypted with those keys cannot be repaired.
+func Repair(input io.Reader, output io.Writer, opts ...unpack.Option) (*Result, error) {
+	packageBuf := unpack.NewBuffer("intunewin-repair-*", opts...)
+	defer packageBuf.Close()
+	size, err := io.Copy(packageBuf, input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read input: %w", err)
+	}
+
+	zipReader, err := zip.NewReader(packageBuf, size)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", unpack.ErrNotIntunewin, err)
+	}
+
+	var metaFile, contents *zip.File
+	for _, file := range zipReader.File {
+		switch file.Name {
+		case detectionXMLPath:
+			metaFile = file
+		case contentsPath:
+			contents = file
+		}
+	}
+	if metaFile == nil {
+		return nil, unpack.ErrMetadataMissing
+	}
+	if contents == nil {
+		return nil, unpack.ErrContentsMissing
+	}
+
+	appInfo, err := readApplicationInfo(metaFile)
+	if err != nil {
+		return nil, err
+	}
+	encKey, macKey, err := decodeKeys(appInfo.EncryptionInfo)
+	if err != nil {
+		return nil, err
+	}
+	defer crypto.Zero(encKey, macKey)
+
+	encrypted := unpack.NewBuffer("intunewin-encrypted-*", opts...)
+	defer encrypted.Close()
+	if err := copyEntry(encrypted, contents); err != nil {
+		return nil, err
+	}
+
+	payloadMAC, mac, iv, err := fixPayloadMAC(encrypted, macKey)
+	if err != nil {
+		return nil, err
+	}
+	result := &Result{PayloadMAC: payloadMAC}
+
+	// Decrypting checks the encryption key through the padding and yields the digest and size
+	digest := sha256.New()
+	counter := &countingWriter{}
+	if _, err := encrypted.Seek(0, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("failed to rewind encrypted contents: %w", err)
+	}
+	if err := crypto.Decrypt(encrypted, io.MultiWriter(digest, counter), encKey, macKey); err != nil {
+		return nil, fmt.Errorf("failed to decrypt contents with the keys in Detection.xml: %w", err)
+	}
+
+	info := appInfo.EncryptionInfo
+	result.set(&info.Mac, base64.StdEncoding.EncodeToString(mac), "Mac")
+	result.set(&info.FileDigest, base64.StdEncoding.EncodeToString(digest.Sum(nil)), "FileDigest")
+	result.set(&info.FileDigestAlgorithm, metadata.KnownFileDigestAlgorithm, "FileDigestAlgorithm")
+	result.set(&info.InitializationVector, base64.StdEncoding.EncodeToString(iv), "InitializationVector")
+	if appInfo.UnencryptedContentSize != counter.n {
+		appInfo.UnencryptedContentSize = counter.n
+		result.Fields = append(result.Fields, "UnencryptedContentSize")
+	}
+
+	metaXML, err := appInfo.ToXML()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create metadata XML: %w", err)
+	}
+
+	if err := writePackage(output, zipReader, metaXML, encrypted, result.PayloadMAC); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// set assigns value to field and records name when it changes
+func (r *Result) set(field *string, value, name string) {
+	if *field != value {
+		*field = value
+		r.Fields = append(r.Fields, name)
+	}
+}
+
+// readApplicationInfo parses Detection.xml
+func readApplicationInfo(file *zip.File) (*metadata.ApplicationInfo, error) {
+	buf := new(bytes.Buffer)
+	if err := copyEntry(buf, file); err != nil {
+		return nil, err
+	}
+	appInfo, err := metadata.FromXMLBytes(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", unpack.ErrInvalidMetadata, err)
+	}
+	if appInfo.EncryptionInfo == nil {
+		return nil, fmt.Errorf("%w: encryption info is missing", unpack.ErrInvalidMetadata)
+	}
+	return appInfo, nil
+}
+
+// decodeKeys decodes the encryption and MAC keys, which cannot be recovered when wrong
+func decodeKeys(info *metadata.XMLEncryptionInfo) (encKey, macKey []byte, err error) {
+	encKey, err = base64.StdEncoding.DecodeString(info.EncryptionKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: failed to decode encryption key: %w", unpack.ErrInvalidMetadata, err)
+	}
+	macKey, err = base64.StdEncoding.DecodeString(info.MacKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: failed to decode MAC key: %w", unpack.ErrInvalidMetadata, err)
+	}
+	if err := crypto.ValidateKeys(encKey, macKey, make([]byte, aes.BlockSize)); err != nil {
+		return nil, nil, fmt.Errorf("%w: %w", unpack.ErrInvalidMetadata, err)
+	}
+	return encKey, macKey, nil
+}
+
+// fixPayloadMAC recomputes the HMAC over the IV and encrypted data and writes it in front of
+// the encrypted contents when it differs. It returns whether it was rewritten, the HMAC and the IV.
+func fixPayloadMAC(encrypted io.ReadWriteSeeker, macKey []byte) (bool, []byte, []byte, error) {
+	if _, err := encrypted.Seek(0, io.SeekStart); err != nil {
+		return false, nil, nil, fmt.Errorf("failed to rewind encrypted contents: %w", err)
+	}
+	stored := make([]byte, sha256.Size)
+	if _, err := io.ReadFull(encrypted, stored); err != nil {
+		return false, nil, nil, fmt.Errorf("failed to read HMAC: %w", err)
+	}
+	iv := make([]byte, aes.BlockSize)
+	if _, err := io.ReadFull(encrypted, iv); err != nil {
+		return false, nil, nil, fmt.Errorf("failed to read IV: %w", err)
+	}
+
+	h := hmac.New(sha256.New, macKey)
+	h.Write(iv)
+	if _, err := io.Copy(h, encrypted); err != nil {
+		return false, nil, nil, fmt.Errorf("failed to read encrypted data: %w", err)
+	}
+	computed := h.Sum(nil)
+	if hmac.Equal(stored, computed) {
+		return false, computed, iv, nil
+	}
+
+	if _, err := encrypted.Seek(0, io.SeekStart); err != nil {
+		return false, nil, nil, fmt.Errorf("failed to rewind encrypted contents: %w", err)
+	}
+	if _, err := encrypted.Write(computed); err != nil {
+		return false, nil, nil, fmt.Errorf("failed to write HMAC: %w", err)
+	}
+	return true, computed, iv, nil
+}
+
+// writePackage writes the entries of zipReader to output, replacing Detection.xml with metaXML
+// and, when rewriteContents is set, the encrypted contents with encrypted
+func writePackage(output io.Writer, zipReader *zip.Reader, metaXML []byte, encrypted io.ReadSeeker, rewriteContents bool) error {
+	zipWriter := zip.NewWriter(output)
+	for _, file := range zipReader.File {
+		var replacement io.Reader
+		switch {
+		case file.Name == detectionXMLPath:
+			replacement = bytes.NewReader(metaXML)
+		case file.Name == contentsPath && rewriteContents:
+			if _, err := encrypted.Seek(0, io.SeekStart); err != nil {
+				zipWriter.Close()
+				return fmt.Errorf("failed to rewind encrypted contents: %w", err)
+			}
+			replacement = encrypted
+		default:
+			if err := zipWriter.Copy(file); err != nil {
+				zipWriter.Close()
+				return fmt.Errorf("failed to copy %s: %w", file.Name, err)
+			}
+			continue
+		}
+
+		w, err := zipWriter.CreateHeader(&zip.FileHeader{
+			Name:     file.Name,
+			Method:   file.Method,
+			Modified: file.Modified,
+		})
+		if err != nil {
+			zipWriter.Close()
+			return fmt.Errorf("failed to create %s: %w", file.Name, err)
+		}
+		if _, err := io.Copy(w, replacement); err != nil {
+			zipWriter.Close()
+			return fmt.Errorf("failed to write %s: %w", file.Name, err)
+		}
+	}
+
+	if err := zipWriter.Close(); err != nil {
+		return fmt.Errorf("failed to close zip writer: %w", err)
+	}
+	return nil
+}
+
+// copyEntry copies the uncompressed data of file to w
+func copyEntry(w io.Writer, file *zip.File) error {
+	rc, err := file.Open()
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", file.Name, err)
+	}
+	defer rc.Close()
+	if _, err := io.Copy(w, rc); err != nil {
+		return fmt.Errorf("failed to read %s: %w", file.Name, err)
+	}
+	return nil
+}
+
+// countingWriter counts the bytes written to it
+type countingWriter struct {
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	c.n += int64(len(p))
+	return len(p), nil
+}
@@ -0,0 +1,149 @@
+package repair
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kenchan0130/intunewin/internal/buildinfo"
+	"github.com/kenchan0130/intunewin/internal/crypto"
+	"github.com/kenchan0130/intunewin/internal/metadata"
+	"github.com/kenchan0130/intunewin/internal/pack"
+	"github.com/kenchan0130/intunewin/internal/unpack"
+	"github.com/kenchan0130/intunewin/internal/verify"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRepair(t *testing.T) {
+	packed := packFixture(t)
+	broken := rewrite(t, packed, func(appInfo *metadata.ApplicationInfo) {
+		appInfo.EncryptionInfo.Mac = base64.StdEncoding.EncodeToString(make([]byte, 32))
+		appInfo.EncryptionInfo.FileDigest = "not base64"
+		appInfo.UnencryptedContentSize = 1
+	}, nil)
+	require.Error(t, verify.File(writeTemp(t, broken)))
+
+	output := new(bytes.Buffer)
+	result, err := Repair(bytes.NewReader(broken), output)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"Mac", "FileDigest", "UnencryptedContentSize"}, result.Fields)
+	assert.False(t, result.PayloadMAC)
+
+	path := writeTemp(t, output.Bytes())
+	require.NoError(t, verify.File(path))
+
+	// Entries other than Detection.xml are kept
+	info, err := unpack.ReadBuildInfo(path)
+	require.NoError(t, err)
+	assert.NotNil(t, info)
+}
+
+func TestRepairPayloadMAC(t *testing.T) {
+	packed := packFixture(t)
+	broken := rewrite(t, packed, func(appInfo *metadata.ApplicationInfo) {
+		appInfo.EncryptionInfo.Mac = base64.StdEncoding.EncodeToString(make([]byte, 32))
+	}, func(encrypted []byte) {
+		copy(encrypted, make([]byte, 32))
+	})
+	_, err := unpack.Decrypt(writeTemp(t, broken), new(bytes.Buffer))
+	require.ErrorIs(t, err, crypto.ErrHMACMismatch)
+
+	output := new(bytes.Buffer)
+	result, err := Repair(bytes.NewReader(broken), output)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Mac"}, result.Fields)
+	assert.True(t, result.PayloadMAC)
+	assert.NoError(t, verify.File(writeTemp(t, output.Bytes())))
+}
+
+func TestRepairIntact(t *testing.T) {
+	output := new(bytes.Buffer)
+	result, err := Repair(bytes.NewReader(packFixture(t)), output)
+	require.NoError(t, err)
+	assert.False(t, result.Changed())
+}
+
+func TestRepairWrongKey(t *testing.T) {
+	// Fixed input so the padding decrypted with the wrong key is reliably invalid
+	packed := packFixture(t,
+		pack.WithReproducible(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)),
+		pack.WithEncryptionKeys(bytes.Repeat([]byte{2}, 32), bytes.Repeat([]byte{3}, 32), bytes.Repeat([]byte{4}, 16)),
+	)
+	broken := rewrite(t, packed, func(appInfo *metadata.ApplicationInfo) {
+		appInfo.EncryptionInfo.EncryptionKey = base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, 32))
+	}, nil)
+
+	_, err := Repair(bytes.NewReader(broken), new(bytes.Buffer))
+	assert.ErrorIs(t, err, crypto.ErrInvalidPadding)
+}
+
+func TestRepairErrors(t *testing.T) {
+	_, err := Repair(bytes.NewReader([]byte("not a zip")), new(bytes.Buffer))
+	assert.ErrorIs(t, err, unpack.ErrNotIntunewin)
+
+	buf := new(bytes.Buffer)
+	require.NoError(t, zip.NewWriter(buf).Close())
+	_, err = Repair(bytes.NewReader(buf.Bytes()), new(bytes.Buffer))
+	assert.ErrorIs(t, err, unpack.ErrMetadataMissing)
+}
+
+// packFixture packs a small folder with build information and returns the package
+func packFixture(t *testing.T, opts ...pack.Option) []byte {
+	t.Helper()
+	sourceDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(sourceDir, "setup.exe"), []byte("setup"), 0600))
+	path := filepath.Join(t.TempDir(), "app.intunewin")
+	require.NoError(t, pack.PackWithInfo(sourceDir, path, "app", "setup.exe", append(opts, pack.WithBuildInfo(buildinfo.New("test", false)))...))
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	return data
+}
+
+// rewrite returns packed with Detection.xml and the encrypted contents modified
+func rewrite(t *testing.T, packed []byte, modifyInfo func(*metadata.ApplicationInfo), modifyContents func([]byte)) []byte {
+	t.Helper()
+	zr, err := zip.NewReader(bytes.NewReader(packed), int64(len(packed)))
+	require.NoError(t, err)
+
+	buf := new(bytes.Buffer)
+	zw := zip.NewWriter(buf)
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		require.NoError(t, err)
+		data := new(bytes.Buffer)
+		_, err = data.ReadFrom(rc)
+		require.NoError(t, err)
+		rc.Close()
+
+		content := data.Bytes()
+		switch {
+		case f.Name == detectionXMLPath && modifyInfo != nil:
+			appInfo, err := metadata.FromXMLBytes(content)
+			require.NoError(t, err)
+			modifyInfo(appInfo)
+			content, err = appInfo.ToXML()
+			require.NoError(t, err)
+		case f.Name == contentsPath && modifyContents != nil:
+			modifyContents(content)
+		}
+
+		w, err := zw.Create(f.Name)
+		require.NoError(t, err)
+		_, err = w.Write(content)
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+func writeTemp(t *testing.T, data []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "app.intunewin")
+	require.NoError(t, os.WriteFile(path, data, 0600))
+	return path
+}
@@ -0,0 +1,73 @@
+package retry
+
+import (
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultPolicy is the retry policy of Graph and storage requests
+var DefaultPolicy = Policy{MaxRetries: 5, BaseDelay: time.Second, MaxDelay: time.Minute}
+
+// Policy bounds the retries of a request
+type Policy struct {
+	// MaxRetries is how many times a failed request is sent again
+	MaxRetries int
+	// BaseDelay is the delay before the first retry, doubled for each later one
+	BaseDelay time.Duration
+	// MaxDelay caps the delay between two attempts, including delays asked for with Retry-After
+	MaxDelay time.Duration
+}
+
+// jitter returns a random duration in [0, n)
+var jitter = func(n time.Duration) time.Duration {
+	if n <= 0 {
+		return 0
+	}
+	return rand.N(n) // #nosec G404 -- jitter needs no cryptographic randomness
+}
+
+// Delay returns how long to wait before retry number attempt, starting at 1. A delay the
+// server asked for with Retry-After is honored; otherwise the delay grows exponentially,
+// with half of it random so that concurrent clients do not retry in lockstep.
+func (p Policy) Delay(attempt int, retryAfter time.Duration) time.Duration {
+	if retryAfter > 0 {
+		return min(retryAfter, p.MaxDelay)
+	}
+	d := p.BaseDelay
+	for i := 1; i < attempt && d < p.MaxDelay; i++ {
+		d *= 2
+	}
+	d = min(d, p.MaxDelay)
+	return d/2 + jitter(d/2)
+}
+
+// RetryAfter returns the delay of the Retry-After header, given in seconds or as an HTTP
+// date, or zero when header has none
+func RetryAfter(header http.Header, now time.Time) time.Duration {
+	value := strings.TrimSpace(header.Get("Retry-After"))
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return max(0, time.Duration(seconds)*time.Second)
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return max(0, at.Sub(now))
+	}
+	return 0
+}
+
+// Status reports whether a response with status may succeed when the request is sent
+// again: throttling, timeouts and server errors
+func Status(status int) bool {
+	switch status {
+	case http.StatusRequestTimeout, http.StatusTooManyRequests, http.StatusInternalServerError,
+		http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,61 @@
+package retry
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDelay(t *testing.T) {
+	orig := jitter
+	jitter = func(n time.Duration) time.Duration { return n }
+	t.Cleanup(func() { jitter = orig })
+
+	p := Policy{MaxRetries: 5, BaseDelay: time.Second, MaxDelay: 10 * time.Second}
+	assert.Equal(t, time.Second, p.Delay(1, 0))
+	assert.Equal(t, 2*time.Second, p.Delay(2, 0))
+	assert.Equal(t, 8*time.Second, p.Delay(4, 0))
+	assert.Equal(t, 10*time.Second, p.Delay(10, 0), "capped at the maximum")
+	assert.Equal(t, 3*time.Second, p.Delay(1, 3*time.Second), "Retry-After is honored")
+	assert.Equal(t, 10*time.Second, p.Delay(1, time.Hour), "Retry-After is capped")
+}
+
+func TestDelayJitter(t *testing.T) {
+	p := Policy{BaseDelay: time.Second, MaxDelay: time.Minute}
+	for range 100 {
+		d := p.Delay(3, 0)
+		assert.GreaterOrEqual(t, d, 2*time.Second)
+		assert.Less(t, d, 4*time.Second)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"", 0},
+		{"30", 30 * time.Second},
+		{"-1", 0},
+		{now.Add(time.Minute).Format(http.TimeFormat), time.Minute},
+		{now.Add(-time.Minute).Format(http.TimeFormat), 0},
+		{"soon", 0},
+	}
+	for _, tt := range tests {
+		header := http.Header{}
+		if tt.value != "" {
+			header.Set("Retry-After", tt.value)
+		}
+		assert.Equal(t, tt.want, RetryAfter(header, now), tt.value)
+	}
+}
+
+func TestStatus(t *testing.T) {
+	assert.True(t, Status(http.StatusTooManyRequests))
+	assert.True(t, Status(http.StatusServiceUnavailable))
+	assert.False(t, Status(http.StatusBadRequest))
+	assert.False(t, Status(http.StatusForbidden))
+}
@@ -0,0 +1,104 @@
+package sbom
+
+import (
+	"strconv"
+)
+
+// cycloneDXDocument is a CycloneDX 1.5 BOM
+type cycloneDXDocument struct {
+	BOMFormat    string                `json:"bomFormat"`
+	SpecVersion  string                `json:"specVersion"`
+	SerialNumber string                `json:"serialNumber"`
+	Version      int                   `json:"version"`
+	Metadata     cycloneDXMetadata     `json:"metadata"`
+	Components   []cycloneDXComponent  `json:"components"`
+	Dependencies []cycloneDXDependency `json:"dependencies"`
+}
+
+type cycloneDXMetadata struct {
+	Timestamp string             `json:"timestamp"`
+	Tools     cycloneDXTools     `json:"tools"`
+	Component cycloneDXComponent `json:"component"`
+}
+
+type cycloneDXTools struct {
+	Components []cycloneDXComponent `json:"components"`
+}
+
+type cycloneDXComponent struct {
+	Type       string              `json:"type"`
+	BOMRef     string              `json:"bom-ref,omitempty"`
+	Supplier   *cycloneDXSupplier  `json:"supplier,omitempty"`
+	Name       string              `json:"name"`
+	Version    string              `json:"version,omitempty"`
+	Hashes     []cycloneDXHash     `json:"hashes,omitempty"`
+	Properties []cycloneDXProperty `json:"properties,omitempty"`
+}
+
+type cycloneDXSupplier struct {
+	Name string `json:"name"`
+}
+
+type cycloneDXHash struct {
+	Alg     string `json:"alg"`
+	Content string `json:"content"`
+}
+
+type cycloneDXProperty struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type cycloneDXDependency struct {
+	Ref       string   `json:"ref"`
+	DependsOn []string `json:"dependsOn"`
+}
+
+// cycloneDX returns the CycloneDX document listing files as file components of the application
+func cycloneDX(files []File, o *Options) cycloneDXDocument {
+	const rootRef = "application"
+
+	components := make([]cycloneDXComponent, 0, len(files))
+	refs := make([]string, 0, len(files))
+	for _, file := range files {
+		ref := "file:" + file.Path
+		component := cycloneDXComponent{
+			Type:    "file",
+			BOMRef:  ref,
+			Name:    file.Path,
+			Version: file.Version,
+			Hashes: []cycloneDXHash{
+				{Alg: "SHA-1", Content: file.SHA1},
+				{Alg: "SHA-256", Content: file.SHA256},
+			},
+			Properties: []cycloneDXProperty{{Name: "intunewin:size", Value: strconv.FormatInt(file.Size, 10)}},
+		}
+		if file.Supplier != "" {
+			component.Supplier = &cycloneDXSupplier{Name: file.Supplier}
+		}
+		if file.Product != "" {
+			component.Properties = append(component.Properties, cycloneDXProperty{Name: "intunewin:productName", Value: file.Product})
+		}
+		if file.ProductCode != "" {
+			component.Properties = append(component.Properties, cycloneDXProperty{Name: "intunewin:msiProductCode", Value: file.ProductCode})
+		}
+		components = append(components, component)
+		refs = append(refs, ref)
+	}
+
+	return cycloneDXDocument{
+		BOMFormat:    "CycloneDX",
+		SpecVersion:  "1.5",
+		SerialNumber: "urn:uuid:" + newUUID(o),
+		Version:      1,
+		Metadata: cycloneDXMetadata{
+			Timestamp: timestamp(o),
+			Tools: cycloneDXTools{Components: []cycloneDXComponent{
+				{Type: "application", Name: "intunewin", Version: o.toolVersion},
+			}},
+			Component: cycloneDXComponent{Type: "application", BOMRef: rootRef, Name: o.name, Version: o.version},
+		},
+		Components:   components,
+		Dependencies: []cycloneDXDependency{{Ref: rootRef, DependsOn: refs}},
+	}
+}
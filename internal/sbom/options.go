@@ -0,0 +1,50 @@
+package sbom
+
+import (
+	"crypto/rand"
+	"io"
+	"time"
+)
+
+// Options configures the generated SBOM
+type Options struct {
+	name        string
+	version     string
+	toolVersion string
+	// now and random are replaced in tests to get reproducible documents
+	now    func() time.Time
+	random io.Reader
+}
+
+// Option is a function that configures Options
+type Option func(*Options)
+
+// WithName sets the name of the application the SBOM describes
+func WithName(name string) Option {
+	return func(o *Options) {
+		o.name = name
+	}
+}
+
+// WithVersion sets the version of the application the SBOM describes
+func WithVersion(version string) Option {
+	return func(o *Options) {
+		o.version = version
+	}
+}
+
+// WithToolVersion sets the version of intunewin recorded as the creator of the SBOM
+func WithToolVersion(version string) Option {
+	return func(o *Options) {
+		o.toolVersion = version
+	}
+}
+
+// newOptions applies opts to the default options
+func newOptions(opts []Option) *Options {
+	o := &Options{now: time.Now, random: rand.Reader}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
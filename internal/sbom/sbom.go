@@ -0,0 +1,180 @@
+package sbom
+
+import (
+	"crypto/sha1" // #nosec G505 -- SPDX requires SHA-1 file checksums, they are not used for security
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kenchan0130/intunewin/internal/msi"
+	"github.com/kenchan0130/intunewin/internal/pe"
+)
+
+// Format is the document format of an SBOM
+type Format string
+
+const (
+	// FormatCycloneDX is a CycloneDX 1.5 JSON document
+	FormatCycloneDX Format = "cyclonedx"
+	// FormatSPDX is an SPDX 2.3 JSON document
+	FormatSPDX Format = "spdx"
+)
+
+// Formats lists every supported format
+var Formats = []Format{FormatCycloneDX, FormatSPDX}
+
+// ErrUnsupportedFormat is returned for an unknown SBOM format
+var ErrUnsupportedFormat = errors.New("unsupported SBOM format")
+
+// ParseFormat parses a format name
+func ParseFormat(s string) (Format, error) {
+	for _, f := range Formats {
+		if strings.EqualFold(s, string(f)) {
+			return f, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %q, expected cyclonedx or spdx", ErrUnsupportedFormat, s)
+}
+
+// File is a packaged file and the metadata read from it
+type File struct {
+	// Path is the slash separated path of the file in the content
+	Path string `json:"path"`
+	// Size is the size of the file in bytes
+	Size int64 `json:"size"`
+	// SHA1 is the hex encoded SHA-1 digest, required by SPDX
+	SHA1 string `json:"sha1"`
+	// SHA256 is the hex encoded SHA-256 digest
+	SHA256 string `json:"sha256"`
+	// Product is the product name of an executable or MSI installer
+	Product string `json:"product,omitempty"`
+	// Version is the file version of an executable or the ProductVersion of an MSI installer
+	Version string `json:"version,omitempty"`
+	// Supplier is the CompanyName of an executable or the Manufacturer of an MSI installer
+	Supplier string `json:"supplier,omitempty"`
+	// ProductCode is the ProductCode of an MSI installer
+	ProductCode string `json:"productCode,omitempty"`
+}
+
+// Scan hashes every file under root and reads the version information of executables
+// and MSI installers. Files are returned sorted by path.
+func Scan(root string) ([]File, error) {
+	var files []File
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err //nolint:wrapcheck // wrapped below
+		}
+		file, err := scanFile(path, filepath.ToSlash(rel))
+		if err != nil {
+			return err
+		}
+		files = append(files, file)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan %s: %w", root, err)
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+	return files, nil
+}
+
+// scanFile hashes the file at path and reads its version information
+func scanFile(path, rel string) (File, error) {
+	f, err := os.Open(path) // #nosec G304 -- path is found by walking the scanned folder
+	if err != nil {
+		return File{}, fmt.Errorf("failed to open %s: %w", rel, err)
+	}
+	defer f.Close()
+
+	sha1Hash := sha1.New() // #nosec G401 -- SPDX requires SHA-1 file checksums, they are not used for security
+	sha256Hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(sha1Hash, sha256Hash), f)
+	if err != nil {
+		return File{}, fmt.Errorf("failed to read %s: %w", rel, err)
+	}
+	file := File{
+		Path:   rel,
+		Size:   size,
+		SHA1:   hex.EncodeToString(sha1Hash.Sum(nil)),
+		SHA256: hex.EncodeToString(sha256Hash.Sum(nil)),
+	}
+
+	// Version information is best effort: files that merely look like installers are listed without it
+	switch strings.ToLower(filepath.Ext(rel)) {
+	case ".exe", ".dll", ".sys", ".ocx":
+		if info, err := pe.ReadVersion(f); err == nil {
+			file.Product = info.ProductName()
+			file.Version = info.DisplayVersion()
+			file.Supplier = info.Strings[pe.KeyCompanyName]
+		}
+	case ".msi":
+		if props, err := msi.ReadProperties(f); err == nil {
+			file.Product = props[msi.PropertyProductName]
+			file.Version = props[msi.PropertyProductVersion]
+			file.Supplier = props[msi.PropertyManufacturer]
+			file.ProductCode = props[msi.PropertyProductCode]
+		}
+	}
+	return file, nil
+}
+
+// Write writes the SBOM of files in format to w
+func Write(w io.Writer, format Format, files []File, opts ...Option) error {
+	o := newOptions(opts)
+	if o.name == "" {
+		o.name = "intunewin-package"
+	}
+
+	var doc any
+	switch format {
+	case FormatCycloneDX:
+		doc = cycloneDX(files, o)
+	case FormatSPDX:
+		doc = spdx(files, o)
+	default:
+		return fmt.Errorf("%w: %q", ErrUnsupportedFormat, format)
+	}
+	return writeJSON(w, doc)
+}
+
+// newUUID returns a random version 4 UUID
+func newUUID(o *Options) string {
+	var b [16]byte
+	_, _ = io.ReadFull(o.random, b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// timestamp returns the creation time of the document
+func timestamp(o *Options) string {
+	return o.now().UTC().Format(time.RFC3339)
+}
+
+// writeJSON writes doc to w as indented JSON
+func writeJSON(w io.Writer, doc any) error {
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode SBOM: %w", err)
+	}
+	if _, err := w.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write SBOM: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package sbom
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// withReproducibleIDs fixes the timestamp and identifiers of the document
+func withReproducibleIDs() Option {
+	return func(o *Options) {
+		o.now = func() time.Time { return time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC) }
+		o.random = bytes.NewReader(make([]byte, 16))
+	}
+}
+
+func writeFiles(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "bin"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "setup.exe"), []byte("not really an executable"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "bin", "abc.txt"), []byte("abc"), 0600))
+	return dir
+}
+
+func TestScan(t *testing.T) {
+	files, err := Scan(writeFiles(t))
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+
+	assert.Equal(t, File{
+		Path:   "bin/abc.txt",
+		Size:   3,
+		SHA1:   "a9993e364706816aba3e25717850c26c9cd0d89d",
+		SHA256: "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad",
+	}, files[0])
+	// Version information is best effort
+	assert.Equal(t, "setup.exe", files[1].Path)
+	assert.Empty(t, files[1].Version)
+}
+
+func TestParseFormat(t *testing.T) {
+	format, err := ParseFormat("CycloneDX")
+	require.NoError(t, err)
+	assert.Equal(t, FormatCycloneDX, format)
+
+	_, err = ParseFormat("swid")
+	assert.ErrorIs(t, err, ErrUnsupportedFormat)
+}
+
+func TestWriteCycloneDX(t *testing.T) {
+	files := []File{{Path: "setup.exe", Size: 10, SHA1: "s1", SHA256: "s256", Product: "App", Version: "1.2.3.4", Supplier: "Contoso"}}
+
+	var buf bytes.Buffer
+	require.NoError(t, Write(&buf, FormatCycloneDX, files, WithName("App"), WithVersion("1.2.3"), WithToolVersion("0.9.0"), withReproducibleIDs()))
+
+	var doc cycloneDXDocument
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &doc))
+	assert.Equal(t, "CycloneDX", doc.BOMFormat)
+	assert.Equal(t, "1.5", doc.SpecVersion)
+	assert.Equal(t, "urn:uuid:00000000-0000-4000-8000-000000000000", doc.SerialNumber)
+	assert.Equal(t, "2024-05-01T12:00:00Z", doc.Metadata.Timestamp)
+	assert.Equal(t, "App", doc.Metadata.Component.Name)
+	assert.Equal(t, "1.2.3", doc.Metadata.Component.Version)
+	assert.Equal(t, "0.9.0", doc.Metadata.Tools.Components[0].Version)
+
+	require.Len(t, doc.Components, 1)
+	component := doc.Components[0]
+	assert.Equal(t, "file", component.Type)
+	assert.Equal(t, "setup.exe", component.Name)
+	assert.Equal(t, "1.2.3.4", component.Version)
+	assert.Equal(t, "Contoso", component.Supplier.Name)
+	assert.Equal(t, []cycloneDXHash{{Alg: "SHA-1", Content: "s1"}, {Alg: "SHA-256", Content: "s256"}}, component.Hashes)
+	assert.Contains(t, component.Properties, cycloneDXProperty{Name: "intunewin:size", Value: "10"})
+	assert.Equal(t, []cycloneDXDependency{{Ref: "application", DependsOn: []string{"file:setup.exe"}}}, doc.Dependencies)
+}
+
+func TestWriteSPDX(t *testing.T) {
+	files, err := Scan(writeFiles(t))
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, Write(&buf, FormatSPDX, files, WithName("App"), withReproducibleIDs()))
+
+	var doc spdxDocument
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &doc))
+	assert.Equal(t, "SPDX-2.3", doc.SPDXVersion)
+	assert.Equal(t, "https://spdx.org/spdxdocs/intunewin/00000000-0000-4000-8000-000000000000", doc.DocumentNamespace)
+	assert.Equal(t, []string{"Tool: intunewin"}, doc.CreationInfo.Creators)
+
+	require.Len(t, doc.Packages, 1)
+	assert.Equal(t, []string{"SPDXRef-File-1", "SPDXRef-File-2"}, doc.Packages[0].HasFiles)
+	assert.Len(t, doc.Packages[0].PackageVerificationCode.Value, 40)
+
+	require.Len(t, doc.Files, 2)
+	assert.Equal(t, "./bin/abc.txt", doc.Files[0].FileName)
+	assert.Equal(t, "Size: 3 bytes", doc.Files[0].Comment)
+	assert.Len(t, doc.Relationships, 3)
+	assert.True(t, strings.HasSuffix(buf.String(), "}\n"))
+}
+
+func TestVerificationCode(t *testing.T) {
+	// The SHA-1 of the concatenation of the sorted digests
+	files := []File{{SHA1: "bb"}, {SHA1: "aa"}}
+	assert.Equal(t, "28cc5fd736aee0939ede3330c2867b31e82d9656", verificationCode(files))
+}
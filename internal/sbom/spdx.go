@@ -0,0 +1,149 @@
+package sbom
+
+import (
+	"crypto/sha1" // #nosec G505 -- the SPDX package verification code is defined with SHA-1
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// noAssertion is the SPDX value for information that was not determined
+const noAssertion = "NOASSERTION"
+
+// spdxDocument is an SPDX 2.3 document
+type spdxDocument struct {
+	SPDXVersion       string             `json:"spdxVersion"`
+	DataLicense       string             `json:"dataLicense"`
+	SPDXID            string             `json:"SPDXID"`
+	Name              string             `json:"name"`
+	DocumentNamespace string             `json:"documentNamespace"`
+	CreationInfo      spdxCreationInfo   `json:"creationInfo"`
+	Packages          []spdxPackage      `json:"packages"`
+	Files             []spdxFile         `json:"files"`
+	Relationships     []spdxRelationship `json:"relationships"`
+}
+
+type spdxCreationInfo struct {
+	Created  string   `json:"created"`
+	Creators []string `json:"creators"`
+}
+
+type spdxPackage struct {
+	SPDXID                  string               `json:"SPDXID"`
+	Name                    string               `json:"name"`
+	VersionInfo             string               `json:"versionInfo,omitempty"`
+	DownloadLocation        string               `json:"downloadLocation"`
+	FilesAnalyzed           bool                 `json:"filesAnalyzed"`
+	PackageVerificationCode spdxVerificationCode `json:"packageVerificationCode"`
+	LicenseConcluded        string               `json:"licenseConcluded"`
+	LicenseDeclared         string               `json:"licenseDeclared"`
+	CopyrightText           string               `json:"copyrightText"`
+	HasFiles                []string             `json:"hasFiles"`
+}
+
+type spdxVerificationCode struct {
+	Value string `json:"packageVerificationCodeValue"`
+}
+
+type spdxFile struct {
+	FileName         string         `json:"fileName"`
+	SPDXID           string         `json:"SPDXID"`
+	Checksums        []spdxChecksum `json:"checksums"`
+	LicenseConcluded string         `json:"licenseConcluded"`
+	CopyrightText    string         `json:"copyrightText"`
+	Comment          string         `json:"comment,omitempty"`
+}
+
+type spdxChecksum struct {
+	Algorithm string `json:"algorithm"`
+	Value     string `json:"checksumValue"`
+}
+
+type spdxRelationship struct {
+	Element string `json:"spdxElementId"`
+	Type    string `json:"relationshipType"`
+	Related string `json:"relatedSpdxElement"`
+}
+
+// spdx returns the SPDX document describing the application as a package containing files
+func spdx(files []File, o *Options) spdxDocument {
+	const packageID = "SPDXRef-Package"
+
+	spdxFiles := make([]spdxFile, 0, len(files))
+	ids := make([]string, 0, len(files))
+	relationships := []spdxRelationship{{Element: "SPDXRef-DOCUMENT", Type: "DESCRIBES", Related: packageID}}
+	for i, file := range files {
+		id := fmt.Sprintf("SPDXRef-File-%d", i+1)
+		spdxFiles = append(spdxFiles, spdxFile{
+			FileName: "./" + file.Path,
+			SPDXID:   id,
+			Checksums: []spdxChecksum{
+				{Algorithm: "SHA1", Value: file.SHA1},
+				{Algorithm: "SHA256", Value: file.SHA256},
+			},
+			LicenseConcluded: noAssertion,
+			CopyrightText:    noAssertion,
+			Comment:          spdxComment(file),
+		})
+		ids = append(ids, id)
+		relationships = append(relationships, spdxRelationship{Element: packageID, Type: "CONTAINS", Related: id})
+	}
+
+	tool := "Tool: intunewin"
+	if o.toolVersion != "" {
+		tool += "-" + o.toolVersion
+	}
+	return spdxDocument{
+		SPDXVersion:       "SPDX-2.3",
+		DataLicense:       "CC0-1.0",
+		SPDXID:            "SPDXRef-DOCUMENT",
+		Name:              o.name,
+		DocumentNamespace: "https://spdx.org/spdxdocs/intunewin/" + newUUID(o),
+		CreationInfo:      spdxCreationInfo{Created: timestamp(o), Creators: []string{tool}},
+		Packages: []spdxPackage{{
+			SPDXID:                  packageID,
+			Name:                    o.name,
+			VersionInfo:             o.version,
+			DownloadLocation:        noAssertion,
+			FilesAnalyzed:           true,
+			PackageVerificationCode: spdxVerificationCode{Value: verificationCode(files)},
+			LicenseConcluded:        noAssertion,
+			LicenseDeclared:         noAssertion,
+			CopyrightText:           noAssertion,
+			HasFiles:                ids,
+		}},
+		Files:         spdxFiles,
+		Relationships: relationships,
+	}
+}
+
+// spdxComment describes the size and version information of file, which SPDX files have no fields for
+func spdxComment(file File) string {
+	parts := []string{fmt.Sprintf("Size: %d bytes", file.Size)}
+	if file.Product != "" {
+		parts = append(parts, "Product: "+file.Product)
+	}
+	if file.Version != "" {
+		parts = append(parts, "Version: "+file.Version)
+	}
+	if file.Supplier != "" {
+		parts = append(parts, "Supplier: "+file.Supplier)
+	}
+	if file.ProductCode != "" {
+		parts = append(parts, "ProductCode: "+file.ProductCode)
+	}
+	return strings.Join(parts, "; ")
+}
+
+// verificationCode returns the SPDX package verification code: the SHA-1 of the sorted
+// SHA-1 digests of the files
+func verificationCode(files []File) string {
+	digests := make([]string, 0, len(files))
+	for _, file := range files {
+		digests = append(digests, file.SHA1)
+	}
+	sort.Strings(digests)
+	sum := sha1.Sum([]byte(strings.Join(digests, ""))) // #nosec G401 -- the SPDX package verification code is defined with SHA-1
+	return hex.EncodeToString(sum[:])
+}
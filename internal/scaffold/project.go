@@ -0,0 +1,57 @@
+package scaffold
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/kenchan0130/intunewin/internal/project"
+	"gopkg.in/yaml.v3"
+)
+
+// WriteProject writes the project file of p to dir, with comments describing every
+// setting. An existing project file is only replaced with force. It returns the path of
+// the file.
+func WriteProject(dir string, p project.Project, force bool) (string, error) {
+	if strings.TrimSpace(p.Name) == "" {
+		return "", ErrAppNameRequired
+	}
+	path := filepath.Join(dir, project.FileName)
+	if _, err := os.Stat(path); err == nil && !force {
+		return "", fmt.Errorf("%w: %s", ErrExists, path)
+	}
+
+	tmpl, err := template.New(project.FileName+".tmpl").Funcs(template.FuncMap{"yaml": quoteYAML}).
+		ParseFS(templates, "templates/project/"+project.FileName+".tmpl")
+	if err != nil {
+		return "", fmt.Errorf("failed to parse template: %w", err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, p); err != nil {
+		return "", fmt.Errorf("failed to render %s: %w", project.FileName, err)
+	}
+	// The written file must be usable as it is
+	if _, err := project.Parse(buf.Bytes()); err != nil {
+		return "", err //nolint:wrapcheck // project errors already describe the failure
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil { // #nosec G301 -- the package folder is not secret
+		return "", fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil { // #nosec G306 -- the project file is not secret
+		return "", fmt.Errorf("failed to write %s: %w", project.FileName, err)
+	}
+	return path, nil
+}
+
+// quoteYAML returns s as a YAML scalar
+func quoteYAML(s string) (string, error) {
+	data, err := yaml.Marshal(s)
+	if err != nil {
+		return "", err //nolint:wrapcheck // reported by the template
+	}
+	return strings.TrimSuffix(string(data), "\n"), nil
+}
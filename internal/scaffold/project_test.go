@@ -0,0 +1,41 @@
+package scaffold
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/kenchan0130/intunewin/internal/project"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteProject(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "myapp")
+	want := project.Project{
+		Name:             "My App: 2024",
+		SetupFile:        "setup.msi",
+		InstallCommand:   `msiexec /i "setup.msi" /qn`,
+		UninstallCommand: `msiexec /x "setup.msi" /qn`,
+	}
+	path, err := WriteProject(dir, want, false)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "intunewin.yaml"), path)
+
+	got, err := project.Load(path)
+	require.NoError(t, err)
+	assert.Equal(t, want.Name, got.Name)
+	assert.Equal(t, want.SetupFile, got.SetupFile)
+	assert.Equal(t, want.InstallCommand, got.InstallCommand)
+	assert.Equal(t, want.UninstallCommand, got.UninstallCommand)
+	assert.Empty(t, got.Exclude)
+
+	_, err = WriteProject(dir, want, false)
+	require.ErrorIs(t, err, ErrExists)
+	_, err = WriteProject(dir, want, true)
+	require.NoError(t, err)
+}
+
+func TestWriteProjectRequiresName(t *testing.T) {
+	_, err := WriteProject(t.TempDir(), project.Project{}, false)
+	require.ErrorIs(t, err, ErrAppNameRequired)
+}
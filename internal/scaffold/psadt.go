@@ -0,0 +1,140 @@
+// Package scaffold lays down starting points for new packages.
+package scaffold
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+)
+
+//go:embed templates
+var templates embed.FS
+
+// PSADTScript is the deployment script of a PowerShell App Deployment Toolkit package
+const PSADTScript = "Deploy-Application.ps1"
+
+// PSADTFolders are the folders of a PowerShell App Deployment Toolkit package that hold
+// the installer and the files it needs
+var PSADTFolders = []string{"Files", "SupportFiles"}
+
+var (
+	// ErrAppNameRequired is returned when no application name is given
+	ErrAppNameRequired = errors.New("an application name is required")
+	// ErrExists is returned when a file to write already exists
+	ErrExists = errors.New("file already exists")
+)
+
+// PSADT describes the application a PowerShell App Deployment Toolkit skeleton deploys
+type PSADT struct {
+	// AppName is the name of the application, required
+	AppName string
+	// Vendor is the publisher of the application
+	Vendor string
+	// Version is the version of the application
+	Version string
+	// Arch is the architecture of the application, x64 when empty
+	Arch string
+	// Lang is the language of the application, EN when empty
+	Lang string
+	// Author is the author of the deployment script
+	Author string
+	// Installer is the name of the installer in the Files folder; an .msi is installed
+	// with Execute-MSI and anything else with Execute-Process
+	Installer string
+	// CloseApps lists the processes closed before installing, comma separated
+	CloseApps string
+	// Date is the script date, today when zero
+	Date time.Time
+}
+
+// WritePSADT lays down the PowerShell App Deployment Toolkit skeleton of p in dir:
+// Deploy-Application.ps1 with the application variables filled in, and the Files and
+// SupportFiles folders. An existing Deploy-Application.ps1 is only replaced with force.
+// It returns the path of the script.
+func WritePSADT(dir string, p PSADT, force bool) (string, error) {
+	if strings.TrimSpace(p.AppName) == "" {
+		return "", ErrAppNameRequired
+	}
+	if p.Arch == "" {
+		p.Arch = "x64"
+	}
+	if p.Lang == "" {
+		p.Lang = "EN"
+	}
+	if p.Date.IsZero() {
+		p.Date = time.Now()
+	}
+
+	script := filepath.Join(dir, PSADTScript)
+	if _, err := os.Stat(script); err == nil && !force {
+		return "", fmt.Errorf("%w: %s", ErrExists, script)
+	}
+
+	data, err := renderPSADT(p)
+	if err != nil {
+		return "", err
+	}
+	for _, folder := range PSADTFolders {
+		if err := os.MkdirAll(filepath.Join(dir, folder), 0755); err != nil { // #nosec G301 -- the package folders are not secret
+			return "", fmt.Errorf("failed to create %s: %w", folder, err)
+		}
+	}
+	if err := os.WriteFile(script, data, 0644); err != nil { // #nosec G306 -- the script is not secret
+		return "", fmt.Errorf("failed to write %s: %w", PSADTScript, err)
+	}
+	return script, nil
+}
+
+// renderPSADT returns Deploy-Application.ps1 for p with Windows line endings
+func renderPSADT(p PSADT) ([]byte, error) {
+	tmpl, err := template.New(PSADTScript+".tmpl").Funcs(template.FuncMap{"ps": quotePS}).
+		ParseFS(templates, "templates/psadt/"+PSADTScript+".tmpl")
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template: %w", err)
+	}
+
+	install, uninstall := psadtCommands(p.Installer)
+	var buf bytes.Buffer
+	err = tmpl.Execute(&buf, map[string]string{
+		"AppName":          p.AppName,
+		"Vendor":           p.Vendor,
+		"Version":          p.Version,
+		"Arch":             p.Arch,
+		"Lang":             p.Lang,
+		"Author":           p.Author,
+		"CloseApps":        p.CloseApps,
+		"Date":             p.Date.Format("01/02/2006"),
+		"InstallCommand":   install,
+		"UninstallCommand": uninstall,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to render %s: %w", PSADTScript, err)
+	}
+	return bytes.ReplaceAll(buf.Bytes(), []byte("\n"), []byte("\r\n")), nil
+}
+
+// psadtCommands returns the install and uninstall commands of installer
+func psadtCommands(installer string) (install, uninstall string) {
+	switch {
+	case installer == "":
+		return "# Execute-MSI -Action 'Install' -Path 'setup.msi'",
+			"# Execute-MSI -Action 'Uninstall' -Path 'setup.msi'"
+	case strings.EqualFold(filepath.Ext(installer), ".msi"):
+		return fmt.Sprintf("Execute-MSI -Action 'Install' -Path '%s'", quotePS(installer)),
+			fmt.Sprintf("Execute-MSI -Action 'Uninstall' -Path '%s'", quotePS(installer))
+	default:
+		return fmt.Sprintf("Execute-Process -Path '%s' -Parameters '/S'", quotePS(installer)),
+			"# Execute-Process -Path \"$envProgramFiles\\<app>\\uninstall.exe\" -Parameters '/S'"
+	}
+}
+
+// quotePS escapes s for a single-quoted PowerShell string
+func quotePS(s string) string {
+	return strings.ReplaceAll(s, "'", "''")
+}
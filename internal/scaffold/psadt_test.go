@@ -0,0 +1,63 @@
+package scaffold
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWritePSADT(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "myapp")
+	script, err := WritePSADT(dir, PSADT{
+		AppName:   "Contoso's App",
+		Vendor:    "Contoso",
+		Version:   "1.2.3",
+		Installer: "ContosoApp.msi",
+		Date:      time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC),
+	}, false)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "Deploy-Application.ps1"), script)
+	assert.DirExists(t, filepath.Join(dir, "Files"))
+	assert.DirExists(t, filepath.Join(dir, "SupportFiles"))
+
+	data, err := os.ReadFile(script)
+	require.NoError(t, err)
+	content := string(data)
+	assert.Contains(t, content, "[String]$appName = 'Contoso''s App'\r\n")
+	assert.Contains(t, content, "[String]$appVendor = 'Contoso'")
+	assert.Contains(t, content, "[String]$appVersion = '1.2.3'")
+	assert.Contains(t, content, "[String]$appArch = 'x64'")
+	assert.Contains(t, content, "[String]$appScriptDate = '05/01/2024'")
+	assert.Contains(t, content, "Execute-MSI -Action 'Install' -Path 'ContosoApp.msi'")
+	assert.Contains(t, content, "Execute-MSI -Action 'Uninstall' -Path 'ContosoApp.msi'")
+	assert.NotContains(t, strings.ReplaceAll(content, "\r\n", ""), "\n", "every line ends with CRLF")
+}
+
+func TestWritePSADTExecutable(t *testing.T) {
+	script, err := WritePSADT(t.TempDir(), PSADT{AppName: "App", Installer: "setup.exe"}, false)
+	require.NoError(t, err)
+	data, err := os.ReadFile(script)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "Execute-Process -Path 'setup.exe' -Parameters '/S'")
+}
+
+func TestWritePSADTExisting(t *testing.T) {
+	dir := t.TempDir()
+	_, err := WritePSADT(dir, PSADT{AppName: "App"}, false)
+	require.NoError(t, err)
+
+	_, err = WritePSADT(dir, PSADT{AppName: "App"}, false)
+	require.ErrorIs(t, err, ErrExists)
+	_, err = WritePSADT(dir, PSADT{AppName: "App"}, true)
+	require.NoError(t, err)
+}
+
+func TestWritePSADTRequiresName(t *testing.T) {
+	_, err := WritePSADT(t.TempDir(), PSADT{}, false)
+	require.ErrorIs(t, err, ErrAppNameRequired)
+}
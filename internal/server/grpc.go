@@ -0,0 +1,208 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	intunewinv1 "github.com/kenchan0130/intunewin/api/gen/intunewin/v1"
+	"github.com/kenchan0130/intunewin/internal/metadata"
+	"github.com/kenchan0130/intunewin/internal/pack"
+	"github.com/kenchan0130/intunewin/internal/spill"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// chunkSize is the largest chunk of data sent in one response message, well below the
+// default message size limit of gRPC
+const chunkSize = 1 << 20
+
+// packagingService serves PackagingService with the packaging of the HTTP handler
+type packagingService struct {
+	intunewinv1.UnimplementedPackagingServiceServer
+
+	h *handler
+}
+
+// RegisterGRPC registers PackagingService, which offers the operations of NewHandler with
+// streamed chunks in place of request and response bodies, on s
+func RegisterGRPC(s grpc.ServiceRegistrar, opts ...Option) {
+	intunewinv1.RegisterPackagingServiceServer(s, &packagingService{h: &handler{opts: newOptions(opts)}})
+}
+
+// Pack packs the source archive streamed after the options of the first message
+func (s *packagingService) Pack(stream grpc.BidiStreamingServer[intunewinv1.PackRequest, intunewinv1.PackResponse]) error {
+	first, err := stream.Recv()
+	if err != nil {
+		return s.status(fmt.Errorf("%w: failed to read request: %w", errBadRequest, err))
+	}
+	options := first.GetOptions()
+	name, setupFile := options.GetName(), options.GetSetupFile()
+	if name == "" {
+		name = defaultName
+	}
+	if setupFile == "" {
+		return status.Error(codes.InvalidArgument, "the setup file is required")
+	}
+
+	output := s.h.newBuffer("intunewin-package-*")
+	defer output.Close()
+	r := s.newChunkReader(first.GetChunk(), func() ([]byte, error) {
+		req, err := stream.Recv()
+		return req.GetChunk(), err
+	})
+	opts := s.h.packOptions(options.GetDescription())
+	switch options.GetFormat() {
+	case intunewinv1.ArchiveFormat_ARCHIVE_FORMAT_TAR:
+		err = pack.PackTarTo(r, output, name, setupFile, opts...)
+	default:
+		err = pack.PackZipTo(r, output, name, setupFile, opts...)
+	}
+	if err != nil {
+		return s.status(fmt.Errorf("failed to pack: %w", err))
+	}
+	return sendChunks(output, func(chunk []byte) error {
+		return stream.Send(&intunewinv1.PackResponse{Chunk: chunk})
+	})
+}
+
+// Unpack decrypts the streamed package and streams back its content zip
+func (s *packagingService) Unpack(stream grpc.BidiStreamingServer[intunewinv1.UnpackRequest, intunewinv1.UnpackResponse]) error {
+	output := s.h.newBuffer("intunewin-content-*")
+	defer output.Close()
+	r := s.newChunkReader(nil, func() ([]byte, error) {
+		req, err := stream.Recv()
+		return req.GetChunk(), err
+	})
+	if err := s.h.decrypt(r, output); err != nil {
+		return s.status(fmt.Errorf("failed to unpack: %w", err))
+	}
+	return sendChunks(output, func(chunk []byte) error {
+		return stream.Send(&intunewinv1.UnpackResponse{Chunk: chunk})
+	})
+}
+
+// Inspect returns the metadata of the streamed package without decrypting it
+func (s *packagingService) Inspect(stream grpc.ClientStreamingServer[intunewinv1.InspectRequest, intunewinv1.InspectResponse]) error {
+	r := s.newChunkReader(nil, func() ([]byte, error) {
+		req, err := stream.Recv()
+		return req.GetChunk(), err
+	})
+	appInfo, err := s.h.readApplicationInfo(r)
+	if err != nil {
+		return s.status(err)
+	}
+	return stream.SendAndClose(inspectResponse(appInfo)) //nolint:wrapcheck // stream errors are returned to gRPC as is
+}
+
+// status converts err to a gRPC status with the code of its HTTP status
+func (s *packagingService) status(err error) error {
+	code := codes.Internal
+	switch statusCode(err) {
+	case http.StatusBadRequest, http.StatusUnprocessableEntity:
+		code = codes.InvalidArgument
+	case http.StatusRequestEntityTooLarge:
+		code = codes.ResourceExhausted
+	default:
+		s.h.opts.Logger.Error("request failed", "error", err)
+	}
+	return status.Error(code, err.Error()) //nolint:wrapcheck // the status carries the message of err
+}
+
+// chunkReader reads the chunks of a request stream as one body
+type chunkReader struct {
+	recv  func() ([]byte, error)
+	chunk []byte
+	// remaining is the number of bytes the body may still have
+	remaining int64
+	limit     int64
+	err       error
+}
+
+// newChunkReader returns a reader of first followed by the chunks recv returns until
+// io.EOF, limited to the maximum body size
+func (s *packagingService) newChunkReader(first []byte, recv func() ([]byte, error)) *chunkReader {
+	limit := s.h.opts.MaxBodySize
+	r := &chunkReader{recv: recv, remaining: limit, limit: limit}
+	r.add(first)
+	return r
+}
+
+// add makes chunk the next data to read, unless the body grows beyond the limit
+func (r *chunkReader) add(chunk []byte) {
+	if int64(len(chunk)) > r.remaining {
+		// The error of the HTTP body limit, so statusCode maps both alike
+		r.err = &http.MaxBytesError{Limit: r.limit}
+		return
+	}
+	r.remaining -= int64(len(chunk))
+	r.chunk = chunk
+}
+
+func (r *chunkReader) Read(p []byte) (int, error) {
+	for len(r.chunk) == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+		chunk, err := r.recv()
+		switch {
+		case errors.Is(err, io.EOF):
+			r.err = io.EOF
+		case err != nil:
+			r.err = fmt.Errorf("%w: failed to read request: %w", errBadRequest, err)
+		default:
+			r.add(chunk)
+		}
+	}
+	n := copy(p, r.chunk)
+	r.chunk = r.chunk[n:]
+	return n, nil
+}
+
+// sendChunks sends the content of buf in chunks of at most chunkSize bytes
+func sendChunks(buf *spill.Buffer, send func([]byte) error) error {
+	if _, err := buf.Seek(0, io.SeekStart); err != nil {
+		return status.Errorf(codes.Internal, "failed to read response: %v", err)
+	}
+	chunk := make([]byte, chunkSize)
+	for {
+		n, err := io.ReadFull(buf, chunk)
+		if n > 0 {
+			if err := send(chunk[:n]); err != nil {
+				return err
+			}
+		}
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil
+		}
+		if err != nil {
+			return status.Errorf(codes.Internal, "failed to read response: %v", err)
+		}
+	}
+}
+
+// inspectResponse converts appInfo to the response of Inspect
+func inspectResponse(appInfo *metadata.ApplicationInfo) *intunewinv1.InspectResponse {
+	resp := &intunewinv1.InspectResponse{
+		ToolVersion:            appInfo.ToolVersion,
+		Name:                   appInfo.Name,
+		Description:            appInfo.Description,
+		UnencryptedContentSize: appInfo.UnencryptedContentSize,
+		FileName:               appInfo.FileName,
+		SetupFile:              appInfo.SetupFile,
+	}
+	if info := appInfo.EncryptionInfo; info != nil {
+		resp.EncryptionInfo = &intunewinv1.EncryptionInfo{
+			EncryptionKey:        info.EncryptionKey,
+			MacKey:               info.MacKey,
+			InitializationVector: info.InitializationVector,
+			Mac:                  info.Mac,
+			ProfileIdentifier:    info.ProfileIdentifier,
+			FileDigest:           info.FileDigest,
+			FileDigestAlgorithm:  info.FileDigestAlgorithm,
+		}
+	}
+	return resp
+}
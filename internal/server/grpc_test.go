@@ -0,0 +1,151 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"testing"
+
+	intunewinv1 "github.com/kenchan0130/intunewin/api/gen/intunewin/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// newTestClient serves PackagingService in memory and returns a client of it
+func newTestClient(t *testing.T, opts ...Option) intunewinv1.PackagingServiceClient {
+	t.Helper()
+	listener := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer()
+	RegisterGRPC(srv, append([]Option{WithTempDir(t.TempDir())}, opts...)...)
+	go func() {
+		_ = srv.Serve(listener)
+	}()
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return listener.Dial() }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	return intunewinv1.NewPackagingServiceClient(conn)
+}
+
+// chunksOf splits data into chunks of size bytes
+func chunksOf(data []byte, size int) [][]byte {
+	var chunks [][]byte
+	for len(data) > size {
+		chunks = append(chunks, data[:size])
+		data = data[size:]
+	}
+	return append(chunks, data)
+}
+
+// receiveAll reads the chunks of a response stream until it ends
+func receiveAll(t *testing.T, recv func() ([]byte, error)) ([]byte, error) {
+	t.Helper()
+	var buf bytes.Buffer
+	for {
+		chunk, err := recv()
+		if errors.Is(err, io.EOF) {
+			return buf.Bytes(), nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(chunk)
+	}
+}
+
+// packOverGRPC packs archive in chunks of 100 bytes and returns the package
+func packOverGRPC(t *testing.T, client intunewinv1.PackagingServiceClient, options *intunewinv1.PackOptions, archive []byte) ([]byte, error) {
+	t.Helper()
+	stream, err := client.Pack(context.Background())
+	require.NoError(t, err)
+	for i, chunk := range chunksOf(archive, 100) {
+		req := &intunewinv1.PackRequest{Chunk: chunk}
+		if i == 0 {
+			req.Options = options
+		}
+		// The server may fail and close the stream before reading every chunk
+		if err := stream.Send(req); err != nil {
+			break
+		}
+	}
+	require.NoError(t, stream.CloseSend())
+	return receiveAll(t, func() ([]byte, error) {
+		resp, err := stream.Recv()
+		return resp.GetChunk(), err
+	})
+}
+
+// unpackOverGRPC decrypts packed in chunks of 100 bytes and returns the content zip
+func unpackOverGRPC(t *testing.T, client intunewinv1.PackagingServiceClient, packed []byte) ([]byte, error) {
+	t.Helper()
+	stream, err := client.Unpack(context.Background())
+	require.NoError(t, err)
+	for _, chunk := range chunksOf(packed, 100) {
+		if err := stream.Send(&intunewinv1.UnpackRequest{Chunk: chunk}); err != nil {
+			break
+		}
+	}
+	require.NoError(t, stream.CloseSend())
+	return receiveAll(t, func() ([]byte, error) {
+		resp, err := stream.Recv()
+		return resp.GetChunk(), err
+	})
+}
+
+// inspectOverGRPC sends packed in chunks of 100 bytes and returns its metadata
+func inspectOverGRPC(t *testing.T, client intunewinv1.PackagingServiceClient, packed []byte) (*intunewinv1.InspectResponse, error) {
+	t.Helper()
+	stream, err := client.Inspect(context.Background())
+	require.NoError(t, err)
+	for _, chunk := range chunksOf(packed, 100) {
+		if err := stream.Send(&intunewinv1.InspectRequest{Chunk: chunk}); err != nil {
+			break
+		}
+	}
+	return stream.CloseAndRecv() //nolint:wrapcheck // test helper
+}
+
+func TestGRPCPackUnpackInspect(t *testing.T) {
+	client := newTestClient(t)
+	files := map[string]string{"setup.exe": "setup", "data/config.ini": "[app]"}
+
+	packed, err := packOverGRPC(t, client, &intunewinv1.PackOptions{Name: "MyApp", SetupFile: "setup.exe", Description: "My app"}, zipOf(t, files))
+	require.NoError(t, err)
+
+	content, err := unpackOverGRPC(t, client, packed)
+	require.NoError(t, err)
+	assert.Equal(t, files, readZip(t, content))
+
+	info, err := inspectOverGRPC(t, client, packed)
+	require.NoError(t, err)
+	assert.Equal(t, "MyApp", info.GetName())
+	assert.Equal(t, "My app", info.GetDescription())
+	assert.Equal(t, "setup.exe", info.GetSetupFile())
+	assert.NotEmpty(t, info.GetEncryptionInfo().GetMac())
+}
+
+func TestGRPCErrors(t *testing.T) {
+	client := newTestClient(t, WithMaxBodySize(1024))
+	archive := zipOf(t, map[string]string{"a.exe": "a"})
+
+	_, err := packOverGRPC(t, client, &intunewinv1.PackOptions{}, archive)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err), "the setup file is required")
+	_, err = packOverGRPC(t, client, &intunewinv1.PackOptions{SetupFile: "b.exe"}, archive)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err), "the setup file is not in the archive")
+	_, err = unpackOverGRPC(t, client, []byte("not a package"))
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = inspectOverGRPC(t, client, []byte("not a package"))
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = inspectOverGRPC(t, client, make([]byte, 2048))
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+}
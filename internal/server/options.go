@@ -0,0 +1,78 @@
+package server
+
+import (
+	"log/slog"
+
+	"github.com/kenchan0130/intunewin/internal/spill"
+)
+
+// DefaultMaxBodySize is the largest request body accepted, the size limit of Win32 apps
+const DefaultMaxBodySize = 30 << 30
+
+// Options holds settings for the handler
+type Options struct {
+	// Logger receives request logs and diagnostic messages
+	Logger *slog.Logger
+	// MaxBodySize is the largest request body accepted, in bytes
+	MaxBodySize int64
+	// TempDir is where large uploads and packages are buffered, the system default when empty
+	TempDir string
+	// SpillThreshold is the size above which data is buffered in temporary files
+	SpillThreshold int64
+	// MemoryLimit caps the data buffered in memory across all requests, no cap when nil
+	MemoryLimit *spill.Limit
+}
+
+// Option configures Options
+type Option func(*Options)
+
+// WithLogger sends request logs and diagnostic messages to logger
+func WithLogger(logger *slog.Logger) Option {
+	return func(o *Options) {
+		o.Logger = logger
+	}
+}
+
+// WithMaxBodySize rejects request bodies larger than size bytes
+func WithMaxBodySize(size int64) Option {
+	return func(o *Options) {
+		o.MaxBodySize = size
+	}
+}
+
+// WithTempDir buffers large data in dir instead of the system temporary directory
+func WithTempDir(dir string) Option {
+	return func(o *Options) {
+		o.TempDir = dir
+	}
+}
+
+// WithSpillThreshold buffers data larger than threshold bytes in temporary files
+func WithSpillThreshold(threshold int64) Option {
+	return func(o *Options) {
+		o.SpillThreshold = threshold
+	}
+}
+
+// WithMemoryLimit buffers data in temporary files once the requests hold the maximum
+// of limit in memory
+func WithMemoryLimit(limit *spill.Limit) Option {
+	return func(o *Options) {
+		o.MemoryLimit = limit
+	}
+}
+
+// newOptions applies opts over the defaults
+func newOptions(opts []Option) *Options {
+	o := &Options{
+		MaxBodySize:    DefaultMaxBodySize,
+		SpillThreshold: spill.DefaultThreshold,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.Logger == nil {
+		o.Logger = slog.New(slog.DiscardHandler)
+	}
+	return o
+}
@@ -0,0 +1,338 @@
+package server
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/kenchan0130/intunewin/internal/crypto"
+	"github.com/kenchan0130/intunewin/internal/metadata"
+	"github.com/kenchan0130/intunewin/internal/pack"
+	"github.com/kenchan0130/intunewin/internal/pathutil"
+	"github.com/kenchan0130/intunewin/internal/spill"
+	"github.com/kenchan0130/intunewin/internal/unpack"
+)
+
+// Content types of request and response bodies
+const (
+	contentTypeZip       = "application/zip"
+	contentTypeTar       = "application/x-tar"
+	contentTypeMultipart = "multipart/form-data"
+	contentTypeIntunewin = "application/octet-stream"
+	contentTypeJSON      = "application/json"
+)
+
+// defaultName is the application name of packages packed without one
+const defaultName = "app"
+
+// errorResponse is the body of an error response
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// handler serves the packaging endpoints
+type handler struct {
+	opts *Options
+	mux  *http.ServeMux
+}
+
+// NewHandler returns an HTTP handler with these endpoints:
+//
+//	POST /v1/pack     zip, tar or multipart folder upload to an intunewin package
+//	POST /v1/unpack   intunewin package to its decrypted content zip
+//	POST /v1/inspect  intunewin package to the JSON rendering of its Detection.xml
+//	GET  /healthz     liveness check
+func NewHandler(opts ...Option) http.Handler {
+	h := &handler{opts: newOptions(opts), mux: http.NewServeMux()}
+	h.mux.HandleFunc("POST /v1/pack", h.pack)
+	h.mux.HandleFunc("POST /v1/unpack", h.unpack)
+	h.mux.HandleFunc("POST /v1/inspect", h.inspect)
+	h.mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		_, _ = io.WriteString(w, "ok\n")
+	})
+	return h
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	r.Body = http.MaxBytesReader(w, r.Body, h.opts.MaxBodySize)
+	rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+	h.mux.ServeHTTP(rec, r)
+	h.opts.Logger.Info("handled request", "method", r.Method, "path", r.URL.Path,
+		"status", rec.status, "duration", time.Since(start))
+}
+
+// statusRecorder records the status code of a response for the request log
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// pack packs the uploaded content. The application name, setup file and description are
+// read from the name, setupFile and description query parameters; the setup file is
+// detected for folder uploads and required for archives.
+func (h *handler) pack(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	name, setupFile := query.Get("name"), query.Get("setupFile")
+	if name == "" {
+		name = defaultName
+	}
+	opts := h.packOptions(query.Get("description"))
+
+	output := h.newBuffer("intunewin-package-*")
+	defer output.Close()
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	var err error
+	switch mediaType {
+	case contentTypeZip, contentTypeTar:
+		if setupFile == "" {
+			h.writeError(w, http.StatusBadRequest, errors.New("the setupFile query parameter is required for archive uploads"))
+			return
+		}
+		if mediaType == contentTypeZip {
+			err = pack.PackZipTo(r.Body, output, name, setupFile, opts...)
+		} else {
+			err = pack.PackTarTo(r.Body, output, name, setupFile, opts...)
+		}
+	case contentTypeMultipart:
+		err = h.packFolder(r, output, name, setupFile, opts)
+	default:
+		h.writeError(w, http.StatusUnsupportedMediaType,
+			fmt.Errorf("unsupported content type %q (expected %s, %s or %s)", mediaType, contentTypeZip, contentTypeTar, contentTypeMultipart))
+		return
+	}
+	if err != nil {
+		h.writeError(w, statusCode(err), fmt.Errorf("failed to pack: %w", err))
+		return
+	}
+
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": name + ".intunewin"}))
+	h.writeBuffer(w, contentTypeIntunewin, output)
+}
+
+// packOptions returns the pack options of a request with description
+func (h *handler) packOptions(description string) []pack.Option {
+	return []pack.Option{
+		pack.WithLogger(h.opts.Logger),
+		pack.WithTempDir(h.opts.TempDir),
+		pack.WithSpillThreshold(h.opts.SpillThreshold),
+		pack.WithMemoryLimit(h.opts.MemoryLimit),
+		pack.WithDescription(description),
+	}
+}
+
+// packFolder writes the files of a multipart upload to a temporary folder and packs it.
+// Each part is a file whose file name is its path relative to the folder.
+func (h *handler) packFolder(r *http.Request, output io.Writer, name, setupFile string, opts []pack.Option) error {
+	dir, err := os.MkdirTemp(h.opts.TempDir, "intunewin-upload-*")
+	if err != nil {
+		return fmt.Errorf("failed to create upload folder: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return fmt.Errorf("%w: %w", errBadRequest, err)
+	}
+	files := 0
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("%w: failed to read upload: %w", errBadRequest, err)
+		}
+		fileName := partFileName(part)
+		if fileName == "" {
+			continue
+		}
+		if err := saveUpload(dir, fileName, part); err != nil {
+			return err
+		}
+		files++
+	}
+	if files == 0 {
+		return fmt.Errorf("%w: the upload has no files", errBadRequest)
+	}
+
+	if setupFile == "" {
+		if setupFile, err = pack.DetectSetupFile(dir); err != nil {
+			return err //nolint:wrapcheck // wrapped by the caller
+		}
+	}
+	return pack.PackWithInfoTo(dir, output, name, setupFile, opts...) //nolint:wrapcheck // wrapped by the caller
+}
+
+// partFileName returns the file name of a multipart part as sent by the client.
+// Part.FileName drops the folders of the name, which are needed to rebuild the folder.
+func partFileName(part *multipart.Part) string {
+	_, params, err := mime.ParseMediaType(part.Header.Get("Content-Disposition"))
+	if err != nil {
+		return ""
+	}
+	return params["filename"]
+}
+
+// saveUpload writes r to name in dir; names that escape dir are rejected
+func saveUpload(dir, name string, r io.Reader) error {
+	path, err := pathutil.Join(dir, filepath.ToSlash(name))
+	if err != nil {
+		return fmt.Errorf("%w: invalid file name %q: %w", errBadRequest, name, err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+		return fmt.Errorf("failed to create upload folder: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600) // #nosec G304 -- path is checked by pathutil.Join
+	if err != nil {
+		return fmt.Errorf("%w: failed to save %q: %w", errBadRequest, name, err)
+	}
+	defer f.Close()
+	if _, err := io.Copy(f, r); err != nil {
+		return fmt.Errorf("failed to save %q: %w", name, err)
+	}
+	return f.Close() //nolint:wrapcheck // close errors of a temporary file need no context
+}
+
+// unpack decrypts the uploaded package and returns its content
+func (h *handler) unpack(w http.ResponseWriter, r *http.Request) {
+	output := h.newBuffer("intunewin-content-*")
+	defer output.Close()
+
+	if err := h.decrypt(r.Body, output); err != nil {
+		h.writeError(w, statusCode(err), fmt.Errorf("failed to unpack: %w", err))
+		return
+	}
+	h.writeBuffer(w, contentTypeZip, output)
+}
+
+// decrypt writes the decrypted content zip of the package read from r to output
+func (h *handler) decrypt(r io.Reader, output io.Writer) error {
+	opts := []unpack.Option{
+		unpack.WithLogger(h.opts.Logger),
+		unpack.WithTempDir(h.opts.TempDir),
+		unpack.WithSpillThreshold(h.opts.SpillThreshold),
+		unpack.WithMemoryLimit(h.opts.MemoryLimit),
+		// An upload inflating far beyond the body size is a decompression bomb
+		unpack.WithLimits(unpack.Limits{MaxTotalSize: h.opts.MaxBodySize}),
+	}
+	_, err := unpack.DecryptReader(r, output, opts...)
+	return err //nolint:wrapcheck // wrapped by the caller
+}
+
+// inspect returns the metadata of the uploaded package without decrypting it
+func (h *handler) inspect(w http.ResponseWriter, r *http.Request) {
+	appInfo, err := h.readApplicationInfo(r.Body)
+	if err != nil {
+		h.writeError(w, statusCode(err), err)
+		return
+	}
+	data, err := appInfo.ToJSON()
+	if err != nil {
+		h.writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", contentTypeJSON)
+	_, _ = w.Write(append(data, '\n'))
+}
+
+// readApplicationInfo returns the metadata of the package read from r. The package is
+// buffered in a temporary file, as its Detection.xml is at the end of the zip.
+func (h *handler) readApplicationInfo(r io.Reader) (*metadata.ApplicationInfo, error) {
+	f, err := os.CreateTemp(h.opts.TempDir, "intunewin-inspect-*.intunewin")
+	if err != nil {
+		return nil, fmt.Errorf("failed to buffer upload: %w", err)
+	}
+	defer os.Remove(f.Name())
+	_, err = io.Copy(f, r)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read upload: %w", err)
+	}
+
+	appInfo, err := unpack.ReadApplicationInfo(f.Name(), unpack.WithLogger(h.opts.Logger))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read metadata: %w", err)
+	}
+	return appInfo, nil
+}
+
+// newBuffer returns a buffer for a response body
+func (h *handler) newBuffer(pattern string) *spill.Buffer {
+	return spill.NewLimited(h.opts.TempDir, h.opts.SpillThreshold, pattern, h.opts.MemoryLimit)
+}
+
+// writeBuffer sends the content of buf as the response body
+func (h *handler) writeBuffer(w http.ResponseWriter, contentType string, buf *spill.Buffer) {
+	size, err := buf.Size()
+	if err == nil {
+		_, err = buf.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		h.writeError(w, http.StatusInternalServerError, fmt.Errorf("failed to read response: %w", err))
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Length", strconv.FormatInt(size, 10))
+	if _, err := io.Copy(w, buf); err != nil {
+		h.opts.Logger.Warn("failed to send response", "error", err)
+	}
+}
+
+// writeError sends err as a JSON error response
+func (h *handler) writeError(w http.ResponseWriter, status int, err error) {
+	if status >= http.StatusInternalServerError {
+		h.opts.Logger.Error("request failed", "error", err)
+	}
+	w.Header().Set("Content-Type", contentTypeJSON)
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(errorResponse{Error: err.Error()})
+}
+
+// errBadRequest marks errors caused by a malformed request
+var errBadRequest = errors.New("bad request")
+
+// statusCode maps err to the status code of its response
+func statusCode(err error) int {
+	var maxBytesErr *http.MaxBytesError
+	switch {
+	case errors.As(err, &maxBytesErr):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, unpack.ErrNotIntunewin),
+		errors.Is(err, unpack.ErrMetadataMissing),
+		errors.Is(err, unpack.ErrContentsMissing),
+		errors.Is(err, unpack.ErrInvalidMetadata),
+		errors.Is(err, unpack.ErrLimitExceeded),
+		errors.Is(err, crypto.ErrHMACMismatch),
+		errors.Is(err, crypto.ErrInvalidPadding),
+		errors.Is(err, crypto.ErrInvalidLength):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, errBadRequest),
+		errors.Is(err, zip.ErrFormat),
+		errors.Is(err, tar.ErrHeader),
+		errors.Is(err, pack.ErrSetupFileNotFound),
+		errors.Is(err, pack.ErrSetupFileAmbiguous):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,165 @@
+package server
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestServer starts a server with the packaging endpoints
+func newTestServer(t *testing.T, opts ...Option) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(NewHandler(append([]Option{WithTempDir(t.TempDir())}, opts...)...))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// zipOf returns a zip archive of files
+func zipOf(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		require.NoError(t, err)
+		_, err = io.WriteString(w, content)
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+// post sends body to path and returns the status and response body
+func post(t *testing.T, server *httptest.Server, path, contentType string, body []byte) (int, []byte) {
+	t.Helper()
+	resp, err := http.Post(server.URL+path, contentType, bytes.NewReader(body))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return resp.StatusCode, data
+}
+
+// readZip returns the files of a zip archive
+func readZip(t *testing.T, data []byte) map[string]string {
+	t.Helper()
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	require.NoError(t, err)
+	files := map[string]string{}
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		rc, err := f.Open()
+		require.NoError(t, err)
+		content, err := io.ReadAll(rc)
+		rc.Close()
+		require.NoError(t, err)
+		files[f.Name] = string(content)
+	}
+	return files
+}
+
+func TestPackUnpackInspect(t *testing.T) {
+	server := newTestServer(t)
+	files := map[string]string{"setup.exe": "setup", "data/config.ini": "[app]"}
+
+	status, packed := post(t, server, "/v1/pack?name=MyApp&setupFile=setup.exe", "application/zip", zipOf(t, files))
+	require.Equal(t, http.StatusOK, status, string(packed))
+
+	status, content := post(t, server, "/v1/unpack", "application/octet-stream", packed)
+	require.Equal(t, http.StatusOK, status, string(content))
+	assert.Equal(t, files, readZip(t, content))
+
+	status, info := post(t, server, "/v1/inspect", "application/octet-stream", packed)
+	require.Equal(t, http.StatusOK, status, string(info))
+	var appInfo map[string]any
+	require.NoError(t, json.Unmarshal(info, &appInfo))
+	assert.Equal(t, "MyApp", appInfo["name"])
+	assert.Equal(t, "setup.exe", appInfo["setupFile"])
+}
+
+func TestPackMultipartFolder(t *testing.T) {
+	server := newTestServer(t)
+
+	files := map[string]string{"setup.msi": "msi", "sub/readme.txt": "readme"}
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	for name, content := range files {
+		w, err := mw.CreateFormFile("file", name)
+		require.NoError(t, err)
+		_, err = io.WriteString(w, content)
+		require.NoError(t, err)
+	}
+	require.NoError(t, mw.Close())
+
+	status, packed := post(t, server, "/v1/pack", mw.FormDataContentType(), body.Bytes())
+	require.Equal(t, http.StatusOK, status, string(packed))
+
+	status, info := post(t, server, "/v1/inspect", "application/octet-stream", packed)
+	require.Equal(t, http.StatusOK, status)
+	assert.Contains(t, string(info), `"setup.msi"`, "the setup file is detected")
+
+	status, content := post(t, server, "/v1/unpack", "application/octet-stream", packed)
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, files, readZip(t, content), "folders of the file names are kept")
+}
+
+func TestServerErrors(t *testing.T) {
+	server := newTestServer(t, WithMaxBodySize(1024))
+
+	tests := []struct {
+		name        string
+		path        string
+		contentType string
+		body        []byte
+		want        int
+	}{
+		{"archive without setup file", "/v1/pack", "application/zip", zipOf(t, map[string]string{"a.exe": "a"}), http.StatusBadRequest},
+		{"setup file not in archive", "/v1/pack?setupFile=b.exe", "application/zip", zipOf(t, map[string]string{"a.exe": "a"}), http.StatusBadRequest},
+		{"unsupported content type", "/v1/pack", "text/plain", []byte("a"), http.StatusUnsupportedMediaType},
+		{"not a package", "/v1/unpack", "application/octet-stream", []byte("not a package"), http.StatusUnprocessableEntity},
+		{"inspect not a package", "/v1/inspect", "application/octet-stream", []byte("not a package"), http.StatusUnprocessableEntity},
+		{"too large", "/v1/inspect", "application/octet-stream", make([]byte, 2048), http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, body := post(t, server, tt.path, tt.contentType, tt.body)
+			assert.Equal(t, tt.want, status, string(body))
+			var resp errorResponse
+			require.NoError(t, json.Unmarshal(body, &resp))
+			assert.NotEmpty(t, resp.Error)
+		})
+	}
+}
+
+func TestPackMultipartRejectsTraversal(t *testing.T) {
+	server := newTestServer(t)
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	w, err := mw.CreateFormFile("file", "../setup.exe")
+	require.NoError(t, err)
+	_, err = io.WriteString(w, "setup")
+	require.NoError(t, err)
+	require.NoError(t, mw.Close())
+
+	status, _ := post(t, server, "/v1/pack", mw.FormDataContentType(), body.Bytes())
+	assert.Equal(t, http.StatusBadRequest, status)
+}
+
+func TestHealthz(t *testing.T) {
+	server := newTestServer(t)
+	resp, err := http.Get(server.URL + "/healthz")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
@@ -0,0 +1,163 @@
+package signature
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Extension is appended to a package path to get the path of its detached signature
+const Extension = ".sig"
+
+var (
+	// ErrInvalidSignature is returned when a signature does not match the file or the key
+	ErrInvalidSignature = errors.New("invalid signature")
+	// ErrInvalidKey is returned when a key file does not hold an Ed25519 key
+	ErrInvalidKey = errors.New("invalid Ed25519 key")
+)
+
+// options selects Ed25519ph (RFC 8032), which signs the SHA-512 digest of the file so that
+// large packages are streamed instead of being held in memory
+var options = &ed25519.Options{Hash: crypto.SHA512}
+
+// Path returns the default path of the detached signature of the file at path
+func Path(path string) string {
+	return path + Extension
+}
+
+// ParsePrivateKey parses a PKCS #8 "PRIVATE KEY" PEM block holding an Ed25519 key,
+// as written by openssl genpkey -algorithm ed25519
+func ParsePrivateKey(data []byte) (ed25519.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "PRIVATE KEY" {
+		return nil, fmt.Errorf("%w: expected a PEM encoded PRIVATE KEY", ErrInvalidKey)
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidKey, err)
+	}
+	private, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("%w: the private key is a %T", ErrInvalidKey, key)
+	}
+	return private, nil
+}
+
+// ParsePublicKey parses a PKIX "PUBLIC KEY" PEM block holding an Ed25519 key. The public
+// key of a "PRIVATE KEY" block is accepted as well.
+func ParsePublicKey(data []byte) (ed25519.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block != nil && block.Type == "PRIVATE KEY" {
+		private, err := ParsePrivateKey(data)
+		if err != nil {
+			return nil, err
+		}
+		return private.Public().(ed25519.PublicKey), nil
+	}
+	if block == nil || block.Type != "PUBLIC KEY" {
+		return nil, fmt.Errorf("%w: expected a PEM encoded PUBLIC KEY", ErrInvalidKey)
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidKey, err)
+	}
+	public, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("%w: the public key is a %T", ErrInvalidKey, key)
+	}
+	return public, nil
+}
+
+// Sign returns the Ed25519ph signature of the data read from r
+func Sign(r io.Reader, key ed25519.PrivateKey) ([]byte, error) {
+	digest, err := sum(r)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := key.Sign(nil, digest, options)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign: %w", err)
+	}
+	return sig, nil
+}
+
+// Verify checks that sig is the Ed25519ph signature of the data read from r made with
+// the private key of key
+func Verify(r io.Reader, key ed25519.PublicKey, sig []byte) error {
+	digest, err := sum(r)
+	if err != nil {
+		return err
+	}
+	if err := ed25519.VerifyWithOptions(key, digest, sig, options); err != nil {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// Encode returns sig as a signature file: base64 followed by a newline
+func Encode(sig []byte) []byte {
+	return []byte(base64.StdEncoding.EncodeToString(sig) + "\n")
+}
+
+// Decode parses a signature file written by Encode
+func Decode(data []byte) ([]byte, error) {
+	sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil || len(sig) != ed25519.SignatureSize {
+		return nil, fmt.Errorf("%w: expected %d base64 encoded bytes", ErrInvalidSignature, ed25519.SignatureSize)
+	}
+	return sig, nil
+}
+
+// SignFile writes the signature of the file at path to sigPath
+func SignFile(path, sigPath string, key ed25519.PrivateKey) error {
+	f, err := os.Open(path) // #nosec G304 -- path is chosen by the caller
+	if err != nil {
+		return fmt.Errorf("failed to open file: %w", err)
+	}
+	defer f.Close()
+
+	sig, err := Sign(f, key)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(sigPath, Encode(sig), 0644); err != nil { // #nosec G306 -- signatures are public
+		return fmt.Errorf("failed to write signature: %w", err)
+	}
+	return nil
+}
+
+// VerifyFile checks the signature at sigPath of the file at path
+func VerifyFile(path, sigPath string, key ed25519.PublicKey) error {
+	data, err := os.ReadFile(sigPath) // #nosec G304 -- path is chosen by the caller
+	if err != nil {
+		return fmt.Errorf("failed to read signature: %w", err)
+	}
+	sig, err := Decode(data)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Open(path) // #nosec G304 -- path is chosen by the caller
+	if err != nil {
+		return fmt.Errorf("failed to open file: %w", err)
+	}
+	defer f.Close()
+	return Verify(f, key, sig)
+}
+
+// sum returns the SHA-512 digest of the data read from r
+func sum(r io.Reader) ([]byte, error) {
+	h := sha512.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return nil, fmt.Errorf("failed to read data: %w", err)
+	}
+	return h.Sum(nil), nil
+}
@@ -0,0 +1,106 @@
+package signature
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// generateKey returns a new key pair as PKCS #8 and PKIX PEM blocks
+func generateKey(t *testing.T) (privatePEM, publicPEM []byte) {
+	t.Helper()
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	privateDER, err := x509.MarshalPKCS8PrivateKey(private)
+	require.NoError(t, err)
+	publicDER, err := x509.MarshalPKIXPublicKey(public)
+	require.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateDER}),
+		pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER})
+}
+
+func TestSignVerify(t *testing.T) {
+	privatePEM, publicPEM := generateKey(t)
+	private, err := ParsePrivateKey(privatePEM)
+	require.NoError(t, err)
+	public, err := ParsePublicKey(publicPEM)
+	require.NoError(t, err)
+
+	data := []byte("intunewin package")
+	sig, err := Sign(bytes.NewReader(data), private)
+	require.NoError(t, err)
+	assert.Len(t, sig, ed25519.SignatureSize)
+
+	require.NoError(t, Verify(bytes.NewReader(data), public, sig))
+	assert.ErrorIs(t, Verify(bytes.NewReader([]byte("tampered package")), public, sig), ErrInvalidSignature)
+
+	_, otherPEM := generateKey(t)
+	other, err := ParsePublicKey(otherPEM)
+	require.NoError(t, err)
+	assert.ErrorIs(t, Verify(bytes.NewReader(data), other, sig), ErrInvalidSignature)
+}
+
+func TestParsePublicKeyFromPrivateKey(t *testing.T) {
+	privatePEM, publicPEM := generateKey(t)
+	fromPrivate, err := ParsePublicKey(privatePEM)
+	require.NoError(t, err)
+	public, err := ParsePublicKey(publicPEM)
+	require.NoError(t, err)
+	assert.Equal(t, public, fromPrivate)
+}
+
+func TestParseInvalidKey(t *testing.T) {
+	_, publicPEM := generateKey(t)
+	_, err := ParsePrivateKey(publicPEM)
+	require.ErrorIs(t, err, ErrInvalidKey)
+	_, err = ParsePublicKey([]byte("not a key"))
+	require.ErrorIs(t, err, ErrInvalidKey)
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(ecKey)
+	require.NoError(t, err)
+	_, err = ParsePrivateKey(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+	require.ErrorIs(t, err, ErrInvalidKey)
+}
+
+func TestDecode(t *testing.T) {
+	sig := bytes.Repeat([]byte{0x5a}, ed25519.SignatureSize)
+	decoded, err := Decode(Encode(sig))
+	require.NoError(t, err)
+	assert.Equal(t, sig, decoded)
+
+	_, err = Decode([]byte("not base64!"))
+	require.ErrorIs(t, err, ErrInvalidSignature)
+	_, err = Decode(Encode(sig[:10]))
+	require.ErrorIs(t, err, ErrInvalidSignature)
+}
+
+func TestSignFileVerifyFile(t *testing.T) {
+	privatePEM, publicPEM := generateKey(t)
+	private, err := ParsePrivateKey(privatePEM)
+	require.NoError(t, err)
+	public, err := ParsePublicKey(publicPEM)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.intunewin")
+	require.NoError(t, os.WriteFile(path, []byte("package"), 0600))
+
+	require.NoError(t, SignFile(path, Path(path), private))
+	assert.FileExists(t, filepath.Join(dir, "app.intunewin.sig"))
+	require.NoError(t, VerifyFile(path, Path(path), public))
+
+	require.NoError(t, os.WriteFile(path, []byte("tampered"), 0600))
+	assert.ErrorIs(t, VerifyFile(path, Path(path), public), ErrInvalidSignature)
+}
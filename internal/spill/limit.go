@@ -0,0 +1,79 @@
+package spill
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Limit caps the memory held by all Buffers sharing it. A Buffer that would take
+// the total beyond the limit moves its data to a temporary file instead, whatever
+// its own threshold. It is safe for concurrent use.
+type Limit struct {
+	max int64
+
+	mu   sync.Mutex
+	used int64
+}
+
+// NewLimit returns a Limit of max bytes
+func NewLimit(max int64) *Limit {
+	return &Limit{max: max}
+}
+
+// Used returns the number of bytes the Buffers sharing the limit hold in memory
+func (l *Limit) Used() int64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.used
+}
+
+// reserve takes n more bytes from the limit and reports whether they were available
+func (l *Limit) reserve(n int64) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.used+n > l.max {
+		return false
+	}
+	l.used += n
+	return true
+}
+
+// release returns n bytes to the limit
+func (l *Limit) release(n int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.used -= n
+}
+
+// sizeUnits are the suffixes accepted by ParseSize, longest first
+var sizeUnits = []struct {
+	suffix     string
+	multiplier int64
+}{
+	{"KIB", 1 << 10}, {"MIB", 1 << 20}, {"GIB", 1 << 30}, {"TIB", 1 << 40},
+	{"KB", 1 << 10}, {"MB", 1 << 20}, {"GB", 1 << 30}, {"TB", 1 << 40},
+	{"K", 1 << 10}, {"M", 1 << 20}, {"G", 1 << 30}, {"T", 1 << 40},
+	{"B", 1},
+}
+
+// ParseSize parses a size in bytes such as 1048576, 512MB or 1.5GiB. Units are
+// case-insensitive powers of 1024, so 512MB and 512MiB are the same size.
+func ParseSize(s string) (int64, error) {
+	number, multiplier := strings.ToUpper(strings.TrimSpace(s)), int64(1)
+	for _, unit := range sizeUnits {
+		if trimmed, ok := strings.CutSuffix(number, unit.suffix); ok {
+			number, multiplier = strings.TrimSpace(trimmed), unit.multiplier
+			break
+		}
+	}
+	if n, err := strconv.ParseInt(number, 10, 64); err == nil && n >= 0 && n <= (1<<63-1)/multiplier {
+		return n * multiplier, nil
+	}
+	f, err := strconv.ParseFloat(number, 64)
+	if err != nil || f < 0 || f*float64(multiplier) >= 1<<63 {
+		return 0, fmt.Errorf("invalid size %q: use a number of bytes with an optional unit such as KB, MB or GB", s)
+	}
+	return int64(f * float64(multiplier)), nil
+}
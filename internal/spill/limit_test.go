@@ -0,0 +1,61 @@
+package spill
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimitSpillsBuffersBeyondMaximum(t *testing.T) {
+	dir := t.TempDir()
+	limit := NewLimit(10)
+
+	first := NewLimited(dir, 100, "test-*", limit)
+	_, err := first.Write([]byte("012345"))
+	require.NoError(t, err)
+	assert.False(t, first.Spilled())
+	assert.Equal(t, int64(6), limit.Used())
+
+	second := NewLimited(dir, 100, "test-*", limit)
+	_, err = second.Write([]byte("0123"))
+	require.NoError(t, err)
+	assert.False(t, second.Spilled())
+	_, err = second.Write([]byte("4"))
+	require.NoError(t, err)
+	assert.True(t, second.Spilled(), "the limit is reached before the threshold")
+	assert.Equal(t, int64(6), limit.Used(), "spilling returns the memory")
+
+	require.NoError(t, second.Close())
+	require.NoError(t, first.Close())
+	assert.Zero(t, limit.Used())
+
+	third := NewLimited(dir, 100, "test-*", limit)
+	defer third.Close()
+	_, err = third.Write([]byte("0123456789"))
+	require.NoError(t, err)
+	assert.False(t, third.Spilled())
+}
+
+func TestParseSize(t *testing.T) {
+	for input, want := range map[string]int64{
+		"0":       0,
+		"1048576": 1 << 20,
+		"512MB":   512 << 20,
+		"512mb":   512 << 20,
+		"512 MiB": 512 << 20,
+		"1.5GiB":  3 << 29,
+		"64k":     64 << 10,
+		"2T":      2 << 40,
+		"100B":    100,
+	} {
+		got, err := ParseSize(input)
+		require.NoError(t, err, input)
+		assert.Equal(t, want, got, input)
+	}
+
+	for _, input := range []string{"", "MB", "-1", "12XB", "1e30GB"} {
+		_, err := ParseSize(input)
+		assert.Error(t, err, input)
+	}
+}
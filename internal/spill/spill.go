@@ -0,0 +1,189 @@
+package spill
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// errNegativeOffset is returned when seeking or reading before the start of a Buffer
+var errNegativeOffset = errors.New("negative offset")
+
+// Buffer holds data in memory until it grows beyond a threshold, then moves it to a
+// temporary file so large payloads are not limited by available memory.
+// It is an io.ReadWriteSeeker and io.ReaderAt; Close removes the temporary file.
+type Buffer struct {
+	dir       string
+	threshold int64
+	pattern   string
+	limit     *Limit
+
+	data []byte
+	pos  int64
+	file *os.File
+}
+
+// New returns an empty Buffer that spills to a temporary file in dir (os.TempDir when empty)
+// once it holds more than threshold bytes. A threshold of 0 or less always uses a file.
+// pattern names the temporary file as in os.CreateTemp.
+func New(dir string, threshold int64, pattern string) *Buffer {
+	return &Buffer{dir: dir, threshold: threshold, pattern: pattern}
+}
+
+// NewLimited is like New, but the buffer also spills once the Buffers sharing limit
+// would hold more than its maximum in memory. A nil limit is ignored.
+func NewLimited(dir string, threshold int64, pattern string, limit *Limit) *Buffer {
+	return &Buffer{dir: dir, threshold: threshold, pattern: pattern, limit: limit}
+}
+
+// Spilled reports whether the data has been moved to a temporary file
+func (b *Buffer) Spilled() bool {
+	return b.file != nil
+}
+
+// Size returns the number of bytes in the buffer
+func (b *Buffer) Size() (int64, error) {
+	if b.file == nil {
+		return int64(len(b.data)), nil
+	}
+	info, err := b.file.Stat()
+	if err != nil {
+		return 0, fmt.Errorf("failed to stat temporary file: %w", err)
+	}
+	return info.Size(), nil
+}
+
+// Write writes p at the current position, spilling to a temporary file when the
+// buffer would grow beyond the threshold
+func (b *Buffer) Write(p []byte) (int, error) {
+	end := b.pos + int64(len(p))
+	if b.file == nil && (end > b.threshold || !b.reserve(end-int64(len(b.data)))) {
+		if err := b.spill(); err != nil {
+			return 0, err
+		}
+	}
+	if b.file != nil {
+		return b.file.Write(p) //nolint:wrapcheck // os errors already name the file
+	}
+
+	if end > int64(len(b.data)) {
+		b.data = append(b.data, make([]byte, end-int64(len(b.data)))...)
+	}
+	copy(b.data[b.pos:], p)
+	b.pos = end
+	return len(p), nil
+}
+
+// Read reads from the current position
+func (b *Buffer) Read(p []byte) (int, error) {
+	if b.file != nil {
+		return b.file.Read(p) //nolint:wrapcheck // io.Reader errors such as io.EOF must not be wrapped
+	}
+	if b.pos >= int64(len(b.data)) {
+		return 0, io.EOF
+	}
+	n := copy(p, b.data[b.pos:])
+	b.pos += int64(n)
+	return n, nil
+}
+
+// ReadAt reads from off without changing the current position
+func (b *Buffer) ReadAt(p []byte, off int64) (int, error) {
+	if b.file != nil {
+		return b.file.ReadAt(p, off) //nolint:wrapcheck // io.ReaderAt errors such as io.EOF must not be wrapped
+	}
+	if off < 0 {
+		return 0, errNegativeOffset
+	}
+	if off >= int64(len(b.data)) {
+		return 0, io.EOF
+	}
+	n := copy(p, b.data[off:])
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// Seek sets the position for the next Read or Write
+func (b *Buffer) Seek(offset int64, whence int) (int64, error) {
+	if b.file != nil {
+		return b.file.Seek(offset, whence) //nolint:wrapcheck // os errors already name the file
+	}
+
+	var pos int64
+	switch whence {
+	case io.SeekStart:
+		pos = offset
+	case io.SeekCurrent:
+		pos = b.pos + offset
+	case io.SeekEnd:
+		pos = int64(len(b.data)) + offset
+	default:
+		return 0, fmt.Errorf("invalid whence %d", whence)
+	}
+	if pos < 0 {
+		return 0, errNegativeOffset
+	}
+	b.pos = pos
+	return pos, nil
+}
+
+// Close releases the memory and removes the temporary file, if any
+func (b *Buffer) Close() error {
+	b.release()
+	b.data = nil
+	b.pos = 0
+	if b.file == nil {
+		return nil
+	}
+	name := b.file.Name()
+	closeErr := b.file.Close()
+	b.file = nil
+	if err := os.Remove(name); err != nil {
+		return fmt.Errorf("failed to remove temporary file: %w", err)
+	}
+	if closeErr != nil {
+		return fmt.Errorf("failed to close temporary file: %w", closeErr)
+	}
+	return nil
+}
+
+// spill moves the buffered data to a new temporary file, keeping the current position
+func (b *Buffer) spill() error {
+	file, err := os.CreateTemp(b.dir, b.pattern)
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	if _, err := file.Write(b.data); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return fmt.Errorf("failed to write temporary file: %w", err)
+	}
+	if _, err := file.Seek(b.pos, io.SeekStart); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return fmt.Errorf("failed to write temporary file: %w", err)
+	}
+	b.file = file
+	b.release()
+	b.data = nil
+	return nil
+}
+
+// reserve takes n more bytes of memory from the limit, if any, and reports whether
+// they were available
+func (b *Buffer) reserve(n int64) bool {
+	if b.limit == nil || n <= 0 {
+		return true
+	}
+	return b.limit.reserve(n)
+}
+
+// release returns the memory held by the data to the limit, if any
+func (b *Buffer) release() {
+	if b.limit != nil {
+		b.limit.release(int64(len(b.data)))
+	}
+}
@@ -0,0 +1,92 @@
+package spill
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBufferInMemory(t *testing.T) {
+	dir := t.TempDir()
+	b := New(dir, 16, "test-*")
+	defer b.Close()
+
+	_, err := b.Write([]byte("hello"))
+	require.NoError(t, err)
+	assert.False(t, b.Spilled())
+
+	_, err = b.Seek(0, io.SeekStart)
+	require.NoError(t, err)
+	data, err := io.ReadAll(b)
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(data))
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestBufferSpills(t *testing.T) {
+	dir := t.TempDir()
+	b := New(dir, 8, "test-*")
+
+	_, err := b.Write([]byte("0123"))
+	require.NoError(t, err)
+	_, err = b.Write([]byte("456789"))
+	require.NoError(t, err)
+	assert.True(t, b.Spilled())
+
+	size, err := b.Size()
+	require.NoError(t, err)
+	assert.Equal(t, int64(10), size)
+
+	// Overwrite in place, as the HMAC placeholder is filled in after encryption
+	_, err = b.Seek(0, io.SeekStart)
+	require.NoError(t, err)
+	_, err = b.Write([]byte("ab"))
+	require.NoError(t, err)
+
+	data := make([]byte, 10)
+	_, err = b.ReadAt(data, 0)
+	require.NoError(t, err)
+	assert.Equal(t, "ab23456789", string(data))
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+
+	require.NoError(t, b.Close())
+	entries, err = os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, entries, "Close should remove the temporary file")
+}
+
+func TestBufferMatchesFile(t *testing.T) {
+	// The same operations must behave identically before and after spilling
+	payload := bytes.Repeat([]byte("intunewin"), 100)
+	for _, threshold := range []int64{0, 100, 1 << 20} {
+		b := New(t.TempDir(), threshold, "test-*")
+
+		_, err := io.Copy(b, bytes.NewReader(payload))
+		require.NoError(t, err)
+
+		pos, err := b.Seek(-9, io.SeekEnd)
+		require.NoError(t, err)
+		assert.Equal(t, int64(len(payload)-9), pos)
+
+		rest, err := io.ReadAll(b)
+		require.NoError(t, err)
+		assert.Equal(t, "intunewin", string(rest))
+
+		buf := make([]byte, 4)
+		n, err := b.ReadAt(buf, int64(len(payload)-2))
+		assert.Equal(t, 2, n)
+		assert.ErrorIs(t, err, io.EOF)
+
+		require.NoError(t, b.Close())
+	}
+}
@@ -0,0 +1,6 @@
+//go:build !js
+
+package spill
+
+// DefaultThreshold is the size above which a Buffer moves its data to a temporary file
+const DefaultThreshold int64 = 64 << 20
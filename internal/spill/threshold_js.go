@@ -0,0 +1,7 @@
+package spill
+
+import "math"
+
+// DefaultThreshold is the size above which a Buffer moves its data to a temporary file.
+// Browsers have no file system for temporary files, so data is kept in memory.
+const DefaultThreshold int64 = math.MaxInt64
@@ -0,0 +1,112 @@
+package unpack
+
+import (
+	"archive/zip"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/kenchan0130/intunewin/internal/pathutil"
+)
+
+// defaultFileMode and defaultDirMode are used for extracted entries with NoPreserve
+const (
+	defaultFileMode os.FileMode = 0644
+	defaultDirMode  os.FileMode = 0755
+)
+
+// Creators of zip entries whose external attributes hold a Unix mode
+const (
+	creatorUnix  = 3
+	creatorMacOS = 19
+)
+
+// msdosReadOnly is the MS-DOS read-only attribute in the external attributes of a zip entry
+const msdosReadOnly = 0x01
+
+// entryMode returns the mode recorded for the zip entry file. Entries without a Unix mode,
+// such as those written by Windows tools with MS-DOS attributes only, get the default modes,
+// read-only when the attribute is set, instead of the world-writable modes that archive/zip
+// derives from the attributes.
+func entryMode(file *zip.File) os.FileMode {
+	mode := file.Mode()
+	if creator := file.CreatorVersion >> 8; (creator == creatorUnix || creator == creatorMacOS) && mode.Perm() != 0 {
+		return mode
+	}
+
+	mode = defaultFileMode
+	if file.FileInfo().IsDir() {
+		mode = os.ModeDir | defaultDirMode
+	}
+	if file.ExternalAttrs&msdosReadOnly != 0 {
+		mode &^= 0222
+	}
+	return mode
+}
+
+// attributes are the permission bits and modification time of an extracted entry
+type attributes struct {
+	path     string
+	mode     os.FileMode
+	modified time.Time
+}
+
+// restorer applies the modes and modification times recorded in an archive to extracted
+// entries. Directories are restored after extraction so that creating their contents
+// does not change their modification time and a read-only mode does not block writes.
+type restorer struct {
+	preserve bool
+	dirs     []attributes
+}
+
+// newRestorer returns a restorer that does nothing with NoPreserve
+func newRestorer(o *Options) *restorer {
+	return &restorer{preserve: !o.NoPreserve}
+}
+
+// fileMode returns the mode to create a file recorded with mode
+func (r *restorer) fileMode(mode os.FileMode) os.FileMode {
+	if !r.preserve {
+		return defaultFileMode
+	}
+	return mode.Perm()
+}
+
+// file restores the attributes of the extracted file at path
+func (r *restorer) file(path string, mode os.FileMode, modified time.Time) error {
+	if !r.preserve {
+		return nil
+	}
+	return restore(attributes{path: path, mode: mode.Perm(), modified: modified})
+}
+
+// dir records the attributes of the extracted directory at path for finish
+func (r *restorer) dir(path string, mode os.FileMode, modified time.Time) {
+	if r.preserve {
+		r.dirs = append(r.dirs, attributes{path: path, mode: mode.Perm(), modified: modified})
+	}
+}
+
+// finish restores the attributes of the recorded directories, deepest first
+func (r *restorer) finish() error {
+	for i := len(r.dirs) - 1; i >= 0; i-- {
+		if err := restore(r.dirs[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// restore sets the mode and modification time of a.path
+func restore(a attributes) error {
+	if err := os.Chmod(pathutil.Long(a.path), a.mode); err != nil {
+		return fmt.Errorf("failed to set mode of %s: %w", a.path, err)
+	}
+	if a.modified.IsZero() {
+		return nil
+	}
+	if err := os.Chtimes(pathutil.Long(a.path), a.modified, a.modified); err != nil {
+		return fmt.Errorf("failed to set modification time of %s: %w", a.path, err)
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package unpack
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	fileTime = time.Date(2021, 3, 4, 5, 6, 8, 0, time.UTC)
+	dirTime  = time.Date(2020, 1, 2, 3, 4, 6, 0, time.UTC)
+)
+
+// attrsZip returns a zip whose directory entry comes after the file inside it
+func attrsZip(t *testing.T) []byte {
+	t.Helper()
+	content := new(bytes.Buffer)
+	zw := zip.NewWriter(content)
+
+	fileHeader := &zip.FileHeader{Name: "bin/setup.sh", Method: zip.Deflate, Modified: fileTime}
+	fileHeader.SetMode(0750)
+	w, err := zw.CreateHeader(fileHeader)
+	require.NoError(t, err)
+	_, err = w.Write([]byte("#!/bin/sh"))
+	require.NoError(t, err)
+
+	dirHeader := &zip.FileHeader{Name: "bin/", Modified: dirTime}
+	dirHeader.SetMode(os.ModeDir | 0700)
+	_, err = zw.CreateHeader(dirHeader)
+	require.NoError(t, err)
+
+	require.NoError(t, zw.Close())
+	return content.Bytes()
+}
+
+// attrsTar returns a tar with the same entries as attrsZip
+func attrsTar(t *testing.T) []byte {
+	t.Helper()
+	content := new(bytes.Buffer)
+	tw := tar.NewWriter(content)
+	data := []byte("#!/bin/sh")
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "bin/setup.sh", Typeflag: tar.TypeReg, Mode: 0750, Size: int64(len(data)), ModTime: fileTime}))
+	_, err := tw.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "bin/", Typeflag: tar.TypeDir, Mode: 0700, ModTime: dirTime}))
+	require.NoError(t, tw.Close())
+	return content.Bytes()
+}
+
+func assertAttributes(t *testing.T, path string, mode os.FileMode, modified time.Time) {
+	t.Helper()
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.True(t, info.ModTime().Equal(modified), "modification time of %s is %s", path, info.ModTime())
+	// Windows only records a read-only attribute
+	if runtime.GOOS != "windows" {
+		assert.Equal(t, mode, info.Mode().Perm(), "mode of %s", path)
+	}
+}
+
+func TestUnpackPreservesAttributes(t *testing.T) {
+	for name, content := range map[string][]byte{"zip": attrsZip(t), "tar": attrsTar(t)} {
+		t.Run(name, func(t *testing.T) {
+			outputDir := filepath.Join(t.TempDir(), "out")
+			require.NoError(t, Unpack(packContent(t, content), outputDir))
+
+			assertAttributes(t, filepath.Join(outputDir, "bin", "setup.sh"), 0750, fileTime)
+			assertAttributes(t, filepath.Join(outputDir, "bin"), 0700, dirTime)
+		})
+	}
+}
+
+func TestUnpackNoPreserve(t *testing.T) {
+	before := time.Now().Add(-time.Minute)
+	outputDir := filepath.Join(t.TempDir(), "out")
+	require.NoError(t, Unpack(packContent(t, attrsZip(t)), outputDir, WithNoPreserve()))
+
+	for _, path := range []string{filepath.Join(outputDir, "bin"), filepath.Join(outputDir, "bin", "setup.sh")} {
+		info, err := os.Stat(path)
+		require.NoError(t, err)
+		assert.True(t, info.ModTime().After(before), "modification time of %s is %s", path, info.ModTime())
+	}
+	if runtime.GOOS != "windows" {
+		info, err := os.Stat(filepath.Join(outputDir, "bin", "setup.sh"))
+		require.NoError(t, err)
+		assert.Zero(t, info.Mode().Perm()&0111, "executable bits should not be restored")
+	}
+}
+
+func TestUnpackMSDOSAttributes(t *testing.T) {
+	content := new(bytes.Buffer)
+	zw := zip.NewWriter(content)
+	// Entries as written by Windows tools, with MS-DOS attributes and no Unix mode
+	for _, header := range []*zip.FileHeader{
+		{Name: "bin/", ExternalAttrs: 0x10},
+		{Name: "bin/setup.exe", ExternalAttrs: 0x20},
+		{Name: "readme.txt", ExternalAttrs: 0x21},
+	} {
+		_, err := zw.CreateHeader(header)
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+
+	zr, err := zip.NewReader(bytes.NewReader(content.Bytes()), int64(content.Len()))
+	require.NoError(t, err)
+	assert.Equal(t, os.ModeDir|0755, entryMode(zr.File[0]))
+	assert.Equal(t, os.FileMode(0644), entryMode(zr.File[1]))
+	assert.Equal(t, os.FileMode(0444), entryMode(zr.File[2]))
+
+	outputDir := filepath.Join(t.TempDir(), "out")
+	require.NoError(t, Unpack(packContent(t, content.Bytes()), outputDir))
+	if runtime.GOOS != "windows" {
+		info, err := os.Stat(filepath.Join(outputDir, "bin", "setup.exe"))
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0644), info.Mode().Perm())
+	}
+	readme := filepath.Join(outputDir, "readme.txt")
+	info, err := os.Stat(readme)
+	require.NoError(t, err)
+	assert.Zero(t, info.Mode().Perm()&0200, "read-only attribute should be restored")
+	require.NoError(t, os.Chmod(readme, 0600))
+}
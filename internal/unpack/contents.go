@@ -0,0 +1,71 @@
+package unpack
+
+import (
+	"archive/zip"
+	"fmt"
+	"os"
+
+	"github.com/kenchan0130/intunewin/internal/crypto"
+	"github.com/kenchan0130/intunewin/internal/pathutil"
+	"github.com/kenchan0130/intunewin/internal/spill"
+)
+
+// OpenContents returns the raw encrypted contents of an intunewin file, the file that is
+// uploaded to Intune, without decrypting it. The caller closes the buffer.
+func OpenContents(inputFile string, opts ...Option) (*spill.Buffer, error) {
+	o := newOptions(opts)
+
+	packageReader, err := zip.OpenReader(pathutil.Long(inputFile))
+	if err != nil {
+		return nil, openError(err)
+	}
+	defer packageReader.Close()
+
+	for _, file := range packageReader.File {
+		name, err := o.entryName(file.Name)
+		if err != nil {
+			return nil, err
+		}
+		if name == contentsPath {
+			return readEncryptedContents(file, o)
+		}
+	}
+	return nil, ErrContentsMissing
+}
+
+// UnpackContents extracts a raw encrypted contents file to outputFolder. The file is the
+// IntunePackage.intunewin entry of a package, as uploaded to Intune, and is decrypted with
+// the given keys, such as the fileEncryptionInfo of the Graph content file or a saved
+// Detection.xml, instead of the keys in the package.
+func UnpackContents(inputFile, outputFolder string, encryptionKey, macKey []byte, opts ...Option) error {
+	o := newOptions(opts)
+	if err := o.checkOnly(); err != nil {
+		return err
+	}
+
+	if err := crypto.ValidateDecryptionKeys(encryptionKey, macKey); err != nil {
+		return err //nolint:wrapcheck // crypto errors already describe the failure
+	}
+
+	f, err := os.Open(pathutil.Long(inputFile)) // #nosec G304 -- path is chosen by the caller
+	if err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("%w: %s", ErrInputNotFound, inputFile)
+		}
+		return fmt.Errorf("failed to open input file: %w", err)
+	}
+	defer f.Close()
+
+	content := o.newBuffer("intunewin-content-*")
+	defer content.Close()
+	if err := crypto.Decrypt(f, content, encryptionKey, macKey); err != nil {
+		return fmt.Errorf("failed to decrypt contents: %w", err)
+	}
+	contentSize, err := content.Size()
+	if err != nil {
+		return fmt.Errorf("failed to read decrypted content: %w", err)
+	}
+	o.Logger.Debug("decrypted contents", "size", contentSize)
+
+	return extractContent(content, contentSize, outputFolder, o)
+}
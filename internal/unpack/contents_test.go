@@ -0,0 +1,92 @@
+package unpack
+
+import (
+	"archive/zip"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kenchan0130/intunewin/internal/crypto"
+	"github.com/kenchan0130/intunewin/internal/pack"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// extractContents packs sourceDir and returns the path of its raw encrypted contents
+// together with the keys recorded in Detection.xml
+func extractContents(t *testing.T, sourceDir string) (string, *crypto.EncryptionInfo) {
+	t.Helper()
+	tempDir := t.TempDir()
+	packedFile := filepath.Join(tempDir, "test.intunewin")
+	require.NoError(t, pack.Pack(sourceDir, packedFile))
+
+	appInfo, err := ReadApplicationInfo(packedFile)
+	require.NoError(t, err)
+	encInfo, err := appInfo.EncryptionInfo.ToEncryptionInfo()
+	require.NoError(t, err)
+
+	reader, err := zip.OpenReader(packedFile)
+	require.NoError(t, err)
+	defer reader.Close()
+	entry, err := reader.Open(contentsPath)
+	require.NoError(t, err)
+	defer entry.Close()
+	data, err := io.ReadAll(entry)
+	require.NoError(t, err)
+
+	contentsFile := filepath.Join(tempDir, "IntunePackage.intunewin")
+	require.NoError(t, os.WriteFile(contentsFile, data, 0600))
+	return contentsFile, encInfo
+}
+
+func TestUnpackContents(t *testing.T) {
+	sourceDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(sourceDir, "setup.exe"), []byte("setup"), 0600))
+	contentsFile, encInfo := extractContents(t, sourceDir)
+
+	outputDir := filepath.Join(t.TempDir(), "out")
+	require.NoError(t, UnpackContents(contentsFile, outputDir, encInfo.EncryptionKey, encInfo.MacKey))
+	content, err := os.ReadFile(filepath.Join(outputDir, "setup.exe"))
+	require.NoError(t, err)
+	assert.Equal(t, "setup", string(content))
+}
+
+func TestUnpackContentsErrors(t *testing.T) {
+	sourceDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(sourceDir, "setup.exe"), []byte("setup"), 0600))
+	contentsFile, encInfo := extractContents(t, sourceDir)
+	outputDir := filepath.Join(t.TempDir(), "out")
+
+	t.Run("wrong MAC key", func(t *testing.T) {
+		err := UnpackContents(contentsFile, outputDir, encInfo.EncryptionKey, make([]byte, 32))
+		assert.ErrorIs(t, err, crypto.ErrHMACMismatch)
+	})
+
+	t.Run("invalid key size", func(t *testing.T) {
+		err := UnpackContents(contentsFile, outputDir, encInfo.EncryptionKey[:16], encInfo.MacKey)
+		assert.ErrorIs(t, err, crypto.ErrInvalidKeys)
+	})
+
+	t.Run("missing input", func(t *testing.T) {
+		err := UnpackContents(filepath.Join(t.TempDir(), "missing"), outputDir, encInfo.EncryptionKey, encInfo.MacKey)
+		assert.ErrorIs(t, err, ErrInputNotFound)
+	})
+}
+
+func TestOpenContents(t *testing.T) {
+	sourceDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(sourceDir, "setup.exe"), []byte("setup"), 0600))
+	packedFile := filepath.Join(t.TempDir(), "test.intunewin")
+	require.NoError(t, pack.Pack(sourceDir, packedFile))
+
+	contents, err := OpenContents(packedFile)
+	require.NoError(t, err)
+	defer contents.Close()
+	size, err := contents.Size()
+	require.NoError(t, err)
+	assert.Positive(t, size)
+
+	_, err = OpenContents(filepath.Join(sourceDir, "setup.exe"))
+	assert.ErrorIs(t, err, ErrNotIntunewin)
+}
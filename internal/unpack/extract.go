@@ -0,0 +1,275 @@
+package unpack
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/kenchan0130/intunewin/internal/pathutil"
+)
+
+// extractZip extracts the zip archive in r to outputFolder
+func extractZip(r io.ReaderAt, size int64, outputFolder string, o *Options) error {
+	zipContentReader, err := zip.NewReader(r, size)
+	if err != nil {
+		return fmt.Errorf("failed to read zip: %w", err)
+	}
+	if err := o.decodeNames(zipContentReader); err != nil {
+		return err
+	}
+	if err := o.checkZip(zipContentReader.File); err != nil {
+		return err
+	}
+
+	restorer := newRestorer(o)
+	only := &selection{o: o}
+	for _, file := range zipContentReader.File {
+		destPath, err := safeJoin(outputFolder, file.Name)
+		if err != nil {
+			return err
+		}
+		if !only.include(file.Name) {
+			continue
+		}
+
+		o.Logger.Debug("extracting file", "path", file.Name, "size", file.UncompressedSize64)
+
+		if file.FileInfo().IsDir() {
+			created, err := makeDir(destPath, o)
+			if err != nil {
+				return fmt.Errorf("failed to create directory %s: %w", file.Name, err)
+			}
+			if created {
+				restorer.dir(destPath, entryMode(file), file.Modified)
+			}
+			continue
+		}
+
+		rc, err := file.Open()
+		if err != nil {
+			return fmt.Errorf("failed to open file %s: %w", file.Name, err)
+		}
+
+		if entryMode(file)&os.ModeSymlink != 0 {
+			target, err := readSymlinkTarget(rc)
+			rc.Close()
+			if err != nil {
+				return fmt.Errorf("failed to extract %s: %w", file.Name, err)
+			}
+			if _, err := writeSymlink(outputFolder, destPath, file.Name, target, o); err != nil {
+				return err
+			}
+			continue
+		}
+
+		// UncompressedSize64 is within int64 range for valid zip files
+		written, err := writeFile(destPath, rc, restorer.fileMode(entryMode(file)), int64(file.UncompressedSize64), o) // #nosec G115
+		rc.Close()
+		if err != nil {
+			return fmt.Errorf("failed to write file %s: %w", file.Name, err)
+		}
+		if !written {
+			continue
+		}
+		if err := restorer.file(destPath, entryMode(file), file.Modified); err != nil {
+			return err
+		}
+	}
+
+	if err := restorer.finish(); err != nil {
+		return err
+	}
+	return only.check()
+}
+
+// extractTar extracts the tar archive in r to outputFolder.
+// Regular files, directories and, with AllowSymlinks, symbolic links are extracted; hard
+// links and devices are skipped.
+func extractTar(r io.Reader, outputFolder string, o *Options) error {
+	tarReader := tar.NewReader(r)
+	restorer := newRestorer(o)
+	only := &selection{o: o}
+	usage := &usage{limits: o.Limits}
+	for {
+		header, err := tarReader.Next()
+		if errors.Is(err, io.EOF) {
+			if err := restorer.finish(); err != nil {
+				return err
+			}
+			return only.check()
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read tar: %w", err)
+		}
+
+		destPath, err := safeJoin(outputFolder, header.Name)
+		if err != nil {
+			return err
+		}
+		if !only.include(header.Name) {
+			continue
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			o.Logger.Debug("extracting file", "path", header.Name, "size", 0)
+			if err := usage.add(header.Name, 0); err != nil {
+				return err
+			}
+			created, err := makeDir(destPath, o)
+			if err != nil {
+				return fmt.Errorf("failed to create directory %s: %w", header.Name, err)
+			}
+			if created {
+				restorer.dir(destPath, header.FileInfo().Mode(), header.ModTime)
+			}
+		case tar.TypeReg:
+			o.Logger.Debug("extracting file", "path", header.Name, "size", header.Size)
+			if err := usage.add(header.Name, header.Size); err != nil {
+				return err
+			}
+			mode := header.FileInfo().Mode()
+			written, err := writeFile(destPath, tarReader, restorer.fileMode(mode), header.Size, o)
+			if err != nil {
+				return fmt.Errorf("failed to write file %s: %w", header.Name, err)
+			}
+			if !written {
+				continue
+			}
+			if err := restorer.file(destPath, mode, header.ModTime); err != nil {
+				return err
+			}
+		case tar.TypeSymlink:
+			o.Logger.Debug("extracting symbolic link", "path", header.Name, "target", header.Linkname)
+			if err := usage.add(header.Name, 0); err != nil {
+				return err
+			}
+			if _, err := writeSymlink(outputFolder, destPath, header.Name, header.Linkname, o); err != nil {
+				return err
+			}
+		default:
+			o.Logger.Warn("skipping unsupported tar entry", "path", header.Name, "type", string(header.Typeflag))
+		}
+	}
+}
+
+// ErrPathTraversal is returned when an archive entry would be extracted outside the output folder
+var ErrPathTraversal = errors.New("path escapes the output folder")
+
+// PathTraversalError reports an archive entry whose path escapes the output folder
+type PathTraversalError struct {
+	// Name is the entry name as stored in the archive
+	Name string
+	// Err describes why the name was rejected
+	Err error
+}
+
+// Error implements error
+func (e *PathTraversalError) Error() string {
+	return fmt.Sprintf("invalid file path: %s", e.Name)
+}
+
+// Unwrap returns the reason the name was rejected
+func (e *PathTraversalError) Unwrap() error {
+	return e.Err
+}
+
+// Is makes errors.Is(err, ErrPathTraversal) match PathTraversalError
+func (e *PathTraversalError) Is(target error) bool {
+	return target == ErrPathTraversal
+}
+
+// safeJoin joins name to outputFolder and rejects names that escape it or cannot be
+// created safely on this platform. Names with a ".." component are rejected even when they
+// stay inside, as no archiver writes them.
+func safeJoin(outputFolder, name string) (string, error) {
+	for elem := range strings.SplitSeq(strings.ReplaceAll(name, `\`, "/"), "/") {
+		if elem == ".." {
+			return "", &PathTraversalError{Name: name, Err: fmt.Errorf("%w: .. component in %s", pathutil.ErrUnsafePath, name)}
+		}
+	}
+	destPath, err := pathutil.Join(outputFolder, name)
+	if err != nil {
+		return "", &PathTraversalError{Name: name, Err: err}
+	}
+	return destPath, nil
+}
+
+// ErrFileExists is returned with OverwriteFail when a file to extract already exists
+var ErrFileExists = errors.New("file already exists")
+
+// makeDir creates the directory at path and its parents. It reports false when the
+// directory already existed and should be left as it is with OverwriteSkip.
+func makeDir(path string, o *Options) (bool, error) {
+	existed := false
+	if info, err := os.Stat(pathutil.Long(path)); err == nil && info.IsDir() {
+		existed = true
+	}
+	if err := os.MkdirAll(pathutil.Long(path), defaultDirMode); err != nil {
+		return false, err //nolint:wrapcheck // wrapped by the caller
+	}
+	return !existed || o.Overwrite != OverwriteSkip, nil
+}
+
+// writeFile writes at most size bytes from r to a file at path. An existing file is
+// replaced, kept or reported according to o.Overwrite; writeFile reports false when
+// the file was kept.
+func writeFile(path string, r io.Reader, mode os.FileMode, size int64, o *Options) (bool, error) {
+	// Create parent directories
+	if err := os.MkdirAll(pathutil.Long(filepath.Dir(path)), defaultDirMode); err != nil {
+		return false, fmt.Errorf("failed to create parent directory: %w", err)
+	}
+
+	destFile, err := createFile(path, mode, o.Overwrite)
+	if errors.Is(err, ErrFileExists) && o.Overwrite == OverwriteSkip {
+		o.Logger.Info("skipping existing file", "path", path)
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	// Decompression bomb protection: limit read size to the declared size
+	if _, err := io.Copy(destFile, io.LimitReader(r, size+1)); err != nil { // #nosec G110
+		destFile.Close()
+		return false, fmt.Errorf("failed to write file: %w", err)
+	}
+	if err := destFile.Close(); err != nil {
+		return false, fmt.Errorf("failed to close file: %w", err)
+	}
+	return true, nil
+}
+
+// createFile opens the file at path for writing according to policy. ErrFileExists is
+// returned for an existing file unless policy is OverwriteForce.
+func createFile(path string, mode os.FileMode, policy OverwritePolicy) (*os.File, error) {
+	flag := os.O_WRONLY | os.O_CREATE
+	switch policy {
+	case OverwriteForce:
+		flag |= os.O_TRUNC
+	case OverwriteSkip, OverwriteFail:
+		flag |= os.O_EXCL
+	default:
+		return nil, fmt.Errorf("invalid overwrite policy %q", policy)
+	}
+
+	f, err := os.OpenFile(pathutil.Long(path), flag, mode) // #nosec G304 -- path is checked by safeJoin
+	if policy == OverwriteForce && os.IsPermission(err) {
+		// A read-only file left by an earlier extraction is replaced as well
+		if chmodErr := os.Chmod(pathutil.Long(path), 0600); chmodErr == nil {
+			f, err = os.OpenFile(pathutil.Long(path), flag, mode) // #nosec G304 -- path is checked by safeJoin
+		}
+	}
+	if os.IsExist(err) {
+		return nil, fmt.Errorf("%w: %s", ErrFileExists, path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create file: %w", err)
+	}
+	return f, nil
+}
@@ -0,0 +1,123 @@
+package unpack
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Format is the type of the decrypted content of an intunewin package
+type Format string
+
+const (
+	// FormatZip is a zip archive, the format created by the Microsoft tool and by pack
+	FormatZip Format = "zip"
+	// FormatTar is an uncompressed tar archive
+	FormatTar Format = "tar"
+	// FormatTarGzip is a gzip compressed tar archive
+	FormatTarGzip Format = "tar.gz"
+	// FormatGzip is gzip compressed data other than a tar archive
+	FormatGzip Format = "gzip"
+	// FormatMSI is an OLE compound file such as an MSI or MSP installer
+	FormatMSI Format = "msi"
+	// FormatCab is a Microsoft cabinet archive
+	FormatCab Format = "cab"
+	// FormatSevenZip is a 7-Zip archive
+	FormatSevenZip Format = "7z"
+	// FormatPE is a Windows executable
+	FormatPE Format = "exe"
+	// FormatUnknown is any other content
+	FormatUnknown Format = "unknown"
+)
+
+// rawContentName is the base name used when content that is not an archive is written out
+const rawContentName = "IntunePackage"
+
+// tarMagicOffset is the offset of the "ustar" magic in a tar header
+const tarMagicOffset = 257
+
+var magics = []struct {
+	format Format
+	magic  []byte
+}{
+	{FormatZip, []byte("PK\x03\x04")},
+	// An empty zip archive only has the end of central directory record
+	{FormatZip, []byte("PK\x05\x06")},
+	{FormatGzip, []byte{0x1F, 0x8B}},
+	{FormatMSI, []byte{0xD0, 0xCF, 0x11, 0xE0, 0xA1, 0xB1, 0x1A, 0xE1}},
+	{FormatCab, []byte("MSCF")},
+	{FormatSevenZip, []byte{'7', 'z', 0xBC, 0xAF, 0x27, 0x1C}},
+	{FormatPE, []byte("MZ")},
+}
+
+// Extension returns the file extension used when content of this format is written out
+func (f Format) Extension() string {
+	switch f {
+	case FormatUnknown:
+		return ".bin"
+	case FormatGzip:
+		return ".gz"
+	default:
+		return "." + string(f)
+	}
+}
+
+// DetectFormat identifies the content format from its leading bytes
+func DetectFormat(header []byte) Format {
+	for _, m := range magics {
+		if bytes.HasPrefix(header, m.magic) {
+			return m.format
+		}
+	}
+	if isTarHeader(header) {
+		return FormatTar
+	}
+	return FormatUnknown
+}
+
+// isTarHeader reports whether header starts with a POSIX or GNU tar header
+func isTarHeader(header []byte) bool {
+	return len(header) >= tarMagicOffset+5 && bytes.Equal(header[tarMagicOffset:tarMagicOffset+5], []byte("ustar"))
+}
+
+// detectFormat reads the start of r to identify the content format.
+// Gzip content is decompressed to tell tar.gz archives apart from other gzip data.
+func detectFormat(r io.ReaderAt) (Format, error) {
+	header, err := readHeader(io.NewSectionReader(r, 0, 512))
+	if err != nil {
+		return "", err
+	}
+
+	format := DetectFormat(header)
+	if format != FormatGzip {
+		return format, nil
+	}
+
+	gz, err := gzip.NewReader(io.NewSectionReader(r, 0, 1<<63-1))
+	if err != nil {
+		// Not valid gzip after all; keep the content as it is
+		return FormatUnknown, nil
+	}
+	defer gz.Close()
+
+	inner, err := readHeader(io.LimitReader(gz, 512))
+	if err != nil {
+		return FormatGzip, nil
+	}
+	if isTarHeader(inner) {
+		return FormatTarGzip, nil
+	}
+	return FormatGzip, nil
+}
+
+// readHeader reads up to 512 bytes from r
+func readHeader(r io.Reader) ([]byte, error) {
+	header := make([]byte, 512)
+	n, err := io.ReadFull(r, header)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to read content header: %w", err)
+	}
+	return header[:n], nil
+}
@@ -0,0 +1,96 @@
+package unpack
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kenchan0130/intunewin/internal/pack"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetectFormat(t *testing.T) {
+	tarHeader := make([]byte, 512)
+	copy(tarHeader[tarMagicOffset:], "ustar\x0000")
+
+	tests := []struct {
+		name   string
+		header []byte
+		want   Format
+	}{
+		{"Zip", []byte("PK\x03\x04rest"), FormatZip},
+		{"Empty zip", []byte("PK\x05\x06rest"), FormatZip},
+		{"MSI", []byte{0xD0, 0xCF, 0x11, 0xE0, 0xA1, 0xB1, 0x1A, 0xE1, 0x00}, FormatMSI},
+		{"Cabinet", []byte("MSCF\x00\x00"), FormatCab},
+		{"7-Zip", []byte{'7', 'z', 0xBC, 0xAF, 0x27, 0x1C, 0x00}, FormatSevenZip},
+		{"Executable", []byte("MZ\x90\x00"), FormatPE},
+		{"Gzip", []byte{0x1F, 0x8B, 0x08}, FormatGzip},
+		{"Tar", tarHeader, FormatTar},
+		{"Unknown", []byte("hello"), FormatUnknown},
+		{"Empty", nil, FormatUnknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, DetectFormat(tt.header))
+		})
+	}
+}
+
+func TestUnpackTarGzipContent(t *testing.T) {
+	content := new(bytes.Buffer)
+	gz := gzip.NewWriter(content)
+	tw := tar.NewWriter(gz)
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "bin/", Typeflag: tar.TypeDir, Mode: 0755}))
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "bin/setup.exe", Typeflag: tar.TypeReg, Mode: 0644, Size: 5}))
+	_, err := tw.Write([]byte("setup"))
+	require.NoError(t, err)
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+
+	outputDir := filepath.Join(t.TempDir(), "out")
+	require.NoError(t, Unpack(packContent(t, content.Bytes()), outputDir))
+
+	data, err := os.ReadFile(filepath.Join(outputDir, "bin", "setup.exe"))
+	require.NoError(t, err)
+	assert.Equal(t, "setup", string(data))
+}
+
+func TestUnpackRawContent(t *testing.T) {
+	content := append([]byte{0xD0, 0xCF, 0x11, 0xE0, 0xA1, 0xB1, 0x1A, 0xE1}, []byte("installer")...)
+
+	outputDir := filepath.Join(t.TempDir(), "out")
+	require.NoError(t, Unpack(packContent(t, content), outputDir))
+
+	data, err := os.ReadFile(filepath.Join(outputDir, "IntunePackage.msi"))
+	require.NoError(t, err)
+	assert.Equal(t, content, data)
+}
+
+func TestUnpackReaderToZipRejectsOtherFormats(t *testing.T) {
+	packed, err := os.ReadFile(packContent(t, []byte("MSCF cabinet")))
+	require.NoError(t, err)
+
+	_, err = UnpackReaderToZip(bytes.NewReader(packed))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "detected cab")
+}
+
+// packContent creates an intunewin file whose encrypted payload is content
+func packContent(t *testing.T, content []byte) string {
+	t.Helper()
+
+	reader, err := pack.PackReaderFromZip(bytes.NewReader(content), "app", "setup.exe", pack.WithSkipSetupFileCheck())
+	require.NoError(t, err)
+	packed, err := io.ReadAll(reader)
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "app.intunewin")
+	require.NoError(t, os.WriteFile(path, packed, 0600))
+	return path
+}
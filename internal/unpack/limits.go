@@ -0,0 +1,80 @@
+package unpack
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// ErrLimitExceeded is returned when the content of a package exceeds one of its Limits
+var ErrLimitExceeded = errors.New("unpack limit exceeded")
+
+// Limits caps the resources unpacking an untrusted package may use. A zero field is not limited.
+type Limits struct {
+	// MaxTotalSize is the largest total size of the extracted files in bytes, and the most the
+	// encrypted contents may inflate beyond their compressed size
+	MaxTotalSize int64
+	// MaxFileSize is the largest size of a single extracted file in bytes
+	MaxFileSize int64
+	// MaxEntries is the largest number of files and folders extracted
+	MaxEntries int
+}
+
+// checkContents returns ErrLimitExceeded, before the encrypted contents entry is read, when
+// it inflates to more than MaxTotalSize bytes beyond its compressed size. Encrypted data does
+// not compress, so only a crafted entry does that, and reading it could fill the disk.
+func (o *Options) checkContents(contents *zip.File) error {
+	limit := o.Limits.MaxTotalSize
+	if limit <= 0 || contents.UncompressedSize64 <= contents.CompressedSize64 {
+		return nil
+	}
+	if contents.UncompressedSize64-contents.CompressedSize64 > uint64(limit) {
+		return fmt.Errorf("%w: encrypted contents of %d bytes inflate to %d bytes",
+			ErrLimitExceeded, contents.CompressedSize64, contents.UncompressedSize64)
+	}
+	return nil
+}
+
+// checkZip checks the entries of a zip content selected for extraction against o.Limits
+// before any of them is written. The zip reader fails on entries larger than their
+// declared size, so the declared sizes can be trusted.
+func (o *Options) checkZip(files []*zip.File) error {
+	usage := &usage{limits: o.Limits}
+	for _, file := range files {
+		if !o.selected(file.Name) {
+			continue
+		}
+		size := int64(math.MaxInt64)
+		if file.UncompressedSize64 <= math.MaxInt64 {
+			size = int64(file.UncompressedSize64)
+		}
+		if err := usage.add(file.Name, size); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// usage counts the entries and bytes extracted against limits
+type usage struct {
+	limits  Limits
+	entries int
+	size    int64
+}
+
+// add counts the entry name of size bytes and returns ErrLimitExceeded when it passes a limit
+func (u *usage) add(name string, size int64) error {
+	u.entries++
+	if u.limits.MaxEntries > 0 && u.entries > u.limits.MaxEntries {
+		return fmt.Errorf("%w: more than %d entries", ErrLimitExceeded, u.limits.MaxEntries)
+	}
+	if u.limits.MaxFileSize > 0 && size > u.limits.MaxFileSize {
+		return fmt.Errorf("%w: %s is larger than %d bytes", ErrLimitExceeded, name, u.limits.MaxFileSize)
+	}
+	if u.limits.MaxTotalSize > 0 && size > u.limits.MaxTotalSize-u.size {
+		return fmt.Errorf("%w: content is larger than %d bytes", ErrLimitExceeded, u.limits.MaxTotalSize)
+	}
+	u.size += size
+	return nil
+}
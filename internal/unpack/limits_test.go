@@ -0,0 +1,114 @@
+package unpack
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kenchan0130/intunewin/internal/pack"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnpackLimits(t *testing.T) {
+	sourceDir := t.TempDir()
+	for name, size := range map[string]int{"setup.exe": 10, "a.dat": 20, "b.dat": 30} {
+		require.NoError(t, os.WriteFile(filepath.Join(sourceDir, name), []byte(strings.Repeat("x", size)), 0600))
+	}
+	packed := filepath.Join(t.TempDir(), "app.intunewin")
+	require.NoError(t, pack.Pack(sourceDir, packed))
+
+	tests := []struct {
+		name    string
+		limits  Limits
+		only    []string
+		wantErr bool
+	}{
+		{name: "within limits", limits: Limits{MaxTotalSize: 60, MaxFileSize: 30, MaxEntries: 3}},
+		{name: "too many entries", limits: Limits{MaxEntries: 2}, wantErr: true},
+		{name: "file too large", limits: Limits{MaxFileSize: 29}, wantErr: true},
+		{name: "total too large", limits: Limits{MaxTotalSize: 59}, wantErr: true},
+		{name: "unselected entries are not counted", limits: Limits{MaxTotalSize: 10, MaxEntries: 1}, only: []string{"setup.exe"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outputDir := filepath.Join(t.TempDir(), "out")
+			opts := []Option{WithLimits(tt.limits)}
+			if tt.only != nil {
+				opts = append(opts, WithOnly(tt.only...))
+			}
+			err := Unpack(packed, outputDir, opts...)
+			if !tt.wantErr {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorIs(t, err, ErrLimitExceeded)
+			// Zip content is checked before any file is written
+			entries, err := os.ReadDir(outputDir)
+			require.NoError(t, err)
+			assert.Empty(t, entries)
+		})
+	}
+}
+
+func TestUnpackLimitsTar(t *testing.T) {
+	var tarBuf bytes.Buffer
+	tw := tar.NewWriter(&tarBuf)
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "Files/", Typeflag: tar.TypeDir, Mode: 0755}))
+	for _, name := range []string{"setup.exe", "Files/a.dat"} {
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Typeflag: tar.TypeReg, Mode: 0644, Size: 5}))
+		_, err := tw.Write([]byte("12345"))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	packed := packContent(t, tarBuf.Bytes())
+
+	require.NoError(t, Unpack(packed, filepath.Join(t.TempDir(), "out"), WithLimits(Limits{MaxTotalSize: 10, MaxEntries: 3})))
+	assert.ErrorIs(t, Unpack(packed, filepath.Join(t.TempDir(), "out"), WithLimits(Limits{MaxEntries: 2})), ErrLimitExceeded)
+	assert.ErrorIs(t, Unpack(packed, filepath.Join(t.TempDir(), "out"), WithLimits(Limits{MaxFileSize: 4})), ErrLimitExceeded)
+	assert.ErrorIs(t, Unpack(packed, filepath.Join(t.TempDir(), "out"), WithLimits(Limits{MaxTotalSize: 9})), ErrLimitExceeded)
+}
+
+func TestCheckContents(t *testing.T) {
+	o := newOptions([]Option{WithLimits(Limits{MaxTotalSize: 100})})
+	contents := &zip.File{FileHeader: zip.FileHeader{CompressedSize64: 1000, UncompressedSize64: 1010}}
+	assert.NoError(t, o.checkContents(contents))
+	contents.UncompressedSize64 = 1101
+	assert.ErrorIs(t, o.checkContents(contents), ErrLimitExceeded)
+	assert.NoError(t, newOptions(nil).checkContents(contents))
+
+	// A package whose encrypted contents are a decompression bomb is rejected before decrypting
+	sourceDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(sourceDir, "setup.exe"), []byte("setup"), 0600))
+	packed := filepath.Join(t.TempDir(), "app.intunewin")
+	require.NoError(t, pack.Pack(sourceDir, packed))
+	zr, err := zip.OpenReader(packed)
+	require.NoError(t, err)
+	defer zr.Close()
+	var metaData []byte
+	for _, file := range zr.File {
+		if file.Name == detectionXMLPath {
+			metaData, err = readZipFileFromReader(file)
+			require.NoError(t, err)
+		}
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, err := zw.Create(detectionXMLPath)
+	require.NoError(t, err)
+	_, err = w.Write(metaData)
+	require.NoError(t, err)
+	w, err = zw.Create(contentsPath)
+	require.NoError(t, err)
+	_, err = w.Write(make([]byte, 1<<20))
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+	_, err = DecryptReader(&buf, io.Discard, WithLimits(Limits{MaxTotalSize: 1 << 10}))
+	assert.ErrorIs(t, err, ErrLimitExceeded)
+}
@@ -0,0 +1,103 @@
+package unpack
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"time"
+)
+
+// Entry describes a file in the decrypted content of an intunewin file
+type Entry struct {
+	Path     string      `json:"path"`
+	Size     int64       `json:"size"`
+	Mode     fs.FileMode `json:"mode"`
+	IsDir    bool        `json:"isDir"`
+	Modified time.Time   `json:"modified"`
+}
+
+// List returns the files in the decrypted content of an intunewin file without extracting them.
+// Content that is not an archive is listed as the single file Unpack would write.
+func List(inputFile string, opts ...Option) ([]Entry, error) {
+	o := newOptions(opts)
+
+	contentBuf, contentSize, err := decryptToBuffer(inputFile, o)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list contents: %w", err)
+	}
+	defer contentBuf.Close()
+
+	content := io.NewSectionReader(contentBuf, 0, contentSize)
+	format, err := detectFormat(content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to detect content format: %w", err)
+	}
+
+	switch format {
+	case FormatZip:
+		return listZip(content, contentSize, o)
+	case FormatTar:
+		return listTar(content)
+	case FormatTarGzip:
+		gz, err := gzip.NewReader(content)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read gzip content: %w", err)
+		}
+		defer gz.Close()
+		return listTar(gz)
+	default:
+		return []Entry{{Path: rawContentName + format.Extension(), Size: contentSize, Mode: 0644}}, nil
+	}
+}
+
+// listZip lists the entries of the zip archive in r
+func listZip(r io.ReaderAt, size int64, o *Options) ([]Entry, error) {
+	zipReader, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read zip: %w", err)
+	}
+	if err := o.decodeNames(zipReader); err != nil {
+		return nil, err
+	}
+
+	entries := make([]Entry, 0, len(zipReader.File))
+	for _, file := range zipReader.File {
+		info := file.FileInfo()
+		entries = append(entries, Entry{
+			Path:     file.Name,
+			Size:     int64(file.UncompressedSize64), // #nosec G115
+			Mode:     entryMode(file),
+			IsDir:    info.IsDir(),
+			Modified: file.Modified,
+		})
+	}
+	return entries, nil
+}
+
+// listTar lists the entries of the tar archive in r
+func listTar(r io.Reader) ([]Entry, error) {
+	tr := tar.NewReader(r)
+
+	var entries []Entry
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return entries, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read tar: %w", err)
+		}
+		info := header.FileInfo()
+		entries = append(entries, Entry{
+			Path:     header.Name,
+			Size:     header.Size,
+			Mode:     info.Mode(),
+			IsDir:    info.IsDir(),
+			Modified: header.ModTime,
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package unpack
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kenchan0130/intunewin/internal/pack"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestList(t *testing.T) {
+	sourceDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(sourceDir, "setup.exe"), []byte("setup"), 0600))
+	require.NoError(t, os.MkdirAll(filepath.Join(sourceDir, "data"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(sourceDir, "data", "config.ini"), []byte("[app]"), 0600))
+
+	packedFile := filepath.Join(t.TempDir(), "app.intunewin")
+	require.NoError(t, pack.Pack(sourceDir, packedFile))
+
+	entries, err := List(packedFile)
+	require.NoError(t, err)
+
+	sizes := map[string]int64{}
+	for _, entry := range entries {
+		if !entry.IsDir {
+			sizes[entry.Path] = entry.Size
+		}
+	}
+	assert.Equal(t, map[string]int64{"setup.exe": 5, "data/config.ini": 5}, sizes)
+}
+
+func TestListRawContent(t *testing.T) {
+	content := append([]byte{0xD0, 0xCF, 0x11, 0xE0, 0xA1, 0xB1, 0x1A, 0xE1}, []byte("installer")...)
+
+	entries, err := List(packContent(t, content))
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "IntunePackage.msi", entries[0].Path)
+	assert.Equal(t, int64(len(content)), entries[0].Size)
+}
+
+func TestListNonExistentFile(t *testing.T) {
+	_, err := List(filepath.Join(t.TempDir(), "missing.intunewin"))
+	assert.ErrorIs(t, err, ErrInputNotFound)
+}
@@ -0,0 +1,82 @@
+package unpack
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/kenchan0130/intunewin/internal/buildinfo"
+	"github.com/kenchan0130/intunewin/internal/pathutil"
+)
+
+const (
+	detectionXMLName  = "Detection.xml"
+	detectionJSONName = "Detection.json"
+	buildInfoName     = "BuildInfo.json"
+)
+
+// ExtractMetadata writes Detection.xml as stored in the intunewin file, a JSON rendering
+// of it as Detection.json and, when present, BuildInfo.json to outputFolder.
+// The encrypted contents are not read.
+func ExtractMetadata(inputFile, outputFolder string, opts ...Option) error {
+	o := newOptions(opts)
+
+	zipReader, err := zip.OpenReader(pathutil.Long(inputFile))
+	if err != nil {
+		return openError(err)
+	}
+	defer zipReader.Close()
+
+	var metaData, buildInfoData []byte
+	for _, file := range zipReader.File {
+		name, err := o.entryName(file.Name)
+		if err != nil {
+			return err
+		}
+		switch name {
+		case detectionXMLPath:
+			metaData, err = readZipFileFromReader(file)
+			if err != nil {
+				return fmt.Errorf("failed to read Detection.xml: %w", err)
+			}
+		case buildinfo.Path:
+			buildInfoData, err = readZipFileFromReader(file)
+			if err != nil {
+				return fmt.Errorf("failed to read build info: %w", err)
+			}
+		}
+	}
+	if metaData == nil {
+		return ErrMetadataMissing
+	}
+
+	appInfo, err := o.parseMetadata(metaData)
+	if err != nil {
+		return err
+	}
+	jsonData, err := appInfo.ToJSON()
+	if err != nil {
+		return fmt.Errorf("failed to render Detection.xml as JSON: %w", err)
+	}
+
+	if err := os.MkdirAll(pathutil.Long(outputFolder), 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	files := map[string][]byte{
+		detectionXMLName:  metaData,
+		detectionJSONName: append(jsonData, '\n'),
+	}
+	if buildInfoData != nil {
+		files[buildInfoName] = buildInfoData
+	}
+	for name, data := range files {
+		o.Logger.Debug("writing metadata file", "path", name, "size", len(data))
+		if _, err := writeFile(filepath.Join(outputFolder, name), bytes.NewReader(data), defaultFileMode, int64(len(data)), o); err != nil {
+			return fmt.Errorf("failed to write %s: %w", name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package unpack
+
+import (
+	"archive/zip"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"unicode/utf8"
+
+	"github.com/kenchan0130/intunewin/internal/charset"
+)
+
+const (
+	// utf8Flag is the general purpose bit telling that the name of an entry is UTF-8
+	utf8Flag = 0x800
+	// unicodePathExtraID is the Info-ZIP Unicode Path extra field, which carries the UTF-8
+	// name of an entry whose header name is in a legacy code page
+	unicodePathExtraID = 0x7075
+)
+
+// decodeNames replaces the name of every entry of zipReader with its UTF-8 name. Names
+// flagged as UTF-8 are kept, entries with an Info-ZIP Unicode Path field take the name it
+// records, and the other names are read in the configured filename encoding, detected from
+// those names when it is charset.Auto, so legacy archives are not extracted as mojibake.
+func (o *Options) decodeNames(zipReader *zip.Reader) error {
+	var legacy []*zip.File
+	for _, file := range zipReader.File {
+		if file.Flags&utf8Flag != 0 {
+			continue
+		}
+		if name, ok := unicodePath(file.Extra, file.Name); ok {
+			file.Name = name
+			continue
+		}
+		legacy = append(legacy, file)
+	}
+	if len(legacy) == 0 {
+		return nil
+	}
+
+	enc := o.FilenameEncoding
+	switch enc {
+	case "", charset.Auto:
+		names := make([]string, len(legacy))
+		for i, file := range legacy {
+			names[i] = file.Name
+		}
+		enc = charset.Detect(names)
+		if enc != charset.UTF8 {
+			o.Logger.Info("detected legacy filename encoding", "encoding", enc)
+		}
+	case charset.UTF8, charset.ShiftJIS, charset.CP437:
+	default:
+		return fmt.Errorf("invalid filename encoding %q", enc)
+	}
+	for _, file := range legacy {
+		file.Name = charset.Decode(enc, file.Name)
+	}
+	return nil
+}
+
+// unicodePath returns the name in the Info-ZIP Unicode Path field of extra when the field
+// was written for name, as tools renaming an entry may leave a stale field behind
+func unicodePath(extra []byte, name string) (string, bool) {
+	for len(extra) >= 4 {
+		id := binary.LittleEndian.Uint16(extra)
+		size := int(binary.LittleEndian.Uint16(extra[2:]))
+		extra = extra[4:]
+		if size > len(extra) {
+			return "", false
+		}
+		field := extra[:size]
+		extra = extra[size:]
+
+		// The field is a version of 1, the CRC32 of the header name and the UTF-8 name
+		if id != unicodePathExtraID || len(field) < 5 || field[0] != 1 {
+			continue
+		}
+		if binary.LittleEndian.Uint32(field[1:]) != crc32.ChecksumIEEE([]byte(name)) {
+			return "", false
+		}
+		unicodeName := string(field[5:])
+		if unicodeName == "" || !utf8.ValidString(unicodeName) {
+			return "", false
+		}
+		return unicodeName, true
+	}
+	return "", false
+}
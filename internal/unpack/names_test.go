@@ -0,0 +1,136 @@
+package unpack
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kenchan0130/intunewin/internal/charset"
+	"github.com/kenchan0130/intunewin/internal/pack"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPackSetsUTF8Flag(t *testing.T) {
+	sourceDir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(sourceDir, "インストーラー"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(sourceDir, "インストーラー", "セットアップ.exe"), []byte("setup"), 0600))
+	packed := filepath.Join(t.TempDir(), "app.intunewin")
+	require.NoError(t, pack.Pack(sourceDir, packed))
+
+	var content bytes.Buffer
+	_, err := Decrypt(packed, &content)
+	require.NoError(t, err)
+	assertUTF8Names(t, content.Bytes(), "インストーラー/セットアップ.exe")
+
+	var tarBuf bytes.Buffer
+	tw := tar.NewWriter(&tarBuf)
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "日本語.exe", Typeflag: tar.TypeReg, Mode: 0644, Size: 5}))
+	_, err = tw.Write([]byte("setup"))
+	require.NoError(t, err)
+	require.NoError(t, tw.Close())
+	var fromTar bytes.Buffer
+	require.NoError(t, pack.PackTarTo(&tarBuf, &fromTar, "app", "日本語.exe"))
+	content.Reset()
+	_, err = DecryptReader(&fromTar, &content)
+	require.NoError(t, err)
+	assertUTF8Names(t, content.Bytes(), "日本語.exe")
+
+	outputDir := filepath.Join(t.TempDir(), "out")
+	require.NoError(t, Unpack(packed, outputDir))
+	data, err := os.ReadFile(filepath.Join(outputDir, "インストーラー", "セットアップ.exe"))
+	require.NoError(t, err)
+	assert.Equal(t, "setup", string(data))
+}
+
+// assertUTF8Names asserts that name is an entry of the zip archive in data and that every
+// entry with a non-ASCII name has the UTF-8 flag
+func assertUTF8Names(t *testing.T, data []byte, name string) {
+	t.Helper()
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	require.NoError(t, err)
+	var names []string
+	for _, file := range zr.File {
+		names = append(names, file.Name)
+		for _, r := range file.Name {
+			if r > 0x7f {
+				assert.NotZero(t, file.Flags&utf8Flag, file.Name)
+				break
+			}
+		}
+	}
+	assert.Contains(t, names, name)
+}
+
+func TestDecodeNames(t *testing.T) {
+	// "café.txt" in CP437 with a Unicode Path field holding its UTF-8 name
+	legacy := "caf\x82.txt"
+	unicodeField := func(name, headerName string) []byte {
+		field := []byte{1, 0, 0, 0, 0}
+		binary.LittleEndian.PutUint32(field[1:], crc32.ChecksumIEEE([]byte(headerName)))
+		field = append(field, name...)
+		extra := binary.LittleEndian.AppendUint16(nil, unicodePathExtraID)
+		extra = binary.LittleEndian.AppendUint16(extra, uint16(len(field))) //nolint:gosec // test names are short
+		return append(extra, field...)
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, header := range []*zip.FileHeader{
+		{Name: legacy, NonUTF8: true, Extra: unicodeField("café.txt", legacy)},
+		{Name: "stale\x82.txt", NonUTF8: true, Extra: unicodeField("renamed.txt", "other")},
+		{Name: "日本語.txt"},
+	} {
+		_, err := zw.CreateHeader(header)
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+
+	// The stale field is ignored and the name is read as CP437
+	assert.Equal(t, []string{"café.txt", "staleé.txt", "日本語.txt"}, decodedNames(t, buf.Bytes(), newOptions(nil)))
+}
+
+func TestDecodeNamesFilenameEncoding(t *testing.T) {
+	// Shift-JIS names as written on Japanese Windows, where the trail byte of 表 is a backslash
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, name := range []string{"setup.exe", "\x83\x5c\x83t\x83g/\x95\x5c\x8e\xa6.exe"} {
+		_, err := zw.CreateHeader(&zip.FileHeader{Name: name, NonUTF8: true})
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+
+	assert.Equal(t, []string{"setup.exe", "ソフト/表示.exe"}, decodedNames(t, buf.Bytes(), newOptions(nil)))
+	assert.Equal(t, []string{"setup.exe", "ソフト/表示.exe"}, decodedNames(t, buf.Bytes(), newOptions([]Option{WithFilenameEncoding(charset.ShiftJIS)})))
+	assert.Equal(t, []string{"setup.exe", "â\\âtâg/ò\\Äª.exe"}, decodedNames(t, buf.Bytes(), newOptions([]Option{WithFilenameEncoding(charset.CP437)})))
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	assert.ErrorContains(t, newOptions([]Option{WithFilenameEncoding("latin1")}).decodeNames(zr), "invalid filename encoding")
+
+	// Unpacking extracts the decoded names
+	var packed bytes.Buffer
+	require.NoError(t, pack.PackZipTo(bytes.NewReader(buf.Bytes()), &packed, "app", "setup.exe"))
+	outputDir := filepath.Join(t.TempDir(), "out")
+	require.NoError(t, UnpackReader(&packed, outputDir))
+	_, err = os.Stat(filepath.Join(outputDir, "ソフト", "表示.exe"))
+	assert.NoError(t, err)
+}
+
+// decodedNames returns the names of the entries of the zip archive in data decoded with o
+func decodedNames(t *testing.T, data []byte, o *Options) []string {
+	t.Helper()
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	require.NoError(t, err)
+	require.NoError(t, o.decodeNames(zr))
+	var names []string
+	for _, file := range zr.File {
+		names = append(names, file.Name)
+	}
+	return names
+}
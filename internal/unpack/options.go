@@ -0,0 +1,262 @@
+package unpack
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/kenchan0130/intunewin/internal/charset"
+	"github.com/kenchan0130/intunewin/internal/metadata"
+	"github.com/kenchan0130/intunewin/internal/spill"
+)
+
+// Options configures how an intunewin package is unpacked
+type Options struct {
+	// Logger receives diagnostic messages
+	Logger *slog.Logger
+	// Strict fails on Detection.xml values this tool does not recognize and on packages that
+	// deviate from the layout of the Microsoft tool instead of warning
+	Strict bool
+	// NoPreserve extracts entries with default modes and the current time instead of
+	// the modes and modification times recorded in the archive
+	NoPreserve bool
+	// Only lists patterns selecting the entries to extract, see WithOnly
+	Only []string
+	// Overwrite selects what happens to files that already exist in the output folder
+	Overwrite OverwritePolicy
+	// FilenameEncoding is the encoding of content entry names without the UTF-8 flag,
+	// detected from the names when empty or charset.Auto
+	FilenameEncoding charset.Encoding
+	// TempDir is where intermediate data larger than SpillThreshold is written, os.TempDir when empty
+	TempDir string
+	// SpillThreshold is the size above which intermediate data is written to a temporary file instead of memory
+	SpillThreshold int64
+	// MemoryLimit caps the intermediate data held in memory by all buffers sharing it, no cap when nil
+	MemoryLimit *spill.Limit
+	// NoVerify skips comparing the size and digest of the decrypted content with Detection.xml
+	NoVerify bool
+	// RetainKeys keeps the encryption info in the ApplicationInfo returned after decrypting
+	RetainKeys bool
+	// EncryptionInfo replaces the encryption info of Detection.xml when set, see WithEncryptionInfo
+	EncryptionInfo *metadata.XMLEncryptionInfo
+	// Limits caps the size and number of entries extracted, see WithLimits
+	Limits Limits
+	// AllowSymlinks extracts symbolic links that point inside the output folder instead of
+	// failing with ErrSymlinkEntry
+	AllowSymlinks bool
+}
+
+// OverwritePolicy selects what happens to files that already exist in the output folder
+type OverwritePolicy string
+
+const (
+	// OverwriteForce replaces existing files
+	OverwriteForce OverwritePolicy = "force"
+	// OverwriteSkip keeps existing files and extracts the rest
+	OverwriteSkip OverwritePolicy = "skip"
+	// OverwriteFail stops with ErrFileExists at the first existing file
+	OverwriteFail OverwritePolicy = "fail"
+)
+
+// Option configures Options
+type Option func(*Options)
+
+// WithLogger sends diagnostic messages to logger
+func WithLogger(logger *slog.Logger) Option {
+	return func(o *Options) {
+		o.Logger = logger
+	}
+}
+
+// WithStrict fails with ErrUnsupportedVersion when Detection.xml has a ToolVersion,
+// ProfileIdentifier or digest algorithm this tool does not recognize, and with ErrNonstandard
+// when the package deviates from the layout of the Microsoft tool, such as entry or element
+// names in a different case or extra elements in Detection.xml, as written by some other
+// packagers. By default a warning is logged and unpacking continues.
+func WithStrict() Option {
+	return func(o *Options) {
+		o.Strict = true
+	}
+}
+
+// WithNoPreserve extracts files with mode 0644 and directories with mode 0755 and leaves
+// their modification times at the time of extraction
+func WithNoPreserve() Option {
+	return func(o *Options) {
+		o.NoPreserve = true
+	}
+}
+
+// WithOnly extracts only the entries of the content matching any of patterns, such as
+// "Files/**" or "*.ps1", and fails with ErrNoMatches when none does; the whole package is
+// still decrypted. A pattern without a slash matches the name of a file or folder at any
+// depth, otherwise it matches the slash separated path in the content, ignoring a leading
+// slash. Elements have the syntax of path.Match, "**" matches any number of folders, and
+// selecting a folder selects everything below it.
+func WithOnly(patterns ...string) Option {
+	return func(o *Options) {
+		o.Only = append(o.Only, patterns...)
+	}
+}
+
+// WithOverwrite selects what happens to files that already exist in the output folder.
+// The default is OverwriteForce.
+func WithOverwrite(policy OverwritePolicy) Option {
+	return func(o *Options) {
+		o.Overwrite = policy
+	}
+}
+
+// WithFilenameEncoding reads the names of content entries that are not flagged as UTF-8 in
+// enc, such as charset.ShiftJIS for archives made on Japanese Windows. By default the
+// encoding is detected: UTF-8 when every such name is valid UTF-8, else Shift-JIS when every
+// name is valid Shift-JIS, else CP437.
+func WithFilenameEncoding(enc charset.Encoding) Option {
+	return func(o *Options) {
+		o.FilenameEncoding = enc
+	}
+}
+
+// WithNoVerify skips comparing the size and SHA256 digest of the decrypted content with
+// UnencryptedContentSize and FileDigest in Detection.xml. By default a mismatch fails with
+// ErrSizeMismatch or ErrDigestMismatch, which rejects corrupted or re-wrapped packages; the
+// HMAC of the encrypted content is verified either way.
+func WithNoVerify() Option {
+	return func(o *Options) {
+		o.NoVerify = true
+	}
+}
+
+// WithTempDir writes temporary files to dir instead of the default temporary directory
+func WithTempDir(dir string) Option {
+	return func(o *Options) {
+		o.TempDir = dir
+	}
+}
+
+// WithSpillThreshold keeps intermediate data of up to threshold bytes in memory and
+// writes larger data to temporary files. A threshold of 0 always uses temporary files.
+func WithSpillThreshold(threshold int64) Option {
+	return func(o *Options) {
+		o.SpillThreshold = threshold
+	}
+}
+
+// WithMemoryLimit writes intermediate data to temporary files once the buffers sharing
+// limit hold its maximum in memory, whatever the spill threshold
+func WithMemoryLimit(limit *spill.Limit) Option {
+	return func(o *Options) {
+		o.MemoryLimit = limit
+	}
+}
+
+// WithRetainKeys keeps the encryption info, including the keys, in the ApplicationInfo
+// returned after decrypting. By default it is left out so the key material does not
+// outlive unpacking.
+func WithRetainKeys() Option {
+	return func(o *Options) {
+		o.RetainKeys = true
+	}
+}
+
+// WithEncryptionInfo decrypts the package with info instead of the encryption info of its
+// Detection.xml, such as keys exported to a key file by a pack that left them out of the
+// package.
+func WithEncryptionInfo(info *metadata.XMLEncryptionInfo) Option {
+	return func(o *Options) {
+		o.EncryptionInfo = info
+	}
+}
+
+// WithLimits fails with ErrLimitExceeded, before anything is written where possible, when the
+// content extracted from an untrusted package exceeds limits. MaxTotalSize also rejects
+// encrypted contents that are a decompression bomb whenever a package is decrypted.
+func WithLimits(limits Limits) Option {
+	return func(o *Options) {
+		o.Limits = limits
+	}
+}
+
+// WithAllowSymlinks extracts the symbolic links of the content. By default a link fails with
+// ErrSymlinkEntry, and with this option a link whose target is absolute or leads outside
+// the output folder fails with ErrPathTraversal.
+func WithAllowSymlinks() Option {
+	return func(o *Options) {
+		o.AllowSymlinks = true
+	}
+}
+
+// NewBuffer returns a buffer for intermediate data that follows the temporary
+// directory and spill threshold of opts
+func NewBuffer(pattern string, opts ...Option) *spill.Buffer {
+	return newOptions(opts).newBuffer(pattern)
+}
+
+// newBuffer returns a buffer for intermediate data named after pattern
+func (o *Options) newBuffer(pattern string) *spill.Buffer {
+	return spill.NewLimited(o.TempDir, o.SpillThreshold, pattern, o.MemoryLimit)
+}
+
+// newOptions applies opts over the defaults
+func newOptions(opts []Option) *Options {
+	o := &Options{SpillThreshold: spill.DefaultThreshold, Overwrite: OverwriteForce}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.Logger == nil {
+		o.Logger = slog.New(slog.DiscardHandler)
+	}
+	return o
+}
+
+// checkCompatibility logs a warning for every unrecognized Detection.xml value and,
+// in strict mode, returns an error for the first one
+func (o *Options) checkCompatibility(appInfo *metadata.ApplicationInfo) error {
+	warnings := appInfo.CheckCompatibility()
+	for _, w := range warnings {
+		o.Logger.Warn("unrecognized Detection.xml value, continuing best-effort",
+			"field", w.Field, "value", w.Value, "expected", w.Expected)
+	}
+	if o.Strict && len(warnings) > 0 {
+		return fmt.Errorf("%w: %s", ErrUnsupportedVersion, warnings[0])
+	}
+	return nil
+}
+
+// parseMetadata parses Detection.xml, logging a warning for every deviation from the format
+// and unrecognized value and, in strict mode, returning an error for the first one
+func (o *Options) parseMetadata(data []byte) (*metadata.ApplicationInfo, error) {
+	appInfo, warnings, err := metadata.ParseXML(data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidMetadata, err)
+	}
+	for _, w := range warnings {
+		o.Logger.Warn("nonstandard Detection.xml, continuing best-effort",
+			"field", w.Field, "value", w.Value, "expected", w.Expected)
+	}
+	if o.Strict && len(warnings) > 0 {
+		return nil, fmt.Errorf("%w: %s", ErrNonstandard, warnings[0])
+	}
+	if err := o.checkCompatibility(appInfo); err != nil {
+		return nil, err
+	}
+	return appInfo, nil
+}
+
+// entryName returns the package path that the zip entry name stands for. Entries whose name
+// matches a path of the format regardless of case and separators are read as that path with
+// a warning, or rejected in strict mode.
+func (o *Options) entryName(name string) (string, error) {
+	slashed := strings.ReplaceAll(name, `\`, "/")
+	for _, known := range []string{detectionXMLPath, contentsPath} {
+		if name == known || len(slashed) != len(known) || !strings.EqualFold(slashed, known) {
+			continue
+		}
+		if o.Strict {
+			return "", fmt.Errorf("%w: entry %q (expected %s)", ErrNonstandard, name, known)
+		}
+		o.Logger.Warn("nonstandard package entry, continuing best-effort", "entry", name, "expected", known)
+		return known, nil
+	}
+	return name, nil
+}
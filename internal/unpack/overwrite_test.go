@@ -0,0 +1,80 @@
+package unpack
+
+import (
+	"archive/zip"
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// overwriteInput returns a package with setup.exe and bin/tool.exe, and an output folder
+// that already contains a different setup.exe
+func overwriteInput(t *testing.T) (string, string) {
+	t.Helper()
+	content := new(bytes.Buffer)
+	zw := zip.NewWriter(content)
+	for name, data := range map[string]string{"setup.exe": "new setup", "bin/tool.exe": "tool"} {
+		w, err := zw.Create(name)
+		require.NoError(t, err)
+		_, err = w.Write([]byte(data))
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+
+	outputDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(outputDir, "setup.exe"), []byte("old setup"), 0600))
+	return packContent(t, content.Bytes()), outputDir
+}
+
+func readString(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	return string(data)
+}
+
+func TestUnpackOverwriteForce(t *testing.T) {
+	input, outputDir := overwriteInput(t)
+	require.NoError(t, Unpack(input, outputDir))
+
+	assert.Equal(t, "new setup", readString(t, filepath.Join(outputDir, "setup.exe")))
+	assert.Equal(t, "tool", readString(t, filepath.Join(outputDir, "bin", "tool.exe")))
+}
+
+func TestUnpackOverwriteForceReadOnly(t *testing.T) {
+	input, outputDir := overwriteInput(t)
+	require.NoError(t, os.Chmod(filepath.Join(outputDir, "setup.exe"), 0400))
+
+	require.NoError(t, Unpack(input, outputDir, WithOverwrite(OverwriteForce)))
+	assert.Equal(t, "new setup", readString(t, filepath.Join(outputDir, "setup.exe")))
+}
+
+func TestUnpackOverwriteSkip(t *testing.T) {
+	input, outputDir := overwriteInput(t)
+	require.NoError(t, Unpack(input, outputDir, WithOverwrite(OverwriteSkip)))
+
+	assert.Equal(t, "old setup", readString(t, filepath.Join(outputDir, "setup.exe")))
+	assert.Equal(t, "tool", readString(t, filepath.Join(outputDir, "bin", "tool.exe")))
+}
+
+func TestUnpackOverwriteFail(t *testing.T) {
+	input, outputDir := overwriteInput(t)
+	err := Unpack(input, outputDir, WithOverwrite(OverwriteFail))
+	require.ErrorIs(t, err, ErrFileExists)
+	assert.Contains(t, err.Error(), "setup.exe")
+
+	assert.Equal(t, "old setup", readString(t, filepath.Join(outputDir, "setup.exe")))
+
+	// An empty folder is extracted normally
+	require.NoError(t, Unpack(input, t.TempDir(), WithOverwrite(OverwriteFail)))
+}
+
+func TestUnpackInvalidOverwritePolicy(t *testing.T) {
+	input, outputDir := overwriteInput(t)
+	err := Unpack(input, outputDir, WithOverwrite("sometimes"))
+	assert.ErrorContains(t, err, "invalid overwrite policy")
+}
@@ -0,0 +1,124 @@
+package unpack
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/kenchan0130/intunewin/internal/metadata"
+)
+
+// UnpackReaderAtToZip is like UnpackReaderToZipWithInfo but reads the intunewin package of
+// the given size from r, such as an *os.File or a memory-mapped file, instead of copying it
+// to a buffer first. The encrypted contents are streamed from r, so only the decrypted zip
+// archive is held in memory.
+func UnpackReaderAtToZip(r io.ReaderAt, size int64, opts ...Option) (io.Reader, *metadata.ApplicationInfo, error) {
+	o := newOptions(opts)
+
+	zipReader, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, nil, openError(err)
+	}
+
+	content, appInfo, err := decryptPackageToMemory(zipReader, o, streamContents)
+	if err != nil {
+		return nil, nil, err
+	}
+	return bytes.NewReader(content), appInfo, nil
+}
+
+// DecryptReaderAt is like DecryptReader but reads the intunewin package of the given size
+// from r without buffering it or its encrypted contents
+func DecryptReaderAt(r io.ReaderAt, size int64, output io.Writer, opts ...Option) (*metadata.ApplicationInfo, error) {
+	o := newOptions(opts)
+
+	zipReader, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, openError(err)
+	}
+	return decryptPackageWith(zipReader, output, o, streamContents)
+}
+
+// streamContents is the contentsOpener reading the encrypted contents from the package
+// itself, inflating the entry again when it is read a second time
+func streamContents(contents *zip.File, _ *Options) (io.ReadSeekCloser, error) {
+	e := &entryReader{file: contents}
+	if err := e.reopen(); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// entryReader reads a zip entry and seeks within it. Seeking forward skips the entry's
+// content and seeking backward reopens the entry, so each pass over a compressed entry
+// inflates it again instead of keeping it in memory.
+type entryReader struct {
+	file *zip.File
+	rc   io.ReadCloser
+	pos  int64
+}
+
+// reopen opens the entry again at its start
+func (e *entryReader) reopen() error {
+	if e.rc != nil {
+		e.rc.Close()
+	}
+	rc, err := e.file.Open()
+	if err != nil {
+		e.rc = nil
+		return fmt.Errorf("failed to open encrypted contents: %w", err)
+	}
+	e.rc, e.pos = rc, 0
+	return nil
+}
+
+func (e *entryReader) Read(p []byte) (int, error) {
+	if e.rc == nil {
+		return 0, errors.New("encrypted contents are closed")
+	}
+	n, err := e.rc.Read(p)
+	e.pos += int64(n)
+	return n, err //nolint:wrapcheck // io.EOF must be returned as is
+}
+
+func (e *entryReader) Seek(offset int64, whence int) (int64, error) {
+	var target int64
+	switch whence {
+	case io.SeekStart:
+		target = offset
+	case io.SeekCurrent:
+		target = e.pos + offset
+	case io.SeekEnd:
+		target = int64(e.file.UncompressedSize64) + offset //nolint:gosec // sizes of zip entries fit in int64
+	default:
+		return 0, errors.New("invalid whence")
+	}
+	if target < 0 {
+		return 0, errors.New("negative position")
+	}
+
+	if target < e.pos || e.rc == nil {
+		if err := e.reopen(); err != nil {
+			return 0, err
+		}
+	}
+	if target > e.pos {
+		n, err := io.CopyN(io.Discard, e.rc, target-e.pos)
+		e.pos += n
+		if err != nil && !errors.Is(err, io.EOF) {
+			return e.pos, fmt.Errorf("failed to read encrypted contents: %w", err)
+		}
+	}
+	return e.pos, nil
+}
+
+func (e *entryReader) Close() error {
+	if e.rc == nil {
+		return nil
+	}
+	err := e.rc.Close()
+	e.rc = nil
+	return err //nolint:wrapcheck // closing a zip entry reports checksum errors as is
+}
@@ -0,0 +1,82 @@
+package unpack
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kenchan0130/intunewin/internal/pack"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnpackReaderAt(t *testing.T) {
+	sourceDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(sourceDir, "setup.exe"), []byte("setup"), 0600))
+	packed := filepath.Join(t.TempDir(), "app.intunewin")
+	require.NoError(t, pack.Pack(sourceDir, packed))
+
+	f, err := os.Open(packed)
+	require.NoError(t, err)
+	defer f.Close()
+	stat, err := f.Stat()
+	require.NoError(t, err)
+
+	var fromFile, fromReaderAt bytes.Buffer
+	_, err = Decrypt(packed, &fromFile)
+	require.NoError(t, err)
+	appInfo, err := DecryptReaderAt(f, stat.Size(), &fromReaderAt)
+	require.NoError(t, err)
+	assert.Equal(t, fromFile.Bytes(), fromReaderAt.Bytes())
+	assert.Equal(t, filepath.Base(sourceDir), appInfo.Name)
+
+	reader, appInfo, err := UnpackReaderAtToZip(f, stat.Size())
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Base(sourceDir), appInfo.Name)
+	content, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	assert.Equal(t, fromFile.Bytes(), content)
+
+	data := []byte("not a package")
+	_, _, err = UnpackReaderAtToZip(bytes.NewReader(data), int64(len(data)))
+	assert.ErrorIs(t, err, ErrNotIntunewin)
+}
+
+func TestEntryReaderSeek(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, err := zw.Create("entry")
+	require.NoError(t, err)
+	_, err = w.Write([]byte("0123456789"))
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+
+	e, err := streamContents(zr.File[0], nil)
+	require.NoError(t, err)
+	defer e.Close()
+
+	pos, err := e.Seek(4, io.SeekStart)
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), pos)
+	rest, err := io.ReadAll(e)
+	require.NoError(t, err)
+	assert.Equal(t, "456789", string(rest))
+
+	pos, err = e.Seek(-3, io.SeekEnd)
+	require.NoError(t, err)
+	assert.Equal(t, int64(7), pos)
+	pos, err = e.Seek(-5, io.SeekCurrent)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), pos)
+	rest, err = io.ReadAll(e)
+	require.NoError(t, err)
+	assert.Equal(t, "23456789", string(rest))
+
+	_, err = e.Seek(-1, io.SeekStart)
+	assert.Error(t, err)
+}
@@ -0,0 +1,73 @@
+package unpack
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/kenchan0130/intunewin/internal/pathutil"
+)
+
+// ErrNoMatches is returned when no entry of the content matches the patterns of WithOnly
+var ErrNoMatches = errors.New("no entries match the selection")
+
+// checkOnly validates the patterns of WithOnly before anything is decrypted
+func (o *Options) checkOnly() error {
+	for _, pattern := range o.Only {
+		if _, err := pathutil.Match(strings.TrimPrefix(pattern, "/"), ""); err != nil {
+			return fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// selected reports whether the entry name is extracted: always without WithOnly,
+// otherwise when it or one of its parent folders matches a pattern
+func (o *Options) selected(name string) bool {
+	if len(o.Only) == 0 {
+		return true
+	}
+	cleaned, err := pathutil.Clean(name)
+	if err != nil {
+		return false
+	}
+	for dir := cleaned; dir != "."; dir = path.Dir(dir) {
+		for _, pattern := range o.Only {
+			var matched bool
+			if strings.Contains(pattern, "/") {
+				matched, _ = pathutil.Match(strings.TrimPrefix(pattern, "/"), dir)
+			} else {
+				matched, _ = path.Match(pattern, path.Base(dir))
+			}
+			if matched {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// selection counts the entries extracted with WithOnly
+type selection struct {
+	o       *Options
+	matched int
+}
+
+// include reports whether the entry name is extracted and counts it
+func (s *selection) include(name string) bool {
+	if !s.o.selected(name) {
+		s.o.Logger.Debug("skipping unselected entry", "path", name)
+		return false
+	}
+	s.matched++
+	return true
+}
+
+// check returns ErrNoMatches when WithOnly selected no entry
+func (s *selection) check() error {
+	if len(s.o.Only) > 0 && s.matched == 0 {
+		return fmt.Errorf("%w: %s", ErrNoMatches, strings.Join(s.o.Only, ", "))
+	}
+	return nil
+}
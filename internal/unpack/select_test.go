@@ -0,0 +1,48 @@
+package unpack
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kenchan0130/intunewin/internal/pack"
+)
+
+func TestUnpackOnly(t *testing.T) {
+	tempDir := t.TempDir()
+	sourceDir := filepath.Join(tempDir, "source")
+	for _, name := range []string{"setup.exe", "deploy.ps1", "Files/a.txt", "Files/sub/b.txt", "scripts/detect.ps1", "docs/readme.txt"} {
+		require.NoError(t, os.MkdirAll(filepath.Join(sourceDir, filepath.Dir(name)), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(sourceDir, name), []byte(name), 0600))
+	}
+	packedFile := filepath.Join(tempDir, "test.intunewin")
+	require.NoError(t, pack.Pack(sourceDir, packedFile))
+
+	outputDir := filepath.Join(tempDir, "out")
+	require.NoError(t, Unpack(packedFile, outputDir, WithOnly("Files/**", "*.ps1")))
+
+	var extracted []string
+	require.NoError(t, filepath.WalkDir(outputDir, func(p string, d os.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			rel, _ := filepath.Rel(outputDir, p)
+			extracted = append(extracted, filepath.ToSlash(rel))
+		}
+		return err
+	}))
+	assert.ElementsMatch(t, []string{"deploy.ps1", "Files/a.txt", "Files/sub/b.txt", "scripts/detect.ps1"}, extracted)
+
+	outputDir = filepath.Join(tempDir, "folder")
+	require.NoError(t, Unpack(packedFile, outputDir, WithOnly("/docs")))
+	assert.FileExists(t, filepath.Join(outputDir, "docs", "readme.txt"))
+	assert.NoFileExists(t, filepath.Join(outputDir, "setup.exe"))
+
+	err := Unpack(packedFile, filepath.Join(tempDir, "none"), WithOnly("*.msi"))
+	assert.ErrorIs(t, err, ErrNoMatches)
+
+	err = Unpack(packedFile, filepath.Join(tempDir, "bad"), WithOnly("Files/[a"))
+	assert.ErrorIs(t, err, path.ErrBadPattern)
+}
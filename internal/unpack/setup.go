@@ -0,0 +1,110 @@
+package unpack
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/kenchan0130/intunewin/internal/pathutil"
+	"github.com/kenchan0130/intunewin/internal/pe"
+	"github.com/kenchan0130/intunewin/internal/spill"
+)
+
+// ErrFileNotInContent is returned when a requested file is not in the decrypted content
+var ErrFileNotInContent = errors.New("file is not in the content")
+
+// OpenSetupFile decrypts an intunewin file and returns the content of the setup file
+// recorded in Detection.xml together with its name in the content. The caller closes the buffer.
+func OpenSetupFile(inputFile string, opts ...Option) (*spill.Buffer, string, error) {
+	appInfo, err := ReadApplicationInfo(inputFile, opts...)
+	if err != nil {
+		return nil, "", err
+	}
+	return OpenFile(inputFile, appInfo.SetupFile, opts...)
+}
+
+// OpenFile decrypts an intunewin file and returns the content of the file at name, which is
+// matched case-insensitively with either kind of slash, together with its name in the
+// content. The caller closes the buffer.
+func OpenFile(inputFile, name string, opts ...Option) (*spill.Buffer, string, error) {
+	o := newOptions(opts)
+
+	want, err := pathutil.Clean(name)
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %s", ErrFileNotInContent, name)
+	}
+
+	contentBuf, contentSize, err := decryptToBuffer(inputFile, o)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to decrypt content: %w", err)
+	}
+	defer contentBuf.Close()
+
+	zipReader, err := zip.NewReader(io.NewSectionReader(contentBuf, 0, contentSize), contentSize)
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: content is not a zip archive", ErrFileNotInContent)
+	}
+	if err := o.decodeNames(zipReader); err != nil {
+		return nil, "", err
+	}
+	for _, file := range zipReader.File {
+		entry, err := pathutil.Clean(file.Name)
+		if err != nil || file.FileInfo().IsDir() || !strings.EqualFold(entry, want) {
+			continue
+		}
+		buf, err := readFile(file, o)
+		if err != nil {
+			return nil, "", err
+		}
+		return buf, entry, nil
+	}
+	return nil, "", fmt.Errorf("%w: %s", ErrFileNotInContent, name)
+}
+
+// readFile copies file to a seekable buffer
+func readFile(file *zip.File, o *Options) (*spill.Buffer, error) {
+	rc, err := file.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", file.Name, err)
+	}
+	defer rc.Close()
+
+	buf := o.newBuffer("intunewin-file-*")
+	if _, err := io.Copy(buf, rc); err != nil {
+		buf.Close()
+		return nil, fmt.Errorf("failed to read %s: %w", file.Name, err)
+	}
+	if _, err := buf.Seek(0, io.SeekStart); err != nil {
+		buf.Close()
+		return nil, fmt.Errorf("failed to read %s: %w", file.Name, err)
+	}
+	return buf, nil
+}
+
+// SetupFileVersion decrypts an intunewin file and reads the version resource of the setup
+// file recorded in Detection.xml. pe.ErrNoVersion is returned when the setup file is not
+// an executable with version information.
+func SetupFileVersion(inputFile string, opts ...Option) (*pe.VersionInfo, error) {
+	appInfo, err := ReadApplicationInfo(inputFile, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.EqualFold(path.Ext(strings.ReplaceAll(appInfo.SetupFile, `\`, "/")), ".exe") {
+		return nil, fmt.Errorf("%w: %s is not an executable", pe.ErrNoVersion, appInfo.SetupFile)
+	}
+
+	buf, _, err := OpenSetupFile(inputFile, opts...)
+	if err != nil {
+		return nil, err
+	}
+	defer buf.Close()
+
+	info, err := pe.ReadVersion(buf)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse setup file: %w", err)
+	}
+	return info, nil
+}
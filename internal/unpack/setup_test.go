@@ -0,0 +1,82 @@
+package unpack
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kenchan0130/intunewin/internal/pack"
+	"github.com/kenchan0130/intunewin/internal/pe"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenSetupFile(t *testing.T) {
+	content := new(bytes.Buffer)
+	zw := zip.NewWriter(content)
+	w, err := zw.Create("bin/Setup.exe")
+	require.NoError(t, err)
+	_, err = w.Write([]byte("setup"))
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+
+	path := filepath.Join(t.TempDir(), "app.intunewin")
+	f, err := os.Create(path)
+	require.NoError(t, err)
+	require.NoError(t, pack.PackZipTo(bytes.NewReader(content.Bytes()), f, "app", `bin\setup.exe`))
+	require.NoError(t, f.Close())
+
+	buf, name, err := OpenSetupFile(path)
+	require.NoError(t, err)
+	defer buf.Close()
+	assert.Equal(t, "bin/Setup.exe", name)
+	data, err := io.ReadAll(buf)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("setup"), data)
+
+	buf, name, err = OpenFile(path, "BIN/setup.exe")
+	require.NoError(t, err)
+	buf.Close()
+	assert.Equal(t, "bin/Setup.exe", name)
+
+	_, _, err = OpenFile(path, "bin")
+	assert.ErrorIs(t, err, ErrFileNotInContent)
+	_, _, err = OpenFile(path, "../setup.exe")
+	assert.ErrorIs(t, err, ErrFileNotInContent)
+}
+
+func TestSetupFileVersionErrors(t *testing.T) {
+	content := new(bytes.Buffer)
+	zw := zip.NewWriter(content)
+	w, err := zw.Create("setup.exe")
+	require.NoError(t, err)
+	_, err = w.Write([]byte("not a PE file"))
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+
+	packWith := func(setupFile string) string {
+		path := filepath.Join(t.TempDir(), "app.intunewin")
+		f, err := os.Create(path)
+		require.NoError(t, err)
+		defer f.Close()
+		require.NoError(t, pack.PackZipTo(bytes.NewReader(content.Bytes()), f, "app", setupFile, pack.WithSkipSetupFileCheck()))
+		return path
+	}
+
+	_, err = SetupFileVersion(packWith("app.msi"))
+	assert.ErrorIs(t, err, pe.ErrNoVersion)
+
+	_, err = SetupFileVersion(packWith("install.exe"))
+	assert.ErrorIs(t, err, ErrFileNotInContent)
+
+	_, err = SetupFileVersion(packWith("setup.exe"))
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, pe.ErrNoVersion)
+	assert.NotErrorIs(t, err, ErrFileNotInContent)
+
+	_, err = SetupFileVersion(filepath.Join(t.TempDir(), "missing.intunewin"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,103 @@
+package unpack
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/kenchan0130/intunewin/internal/pathutil"
+)
+
+// ErrSymlinkEntry is returned for a symbolic link in the content unless WithAllowSymlinks is given
+var ErrSymlinkEntry = errors.New("content contains a symbolic link")
+
+// maxSymlinkTarget is the longest link target read from a zip entry
+const maxSymlinkTarget = 4096
+
+// readSymlinkTarget reads the link target stored as the content of a zip entry
+func readSymlinkTarget(r io.Reader) (string, error) {
+	target, err := io.ReadAll(io.LimitReader(r, maxSymlinkTarget+1))
+	if err != nil {
+		return "", fmt.Errorf("failed to read link target: %w", err)
+	}
+	if len(target) > maxSymlinkTarget {
+		return "", fmt.Errorf("link target is longer than %d bytes", maxSymlinkTarget)
+	}
+	return string(target), nil
+}
+
+// writeSymlink creates the symbolic link name at path pointing to target. It fails with
+// ErrSymlinkEntry without AllowSymlinks, and with ErrPathTraversal when the link would
+// point outside outputFolder. An existing file is replaced, kept or reported according to
+// o.Overwrite; writeSymlink reports false when the file was kept.
+func writeSymlink(outputFolder, path, name, target string, o *Options) (bool, error) {
+	if !o.AllowSymlinks {
+		return false, fmt.Errorf("%w: %s", ErrSymlinkEntry, name)
+	}
+	if err := os.MkdirAll(pathutil.Long(filepath.Dir(path)), defaultDirMode); err != nil {
+		return false, fmt.Errorf("failed to create parent directory: %w", err)
+	}
+	linkTarget, err := symlinkTarget(outputFolder, path, target)
+	if err != nil {
+		return false, &PathTraversalError{Name: name, Err: err}
+	}
+
+	if _, err := os.Lstat(pathutil.Long(path)); err == nil {
+		switch o.Overwrite {
+		case OverwriteForce:
+			if err := os.Remove(pathutil.Long(path)); err != nil {
+				return false, fmt.Errorf("failed to replace %s: %w", path, err)
+			}
+		case OverwriteSkip:
+			o.Logger.Info("skipping existing file", "path", path)
+			return false, nil
+		case OverwriteFail:
+			return false, fmt.Errorf("%w: %s", ErrFileExists, path)
+		default:
+			return false, fmt.Errorf("invalid overwrite policy %q", o.Overwrite)
+		}
+	}
+	if err := os.Symlink(linkTarget, pathutil.Long(path)); err != nil {
+		return false, fmt.Errorf("failed to create symbolic link: %w", err)
+	}
+	return true, nil
+}
+
+// symlinkTarget returns target in the form of this platform when a link at path pointing to it
+// stays below outputFolder. The target must be relative and may only climb with leading ".."
+// components, which are resolved from the real folder of the link, so a link cannot reach
+// outside through another link extracted before it.
+func symlinkTarget(outputFolder, path, target string) (string, error) {
+	slashed := strings.ReplaceAll(target, `\`, "/")
+	switch {
+	case slashed == "":
+		return "", fmt.Errorf("%w: empty link target", pathutil.ErrUnsafePath)
+	case strings.HasPrefix(slashed, "/"), pathutil.HasDriveLetter(slashed):
+		return "", fmt.Errorf("%w: absolute link target %s", pathutil.ErrUnsafePath, target)
+	}
+	climbing := true
+	for elem := range strings.SplitSeq(slashed, "/") {
+		if elem != ".." {
+			climbing = false
+		} else if !climbing {
+			return "", fmt.Errorf("%w: link target %s has a .. component after a folder", pathutil.ErrUnsafePath, target)
+		}
+	}
+
+	root, err := filepath.EvalSymlinks(outputFolder)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve output folder: %w", err)
+	}
+	parent, err := filepath.EvalSymlinks(filepath.Dir(path))
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve link folder: %w", err)
+	}
+	resolved := filepath.Join(parent, filepath.FromSlash(slashed))
+	if rel, err := filepath.Rel(root, resolved); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%w: link target %s escapes the output folder", pathutil.ErrUnsafePath, target)
+	}
+	return filepath.FromSlash(slashed), nil
+}
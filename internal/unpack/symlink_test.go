@@ -0,0 +1,130 @@
+package unpack
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// zipEntry is an entry of a crafted zip content, a symbolic link to target when target is set
+type zipEntry struct {
+	name   string
+	target string
+}
+
+// packZipEntries packs a zip content with entries and returns the path of the package
+func packZipEntries(t *testing.T, entries ...zipEntry) string {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, entry := range entries {
+		header := &zip.FileHeader{Name: entry.name}
+		data := "data"
+		if entry.target != "" {
+			header.SetMode(os.ModeSymlink | 0777)
+			data = entry.target
+		}
+		w, err := zw.CreateHeader(header)
+		require.NoError(t, err)
+		_, err = w.Write([]byte(data))
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+	return packContent(t, buf.Bytes())
+}
+
+func TestUnpackRejectsSymlinks(t *testing.T) {
+	outputDir := filepath.Join(t.TempDir(), "out")
+	err := Unpack(packZipEntries(t, zipEntry{name: "setup.exe"}, zipEntry{name: "link", target: "setup.exe"}), outputDir)
+	require.ErrorIs(t, err, ErrSymlinkEntry)
+	_, err = os.Lstat(filepath.Join(outputDir, "link"))
+	assert.True(t, os.IsNotExist(err))
+
+	var tarBuf bytes.Buffer
+	tw := tar.NewWriter(&tarBuf)
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "link", Typeflag: tar.TypeSymlink, Linkname: "/etc/passwd"}))
+	require.NoError(t, tw.Close())
+	err = Unpack(packContent(t, tarBuf.Bytes()), filepath.Join(t.TempDir(), "out"))
+	assert.ErrorIs(t, err, ErrSymlinkEntry)
+}
+
+func TestUnpackAllowSymlinks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("creating symbolic links needs a privilege on Windows")
+	}
+
+	outputDir := filepath.Join(t.TempDir(), "out")
+	packed := packZipEntries(t,
+		zipEntry{name: "bin/setup.exe"},
+		zipEntry{name: "setup", target: "bin/setup.exe"},
+		zipEntry{name: "lib/bin", target: "../bin"},
+		zipEntry{name: "lib/bin/tool.exe"},
+	)
+	require.NoError(t, Unpack(packed, outputDir, WithAllowSymlinks()))
+
+	target, err := os.Readlink(filepath.Join(outputDir, "setup"))
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join("bin", "setup.exe"), target)
+	data, err := os.ReadFile(filepath.Join(outputDir, "setup"))
+	require.NoError(t, err)
+	assert.Equal(t, "data", string(data))
+	// Writing through a link that stays inside lands inside
+	assert.FileExists(t, filepath.Join(outputDir, "bin", "tool.exe"))
+
+	var tarBuf bytes.Buffer
+	tw := tar.NewWriter(&tarBuf)
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "link", Typeflag: tar.TypeSymlink, Linkname: "setup.exe"}))
+	require.NoError(t, tw.Close())
+	tarOutput := filepath.Join(t.TempDir(), "out")
+	require.NoError(t, Unpack(packContent(t, tarBuf.Bytes()), tarOutput, WithAllowSymlinks()))
+	target, err = os.Readlink(filepath.Join(tarOutput, "link"))
+	require.NoError(t, err)
+	assert.Equal(t, "setup.exe", target)
+}
+
+func TestUnpackRejectsEscapingSymlinks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("creating symbolic links needs a privilege on Windows")
+	}
+
+	tests := []struct {
+		name    string
+		entries []zipEntry
+	}{
+		{"parent", []zipEntry{{name: "link", target: "../outside"}}},
+		{"nested parent", []zipEntry{{name: "a/link", target: "../../etc/passwd"}}},
+		{"absolute", []zipEntry{{name: "link", target: "/etc/passwd"}}},
+		{"drive letter", []zipEntry{{name: "link", target: `C:\Windows\System32`}}},
+		{"backslashes", []zipEntry{{name: "link", target: `..\..\outside`}}},
+		{"climb after folder", []zipEntry{{name: "link", target: "a/../../outside"}}},
+		// x is the output folder itself, so x/y climbs out although x/../z looks inside
+		{"through link", []zipEntry{{name: "x", target: "."}, {name: "x/y", target: "../z"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			outputDir := filepath.Join(root, "out")
+			err := Unpack(packZipEntries(t, tt.entries...), outputDir, WithAllowSymlinks())
+			require.ErrorIs(t, err, ErrPathTraversal)
+			entries, err := os.ReadDir(root)
+			require.NoError(t, err)
+			assert.Len(t, entries, 1, "nothing is created next to the output folder")
+		})
+	}
+}
+
+func TestUnpackRejectsDotDotComponents(t *testing.T) {
+	for _, name := range []string{"a/../setup.exe", `a\..\setup.exe`, "a/b/../../../evil.exe"} {
+		t.Run(name, func(t *testing.T) {
+			err := Unpack(packZipEntries(t, zipEntry{name: name}), filepath.Join(t.TempDir(), "out"))
+			assert.ErrorIs(t, err, ErrPathTraversal)
+		})
+	}
+}
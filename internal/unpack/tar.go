@@ -0,0 +1,140 @@
+package unpack
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/kenchan0130/intunewin/internal/pathutil"
+	"github.com/kenchan0130/intunewin/internal/spill"
+)
+
+// ErrNotArchive is returned when decrypted content that is not an archive is requested as a tar stream
+var ErrNotArchive = errors.New("decrypted content is not an archive")
+
+// UnpackToTar decrypts an intunewin file and writes its content to w as a tar archive.
+// A zip content is converted entry by entry, and tar content is written as is.
+func UnpackToTar(inputFile string, w io.Writer, opts ...Option) error {
+	o := newOptions(opts)
+
+	content, contentSize, err := decryptToBuffer(inputFile, o)
+	if err != nil {
+		return fmt.Errorf("failed to unpack: %w", err)
+	}
+	defer content.Close()
+
+	return writeTar(content, contentSize, w, o)
+}
+
+// UnpackReaderToTar is like UnpackToTar but reads the intunewin package from input
+func UnpackReaderToTar(input io.Reader, w io.Writer, opts ...Option) error {
+	o := newOptions(opts)
+
+	inputBuf, zipReader, err := bufferPackage(input, o)
+	if err != nil {
+		return fmt.Errorf("failed to unpack: %w", err)
+	}
+	defer inputBuf.Close()
+
+	content, contentSize, err := decryptZipToBuffer(zipReader, o)
+	if err != nil {
+		return fmt.Errorf("failed to unpack: %w", err)
+	}
+	defer content.Close()
+
+	return writeTar(content, contentSize, w, o)
+}
+
+// writeTar writes the decrypted content of contentSize bytes to w as a tar archive
+func writeTar(content *spill.Buffer, contentSize int64, w io.Writer, o *Options) error {
+	section := io.NewSectionReader(content, 0, contentSize)
+	format, err := detectFormat(section)
+	if err != nil {
+		return fmt.Errorf("failed to detect content format: %w", err)
+	}
+	o.Logger.Debug("detected content format", "format", format)
+
+	switch format {
+	case FormatZip:
+		return zipToTar(content, contentSize, w, o)
+	case FormatTar:
+		if _, err := io.Copy(w, section); err != nil {
+			return fmt.Errorf("failed to write tar: %w", err)
+		}
+		return nil
+	case FormatTarGzip:
+		gz, err := gzip.NewReader(section)
+		if err != nil {
+			return fmt.Errorf("failed to read gzip content: %w", err)
+		}
+		defer gz.Close()
+		if _, err := io.Copy(w, gz); err != nil { // #nosec G110 -- the output is a stream chosen by the caller
+			return fmt.Errorf("failed to write tar: %w", err)
+		}
+		return nil
+	default:
+		return fmt.Errorf("%w (detected %s)", ErrNotArchive, format)
+	}
+}
+
+// zipToTar converts the zip archive in r to a tar archive written to w.
+// Entry names are checked like when extracting, so the tar can be extracted safely.
+func zipToTar(r io.ReaderAt, size int64, w io.Writer, o *Options) error {
+	zipReader, err := zip.NewReader(r, size)
+	if err != nil {
+		return fmt.Errorf("failed to read zip: %w", err)
+	}
+	if err := o.decodeNames(zipReader); err != nil {
+		return err
+	}
+
+	tarWriter := tar.NewWriter(w)
+	for _, file := range zipReader.File {
+		name, err := pathutil.Clean(file.Name)
+		if err != nil {
+			return &PathTraversalError{Name: file.Name, Err: err}
+		}
+		if name == "." {
+			continue
+		}
+
+		header := &tar.Header{
+			Name:    name,
+			Mode:    int64(entryMode(file).Perm()),
+			ModTime: file.Modified,
+		}
+		if file.FileInfo().IsDir() {
+			header.Typeflag = tar.TypeDir
+			header.Name += "/"
+			if err := tarWriter.WriteHeader(header); err != nil {
+				return fmt.Errorf("failed to write tar entry %s: %w", name, err)
+			}
+			continue
+		}
+
+		header.Typeflag = tar.TypeReg
+		header.Size = int64(file.UncompressedSize64) // #nosec G115 -- within int64 range for valid zip files
+		o.Logger.Debug("writing tar entry", "path", name, "size", header.Size)
+		if err := tarWriter.WriteHeader(header); err != nil {
+			return fmt.Errorf("failed to write tar entry %s: %w", name, err)
+		}
+		rc, err := file.Open()
+		if err != nil {
+			return fmt.Errorf("failed to open file %s: %w", file.Name, err)
+		}
+		// Decompression bomb protection: the tar writer rejects more data than the declared size
+		_, err = io.Copy(tarWriter, io.LimitReader(rc, header.Size+1)) // #nosec G110
+		rc.Close()
+		if err != nil {
+			return fmt.Errorf("failed to write tar entry %s: %w", name, err)
+		}
+	}
+
+	if err := tarWriter.Close(); err != nil {
+		return fmt.Errorf("failed to close tar writer: %w", err)
+	}
+	return nil
+}
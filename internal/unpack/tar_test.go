@@ -0,0 +1,77 @@
+package unpack
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kenchan0130/intunewin/internal/pack"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readTar(t *testing.T, data []byte) map[string]string {
+	t.Helper()
+	entries := map[string]string{}
+	tr := tar.NewReader(bytes.NewReader(data))
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return entries
+		}
+		require.NoError(t, err)
+		content, err := io.ReadAll(tr)
+		require.NoError(t, err)
+		entries[header.Name] = string(content)
+	}
+}
+
+func TestUnpackToTar(t *testing.T) {
+	sourceDir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(sourceDir, "bin"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(sourceDir, "bin", "setup.exe"), []byte("setup"), 0600))
+	packed := filepath.Join(t.TempDir(), "app.intunewin")
+	require.NoError(t, pack.Pack(sourceDir, packed))
+
+	var fromFile bytes.Buffer
+	require.NoError(t, UnpackToTar(packed, &fromFile))
+	assert.Equal(t, map[string]string{"bin/": "", "bin/setup.exe": "setup"}, readTar(t, fromFile.Bytes()))
+
+	data, err := os.ReadFile(packed)
+	require.NoError(t, err)
+	var fromReader bytes.Buffer
+	require.NoError(t, UnpackReaderToTar(bytes.NewReader(data), &fromReader))
+	assert.Equal(t, fromFile.Bytes(), fromReader.Bytes())
+}
+
+func TestUnpackToTarPassesTarContent(t *testing.T) {
+	content := new(bytes.Buffer)
+	tw := tar.NewWriter(content)
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "setup.exe", Typeflag: tar.TypeReg, Mode: 0644, Size: 5, ModTime: time.Unix(0, 0)}))
+	_, err := tw.Write([]byte("setup"))
+	require.NoError(t, err)
+	require.NoError(t, tw.Close())
+
+	var output bytes.Buffer
+	require.NoError(t, UnpackToTar(packContent(t, content.Bytes()), &output))
+	assert.Equal(t, content.Bytes(), output.Bytes())
+}
+
+func TestUnpackToTarErrors(t *testing.T) {
+	err := UnpackToTar(packContent(t, []byte("MSCF cabinet")), io.Discard)
+	require.ErrorIs(t, err, ErrNotArchive)
+
+	content := new(bytes.Buffer)
+	zw := zip.NewWriter(content)
+	_, err = zw.Create("../evil.dll")
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+	err = UnpackToTar(packContent(t, content.Bytes()), io.Discard)
+	assert.ErrorIs(t, err, ErrPathTraversal)
+}
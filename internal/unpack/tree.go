@@ -0,0 +1,81 @@
+package unpack
+
+import (
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/kenchan0130/intunewin/internal/pathutil"
+)
+
+// Node is a file or folder in the tree of the decrypted content of an intunewin file
+type Node struct {
+	Name string `json:"name"`
+	// Size is the size of the file, or the total size of the files below the folder
+	Size int64 `json:"size"`
+	// Files is the number of files below the folder
+	Files    int     `json:"files,omitempty"`
+	IsDir    bool    `json:"isDir"`
+	Children []*Node `json:"children,omitempty"`
+}
+
+// Tree arranges the entries returned by List below a root folder named ".", with the
+// total size and number of files of every folder. Folders only implied by the paths of
+// files are added, and entries whose names cannot be extracted safely are left out.
+// The children of a folder are sorted with folders first, then by name.
+func Tree(entries []Entry) *Node {
+	root := &Node{Name: ".", IsDir: true}
+	dirs := map[string]*Node{".": root}
+
+	var dirFor func(dir string) *Node
+	dirFor = func(dir string) *Node {
+		if node, ok := dirs[dir]; ok {
+			return node
+		}
+		node := &Node{Name: path.Base(dir), IsDir: true}
+		parent := dirFor(path.Dir(dir))
+		parent.Children = append(parent.Children, node)
+		dirs[dir] = node
+		return node
+	}
+
+	for _, entry := range entries {
+		name, err := pathutil.Clean(entry.Path)
+		if err != nil || name == "." {
+			continue
+		}
+		if entry.IsDir {
+			dirFor(name)
+			continue
+		}
+		parent := dirFor(path.Dir(name))
+		parent.Children = append(parent.Children, &Node{Name: path.Base(name), Size: entry.Size})
+		for dir := path.Dir(name); ; dir = path.Dir(dir) {
+			dirs[dir].Size += entry.Size
+			dirs[dir].Files++
+			if dir == "." {
+				break
+			}
+		}
+	}
+
+	root.sort()
+	return root
+}
+
+// sort orders the children of n and of its folders with folders first, then by name
+func (n *Node) sort() {
+	sort.Slice(n.Children, func(i, j int) bool {
+		a, b := n.Children[i], n.Children[j]
+		if a.IsDir != b.IsDir {
+			return a.IsDir
+		}
+		if la, lb := strings.ToLower(a.Name), strings.ToLower(b.Name); la != lb {
+			return la < lb
+		}
+		return a.Name < b.Name
+	})
+	for _, child := range n.Children {
+		child.sort()
+	}
+}
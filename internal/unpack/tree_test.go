@@ -0,0 +1,31 @@
+package unpack
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTree(t *testing.T) {
+	root := Tree([]Entry{
+		{Path: "setup.exe", Size: 100},
+		{Path: "Files/", IsDir: true},
+		{Path: "Files/b.txt", Size: 20},
+		{Path: "Files/sub/a.txt", Size: 5},
+		{Path: "empty/", IsDir: true},
+		{Path: "Config.ini", Size: 1},
+		{Path: "../escape.txt", Size: 1000},
+	})
+
+	assert.Equal(t, &Node{Name: ".", Size: 126, Files: 4, IsDir: true, Children: []*Node{
+		{Name: "empty", IsDir: true},
+		{Name: "Files", Size: 25, Files: 2, IsDir: true, Children: []*Node{
+			{Name: "sub", Size: 5, Files: 1, IsDir: true, Children: []*Node{
+				{Name: "a.txt", Size: 5},
+			}},
+			{Name: "b.txt", Size: 20},
+		}},
+		{Name: "Config.ini", Size: 1},
+		{Name: "setup.exe", Size: 100},
+	}}, root)
+}
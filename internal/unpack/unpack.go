@@ -3,78 +3,353 @@ package unpack
 import (
 	"archive/zip"
 	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"errors"
 	"fmt"
+	"hash"
 	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"strings"
 
+	"github.com/kenchan0130/intunewin/internal/buildinfo"
 	"github.com/kenchan0130/intunewin/internal/crypto"
 	"github.com/kenchan0130/intunewin/internal/metadata"
+	"github.com/kenchan0130/intunewin/internal/pathutil"
+	"github.com/kenchan0130/intunewin/internal/spill"
+)
+
+const (
+	detectionXMLPath = "IntuneWinPackage/Metadata/Detection.xml"
+	contentsPath     = "IntuneWinPackage/Contents/IntunePackage.intunewin"
+)
+
+var (
+	// ErrInputNotFound is returned when the intunewin file does not exist
+	ErrInputNotFound = errors.New("input file does not exist")
+	// ErrNotIntunewin is returned when the input is not a zip archive and so cannot be an intunewin package
+	ErrNotIntunewin = errors.New("not an intunewin package")
+	// ErrMetadataMissing is returned when the package has no Detection.xml
+	ErrMetadataMissing = errors.New("detection.xml not found in intunewin package")
+	// ErrContentsMissing is returned when the package has no encrypted contents
+	ErrContentsMissing = errors.New("encrypted contents not found in intunewin package")
+	// ErrInvalidMetadata is returned when Detection.xml cannot be parsed
+	ErrInvalidMetadata = errors.New("invalid Detection.xml")
+	// ErrUnsupportedVersion is returned in strict mode when Detection.xml has values this tool does not recognize
+	ErrUnsupportedVersion = errors.New("unsupported intunewin format version")
+	// ErrNonstandard is returned in strict mode when the package deviates from the layout of the Microsoft tool
+	ErrNonstandard = errors.New("nonstandard intunewin package")
+	// ErrNotZip is returned by UnpackReaderToZip when the decrypted content is not a zip archive
+	ErrNotZip = errors.New("decrypted content is not a zip archive")
+	// ErrSizeMismatch is returned when the decrypted content size differs from Detection.xml
+	ErrSizeMismatch = errors.New("content size does not match Detection.xml")
+	// ErrDigestMismatch is returned when the SHA256 digest of the decrypted content differs from Detection.xml
+	ErrDigestMismatch = errors.New("content digest does not match Detection.xml")
 )
 
 // UnpackReaderToZip extracts an intunewin package and returns a zip stream.
 // input should contain the intunewin package (zip format with encrypted contents).
 // Returns an io.Reader containing the decrypted zip archive.
-func UnpackReaderToZip(input io.Reader) (io.Reader, error) {
-	// Read all input data
-	inputData, err := io.ReadAll(input)
+func UnpackReaderToZip(input io.Reader, opts ...Option) (io.Reader, error) {
+	reader, _, err := UnpackReaderToZipWithInfo(input, opts...)
+	return reader, err
+}
+
+// UnpackReaderToZipWithInfo is like UnpackReaderToZip but also returns the parsed Detection.xml,
+// without the encryption info unless WithRetainKeys is given
+func UnpackReaderToZipWithInfo(input io.Reader, opts ...Option) (io.Reader, *metadata.ApplicationInfo, error) {
+	content, appInfo, err := decryptZipToMemory(input, newOptions(opts))
+	if err != nil {
+		return nil, nil, err
+	}
+	return bytes.NewReader(content), appInfo, nil
+}
+
+// UnpackReaderToFS is like UnpackReaderToZipWithInfo but returns the decrypted content as a
+// read-only file system held in memory, so its files can be read or served without writing
+// them to disk. Backslashes in entry names are read as slashes.
+func UnpackReaderToFS(input io.Reader, opts ...Option) (fs.FS, *metadata.ApplicationInfo, error) {
+	o := newOptions(opts)
+	content, appInfo, err := decryptZipToMemory(input, o)
+	if err != nil {
+		return nil, nil, err
+	}
+	zipReader, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %w", ErrNotZip, err)
+	}
+	if err := o.decodeNames(zipReader); err != nil {
+		return nil, nil, err
+	}
+	if err := o.checkZip(zipReader.File); err != nil {
+		return nil, nil, err
+	}
+	return zipReader, appInfo, nil
+}
+
+// decryptZipToMemory decrypts the intunewin package read from input into memory and
+// returns the content, which must be a zip archive, with the parsed Detection.xml
+func decryptZipToMemory(input io.Reader, o *Options) ([]byte, *metadata.ApplicationInfo, error) {
+	inputBuf, zipReader, err := bufferPackage(input, o)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read input: %w", err)
+		return nil, nil, err
 	}
+	defer inputBuf.Close()
+
+	return decryptPackageToMemory(zipReader, o, bufferContents)
+}
 
-	// Open as zip archive
-	zipReader, err := zip.NewReader(bytes.NewReader(inputData), int64(len(inputData)))
+// decryptPackageToMemory decrypts the opened intunewin package into memory, reading its
+// encrypted contents with open, and returns the content, which must be a zip archive
+func decryptPackageToMemory(zipReader *zip.Reader, o *Options, open contentsOpener) ([]byte, *metadata.ApplicationInfo, error) {
+	decryptedBuf := new(bytes.Buffer)
+	appInfo, err := decryptPackageWith(zipReader, decryptedBuf, o, open)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open intunewin package: %w", err)
+		return nil, nil, err
 	}
 
-	// Read metadata (Detection.xml) and encrypted contents
+	if format, err := detectFormat(bytes.NewReader(decryptedBuf.Bytes())); err == nil && format != FormatZip {
+		return nil, nil, fmt.Errorf("%w (detected %s)", ErrNotZip, format)
+	}
+	return decryptedBuf.Bytes(), appInfo, nil
+}
+
+// decryptPackage reads Detection.xml from the intunewin package and writes the
+// decrypted content zip to output
+func decryptPackage(zipReader *zip.Reader, output io.Writer, o *Options) (*metadata.ApplicationInfo, error) {
+	return decryptPackageWith(zipReader, output, o, bufferContents)
+}
+
+// contentsOpener returns the encrypted contents entry as a reader positioned at its start
+// that can seek back to it, for verifying the HMAC before decrypting
+type contentsOpener func(contents *zip.File, o *Options) (io.ReadSeekCloser, error)
+
+// decryptPackageWith is decryptPackage reading the encrypted contents with open
+func decryptPackageWith(zipReader *zip.Reader, output io.Writer, o *Options, open contentsOpener) (*metadata.ApplicationInfo, error) {
+	// Read metadata (Detection.xml) and find encrypted contents
 	var metaData []byte
-	var encryptedData []byte
+	var contents *zip.File
 
 	for _, file := range zipReader.File {
-		switch file.Name {
-		case "IntuneWinPackage/Metadata/Detection.xml":
+		name, err := o.entryName(file.Name)
+		if err != nil {
+			return nil, err
+		}
+		switch name {
+		case detectionXMLPath:
 			metaData, err = readZipFileFromReader(file)
 			if err != nil {
 				return nil, fmt.Errorf("failed to read Detection.xml: %w", err)
 			}
-		case "IntuneWinPackage/Contents/IntunePackage.intunewin":
-			encryptedData, err = readZipFileFromReader(file)
-			if err != nil {
-				return nil, fmt.Errorf("failed to read encrypted contents: %w", err)
-			}
+		case contentsPath:
+			contents = file
 		}
 	}
 
 	if metaData == nil {
-		return nil, fmt.Errorf("detection.xml not found in intunewin package")
+		return nil, ErrMetadataMissing
 	}
-	if encryptedData == nil {
-		return nil, fmt.Errorf("encrypted contents not found in intunewin package")
+	if contents == nil {
+		return nil, ErrContentsMissing
 	}
 
 	// Parse metadata (XML format)
-	appInfo, err := metadata.FromXMLBytes(metaData)
+	appInfo, err := o.parseMetadata(metaData)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse Detection.xml: %w", err)
+		return nil, err
+	}
+	if o.EncryptionInfo != nil {
+		appInfo.EncryptionInfo = o.EncryptionInfo
+	}
+	if appInfo.EncryptionInfo == nil {
+		return nil, fmt.Errorf("%w: encryption info is missing", ErrInvalidMetadata)
 	}
 
 	// Convert XML encryption info to crypto.EncryptionInfo
 	encInfo, err := appInfo.EncryptionInfo.ToEncryptionInfo()
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse encryption info: %w", err)
+		return nil, fmt.Errorf("%w: failed to parse encryption info: %w", ErrInvalidMetadata, err)
 	}
+	defer encInfo.Zero()
 
-	// Decrypt contents
-	encReader := bytes.NewReader(encryptedData)
-	decryptedBuf := new(bytes.Buffer)
-	if err := crypto.Decrypt(encReader, decryptedBuf, encInfo.EncryptionKey, encInfo.MacKey); err != nil {
+	o.Logger.Debug("decrypting contents", "name", appInfo.Name, "size", appInfo.UnencryptedContentSize)
+
+	if err := o.checkContents(contents); err != nil {
+		return nil, err
+	}
+
+	// Read the encrypted contents from a seekable source so the HMAC can be verified
+	// before decrypting without holding large contents in memory
+	encrypted, err := open(contents, o)
+	if err != nil {
+		return nil, err
+	}
+	defer encrypted.Close()
+
+	check := &contentCheck{digest: sha256.New()}
+	if err := crypto.Decrypt(encrypted, io.MultiWriter(output, check), encInfo.EncryptionKey, encInfo.MacKey); err != nil {
 		return nil, fmt.Errorf("failed to decrypt contents: %w", err)
 	}
+	if o.NoVerify {
+		o.Logger.Debug("skipping content size and digest verification")
+	} else if err := check.verify(appInfo, encInfo); err != nil {
+		return nil, err
+	}
+	if !o.RetainKeys {
+		return appInfo.WithoutEncryptionInfo(), nil
+	}
+	return appInfo, nil
+}
+
+// contentCheck counts and hashes the decrypted content to compare it with Detection.xml
+type contentCheck struct {
+	size   int64
+	digest hash.Hash
+}
+
+func (c *contentCheck) Write(p []byte) (int, error) {
+	c.size += int64(len(p))
+	return c.digest.Write(p) //nolint:wrapcheck // hashes never fail
+}
+
+// verify returns ErrSizeMismatch or ErrDigestMismatch when the content differs from
+// UnencryptedContentSize or FileDigest. A digest of an algorithm other than SHA256 is not checked.
+func (c *contentCheck) verify(appInfo *metadata.ApplicationInfo, encInfo *crypto.EncryptionInfo) error {
+	if c.size != appInfo.UnencryptedContentSize {
+		return fmt.Errorf("%w: got %d bytes, expected %d", ErrSizeMismatch, c.size, appInfo.UnencryptedContentSize)
+	}
+	if !strings.EqualFold(encInfo.FileDigestAlgorithm, metadata.KnownFileDigestAlgorithm) {
+		return nil
+	}
+	if !bytes.Equal(c.digest.Sum(nil), encInfo.FileDigest) {
+		return ErrDigestMismatch
+	}
+	return nil
+}
+
+// bufferContents is the contentsOpener copying the encrypted contents to a spill buffer
+func bufferContents(contents *zip.File, o *Options) (io.ReadSeekCloser, error) {
+	return readEncryptedContents(contents, o)
+}
+
+// readEncryptedContents copies the encrypted contents entry to a buffer positioned at its start
+func readEncryptedContents(contents *zip.File, o *Options) (*spill.Buffer, error) {
+	encReader, err := contents.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read encrypted contents: %w", err)
+	}
+	defer encReader.Close()
+
+	encrypted := o.newBuffer("intunewin-encrypted-*")
+	if _, err := io.Copy(encrypted, encReader); err != nil {
+		encrypted.Close()
+		return nil, fmt.Errorf("failed to read encrypted contents: %w", err)
+	}
+	if _, err := encrypted.Seek(0, io.SeekStart); err != nil {
+		encrypted.Close()
+		return nil, fmt.Errorf("failed to read encrypted contents: %w", err)
+	}
+	return encrypted, nil
+}
+
+// Decrypt writes the decrypted content of the intunewin file to output and returns its metadata,
+// without the encryption info unless WithRetainKeys is given.
+// The HMAC of the encrypted content is verified, and so are the size and digest of the
+// decrypted content unless WithNoVerify is given, but the content itself is not inspected.
+// Content is written to output before it is verified.
+func Decrypt(inputFile string, output io.Writer, opts ...Option) (*metadata.ApplicationInfo, error) {
+	o := newOptions(opts)
+
+	packageReader, err := zip.OpenReader(pathutil.Long(inputFile))
+	if err != nil {
+		return nil, openError(err)
+	}
+	defer packageReader.Close()
+
+	return decryptPackage(&packageReader.Reader, output, o)
+}
+
+// DecryptReader is like Decrypt but reads the intunewin package from input, such as
+// standard input
+func DecryptReader(input io.Reader, output io.Writer, opts ...Option) (*metadata.ApplicationInfo, error) {
+	o := newOptions(opts)
+
+	inputBuf, zipReader, err := bufferPackage(input, o)
+	if err != nil {
+		return nil, err
+	}
+	defer inputBuf.Close()
+
+	return decryptPackage(zipReader, output, o)
+}
+
+// ReadApplicationInfo reads Detection.xml from an intunewin file without decrypting its contents
+func ReadApplicationInfo(inputFile string, opts ...Option) (*metadata.ApplicationInfo, error) {
+	o := newOptions(opts)
+
+	zipReader, err := zip.OpenReader(pathutil.Long(inputFile))
+	if err != nil {
+		return nil, openError(err)
+	}
+	defer zipReader.Close()
+
+	for _, file := range zipReader.File {
+		name, err := o.entryName(file.Name)
+		if err != nil {
+			return nil, err
+		}
+		if name != detectionXMLPath {
+			continue
+		}
+		metaData, err := readZipFileFromReader(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read Detection.xml: %w", err)
+		}
+		return o.parseMetadata(metaData)
+	}
+
+	return nil, ErrMetadataMissing
+}
+
+// ReadBuildInfo reads the build information recorded at pack time from an intunewin file.
+// It returns nil without an error when the package has no build information.
+func ReadBuildInfo(inputFile string) (*buildinfo.Info, error) {
+	zipReader, err := zip.OpenReader(pathutil.Long(inputFile))
+	if err != nil {
+		return nil, openError(err)
+	}
+	defer zipReader.Close()
+
+	for _, file := range zipReader.File {
+		if file.Name != buildinfo.Path {
+			continue
+		}
+		data, err := readZipFileFromReader(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read build info: %w", err)
+		}
+		info, err := buildinfo.Parse(data)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidMetadata, err)
+		}
+		return info, nil
+	}
 
-	return bytes.NewReader(decryptedBuf.Bytes()), nil
+	return nil, nil
+}
+
+// openError describes a failure to open an intunewin package as a zip archive
+func openError(err error) error {
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return fmt.Errorf("%w: %w", ErrInputNotFound, err)
+	case errors.Is(err, zip.ErrFormat):
+		return fmt.Errorf("%w: %w", ErrNotIntunewin, err)
+	default:
+		return fmt.Errorf("failed to open intunewin package: %w", err)
+	}
 }
 
 // readZipFileFromReader reads a file from a zip.File
@@ -92,92 +367,140 @@ func readZipFileFromReader(file *zip.File) ([]byte, error) {
 	return data, nil
 }
 
-// Unpack extracts an intunewin file to a folder
-func Unpack(inputFile, outputFolder string) error {
-	// Check if input file exists
-	if _, err := os.Stat(inputFile); err != nil {
-		if os.IsNotExist(err) {
-			return fmt.Errorf("input file does not exist: %s", inputFile)
-		}
-		return fmt.Errorf("failed to access input file: %w", err)
+// Unpack extracts an intunewin file to a folder.
+// The decrypted content is normally a zip archive; tar archives are extracted as well,
+// and any other payload is written to the folder as a single file.
+func Unpack(inputFile, outputFolder string, opts ...Option) error {
+	o := newOptions(opts)
+	if err := o.checkOnly(); err != nil {
+		return err
 	}
 
-	// Read input file
-	inputData, err := os.ReadFile(inputFile)
+	content, contentSize, err := decryptToBuffer(inputFile, o)
 	if err != nil {
-		return fmt.Errorf("failed to read input file: %w", err)
+		return fmt.Errorf("failed to unpack: %w", err)
+	}
+	defer content.Close()
+
+	return extractContent(content, contentSize, outputFolder, o)
+}
+
+// UnpackReader is like Unpack but reads the intunewin package from input, such as
+// standard input. The package is buffered, spilling to a temporary file when large.
+func UnpackReader(input io.Reader, outputFolder string, opts ...Option) error {
+	o := newOptions(opts)
+	if err := o.checkOnly(); err != nil {
+		return err
 	}
 
-	// Use UnpackReaderToZip to get zip stream
-	zipReader, err := UnpackReaderToZip(bytes.NewReader(inputData))
+	inputBuf, zipReader, err := bufferPackage(input, o)
 	if err != nil {
 		return fmt.Errorf("failed to unpack: %w", err)
 	}
+	defer inputBuf.Close()
 
-	// Read zip data
-	zipData, err := io.ReadAll(zipReader)
+	content, contentSize, err := decryptZipToBuffer(zipReader, o)
 	if err != nil {
-		return fmt.Errorf("failed to read zip data: %w", err)
+		return fmt.Errorf("failed to unpack: %w", err)
 	}
+	defer content.Close()
 
-	// Parse zip
-	zipBytesReader := bytes.NewReader(zipData)
-	zipContentReader, err := zip.NewReader(zipBytesReader, int64(len(zipData)))
+	return extractContent(content, contentSize, outputFolder, o)
+}
+
+// extractContent extracts the decrypted content of contentSize bytes to outputFolder
+func extractContent(content io.ReaderAt, contentSize int64, outputFolder string, o *Options) error {
+	format, err := detectFormat(io.NewSectionReader(content, 0, contentSize))
 	if err != nil {
-		return fmt.Errorf("failed to read zip: %w", err)
+		return fmt.Errorf("failed to detect content format: %w", err)
 	}
+	o.Logger.Debug("detected content format", "format", format)
 
 	// Create output directory
-	if err := os.MkdirAll(outputFolder, 0755); err != nil {
+	if err := os.MkdirAll(pathutil.Long(outputFolder), 0755); err != nil {
 		return fmt.Errorf("failed to create output directory: %w", err)
 	}
 
-	// Extract files
-	for _, file := range zipContentReader.File {
-		// #nosec G305 -- Path traversal check is performed below
-		destPath := filepath.Join(outputFolder, file.Name)
+	switch format {
+	case FormatZip:
+		return extractZip(content, contentSize, outputFolder, o)
+	case FormatTar:
+		return extractTar(io.NewSectionReader(content, 0, contentSize), outputFolder, o)
+	case FormatTarGzip:
+		gz, err := gzip.NewReader(io.NewSectionReader(content, 0, contentSize))
+		if err != nil {
+			return fmt.Errorf("failed to read gzip content: %w", err)
+		}
+		defer gz.Close()
+		return extractTar(gz, outputFolder, o)
+	default:
+		name := rawContentName + format.Extension()
+		only := &selection{o: o}
+		if !only.include(name) {
+			return only.check()
+		}
+		if err := (&usage{limits: o.Limits}).add(name, contentSize); err != nil {
+			return err
+		}
+		o.Logger.Warn("decrypted content is not an archive, writing it as a single file",
+			"format", format, "file", name)
+		_, err := writeFile(filepath.Join(outputFolder, name), io.NewSectionReader(content, 0, contentSize), defaultFileMode, contentSize, o)
+		return err
+	}
+}
 
-		// Check for directory traversal
-		cleanOutput := filepath.Clean(outputFolder) + string(os.PathSeparator)
-		if !strings.HasPrefix(destPath, cleanOutput) {
-			return fmt.Errorf("invalid file path: %s", file.Name)
+// decryptToBuffer decrypts the content of an intunewin file to a buffer that spills
+// to a temporary file above the configured threshold, and returns it with the content size.
+// The caller must close the buffer.
+func decryptToBuffer(inputFile string, o *Options) (*spill.Buffer, int64, error) {
+	// Check if input file exists
+	if _, err := os.Stat(pathutil.Long(inputFile)); err != nil {
+		if os.IsNotExist(err) {
+			return nil, 0, fmt.Errorf("%w: %s", ErrInputNotFound, inputFile)
 		}
+		return nil, 0, fmt.Errorf("failed to access input file: %w", err)
+	}
 
-		if file.FileInfo().IsDir() {
-			// Create directory
-			if err := os.MkdirAll(destPath, file.Mode()); err != nil {
-				return fmt.Errorf("failed to create directory %s: %w", file.Name, err)
-			}
-		} else {
-			// Create parent directories
-			if err := os.MkdirAll(filepath.Dir(destPath), 0755); err != nil {
-				return fmt.Errorf("failed to create parent directory for %s: %w", file.Name, err)
-			}
+	packageReader, err := zip.OpenReader(pathutil.Long(inputFile))
+	if err != nil {
+		return nil, 0, openError(err)
+	}
+	defer packageReader.Close()
 
-			// Write file
-			destFile, err := os.OpenFile(destPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, file.Mode())
-			if err != nil {
-				return fmt.Errorf("failed to create file %s: %w", file.Name, err)
-			}
+	return decryptZipToBuffer(&packageReader.Reader, o)
+}
 
-			rc, err := file.Open()
-			if err != nil {
-				destFile.Close()
-				return fmt.Errorf("failed to open file %s: %w", file.Name, err)
-			}
+// decryptZipToBuffer is like decryptToBuffer for an opened intunewin package
+func decryptZipToBuffer(zipReader *zip.Reader, o *Options) (*spill.Buffer, int64, error) {
+	content := o.newBuffer("intunewin-content-*")
+	if _, err := decryptPackage(zipReader, content, o); err != nil {
+		content.Close()
+		return nil, 0, err
+	}
 
-			// Decompression bomb protection: limit read size to uncompressed size
-			// UncompressedSize64 is within int64 range for valid zip files
-			limitedReader := io.LimitReader(rc, int64(file.UncompressedSize64)+1) // #nosec G110 G115
-			if _, err := io.Copy(destFile, limitedReader); err != nil {
-				rc.Close()
-				destFile.Close()
-				return fmt.Errorf("failed to write file %s: %w", file.Name, err)
-			}
-			rc.Close()
-			destFile.Close()
-		}
+	contentSize, err := content.Size()
+	if err != nil {
+		content.Close()
+		return nil, 0, fmt.Errorf("failed to read decrypted content: %w", err)
 	}
+	return content, contentSize, nil
+}
 
-	return nil
+// bufferPackage copies the intunewin package in input to a buffer that spills to a
+// temporary file above the configured threshold and opens it as a zip archive.
+// The caller must close the buffer.
+func bufferPackage(input io.Reader, o *Options) (*spill.Buffer, *zip.Reader, error) {
+	inputBuf := o.newBuffer("intunewin-package-*")
+	inputSize, err := io.Copy(inputBuf, input)
+	if err != nil {
+		inputBuf.Close()
+		return nil, nil, fmt.Errorf("failed to read input: %w", err)
+	}
+
+	zipReader, err := zip.NewReader(inputBuf, inputSize)
+	if err != nil {
+		inputBuf.Close()
+		return nil, nil, openError(err)
+	}
+	return inputBuf, zipReader, nil
 }
@@ -1,10 +1,18 @@
 package unpack
 
 import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
+	"github.com/kenchan0130/intunewin/internal/buildinfo"
+	"github.com/kenchan0130/intunewin/internal/crypto"
+	"github.com/kenchan0130/intunewin/internal/metadata"
 	"github.com/kenchan0130/intunewin/internal/pack"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -51,8 +59,7 @@ func TestUnpackNonExistentFile(t *testing.T) {
 	outputDir := filepath.Join(tempDir, "output")
 
 	err := Unpack(inputFile, outputDir)
-	assert.Error(t, err)
-	assert.Contains(t, err.Error(), "does not exist")
+	assert.ErrorIs(t, err, ErrInputNotFound)
 }
 
 func TestUnpackInvalidFile(t *testing.T) {
@@ -64,5 +71,391 @@ func TestUnpackInvalidFile(t *testing.T) {
 	require.NoError(t, os.WriteFile(inputFile, []byte("not a valid intunewin file"), 0600))
 
 	err := Unpack(inputFile, outputDir)
-	assert.Error(t, err)
+	assert.ErrorIs(t, err, ErrNotIntunewin)
+}
+
+func TestReadApplicationInfo(t *testing.T) {
+	tempDir := t.TempDir()
+	sourceDir := filepath.Join(tempDir, "source")
+	packedFile := filepath.Join(tempDir, "test.intunewin")
+
+	require.NoError(t, os.MkdirAll(sourceDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(sourceDir, "setup.exe"), []byte("setup"), 0600))
+	require.NoError(t, pack.PackWithInfo(sourceDir, packedFile, "MyApp", "setup.exe"))
+
+	appInfo, err := ReadApplicationInfo(packedFile)
+	require.NoError(t, err)
+	assert.Equal(t, "MyApp", appInfo.Name)
+	assert.Equal(t, "setup.exe", appInfo.SetupFile)
+	assert.Equal(t, "IntunePackage.intunewin", appInfo.FileName)
+}
+
+func TestUnpackLogger(t *testing.T) {
+	tempDir := t.TempDir()
+	sourceDir := filepath.Join(tempDir, "source")
+	packedFile := filepath.Join(tempDir, "test.intunewin")
+	require.NoError(t, os.MkdirAll(sourceDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(sourceDir, "setup.exe"), []byte("setup"), 0600))
+	require.NoError(t, pack.Pack(sourceDir, packedFile))
+
+	logs := new(bytes.Buffer)
+	logger := slog.New(slog.NewTextHandler(logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	require.NoError(t, Unpack(packedFile, filepath.Join(tempDir, "extracted"), WithLogger(logger)))
+
+	assert.Contains(t, logs.String(), `msg="decrypting contents" name=source`)
+	assert.Contains(t, logs.String(), `msg="extracting file" path=setup.exe size=5`)
+}
+
+func TestUnpackErrors(t *testing.T) {
+	zipWith := func(t *testing.T, names ...string) []byte {
+		t.Helper()
+		buf := new(bytes.Buffer)
+		zw := zip.NewWriter(buf)
+		for _, name := range names {
+			w, err := zw.Create(name)
+			require.NoError(t, err)
+			_, err = w.Write([]byte("<ApplicationInfo></ApplicationInfo>"))
+			require.NoError(t, err)
+		}
+		require.NoError(t, zw.Close())
+		return buf.Bytes()
+	}
+
+	tests := []struct {
+		name    string
+		input   func(t *testing.T) string
+		wantErr error
+	}{
+		{
+			name: "Missing Detection.xml",
+			input: func(t *testing.T) string {
+				return writeTemp(t, zipWith(t, contentsPath))
+			},
+			wantErr: ErrMetadataMissing,
+		},
+		{
+			name: "Missing contents",
+			input: func(t *testing.T) string {
+				return writeTemp(t, zipWith(t, detectionXMLPath))
+			},
+			wantErr: ErrContentsMissing,
+		},
+		{
+			name: "Tampered contents",
+			input: func(t *testing.T) string {
+				packed, err := os.ReadFile(packContent(t, zipWith(t, "setup.exe")))
+				require.NoError(t, err)
+				return writeTemp(t, tamperContents(t, packed))
+			},
+			wantErr: crypto.ErrHMACMismatch,
+		},
+		{
+			name: "Path traversal",
+			input: func(t *testing.T) string {
+				return packContent(t, zipWith(t, "../evil.txt"))
+			},
+			wantErr: ErrPathTraversal,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Unpack(tt.input(t), filepath.Join(t.TempDir(), "out"))
+			assert.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestPathTraversalErrorAs(t *testing.T) {
+	_, err := safeJoin(t.TempDir(), "../../etc/passwd")
+
+	var traversal *PathTraversalError
+	require.ErrorAs(t, err, &traversal)
+	assert.Equal(t, "../../etc/passwd", traversal.Name)
+}
+
+func TestUnpackBackslashNames(t *testing.T) {
+	// Zip tools on Windows sometimes store backslashes as separators
+	content := new(bytes.Buffer)
+	zw := zip.NewWriter(content)
+	w, err := zw.Create(`bin\setup.exe`)
+	require.NoError(t, err)
+	_, err = w.Write([]byte("setup"))
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+
+	outputDir := filepath.Join(t.TempDir(), "out")
+	require.NoError(t, Unpack(packContent(t, content.Bytes()), outputDir))
+
+	data, err := os.ReadFile(filepath.Join(outputDir, "bin", "setup.exe"))
+	require.NoError(t, err)
+	assert.Equal(t, "setup", string(data))
+}
+
+func TestUnpackRejectsWindowsAbsoluteNames(t *testing.T) {
+	for _, name := range []string{`C:\Windows\evil.dll`, `\\server\share\evil.dll`, `..\evil.dll`} {
+		t.Run(name, func(t *testing.T) {
+			content := new(bytes.Buffer)
+			zw := zip.NewWriter(content)
+			_, err := zw.Create(name)
+			require.NoError(t, err)
+			require.NoError(t, zw.Close())
+
+			err = Unpack(packContent(t, content.Bytes()), filepath.Join(t.TempDir(), "out"))
+			assert.ErrorIs(t, err, ErrPathTraversal)
+		})
+	}
+}
+
+// writeTemp writes data to a temporary intunewin file
+func writeTemp(t *testing.T, data []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "input.intunewin")
+	require.NoError(t, os.WriteFile(path, data, 0600))
+	return path
+}
+
+// tamperContents flips a byte of the encrypted contents of an intunewin package
+func tamperContents(t *testing.T, packed []byte) []byte {
+	t.Helper()
+	zr, err := zip.NewReader(bytes.NewReader(packed), int64(len(packed)))
+	require.NoError(t, err)
+
+	buf := new(bytes.Buffer)
+	zw := zip.NewWriter(buf)
+	for _, f := range zr.File {
+		data, err := readZipFileFromReader(f)
+		require.NoError(t, err)
+		if f.Name == contentsPath {
+			data[len(data)-1] ^= 0xFF
+		}
+		w, err := zw.Create(f.Name)
+		require.NoError(t, err)
+		_, err = w.Write(data)
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+func TestReadBuildInfo(t *testing.T) {
+	tempDir := t.TempDir()
+	sourceDir := filepath.Join(tempDir, "source")
+	require.NoError(t, os.MkdirAll(sourceDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(sourceDir, "setup.exe"), []byte("setup"), 0600))
+
+	withoutInfo := filepath.Join(tempDir, "plain.intunewin")
+	require.NoError(t, pack.Pack(sourceDir, withoutInfo))
+	info, err := ReadBuildInfo(withoutInfo)
+	require.NoError(t, err)
+	assert.Nil(t, info)
+
+	withInfo := filepath.Join(tempDir, "info.intunewin")
+	require.NoError(t, pack.Pack(sourceDir, withInfo, pack.WithBuildInfo(buildinfo.New("1.2.3", false))))
+	info, err = ReadBuildInfo(withInfo)
+	require.NoError(t, err)
+	require.NotNil(t, info)
+	assert.Equal(t, "1.2.3", info.ToolVersion)
+}
+
+func TestExtractMetadata(t *testing.T) {
+	tempDir := t.TempDir()
+	sourceDir := filepath.Join(tempDir, "source")
+	packedFile := filepath.Join(tempDir, "test.intunewin")
+	outputDir := filepath.Join(tempDir, "metadata")
+	require.NoError(t, os.MkdirAll(sourceDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(sourceDir, "setup.exe"), []byte("setup"), 0600))
+	require.NoError(t, pack.PackWithInfo(sourceDir, packedFile, "MyApp", "setup.exe"))
+
+	require.NoError(t, ExtractMetadata(packedFile, outputDir))
+
+	entries, err := os.ReadDir(outputDir)
+	require.NoError(t, err)
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	assert.Equal(t, []string{"Detection.json", "Detection.xml"}, names)
+
+	xmlData, err := os.ReadFile(filepath.Join(outputDir, "Detection.xml"))
+	require.NoError(t, err)
+	assert.Contains(t, string(xmlData), "<Name>MyApp</Name>")
+
+	jsonData, err := os.ReadFile(filepath.Join(outputDir, "Detection.json"))
+	require.NoError(t, err)
+	var decoded map[string]any
+	require.NoError(t, json.Unmarshal(jsonData, &decoded))
+	assert.Equal(t, "MyApp", decoded["name"])
+	assert.Equal(t, "setup.exe", decoded["setupFile"])
+	assert.NotContains(t, decoded, "XMLName")
+}
+
+func TestUnpackUnknownToolVersion(t *testing.T) {
+	tempDir := t.TempDir()
+	sourceDir := filepath.Join(tempDir, "source")
+	require.NoError(t, os.MkdirAll(sourceDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(sourceDir, "setup.exe"), []byte("setup"), 0600))
+	packedFile := filepath.Join(tempDir, "test.intunewin")
+	require.NoError(t, pack.Pack(sourceDir, packedFile))
+	rewriteDetectionXML(t, packedFile, func(appInfo *metadata.ApplicationInfo) {
+		appInfo.ToolVersion = "2.0.0.0"
+	})
+
+	logs := new(bytes.Buffer)
+	logger := slog.New(slog.NewTextHandler(logs, nil))
+	require.NoError(t, Unpack(packedFile, filepath.Join(tempDir, "lenient"), WithLogger(logger)))
+	assert.Contains(t, logs.String(), `level=WARN msg="unrecognized Detection.xml value, continuing best-effort" field=ToolVersion value=2.0.0.0 expected=1.x`)
+
+	err := Unpack(packedFile, filepath.Join(tempDir, "strict"), WithStrict())
+	assert.ErrorIs(t, err, ErrUnsupportedVersion)
+
+	_, err = ReadApplicationInfo(packedFile, WithStrict())
+	assert.ErrorIs(t, err, ErrUnsupportedVersion)
+}
+
+func TestUnpackNonstandardPackage(t *testing.T) {
+	tempDir := t.TempDir()
+	sourceDir := filepath.Join(tempDir, "source")
+	require.NoError(t, os.MkdirAll(sourceDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(sourceDir, "setup.exe"), []byte("setup"), 0600))
+	packedFile := filepath.Join(tempDir, "test.intunewin")
+	require.NoError(t, pack.Pack(sourceDir, packedFile))
+
+	packed, err := os.ReadFile(packedFile)
+	require.NoError(t, err)
+	zr, err := zip.NewReader(bytes.NewReader(packed), int64(len(packed)))
+	require.NoError(t, err)
+	buf := new(bytes.Buffer)
+	zw := zip.NewWriter(buf)
+	for _, f := range zr.File {
+		data, err := readZipFileFromReader(f)
+		require.NoError(t, err)
+		name := f.Name
+		switch name {
+		case detectionXMLPath:
+			name = `IntuneWinPackage\Metadata\detection.xml`
+			data = bytes.Replace(data, []byte("<Name>"), []byte("<Vendor>Contoso</Vendor><name>"), 1)
+			data = bytes.Replace(data, []byte("</Name>"), []byte("</name>"), 1)
+		case contentsPath:
+			name = strings.ToLower(name)
+		}
+		w, err := zw.Create(name)
+		require.NoError(t, err)
+		_, err = w.Write(data)
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+	require.NoError(t, os.WriteFile(packedFile, buf.Bytes(), 0600))
+
+	logs := new(bytes.Buffer)
+	logger := slog.New(slog.NewTextHandler(logs, nil))
+	require.NoError(t, Unpack(packedFile, filepath.Join(tempDir, "lenient"), WithLogger(logger)))
+	assert.FileExists(t, filepath.Join(tempDir, "lenient", "setup.exe"))
+	assert.Contains(t, logs.String(), `msg="nonstandard package entry, continuing best-effort"`)
+	assert.Contains(t, logs.String(), `field=element value=ApplicationInfo/Vendor`)
+	assert.Contains(t, logs.String(), `field=element value=ApplicationInfo/name expected=Name`)
+
+	appInfo, err := ReadApplicationInfo(packedFile)
+	require.NoError(t, err)
+	assert.Equal(t, "source", appInfo.Name)
+
+	err = Unpack(packedFile, filepath.Join(tempDir, "strict"), WithStrict())
+	assert.ErrorIs(t, err, ErrNonstandard)
+}
+
+func TestUnpackVerifiesContent(t *testing.T) {
+	tempDir := t.TempDir()
+	sourceDir := filepath.Join(tempDir, "source")
+	require.NoError(t, os.MkdirAll(sourceDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(sourceDir, "setup.exe"), []byte("setup"), 0600))
+	packedFile := filepath.Join(tempDir, "test.intunewin")
+	require.NoError(t, pack.Pack(sourceDir, packedFile))
+
+	rewriteDetectionXML(t, packedFile, func(appInfo *metadata.ApplicationInfo) {
+		appInfo.EncryptionInfo.FileDigest = appInfo.EncryptionInfo.Mac
+	})
+	assert.ErrorIs(t, Unpack(packedFile, filepath.Join(tempDir, "digest")), ErrDigestMismatch)
+
+	rewriteDetectionXML(t, packedFile, func(appInfo *metadata.ApplicationInfo) {
+		appInfo.UnencryptedContentSize++
+	})
+	_, err := Decrypt(packedFile, new(bytes.Buffer))
+	assert.ErrorIs(t, err, ErrSizeMismatch)
+
+	require.NoError(t, Unpack(packedFile, filepath.Join(tempDir, "unverified"), WithNoVerify()))
+	assert.FileExists(t, filepath.Join(tempDir, "unverified", "setup.exe"))
+}
+
+// rewriteDetectionXML modifies Detection.xml of the intunewin file at path
+func rewriteDetectionXML(t *testing.T, path string, modify func(*metadata.ApplicationInfo)) {
+	t.Helper()
+	packed, err := os.ReadFile(path)
+	require.NoError(t, err)
+	zr, err := zip.NewReader(bytes.NewReader(packed), int64(len(packed)))
+	require.NoError(t, err)
+
+	buf := new(bytes.Buffer)
+	zw := zip.NewWriter(buf)
+	for _, f := range zr.File {
+		data, err := readZipFileFromReader(f)
+		require.NoError(t, err)
+		if f.Name == detectionXMLPath {
+			appInfo, err := metadata.FromXMLBytes(data)
+			require.NoError(t, err)
+			modify(appInfo)
+			data, err = appInfo.ToXML()
+			require.NoError(t, err)
+		}
+		w, err := zw.Create(f.Name)
+		require.NoError(t, err)
+		_, err = w.Write(data)
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+	require.NoError(t, os.WriteFile(path, buf.Bytes(), 0600))
+}
+
+func TestUnpackWithEncryptionInfo(t *testing.T) {
+	tempDir := t.TempDir()
+	sourceDir := filepath.Join(tempDir, "source")
+	require.NoError(t, os.MkdirAll(sourceDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(sourceDir, "setup.exe"), []byte("setup"), 0600))
+	packedFile := filepath.Join(tempDir, "test.intunewin")
+	var summary pack.Summary
+	require.NoError(t, pack.Pack(sourceDir, packedFile,
+		pack.WithSummary(&summary), pack.WithRetainKeys(), pack.WithOmitEncryptionInfo()))
+
+	assert.ErrorIs(t, Unpack(packedFile, filepath.Join(tempDir, "nokeys")), ErrInvalidMetadata)
+
+	extractDir := filepath.Join(tempDir, "extracted")
+	require.NoError(t, Unpack(packedFile, extractDir, WithEncryptionInfo(summary.ApplicationInfo.EncryptionInfo)))
+	content, err := os.ReadFile(filepath.Join(extractDir, "setup.exe"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("setup"), content)
+}
+
+func TestUnpackReaderAndDecryptReader(t *testing.T) {
+	sourceDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(sourceDir, "setup.exe"), []byte("setup"), 0600))
+	packed := filepath.Join(t.TempDir(), "app.intunewin")
+	require.NoError(t, pack.Pack(sourceDir, packed))
+	data, err := os.ReadFile(packed)
+	require.NoError(t, err)
+
+	outputDir := filepath.Join(t.TempDir(), "out")
+	require.NoError(t, UnpackReader(bytes.NewReader(data), outputDir))
+	content, err := os.ReadFile(filepath.Join(outputDir, "setup.exe"))
+	require.NoError(t, err)
+	assert.Equal(t, "setup", string(content))
+
+	var fromFile, fromReader bytes.Buffer
+	_, err = Decrypt(packed, &fromFile)
+	require.NoError(t, err)
+	appInfo, err := DecryptReader(bytes.NewReader(data), &fromReader)
+	require.NoError(t, err)
+	assert.Equal(t, fromFile.Bytes(), fromReader.Bytes())
+	assert.Equal(t, filepath.Base(sourceDir), appInfo.Name)
+
+	err = UnpackReader(bytes.NewReader([]byte("not a package")), outputDir)
+	assert.ErrorIs(t, err, ErrNotIntunewin)
 }
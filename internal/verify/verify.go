@@ -0,0 +1,118 @@
+package verify
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/kenchan0130/intunewin/internal/unpack"
+)
+
+var (
+	// ErrSizeMismatch is returned when the decrypted content size differs from Detection.xml
+	ErrSizeMismatch = unpack.ErrSizeMismatch
+	// ErrDigestMismatch is returned when the SHA256 digest of the decrypted content differs from Detection.xml
+	ErrDigestMismatch = unpack.ErrDigestMismatch
+)
+
+// Result is the outcome of verifying a single package
+type Result struct {
+	Path string
+	// Err is nil when the package passed verification
+	Err error
+}
+
+// File verifies the intunewin file at path: Detection.xml and the optional build
+// information must be valid, the encrypted
+// content must pass HMAC verification, its size and digest must match Detection.xml,
+// and every entry of the content zip must be readable with a matching CRC
+func File(path string, opts ...unpack.Option) error {
+	content := unpack.NewBuffer("intunewin-verify-*", opts...)
+	defer content.Close()
+
+	digest := sha256.New()
+	appInfo, err := unpack.Decrypt(path, io.MultiWriter(content, digest), append(opts[:len(opts):len(opts)], unpack.WithRetainKeys())...)
+	if err != nil {
+		return err //nolint:wrapcheck // unpack errors already describe the failure
+	}
+
+	if err := appInfo.Validate(); err != nil {
+		return fmt.Errorf("%w: %w", unpack.ErrInvalidMetadata, err)
+	}
+	if _, err := unpack.ReadBuildInfo(path); err != nil {
+		return err //nolint:wrapcheck // unpack errors already describe the failure
+	}
+
+	size, err := content.Size()
+	if err != nil {
+		return fmt.Errorf("failed to read decrypted content: %w", err)
+	}
+	if size != appInfo.UnencryptedContentSize {
+		return fmt.Errorf("%w: got %d bytes, expected %d", ErrSizeMismatch, size, appInfo.UnencryptedContentSize)
+	}
+
+	// Validate has already checked that the encryption info decodes
+	encInfo, err := appInfo.EncryptionInfo.ToEncryptionInfo()
+	if err != nil {
+		return fmt.Errorf("%w: %w", unpack.ErrInvalidMetadata, err)
+	}
+	defer encInfo.Zero()
+	if !bytes.Equal(digest.Sum(nil), encInfo.FileDigest) {
+		return ErrDigestMismatch
+	}
+
+	return checkZip(content, size)
+}
+
+// checkZip reads every entry of the zip archive in r so that corrupt data and CRC mismatches are detected
+func checkZip(r io.ReaderAt, size int64) error {
+	zipReader, err := zip.NewReader(r, size)
+	if err != nil {
+		return fmt.Errorf("failed to read content zip: %w", err)
+	}
+
+	for _, file := range zipReader.File {
+		rc, err := file.Open()
+		if err != nil {
+			return fmt.Errorf("failed to open %s: %w", file.Name, err)
+		}
+		_, err = io.Copy(io.Discard, rc)
+		rc.Close()
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", file.Name, err)
+		}
+	}
+	return nil
+}
+
+// Files verifies paths concurrently using up to jobs workers and returns
+// the results in the same order as paths
+func Files(paths []string, jobs int, opts ...unpack.Option) []Result {
+	if jobs < 1 {
+		jobs = 1
+	}
+
+	results := make([]Result, len(paths))
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for range min(jobs, len(paths)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				results[i] = Result{Path: paths[i], Err: File(paths[i], opts...)}
+			}
+		}()
+	}
+
+	for i := range paths {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	return results
+}
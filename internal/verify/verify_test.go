@@ -0,0 +1,103 @@
+package verify
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kenchan0130/intunewin/internal/metadata"
+	"github.com/kenchan0130/intunewin/internal/pack"
+	"github.com/kenchan0130/intunewin/internal/unpack"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFile(t *testing.T) {
+	assert.NoError(t, File(packFixture(t, "setup.exe")))
+}
+
+func TestFileDigestMismatch(t *testing.T) {
+	path := packFixture(t, "setup.exe")
+	rewriteDetectionXML(t, path, func(appInfo *metadata.ApplicationInfo) {
+		appInfo.EncryptionInfo.FileDigest = appInfo.EncryptionInfo.Mac
+	})
+
+	assert.ErrorIs(t, File(path), ErrDigestMismatch)
+}
+
+func TestFileSizeMismatch(t *testing.T) {
+	path := packFixture(t, "setup.exe")
+	rewriteDetectionXML(t, path, func(appInfo *metadata.ApplicationInfo) {
+		appInfo.UnencryptedContentSize++
+	})
+
+	assert.ErrorIs(t, File(path), ErrSizeMismatch)
+}
+
+func TestFiles(t *testing.T) {
+	var paths []string
+	for i := range 5 {
+		paths = append(paths, packFixture(t, fmt.Sprintf("setup%d.exe", i)))
+	}
+	invalid := filepath.Join(t.TempDir(), "invalid.intunewin")
+	require.NoError(t, os.WriteFile(invalid, []byte("not a package"), 0600))
+	paths = append(paths, invalid)
+
+	results := Files(paths, 3)
+	require.Len(t, results, len(paths))
+	for i, result := range results {
+		assert.Equal(t, paths[i], result.Path)
+		if result.Path == invalid {
+			assert.ErrorIs(t, result.Err, unpack.ErrNotIntunewin)
+		} else {
+			assert.NoError(t, result.Err)
+		}
+	}
+}
+
+// packFixture packs a folder with a single file named name
+func packFixture(t *testing.T, name string) string {
+	t.Helper()
+	sourceDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(sourceDir, name), []byte("content of "+name), 0600))
+	path := filepath.Join(t.TempDir(), "app.intunewin")
+	require.NoError(t, pack.PackWithInfo(sourceDir, path, "app", name))
+	return path
+}
+
+// rewriteDetectionXML modifies Detection.xml of the intunewin file at path
+func rewriteDetectionXML(t *testing.T, path string, modify func(*metadata.ApplicationInfo)) {
+	t.Helper()
+	zr, err := zip.OpenReader(path)
+	require.NoError(t, err)
+
+	buf := new(bytes.Buffer)
+	zw := zip.NewWriter(buf)
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		require.NoError(t, err)
+		data := new(bytes.Buffer)
+		_, err = data.ReadFrom(rc)
+		require.NoError(t, err)
+		rc.Close()
+
+		content := data.Bytes()
+		if f.Name == "IntuneWinPackage/Metadata/Detection.xml" {
+			appInfo, err := metadata.FromXMLBytes(content)
+			require.NoError(t, err)
+			modify(appInfo)
+			content, err = appInfo.ToXML()
+			require.NoError(t, err)
+		}
+		w, err := zw.Create(f.Name)
+		require.NoError(t, err)
+		_, err = w.Write(content)
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+	require.NoError(t, zr.Close())
+	require.NoError(t, os.WriteFile(path, buf.Bytes(), 0600))
+}
@@ -0,0 +1,80 @@
+package watch
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// newWatcher returns a watcher of root and every folder below it
+func newWatcher(root string) (*fsnotify.Watcher, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create watcher: %w", err)
+	}
+	if err := addTree(watcher, root); err != nil {
+		watcher.Close()
+		return nil, err
+	}
+	return watcher, nil
+}
+
+// addTree watches dir and the folders below it; fsnotify does not watch recursively
+func addTree(watcher *fsnotify.Watcher, dir string) error {
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		return watcher.Add(path) //nolint:wrapcheck // wrapped below
+	})
+	if err != nil {
+		return fmt.Errorf("failed to watch %s: %w", dir, err)
+	}
+	return nil
+}
+
+// notify calls onChange once the events of watcher stopped for o.Debounce
+func notify(ctx context.Context, watcher *fsnotify.Watcher, onChange ChangeFunc, o *Options) error {
+	// quiet fires once no event arrived for the debounce period, nil while nothing changed
+	var quiet <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			o.Logger.Debug("source folder changed", "path", event.Name, "op", event.Op.String())
+			if event.Op&fsnotify.Create != 0 {
+				// Folders created, or moved in, after the watch started are not watched yet
+				if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+					if err := addTree(watcher, event.Name); err != nil {
+						o.Logger.Warn("failed to watch new folder", "error", err)
+					}
+				}
+			}
+			quiet = time.After(o.Debounce)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			// Events may have been dropped, such as on a queue overflow, so rebuild anyway
+			o.Logger.Warn("failed to watch source folder", "error", err)
+			quiet = time.After(o.Debounce)
+		case <-quiet:
+			quiet = nil
+			if err := onChange(ctx); err != nil {
+				return err
+			}
+		}
+	}
+}
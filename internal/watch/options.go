@@ -0,0 +1,72 @@
+package watch
+
+import (
+	"log/slog"
+	"time"
+)
+
+// DefaultInterval is how often the source folder is scanned for changes when polling
+const DefaultInterval = 500 * time.Millisecond
+
+// DefaultDebounce is how long the source folder must stay unchanged before a rebuild
+const DefaultDebounce = time.Second
+
+// Options holds settings for Watch
+type Options struct {
+	// Logger receives diagnostic messages
+	Logger *slog.Logger
+	// Polling scans the folder every Interval instead of being notified of changes
+	Polling bool
+	// Interval is the time between two scans of the folder when polling
+	Interval time.Duration
+	// Debounce is the quiet period after the last change before OnChange is called
+	Debounce time.Duration
+}
+
+// Option configures Options
+type Option func(*Options)
+
+// WithLogger sends diagnostic messages to logger
+func WithLogger(logger *slog.Logger) Option {
+	return func(o *Options) {
+		o.Logger = logger
+	}
+}
+
+// WithPolling scans the folder for changes instead of being notified of them by the
+// operating system, for file systems that do not deliver notifications such as network
+// shares and some container mounts
+func WithPolling() Option {
+	return func(o *Options) {
+		o.Polling = true
+	}
+}
+
+// WithInterval scans the folder every interval instead of DefaultInterval when polling
+func WithInterval(interval time.Duration) Option {
+	return func(o *Options) {
+		o.Interval = interval
+	}
+}
+
+// WithDebounce waits for the folder to stay unchanged for d instead of DefaultDebounce
+func WithDebounce(d time.Duration) Option {
+	return func(o *Options) {
+		o.Debounce = d
+	}
+}
+
+// newOptions applies opts over the defaults
+func newOptions(opts []Option) *Options {
+	o := &Options{
+		Interval: DefaultInterval,
+		Debounce: DefaultDebounce,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.Logger == nil {
+		o.Logger = slog.New(slog.DiscardHandler)
+	}
+	return o
+}
@@ -0,0 +1,113 @@
+// Package watch reports changes of a folder. Changes are delivered by the operating
+// system through fsnotify; polling scans the folder periodically instead, which works the
+// same on every platform and file system, including network shares and containers.
+package watch
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// fileState is what a scan records of a file to detect changes
+type fileState struct {
+	size    int64
+	modTime time.Time
+	mode    fs.FileMode
+}
+
+// snapshot is the state of every file and folder below a root, by relative path
+type snapshot map[string]fileState
+
+// ChangeFunc is called once the folder stopped changing. The watch stops when it
+// returns an error.
+type ChangeFunc func(ctx context.Context) error
+
+// Watch calls onChange when files below root were added, removed or modified and no
+// further change happened for the debounce period. Changes are reported by the operating
+// system unless WithPolling is given, or watching the folder is not supported, in which
+// case root is scanned every interval. It returns when ctx is done or onChange fails.
+func Watch(ctx context.Context, root string, onChange ChangeFunc, opts ...Option) error {
+	o := newOptions(opts)
+	if o.Interval <= 0 {
+		return fmt.Errorf("invalid interval %s", o.Interval)
+	}
+	if _, err := os.Stat(root); err != nil {
+		return fmt.Errorf("failed to watch %s: %w", root, err)
+	}
+
+	if !o.Polling {
+		watcher, err := newWatcher(root)
+		if err == nil {
+			defer watcher.Close()
+			return notify(ctx, watcher, onChange, o)
+		}
+		o.Logger.Warn("failed to watch source folder, scanning it instead", "error", err, "interval", o.Interval)
+	}
+	return poll(ctx, root, onChange, o)
+}
+
+// poll scans root every o.Interval and calls onChange once it stopped changing
+func poll(ctx context.Context, root string, onChange ChangeFunc, o *Options) error {
+	last, err := scan(root)
+	if err != nil {
+		return err
+	}
+	ticker := time.NewTicker(o.Interval)
+	defer ticker.Stop()
+
+	var changedAt time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case now := <-ticker.C:
+			current, err := scan(root)
+			if err != nil {
+				// The folder may be in the middle of a rename, so look again later
+				o.Logger.Warn("failed to scan source folder", "error", err)
+				continue
+			}
+			if !maps.Equal(last, current) {
+				o.Logger.Debug("source folder changed", "files", len(current))
+				last, changedAt = current, now
+				continue
+			}
+			if changedAt.IsZero() || now.Sub(changedAt) < o.Debounce {
+				continue
+			}
+			changedAt = time.Time{}
+			if err := onChange(ctx); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// scan records the state of every entry below root
+func scan(root string) (snapshot, error) {
+	s := snapshot{}
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err //nolint:wrapcheck // wrapped below
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err //nolint:wrapcheck // wrapped below
+		}
+		s[rel] = fileState{size: info.Size(), modTime: info.ModTime(), mode: info.Mode()}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan %s: %w", root, err)
+	}
+	return s, nil
+}
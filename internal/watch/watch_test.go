@@ -0,0 +1,88 @@
+package watch
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// startWatch watches root in the background and returns the number of onChange calls
+func startWatch(t *testing.T, root string, opts ...Option) *atomic.Int32 {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	var calls atomic.Int32
+	go func() {
+		done <- Watch(ctx, root, func(context.Context) error {
+			calls.Add(1)
+			return nil
+		}, append([]Option{WithInterval(10 * time.Millisecond), WithDebounce(50 * time.Millisecond)}, opts...)...)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		assert.NoError(t, <-done)
+	})
+	return &calls
+}
+
+func TestWatch(t *testing.T) {
+	testWatch(t)
+}
+
+func TestWatchPolling(t *testing.T) {
+	testWatch(t, WithPolling())
+}
+
+// testWatch checks that changes of a folder watched with opts are reported once settled
+func testWatch(t *testing.T, opts ...Option) {
+	t.Helper()
+	root := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(root, "setup.exe"), []byte("v1"), 0600))
+	calls := startWatch(t, root, opts...)
+
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, int32(0), calls.Load(), "an unchanged folder is not reported")
+
+	require.NoError(t, os.WriteFile(filepath.Join(root, "setup.exe"), []byte("v2 longer"), 0600))
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "sub"), 0750))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "sub", "a.txt"), []byte("a"), 0600))
+	assert.Eventually(t, func() bool { return calls.Load() == 1 }, 2*time.Second, 10*time.Millisecond)
+
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, int32(1), calls.Load(), "a burst of changes is reported once")
+
+	// Files in folders created after the watch started are watched as well
+	require.NoError(t, os.Remove(filepath.Join(root, "sub", "a.txt")))
+	assert.Eventually(t, func() bool { return calls.Load() == 2 }, 2*time.Second, 10*time.Millisecond)
+}
+
+func TestWatchStopsOnError(t *testing.T) {
+	root := t.TempDir()
+	errRebuild := errors.New("rebuild failed")
+	done := make(chan error, 1)
+	go func() {
+		done <- Watch(context.Background(), root, func(context.Context) error { return errRebuild },
+			WithInterval(10*time.Millisecond), WithDebounce(20*time.Millisecond))
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	require.NoError(t, os.WriteFile(filepath.Join(root, "setup.exe"), []byte("v1"), 0600))
+	select {
+	case err := <-done:
+		assert.ErrorIs(t, err, errRebuild)
+	case <-time.After(2 * time.Second):
+		t.Fatal("watch did not stop")
+	}
+}
+
+func TestWatchMissingFolder(t *testing.T) {
+	err := Watch(context.Background(), filepath.Join(t.TempDir(), "missing"), func(context.Context) error { return nil })
+	assert.Error(t, err)
+}
@@ -0,0 +1,73 @@
+package intunewin
+
+import (
+	"github.com/kenchan0130/intunewin/internal/crypto"
+	"github.com/kenchan0130/intunewin/internal/metadata"
+	"github.com/kenchan0130/intunewin/internal/pack"
+	"github.com/kenchan0130/intunewin/internal/unpack"
+)
+
+// Errors returned by the functions of this package. Check them with errors.Is.
+var (
+	// ErrSourceNotFound is returned when the source folder does not exist.
+	ErrSourceNotFound = pack.ErrSourceNotFound
+	// ErrSourceNotDirectory is returned when the source path is not a directory.
+	ErrSourceNotDirectory = pack.ErrSourceNotDirectory
+	// ErrSetupFileNotFound is returned when the declared setup file is not part of the packaged content.
+	ErrSetupFileNotFound = pack.ErrSetupFileNotFound
+	// ErrSetupFileAmbiguous is returned by DetectSetupFile when several files could be the setup file.
+	ErrSetupFileAmbiguous = pack.ErrSetupFileAmbiguous
+	// ErrSymlink is returned for a symbolic link in the source folder with SymlinkError.
+	ErrSymlink = pack.ErrSymlink
+	// ErrSymlinkLoop is returned when a followed symbolic link leads back to one of its parent folders.
+	ErrSymlinkLoop = pack.ErrSymlinkLoop
+	// ErrHookFailed is returned when a pack hook fails.
+	ErrHookFailed = pack.ErrHookFailed
+	// ErrInvalidToolVersion is returned when the version passed to WithToolVersion is not dotted numbers.
+	ErrInvalidToolVersion = metadata.ErrInvalidToolVersion
+	// ErrInvalidKeys is returned when key material passed to WithEncryptionKeys has the wrong size.
+	ErrInvalidKeys = crypto.ErrInvalidKeys
+	// ErrInputNotFound is returned when the intunewin file does not exist.
+	ErrInputNotFound = unpack.ErrInputNotFound
+	// ErrFileExists is returned with OverwriteFail when a file to extract already exists.
+	ErrFileExists = unpack.ErrFileExists
+	// ErrNoMatches is returned with WithOnly when no entry of the content matches the patterns.
+	ErrNoMatches = unpack.ErrNoMatches
+	// ErrSymlinkEntry is returned when unpacking content with a symbolic link without WithAllowSymlinks.
+	ErrSymlinkEntry = unpack.ErrSymlinkEntry
+	// ErrLimitExceeded is returned with WithUnpackLimits when the content of a package exceeds a limit.
+	ErrLimitExceeded = unpack.ErrLimitExceeded
+	// ErrNotIntunewin is returned when the input is not a zip archive and so cannot be an intunewin package.
+	ErrNotIntunewin = unpack.ErrNotIntunewin
+	// ErrMetadataMissing is returned when the package has no Detection.xml.
+	ErrMetadataMissing = unpack.ErrMetadataMissing
+	// ErrContentsMissing is returned when the package has no encrypted contents.
+	ErrContentsMissing = unpack.ErrContentsMissing
+	// ErrInvalidMetadata is returned when Detection.xml cannot be parsed.
+	ErrInvalidMetadata = unpack.ErrInvalidMetadata
+	// ErrHMACMismatch is returned when the encrypted contents are corrupt or do not match the MAC key.
+	ErrHMACMismatch = crypto.ErrHMACMismatch
+	// ErrInvalidPadding is returned when the contents cannot be decrypted with the encryption key.
+	ErrInvalidPadding = crypto.ErrInvalidPadding
+	// ErrInvalidLength is returned when the encrypted contents are not made of whole AES blocks.
+	ErrInvalidLength = crypto.ErrInvalidLength
+	// ErrUnsupportedVersion is returned with WithStrict when Detection.xml has values this library does not recognize.
+	ErrUnsupportedVersion = unpack.ErrUnsupportedVersion
+	// ErrNonstandard is returned with WithStrict when the package deviates from the layout of the Microsoft tool,
+	// such as entry or element names in a different case or extra elements in Detection.xml.
+	ErrNonstandard = unpack.ErrNonstandard
+	// ErrNotZip is returned by UnpackReader when the decrypted content is not a zip archive.
+	ErrNotZip = unpack.ErrNotZip
+	// ErrNotArchive is returned by UnpackToTar when the decrypted content is neither a zip nor a tar archive.
+	ErrNotArchive = unpack.ErrNotArchive
+	// ErrSizeMismatch is returned by Verify and when unpacking when the decrypted content size differs from Detection.xml.
+	ErrSizeMismatch = unpack.ErrSizeMismatch
+	// ErrDigestMismatch is returned by Verify and when unpacking when the decrypted content digest differs from Detection.xml.
+	ErrDigestMismatch = unpack.ErrDigestMismatch
+	// ErrPathTraversal is returned when an archive entry would be extracted outside the output folder.
+	// The error can also be inspected with errors.As as a *PathTraversalError.
+	ErrPathTraversal = unpack.ErrPathTraversal
+)
+
+// PathTraversalError reports an archive entry whose path escapes the output folder.
+type PathTraversalError = unpack.PathTraversalError
@@ -0,0 +1,18 @@
+package intunewin
+
+import (
+	"fmt"
+
+	"github.com/kenchan0130/intunewin/internal/icon"
+)
+
+// ExtractIcon extracts the main icon of a setup executable (or an .ico file) and returns it as PNG.
+// path: Path to an EXE, DLL or ICO file
+// Returns the PNG encoded icon and error if the file has no usable icon.
+func ExtractIcon(path string) ([]byte, error) {
+	data, err := icon.FromFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract icon: %w", err)
+	}
+	return data, nil
+}
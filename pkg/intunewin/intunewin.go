@@ -3,31 +3,164 @@ package intunewin
 import (
 	"fmt"
 	"io"
+	"io/fs"
 
 	"github.com/kenchan0130/intunewin/internal/pack"
+	"github.com/kenchan0130/intunewin/internal/repair"
 	"github.com/kenchan0130/intunewin/internal/unpack"
+	"github.com/kenchan0130/intunewin/internal/verify"
+	"github.com/kenchan0130/intunewin/pkg/metadata"
 )
 
 // PackReader creates an intunewin package from a zip stream.
 // zipReader: io.Reader containing a zip archive of files to pack
 // name: Application name for metadata
-// setupFile: Setup file name within the content file
+// setupFile: Setup file within the zip archive; ErrSetupFileNotFound is returned when it is missing
+// opts: Options such as WithReproducible or WithEncryptionKeys
 // Returns an io.Reader for the encrypted intunewin package and error if packing fails.
-func PackReader(zipReader io.Reader, name, setupFile string) (io.Reader, error) {
-	reader, err := pack.PackReaderFromZip(zipReader, name, setupFile)
+func PackReader(zipReader io.Reader, name, setupFile string, opts ...PackOption) (io.Reader, error) {
+	reader, err := pack.PackReaderFromZip(zipReader, name, setupFile, opts...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to pack reader: %w", err)
 	}
 	return reader, nil
 }
 
+// PackTo is like PackReader but writes the intunewin package to w instead of holding it in memory.
+func PackTo(zipReader io.Reader, w io.Writer, name, setupFile string, opts ...PackOption) error {
+	if err := pack.PackZipTo(zipReader, w, name, setupFile, opts...); err != nil {
+		return fmt.Errorf("failed to pack reader: %w", err)
+	}
+	return nil
+}
+
+// PackTarTo is like PackTo but reads a tar archive, which is converted to the content zip.
+// Only regular files and directories are packed.
+func PackTarTo(tarReader io.Reader, w io.Writer, name, setupFile string, opts ...PackOption) error {
+	if err := pack.PackTarTo(tarReader, w, name, setupFile, opts...); err != nil {
+		return fmt.Errorf("failed to pack tar: %w", err)
+	}
+	return nil
+}
+
+// DetectSetupFile returns the setup file in the top level of sourceFolder: its only .msi
+// installer or a program named setup.exe, install.exe or Deploy-Application.exe.
+// ErrSetupFileNotFound or ErrSetupFileAmbiguous is returned unless there is exactly one.
+func DetectSetupFile(sourceFolder string) (string, error) {
+	setupFile, err := pack.DetectSetupFile(sourceFolder)
+	if err != nil {
+		return "", fmt.Errorf("failed to detect setup file: %w", err)
+	}
+	return setupFile, nil
+}
+
+// Unpack extracts the intunewin file at inputFile to outputFolder, which is created when
+// missing. The decrypted content is normally a zip archive; tar archives are extracted as
+// well, and any other payload is written to the folder as a single file. File modes and
+// modification times are restored unless WithNoPreserve is given, and WithOnly,
+// WithOverwrite, WithFilenameEncoding, WithAllowSymlinks and WithUnpackLimits control which
+// entries are written and how.
+func Unpack(inputFile, outputFolder string, opts ...UnpackOption) error {
+	if err := unpack.Unpack(inputFile, outputFolder, opts...); err != nil {
+		return fmt.Errorf("failed to unpack %s: %w", inputFile, err)
+	}
+	return nil
+}
+
 // UnpackReader extracts an intunewin package and returns a zip stream.
 // input: io.Reader containing the intunewin package
+// opts: Options such as WithUnpackLogger
 // Returns an io.Reader containing the decrypted zip archive and error if unpacking fails.
-func UnpackReader(input io.Reader) (io.Reader, error) {
-	reader, err := unpack.UnpackReaderToZip(input)
+func UnpackReader(input io.Reader, opts ...UnpackOption) (io.Reader, error) {
+	reader, err := unpack.UnpackReaderToZip(input, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unpack reader: %w", err)
+	}
+	return reader, nil
+}
+
+// UnpackReaderWithInfo is like UnpackReader but also returns the parsed Detection.xml,
+// so the application name and setup file are available without reading the package twice.
+// The encryption info is left out unless WithUnpackRetainKeys is given.
+func UnpackReaderWithInfo(input io.Reader, opts ...UnpackOption) (io.Reader, *metadata.ApplicationInfo, error) {
+	reader, appInfo, err := unpack.UnpackReaderToZipWithInfo(input, opts...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to unpack reader: %w", err)
+	}
+	return reader, appInfo, nil
+}
+
+// UnpackReaderAt is like UnpackReader but reads the intunewin package of the given size from r,
+// such as an *os.File or a memory-mapped file. The package is read lazily and its encrypted
+// contents are streamed from r instead of being copied to a buffer first, so only the
+// decrypted zip archive is held in memory.
+func UnpackReaderAt(r io.ReaderAt, size int64, opts ...UnpackOption) (io.Reader, error) {
+	reader, _, err := unpack.UnpackReaderAtToZip(r, size, opts...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to unpack reader: %w", err)
 	}
 	return reader, nil
 }
+
+// UnpackReaderAtTo is like UnpackReaderAt but writes the decrypted content to w, so neither
+// the package nor its content is held in memory. The content is written as is, whatever its
+// format.
+func UnpackReaderAtTo(r io.ReaderAt, size int64, w io.Writer, opts ...UnpackOption) error {
+	if _, err := unpack.DecryptReaderAt(r, size, w, opts...); err != nil {
+		return fmt.Errorf("failed to unpack reader: %w", err)
+	}
+	return nil
+}
+
+// Open decrypts the intunewin package read from input and returns its content as a read-only
+// file system held in memory, with the parsed Detection.xml, so files can be inspected or
+// served (for example with http.FS) without writing them to disk. The encryption info is left
+// out unless WithUnpackRetainKeys is given; ErrNotZip is returned when the content is not a
+// zip archive.
+func Open(input io.Reader, opts ...UnpackOption) (fs.FS, *metadata.ApplicationInfo, error) {
+	fsys, appInfo, err := unpack.UnpackReaderToFS(input, opts...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open package: %w", err)
+	}
+	return fsys, appInfo, nil
+}
+
+// UnpackTo is like UnpackReader but writes the decrypted content to w instead of holding it
+// in memory. The content is written as is, whatever its format.
+func UnpackTo(input io.Reader, w io.Writer, opts ...UnpackOption) error {
+	if _, err := unpack.DecryptReader(input, w, opts...); err != nil {
+		return fmt.Errorf("failed to unpack reader: %w", err)
+	}
+	return nil
+}
+
+// UnpackToTar is like UnpackTo but writes the decrypted content to w as a tar archive.
+func UnpackToTar(input io.Reader, w io.Writer, opts ...UnpackOption) error {
+	if err := unpack.UnpackReaderToTar(input, w, opts...); err != nil {
+		return fmt.Errorf("failed to unpack reader: %w", err)
+	}
+	return nil
+}
+
+// Verify checks the integrity of the intunewin file at path.
+// Detection.xml must be valid, the encrypted contents must pass HMAC verification,
+// the decrypted size and SHA256 digest must match Detection.xml and every file in
+// the content zip must be readable.
+func Verify(path string) error {
+	if err := verify.File(path); err != nil {
+		return fmt.Errorf("failed to verify %s: %w", path, err)
+	}
+	return nil
+}
+
+// Repair reads an intunewin package whose Detection.xml has a wrong Mac, FileDigest or
+// UnencryptedContentSize (for example from a faulty generator) and writes a copy to w with
+// those fields recomputed from the encrypted contents, using the keys in Detection.xml.
+// The original source is not needed, but the contents must decrypt with those keys.
+// Every other entry of the package is copied unchanged.
+func Repair(r io.Reader, w io.Writer, opts ...UnpackOption) error {
+	if _, err := repair.Repair(r, w, opts...); err != nil {
+		return fmt.Errorf("failed to repair package: %w", err)
+	}
+	return nil
+}
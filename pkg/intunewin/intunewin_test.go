@@ -1,15 +1,19 @@
 package intunewin
 
 import (
+	"archive/tar"
 	"archive/zip"
 	"bytes"
+	"errors"
 	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"testing/fstest"
 
 	"github.com/kenchan0130/intunewin/internal/pack"
-	"github.com/kenchan0130/intunewin/internal/unpack"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -39,7 +43,7 @@ func TestPackAndUnpack(t *testing.T) {
 	assert.Greater(t, info.Size(), int64(0))
 
 	// Unpack
-	err = unpack.Unpack(packedFile, extractDir)
+	err = Unpack(packedFile, extractDir)
 	require.NoError(t, err)
 
 	// Verify extracted files
@@ -54,6 +58,25 @@ func TestPackAndUnpack(t *testing.T) {
 	assert.Equal(t, testContent2, content2)
 }
 
+func TestPackAndUnpackLongPaths(t *testing.T) {
+	tempDir := t.TempDir()
+	sourceDir := filepath.Join(tempDir, "source")
+	packedFile := filepath.Join(tempDir, "test.intunewin")
+	extractDir := filepath.Join(tempDir, "extracted")
+
+	// Longer than MAX_PATH (260) on Windows
+	nested := filepath.Join(strings.Repeat("nested-folder"+string(filepath.Separator), 25), "setup.exe")
+	require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(sourceDir, nested)), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(sourceDir, nested), []byte("setup"), 0600))
+
+	require.NoError(t, pack.Pack(sourceDir, packedFile))
+	require.NoError(t, Unpack(packedFile, extractDir))
+
+	content, err := os.ReadFile(filepath.Join(extractDir, nested))
+	require.NoError(t, err)
+	assert.Equal(t, "setup", string(content))
+}
+
 func TestPackWithNonExistentSource(t *testing.T) {
 	tempDir := t.TempDir()
 	sourceDir := filepath.Join(tempDir, "nonexistent")
@@ -69,11 +92,30 @@ func TestUnpackWithNonExistentFile(t *testing.T) {
 	inputFile := filepath.Join(tempDir, "nonexistent.intunewin")
 	outputDir := filepath.Join(tempDir, "output")
 
-	err := unpack.Unpack(inputFile, outputDir)
+	err := Unpack(inputFile, outputDir)
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "does not exist")
 }
 
+func TestUnpackOptions(t *testing.T) {
+	tempDir := t.TempDir()
+	sourceDir := filepath.Join(tempDir, "source")
+	packedFile := filepath.Join(tempDir, "test.intunewin")
+	extractDir := filepath.Join(tempDir, "extracted")
+
+	require.NoError(t, os.MkdirAll(filepath.Join(sourceDir, "Files"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(sourceDir, "Deploy-Application.ps1"), []byte("script"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(sourceDir, "Files", "setup.msi"), []byte("msi"), 0600))
+	require.NoError(t, pack.Pack(sourceDir, packedFile))
+
+	require.NoError(t, Unpack(packedFile, extractDir, WithOnly("*.ps1")))
+	assert.FileExists(t, filepath.Join(extractDir, "Deploy-Application.ps1"))
+	assert.NoDirExists(t, filepath.Join(extractDir, "Files"))
+
+	err := NewUnpacker(WithOverwrite(OverwriteFail)).Unpack(packedFile, extractDir)
+	assert.ErrorIs(t, err, ErrFileExists)
+}
+
 func TestPackReaderAndUnpackReader(t *testing.T) {
 	// Create a zip archive in memory
 	zipBuf := new(bytes.Buffer)
@@ -162,3 +204,276 @@ func TestUnpackReaderWithInvalidData(t *testing.T) {
 	_, err := UnpackReader(bytes.NewReader(invalidData))
 	assert.Error(t, err)
 }
+
+func TestPackReaderWithEncryptionKeys(t *testing.T) {
+	zipBuf := new(bytes.Buffer)
+	zipWriter := zip.NewWriter(zipBuf)
+	w, err := zipWriter.Create("setup.exe")
+	require.NoError(t, err)
+	_, err = w.Write([]byte("setup"))
+	require.NoError(t, err)
+	require.NoError(t, zipWriter.Close())
+
+	modTime, err := SourceDateEpoch()
+	require.NoError(t, err)
+	opts := []PackOption{
+		WithReproducible(modTime),
+		WithEncryptionKeys(bytes.Repeat([]byte{1}, 32), bytes.Repeat([]byte{2}, 32), bytes.Repeat([]byte{3}, 16)),
+	}
+
+	first, err := PackReader(bytes.NewReader(zipBuf.Bytes()), "test", "setup.exe", opts...)
+	require.NoError(t, err)
+	firstData, err := io.ReadAll(first)
+	require.NoError(t, err)
+
+	second, err := PackReader(bytes.NewReader(zipBuf.Bytes()), "test", "setup.exe", opts...)
+	require.NoError(t, err)
+	secondData, err := io.ReadAll(second)
+	require.NoError(t, err)
+
+	assert.Equal(t, firstData, secondData)
+}
+
+func TestUnpackReaderErrors(t *testing.T) {
+	_, err := UnpackReader(bytes.NewReader([]byte("not a zip")))
+	assert.ErrorIs(t, err, ErrNotIntunewin)
+}
+
+func TestUnpackReaderWithInfo(t *testing.T) {
+	zipBuf := new(bytes.Buffer)
+	zipWriter := zip.NewWriter(zipBuf)
+	w, err := zipWriter.Create("setup.exe")
+	require.NoError(t, err)
+	_, err = w.Write([]byte("setup"))
+	require.NoError(t, err)
+	require.NoError(t, zipWriter.Close())
+
+	packed, err := PackReader(bytes.NewReader(zipBuf.Bytes()), "MyApp", "setup.exe")
+	require.NoError(t, err)
+
+	unpacked, appInfo, err := UnpackReaderWithInfo(packed)
+	require.NoError(t, err)
+	assert.Equal(t, "MyApp", appInfo.Name)
+	assert.Equal(t, "setup.exe", appInfo.SetupFile)
+	assert.Nil(t, appInfo.EncryptionInfo)
+
+	data, err := io.ReadAll(unpacked)
+	require.NoError(t, err)
+	assert.Equal(t, zipBuf.Bytes(), data)
+}
+
+func TestPackReaderWithToolVersion(t *testing.T) {
+	zipBuf := new(bytes.Buffer)
+	zipWriter := zip.NewWriter(zipBuf)
+	w, err := zipWriter.Create("setup.exe")
+	require.NoError(t, err)
+	_, err = w.Write([]byte("setup"))
+	require.NoError(t, err)
+	require.NoError(t, zipWriter.Close())
+
+	packed, err := PackReader(bytes.NewReader(zipBuf.Bytes()), "MyApp", "setup.exe", WithToolVersion("1.8.6.0"))
+	require.NoError(t, err)
+	_, appInfo, err := UnpackReaderWithInfo(packed)
+	require.NoError(t, err)
+	assert.Equal(t, "1.8.6.0", appInfo.ToolVersion)
+
+	_, err = PackReader(bytes.NewReader(zipBuf.Bytes()), "MyApp", "setup.exe", WithToolVersion("1.8-beta"))
+	assert.ErrorIs(t, err, ErrInvalidToolVersion)
+}
+
+func TestPackReaderAndUnpackReaderSpill(t *testing.T) {
+	zipBuf := new(bytes.Buffer)
+	zipWriter := zip.NewWriter(zipBuf)
+	w, err := zipWriter.Create("setup.exe")
+	require.NoError(t, err)
+	_, err = w.Write(bytes.Repeat([]byte("setup"), 10000))
+	require.NoError(t, err)
+	require.NoError(t, zipWriter.Close())
+
+	// A threshold of 0 sends all intermediate data through temporary files
+	tempDir := t.TempDir()
+	packedReader, err := PackReader(bytes.NewReader(zipBuf.Bytes()), "app", "setup.exe",
+		WithTempDir(tempDir), WithSpillThreshold(0))
+	require.NoError(t, err)
+
+	unpackedReader, err := UnpackReader(packedReader, WithUnpackTempDir(tempDir), WithUnpackSpillThreshold(0))
+	require.NoError(t, err)
+	unpacked, err := io.ReadAll(unpackedReader)
+	require.NoError(t, err)
+	assert.Equal(t, zipBuf.Bytes(), unpacked)
+
+	entries, err := os.ReadDir(tempDir)
+	require.NoError(t, err)
+	assert.Empty(t, entries, "temporary files should be removed")
+}
+
+func TestMaxMemory(t *testing.T) {
+	zipBuf := new(bytes.Buffer)
+	zipWriter := zip.NewWriter(zipBuf)
+	w, err := zipWriter.Create("setup.exe")
+	require.NoError(t, err)
+	_, err = w.Write(bytes.Repeat([]byte("setup"), 10000))
+	require.NoError(t, err)
+	require.NoError(t, zipWriter.Close())
+
+	// The memory limit is hit long before the default spill threshold
+	tempDir := t.TempDir()
+	packedReader, err := PackReader(bytes.NewReader(zipBuf.Bytes()), "app", "setup.exe",
+		WithTempDir(tempDir), WithMaxMemory(1024))
+	require.NoError(t, err)
+
+	unpackedReader, err := UnpackReader(packedReader, WithUnpackTempDir(tempDir), WithUnpackMaxMemory(1024))
+	require.NoError(t, err)
+	unpacked, err := io.ReadAll(unpackedReader)
+	require.NoError(t, err)
+	assert.Equal(t, zipBuf.Bytes(), unpacked)
+
+	entries, err := os.ReadDir(tempDir)
+	require.NoError(t, err)
+	assert.Empty(t, entries, "temporary files should be removed")
+}
+
+func TestPackHooks(t *testing.T) {
+	zipBuf := new(bytes.Buffer)
+	zipWriter := zip.NewWriter(zipBuf)
+	_, err := zipWriter.Create("setup.exe")
+	require.NoError(t, err)
+	require.NoError(t, zipWriter.Close())
+
+	var stages []PackHookStage
+	var digest string
+	output := new(bytes.Buffer)
+	err = PackTo(bytes.NewReader(zipBuf.Bytes()), output, "app", "setup.exe",
+		WithPrePackHook(func(info PackHookInfo) error {
+			stages = append(stages, info.Stage)
+			return nil
+		}),
+		WithPostPackHook(func(info PackHookInfo) error {
+			stages = append(stages, info.Stage)
+			digest = info.SHA256
+			return nil
+		}))
+	require.NoError(t, err)
+	assert.Equal(t, []PackHookStage{PackHookPrePack, PackHookPostPack}, stages)
+	assert.Len(t, digest, 64)
+
+	err = PackTo(bytes.NewReader(zipBuf.Bytes()), io.Discard, "app", "setup.exe",
+		WithPrePackHook(func(PackHookInfo) error { return errors.New("scan failed") }))
+	assert.ErrorIs(t, err, ErrHookFailed)
+}
+
+func TestRepair(t *testing.T) {
+	zipBuf := new(bytes.Buffer)
+	zipWriter := zip.NewWriter(zipBuf)
+	_, err := zipWriter.Create("setup.exe")
+	require.NoError(t, err)
+	require.NoError(t, zipWriter.Close())
+
+	packedReader, err := PackReader(bytes.NewReader(zipBuf.Bytes()), "app", "setup.exe")
+	require.NoError(t, err)
+	packed, err := io.ReadAll(packedReader)
+	require.NoError(t, err)
+
+	repaired := new(bytes.Buffer)
+	require.NoError(t, Repair(bytes.NewReader(packed), repaired))
+
+	path := filepath.Join(t.TempDir(), "app.intunewin")
+	require.NoError(t, os.WriteFile(path, repaired.Bytes(), 0600))
+	assert.NoError(t, Verify(path))
+
+	assert.ErrorIs(t, Repair(bytes.NewReader([]byte("not a package")), new(bytes.Buffer)), ErrNotIntunewin)
+}
+
+func TestPackToAndUnpackTo(t *testing.T) {
+	zipBuf := new(bytes.Buffer)
+	zw := zip.NewWriter(zipBuf)
+	w, err := zw.Create("setup.exe")
+	require.NoError(t, err)
+	_, err = w.Write([]byte("setup"))
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+
+	var packed bytes.Buffer
+	require.NoError(t, PackTo(bytes.NewReader(zipBuf.Bytes()), &packed, "app", "setup.exe"))
+
+	var unpacked bytes.Buffer
+	require.NoError(t, UnpackTo(bytes.NewReader(packed.Bytes()), &unpacked))
+	assert.Equal(t, zipBuf.Bytes(), unpacked.Bytes())
+}
+
+func TestUnpackReaderAt(t *testing.T) {
+	zipBuf := new(bytes.Buffer)
+	zw := zip.NewWriter(zipBuf)
+	w, err := zw.Create("setup.exe")
+	require.NoError(t, err)
+	_, err = w.Write([]byte("setup"))
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+
+	var packed bytes.Buffer
+	require.NoError(t, PackTo(bytes.NewReader(zipBuf.Bytes()), &packed, "app", "setup.exe"))
+	input := bytes.NewReader(packed.Bytes())
+
+	reader, err := UnpackReaderAt(input, input.Size())
+	require.NoError(t, err)
+	content, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	assert.Equal(t, zipBuf.Bytes(), content)
+
+	var unpacked bytes.Buffer
+	require.NoError(t, NewUnpacker().UnpackReaderAtTo(input, input.Size(), &unpacked))
+	assert.Equal(t, zipBuf.Bytes(), unpacked.Bytes())
+
+	_, err = UnpackReaderAt(bytes.NewReader([]byte("not a package")), 13)
+	assert.ErrorIs(t, err, ErrNotIntunewin)
+}
+
+func TestPackTarToAndUnpackToTar(t *testing.T) {
+	tarBuf := new(bytes.Buffer)
+	tw := tar.NewWriter(tarBuf)
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "setup.exe", Typeflag: tar.TypeReg, Mode: 0644, Size: 5}))
+	_, err := tw.Write([]byte("setup"))
+	require.NoError(t, err)
+	require.NoError(t, tw.Close())
+
+	var packed bytes.Buffer
+	require.NoError(t, PackTarTo(bytes.NewReader(tarBuf.Bytes()), &packed, "app", "setup.exe"))
+
+	var unpacked bytes.Buffer
+	require.NoError(t, UnpackToTar(bytes.NewReader(packed.Bytes()), &unpacked))
+	tr := tar.NewReader(&unpacked)
+	header, err := tr.Next()
+	require.NoError(t, err)
+	assert.Equal(t, "setup.exe", header.Name)
+	content, err := io.ReadAll(tr)
+	require.NoError(t, err)
+	assert.Equal(t, "setup", string(content))
+}
+
+func TestOpen(t *testing.T) {
+	zipBuf := new(bytes.Buffer)
+	zw := zip.NewWriter(zipBuf)
+	for name, content := range map[string]string{"setup.exe": "setup", "scripts/install.ps1": "Write-Host install"} {
+		w, err := zw.Create(name)
+		require.NoError(t, err)
+		_, err = w.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+
+	var packed bytes.Buffer
+	require.NoError(t, PackTo(bytes.NewReader(zipBuf.Bytes()), &packed, "app", "setup.exe"))
+
+	fsys, appInfo, err := Open(bytes.NewReader(packed.Bytes()))
+	require.NoError(t, err)
+	assert.Equal(t, "app", appInfo.Name)
+	assert.Nil(t, appInfo.EncryptionInfo)
+	require.NoError(t, fstest.TestFS(fsys, "setup.exe", "scripts/install.ps1"))
+
+	data, err := fs.ReadFile(fsys, "scripts/install.ps1")
+	require.NoError(t, err)
+	assert.Equal(t, "Write-Host install", string(data))
+
+	_, _, err = Open(bytes.NewReader([]byte("not a package")))
+	assert.ErrorIs(t, err, ErrNotIntunewin)
+}
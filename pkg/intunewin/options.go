@@ -0,0 +1,343 @@
+package intunewin
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/kenchan0130/intunewin/internal/charset"
+	"github.com/kenchan0130/intunewin/internal/pack"
+	"github.com/kenchan0130/intunewin/internal/progress"
+	"github.com/kenchan0130/intunewin/internal/spill"
+	"github.com/kenchan0130/intunewin/internal/unpack"
+)
+
+// PackOption configures how PackReader creates a package.
+type PackOption = pack.Option
+
+// UnpackOption configures how Unpack, UnpackReader and the other unpack functions extract
+// a package. Options about the files written to a folder, such as WithOnly, WithOverwrite,
+// WithNoPreserve and WithAllowSymlinks, only apply to Unpack.
+type UnpackOption = unpack.Option
+
+// WithLogger sends diagnostic messages emitted while packing to logger.
+// Nothing is logged by default.
+func WithLogger(logger *slog.Logger) PackOption {
+	return pack.WithLogger(logger)
+}
+
+// WithStrict makes unpacking fail with ErrUnsupportedVersion when Detection.xml has a ToolVersion,
+// ProfileIdentifier or digest algorithm this library does not recognize, and with ErrNonstandard
+// when the package deviates from the layout of the Microsoft tool, such as entry or element names
+// in a different case or extra elements in Detection.xml.
+// By default such packages are unpacked best-effort and a warning is logged.
+func WithStrict() UnpackOption {
+	return unpack.WithStrict()
+}
+
+// WithNoVerify skips comparing the size and SHA-256 digest of the decrypted content with
+// Detection.xml. By default a mismatch fails with ErrSizeMismatch or ErrDigestMismatch.
+func WithNoVerify() UnpackOption {
+	return unpack.WithNoVerify()
+}
+
+// WithNoPreserve makes Unpack create files with mode 0644 and folders with mode 0755 at the
+// current time. By default the modes and modification times recorded in the package are restored.
+func WithNoPreserve() UnpackOption {
+	return unpack.WithNoPreserve()
+}
+
+// WithOnly makes Unpack extract only the entries matching any of patterns, such as "Files/**"
+// or "*.ps1", and return ErrNoMatches when none does. A pattern without a slash matches the name
+// of a file or folder at any depth, otherwise the path in the content; "**" matches any number
+// of folders, and selecting a folder selects everything below it.
+func WithOnly(patterns ...string) UnpackOption {
+	return unpack.WithOnly(patterns...)
+}
+
+// OverwritePolicy selects what Unpack does with files that already exist in the output folder.
+type OverwritePolicy = unpack.OverwritePolicy
+
+// Overwrite policies for WithOverwrite.
+const (
+	OverwriteForce = unpack.OverwriteForce
+	OverwriteSkip  = unpack.OverwriteSkip
+	OverwriteFail  = unpack.OverwriteFail
+)
+
+// WithOverwrite selects what Unpack does with files that already exist in the output folder.
+// The default is OverwriteForce; OverwriteFail returns ErrFileExists.
+func WithOverwrite(policy OverwritePolicy) UnpackOption {
+	return unpack.WithOverwrite(policy)
+}
+
+// FilenameEncoding is the encoding of file names that are not flagged as UTF-8 in the content.
+type FilenameEncoding = charset.Encoding
+
+// Filename encodings for WithFilenameEncoding.
+const (
+	FilenameEncodingAuto     = charset.Auto
+	FilenameEncodingUTF8     = charset.UTF8
+	FilenameEncodingShiftJIS = charset.ShiftJIS
+	FilenameEncodingCP437    = charset.CP437
+)
+
+// WithFilenameEncoding makes Unpack, UnpackToTar and Open read the file names of the content
+// that are not flagged as UTF-8 in enc, such as FilenameEncodingShiftJIS for archives made on
+// Japanese Windows. By default the encoding is detected from the names: UTF-8, else Shift-JIS, else CP437.
+func WithFilenameEncoding(enc FilenameEncoding) UnpackOption {
+	return unpack.WithFilenameEncoding(enc)
+}
+
+// WithAllowSymlinks makes Unpack extract the symbolic links of the content. By default a link
+// fails with ErrSymlinkEntry, and with this option a link whose target is absolute or leads
+// outside the output folder fails with ErrPathTraversal.
+func WithAllowSymlinks() UnpackOption {
+	return unpack.WithAllowSymlinks()
+}
+
+// UnpackLimits caps the size and number of entries unpacked from an untrusted package.
+// A zero field is not limited.
+type UnpackLimits = unpack.Limits
+
+// WithUnpackLimits fails with ErrLimitExceeded when the content of a package exceeds limits,
+// checked before the content is decrypted or read where possible, such as for a service
+// unpacking uploaded packages. MaxTotalSize also rejects encrypted contents that inflate by
+// more than that many bytes.
+func WithUnpackLimits(limits UnpackLimits) UnpackOption {
+	return unpack.WithLimits(limits)
+}
+
+// WithUnpackLogger sends diagnostic messages emitted while unpacking to logger.
+// Nothing is logged by default.
+func WithUnpackLogger(logger *slog.Logger) UnpackOption {
+	return unpack.WithLogger(logger)
+}
+
+// DefaultSpillThreshold is the size in bytes above which intermediate data is written
+// to temporary files instead of memory by default.
+const DefaultSpillThreshold = spill.DefaultThreshold
+
+// WithTempDir writes temporary files created while packing to dir instead of os.TempDir.
+func WithTempDir(dir string) PackOption {
+	return pack.WithTempDir(dir)
+}
+
+// WithSpillThreshold keeps intermediate data of up to threshold bytes in memory while
+// packing and writes larger data to temporary files, which are removed when done.
+// A threshold of 0 always uses temporary files.
+func WithSpillThreshold(threshold int64) PackOption {
+	return pack.WithSpillThreshold(threshold)
+}
+
+// WithUnpackTempDir writes temporary files created while unpacking to dir instead of os.TempDir.
+func WithUnpackTempDir(dir string) UnpackOption {
+	return unpack.WithTempDir(dir)
+}
+
+// WithUnpackSpillThreshold keeps intermediate data of up to threshold bytes in memory while
+// unpacking and writes larger data to temporary files, which are removed when done.
+// A threshold of 0 always uses temporary files.
+func WithUnpackSpillThreshold(threshold int64) UnpackOption {
+	return unpack.WithSpillThreshold(threshold)
+}
+
+// WithMaxMemory keeps at most n bytes of intermediate data in memory while packing,
+// writing further data to temporary files even below the spill threshold.
+func WithMaxMemory(n int64) PackOption {
+	return pack.WithMemoryLimit(spill.NewLimit(n))
+}
+
+// WithUnpackMaxMemory keeps at most n bytes of intermediate data in memory while unpacking,
+// writing further data to temporary files even below the spill threshold.
+func WithUnpackMaxMemory(n int64) UnpackOption {
+	return unpack.WithMemoryLimit(spill.NewLimit(n))
+}
+
+// WithReproducible makes packing deterministic: entries are sorted, file modes are
+// normalized and every timestamp is set to modTime.
+// Combine with WithEncryptionKeys to get byte-identical output for the same input.
+func WithReproducible(modTime time.Time) PackOption {
+	return pack.WithReproducible(modTime)
+}
+
+// WithEncryptionKeys uses the given key material instead of generating random keys.
+// encryptionKey and macKey must be 32 bytes, iv must be 16 bytes.
+func WithEncryptionKeys(encryptionKey, macKey, iv []byte) PackOption {
+	return pack.WithEncryptionKeys(encryptionKey, macKey, iv)
+}
+
+// KeyProvider returns the key material of a package: a 32-byte encryption key, a 32-byte
+// MAC key and a 16-byte initialization vector.
+type KeyProvider = pack.KeyProvider
+
+// WithKeyProvider calls provider for the key material of every package instead of generating
+// random keys, such as to take keys from a hardware security module. Unlike WithEncryptionKeys,
+// every package gets its own keys when the option is given to a Packer.
+func WithKeyProvider(provider KeyProvider) PackOption {
+	return pack.WithKeyProvider(provider)
+}
+
+// SourceDateEpoch returns the time given by the SOURCE_DATE_EPOCH environment variable,
+// or 1980-01-01 when it is not set. It is suitable as the modTime of WithReproducible.
+func SourceDateEpoch() (time.Time, error) {
+	t, err := pack.SourceDateEpoch()
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to read source date epoch: %w", err)
+	}
+	return t, nil
+}
+
+// WithCompressionLevel sets the deflate level from 0 (no compression) to 9 (best compression).
+func WithCompressionLevel(level int) PackOption {
+	return pack.WithCompressionLevel(level)
+}
+
+// WithStoreExtensions replaces the list of file extensions (such as ".msi") that are
+// stored without compression because their content is already compressed.
+// Pass no extensions to deflate every file.
+func WithStoreExtensions(exts ...string) PackOption {
+	return pack.WithStoreExtensions(exts...)
+}
+
+// WithThreads reads and compresses up to n files concurrently when the content zip is built
+// from individual files, as the intunewin command does when packing a folder. PackReader,
+// PackTo and PackTarTo take an existing archive and ignore it. The default, 0, uses the
+// number of CPUs.
+func WithThreads(n int) PackOption {
+	return pack.WithThreads(n)
+}
+
+// SymlinkPolicy selects how symbolic links in the source folder are packed.
+type SymlinkPolicy = pack.SymlinkPolicy
+
+// Symbolic link policies for WithSymlinks.
+const (
+	SymlinkFollow = pack.SymlinkFollow
+	SymlinkSkip   = pack.SymlinkSkip
+	SymlinkError  = pack.SymlinkError
+)
+
+// WithPOSIXModes records the Unix mode of every entry, such as the executable bit, in addition
+// to its MS-DOS attributes, so that unpacking on Linux or macOS restores it. By default only
+// MS-DOS attributes are written, as by the Microsoft tool.
+func WithPOSIXModes() PackOption {
+	return pack.WithPOSIXModes()
+}
+
+// WithNoDataDescriptors writes the CRC32 and sizes of every entry in its local header, as the
+// Microsoft tool does, instead of in a data descriptor after the entry, for zip consumers that
+// mishandle data descriptors. Each entry is compressed to a temporary buffer first.
+func WithNoDataDescriptors() PackOption {
+	return pack.WithNoDataDescriptors()
+}
+
+// WithSymlinks selects how symbolic links in the source folder are packed.
+// The default is SymlinkFollow, which fails on links that lead back to a parent folder.
+func WithSymlinks(policy SymlinkPolicy) PackOption {
+	return pack.WithSymlinks(policy)
+}
+
+// WithSkipSetupFileCheck packs content that does not contain the setup file.
+// By default packing fails with ErrSetupFileNotFound when the content zip has no such file.
+func WithSkipSetupFileCheck() PackOption {
+	return pack.WithSkipSetupFileCheck()
+}
+
+// WithDescription records description in Detection.xml.
+func WithDescription(description string) PackOption {
+	return pack.WithDescription(description)
+}
+
+// WithToolVersion records version, such as 1.8.6.0, as the ToolVersion in Detection.xml.
+// The default is a version Intune accepts; packing fails with ErrInvalidToolVersion when
+// version is not dotted numbers.
+func WithToolVersion(version string) PackOption {
+	return pack.WithToolVersion(version)
+}
+
+// PackSummary describes a written package: its file count, unencrypted content size, SHA-256 digest
+// and Detection.xml metadata.
+type PackSummary = pack.Summary
+
+// WithSummary fills s once the package has been written.
+// The encryption info is left out of its ApplicationInfo unless WithRetainKeys is given.
+func WithSummary(s *PackSummary) PackOption {
+	return pack.WithSummary(s)
+}
+
+// WithRetainKeys keeps the encryption info, including the keys, in the ApplicationInfo of
+// the PackSummary. Leave it out unless the keys are needed, such as to commit the file to Intune.
+func WithRetainKeys() PackOption {
+	return pack.WithRetainKeys()
+}
+
+// PackHookStage is when a pack hook runs.
+type PackHookStage = pack.HookStage
+
+// Stages at which pack hooks run.
+const (
+	PackHookPrePack  = pack.HookPrePack
+	PackHookPostPack = pack.HookPostPack
+)
+
+// PackHookInfo describes the package being created to a pack hook: the Detection.xml name
+// and setup file, and, after packing, the file count, unencrypted size and SHA-256 digest of the package.
+type PackHookInfo = pack.HookInfo
+
+// PackHook is called before or after a package is created. An error aborts the pack
+// with ErrHookFailed.
+type PackHook = pack.Hook
+
+// WithPrePackHook runs hook before the archive is read, such as to check that the build
+// producing it passed a malware scan. Hooks run in the order given.
+func WithPrePackHook(hook PackHook) PackOption {
+	return pack.WithPrePackHook(hook)
+}
+
+// WithPostPackHook runs hook after the package has been written, such as to send a notification.
+// The hook receives the digest of the package; the package has already been written to w when it fails.
+func WithPostPackHook(hook PackHook) PackOption {
+	return pack.WithPostPackHook(hook)
+}
+
+// WithUnpackRetainKeys keeps the encryption info, including the keys, in the ApplicationInfo
+// returned by UnpackReaderWithInfo.
+func WithUnpackRetainKeys() UnpackOption {
+	return unpack.WithRetainKeys()
+}
+
+// DefaultStoreExtensions returns the extensions that are stored without compression by default.
+func DefaultStoreExtensions() []string {
+	return pack.DefaultStoreExtensions()
+}
+
+// ProgressStage identifies a step of packing.
+type ProgressStage = progress.Stage
+
+// Packing stages reported to a ProgressReporter.
+const (
+	// ProgressWalk counts the bytes of the files found in the source folder.
+	ProgressWalk = progress.Walk
+	// ProgressCompress counts the bytes of source files written to the content zip.
+	ProgressCompress = progress.Compress
+	// ProgressEncrypt counts the bytes of the content zip that have been encrypted.
+	ProgressEncrypt = progress.Encrypt
+)
+
+// ProgressReporter receives progress updates while packing.
+// Progress is called with the bytes processed so far and the total for the stage,
+// or 0 when the total is not known yet.
+type ProgressReporter = progress.Reporter
+
+// ProgressFileReporter can be implemented by a ProgressReporter that also wants to
+// know which file is being walked or compressed.
+type ProgressFileReporter = progress.FileReporter
+
+// ProgressReporterFunc adapts a function to the ProgressReporter interface.
+type ProgressReporterFunc = progress.ReporterFunc
+
+// WithProgress reports the progress of packing to reporter.
+func WithProgress(reporter ProgressReporter) PackOption {
+	return pack.WithProgress(reporter)
+}
@@ -0,0 +1,109 @@
+package intunewin
+
+import (
+	"io"
+	"io/fs"
+	"slices"
+
+	"github.com/kenchan0130/intunewin/pkg/metadata"
+)
+
+// Packer packs intunewin packages with a configuration shared by every call, such as a
+// logger, a memory limit from WithMaxMemory or a KeyProvider. It keeps no state between
+// calls, so a single Packer can be used concurrently, for example by the handlers of a
+// server processing many packages.
+//
+// Options given to a method apply to that call only and come after the options of the
+// Packer. Options that describe a single package, such as WithSummary, WithEncryptionKeys
+// or WithDescription, belong in the call rather than in NewPacker.
+type Packer struct {
+	opts []PackOption
+}
+
+// NewPacker returns a Packer that applies opts to every package.
+func NewPacker(opts ...PackOption) *Packer {
+	return &Packer{opts: slices.Clone(opts)}
+}
+
+// options returns the options of p followed by the options of a call
+func (p *Packer) options(opts []PackOption) []PackOption {
+	return append(slices.Clone(p.opts), opts...)
+}
+
+// PackReader is like the package-level PackReader with the options of p.
+func (p *Packer) PackReader(zipReader io.Reader, name, setupFile string, opts ...PackOption) (io.Reader, error) {
+	return PackReader(zipReader, name, setupFile, p.options(opts)...)
+}
+
+// PackTo is like the package-level PackTo with the options of p.
+func (p *Packer) PackTo(zipReader io.Reader, w io.Writer, name, setupFile string, opts ...PackOption) error {
+	return PackTo(zipReader, w, name, setupFile, p.options(opts)...)
+}
+
+// PackTarTo is like the package-level PackTarTo with the options of p.
+func (p *Packer) PackTarTo(tarReader io.Reader, w io.Writer, name, setupFile string, opts ...PackOption) error {
+	return PackTarTo(tarReader, w, name, setupFile, p.options(opts)...)
+}
+
+// Unpacker unpacks intunewin packages with a configuration shared by every call, such as
+// a logger, WithStrict or a memory limit from WithUnpackMaxMemory. Like Packer, it keeps
+// no state between calls and can be used concurrently; options given to a method apply
+// to that call only and come after the options of the Unpacker.
+type Unpacker struct {
+	opts []UnpackOption
+}
+
+// NewUnpacker returns an Unpacker that applies opts to every package.
+func NewUnpacker(opts ...UnpackOption) *Unpacker {
+	return &Unpacker{opts: slices.Clone(opts)}
+}
+
+// options returns the options of u followed by the options of a call
+func (u *Unpacker) options(opts []UnpackOption) []UnpackOption {
+	return append(slices.Clone(u.opts), opts...)
+}
+
+// Unpack is like the package-level Unpack with the options of u.
+func (u *Unpacker) Unpack(inputFile, outputFolder string, opts ...UnpackOption) error {
+	return Unpack(inputFile, outputFolder, u.options(opts)...)
+}
+
+// UnpackReader is like the package-level UnpackReader with the options of u.
+func (u *Unpacker) UnpackReader(input io.Reader, opts ...UnpackOption) (io.Reader, error) {
+	return UnpackReader(input, u.options(opts)...)
+}
+
+// UnpackReaderWithInfo is like the package-level UnpackReaderWithInfo with the options of u.
+func (u *Unpacker) UnpackReaderWithInfo(input io.Reader, opts ...UnpackOption) (io.Reader, *metadata.ApplicationInfo, error) {
+	return UnpackReaderWithInfo(input, u.options(opts)...)
+}
+
+// UnpackReaderAt is like the package-level UnpackReaderAt with the options of u.
+func (u *Unpacker) UnpackReaderAt(r io.ReaderAt, size int64, opts ...UnpackOption) (io.Reader, error) {
+	return UnpackReaderAt(r, size, u.options(opts)...)
+}
+
+// UnpackReaderAtTo is like the package-level UnpackReaderAtTo with the options of u.
+func (u *Unpacker) UnpackReaderAtTo(r io.ReaderAt, size int64, w io.Writer, opts ...UnpackOption) error {
+	return UnpackReaderAtTo(r, size, w, u.options(opts)...)
+}
+
+// Open is like the package-level Open with the options of u.
+func (u *Unpacker) Open(input io.Reader, opts ...UnpackOption) (fs.FS, *metadata.ApplicationInfo, error) {
+	return Open(input, u.options(opts)...)
+}
+
+// UnpackTo is like the package-level UnpackTo with the options of u.
+func (u *Unpacker) UnpackTo(input io.Reader, w io.Writer, opts ...UnpackOption) error {
+	return UnpackTo(input, w, u.options(opts)...)
+}
+
+// UnpackToTar is like the package-level UnpackToTar with the options of u.
+func (u *Unpacker) UnpackToTar(input io.Reader, w io.Writer, opts ...UnpackOption) error {
+	return UnpackToTar(input, w, u.options(opts)...)
+}
+
+// Repair is like the package-level Repair with the options of u.
+func (u *Unpacker) Repair(r io.Reader, w io.Writer, opts ...UnpackOption) error {
+	return Repair(r, w, u.options(opts)...)
+}
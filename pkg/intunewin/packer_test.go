@@ -0,0 +1,64 @@
+package intunewin
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPackerAndUnpackerConcurrent(t *testing.T) {
+	packer := NewPacker(WithMaxMemory(1<<20), WithCompressionLevel(1))
+	unpacker := NewUnpacker(WithStrict(), WithUnpackMaxMemory(1<<20))
+
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			content := fmt.Appendf(nil, "setup %d", i)
+			zipBuf := new(bytes.Buffer)
+			zw := zip.NewWriter(zipBuf)
+			w, err := zw.Create("setup.exe")
+			assert.NoError(t, err)
+			_, err = w.Write(content)
+			assert.NoError(t, err)
+			assert.NoError(t, zw.Close())
+
+			var summary PackSummary
+			var packed bytes.Buffer
+			name := fmt.Sprintf("app%d", i)
+			if !assert.NoError(t, packer.PackTo(bytes.NewReader(zipBuf.Bytes()), &packed, name, "setup.exe", WithSummary(&summary))) {
+				return
+			}
+			assert.Equal(t, name, summary.ApplicationInfo.Name)
+
+			var unpacked bytes.Buffer
+			if assert.NoError(t, unpacker.UnpackTo(bytes.NewReader(packed.Bytes()), &unpacked)) {
+				assert.Equal(t, zipBuf.Bytes(), unpacked.Bytes())
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestPackerKeyProvider(t *testing.T) {
+	var mu sync.Mutex
+	calls := 0
+	packer := NewPacker(WithKeyProvider(func() ([]byte, []byte, []byte, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+		return bytes.Repeat([]byte{byte(calls)}, 32), bytes.Repeat([]byte{2}, 32), bytes.Repeat([]byte{3}, 16), nil
+	}))
+
+	for range 2 {
+		_, err := packer.PackReader(bytes.NewReader([]byte("MSCF content")), "app", "setup.exe")
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 2, calls)
+}
@@ -0,0 +1,66 @@
+// Package metadata reads, writes and validates Detection.xml, the metadata file
+// stored at IntuneWinPackage/Metadata/Detection.xml in every intunewin package.
+package metadata
+
+import (
+	"fmt"
+
+	"github.com/kenchan0130/intunewin/internal/crypto"
+	"github.com/kenchan0130/intunewin/internal/metadata"
+	"github.com/kenchan0130/intunewin/internal/unpack"
+)
+
+// ApplicationInfo is the root element of Detection.xml.
+type ApplicationInfo = metadata.ApplicationInfo
+
+// XMLEncryptionInfo is the EncryptionInfo element of Detection.xml with base64 encoded values.
+// Use its ToEncryptionInfo method to decode it.
+type XMLEncryptionInfo = metadata.XMLEncryptionInfo
+
+// EncryptionInfo is the decoded key material and digest of the encrypted contents.
+type EncryptionInfo = crypto.EncryptionInfo
+
+// CommitRequest is the body of the Graph request that commits the content file of a Win32 app.
+// Its fileEncryptionInfo holds the base64 encoded values of Detection.xml.
+type CommitRequest = metadata.CommitRequest
+
+// FileEncryptionInfo is the fileEncryptionInfo object of a CommitRequest.
+type FileEncryptionInfo = metadata.FileEncryptionInfo
+
+// NewCommitRequest creates the commit request for the package described by appInfo.
+// It fails when the encryption info of appInfo is missing or invalid.
+func NewCommitRequest(appInfo *ApplicationInfo) (*CommitRequest, error) {
+	return metadata.NewCommitRequest(appInfo) //nolint:wrapcheck // metadata errors already describe the failure
+}
+
+// NewApplicationInfo creates the ApplicationInfo for a package with the given application name,
+// setup file, size of the unencrypted content zip and encryption info.
+// Text values are normalized so they survive an XML round trip.
+func NewApplicationInfo(name, setupFile string, unencryptedContentSize int64, encryptionInfo *EncryptionInfo) *ApplicationInfo {
+	return metadata.NewApplicationInfo(name, setupFile, unencryptedContentSize, encryptionInfo)
+}
+
+// Parse parses the content of a Detection.xml file.
+// The result is not validated; call its Validate method to check it.
+func Parse(data []byte) (*ApplicationInfo, error) {
+	appInfo, err := metadata.FromXMLBytes(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse Detection.xml: %w", err)
+	}
+	return appInfo, nil
+}
+
+// ReadFile reads Detection.xml from the intunewin file at path without decrypting its contents.
+func ReadFile(path string) (*ApplicationInfo, error) {
+	appInfo, err := unpack.ReadApplicationInfo(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Detection.xml: %w", err)
+	}
+	return appInfo, nil
+}
+
+// NormalizeText makes s safe to store in Detection.xml: invalid UTF-8 is replaced,
+// line endings are converted to LF and characters XML 1.0 cannot represent are removed.
+func NormalizeText(s string) string {
+	return metadata.NormalizeText(s)
+}
@@ -0,0 +1,43 @@
+package metadata
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kenchan0130/intunewin/internal/pack"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadFileAndValidate(t *testing.T) {
+	sourceDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(sourceDir, "setup.exe"), []byte("setup"), 0600))
+	packedFile := filepath.Join(t.TempDir(), "app.intunewin")
+	require.NoError(t, pack.PackWithInfo(sourceDir, packedFile, "MyApp", "setup.exe"))
+
+	appInfo, err := ReadFile(packedFile)
+	require.NoError(t, err)
+	assert.Equal(t, "MyApp", appInfo.Name)
+	assert.Equal(t, "setup.exe", appInfo.SetupFile)
+	require.NoError(t, appInfo.Validate())
+
+	data, err := appInfo.ToXML()
+	require.NoError(t, err)
+	parsed, err := Parse(data)
+	require.NoError(t, err)
+	assert.Equal(t, appInfo.EncryptionInfo, parsed.EncryptionInfo)
+
+	encInfo, err := parsed.EncryptionInfo.ToEncryptionInfo()
+	require.NoError(t, err)
+	assert.Len(t, encInfo.EncryptionKey, 32)
+
+	commit, err := NewCommitRequest(appInfo)
+	require.NoError(t, err)
+	assert.Equal(t, appInfo.EncryptionInfo.Mac, commit.FileEncryptionInfo.Mac)
+}
+
+func TestParseInvalid(t *testing.T) {
+	_, err := Parse([]byte("<ApplicationInfo"))
+	assert.Error(t, err)
+}